@@ -0,0 +1,280 @@
+// Package channeldoc exports the channels to portable JSON or YAML documents
+// and imports them back into the database.
+//
+// The channels in the document have the same shape as in the Control
+// server API, so that the documents can be kept in a version control system
+// and moved between the deployments.
+package channeldoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/mailgun-relay-controlery/control"
+)
+
+// Format enumerates the supported document formats.
+type Format int
+
+const (
+	// JSON defines the JSON document format.
+	JSON Format = 0
+	// YAML defines the YAML document format.
+	YAML Format = 1
+)
+
+// ParseFormat parses the name of the format ("json" or "yaml").
+func ParseFormat(name string) (format Format, err error) {
+	switch name {
+	case "json":
+		format = JSON
+	case "yaml":
+		format = YAML
+	default:
+		err = fmt.Errorf("expected the format to be either json or yaml, "+
+			"got: %#v", name)
+	}
+	return
+}
+
+// FormatOfPath infers the format from the extension of the path.
+// Paths ending in .yaml or .yml are YAML documents, all the others
+// are JSON documents.
+func FormatOfPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML
+	default:
+		return JSON
+	}
+}
+
+// Entry is a channel in the document.
+type Entry struct {
+	control.Channel
+
+	// LastRelay is the time of the last relayed message in RFC 3339 format.
+	LastRelay *string `json:"last_relay,omitempty"`
+}
+
+// Document lists the channels.
+type Document struct {
+	Channels []Entry `json:"channels"`
+}
+
+// Marshal serializes the document in the given format.
+//
+// Marshal requires:
+// * doc != nil
+func Marshal(doc *Document, format Format) (data []byte, err error) {
+	// Pre-condition
+	if !(doc != nil) {
+		panic("Violated: doc != nil")
+	}
+
+	data, err = json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the document to JSON: %s",
+			err.Error())
+		return
+	}
+
+	if format == YAML {
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			err = fmt.Errorf("failed to convert the document to YAML: %s",
+				err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+// Unmarshal parses and validates the document given in the given format.
+//
+// Every channel is validated against the JSON schema of the Control server
+// API and needs to have at most one of the token and the token hash as well as
+// at least one token.
+//
+// Unmarshal ensures:
+// * err != nil || doc != nil
+func Unmarshal(data []byte, format Format) (doc *Document, err error) {
+	// Post-condition
+	defer func() {
+		if !(err != nil || doc != nil) {
+			panic("Violated: err != nil || doc != nil")
+		}
+	}()
+
+	if format == YAML {
+		data, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			err = fmt.Errorf("failed to convert the document from YAML: %s",
+				err.Error())
+			return
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	parsed := &Document{}
+	err = decoder.Decode(parsed)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the document: %s", err.Error())
+		return
+	}
+
+	raw := struct {
+		Channels []map[string]json.RawMessage `json:"channels"`
+	}{}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the document: %s", err.Error())
+		return
+	}
+
+	seen := make(map[control.Descriptor]bool)
+	for i, entry := range parsed.Channels {
+		err = validateEntry(raw.Channels[i], entry)
+		if err != nil {
+			err = fmt.Errorf("invalid channel %d (%#v): %s",
+				i, entry.Descriptor, err.Error())
+			return
+		}
+
+		if seen[entry.Descriptor] {
+			err = fmt.Errorf("duplicate channel %#v", entry.Descriptor)
+			return
+		}
+		seen[entry.Descriptor] = true
+	}
+
+	doc = parsed
+	return
+}
+
+// validateEntry validates the channel as given in the document, so that
+// the missing required properties are detected, and the parsed entry.
+func validateEntry(raw map[string]json.RawMessage, entry Entry) (err error) {
+	delete(raw, "last_relay")
+
+	serialized, err := json.Marshal(raw)
+	if err != nil {
+		return
+	}
+
+	err = control.ValidateAgainstChannelSchema(serialized)
+	if err != nil {
+		return
+	}
+
+	if entry.Token != nil && entry.TokenHash != nil {
+		err = fmt.Errorf("expected at most one of 'token' and 'token_hash'")
+		return
+	}
+
+	if entry.Token == nil && entry.TokenHash == nil && len(entry.Tokens) == 0 {
+		err = fmt.Errorf("expected 'token', 'token_hash' or 'tokens'")
+		return
+	}
+
+	if entry.LastRelay != nil {
+		_, err = parseLastRelay(*entry.LastRelay)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// formatLastRelay formats the timestamp in RFC 3339 format.
+func formatLastRelay(timestamp database.Timestamp) string {
+	return timestamp.ToTime().Format(time.RFC3339Nano)
+}
+
+// parseLastRelay parses the timestamp given in RFC 3339 format.
+func parseLastRelay(text string) (timestamp database.Timestamp, err error) {
+	parsed, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the last relay time: %s",
+			err.Error())
+		return
+	}
+
+	timestamp = database.TimestampFromTime(parsed)
+	if parsed.Before(time.Unix(0, 0)) || timestamp == 0 {
+		err = fmt.Errorf("expected the last relay time to be after "+
+			"the epoch, got: %s", text)
+		return
+	}
+
+	return
+}
+
+// Export lists all the channels of the database in a document.
+// The time of the last relayed message is included only if withTimestamps
+// is set.
+//
+// Export requires:
+// * txn != nil
+//
+// Export ensures:
+// * err != nil || doc != nil
+func Export(txn *database.Txn, withTimestamps bool) (doc *Document,
+	err error) {
+	// Pre-condition
+	if !(txn != nil) {
+		panic("Violated: txn != nil")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || doc != nil) {
+			panic("Violated: err != nil || doc != nil")
+		}
+	}()
+
+	channels, err := txn.AllChannels()
+	if err != nil {
+		return
+	}
+
+	exported := &Document{Channels: []Entry{}}
+	for _, channel := range channels {
+		if channel.TokenHash == nil && len(channel.Tokens) == 0 {
+			err = fmt.Errorf("the channel %#v has no token hash; "+
+				"please upgrade the database first", channel.Descriptor_)
+			return
+		}
+
+		entry := Entry{Channel: *control.ProtoToJSON(channel)}
+
+		if withTimestamps {
+			var timestamp *database.Timestamp
+			timestamp, err = txn.GetLastRelay(channel.Descriptor_)
+			if err != nil {
+				return
+			}
+
+			if timestamp != nil {
+				lastRelay := formatLastRelay(*timestamp)
+				entry.LastRelay = &lastRelay
+			}
+		}
+
+		exported.Channels = append(exported.Channels, entry)
+	}
+
+	doc = exported
+	return
+}
@@ -0,0 +1,641 @@
+// +build cgo
+
+package channeldoc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []Format{JSON, YAML} {
+		source, err := emptyDatabase()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer func() {
+			err = os.RemoveAll(source.Path)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+		defer func() {
+			err = source.Close()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+
+		channels := []*protoed.Channel{
+			dummyChannel("channel-01", 0.0001),
+			dummyChannel("channel-02", 60)}
+		timestamp := database.Timestamp(1538476500123)
+
+		err = source.Update(func(txn *database.Txn) (txnErr error) {
+			for _, channel := range channels {
+				txnErr = txn.PutChannel(channel)
+				if txnErr != nil {
+					return
+				}
+			}
+
+			txnErr = txn.PutLastRelay("channel-01", timestamp)
+			if txnErr != nil {
+				return
+			}
+
+			// The rate state moved by a later reservation is not exported.
+			reserved := timestamp + 60000
+			txnErr = txn.PutTimestamp("channel-01", &reserved)
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var doc *Document
+		err = source.View(func(txn *database.Txn) (txnErr error) {
+			doc, txnErr = Export(txn, true)
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		data, err := Marshal(doc, format)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		parsed, err := Unmarshal(data, format)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		target, err := emptyDatabase()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer func() {
+			err = os.RemoveAll(target.Path)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+		defer func() {
+			err = target.Close()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+
+		var changes []Change
+		err = target.Update(func(txn *database.Txn) (txnErr error) {
+			changes, txnErr = Import(txn, parsed, Merge, false,
+				database.Actor{Name: "test"}, database.DefaultRevisions)
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		expectedChanges := []Change{
+			{Descriptor: "channel-01", Action: Add},
+			{Descriptor: "channel-02", Action: Add}}
+		if !reflect.DeepEqual(expectedChanges, changes) {
+			t.Fatalf("expected changes %#v, got %#v",
+				expectedChanges, changes)
+		}
+
+		err = target.View(func(txn *database.Txn) (txnErr error) {
+			for _, expected := range channels {
+				var got *protoed.Channel
+				got, txnErr = txn.GetChannel(expected.Descriptor_)
+				if txnErr != nil {
+					return
+				}
+				database.CompareChannels(expected, got, t)
+			}
+
+			var got *database.Timestamp
+			got, txnErr = txn.GetLastRelay("channel-01")
+			if txnErr != nil {
+				return
+			}
+			if got == nil || *got != timestamp {
+				t.Fatalf("expected the last relay %d, got %v", timestamp, got)
+			}
+
+			// The last relay is not imported as the rate state.
+			got, txnErr = txn.GetTimestamp("channel-01")
+			if txnErr != nil {
+				return
+			}
+			if got != nil {
+				t.Fatalf("expected no rate state, got %d", *got)
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	type testCase struct {
+		name string
+		text string
+	}
+
+	testCases := []testCase{
+		{name: "unknown field", text: `channels:
+- descriptor: some-channel
+  token: some-token
+  sender: {email: some@sender.com}
+  recipients: [{email: some@recipient.com}]
+  domain: some-domain.com
+  min_period: 1
+  max_size: 1000
+  unknown: 1
+`},
+		{name: "both token and hash", text: `channels:
+- descriptor: some-channel
+  token: some-token
+  token_hash: "$argon2id$v=19$m=65536,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"
+  sender: {email: some@sender.com}
+  recipients: [{email: some@recipient.com}]
+  domain: some-domain.com
+  min_period: 1
+  max_size: 1000
+`},
+		{name: "no token", text: `channels:
+- descriptor: some-channel
+  sender: {email: some@sender.com}
+  recipients: [{email: some@recipient.com}]
+  domain: some-domain.com
+  min_period: 1
+  max_size: 1000
+`},
+		{name: "missing domain", text: `channels:
+- descriptor: some-channel
+  token: some-token
+  sender: {email: some@sender.com}
+  recipients: [{email: some@recipient.com}]
+  min_period: 1
+  max_size: 1000
+`},
+		{name: "duplicate", text: `channels:
+- descriptor: some-channel
+  token: some-token
+  sender: {email: some@sender.com}
+  recipients: [{email: some@recipient.com}]
+  domain: some-domain.com
+  min_period: 1
+  max_size: 1000
+- descriptor: some-channel
+  token: other-token
+  sender: {email: some@sender.com}
+  recipients: [{email: some@recipient.com}]
+  domain: some-domain.com
+  min_period: 1
+  max_size: 1000
+`},
+		{name: "invalid last relay", text: `channels:
+- descriptor: some-channel
+  token: some-token
+  sender: {email: some@sender.com}
+  recipients: [{email: some@recipient.com}]
+  domain: some-domain.com
+  min_period: 1
+  max_size: 1000
+  last_relay: yesterday
+`},
+	}
+
+	for _, tc := range testCases {
+		_, err := Unmarshal([]byte(tc.text), YAML)
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestImport_Policies(t *testing.T) {
+	text := `channels:
+- descriptor: channel-01
+  token_hash: "$argon2id$v=19$m=65536,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"
+  sender: {email: johann.bach@composers.com, name: Johann Sebastian Bach}
+  recipients: [{email: cpe.bach@composers.com, name: CPE Bach}]
+  domain: some-domain.com
+  min_period: 30
+  max_size: 10000000
+- descriptor: channel-02
+  token_hash: "$argon2id$v=19$m=65536,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"
+  sender: {email: johann.bach@composers.com, name: Johann Sebastian Bach}
+  recipients: [{email: cpe.bach@composers.com, name: CPE Bach}]
+  domain: some-domain.com
+  min_period: 60
+  max_size: 10000000
+- descriptor: channel-04
+  token: some-token
+  sender: {email: johann.bach@composers.com, name: Johann Sebastian Bach}
+  recipients: [{email: cpe.bach@composers.com, name: CPE Bach}]
+  domain: some-domain.com
+  min_period: 60
+  max_size: 10000000
+`
+
+	doc, err := Unmarshal([]byte(text), YAML)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type testCase struct {
+		policy      Policy
+		dryRun      bool
+		changes     []Change
+		descriptors []string
+	}
+
+	testCases := []testCase{
+		{policy: Merge,
+			changes: []Change{
+				{Descriptor: "channel-01", Action: Update,
+					Fields: []string{"min_period"}},
+				{Descriptor: "channel-02", Action: Unchanged},
+				{Descriptor: "channel-04", Action: Add}},
+			descriptors: []string{"channel-01", "channel-02",
+				"channel-03", "channel-04"}},
+		{policy: Overwrite,
+			changes: []Change{
+				{Descriptor: "channel-01", Action: Update,
+					Fields: []string{"min_period"}},
+				{Descriptor: "channel-02", Action: Unchanged},
+				{Descriptor: "channel-04", Action: Add},
+				{Descriptor: "channel-03", Action: Remove}},
+			descriptors: []string{"channel-01", "channel-02",
+				"channel-04"}},
+		{policy: SkipExisting,
+			changes: []Change{
+				{Descriptor: "channel-01", Action: Skip},
+				{Descriptor: "channel-02", Action: Skip},
+				{Descriptor: "channel-04", Action: Add}},
+			descriptors: []string{"channel-01", "channel-02",
+				"channel-03", "channel-04"}},
+		{policy: Overwrite, dryRun: true,
+			changes: []Change{
+				{Descriptor: "channel-01", Action: Update,
+					Fields: []string{"min_period"}},
+				{Descriptor: "channel-02", Action: Unchanged},
+				{Descriptor: "channel-04", Action: Add},
+				{Descriptor: "channel-03", Action: Remove}},
+			descriptors: []string{"channel-01", "channel-02",
+				"channel-03"}},
+	}
+
+	for i, tc := range testCases {
+		func() {
+			d, err := emptyDatabase()
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			defer func() {
+				err = os.RemoveAll(d.Path)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+			}()
+			defer func() {
+				err = d.Close()
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+			}()
+
+			err = d.Update(func(txn *database.Txn) (txnErr error) {
+				for _, channel := range []*protoed.Channel{
+					dummyChannel("channel-01", 0.0001),
+					dummyChannel("channel-02", 60),
+					dummyChannel("channel-03", 60)} {
+					txnErr = txn.PutChannel(channel)
+					if txnErr != nil {
+						return
+					}
+				}
+				return
+			})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			var changes []Change
+			importFn := func(txn *database.Txn) (txnErr error) {
+				changes, txnErr = Import(txn, doc, tc.policy, tc.dryRun,
+					database.Actor{Name: "test"}, database.DefaultRevisions)
+				return
+			}
+			if tc.dryRun {
+				err = d.View(importFn)
+			} else {
+				err = d.Update(importFn)
+			}
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if !reflect.DeepEqual(tc.changes, changes) {
+				t.Fatalf("test case %d: expected changes %v, got %v",
+					i, tc.changes, changes)
+			}
+
+			err = d.View(func(txn *database.Txn) (txnErr error) {
+				var channels []*protoed.Channel
+				channels, txnErr = txn.AllChannels()
+				if txnErr != nil {
+					return
+				}
+
+				var descriptors []string
+				for _, channel := range channels {
+					descriptors = append(descriptors, channel.Descriptor_)
+
+					if channel.Token != "" {
+						t.Fatalf("test case %d: expected no plain-text "+
+							"token in %s", i, channel.Descriptor_)
+					}
+				}
+
+				if !reflect.DeepEqual(tc.descriptors, descriptors) {
+					t.Fatalf("test case %d: expected the channels %v, "+
+						"got %v", i, tc.descriptors, descriptors)
+				}
+
+				audited := uint64(0)
+				for _, change := range changes {
+					if !tc.dryRun && (change.Action == Add ||
+						change.Action == Update || change.Action == Remove) {
+						audited++
+					}
+				}
+
+				var count uint64
+				count, txnErr = txn.CountAuditRecords()
+				if txnErr != nil {
+					return
+				}
+
+				if count != audited {
+					t.Fatalf("test case %d: expected %d audit records, "+
+						"got %d", i, audited, count)
+				}
+				return
+			})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}()
+	}
+}
+
+func TestImport_PlaintextToken(t *testing.T) {
+	d, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	text := `{"channels": [{
+  "descriptor": "some-channel",
+  "token": "some-token",
+  "sender": {"email": "some@sender.com"},
+  "recipients": [{"email": "some@recipient.com"}],
+  "domain": "some-domain.com",
+  "min_period": 1,
+  "max_size": 1000}]}`
+
+	doc, err := Unmarshal([]byte(text), JSON)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var hash *protoed.TokenHash
+	for i, expected := range []Action{Add, Unchanged} {
+		var changes []Change
+		err = d.Update(func(txn *database.Txn) (txnErr error) {
+			changes, txnErr = Import(txn, doc, Merge, false,
+				database.Actor{Name: "test"}, database.DefaultRevisions)
+			if txnErr != nil {
+				return
+			}
+
+			var channel *protoed.Channel
+			channel, txnErr = txn.GetChannel("some-channel")
+			if txnErr != nil {
+				return
+			}
+
+			if channel.Token != "" ||
+				!tokenhash.Verify(channel.TokenHash, "some-token") {
+				t.Fatalf("import %d: expected the token to be hashed, "+
+					"got %s", i, channel.String())
+			}
+
+			if hash != nil && channel.TokenHash.String() != hash.String() {
+				t.Fatalf("import %d: expected the stored hash to be kept", i)
+			}
+			hash = channel.TokenHash
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(changes) != 1 || changes[0].Action != expected {
+			t.Fatalf("import %d: expected the action %d, got %v",
+				i, expected, changes)
+		}
+	}
+}
+
+func TestImport_KeepsDisabled(t *testing.T) {
+	d, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	disabled := dummyChannel("some-channel", 1)
+	disabled.Disabled = &protoed.Disabled{Time: 1538404620000000000,
+		Reason: "sends a message every second"}
+
+	err = d.Update(func(txn *database.Txn) error {
+		return txn.PutChannel(disabled)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var doc *Document
+	err = d.View(func(txn *database.Txn) (txnErr error) {
+		doc, txnErr = Export(txn, false)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if doc.Channels[0].Disabled == nil {
+		t.Fatalf("expected the disabled state to be exported")
+	}
+
+	// The document without the state leaves the channel disabled, while
+	// the exported state is restored as-is.
+	withoutState := *doc
+	withoutState.Channels = []Entry{doc.Channels[0]}
+	withoutState.Channels[0].Disabled = nil
+
+	for i, imported := range []*Document{&withoutState, doc} {
+		var changes []Change
+		err = d.Update(func(txn *database.Txn) (txnErr error) {
+			changes, txnErr = Import(txn, imported, Merge, false,
+				database.Actor{Name: "test"}, database.DefaultRevisions)
+			if txnErr != nil {
+				return
+			}
+
+			var channel *protoed.Channel
+			channel, txnErr = txn.GetChannel("some-channel")
+			if txnErr != nil {
+				return
+			}
+
+			if !proto.Equal(channel.Disabled, disabled.Disabled) {
+				t.Fatalf("import %d: expected the state %v, got %v",
+					i, disabled.Disabled, channel.Disabled)
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(changes) != 1 || changes[0].Action != Unchanged {
+			t.Fatalf("import %d: expected the channel to be unchanged, "+
+				"got %v", i, changes)
+		}
+	}
+}
+
+func dummyChannel(descriptor string, minPeriod float32) *protoed.Channel {
+	return &protoed.Channel{Descriptor_: descriptor,
+		TokenHash: database.DummyTokenHash(),
+		Sender: &protoed.Entity{Name: "Johann Sebastian Bach",
+			Email: "johann.bach@composers.com"},
+		Recipients: []*protoed.Entity{{Name: "CPE Bach",
+			Email: "cpe.bach@composers.com"}},
+		Domain:    "some-domain.com",
+		MinPeriod: minPeriod, MaxSize: 10000000}
+}
+
+// emptyDatabase creates an empty database.
+func emptyDatabase() (e *database.Env, err error) {
+	tmpdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		return
+	}
+
+	err = database.Initialize(database.ControlAccess, tmpdir)
+	if err != nil {
+		removeErr := os.RemoveAll(tmpdir)
+		if removeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		}
+		return
+	}
+
+	e, err = database.NewEnv(database.ControlAccess, tmpdir)
+	if err != nil {
+		removeErr := os.RemoveAll(tmpdir)
+		if removeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		}
+		return
+	}
+
+	return
+}
+
+func TestImport_InvalidDescriptor(t *testing.T) {
+	d, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	text := `{"channels": [{
+  "descriptor": "some\u0000channel",
+  "token": "some-token",
+  "sender": {"email": "some@sender.com"},
+  "recipients": [{"email": "some@recipient.com"}],
+  "domain": "some-domain.com",
+  "min_period": 1,
+  "max_size": 1000}]}`
+
+	doc, err := Unmarshal([]byte(text), JSON)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = d.Update(func(txn *database.Txn) (txnErr error) {
+		_, txnErr = Import(txn, doc, Merge, false,
+			database.Actor{Name: "test"}, database.DefaultRevisions)
+		return
+	})
+	if err == nil || !strings.Contains(err.Error(), "zero byte") {
+		t.Fatalf("expected an error on the invalid descriptor, got: %v", err)
+	}
+}
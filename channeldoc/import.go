@@ -0,0 +1,412 @@
+package channeldoc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/mailgun-relay-controlery/control"
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+// Policy defines how the channels already in the database are treated
+// on import.
+type Policy int
+
+const (
+	// Merge adds the new channels and updates the existing ones.
+	// The channels missing in the document are kept.
+	Merge Policy = 0
+	// Overwrite makes the database mirror the document: the new channels
+	// are added, the existing ones are updated and the channels missing
+	// in the document are removed.
+	Overwrite Policy = 1
+	// SkipExisting adds only the new channels and leaves the existing
+	// ones untouched.
+	SkipExisting Policy = 2
+)
+
+// ParsePolicy parses the name of the policy
+// ("merge", "overwrite" or "skip-existing").
+func ParsePolicy(name string) (policy Policy, err error) {
+	switch name {
+	case "merge":
+		policy = Merge
+	case "overwrite":
+		policy = Overwrite
+	case "skip-existing":
+		policy = SkipExisting
+	default:
+		err = fmt.Errorf("expected the policy to be one of merge, "+
+			"overwrite or skip-existing, got: %#v", name)
+	}
+	return
+}
+
+// Action enumerates what happens to a channel on import.
+type Action int
+
+const (
+	// Add denotes a new channel.
+	Add Action = 0
+	// Update denotes an existing channel which differs from the document.
+	Update Action = 1
+	// Remove denotes a channel missing in the document.
+	Remove Action = 2
+	// Skip denotes an existing channel left untouched due to the policy.
+	Skip Action = 3
+	// Unchanged denotes an existing channel equal to the document.
+	Unchanged Action = 4
+)
+
+// Change describes what happens to a channel on import.
+type Change struct {
+	Descriptor string
+	Action     Action
+
+	// Fields lists the names of the differing fields of the updated channel.
+	Fields []string
+}
+
+// String represents the change as a line of a diff.
+func (c Change) String() string {
+	switch c.Action {
+	case Add:
+		return "+ " + c.Descriptor
+	case Update:
+		return fmt.Sprintf("~ %s (%s)", c.Descriptor,
+			strings.Join(c.Fields, ", "))
+	case Remove:
+		return "- " + c.Descriptor
+	case Skip:
+		return fmt.Sprintf("  %s (skipped)", c.Descriptor)
+	case Unchanged:
+		return "  " + c.Descriptor
+	default:
+		panic(fmt.Sprintf("unhandled action: %d", c.Action))
+	}
+}
+
+// Import loads the channels of the document into the database according to
+// the policy and returns the changes. If dryRun is set, the changes are
+// only computed and the database is left untouched.
+//
+// The plain-text tokens in the document are hashed before they are stored.
+// A plain-text token matching the stored hash keeps the stored hash.
+// The default and the named tokens of a channel are kept if the document
+// omits them.
+//
+// The changes are recorded in the audit log on behalf of the actor and
+// the channels are stored as their next revisions keeping at most
+// the given number of revisions per channel.
+//
+// Import requires:
+// * txn != nil
+// * doc != nil
+// * revisions > 0
+func Import(txn *database.Txn, doc *Document, policy Policy,
+	dryRun bool, actor database.Actor, revisions uint) (changes []Change,
+	err error) {
+	// Pre-conditions
+	switch {
+	case !(txn != nil):
+		panic("Violated: txn != nil")
+	case !(doc != nil):
+		panic("Violated: doc != nil")
+	case !(revisions > 0):
+		panic("Violated: revisions > 0")
+	default:
+		// Pass
+	}
+
+	inDocument := make(map[string]bool)
+	now := time.Now()
+
+	for _, entry := range doc.Channels {
+		entry := entry
+
+		var change Change
+		change, err = importEntry(txn, &entry, policy, dryRun, actor, now,
+			revisions)
+		if err != nil {
+			err = fmt.Errorf("failed to import the channel %#v: %s",
+				entry.Descriptor, err.Error())
+			return
+		}
+
+		inDocument[change.Descriptor] = true
+		changes = append(changes, change)
+	}
+
+	if policy != Overwrite {
+		return
+	}
+
+	existing, err := txn.AllChannels()
+	if err != nil {
+		return
+	}
+
+	for _, channel := range existing {
+		if inDocument[channel.Descriptor_] {
+			continue
+		}
+
+		if !dryRun {
+			err = txn.RemoveChannel(channel.Descriptor_)
+			if err != nil {
+				return
+			}
+
+			err = txn.RecordChange(protoed.AuditRecord_DELETE, channel, nil,
+				actor, now, revisions)
+			if err != nil {
+				return
+			}
+		}
+
+		changes = append(changes,
+			Change{Descriptor: channel.Descriptor_, Action: Remove})
+	}
+
+	return
+}
+
+// importEntry imports a single channel of the document.
+func importEntry(txn *database.Txn, entry *Entry, policy Policy,
+	dryRun bool, actor database.Actor, now time.Time, revisions uint) (
+	change Change, err error) {
+	err = database.ValidateDescriptor(string(entry.Descriptor))
+	if err != nil {
+		return
+	}
+
+	channel, err := control.JSONToProto(&entry.Channel)
+	if err != nil {
+		return
+	}
+
+	var timestamp *database.Timestamp
+	if entry.LastRelay != nil {
+		var parsed database.Timestamp
+		parsed, err = parseLastRelay(*entry.LastRelay)
+		if err != nil {
+			return
+		}
+		timestamp = &parsed
+	}
+
+	change.Descriptor = channel.Descriptor_
+
+	old, err := txn.GetChannel(channel.Descriptor_)
+	if err != nil {
+		return
+	}
+
+	// Like an update through the control API, an import keeps the state of
+	// the channel if the document does not specify it.
+	if channel.Disabled == nil && old != nil {
+		channel.Disabled = old.Disabled
+	}
+
+	if old != nil {
+		if entry.Token == nil && entry.TokenHash == nil {
+			channel.TokenHash = old.TokenHash
+		}
+		if entry.Tokens == nil {
+			channel.Tokens = old.Tokens
+		}
+		keepCreated(old, channel)
+	}
+
+	for _, token := range channel.Tokens {
+		if token.Created == 0 {
+			token.Created = now.UnixNano()
+		}
+	}
+
+	switch {
+	case old == nil:
+		change.Action = Add
+	case policy == SkipExisting:
+		change.Action = Skip
+		return
+	default:
+		var oldLastRelay *database.Timestamp
+		oldLastRelay, err = txn.GetLastRelay(channel.Descriptor_)
+		if err != nil {
+			return
+		}
+
+		change.Fields = diff(old, channel)
+		if timestamp != nil &&
+			(oldLastRelay == nil || *oldLastRelay < *timestamp) {
+			change.Fields = append(change.Fields, "last_relay")
+		}
+
+		if len(change.Fields) == 0 {
+			change.Action = Unchanged
+			return
+		}
+		change.Action = Update
+	}
+
+	if dryRun {
+		return
+	}
+
+	if channel.Token != "" {
+		if old != nil && tokenhash.Matches(old, channel.Token) {
+			channel.TokenHash = old.TokenHash
+		} else {
+			channel.TokenHash, err = tokenhash.New(channel.Token)
+			if err != nil {
+				err = fmt.Errorf("failed to hash the token: %s", err.Error())
+				return
+			}
+		}
+		channel.Token = ""
+	}
+
+	err = txn.PutChannel(channel)
+	if err != nil {
+		return
+	}
+
+	err = txn.RecordChange(protoed.AuditRecord_PUT, old, channel, actor, now,
+		revisions)
+	if err != nil {
+		return
+	}
+
+	// The last relay is only informative and does not limit the rate of
+	// the channel.
+	if timestamp != nil {
+		err = txn.PutLastRelay(database.Descriptor(channel.Descriptor_),
+			*timestamp)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// diff lists the names of the fields which differ between the stored channel
+// and the channel from the document.
+func diff(old *protoed.Channel, channel *protoed.Channel) (fields []string) {
+	if channel.TokenHash != nil {
+		if !proto.Equal(old.TokenHash, channel.TokenHash) {
+			fields = append(fields, "token_hash")
+		}
+	} else if channel.Token != "" && !tokenhash.Matches(old, channel.Token) {
+		fields = append(fields, "token")
+	}
+
+	if !tokensEqual(old.Tokens, channel.Tokens) {
+		fields = append(fields, "tokens")
+	}
+
+	if !proto.Equal(old.Sender, channel.Sender) {
+		fields = append(fields, "sender")
+	}
+
+	if !entitiesEqual(old.Recipients, channel.Recipients) {
+		fields = append(fields, "recipients")
+	}
+
+	if !entitiesEqual(old.Cc, channel.Cc) {
+		fields = append(fields, "cc")
+	}
+
+	if !entitiesEqual(old.Bcc, channel.Bcc) {
+		fields = append(fields, "bcc")
+	}
+
+	if old.Domain != channel.Domain {
+		fields = append(fields, "domain")
+	}
+
+	if old.MinPeriod != channel.MinPeriod {
+		fields = append(fields, "min_period")
+	}
+
+	if old.MaxSize != channel.MaxSize {
+		fields = append(fields, "max_size")
+	}
+
+	if !proto.Equal(old.Disabled, channel.Disabled) {
+		fields = append(fields, "disabled")
+	}
+
+	if old.ValidUntil != channel.ValidUntil {
+		fields = append(fields, "valid_until")
+	}
+
+	if !proto.Equal(old.RateLimit, channel.RateLimit) {
+		fields = append(fields, "rate_limit")
+	}
+
+	if !proto.Equal(old.Quota, channel.Quota) {
+		fields = append(fields, "quota")
+	}
+
+	if old.OnThrottle != channel.OnThrottle {
+		fields = append(fields, "on_throttle")
+	}
+
+	if old.MaxQueueDepth != channel.MaxQueueDepth {
+		fields = append(fields, "max_queue_depth")
+	}
+
+	return
+}
+
+// keepCreated copies the creation time of the stored named tokens to
+// the named tokens of the document which omit it and have the same hash.
+func keepCreated(old *protoed.Channel, channel *protoed.Channel) {
+	for _, token := range channel.Tokens {
+		if token.Created != 0 {
+			continue
+		}
+
+		for _, oldToken := range old.Tokens {
+			if oldToken.Name == token.Name &&
+				proto.Equal(oldToken.Hash, token.Hash) {
+				token.Created = oldToken.Created
+			}
+		}
+	}
+}
+
+func tokensEqual(a []*protoed.ChannelToken, b []*protoed.ChannelToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func entitiesEqual(a []*protoed.Entity, b []*protoed.Entity) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,363 @@
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/dbc"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+const dbAuditName = "audit"
+
+// auditKey encodes the key of an audit record as the big-endian time in
+// nanoseconds so that the records of all the channels are ordered by time.
+func auditKey(nanos int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(nanos))
+	return key
+}
+
+// Actor identifies the client which changed a channel.
+type Actor struct {
+	// Name is the self-declared identity of the client.
+	Name string
+
+	// RemoteAddr is the network address of the client; empty if the change
+	// has been made locally.
+	RemoteAddr string
+
+	// ForwardedFor is the X-Forwarded-For header of the request.
+	ForwardedFor string
+
+	// UserAgent is the User-Agent header of the request.
+	UserAgent string
+}
+
+// redactChannel copies the channel without the tokens and their hashes.
+func redactChannel(channel *protoed.Channel) *protoed.Channel {
+	if channel == nil {
+		return nil
+	}
+
+	redacted := proto.Clone(channel).(*protoed.Channel)
+	redacted.Token = ""
+	redacted.TokenHash = nil
+	for _, token := range redacted.Tokens {
+		token.Hash = nil
+	}
+	return redacted
+}
+
+// NewAuditRecord creates the audit record of a change of a channel made by
+// the actor at the given time. The tokens of the channels are redacted.
+//
+// NewAuditRecord requires:
+// * before != nil || after != nil
+// * operation != protoed.AuditRecord_UNKNOWN
+// * (operation == protoed.AuditRecord_DELETE) == (after == nil)
+// * before == nil || after == nil || before.Descriptor_ == after.Descriptor_
+//
+// NewAuditRecord ensures:
+// * record.Before == nil || (record.Before.Token == "" && record.Before.TokenHash == nil)
+// * record.After == nil || (record.After.Token == "" && record.After.TokenHash == nil)
+func NewAuditRecord(operation protoed.AuditRecord_Operation,
+	before *protoed.Channel, after *protoed.Channel, actor Actor,
+	now time.Time) (record *protoed.AuditRecord) {
+	// Pre-conditions
+	switch {
+	case !(before != nil || after != nil):
+		panic("Violated: before != nil || after != nil")
+	case !(operation != protoed.AuditRecord_UNKNOWN):
+		panic("Violated: operation != protoed.AuditRecord_UNKNOWN")
+	case !((operation == protoed.AuditRecord_DELETE) == (after == nil)):
+		panic("Violated: (operation == protoed.AuditRecord_DELETE) == (after == nil)")
+	case !(before == nil || after == nil || before.Descriptor_ == after.Descriptor_):
+		panic("Violated: before == nil || after == nil || before.Descriptor_ == after.Descriptor_")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(record.Before == nil || (record.Before.Token == "" && record.Before.TokenHash == nil)):
+			panic("Violated: record.Before == nil || (record.Before.Token == \"\" && record.Before.TokenHash == nil)")
+		case !(record.After == nil || (record.After.Token == "" && record.After.TokenHash == nil)):
+			panic("Violated: record.After == nil || (record.After.Token == \"\" && record.After.TokenHash == nil)")
+		default:
+			// Pass
+		}
+	}()
+
+	descriptor := ""
+	if after != nil {
+		descriptor = after.Descriptor_
+	} else {
+		descriptor = before.Descriptor_
+	}
+
+	tokenChanged := before != nil && after != nil &&
+		(before.Token != after.Token ||
+			!proto.Equal(before.TokenHash, after.TokenHash) ||
+			!tokenHashesEqual(before.Tokens, after.Tokens))
+
+	record = &protoed.AuditRecord{
+		Time:         now.UnixNano(),
+		Descriptor_:  descriptor,
+		Operation:    operation,
+		Actor:        actor.Name,
+		RemoteAddr:   actor.RemoteAddr,
+		ForwardedFor: actor.ForwardedFor,
+		UserAgent:    actor.UserAgent,
+		Before:       redactChannel(before),
+		After:        redactChannel(after),
+		TokenChanged: tokenChanged}
+	return
+}
+
+// tokenHashesEqual indicates that the named tokens have the same names and
+// hashes in the same order.
+func tokenHashesEqual(a []*protoed.ChannelToken,
+	b []*protoed.ChannelToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Name != b[i].Name || !proto.Equal(a[i].Hash, b[i].Hash) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PutAuditRecord appends the record of a change to the audit log.
+// The audit log is append-only; the records are never removed.
+//
+// If a record already exists at the very same time, the time of the record
+// is shifted by nanoseconds until it is unique.
+//
+// PutAuditRecord requires:
+// * t.access == ControlAccess
+// * record != nil
+// * record.Descriptor_ != ""
+// * record.Time > 0
+// * record.Operation != protoed.AuditRecord_UNKNOWN
+//
+// PutAuditRecord preamble:
+//  oldCount := uint64(0)
+//  if dbc.InTest {
+//  	oldCount = t.mustCountAu()
+//  }
+//
+// PutAuditRecord ensures:
+// * !dbc.InTest || err != nil || t.mustCountAu() == oldCount+1
+func (t *Txn) PutAuditRecord(record *protoed.AuditRecord) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(record != nil):
+		panic("Violated: record != nil")
+	case !(record.Descriptor_ != ""):
+		panic("Violated: record.Descriptor_ != \"\"")
+	case !(record.Time > 0):
+		panic("Violated: record.Time > 0")
+	case !(record.Operation != protoed.AuditRecord_UNKNOWN):
+		panic("Violated: record.Operation != protoed.AuditRecord_UNKNOWN")
+	default:
+		// Pass
+	}
+
+	// Preamble starts.
+	oldCount := uint64(0)
+	if dbc.InTest {
+		oldCount = t.mustCountAu()
+	}
+	// Preamble ends.
+
+	// Post-condition
+	defer func() {
+		if !(!dbc.InTest || err != nil || t.mustCountAu() == oldCount+1) {
+			panic("Violated: !dbc.InTest || err != nil || t.mustCountAu() == oldCount+1")
+		}
+	}()
+
+	key := auditKey(record.Time)
+	for {
+		var existing []byte
+		existing, err = t.kv.get(auditBucket, key)
+		if err != nil {
+			err = fmt.Errorf("failed to check for an existing audit "+
+				"record: %s", err.Error())
+			return
+		}
+
+		if existing == nil {
+			break
+		}
+
+		record.Time++
+		key = auditKey(record.Time)
+	}
+
+	serialized, err := proto.Marshal(record)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the audit record: %s",
+			err.Error())
+		return
+	}
+
+	err = t.kv.put(auditBucket, key, serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the audit record: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// AuditFilter narrows down the listed audit records.
+// Zero fields match all the records.
+type AuditFilter struct {
+	// Descriptor matches the records of the channel.
+	Descriptor string
+
+	// Since matches the records at or after the time.
+	Since time.Time
+
+	// Until matches the records before the time.
+	Until time.Time
+}
+
+// AuditRecords returns the audit records matching the filter ordered by
+// time. If after is positive, only the records after the time in
+// nanoseconds are returned so that the time of the last record of a page
+// serves as the cursor to the following page.
+//
+// At most limit records are returned; more indicates that further records
+// match the filter.
+//
+// AuditRecords requires:
+// * t.access == ControlAccess
+// * limit > 0
+// * filter.Since.IsZero() || filter.Until.IsZero() || !filter.Until.Before(filter.Since)
+//
+// AuditRecords ensures:
+// * err != nil || uint(len(records)) <= limit
+// * err != nil || !more || uint(len(records)) == limit
+func (t *Txn) AuditRecords(filter AuditFilter, after int64,
+	limit uint) (records []*protoed.AuditRecord, more bool, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(limit > 0):
+		panic("Violated: limit > 0")
+	case !(filter.Since.IsZero() || filter.Until.IsZero() || !filter.Until.Before(filter.Since)):
+		panic("Violated: filter.Since.IsZero() || filter.Until.IsZero() || !filter.Until.Before(filter.Since)")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || uint(len(records)) <= limit):
+			panic("Violated: err != nil || uint(len(records)) <= limit")
+		case !(err != nil || !more || uint(len(records)) == limit):
+			panic("Violated: err != nil || !more || uint(len(records)) == limit")
+		default:
+			// Pass
+		}
+	}()
+
+	start := int64(0)
+	if !filter.Since.IsZero() && filter.Since.UnixNano() > 0 {
+		start = filter.Since.UnixNano()
+	}
+	if after > 0 && after+1 > start {
+		start = after + 1
+	}
+
+	err = t.kv.seek(auditBucket, auditKey(start),
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if len(key) != 8 {
+				seekErr = fmt.Errorf("invalid key of an audit record: %q",
+					key)
+				return
+			}
+
+			nanos := int64(binary.BigEndian.Uint64(key))
+			if !filter.Until.IsZero() && nanos >= filter.Until.UnixNano() {
+				stop = true
+				return
+			}
+
+			record := &protoed.AuditRecord{}
+			seekErr = proto.Unmarshal(val, record)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the audit "+
+					"record: %s", seekErr.Error())
+				return
+			}
+
+			if filter.Descriptor != "" &&
+				record.Descriptor_ != filter.Descriptor {
+				return
+			}
+
+			if uint(len(records)) == limit {
+				more = true
+				stop = true
+				return
+			}
+
+			records = append(records, record)
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the audit log: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// CountAuditRecords returns the total number of records in the audit log.
+//
+// CountAuditRecords requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+func (t *Txn) CountAuditRecords() (count uint64, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess || t.access == RelayAccess) {
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	}
+
+	count, err = t.kv.count(auditBucket)
+	if err != nil {
+		err = fmt.Errorf("failed to count the audit records: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// mustCountAu returns the count of entries in the audit database.
+// In case of error, it panics.
+func (t *Txn) mustCountAu() uint64 {
+
+	count, getErr := t.CountAuditRecords()
+	if getErr != nil {
+		panic(fmt.Sprintf("failed to get the audit records count: %s", getErr.Error()))
+	}
+
+	return count
+}
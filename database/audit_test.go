@@ -0,0 +1,119 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestNewAuditRecord(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	actor := Actor{Name: "ops@composers.com", RemoteAddr: "127.0.0.1:53124"}
+
+	before := &protoed.Channel{Descriptor_: "client-1",
+		TokenHash: DummyTokenHash(), MinPeriod: 1}
+	after := &protoed.Channel{Descriptor_: "client-1",
+		TokenHash: DummyTokenHash(), MinPeriod: 2}
+
+	record := NewAuditRecord(protoed.AuditRecord_PUT, before, after, actor,
+		now)
+
+	switch {
+	case record.Descriptor_ != "client-1":
+		t.Errorf("expected the descriptor client-1, got %s",
+			record.Descriptor_)
+	case record.Time != now.UnixNano():
+		t.Errorf("expected the time %d, got %d", now.UnixNano(), record.Time)
+	case record.Actor != actor.Name || record.RemoteAddr != actor.RemoteAddr:
+		t.Errorf("expected the actor %v, got %s", actor, record.String())
+	case record.TokenChanged:
+		t.Errorf("expected no token change on an equal hash")
+	case record.Before.MinPeriod != 1 || record.After.MinPeriod != 2:
+		t.Errorf("expected the channels before and after, got %s",
+			record.String())
+	case before.TokenHash == nil || after.TokenHash == nil:
+		t.Errorf("expected the given channels to be left intact")
+	default:
+		// pass
+	}
+
+	after.TokenHash.Salt = []byte("another salt")
+	record = NewAuditRecord(protoed.AuditRecord_PUT, before, after, actor,
+		now)
+	if !record.TokenChanged {
+		t.Errorf("expected a token change on a different hash")
+	}
+
+	record = NewAuditRecord(protoed.AuditRecord_DELETE, before, nil, actor,
+		now)
+	if record.Descriptor_ != "client-1" || record.After != nil ||
+		record.TokenChanged {
+		t.Errorf("expected only the channel before the removal, got %s",
+			record.String())
+	}
+}
+
+func TestTxn_AuditRecords(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	start := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for i, descriptor := range []string{"client-1", "client-2",
+			"client-1", "client-1"} {
+			txnErr = txn.PutAuditRecord(NewAuditRecord(
+				protoed.AuditRecord_PUT, nil,
+				&protoed.Channel{Descriptor_: descriptor}, Actor{},
+				start.Add(time.Duration(i)*time.Minute)))
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type testcase struct {
+		filter AuditFilter
+		after  int64
+		limit  uint
+		count  int
+		more   bool
+	}
+
+	testcases := []testcase{
+		{limit: 10, count: 4},
+		{filter: AuditFilter{Descriptor: "client-1"}, limit: 10, count: 3},
+		{filter: AuditFilter{Descriptor: "client-1"}, limit: 2, count: 2,
+			more: true},
+		{filter: AuditFilter{Descriptor: "client-1"},
+			after: start.Add(2 * time.Minute).UnixNano(), limit: 2, count: 1},
+		{filter: AuditFilter{Since: start.Add(time.Minute)}, limit: 10,
+			count: 3},
+		{filter: AuditFilter{Since: start.Add(time.Minute),
+			Until: start.Add(3 * time.Minute)}, limit: 10, count: 2},
+		{filter: AuditFilter{Descriptor: "client-3"}, limit: 10, count: 0},
+	}
+
+	for i, tc := range testcases {
+		err = s.View(func(txn *Txn) (txnErr error) {
+			records, more, txnErr := txn.AuditRecords(tc.filter, tc.after,
+				tc.limit)
+			if txnErr != nil {
+				return
+			}
+
+			if len(records) != tc.count || more != tc.more {
+				t.Errorf("test case %d: expected %d records (more: %v), "+
+					"got %d (more: %v)", i, tc.count, tc.more,
+					len(records), more)
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package database
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Backend enumerates the storage backends of a database directory.
+type Backend string
+
+const (
+	// LMDBBackend stores the database in an LMDB environment.
+	// The backend is only available if the program has been built with cgo.
+	LMDBBackend Backend = "lmdb"
+
+	// BoltBackend stores the database in a single bbolt file.
+	// The backend is implemented in pure Go.
+	BoltBackend Backend = "bolt"
+)
+
+// Backends lists all the storage backends.
+var Backends = []Backend{LMDBBackend, BoltBackend}
+
+// ParseBackend parses the name of a storage backend.
+func ParseBackend(name string) (backend Backend, err error) {
+	for _, b := range Backends {
+		if Backend(name) == b {
+			backend = b
+			return
+		}
+	}
+
+	var names []string
+	for _, b := range Backends {
+		names = append(names, string(b))
+	}
+
+	err = fmt.Errorf("unknown database backend %#v, expected one of: %s",
+		name, strings.Join(names, ", "))
+	return
+}
+
+// DataFile returns the name of the file in the database directory
+// where the backend stores the data.
+func (b Backend) DataFile() string {
+	switch b {
+	case LMDBBackend:
+		return "data.mdb"
+	case BoltBackend:
+		return boltDataFile
+	default:
+		panic(fmt.Sprintf("unhandled backend: %#v", b))
+	}
+}
+
+// Database is a store persisted in a database directory.
+type Database interface {
+	Store
+
+	// SchemaVersion returns the schema version of the database.
+	SchemaVersion() (uint64, error)
+
+	// Upgrade runs all the pending migrations in a single transaction.
+	Upgrade() (from uint64, to uint64, err error)
+
+	// Backup writes a consistent snapshot of the database to the given
+	// empty directory.
+	Backup(dir string) error
+}
+
+// LMDBOptions configures the LMDB environments.
+type LMDBOptions struct {
+	// MapSize is the initial size of the memory map in bytes.
+	MapSize int64
+
+	// MaxMapSize is the size in bytes up to which the memory map is grown
+	// when it is full.
+	MaxMapSize int64
+
+	// MaxDBs is the maximum number of named databases in the environment.
+	MaxDBs int
+
+	// OnGrow, if set, is called after the memory map has been grown.
+	OnGrow func(usage MapUsage)
+}
+
+// minLMDBDBs is the number of the named databases used by the current
+// schema: one per bucket and the meta database.
+const minLMDBDBs = bucketCount + 1
+
+// DefaultLMDBOptions returns the options of the LMDB environments
+// used if not configured otherwise.
+func DefaultLMDBOptions() LMDBOptions {
+	return LMDBOptions{
+		MapSize:    32 * 1024 * 1024 * 1024,
+		MaxMapSize: 128 * 1024 * 1024 * 1024,
+		MaxDBs:     16}
+}
+
+// Validate returns an error if the options are inconsistent.
+func (o LMDBOptions) Validate() (err error) {
+	switch {
+	case o.MapSize <= 0:
+		err = fmt.Errorf("expected a positive map size, got %d", o.MapSize)
+	case o.MaxMapSize < o.MapSize:
+		err = fmt.Errorf("expected the max. map size %d to be at least "+
+			"the map size %d", o.MaxMapSize, o.MapSize)
+	case o.MaxDBs < minLMDBDBs:
+		err = fmt.Errorf("expected the max. number of DBs to be at "+
+			"least %d, got %d", minLMDBDBs, o.MaxDBs)
+	default:
+		// pass
+	}
+	return
+}
+
+// MapUsage describes how much of the memory map of an LMDB environment
+// is used.
+type MapUsage struct {
+	// Used is the size of the used pages in bytes.
+	Used int64
+
+	// Size is the size of the memory map in bytes.
+	Size int64
+}
+
+// Ratio returns the used fraction of the memory map.
+func (u MapUsage) Ratio() float64 {
+	if u.Size == 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Size)
+}
+
+// String represents the usage in a human-readable form.
+func (u MapUsage) String() string {
+	const mib = 1024 * 1024
+	return fmt.Sprintf("%d MiB of %d MiB used (%.1f%%)",
+		u.Used/mib, u.Size/mib, 100*u.Ratio())
+}
+
+// MapUsageReporter is implemented by the databases stored in a memory map.
+type MapUsageReporter interface {
+	// MapUsage returns the used and the available size of the memory map.
+	MapUsage() (MapUsage, error)
+}
+
+// Open opens the database directory initialized with the given backend.
+// The LMDB options are ignored by the other backends.
+func Open(backend Backend, access Access, path string,
+	lmdbOptions LMDBOptions) (db Database, err error) {
+	switch backend {
+	case LMDBBackend:
+		return openLMDB(access, path, lmdbOptions)
+	case BoltBackend:
+		var b *BoltStore
+		b, err = OpenBolt(access, path)
+		if err != nil {
+			return
+		}
+
+		db = b
+		return
+	default:
+		err = fmt.Errorf("unknown database backend: %#v", backend)
+		return
+	}
+}
+
+// OpenReadOnly opens the database directory initialized with the given
+// backend so that the database can only be read. The read-write
+// transactions fail. The LMDB options are ignored by the other backends.
+func OpenReadOnly(backend Backend, access Access, path string,
+	lmdbOptions LMDBOptions) (db Database, err error) {
+	switch backend {
+	case LMDBBackend:
+		return openLMDBReadOnly(access, path, lmdbOptions)
+	case BoltBackend:
+		var b *BoltStore
+		b, err = OpenBolt(access, path)
+		if err != nil {
+			return
+		}
+
+		b.ReadOnly = true
+		db = b
+		return
+	default:
+		err = fmt.Errorf("unknown database backend: %#v", backend)
+		return
+	}
+}
+
+// InitializeBackend initializes the database directory with the given
+// backend. See Initialize and InitializeBolt for details.
+// The LMDB options are ignored by the other backends.
+func InitializeBackend(backend Backend, access Access, path string,
+	lmdbOptions LMDBOptions) error {
+	switch backend {
+	case LMDBBackend:
+		return initializeLMDB(access, path, lmdbOptions)
+	case BoltBackend:
+		return InitializeBolt(access, path)
+	default:
+		return fmt.Errorf("unknown database backend: %#v", backend)
+	}
+}
+
+// RestoreBackend restores the snapshot taken with the given backend into
+// the empty database directory. See Restore and RestoreBolt for details.
+func RestoreBackend(backend Backend, snapshotDir string,
+	path string) (version uint64, err error) {
+	switch backend {
+	case LMDBBackend:
+		return restoreLMDB(snapshotDir, path)
+	case BoltBackend:
+		return RestoreBolt(snapshotDir, path)
+	default:
+		err = fmt.Errorf("unknown database backend: %#v", backend)
+		return
+	}
+}
+
+// Convert copies the database from one backend into a new database of
+// another backend in the given empty directory.
+//
+// The channels, the timestamps, the relay log and the audit log are copied
+// as they are.
+// The source database must be at the current SchemaVersion; older
+// databases need to be upgraded before the conversion.
+func Convert(from Backend, fromPath string, to Backend, toPath string,
+	lmdbOptions LMDBOptions) (err error) {
+	err = ensureEmptyDir(toPath)
+	if err != nil {
+		return
+	}
+
+	src, err := Open(from, ControlAccess, fromPath, lmdbOptions)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := src.Close()
+		if closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("%s; failed to close the database %s: %s",
+					err.Error(), fromPath, closeErr.Error())
+			}
+		}
+	}()
+
+	err = src.CheckSchemaVersion()
+	if err != nil {
+		return
+	}
+
+	err = InitializeBackend(to, ControlAccess, toPath, lmdbOptions)
+	if err != nil {
+		return
+	}
+
+	dst, err := Open(to, ControlAccess, toPath, lmdbOptions)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := dst.Close()
+		if closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("%s; failed to close the database %s: %s",
+					err.Error(), toPath, closeErr.Error())
+			}
+		}
+	}()
+
+	err = src.View(func(srcTxn *Txn) error {
+		return dst.Update(func(dstTxn *Txn) (txnErr error) {
+			for b := bucket(0); b < bucketCount; b++ {
+				txnErr = srcTxn.kv.seek(b, nil,
+					func(key []byte, value []byte) (bool, error) {
+						return false, dstTxn.kv.put(b, key, value)
+					})
+				if txnErr != nil {
+					txnErr = fmt.Errorf("failed to copy the %s: %s",
+						bucketNames[b], txnErr.Error())
+					return
+				}
+			}
+			return
+		})
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to convert the database %s to %s: %s",
+			fromPath, toPath, err.Error())
+		return
+	}
+
+	return
+}
+
+// ensureEmptyDir returns an error if the path is not an existing empty
+// directory.
+func ensureEmptyDir(path string) (err error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		err = fmt.Errorf("the directory %s is expected to exist: %s",
+			path, err.Error())
+		return
+	}
+
+	if len(entries) > 0 {
+		err = fmt.Errorf("the directory %s is expected to be empty, "+
+			"but it contains %d entries", path, len(entries))
+		return
+	}
+
+	return
+}
@@ -0,0 +1,231 @@
+// +build cgo
+
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestParseBackend(t *testing.T) {
+	for _, backend := range Backends {
+		got, err := ParseBackend(string(backend))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if got != backend {
+			t.Errorf("expected the backend %s, got %s", backend, got)
+		}
+	}
+
+	_, err := ParseBackend("leveldb")
+	if err == nil {
+		t.Errorf("expected an error on an unknown backend")
+	}
+}
+
+// dumpStore reads all the channels, timestamps, relay and audit records of
+// the store.
+func dumpStore(s Store) (dump map[string][]string, err error) {
+	dump = make(map[string][]string)
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		for b := bucket(0); b < bucketCount; b++ {
+			txnErr = txn.kv.seek(b, nil,
+				func(key []byte, value []byte) (bool, error) {
+					dump[bucketNames[b]] = append(dump[bucketNames[b]],
+						string(key)+"="+string(value))
+					return false, nil
+				})
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	return
+}
+
+func TestConvert(t *testing.T) {
+	d, err := emptyDatabase(RelayAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	d.Access = ControlAccess
+	err = d.Update(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range []string{"client-1", "client-2"} {
+			txnErr = txn.PutChannel(&protoed.Channel{Descriptor_: descriptor,
+				TokenHash: DummyTokenHash(),
+				Sender:    &protoed.Entity{Email: "johann.bach@composers.com"},
+				Domain:    "composers.com", MinPeriod: 0.1, MaxSize: 10000000})
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	d.Access = RelayAccess
+	err = d.Update(func(txn *Txn) (txnErr error) {
+		ts := TimestampFromTime(now)
+		txnErr = txn.PutTimestamp("client-1", &ts)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.PutRelayRecord(&protoed.RelayRecord{
+			Descriptor_: "client-1", Time: now.UnixNano(),
+			Outcome: protoed.RelayRecord_RELAYED, Status: 200})
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = d.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dirs := make(map[string]string)
+	for _, name := range []string{"bolt", "lmdb"} {
+		dirs[name], err = ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer func(dir string) {
+			err = os.RemoveAll(dir)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}(dirs[name])
+	}
+
+	err = Convert(LMDBBackend, d.Path, BoltBackend, dirs["bolt"],
+		DefaultLMDBOptions())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = Convert(BoltBackend, dirs["bolt"], LMDBBackend, dirs["lmdb"],
+		DefaultLMDBOptions())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = Convert(LMDBBackend, d.Path, BoltBackend, dirs["bolt"],
+		DefaultLMDBOptions())
+	if err == nil {
+		t.Fatalf("expected an error on a conversion into a non-empty " +
+			"directory")
+	}
+
+	var dumps []map[string][]string
+	for _, source := range []struct {
+		backend Backend
+		path    string
+	}{
+		{LMDBBackend, d.Path},
+		{BoltBackend, dirs["bolt"]},
+		{LMDBBackend, dirs["lmdb"]},
+	} {
+		var db Database
+		db, err = Open(source.backend, ControlAccess, source.path,
+			DefaultLMDBOptions())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var dump map[string][]string
+		dump, err = dumpStore(db)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		dumps = append(dumps, dump)
+	}
+
+	if len(dumps[0][dbChannelName]) != 2 ||
+		len(dumps[0][dbTimestampName]) != 1 ||
+		len(dumps[0][dbRelayLogName]) != 1 {
+		t.Fatalf("expected 2 channels, 1 timestamp and 1 relay record, "+
+			"got %v", dumps[0])
+	}
+
+	for i := 1; i < len(dumps); i++ {
+		if !reflect.DeepEqual(dumps[0], dumps[i]) {
+			t.Errorf("expected the converted database %d to equal "+
+				"the original, got %v instead of %v", i, dumps[i], dumps[0])
+		}
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	for _, backend := range Backends {
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer func(dir string) {
+			err = os.RemoveAll(dir)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}(dir)
+
+		err = InitializeBackend(backend, ControlAccess, dir,
+			DefaultLMDBOptions())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		db, err := OpenReadOnly(backend, ControlAccess, dir,
+			DefaultLMDBOptions())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		err = db.CheckSchemaVersion()
+		if err != nil {
+			t.Errorf("%s: expected the schema version to be readable, "+
+				"got: %s", backend, err.Error())
+		}
+
+		err = db.Update(func(txn *Txn) error {
+			return txn.PutChannel(&protoed.Channel{
+				Descriptor_: "client-1", TokenHash: DummyTokenHash()})
+		})
+		if err == nil {
+			t.Errorf("%s: expected an error on a read-write transaction",
+				backend)
+		}
+
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// +build cgo
+
+package database
+
+import (
+	"fmt"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+)
+
+// Backup writes a consistent and compacted snapshot of the database
+// to the given empty directory.
+//
+// The snapshot is taken within a read-only transaction so that both
+// the control and the relay server can keep on running.
+func (e *Env) Backup(dir string) (err error) {
+	err = ensureEmptyDir(dir)
+	if err != nil {
+		return
+	}
+
+	e.resizeMu.RLock()
+	err = e.env.CopyFlag(dir, lmdb.CopyCompact)
+	e.resizeMu.RUnlock()
+	if err != nil {
+		err = fmt.Errorf("failed to copy the database %s to %s: %s",
+			e.Path, dir, err.Error())
+		return
+	}
+
+	return
+}
+
+// Restore copies the snapshot taken by Backup into the given empty
+// directory and returns the schema version of the snapshot.
+//
+// The snapshot is checked before anything is written: it must contain
+// the channel and timestamp databases and its schema version must not be
+// newer than SchemaVersion. Snapshots of older schema versions need to be
+// upgraded after the restore.
+//
+// Restore ensures:
+// * err != nil || version <= SchemaVersion
+func Restore(snapshotDir string, path string) (version uint64, err error) {
+	// Post-condition
+	defer func() {
+		if !(err != nil || version <= SchemaVersion) {
+			panic("Violated: err != nil || version <= SchemaVersion")
+		}
+	}()
+
+	err = ensureEmptyDir(path)
+	if err != nil {
+		return
+	}
+
+	// The snapshot is opened without a lock file so that it is not modified.
+	snapshot, err := openEnv(ControlAccess, snapshotDir,
+		lmdb.Readonly|lmdb.NoLock, DefaultLMDBOptions())
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := snapshot.Close()
+		if closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("%s; failed to close the snapshot: %s",
+					err.Error(), closeErr.Error())
+			}
+		}
+	}()
+
+	version, err = snapshot.SchemaVersion()
+	if err != nil {
+		err = fmt.Errorf("failed to read the schema version of the "+
+			"snapshot %s: %s", snapshotDir, err.Error())
+		return
+	}
+
+	if version > SchemaVersion {
+		err = fmt.Errorf("the snapshot %s has the schema version %d "+
+			"which is newer than the supported version %d",
+			snapshotDir, version, SchemaVersion)
+		return
+	}
+
+	err = snapshot.View(func(txn *Txn) (txnErr error) {
+		_, txnErr = txn.CountChannels()
+		if txnErr != nil {
+			return
+		}
+
+		_, txnErr = txn.CountTimestamps()
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("the snapshot %s is not a valid channel "+
+			"database: %s", snapshotDir, err.Error())
+		return
+	}
+
+	err = snapshot.env.CopyFlag(path, lmdb.CopyCompact)
+	if err != nil {
+		err = fmt.Errorf("failed to copy the snapshot %s to %s: %s",
+			snapshotDir, path, err.Error())
+		return
+	}
+
+	return
+}
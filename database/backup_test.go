@@ -0,0 +1,210 @@
+// +build cgo
+
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestEnv_BackupRestore(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	sender := protoed.Entity{Name: "Johann Sebastian Bach",
+		Email: "johann.bach@composers.com"}
+	recipients := []*protoed.Entity{
+		{Name: "CPE Bach", Email: "cpe.bach@composers.com"}}
+
+	var channels []*protoed.Channel
+	err = d.Update(func(txn *Txn) (txnerr error) {
+		for i := 0; i < 10; i++ {
+			channel := &protoed.Channel{
+				Descriptor_: "some-channel" + strconv.Itoa(i),
+				TokenHash:   DummyTokenHash(),
+				Sender:      &sender, Recipients: recipients,
+				MinPeriod: 0.0001, MaxSize: 10000000}
+			channels = append(channels, channel)
+
+			txnerr = txn.PutChannel(channel)
+			if txnerr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tmpdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(tmpdir)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	backupDir := filepath.Join(tmpdir, "backup")
+	restoredDir := filepath.Join(tmpdir, "restored")
+	for _, dir := range []string{backupDir, restoredDir} {
+		err = os.Mkdir(dir, 0700)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	// back up while another transaction is open
+	err = d.View(func(txn *Txn) error {
+		return d.Backup(backupDir)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the backup directory is not empty anymore
+	err = d.Backup(backupDir)
+	if err == nil {
+		t.Fatalf("expected an error when backing up into " +
+			"a non-empty directory")
+	}
+
+	version, err := Restore(backupDir, restoredDir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if version != SchemaVersion {
+		t.Fatalf("expected the schema version %d, got %d",
+			SchemaVersion, version)
+	}
+
+	restored, err := NewEnv(ControlAccess, restoredDir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = restored.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	err = restored.CheckSchemaVersion()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = restored.View(func(txn *Txn) (txnerr error) {
+		for _, expected := range channels {
+			var got *protoed.Channel
+			got, txnerr = txn.GetChannel(expected.Descriptor_)
+			if txnerr != nil {
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected the channel %s in the restored "+
+					"database", expected.Descriptor_)
+			}
+			CompareChannels(expected, got, t)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the restored directory is not empty anymore
+	_, err = Restore(backupDir, restoredDir)
+	if err == nil {
+		t.Fatalf("expected an error when restoring into " +
+			"a non-empty directory")
+	}
+}
+
+func TestRestore_Newer(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	err = d.env.Update(func(txn *lmdb.Txn) error {
+		return writeSchemaVersion(txn, SchemaVersion+1)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tmpdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(tmpdir)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	backupDir := filepath.Join(tmpdir, "backup")
+	restoredDir := filepath.Join(tmpdir, "restored")
+	for _, dir := range []string{backupDir, restoredDir} {
+		err = os.Mkdir(dir, 0700)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	err = d.Backup(backupDir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = Restore(backupDir, restoredDir)
+	if err == nil {
+		t.Fatalf("expected an error when restoring a snapshot " +
+			"of a newer schema version")
+	}
+
+	err = ensureEmptyDir(restoredDir)
+	if err != nil {
+		t.Fatalf("expected the target directory to remain empty: %s",
+			err.Error())
+	}
+}
@@ -0,0 +1,448 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltDataFile is the name of the bbolt file in the database directory.
+const boltDataFile = "data.bolt"
+
+// boltLockTimeout is the maximum time a transaction waits for the lock on
+// the bbolt file held by another process.
+const boltLockTimeout = 10 * time.Second
+
+// BoltStore represents a database of channels stored in a bbolt file.
+//
+// bbolt locks the file as long as it is open. To allow the control and
+// the relay server to share the database, the file is opened only for
+// the duration of each transaction. Concurrent read-only transactions
+// share the lock while a read-write transaction waits until it can lock
+// the file exclusively.
+type BoltStore struct {
+	// Path is the database directory.
+	Path string
+
+	// Access defines the access rights of the transactions.
+	Access Access
+
+	// ReadOnly indicates that the bbolt file is opened read-only even for
+	// the read-write transactions so that they fail.
+	ReadOnly bool
+}
+
+// OpenBolt creates a new bbolt store object.
+// The database directory is assumed to be already initialized.
+func OpenBolt(access Access, path string) (b *BoltStore, err error) {
+	file := filepath.Join(path, boltDataFile)
+	_, err = os.Stat(file)
+	if err != nil {
+		err = fmt.Errorf("failed to open the database in the "+
+			"directory %s: %s", path, err.Error())
+		return
+	}
+
+	b = &BoltStore{Path: path, Access: access}
+	return
+}
+
+// InitializeBolt initializes the bbolt file in the given directory.
+// InitializeBolt creates the expected buckets and should be called only once
+// during the deployment.
+//
+// The database is stamped with the current SchemaVersion.
+func InitializeBolt(access Access, path string) (err error) {
+	_, err = os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("the directory of the bbolt database "+
+			"is expected to exist: %s", err.Error())
+		return
+	}
+
+	b := &BoltStore{Path: path, Access: access}
+	err = b.withDB(false, func(db *bolt.DB) error {
+		return db.Update(func(tx *bolt.Tx) (txErr error) {
+			for _, name := range bucketNames {
+				_, txErr = tx.CreateBucketIfNotExists([]byte(name))
+				if txErr != nil {
+					return
+				}
+			}
+
+			txErr = writeBoltSchemaVersion(tx, SchemaVersion)
+			return
+		})
+	})
+
+	return
+}
+
+// withDB opens the bbolt file, calls fn on it and closes the file.
+func (b *BoltStore) withDB(readOnly bool, fn func(db *bolt.DB) error) (
+	err error) {
+	file := filepath.Join(b.Path, boltDataFile)
+
+	db, err := bolt.Open(file, 0660, &bolt.Options{
+		Timeout:  boltLockTimeout,
+		ReadOnly: readOnly || b.ReadOnly})
+	if err != nil {
+		err = fmt.Errorf("failed to open the database %s: %s",
+			file, err.Error())
+		return
+	}
+	defer func() {
+		closeErr := db.Close()
+		if closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("%s; failed to close the database: %s",
+					err.Error(), closeErr.Error())
+			}
+		}
+	}()
+
+	err = fn(db)
+	return
+}
+
+// newBoltTxn wraps the bbolt transaction and looks up the buckets.
+func (b *BoltStore) newBoltTxn(tx *bolt.Tx) *Txn {
+	kv := &boltKV{tx: tx}
+	for i, name := range bucketNames {
+		kv.buckets[i] = tx.Bucket([]byte(name))
+	}
+
+	return &Txn{kv: kv, access: b.Access}
+}
+
+// Update executes a read-write transaction.
+func (b *BoltStore) Update(fn func(txn *Txn) error) error {
+	return b.withDB(false, func(db *bolt.DB) error {
+		return db.Update(func(tx *bolt.Tx) error {
+			return fn(b.newBoltTxn(tx))
+		})
+	})
+}
+
+// View executes a read-only transaction.
+func (b *BoltStore) View(fn func(txn *Txn) error) error {
+	return b.withDB(true, func(db *bolt.DB) error {
+		return db.View(func(tx *bolt.Tx) error {
+			return fn(b.newBoltTxn(tx))
+		})
+	})
+}
+
+// Close releases the store. The bbolt file is already closed after each
+// transaction so that there is nothing left to release.
+func (b *BoltStore) Close() error {
+	return nil
+}
+
+// readBoltSchemaVersion reads the schema version from the meta bucket.
+func readBoltSchemaVersion(tx *bolt.Tx) (version uint64, err error) {
+	meta := tx.Bucket([]byte(dbMetaName))
+	if meta == nil {
+		err = fmt.Errorf("the meta bucket does not exist")
+		return
+	}
+
+	value := meta.Get(schemaVersionKey)
+	if value == nil {
+		err = fmt.Errorf("the schema version does not exist")
+		return
+	}
+
+	version, err = decodeSchemaVersion(value)
+	return
+}
+
+// writeBoltSchemaVersion stores the schema version in the meta bucket,
+// creating the meta bucket if necessary.
+func writeBoltSchemaVersion(tx *bolt.Tx, version uint64) (err error) {
+	meta, err := tx.CreateBucketIfNotExists([]byte(dbMetaName))
+	if err != nil {
+		err = fmt.Errorf("failed to create the meta bucket: %s",
+			err.Error())
+		return
+	}
+
+	err = meta.Put(schemaVersionKey, encodeSchemaVersion(version))
+	if err != nil {
+		err = fmt.Errorf("failed to put the schema version: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// SchemaVersion returns the schema version of the database.
+func (b *BoltStore) SchemaVersion() (version uint64, err error) {
+	err = b.withDB(true, func(db *bolt.DB) error {
+		return db.View(func(tx *bolt.Tx) (txErr error) {
+			version, txErr = readBoltSchemaVersion(tx)
+			return
+		})
+	})
+	return
+}
+
+// CheckSchemaVersion returns an error if the schema version of the database
+// differs from SchemaVersion.
+func (b *BoltStore) CheckSchemaVersion() (err error) {
+	version, err := b.SchemaVersion()
+	if err != nil {
+		return
+	}
+
+	err = schemaVersionError(b.Path, version)
+	return
+}
+
+// Upgrade runs all the pending migrations in a single transaction.
+// If any migration fails, the database is left unchanged.
+//
+// Upgrade requires:
+// * b.Access == ControlAccess
+//
+// Upgrade ensures:
+// * err != nil || to == SchemaVersion
+// * err != nil || from <= to
+func (b *BoltStore) Upgrade() (from uint64, to uint64, err error) {
+	// Pre-condition
+	if !(b.Access == ControlAccess) {
+		panic("Violated: b.Access == ControlAccess")
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || to == SchemaVersion):
+			panic("Violated: err != nil || to == SchemaVersion")
+		case !(err != nil || from <= to):
+			panic("Violated: err != nil || from <= to")
+		default:
+			// Pass
+		}
+	}()
+
+	err = b.withDB(false, func(db *bolt.DB) error {
+		return db.Update(func(tx *bolt.Tx) (txErr error) {
+			from, txErr = readBoltSchemaVersion(tx)
+			if txErr != nil {
+				return
+			}
+
+			txErr = migrate(b.newBoltTxn(tx), from)
+			if txErr != nil {
+				return
+			}
+
+			txErr = writeBoltSchemaVersion(tx, SchemaVersion)
+			return
+		})
+	})
+	if err != nil {
+		return
+	}
+
+	to = SchemaVersion
+	return
+}
+
+// Backup writes a consistent snapshot of the database to the given empty
+// directory.
+//
+// The snapshot is taken within a read-only transaction so that both
+// the control and the relay server can keep on running.
+func (b *BoltStore) Backup(dir string) (err error) {
+	err = ensureEmptyDir(dir)
+	if err != nil {
+		return
+	}
+
+	err = b.withDB(true, func(db *bolt.DB) error {
+		return db.View(func(tx *bolt.Tx) error {
+			return tx.CopyFile(filepath.Join(dir, boltDataFile), 0660)
+		})
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to copy the database %s to %s: %s",
+			b.Path, dir, err.Error())
+		return
+	}
+
+	return
+}
+
+// RestoreBolt copies the snapshot taken by BoltStore.Backup into the given
+// empty directory and returns the schema version of the snapshot.
+//
+// The snapshot is checked before anything is written: it must contain
+// the channel and timestamp buckets and its schema version must not be
+// newer than SchemaVersion.
+//
+// RestoreBolt ensures:
+// * err != nil || version <= SchemaVersion
+func RestoreBolt(snapshotDir string, path string) (version uint64, err error) {
+	// Post-condition
+	defer func() {
+		if !(err != nil || version <= SchemaVersion) {
+			panic("Violated: err != nil || version <= SchemaVersion")
+		}
+	}()
+
+	err = ensureEmptyDir(path)
+	if err != nil {
+		return
+	}
+
+	snapshot, err := OpenBolt(ControlAccess, snapshotDir)
+	if err != nil {
+		return
+	}
+
+	err = snapshot.withDB(true, func(db *bolt.DB) error {
+		return db.View(func(tx *bolt.Tx) (txErr error) {
+			version, txErr = readBoltSchemaVersion(tx)
+			if txErr != nil {
+				txErr = fmt.Errorf("failed to read the schema version of "+
+					"the snapshot %s: %s", snapshotDir, txErr.Error())
+				return
+			}
+
+			if version > SchemaVersion {
+				txErr = fmt.Errorf("the snapshot %s has the schema version "+
+					"%d which is newer than the supported version %d",
+					snapshotDir, version, SchemaVersion)
+				return
+			}
+
+			txn := snapshot.newBoltTxn(tx)
+			_, txErr = txn.CountChannels()
+			if txErr == nil {
+				_, txErr = txn.CountTimestamps()
+			}
+			if txErr != nil {
+				txErr = fmt.Errorf("the snapshot %s is not a valid channel "+
+					"database: %s", snapshotDir, txErr.Error())
+				return
+			}
+
+			txErr = tx.CopyFile(filepath.Join(path, boltDataFile), 0660)
+			if txErr != nil {
+				txErr = fmt.Errorf("failed to copy the snapshot %s to %s: %s",
+					snapshotDir, path, txErr.Error())
+				return
+			}
+
+			return
+		})
+	})
+	if err != nil {
+		version = 0
+		return
+	}
+
+	return
+}
+
+// boltKV implements the key-value transaction on a bbolt transaction.
+type boltKV struct {
+	tx      *bolt.Tx
+	buckets [bucketCount]*bolt.Bucket
+}
+
+// bucket returns the bbolt bucket or an error if it does not exist.
+func (kv *boltKV) bucket(b bucket) (bkt *bolt.Bucket, err error) {
+	bkt = kv.buckets[b]
+	if bkt == nil {
+		err = fmt.Errorf("the bucket %s does not exist", bucketNames[b])
+	}
+	return
+}
+
+// create creates the bbolt bucket if it does not exist.
+func (kv *boltKV) create(b bucket) (err error) {
+	kv.buckets[b], err = kv.tx.CreateBucketIfNotExists([]byte(bucketNames[b]))
+	return
+}
+
+func (kv *boltKV) get(b bucket, key []byte) (value []byte, err error) {
+	bkt, err := kv.bucket(b)
+	if err != nil {
+		return
+	}
+
+	value = bkt.Get(key)
+	return
+}
+
+func (kv *boltKV) put(b bucket, key []byte, value []byte) (err error) {
+	bkt, err := kv.bucket(b)
+	if err != nil {
+		return
+	}
+
+	err = bkt.Put(key, value)
+	return
+}
+
+func (kv *boltKV) remove(b bucket, key []byte) (err error) {
+	bkt, err := kv.bucket(b)
+	if err != nil {
+		return
+	}
+
+	err = bkt.Delete(key)
+	return
+}
+
+func (kv *boltKV) count(b bucket) (count uint64, err error) {
+	bkt, err := kv.bucket(b)
+	if err != nil {
+		return
+	}
+
+	// bbolt does not keep the number of keys. The statistics of a bucket are
+	// computed from the committed pages and miss the changes of the current
+	// transaction so that the keys need to be counted with a cursor.
+	cur := bkt.Cursor()
+	for k, _ := cur.First(); k != nil; k, _ = cur.Next() {
+		count++
+	}
+	return
+}
+
+func (kv *boltKV) seek(b bucket, key []byte,
+	fn func(key []byte, value []byte) (stop bool, err error)) (err error) {
+	bkt, err := kv.bucket(b)
+	if err != nil {
+		return
+	}
+
+	cur := bkt.Cursor()
+
+	var k, v []byte
+	if key == nil {
+		k, v = cur.First()
+	} else {
+		k, v = cur.Seek(key)
+	}
+
+	for ; k != nil; k, v = cur.Next() {
+		var stop bool
+		stop, err = fn(k, v)
+		if err != nil || stop {
+			return
+		}
+	}
+
+	return
+}
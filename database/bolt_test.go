@@ -0,0 +1,191 @@
+package database
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestInitializeBolt_SchemaVersion(t *testing.T) {
+	b, err := emptyBolt(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(b.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	version, err := b.SchemaVersion()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if version != SchemaVersion {
+		t.Fatalf("expected the schema version %d, got %d",
+			SchemaVersion, version)
+	}
+
+	err = b.CheckSchemaVersion()
+	if err != nil {
+		t.Fatalf("expected no error on a new database, got: %s", err.Error())
+	}
+
+	from, to, err := b.Upgrade()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if from != SchemaVersion || to != SchemaVersion {
+		t.Fatalf("expected a no-op upgrade, got from %d to %d", from, to)
+	}
+}
+
+func TestOpenBolt_Uninitialized(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(tmpdir)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	_, err = OpenBolt(ControlAccess, tmpdir)
+	if err == nil {
+		t.Fatalf("expected an error on an uninitialized directory")
+	}
+}
+
+func TestBoltStore_BackupRestore(t *testing.T) {
+	b, err := emptyBolt(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(b.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	channel := &protoed.Channel{Descriptor_: "client-1",
+		TokenHash: DummyTokenHash(),
+		Sender:    &protoed.Entity{Email: "johann.bach@composers.com"},
+		Domain:    "composers.com", MinPeriod: 0.1, MaxSize: 10000000}
+
+	ts := TimestampFromTime(time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC))
+
+	err = b.Update(func(txn *Txn) (txnErr error) {
+		txnErr = txn.PutChannel(channel)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.PutTimestamp("client-1", &ts)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dirs := make(map[string]string)
+	for _, name := range []string{"backup", "restored"} {
+		dirs[name], err = ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer func(dir string) {
+			err = os.RemoveAll(dir)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+		}(dirs[name])
+	}
+
+	err = b.Backup(dirs["backup"])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = b.Backup(dirs["backup"])
+	if err == nil {
+		t.Fatalf("expected an error on a backup into a non-empty directory")
+	}
+
+	version, err := RestoreBolt(dirs["backup"], dirs["restored"])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if version != SchemaVersion {
+		t.Fatalf("expected the schema version %d, got %d",
+			SchemaVersion, version)
+	}
+
+	restored, err := OpenBolt(ControlAccess, dirs["restored"])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = restored.View(func(txn *Txn) (txnErr error) {
+		got, txnErr := txn.GetChannel("client-1")
+		if txnErr != nil {
+			return
+		}
+
+		if got == nil {
+			t.Fatalf("expected the channel to be restored")
+		}
+		CompareChannels(channel, got, t)
+
+		gotTs, txnErr := txn.GetTimestamp("client-1")
+		if txnErr != nil {
+			return
+		}
+
+		if gotTs == nil || *gotTs != ts {
+			t.Errorf("expected the timestamp %d, got %v", ts, gotTs)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+// emptyBolt creates an empty bbolt database.
+func emptyBolt(access Access) (b *BoltStore, err error) {
+	tmpdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		return
+	}
+
+	err = InitializeBolt(access, tmpdir)
+	if err != nil {
+		removeErr := os.RemoveAll(tmpdir)
+		if removeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		}
+		return
+	}
+
+	b, err = OpenBolt(access, tmpdir)
+	if err != nil {
+		removeErr := os.RemoveAll(tmpdir)
+		if removeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		}
+		return
+	}
+
+	return
+}
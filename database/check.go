@@ -0,0 +1,314 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// ProblemKind enumerates the kinds of inconsistencies found in a database.
+type ProblemKind string
+
+const (
+	// UndecodableChannel signals a channel record which can not be
+	// unmarshaled.
+	UndecodableChannel ProblemKind = "undecodable-channel"
+
+	// DescriptorMismatch signals a channel whose descriptor differs from
+	// the key it is stored at.
+	DescriptorMismatch ProblemKind = "descriptor-mismatch"
+
+	// InvalidDescriptor signals a channel stored at a descriptor which is
+	// rejected by ValidateDescriptor.
+	InvalidDescriptor ProblemKind = "invalid-descriptor"
+
+	// MissingToken signals a channel without any token.
+	MissingToken ProblemKind = "missing-token"
+
+	// InvalidChannel signals a channel rejected by the channel validator.
+	InvalidChannel ProblemKind = "invalid-channel"
+
+	// MalformedTimestamp signals a timestamp which can not be decoded.
+	MalformedTimestamp ProblemKind = "malformed-timestamp"
+
+	// OrphanTimestamp signals a timestamp without a channel.
+	OrphanTimestamp ProblemKind = "orphan-timestamp"
+
+	// FutureTimestamp signals a timestamp later than the time of the check.
+	FutureTimestamp ProblemKind = "future-timestamp"
+)
+
+// InvalidReason is the reason given to the channels disabled on repair.
+const InvalidReason = "invalid channel"
+
+// Problem describes an inconsistency found in a database.
+type Problem struct {
+	// Kind is the kind of the inconsistency.
+	Kind ProblemKind
+
+	// Descriptor is the key of the inconsistent record.
+	Descriptor string
+
+	// Detail explains the inconsistency.
+	Detail string
+
+	// Repairable indicates that the inconsistency can be safely repaired.
+	Repairable bool
+}
+
+// String represents the problem as a single line.
+func (p Problem) String() string {
+	repairable := ""
+	if p.Repairable {
+		repairable = " (repairable)"
+	}
+
+	return fmt.Sprintf("%s %q: %s%s", p.Kind, p.Descriptor, p.Detail,
+		repairable)
+}
+
+// ChannelValidator validates a decoded channel.
+type ChannelValidator func(channel *protoed.Channel) error
+
+// Check browses the channels and the timestamps and reports
+// the inconsistencies ordered by the database and the key.
+//
+// If validate is given, every decoded channel is validated with it as well.
+//
+// Check requires:
+// * t.access == ControlAccess
+func (t *Txn) Check(now time.Time, validate ChannelValidator) (
+	problems []Problem, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	err = t.kv.seek(channelBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			descriptor := string(DecodeDescriptor(key))
+
+			channel := &protoed.Channel{}
+			unmarshalErr := proto.Unmarshal(val, channel)
+			if unmarshalErr != nil {
+				problems = append(problems, Problem{
+					Kind: UndecodableChannel, Descriptor: descriptor,
+					Detail: unmarshalErr.Error()})
+				return
+			}
+
+			descriptorErr := ValidateDescriptor(descriptor)
+			if descriptorErr != nil {
+				problems = append(problems, Problem{
+					Kind: InvalidDescriptor, Descriptor: descriptor,
+					Detail: descriptorErr.Error()})
+			}
+
+			if channel.Descriptor_ != descriptor {
+				problems = append(problems, Problem{
+					Kind: DescriptorMismatch, Descriptor: descriptor,
+					Detail: fmt.Sprintf("the channel has the descriptor %q",
+						channel.Descriptor_),
+					Repairable: descriptorErr == nil})
+			}
+
+			if channel.TokenHash == nil && len(channel.Tokens) == 0 {
+				problems = append(problems, Problem{
+					Kind: MissingToken, Descriptor: descriptor,
+					Detail: "the channel has no token"})
+			}
+
+			if validate != nil {
+				validateErr := validate(channel)
+				if validateErr != nil {
+					problems = append(problems, Problem{
+						Kind: InvalidChannel, Descriptor: descriptor,
+						Detail: validateErr.Error(),
+						Repairable: channel.Disabled == nil &&
+							descriptorErr == nil})
+				}
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the channels: %s",
+			err.Error())
+		return
+	}
+
+	err = t.kv.seek(timestampBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			descriptor := string(DecodeDescriptor(key))
+
+			state, decodeErr := decodeRateState(val)
+			if decodeErr != nil {
+				problems = append(problems, Problem{
+					Kind: MalformedTimestamp, Descriptor: descriptor,
+					Detail:     decodeErr.Error(),
+					Repairable: true})
+				return
+			}
+
+			var channelVal []byte
+			channelVal, seekErr = t.kv.get(channelBucket, key)
+			if seekErr != nil {
+				return
+			}
+
+			if channelVal == nil {
+				problems = append(problems, Problem{
+					Kind: OrphanTimestamp, Descriptor: descriptor,
+					Detail:     "there is no channel for the timestamp",
+					Repairable: true})
+				return
+			}
+
+			ts := Timestamp(state.Time)
+			if ts.ToTime().After(now) {
+				problems = append(problems, Problem{
+					Kind: FutureTimestamp, Descriptor: descriptor,
+					Detail: fmt.Sprintf("the timestamp %s is in the future",
+						ts.ToTime().Format(time.RFC3339Nano)),
+					Repairable: true})
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the timestamps: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// Repair repairs the repairable problems and returns them.
+//
+// A channel stored at a mismatching key gets the descriptor of the key.
+// A malformed or orphan timestamp or a timestamp in the future is removed.
+// An invalid channel is disabled so that the Relay server refuses its
+// messages; the change is recorded in the audit log on behalf of the actor
+// and the channel is stored as its next revision keeping at most
+// the given number of revisions.
+//
+// Repair requires:
+// * t.access == ControlAccess
+// * keep > 0
+func (t *Txn) Repair(problems []Problem, now time.Time, actor Actor,
+	keep uint) (repaired []Problem, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(keep > 0):
+		panic("Violated: keep > 0")
+	default:
+		// Pass
+	}
+
+	for _, problem := range problems {
+		if !problem.Repairable {
+			continue
+		}
+
+		switch problem.Kind {
+		case DescriptorMismatch:
+			var channel *protoed.Channel
+			channel, err = t.getRawChannel(problem.Descriptor)
+			if err != nil {
+				return
+			}
+
+			channel.Descriptor_ = problem.Descriptor
+			err = t.putRawChannel(channel)
+			if err != nil {
+				return
+			}
+
+		case InvalidChannel:
+			var before *protoed.Channel
+			before, err = t.getRawChannel(problem.Descriptor)
+			if err != nil {
+				return
+			}
+
+			after := proto.Clone(before).(*protoed.Channel)
+			after.Disabled = &protoed.Disabled{
+				Time: now.UnixNano(), Reason: InvalidReason}
+
+			err = t.putRawChannel(after)
+			if err != nil {
+				return
+			}
+
+			err = t.RecordChange(protoed.AuditRecord_DISABLE, before, after,
+				actor, now, keep)
+			if err != nil {
+				return
+			}
+
+		case MalformedTimestamp, OrphanTimestamp, FutureTimestamp:
+			err = t.kv.remove(timestampBucket,
+				Descriptor(problem.Descriptor).Encode())
+			if err != nil {
+				err = fmt.Errorf("failed to erase the timestamp: %s",
+					err.Error())
+				return
+			}
+
+		default:
+			panic(fmt.Sprintf("unhandled repairable problem: %s", problem))
+		}
+
+		repaired = append(repaired, problem)
+	}
+
+	return
+}
+
+// getRawChannel fetches the channel stored at the key of the descriptor
+// without any checks on its content.
+func (t *Txn) getRawChannel(descriptor string) (
+	channel *protoed.Channel, err error) {
+	val, err := t.kv.get(channelBucket, Descriptor(descriptor).Encode())
+	if err != nil {
+		err = fmt.Errorf("failed to get the channel: %s", err.Error())
+		return
+	}
+
+	if val == nil {
+		err = fmt.Errorf("the channel %q does not exist", descriptor)
+		return
+	}
+
+	channel = &protoed.Channel{}
+	err = proto.Unmarshal(val, channel)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal the channel: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// putRawChannel stores the channel at the key of its descriptor without
+// any checks on its content.
+func (t *Txn) putRawChannel(channel *protoed.Channel) (err error) {
+	serialized, err := proto.Marshal(channel)
+	if err != nil {
+		err = fmt.Errorf("failed to serialize the channel: %s", err.Error())
+		return
+	}
+
+	err = t.kv.put(channelBucket, Descriptor(channel.Descriptor_).Encode(),
+		serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the channel: %s", err.Error())
+		return
+	}
+
+	return
+}
@@ -0,0 +1,238 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// requireRecipients is a channel validator rejecting the channels without
+// recipients.
+func requireRecipients(channel *protoed.Channel) error {
+	if len(channel.Recipients) == 0 {
+		return errors.New("expected at least one recipient")
+	}
+	return nil
+}
+
+// putInconsistencies stores a consistent channel together with a record of
+// each kind of inconsistency, bypassing the checks of the transaction.
+func putInconsistencies(s Store, now time.Time) error {
+	return s.Update(func(txn *Txn) (txnErr error) {
+		recipients := []*protoed.Entity{{Email: "someone@example.com"}}
+
+		for _, channel := range []*protoed.Channel{
+			{Descriptor_: "client-1", TokenHash: DummyTokenHash(),
+				Recipients: recipients},
+			{Descriptor_: "client-x", TokenHash: DummyTokenHash(),
+				Recipients: recipients},
+			{Descriptor_: "client-3", Recipients: recipients},
+			{Descriptor_: "client-4", TokenHash: DummyTokenHash()},
+			{Descriptor_: "client-7\x00legacy", TokenHash: DummyTokenHash(),
+				Recipients: recipients}} {
+			var serialized []byte
+			serialized, txnErr = proto.Marshal(channel)
+			if txnErr != nil {
+				return
+			}
+
+			key := channel.Descriptor_
+			if key == "client-x" {
+				key = "client-2"
+			}
+
+			txnErr = txn.kv.put(channelBucket, []byte(key), serialized)
+			if txnErr != nil {
+				return
+			}
+		}
+
+		txnErr = txn.kv.put(channelBucket, []byte("client-5"),
+			[]byte("\xff\xff"))
+		if txnErr != nil {
+			return
+		}
+
+		var past, future []byte
+		past, txnErr = proto.Marshal(&protoed.RateState{
+			Time: int64(TimestampFromTime(now.Add(-time.Hour)))})
+		if txnErr != nil {
+			return
+		}
+
+		future, txnErr = proto.Marshal(&protoed.RateState{
+			Time: int64(TimestampFromTime(now.Add(time.Hour)))})
+		if txnErr != nil {
+			return
+		}
+
+		for descriptor, encoded := range map[string][]byte{
+			"client-1": past,
+			"client-2": future,
+			"client-4": []byte("\x01\x02"),
+			"client-6": past} {
+			txnErr = txn.kv.put(timestampBucket, []byte(descriptor), encoded)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
+func TestTxn_Check(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	err := putInconsistencies(s, now)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var problems []Problem
+	err = s.View(func(txn *Txn) (txnErr error) {
+		problems, txnErr = txn.Check(now, requireRecipients)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type found struct {
+		kind       ProblemKind
+		descriptor string
+		repairable bool
+	}
+
+	var got []found
+	for _, problem := range problems {
+		got = append(got, found{problem.Kind, problem.Descriptor,
+			problem.Repairable})
+	}
+
+	expected := []found{
+		{DescriptorMismatch, "client-2", true},
+		{MissingToken, "client-3", false},
+		{InvalidChannel, "client-4", true},
+		{UndecodableChannel, "client-5", false},
+		{InvalidDescriptor, "client-7\x00legacy", false},
+		{FutureTimestamp, "client-2", true},
+		{MalformedTimestamp, "client-4", true},
+		{OrphanTimestamp, "client-6", true}}
+
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected the problems %v, got %v", expected, got)
+	}
+}
+
+func TestTxn_Repair(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	err := putInconsistencies(s, now)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		var problems []Problem
+		problems, txnErr = txn.Check(now, requireRecipients)
+		if txnErr != nil {
+			return
+		}
+
+		var repaired []Problem
+		repaired, txnErr = txn.Repair(problems, now, Actor{Name: "ops"},
+			DefaultRevisions)
+		if txnErr != nil {
+			return
+		}
+
+		if len(repaired) != 5 {
+			t.Errorf("expected 5 repaired problems, got %v", repaired)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		var problems []Problem
+		problems, txnErr = txn.Check(now, requireRecipients)
+		if txnErr != nil {
+			return
+		}
+
+		var kinds []ProblemKind
+		for _, problem := range problems {
+			if problem.Repairable {
+				t.Errorf("unexpected repairable problem after "+
+					"the repair: %s", problem)
+			}
+			kinds = append(kinds, problem.Kind)
+		}
+
+		expected := []ProblemKind{
+			MissingToken, InvalidChannel, UndecodableChannel,
+			InvalidDescriptor}
+		if !reflect.DeepEqual(expected, kinds) {
+			t.Errorf("expected the remaining problems %v, got %v",
+				expected, kinds)
+		}
+
+		var channel *protoed.Channel
+		channel, txnErr = txn.GetChannel("client-2")
+		if txnErr != nil {
+			return
+		}
+
+		if channel == nil || channel.Descriptor_ != "client-2" {
+			t.Errorf("expected the descriptor client-2, got %v", channel)
+		}
+
+		channel, txnErr = txn.GetChannel("client-4")
+		if txnErr != nil {
+			return
+		}
+
+		if channel == nil || channel.Disabled == nil ||
+			channel.Disabled.Reason != InvalidReason {
+			t.Errorf("expected the channel client-4 to be disabled, got %v",
+				channel)
+		}
+
+		var count uint64
+		count, txnErr = txn.CountTimestamps()
+		if txnErr != nil {
+			return
+		}
+
+		if count != 1 {
+			t.Errorf("expected only the timestamp of client-1 to be kept, "+
+				"got %d timestamps", count)
+		}
+
+		var records []*protoed.AuditRecord
+		records, _, txnErr = txn.AuditRecords(AuditFilter{}, 0, 10)
+		if txnErr != nil {
+			return
+		}
+
+		if len(records) != 1 ||
+			records[0].Operation != protoed.AuditRecord_DISABLE ||
+			records[0].Actor != "ops" {
+			t.Errorf("expected a single disable by ops in the audit log, "+
+				"got %v", records)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
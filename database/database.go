@@ -2,15 +2,14 @@ package database
 
 import (
 	"fmt"
-	"os"
 
-	"github.com/bmatsuo/lmdb-go/lmdb"
 	"github.com/golang/protobuf/proto"
 
 	"encoding/binary"
 	"github.com/Parquery/mailgun-relayery/dbc"
 	"github.com/Parquery/mailgun-relayery/protoed"
 	"math"
+	"strings"
 	"time"
 )
 
@@ -40,6 +39,24 @@ func DecodeDescriptor(data []byte) Descriptor {
 	return Descriptor(data)
 }
 
+// ValidateDescriptor checks that the descriptor can identify a channel.
+//
+// The descriptor must not be empty and must not contain a zero byte since
+// the zero byte separates the descriptor from the rest of the keys in
+// the revisions, the relay log, the usage and the other per-channel records.
+func ValidateDescriptor(descriptor string) error {
+	if descriptor == "" {
+		return fmt.Errorf("expected a non-empty descriptor")
+	}
+
+	if strings.Contains(descriptor, "\x00") {
+		return fmt.Errorf("unexpected zero byte in the descriptor: %q",
+			descriptor)
+	}
+
+	return nil
+}
+
 // Timestamp is a timestamp expressed as milliseconds from epoch
 // in UTC.
 type Timestamp uint64
@@ -71,105 +88,6 @@ func TimestampFromTime(tm time.Time) Timestamp {
 	return Timestamp(ts)
 }
 
-// NewEnv creates a new database environment object.
-// The database directory is assumed to be already initialized.
-func NewEnv(access Access, path string) (e *Env, err error) {
-
-	env, err := lmdb.NewEnv()
-	if err != nil {
-		err = fmt.Errorf("failed to initialize a database "+
-			"environment: %s", err)
-		return
-	}
-
-	err = env.SetMaxDBs(2)
-	if err != nil {
-		err = fmt.Errorf("failed to set the max. number of DBs to 1: "+
-			"%s", err)
-		closeErr := env.Close()
-		if closeErr != nil {
-			err = fmt.Errorf("%s; failed to close the database: "+
-				"%s", err, closeErr)
-		}
-		return
-	}
-
-	mapSize := int64(32 * 1024 * 1024 * 1024)
-	err = env.SetMapSize(mapSize)
-	if err != nil {
-		err = fmt.Errorf("failed to set the map size to "+
-			"%d: %s", mapSize, err)
-		closeErr := env.Close()
-		if closeErr != nil {
-			err = fmt.Errorf("%s; failed to close the "+
-				"database: %s", err, closeErr)
-		}
-		return
-	}
-
-	err = env.Open(path, 0, 0660)
-
-	if err != nil {
-		err = fmt.Errorf("failed to open the database in the "+
-			"directory %s: %s", path, err)
-		closeErr := env.Close()
-		if closeErr != nil {
-			err = fmt.Errorf("%s; failed to close the database: "+
-				"%s", err, closeErr)
-		}
-		return
-	}
-
-	e = &Env{
-		Path:   path,
-		env:    env,
-		Access: access}
-
-	return
-}
-
-// Env represents a database of channels.
-type Env struct {
-	Path   string
-	env    *lmdb.Env
-	Access Access
-}
-
-// Initialize initializes the database environment in the given directory.
-// Initialize creates the expected database and should be called only once
-// during the deployment.
-func Initialize(access Access, path string) (err error) {
-	_, err = os.Stat(path)
-	if err != nil {
-		err = fmt.Errorf("the directory of LMDB environment "+
-			"is expected to exist: %s", err.Error())
-		return
-	}
-
-	env, err := NewEnv(access, path)
-	if err != nil {
-		return
-	}
-	defer func() {
-		err = env.env.Close()
-	}()
-
-	err = env.env.Update(func(txn *lmdb.Txn) (txnErr error) {
-		_, txnErr = txn.OpenDBI(dbChannelName, lmdb.Create)
-		if txnErr != nil {
-			return
-		}
-
-		_, txnErr = txn.OpenDBI(dbTimestampName, lmdb.Create)
-		if txnErr != nil {
-			return
-		}
-		return
-	})
-
-	return
-}
-
 // GetChannel returns the channel associated with the descriptor in the
 // database, if it exists; nil otherwise.
 //
@@ -203,15 +121,14 @@ func (t *Txn) GetChannel(descriptor string) (channel *protoed.Channel,
 
 	encodedKey := Descriptor(descriptor).Encode()
 
-	value, getErr := t.lmdbTxn.Get(t.channelDbi, encodedKey)
-	switch {
-	case getErr == nil:
-		// pass
-	case lmdb.IsNotFound(getErr):
-		// not found, return
+	value, err := t.kv.get(channelBucket, encodedKey)
+	if err != nil {
+		err = fmt.Errorf("failed to get the channel: %s", err.Error())
 		return
-	default:
-		err = fmt.Errorf("failed to get the channel: %s", getErr.Error())
+	}
+
+	if value == nil {
+		// not found, return
 		return
 	}
 
@@ -228,7 +145,8 @@ func (t *Txn) GetChannel(descriptor string) (channel *protoed.Channel,
 }
 
 // GetTimestamp returns the Timestamp associated with the descriptor in the
-// database, if it exists; nil otherwise.
+// database, if it exists; nil otherwise. The Timestamp is the time of
+// the last relayed message as recorded in the rate state of the channel.
 //
 // GetTimestamp requires:
 // * t.access == ControlAccess || t.access == RelayAccess
@@ -259,27 +177,25 @@ func (t *Txn) GetTimestamp(descriptor string) (Timestamp *Timestamp, err error)
 
 	encodedKey := Descriptor(descriptor).Encode()
 
-	value, getErr := t.lmdbTxn.Get(t.timestampDbi, encodedKey)
-	switch {
-	case getErr == nil:
-		// pass
-	case lmdb.IsNotFound(getErr):
-		// not found, return
-		return
-	default:
-		err = fmt.Errorf("failed to get the Timestamp: %s", getErr.Error())
+	value, err := t.kv.get(timestampBucket, encodedKey)
+	if err != nil {
+		err = fmt.Errorf("failed to get the Timestamp: %s", err.Error())
 		return
 	}
 
-	ts := DecodeTimestamp(value)
-	Timestamp = &ts
+	if value == nil {
+		// not found, return
+		return
+	}
 
+	state, err := decodeRateState(value)
 	if err != nil {
-		err = fmt.Errorf("failed to unmarshal the Timestamp: %s",
-			err.Error())
 		return
 	}
 
+	ts := stateTimestamp(state)
+	Timestamp = &ts
+
 	return
 }
 
@@ -303,14 +219,13 @@ func (t *Txn) CountChannels() (count uint64, err error) {
 		}
 	}()
 
-	stat, err := t.lmdbTxn.Stat(t.channelDbi)
+	count, err = t.kv.count(channelBucket)
 	if err != nil {
-		err = fmt.Errorf("error while accessing the lmdb stats: %s",
+		err = fmt.Errorf("failed to count the channels: %s",
 			err.Error())
 		return
 	}
 
-	count = stat.Entries
 	return
 }
 
@@ -324,14 +239,13 @@ func (t *Txn) CountTimestamps() (count uint64, err error) {
 		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
 	}
 
-	stat, err := t.lmdbTxn.Stat(t.timestampDbi)
+	count, err = t.kv.count(timestampBucket)
 	if err != nil {
-		err = fmt.Errorf("error while accessing the lmdb stats: %s",
+		err = fmt.Errorf("failed to count the timestamps: %s",
 			err.Error())
 		return
 	}
 
-	count = stat.Entries
 	return
 }
 
@@ -348,9 +262,9 @@ func (t *Txn) CountTimestamps() (count uint64, err error) {
 //
 // ChannelPage ensures:
 // * err != nil || len(channels) <= int(perPage)
-// * err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage) || len(channels) == 0)
-// * err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage) || len(channels) > 0)
-// * err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage) || len(channels) == int(perPage))
+// * err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage)) || len(channels) == 0
+// * err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage)) || len(channels) > 0
+// * err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage)) || len(channels) == int(perPage)
 func (t *Txn) ChannelPage(page uint,
 	perPage uint) (channels []*protoed.Channel, err error) {
 	// Pre-condition
@@ -363,12 +277,12 @@ func (t *Txn) ChannelPage(page uint,
 		switch {
 		case !(err != nil || len(channels) <= int(perPage)):
 			panic("Violated: err != nil || len(channels) <= int(perPage)")
-		case !(err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage) || len(channels) == 0)):
-			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage) || len(channels) == 0)")
-		case !(err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage) || len(channels) > 0)):
-			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage) || len(channels) > 0)")
-		case !(err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage) || len(channels) == int(perPage))):
-			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage) || len(channels) == int(perPage))")
+		case !(err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage)) || len(channels) == 0):
+			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage)) || len(channels) == 0")
+		case !(err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage)) || len(channels) > 0):
+			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage)) || len(channels) > 0")
+		case !(err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage)) || len(channels) == int(perPage)):
+			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage)) || len(channels) == int(perPage)")
 		default:
 			// Pass
 		}
@@ -384,43 +298,136 @@ func (t *Txn) ChannelPage(page uint,
 		return
 	}
 
-	cur, err := t.lmdbTxn.OpenCursor(t.channelDbi)
+	index := uint(0)
+	err = t.kv.seek(channelBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if index >= pagesRange.end {
+				stop = true
+				return
+			}
+
+			index++
+			if index <= pagesRange.start {
+				return
+			}
+
+			channel := &protoed.Channel{}
+			seekErr = proto.Unmarshal(val, channel)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the channel: %s",
+					seekErr.Error())
+				return
+			}
+			channels = append(channels, channel)
+			return
+		})
 	if err != nil {
-		err = fmt.Errorf("error while accessing the cursor: %s",
+		err = fmt.Errorf("error while browsing the channels: %s",
 			err.Error())
 		return
 	}
-	defer cur.Close()
 
-	for index := uint(0); index < pagesRange.end; index++ {
-		_, val, curErr := cur.Get(nil, nil, lmdb.Next)
-		if lmdb.IsNotFound(curErr) {
-			return
-		}
+	return
 
-		if curErr != nil {
-			err = fmt.Errorf("error while browsing the database with "+
-				"the cursor: %s", curErr.Error())
-			return
-		}
+}
 
-		if index < pagesRange.start {
-			continue
-		}
+// AllChannels returns all the channels in the database ordered by their
+// descriptors.
+//
+// AllChannels requires:
+// * t.access == ControlAccess
+//
+// AllChannels ensures:
+// * !dbc.InTest || err != nil || uint64(len(channels)) == t.mustCountCh()
+func (t *Txn) AllChannels() (channels []*protoed.Channel, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
 
-		channel := &protoed.Channel{}
-		err = proto.Unmarshal(val, channel)
+	// Post-condition
+	defer func() {
+		if !(!dbc.InTest || err != nil || uint64(len(channels)) == t.mustCountCh()) {
+			panic("Violated: !dbc.InTest || err != nil || uint64(len(channels)) == t.mustCountCh()")
+		}
+	}()
 
-		if err != nil {
-			err = fmt.Errorf("failed to unmarshal the channel: %s",
-				err.Error())
+	err = t.kv.seek(channelBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			channel := &protoed.Channel{}
+			seekErr = proto.Unmarshal(val, channel)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the channel: %s",
+					seekErr.Error())
+				return
+			}
+
+			channels = append(channels, channel)
 			return
-		}
-		channels = append(channels, channel)
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the channels: %s",
+			err.Error())
+		return
 	}
 
 	return
+}
 
+// ChannelsAfter returns at most `limit` channels matching the filter whose
+// descriptors follow the descriptor `after` in the key order. An empty
+// `after` lists the channels from the first one. The flag `more` indicates
+// that further matching channels follow the returned ones.
+//
+// Unlike ChannelPage, ChannelsAfter seeks directly to the descriptor so
+// that the cost does not grow with the depth of the listing.
+//
+// ChannelsAfter requires:
+// * t.access == ControlAccess
+// * limit > 0
+//
+// ChannelsAfter ensures:
+// * err != nil || len(channels) <= int(limit)
+// * err != nil || !more || len(channels) == int(limit)
+// * err != nil || len(channels) == 0 || channels[0].Descriptor_ > after
+func (t *Txn) ChannelsAfter(after string, limit uint, filter ChannelFilter) (
+	channels []*protoed.Channel, more bool, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(limit > 0):
+		panic("Violated: limit > 0")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || len(channels) <= int(limit)):
+			panic("Violated: err != nil || len(channels) <= int(limit)")
+		case !(err != nil || !more || len(channels) == int(limit)):
+			panic("Violated: err != nil || !more || len(channels) == int(limit)")
+		case !(err != nil || len(channels) == 0 || channels[0].Descriptor_ > after):
+			panic("Violated: err != nil || len(channels) == 0 || channels[0].Descriptor_ > after")
+		default:
+			// Pass
+		}
+	}()
+
+	err = t.IterateChannels(after, filter,
+		func(channel *protoed.Channel) (stop bool, fnErr error) {
+			if len(channels) == int(limit) {
+				more = true
+				stop = true
+				return
+			}
+
+			channels = append(channels, channel)
+			return
+		})
+	return
 }
 
 // pageRange contains the start (inclusive) and end (exclusive) index of a
@@ -434,29 +441,29 @@ type pageRange struct {
 // out-of-bounds.
 //
 // pageRange ensures:
-// * err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64(page*perPage)) || pRange == nil
-// * err != nil || !dbc.InTest || !(t.mustCountCh() > uint64(page*perPage)) || pRange != nil
+// * err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage)) || pRange == nil
+// * err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage)) || pRange != nil
 // * err != nil || pRange == nil || pRange.start < pRange.end
 // * err != nil || pRange == nil || pRange.end-pRange.start <= perPage
 // * err != nil || pRange == nil || (page-1)*perPage == pRange.start
-// * err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64((page+1)*perPage)) || pRange.start-pRange.end == perPage
+// * err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage)) || pRange.end-pRange.start == perPage
 func (t *Txn) pageRange(page uint,
 	perPage uint) (pRange *pageRange, err error) {
 	// Post-conditions
 	defer func() {
 		switch {
-		case !(err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64(page*perPage)) || pRange == nil):
-			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64(page*perPage)) || pRange == nil")
-		case !(err != nil || !dbc.InTest || !(t.mustCountCh() > uint64(page*perPage)) || pRange != nil):
-			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() > uint64(page*perPage)) || pRange != nil")
+		case !(err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage)) || pRange == nil):
+			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() <= uint64((page-1)*perPage)) || pRange == nil")
+		case !(err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage)) || pRange != nil):
+			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() > uint64((page-1)*perPage)) || pRange != nil")
 		case !(err != nil || pRange == nil || pRange.start < pRange.end):
 			panic("Violated: err != nil || pRange == nil || pRange.start < pRange.end")
 		case !(err != nil || pRange == nil || pRange.end-pRange.start <= perPage):
 			panic("Violated: err != nil || pRange == nil || pRange.end-pRange.start <= perPage")
 		case !(err != nil || pRange == nil || (page-1)*perPage == pRange.start):
 			panic("Violated: err != nil || pRange == nil || (page-1)*perPage == pRange.start")
-		case !(err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64((page+1)*perPage)) || pRange.start-pRange.end == perPage):
-			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64((page+1)*perPage)) || pRange.start-pRange.end == perPage")
+		case !(err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage)) || pRange.end-pRange.start == perPage):
+			panic("Violated: err != nil || !dbc.InTest || !(t.mustCountCh() >= uint64(page*perPage)) || pRange.end-pRange.start == perPage")
 		default:
 			// Pass
 		}
@@ -481,9 +488,16 @@ func (t *Txn) pageRange(page uint,
 
 // PutChannel inserts a channel in the database, keyed on its descriptor.
 //
+// The token of the channel needs to be hashed beforehand since plain-text
+// tokens are never stored. The last uses of the tokens which the channel
+// no longer has are erased. The rate state of the channel is erased if
+// its minimum period or its rate limit changed.
+//
 // PutChannel requires:
 // * t.access == ControlAccess
 // * channel != nil
+// * channel.Token == ""
+// * channel.TokenHash != nil || len(channel.Tokens) > 0
 //
 // PutChannel preamble:
 //  var oldHas bool
@@ -505,6 +519,10 @@ func (t *Txn) PutChannel(channel *protoed.Channel) (err error) {
 		panic("Violated: t.access == ControlAccess")
 	case !(channel != nil):
 		panic("Violated: channel != nil")
+	case !(channel.Token == ""):
+		panic("Violated: channel.Token == \"\"")
+	case !(channel.TokenHash != nil || len(channel.Tokens) > 0):
+		panic("Violated: channel.TokenHash != nil || len(channel.Tokens) > 0")
 	default:
 		// Pass
 	}
@@ -550,13 +568,14 @@ func (t *Txn) PutChannel(channel *protoed.Channel) (err error) {
 		return
 	}
 
-	err = t.lmdbTxn.Put(t.channelDbi, encodedKey, serialized, 0)
+	err = t.kv.put(channelBucket, encodedKey, serialized)
 	if err != nil {
 		err = fmt.Errorf("failed to put the channel: %s", err.Error())
 		return
 	}
 
-	if prevChan != nil && prevChan.MinPeriod != channel.MinPeriod {
+	if prevChan != nil && (prevChan.MinPeriod != channel.MinPeriod ||
+		!proto.Equal(prevChan.RateLimit, channel.RateLimit)) {
 		err = t.removeTimestamp(channel.Descriptor_)
 		if err != nil {
 			err = fmt.Errorf("failed to erase the timestamp: %s", err.Error())
@@ -564,13 +583,27 @@ func (t *Txn) PutChannel(channel *protoed.Channel) (err error) {
 		}
 	}
 
+	if prevChan != nil {
+		removed := removedTokenNames(prevChan, channel)
+		if len(removed) > 0 {
+			err = t.RemoveTokenUses(channel.Descriptor_, removed...)
+			if err != nil {
+				return
+			}
+		}
+	}
+
 	return
 }
 
 // PutTimestamp inserts a Timestamp in the database, keyed on its descriptor.
+// The token bucket of the channel is stored as emptied at the Timestamp.
+//
+// The Relay server puts the timestamps when relaying the messages while
+// the Control server only puts them when importing the channels.
 //
 // PutTimestamp requires:
-// * !dbc.InTest || t.access == RelayAccess
+// * !dbc.InTest || t.access == RelayAccess || t.access == ControlAccess
 // * Timestamp != nil
 // * *Timestamp > 0
 //
@@ -590,8 +623,8 @@ func (t *Txn) PutTimestamp(descriptor Descriptor,
 	Timestamp *Timestamp) (err error) {
 	// Pre-conditions
 	switch {
-	case !(!dbc.InTest || t.access == RelayAccess):
-		panic("Violated: !dbc.InTest || t.access == RelayAccess")
+	case !(!dbc.InTest || t.access == RelayAccess || t.access == ControlAccess):
+		panic("Violated: !dbc.InTest || t.access == RelayAccess || t.access == ControlAccess")
 	case !(Timestamp != nil):
 		panic("Violated: Timestamp != nil")
 	case !(*Timestamp > 0):
@@ -623,10 +656,10 @@ func (t *Txn) PutTimestamp(descriptor Descriptor,
 		}
 	}()
 
-	encodedTs := Timestamp.Encode()
-	encodedKey := descriptor.Encode()
-
-	err = t.lmdbTxn.Put(t.timestampDbi, encodedKey, encodedTs, 0)
+	// The token bucket is considered empty right after the last relayed
+	// message.
+	err = t.PutRateState(descriptor, &protoed.RateState{
+		Time: int64(*Timestamp)})
 	if err != nil {
 		err = fmt.Errorf("failed to put the Timestamp: %s", err.Error())
 		return
@@ -635,7 +668,8 @@ func (t *Txn) PutTimestamp(descriptor Descriptor,
 	return
 }
 
-// RemoveChannel removes a channel from the database.
+// RemoveChannel removes a channel from the database together with its
+// timestamp, its last relay and the last uses of its tokens.
 //
 // RemoveChannel requires:
 // * t.access == ControlAccess
@@ -695,14 +729,9 @@ func (t *Txn) RemoveChannel(descriptor string) (err error) {
 
 	encodedKey := Descriptor(descriptor).Encode()
 
-	delErr := t.lmdbTxn.Del(t.channelDbi, encodedKey, []byte{0})
-	switch {
-	case delErr == nil:
-		// pass
-	case lmdb.IsNotFound(delErr):
-		// not found, pass
-	default:
-		err = fmt.Errorf("failed to erase the channel: %s", delErr.Error())
+	err = t.kv.remove(channelBucket, encodedKey)
+	if err != nil {
+		err = fmt.Errorf("failed to erase the channel: %s", err.Error())
 		return
 	}
 
@@ -713,6 +742,16 @@ func (t *Txn) RemoveChannel(descriptor string) (err error) {
 		return
 	}
 
+	err = t.removeLastRelay(descriptor)
+	if err != nil {
+		return
+	}
+
+	err = t.RemoveTokenUses(descriptor)
+	if err != nil {
+		return
+	}
+
 	return
 }
 
@@ -764,15 +803,9 @@ func (t *Txn) removeTimestamp(descriptor string) (err error) {
 
 	encodedKey := Descriptor(descriptor).Encode()
 
-	delErr := t.lmdbTxn.Del(t.timestampDbi, encodedKey, []byte{0})
-	switch {
-	case delErr == nil:
-		// pass
-	case lmdb.IsNotFound(delErr):
-		// not found, return
-		return
-	default:
-		err = fmt.Errorf("failed to erase the Timestamp: %s", delErr.Error())
+	err = t.kv.remove(timestampBucket, encodedKey)
+	if err != nil {
+		err = fmt.Errorf("failed to erase the Timestamp: %s", err.Error())
 		return
 	}
 
@@ -829,56 +862,10 @@ func (t *Txn) mustGetTs(descriptor string) *Timestamp {
 	return elem
 }
 
-// Update executes a read-write transaction.
-func (e *Env) Update(fn func(txn *Txn) error) error {
-	return e.env.Update(func(lmdbTxn *lmdb.Txn) error {
-		channelDbi, err := lmdbTxn.OpenDBI(dbChannelName, 0)
-		if err != nil {
-			return err
-		}
-
-		timestampDbi, err := lmdbTxn.OpenDBI(dbTimestampName, 0)
-		if err != nil {
-			return err
-		}
-
-		txn := &Txn{lmdbTxn: lmdbTxn,
-			channelDbi: channelDbi, timestampDbi: timestampDbi,
-			env: e, access: e.Access}
-		return fn(txn)
-	})
-}
-
-// View executes a read-only transaction.
-func (e *Env) View(fn func(txn *Txn) error) error {
-	return e.env.View(func(lmdbTxn *lmdb.Txn) error {
-		channelDbi, err := lmdbTxn.OpenDBI(dbChannelName, 0)
-		if err != nil {
-			return err
-		}
-
-		timestampDbi, err := lmdbTxn.OpenDBI(dbTimestampName, 0)
-		if err != nil {
-			return err
-		}
-
-		txn := &Txn{lmdbTxn: lmdbTxn,
-			channelDbi: channelDbi, timestampDbi: timestampDbi,
-			env: e, access: e.Access}
-		return fn(txn)
-	})
-}
-
-// Close closes the database.
-func (e *Env) Close() error {
-	return e.env.Close()
-}
-
 // Txn represents a transaction over the entries of the database.
+//
+// The transaction is independent of the storage backend.
 type Txn struct {
-	lmdbTxn      *lmdb.Txn
-	channelDbi   lmdb.DBI
-	timestampDbi lmdb.DBI
-	env          *Env
-	access       Access
+	kv     kvTxn
+	access Access
 }
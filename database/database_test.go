@@ -1,3 +1,5 @@
+// +build cgo
+
 package database
 
 import (
@@ -71,6 +73,20 @@ func TestEncodeDecode(t *testing.T) {
 	}
 }
 
+func TestValidateDescriptor(t *testing.T) {
+	for _, valid := range []string{"client-1/pipeline-2", "a b"} {
+		if err := ValidateDescriptor(valid); err != nil {
+			t.Errorf("expected %#v to be valid, got: %s", valid, err.Error())
+		}
+	}
+
+	for _, invalid := range []string{"", "\x00", "a\x00b"} {
+		if ValidateDescriptor(invalid) == nil {
+			t.Errorf("expected %#v to be invalid", invalid)
+		}
+	}
+}
+
 func TestFromToTime(t *testing.T) {
 	timestamps := []uint64{
 		1545396245000,
@@ -128,8 +144,8 @@ func TestTxn_CountChannels(t *testing.T) {
 		{Name: "Robert Schumann", Email: "robert.schumann@composers.com"}}
 
 	channel := protoed.Channel{Descriptor_: descriptor,
-		Token:  "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
-		Sender: &sender, Recipients: recipients,
+		TokenHash: DummyTokenHash(),
+		Sender:    &sender, Recipients: recipients,
 		Cc: cc, Bcc: bcc, MinPeriod: 0.0001, MaxSize: 10000000}
 
 	// check that count is zero
@@ -272,8 +288,8 @@ func TestTxn_ChannelPage(t *testing.T) {
 		{Name: "Robert Schumann", Email: "robert.schumann@composers.com"}}
 
 	channel := protoed.Channel{Descriptor_: descriptor,
-		Token:  "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
-		Sender: &sender, Recipients: recipients,
+		TokenHash: DummyTokenHash(),
+		Sender:    &sender, Recipients: recipients,
 		Cc: cc, Bcc: bcc, MinPeriod: 0.0001, MaxSize: 10000000}
 
 	// empty database calls
@@ -384,6 +400,125 @@ func TestTxn_ChannelPage(t *testing.T) {
 	}
 }
 
+func TestTxn_ChannelsAfter(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	descriptor := "some-channel"
+	sender := protoed.Entity{Name: "Ludwig van Beethoven",
+		Email: "ludwig.van.beethoven@composers.com"}
+	recipients := []*protoed.Entity{
+		{Name: "Johannes Brahms", Email: "johannes.brahms@composers.com"}}
+
+	channel := protoed.Channel{Descriptor_: descriptor,
+		TokenHash: DummyTokenHash(),
+		Sender:    &sender, Recipients: recipients,
+		MinPeriod: 0.0001, MaxSize: 10000000}
+
+	// empty database call
+	err = d.View(func(txn *Txn) (txnerr error) {
+		channels, more, txnerr := txn.ChannelsAfter("", 10, ChannelFilter{})
+		if txnerr != nil {
+			t.Fatalf(txnerr.Error())
+		}
+		if len(channels) != 0 || more {
+			t.Fatalf("Expected no channels but got count = %d, more = %v",
+				len(channels), more)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// put every other channel
+	err = d.Update(func(txn *Txn) (txnerr error) {
+		for i := 0; i < 10; i += 2 {
+			channel.Descriptor_ = descriptor + "0" + strconv.Itoa(i)
+			txnerr = txn.PutChannel(&channel)
+			if txnerr != nil {
+				t.Fatalf(txnerr.Error())
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type testcase struct {
+		after       string
+		limit       uint
+		descriptors []string
+		more        bool
+	}
+	testcases := []testcase{
+		{after: "", limit: 2,
+			descriptors: []string{descriptor + "00", descriptor + "02"},
+			more:        true},
+		{after: descriptor + "02", limit: 2,
+			descriptors: []string{descriptor + "04", descriptor + "06"},
+			more:        true},
+		{after: descriptor + "03", limit: 2,
+			descriptors: []string{descriptor + "04", descriptor + "06"},
+			more:        true},
+		{after: descriptor + "06", limit: 2,
+			descriptors: []string{descriptor + "08"}},
+		{after: descriptor + "04", limit: 2,
+			descriptors: []string{descriptor + "06", descriptor + "08"}},
+		{after: descriptor + "08", limit: 2,
+			descriptors: []string{}},
+		{after: "a", limit: 10,
+			descriptors: []string{descriptor + "00", descriptor + "02",
+				descriptor + "04", descriptor + "06", descriptor + "08"}},
+		{after: "z", limit: 10,
+			descriptors: []string{}},
+	}
+
+	for _, testcase := range testcases {
+		err = d.View(func(txn *Txn) (txnerr error) {
+			channels, more, txnerr := txn.ChannelsAfter(testcase.after,
+				testcase.limit, ChannelFilter{})
+			if txnerr != nil {
+				t.Fatalf(txnerr.Error())
+			}
+			if more != testcase.more {
+				t.Errorf("Expected more = %v after %#v, got %v",
+					testcase.more, testcase.after, more)
+			}
+			if len(channels) != len(testcase.descriptors) {
+				t.Errorf("Expected %d channels after %#v, got %d",
+					len(testcase.descriptors), testcase.after, len(channels))
+			} else {
+				for i, channel := range channels {
+					if channel.Descriptor_ != testcase.descriptors[i] {
+						t.Errorf("Expected descriptor %s, got %s",
+							testcase.descriptors[i], channel.Descriptor_)
+					}
+				}
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
+
 func TestTxn_PutChannel(t *testing.T) {
 	d, err := emptyDatabase(ControlAccess)
 	if err != nil {
@@ -413,8 +548,8 @@ func TestTxn_PutChannel(t *testing.T) {
 		{Name: "Robert Schumann", Email: "robert.schumann@composers.com"}}
 
 	channel := protoed.Channel{Descriptor_: descriptor,
-		Token:  "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
-		Sender: &sender, Recipients: recipients,
+		TokenHash: DummyTokenHash(),
+		Sender:    &sender, Recipients: recipients,
 		Cc: cc, Bcc: bcc, MinPeriod: 0.0001, MaxSize: 10000000}
 
 	// check absence of the channel
@@ -613,8 +748,8 @@ func TestTxn_RemoveChannel(t *testing.T) {
 		{Name: "Franz Schubert", Email: "franz.schubert@composers.com"}}
 
 	channel := protoed.Channel{Descriptor_: descriptor,
-		Token:  "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
-		Sender: &sender, Recipients: recipients, Cc: cc, Bcc: bcc,
+		TokenHash: DummyTokenHash(),
+		Sender:    &sender, Recipients: recipients, Cc: cc, Bcc: bcc,
 		MinPeriod: 0.0001, MaxSize: 10000000}
 
 	// put the channel
@@ -737,6 +872,27 @@ func TestTxn_RemoveTimestamp(t *testing.T) {
 	}
 }
 
+func TestEnv_Store(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	exerciseStore(d, func(access Access) { d.Access = access }, t)
+}
+
 // emptyDatabase creates an empty database.
 func emptyDatabase(access Access) (e *Env, err error) {
 	tmpdir, err := ioutil.TempDir("", "")
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// ExpiryAction enumerates what happens to the channels which expired.
+type ExpiryAction string
+
+const (
+	// RemoveExpired removes the expired channels.
+	RemoveExpired ExpiryAction = "remove"
+
+	// DisableExpired disables the expired channels.
+	DisableExpired ExpiryAction = "disable"
+)
+
+// ExpiryActions lists all the expiry actions.
+var ExpiryActions = []ExpiryAction{RemoveExpired, DisableExpired}
+
+// ExpiredReason is the reason given to the channels disabled on expiry.
+const ExpiredReason = "expired"
+
+// ParseExpiryAction parses the name of an expiry action.
+func ParseExpiryAction(name string) (action ExpiryAction, err error) {
+	for _, a := range ExpiryActions {
+		if ExpiryAction(name) == a {
+			action = a
+			return
+		}
+	}
+
+	var names []string
+	for _, a := range ExpiryActions {
+		names = append(names, string(a))
+	}
+
+	err = fmt.Errorf("unknown expiry action %#v, expected one of: %s",
+		name, strings.Join(names, ", "))
+	return
+}
+
+// Expired indicates that the channel has a valid_until time which passed
+// at least the grace period before now.
+func Expired(channel *protoed.Channel, now time.Time,
+	grace time.Duration) bool {
+	return channel.ValidUntil > 0 &&
+		channel.ValidUntil+int64(grace) <= now.UnixNano()
+}
+
+// SweepExpired removes or disables the channels which expired at least
+// the grace period before now and erases their timestamps.
+//
+// Every change is recorded in the audit log on behalf of the actor and
+// a disabled channel is stored as its next revision keeping at most
+// the given number of revisions. The channels which have been already
+// disabled are left untouched and so are the channels with invalid
+// descriptors on disable since they can not be stored as revisions.
+//
+// SweepExpired requires:
+// * t.access == ControlAccess
+// * grace >= 0
+// * action == RemoveExpired || action == DisableExpired
+// * keep > 0
+func (t *Txn) SweepExpired(now time.Time, grace time.Duration,
+	action ExpiryAction, actor Actor, keep uint) (
+	descriptors []string, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(grace >= 0):
+		panic("Violated: grace >= 0")
+	case !(action == RemoveExpired || action == DisableExpired):
+		panic("Violated: action == RemoveExpired || action == DisableExpired")
+	case !(keep > 0):
+		panic("Violated: keep > 0")
+	default:
+		// Pass
+	}
+
+	// The channels are collected first and changed after the iteration.
+	var expired []*protoed.Channel
+	err = t.kv.seek(channelBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			channel := &protoed.Channel{}
+			seekErr = proto.Unmarshal(val, channel)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the channel: %s",
+					seekErr.Error())
+				return
+			}
+
+			if Expired(channel, now, grace) &&
+				!(action == DisableExpired && (channel.Disabled != nil ||
+					ValidateDescriptor(channel.Descriptor_) != nil)) {
+				expired = append(expired, channel)
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the channels: %s",
+			err.Error())
+		return
+	}
+
+	for _, channel := range expired {
+		switch action {
+		case RemoveExpired:
+			err = t.RemoveChannel(channel.Descriptor_)
+			if err != nil {
+				return
+			}
+
+			err = t.RecordChange(protoed.AuditRecord_DELETE, channel, nil,
+				actor, now, keep)
+			if err != nil {
+				return
+			}
+
+		case DisableExpired:
+			after := proto.Clone(channel).(*protoed.Channel)
+			after.Disabled = &protoed.Disabled{
+				Time: now.UnixNano(), Reason: ExpiredReason}
+
+			err = t.PutChannel(after)
+			if err != nil {
+				return
+			}
+
+			err = t.removeTimestamp(channel.Descriptor_)
+			if err != nil {
+				err = fmt.Errorf("failed to erase the timestamp: %s",
+					err.Error())
+				return
+			}
+
+			err = t.RecordChange(protoed.AuditRecord_DISABLE, channel, after,
+				actor, now, keep)
+			if err != nil {
+				return
+			}
+		}
+
+		descriptors = append(descriptors, channel.Descriptor_)
+	}
+
+	return
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestParseExpiryAction(t *testing.T) {
+	for _, action := range ExpiryActions {
+		parsed, err := ParseExpiryAction(string(action))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if parsed != action {
+			t.Errorf("expected %s, got %s", action, parsed)
+		}
+	}
+
+	_, err := ParseExpiryAction("archive")
+	if err == nil {
+		t.Errorf("expected an error on an unknown expiry action")
+	}
+}
+
+// putExpiringChannels stores a channel which never expires, a channel which
+// expired within the grace period and two channels which expired before it,
+// together with their timestamps.
+func putExpiringChannels(s Store, now time.Time, grace time.Duration) error {
+	validUntil := map[string]int64{
+		"client-1": 0,
+		"client-2": now.Add(-grace / 2).UnixNano(),
+		"client-3": now.Add(-grace).UnixNano(),
+		"client-4": now.Add(-2 * grace).UnixNano()}
+
+	return s.Update(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range []string{
+			"client-1", "client-2", "client-3", "client-4"} {
+			txnErr = txn.PutChannel(&protoed.Channel{
+				Descriptor_: descriptor, TokenHash: DummyTokenHash(),
+				ValidUntil: validUntil[descriptor]})
+			if txnErr != nil {
+				return
+			}
+
+			ts := TimestampFromTime(now.Add(-3 * grace))
+			txnErr = txn.PutTimestamp(Descriptor(descriptor), &ts)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
+func TestTxn_SweepExpired_Remove(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	grace := 24 * time.Hour
+
+	err := putExpiringChannels(s, now, grace)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var descriptors []string
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		descriptors, txnErr = txn.SweepExpired(now, grace, RemoveExpired,
+			Actor{Name: "expiry sweeper"}, DefaultRevisions)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reflect.DeepEqual([]string{"client-3", "client-4"}, descriptors) {
+		t.Errorf("expected [client-3 client-4] to be swept, got %v",
+			descriptors)
+	}
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range []string{
+			"client-1", "client-2", "client-3", "client-4"} {
+			var channel *protoed.Channel
+			channel, txnErr = txn.GetChannel(descriptor)
+			if txnErr != nil {
+				return
+			}
+
+			var ts *Timestamp
+			ts, txnErr = txn.GetTimestamp(descriptor)
+			if txnErr != nil {
+				return
+			}
+
+			swept := descriptor == "client-3" || descriptor == "client-4"
+			if (channel == nil) != swept || (ts == nil) != swept {
+				t.Errorf("expected the channel %s to be swept: %v, "+
+					"got channel %v and timestamp %v",
+					descriptor, swept, channel, ts)
+			}
+		}
+
+		var records []*protoed.AuditRecord
+		records, _, txnErr = txn.AuditRecords(AuditFilter{}, 0, 10)
+		if txnErr != nil {
+			return
+		}
+
+		if len(records) != 2 {
+			t.Fatalf("expected 2 audit records, got %d", len(records))
+		}
+
+		for _, record := range records {
+			if record.Operation != protoed.AuditRecord_DELETE ||
+				record.Actor != "expiry sweeper" {
+				t.Errorf("unexpected audit record: %s", record.String())
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestTxn_SweepExpired_Disable(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	grace := 24 * time.Hour
+
+	err := putExpiringChannels(s, now, grace)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i, expected := range [][]string{{"client-3", "client-4"}, nil} {
+		var descriptors []string
+		err = s.Update(func(txn *Txn) (txnErr error) {
+			descriptors, txnErr = txn.SweepExpired(now, grace,
+				DisableExpired, Actor{Name: "expiry sweeper"},
+				DefaultRevisions)
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !reflect.DeepEqual(expected, descriptors) {
+			t.Errorf("sweep %d: expected %v to be swept, got %v",
+				i, expected, descriptors)
+		}
+	}
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range []string{
+			"client-1", "client-2", "client-3", "client-4"} {
+			var channel *protoed.Channel
+			channel, txnErr = txn.GetChannel(descriptor)
+			if txnErr != nil {
+				return
+			}
+
+			var ts *Timestamp
+			ts, txnErr = txn.GetTimestamp(descriptor)
+			if txnErr != nil {
+				return
+			}
+
+			swept := descriptor == "client-3" || descriptor == "client-4"
+			if channel == nil {
+				t.Fatalf("expected the channel %s to be kept", descriptor)
+			}
+
+			if (channel.Disabled != nil) != swept || (ts == nil) != swept {
+				t.Errorf("expected the channel %s to be disabled: %v, "+
+					"got channel %s and timestamp %v",
+					descriptor, swept, channel.String(), ts)
+			}
+
+			if swept && (channel.Disabled.Reason != ExpiredReason ||
+				channel.Disabled.Time != now.UnixNano()) {
+				t.Errorf("unexpected disabled state of %s: %s",
+					descriptor, channel.Disabled.String())
+			}
+		}
+
+		var revisions []*protoed.ChannelRevision
+		revisions, txnErr = txn.Revisions("client-3")
+		if txnErr != nil {
+			return
+		}
+
+		if len(revisions) != 1 || revisions[0].Actor != "expiry sweeper" {
+			t.Errorf("expected a single revision by the expiry sweeper, "+
+				"got %v", revisions)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// ChannelFilter selects the channels. Empty and nil fields match all
+// the channels.
+//
+// The domains and the email addresses are compared case-insensitively.
+type ChannelFilter struct {
+	// Prefix is the prefix of the descriptors.
+	Prefix string
+
+	// Domain is the MailGun domain.
+	Domain string
+
+	// Sender is the email address of the sender.
+	Sender string
+
+	// Recipient is the email address of any recipient, cc or bcc.
+	Recipient string
+
+	// Disabled selects the disabled channels if true and the enabled ones
+	// if false.
+	Disabled *bool
+}
+
+// IsEmpty indicates that the filter matches all the channels.
+func (f ChannelFilter) IsEmpty() bool {
+	return f.Prefix == "" && f.Domain == "" && f.Sender == "" &&
+		f.Recipient == "" && f.Disabled == nil
+}
+
+// Matches indicates that the channel satisfies the filter.
+//
+// Matches requires:
+// * channel != nil
+func (f ChannelFilter) Matches(channel *protoed.Channel) bool {
+	// Pre-condition
+	if !(channel != nil) {
+		panic("Violated: channel != nil")
+	}
+
+	switch {
+	case !strings.HasPrefix(channel.Descriptor_, f.Prefix):
+		return false
+	case f.Domain != "" && !strings.EqualFold(channel.Domain, f.Domain):
+		return false
+	case f.Sender != "" &&
+		(channel.Sender == nil ||
+			!strings.EqualFold(channel.Sender.Email, f.Sender)):
+		return false
+	case f.Recipient != "" &&
+		!containsEmail(channel.Recipients, f.Recipient) &&
+		!containsEmail(channel.Cc, f.Recipient) &&
+		!containsEmail(channel.Bcc, f.Recipient):
+		return false
+	case f.Disabled != nil && *f.Disabled != (channel.Disabled != nil):
+		return false
+	default:
+		return true
+	}
+}
+
+func containsEmail(entities []*protoed.Entity, email string) bool {
+	for _, entity := range entities {
+		if strings.EqualFold(entity.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// IterateChannels calls fn on the channels matching the filter whose
+// descriptors follow the descriptor `after` in the key order, until fn
+// requests to stop or returns an error. An empty `after` starts from
+// the first channel.
+//
+// The iteration seeks directly to the descriptor prefix of the filter and
+// ends with the last descriptor sharing the prefix, while the other criteria
+// are checked on each channel.
+//
+// IterateChannels requires:
+// * t.access == ControlAccess
+// * fn != nil
+func (t *Txn) IterateChannels(after string, filter ChannelFilter,
+	fn func(channel *protoed.Channel) (stop bool, err error)) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(fn != nil):
+		panic("Violated: fn != nil")
+	default:
+		// Pass
+	}
+
+	start := after
+	if filter.Prefix > after {
+		start = filter.Prefix
+	}
+
+	var startKey []byte
+	if start != "" {
+		startKey = Descriptor(start).Encode()
+	}
+
+	err = t.kv.seek(channelBucket, startKey,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			descriptor := string(DecodeDescriptor(key))
+			if !strings.HasPrefix(descriptor, filter.Prefix) {
+				// past the range of the descriptor prefix
+				stop = true
+				return
+			}
+
+			if descriptor == after {
+				return
+			}
+
+			channel := &protoed.Channel{}
+			seekErr = proto.Unmarshal(val, channel)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the channel %s: %s",
+					descriptor, seekErr.Error())
+				return
+			}
+
+			if filter.Matches(channel) {
+				stop, seekErr = fn(channel)
+			}
+			return
+		})
+	return
+}
+
+// CountMatchingChannels returns the number of channels matching the filter.
+//
+// Unlike CountChannels, the count requires a pass over the channels
+// in the range of the descriptor prefix unless the filter is empty.
+//
+// CountMatchingChannels requires:
+// * t.access == ControlAccess
+func (t *Txn) CountMatchingChannels(filter ChannelFilter) (count uint64,
+	err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	if filter.IsEmpty() {
+		return t.CountChannels()
+	}
+
+	err = t.IterateChannels("", filter,
+		func(channel *protoed.Channel) (bool, error) {
+			count++
+			return false, nil
+		})
+	return
+}
@@ -0,0 +1,197 @@
+// +build cgo
+
+package database
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestChannelFilter_Matches(t *testing.T) {
+	channel := &protoed.Channel{Descriptor_: "client-1/pipeline-3",
+		TokenHash: DummyTokenHash(),
+		Sender: &protoed.Entity{Name: "Ludwig van Beethoven",
+			Email: "ludwig.van.beethoven@composers.com"},
+		Recipients: []*protoed.Entity{{Name: "Johannes Brahms",
+			Email: "johannes.brahms@composers.com"}},
+		Cc: []*protoed.Entity{{Name: "Richard Wagner",
+			Email: "richard.wagner@composers.com"}},
+		Bcc: []*protoed.Entity{{Name: "Robert Schumann",
+			Email: "robert.schumann@composers.com"}},
+		Domain:    "marketing.composers.com",
+		MinPeriod: 0.0001, MaxSize: 10000000}
+
+	yes := true
+	no := false
+
+	type testcase struct {
+		filter  ChannelFilter
+		matches bool
+	}
+
+	testcases := []testcase{
+		{filter: ChannelFilter{}, matches: true},
+		{filter: ChannelFilter{Prefix: "client-1/"}, matches: true},
+		{filter: ChannelFilter{Prefix: "client-2/"}, matches: false},
+		{filter: ChannelFilter{Domain: "Marketing.Composers.com"},
+			matches: true},
+		{filter: ChannelFilter{Domain: "composers.com"}, matches: false},
+		{filter: ChannelFilter{Sender: "ludwig.van.beethoven@composers.com"},
+			matches: true},
+		{filter: ChannelFilter{Sender: "johannes.brahms@composers.com"},
+			matches: false},
+		{filter: ChannelFilter{Recipient: "johannes.brahms@composers.com"},
+			matches: true},
+		{filter: ChannelFilter{Recipient: "Richard.Wagner@composers.com"},
+			matches: true},
+		{filter: ChannelFilter{Recipient: "robert.schumann@composers.com"},
+			matches: true},
+		{filter: ChannelFilter{Recipient: "ludwig.van.beethoven@composers.com"},
+			matches: false},
+		{filter: ChannelFilter{Prefix: "client-1/",
+			Recipient: "robert.schumann@composers.com"}, matches: true},
+		{filter: ChannelFilter{Prefix: "client-1/",
+			Domain: "composers.com"}, matches: false},
+		{filter: ChannelFilter{Disabled: &no}, matches: true},
+		{filter: ChannelFilter{Disabled: &yes}, matches: false},
+	}
+
+	for i, tc := range testcases {
+		if got := tc.filter.Matches(channel); got != tc.matches {
+			t.Errorf("test case %d: expected %v, got %v", i, tc.matches, got)
+		}
+	}
+
+	channel.Disabled = &protoed.Disabled{Time: 1538404200000000000}
+	if !(ChannelFilter{Disabled: &yes}).Matches(channel) {
+		t.Errorf("expected the disabled channel to match the filter " +
+			"of the disabled channels")
+	}
+	if (ChannelFilter{Disabled: &no}).Matches(channel) {
+		t.Errorf("expected the disabled channel not to match the filter " +
+			"of the enabled channels")
+	}
+}
+
+func TestTxn_IterateChannels(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	sender := protoed.Entity{Name: "Ludwig van Beethoven",
+		Email: "ludwig.van.beethoven@composers.com"}
+	brahms := []*protoed.Entity{
+		{Name: "Johannes Brahms", Email: "johannes.brahms@composers.com"}}
+	wagner := []*protoed.Entity{
+		{Name: "Richard Wagner", Email: "richard.wagner@composers.com"}}
+
+	err = d.Update(func(txn *Txn) (txnerr error) {
+		for _, channel := range []*protoed.Channel{
+			{Descriptor_: "client-1/pipeline-1", Recipients: brahms},
+			{Descriptor_: "client-1/pipeline-2", Recipients: wagner},
+			{Descriptor_: "client-1/pipeline-3", Recipients: brahms,
+				Cc: wagner},
+			{Descriptor_: "client-10/pipeline-1", Recipients: wagner},
+			{Descriptor_: "client-2/pipeline-1", Recipients: brahms},
+		} {
+			channel.TokenHash = DummyTokenHash()
+			channel.Sender = &sender
+			channel.Domain = "composers.com"
+			channel.MinPeriod = 0.0001
+			channel.MaxSize = 10000000
+
+			txnerr = txn.PutChannel(channel)
+			if txnerr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type testcase struct {
+		after       string
+		filter      ChannelFilter
+		descriptors []string
+	}
+
+	testcases := []testcase{
+		{filter: ChannelFilter{},
+			descriptors: []string{"client-1/pipeline-1",
+				"client-1/pipeline-2", "client-1/pipeline-3",
+				"client-10/pipeline-1", "client-2/pipeline-1"}},
+		{filter: ChannelFilter{Prefix: "client-1/"},
+			descriptors: []string{"client-1/pipeline-1",
+				"client-1/pipeline-2", "client-1/pipeline-3"}},
+		{after: "client-1/pipeline-1", filter: ChannelFilter{Prefix: "client-1/"},
+			descriptors: []string{"client-1/pipeline-2",
+				"client-1/pipeline-3"}},
+		{after: "a", filter: ChannelFilter{Prefix: "client-2/"},
+			descriptors: []string{"client-2/pipeline-1"}},
+		{after: "client-3", filter: ChannelFilter{Prefix: "client-2/"},
+			descriptors: nil},
+		{filter: ChannelFilter{Recipient: "richard.wagner@composers.com"},
+			descriptors: []string{"client-1/pipeline-2",
+				"client-1/pipeline-3", "client-10/pipeline-1"}},
+		{filter: ChannelFilter{Prefix: "client-1/",
+			Recipient: "johannes.brahms@composers.com"},
+			descriptors: []string{"client-1/pipeline-1",
+				"client-1/pipeline-3"}},
+		{filter: ChannelFilter{Sender: "johannes.brahms@composers.com"},
+			descriptors: nil},
+	}
+
+	for i, tc := range testcases {
+		err = d.View(func(txn *Txn) (txnerr error) {
+			var descriptors []string
+			txnerr = txn.IterateChannels(tc.after, tc.filter,
+				func(channel *protoed.Channel) (bool, error) {
+					descriptors = append(descriptors, channel.Descriptor_)
+					return false, nil
+				})
+			if txnerr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(tc.descriptors, descriptors) {
+				t.Errorf("test case %d: expected %v, got %v",
+					i, tc.descriptors, descriptors)
+			}
+
+			if tc.after == "" {
+				var count uint64
+				count, txnerr = txn.CountMatchingChannels(tc.filter)
+				if txnerr != nil {
+					return
+				}
+
+				if count != uint64(len(tc.descriptors)) {
+					t.Errorf("test case %d: expected the count %d, got %d",
+						i, len(tc.descriptors), count)
+				}
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"fmt"
+)
+
+const dbLastRelayName = "last_relay"
+
+// GetLastRelay returns the time when the last message of the channel has
+// been relayed, or nil if none has been relayed yet.
+//
+// Unlike the rate state of the channel, the time is only set once MailGun
+// accepted the message so that the reservations and the rollbacks do not
+// move it.
+//
+// GetLastRelay requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+//
+// GetLastRelay ensures:
+// * err != nil || timestamp == nil || *timestamp > 0
+func (t *Txn) GetLastRelay(descriptor string) (timestamp *Timestamp,
+	err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess || t.access == RelayAccess) {
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || timestamp == nil || *timestamp > 0) {
+			panic("Violated: err != nil || timestamp == nil || *timestamp > 0")
+		}
+	}()
+
+	value, err := t.kv.get(lastRelayBucket, Descriptor(descriptor).Encode())
+	if err != nil {
+		err = fmt.Errorf("failed to get the last relay: %s", err.Error())
+		return
+	}
+
+	if value == nil {
+		return
+	}
+
+	if len(value) != 8 {
+		err = fmt.Errorf("expected the last relay of 8 bytes, got %d",
+			len(value))
+		return
+	}
+
+	ts := DecodeTimestamp(value)
+	if ts == 0 {
+		err = fmt.Errorf("expected a positive last relay, got 0")
+		return
+	}
+
+	timestamp = &ts
+	return
+}
+
+// PutLastRelay records the time when the last message of the channel has
+// been relayed. An earlier time than the recorded one is ignored so that
+// the messages relayed concurrently can not move it back.
+//
+// PutLastRelay requires:
+// * t.access == RelayAccess || t.access == ControlAccess
+// * timestamp > 0
+func (t *Txn) PutLastRelay(descriptor Descriptor,
+	timestamp Timestamp) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess || t.access == ControlAccess):
+		panic("Violated: t.access == RelayAccess || t.access == ControlAccess")
+	case !(timestamp > 0):
+		panic("Violated: timestamp > 0")
+	default:
+		// Pass
+	}
+
+	old, err := t.GetLastRelay(string(descriptor))
+	if err != nil {
+		return
+	}
+
+	if old != nil && *old >= timestamp {
+		return
+	}
+
+	err = t.kv.put(lastRelayBucket, descriptor.Encode(), timestamp.Encode())
+	if err != nil {
+		err = fmt.Errorf("failed to put the last relay: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// removeLastRelay removes the time of the last relayed message of
+// the channel.
+//
+// removeLastRelay requires:
+// * t.access == ControlAccess
+func (t *Txn) removeLastRelay(descriptor string) (err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	err = t.kv.remove(lastRelayBucket, Descriptor(descriptor).Encode())
+	if err != nil {
+		err = fmt.Errorf("failed to erase the last relay: %s", err.Error())
+		return
+	}
+
+	return
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestTxn_PutLastRelay(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+
+	get := func() (timestamp *Timestamp) {
+		err := s.View(func(txn *Txn) (txnErr error) {
+			timestamp, txnErr = txn.GetLastRelay("client-1")
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return
+	}
+
+	if got := get(); got != nil {
+		t.Fatalf("expected no last relay, got %d", *got)
+	}
+
+	for _, timestamp := range []Timestamp{1538476500123, 1538476400123} {
+		err := s.Update(func(txn *Txn) error {
+			return txn.PutLastRelay("client-1", timestamp)
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	// The earlier relay does not move the last relay back.
+	if got := get(); got == nil || *got != 1538476500123 {
+		t.Errorf("expected the last relay 1538476500123, got %v", got)
+	}
+
+	// The last relay is kept apart from the rate state.
+	err := s.View(func(txn *Txn) error {
+		state, err := txn.GetRateState("client-1")
+		if err == nil && state != nil {
+			t.Errorf("expected no rate state, got %v", state)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,462 @@
+// +build cgo
+
+package database
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+)
+
+// NewEnv creates a new database environment object with
+// the DefaultLMDBOptions.
+// The database directory is assumed to be already initialized.
+func NewEnv(access Access, path string) (e *Env, err error) {
+	return openEnv(access, path, 0, DefaultLMDBOptions())
+}
+
+// NewEnvWithOptions creates a new database environment object with
+// the given options.
+// The database directory is assumed to be already initialized.
+func NewEnvWithOptions(access Access, path string,
+	options LMDBOptions) (e *Env, err error) {
+	return openEnv(access, path, 0, options)
+}
+
+// openEnv creates a new database environment object opened with the given
+// LMDB flags.
+func openEnv(access Access, path string, flags uint,
+	options LMDBOptions) (e *Env, err error) {
+	err = options.Validate()
+	if err != nil {
+		return
+	}
+
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		err = fmt.Errorf("failed to initialize a database "+
+			"environment: %s", err)
+		return
+	}
+
+	err = env.SetMaxDBs(options.MaxDBs)
+	if err != nil {
+		err = fmt.Errorf("failed to set the max. number of DBs to %d: "+
+			"%s", options.MaxDBs, err)
+		closeErr := env.Close()
+		if closeErr != nil {
+			err = fmt.Errorf("%s; failed to close the database: "+
+				"%s", err, closeErr)
+		}
+		return
+	}
+
+	err = env.SetMapSize(options.MapSize)
+	if err != nil {
+		err = fmt.Errorf("failed to set the map size to "+
+			"%d: %s", options.MapSize, err)
+		closeErr := env.Close()
+		if closeErr != nil {
+			err = fmt.Errorf("%s; failed to close the "+
+				"database: %s", err, closeErr)
+		}
+		return
+	}
+
+	err = env.Open(path, flags, 0660)
+
+	if err != nil {
+		err = fmt.Errorf("failed to open the database in the "+
+			"directory %s: %s", path, err)
+		closeErr := env.Close()
+		if closeErr != nil {
+			err = fmt.Errorf("%s; failed to close the database: "+
+				"%s", err, closeErr)
+		}
+		return
+	}
+
+	e = &Env{
+		Path:    path,
+		env:     env,
+		Access:  access,
+		options: options}
+
+	return
+}
+
+// Env represents a database of channels.
+type Env struct {
+	Path   string
+	env    *lmdb.Env
+	Access Access
+
+	options LMDBOptions
+
+	// resizeMu is held shared by the transactions and exclusively while
+	// the memory map is resized, since LMDB can not resize the map during
+	// an active transaction.
+	resizeMu sync.RWMutex
+}
+
+// Initialize initializes the database environment in the given directory
+// with the DefaultLMDBOptions.
+// Initialize creates the expected database and should be called only once
+// during the deployment.
+//
+// The database is stamped with the current SchemaVersion.
+func Initialize(access Access, path string) (err error) {
+	return InitializeWithOptions(access, path, DefaultLMDBOptions())
+}
+
+// InitializeWithOptions initializes the database environment in the given
+// directory with the given options. See Initialize for details.
+func InitializeWithOptions(access Access, path string,
+	options LMDBOptions) (err error) {
+	_, err = os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("the directory of LMDB environment "+
+			"is expected to exist: %s", err.Error())
+		return
+	}
+
+	env, err := NewEnvWithOptions(access, path, options)
+	if err != nil {
+		return
+	}
+	defer func() {
+		err = env.env.Close()
+	}()
+
+	err = env.env.Update(func(txn *lmdb.Txn) (txnErr error) {
+		for _, name := range bucketNames {
+			_, txnErr = txn.OpenDBI(name, lmdb.Create)
+			if txnErr != nil {
+				return
+			}
+		}
+
+		txnErr = writeSchemaVersion(txn, SchemaVersion)
+		return
+	})
+
+	return
+}
+
+// newTxn wraps the LMDB transaction and opens the databases.
+func (e *Env) newTxn(lmdbTxn *lmdb.Txn) (txn *Txn, err error) {
+	kv := &lmdbKV{txn: lmdbTxn}
+	for b, name := range bucketNames {
+		kv.dbis[b], err = lmdbTxn.OpenDBI(name, 0)
+
+		// The relay log, the audit log, the revisions, the token usage,
+		// the usage counters, the quota counters, the lockouts, the queue
+		// and the last relays are missing in the databases which still
+		// need to be migrated to the schema versions 3, 4, 5, 6, 7, 9, 10,
+		// 11 and 12, respectively; the migrations create them.
+		if lmdb.IsNotFound(err) && bucket(b) > timestampBucket {
+			err = nil
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	txn = &Txn{kv: kv, access: e.Access}
+	return
+}
+
+// lmdbKV implements the key-value transaction on an LMDB transaction.
+type lmdbKV struct {
+	txn  *lmdb.Txn
+	dbis [bucketCount]lmdb.DBI
+
+	// mapFull is set if a put failed since the memory map is full.
+	mapFull bool
+}
+
+// create creates the LMDB database of the bucket if it does not exist.
+func (kv *lmdbKV) create(b bucket) (err error) {
+	kv.dbis[b], err = kv.txn.OpenDBI(bucketNames[b], lmdb.Create)
+	return
+}
+
+func (kv *lmdbKV) get(b bucket, key []byte) (value []byte, err error) {
+	value, err = kv.txn.Get(kv.dbis[b], key)
+	if lmdb.IsNotFound(err) {
+		value, err = nil, nil
+	}
+	return
+}
+
+func (kv *lmdbKV) put(b bucket, key []byte, value []byte) (err error) {
+	err = kv.txn.Put(kv.dbis[b], key, value, 0)
+	if lmdb.IsMapFull(err) {
+		kv.mapFull = true
+	}
+	return
+}
+
+func (kv *lmdbKV) remove(b bucket, key []byte) (err error) {
+	err = kv.txn.Del(kv.dbis[b], key, nil)
+	if lmdb.IsNotFound(err) {
+		err = nil
+	}
+	return
+}
+
+func (kv *lmdbKV) count(b bucket) (count uint64, err error) {
+	stat, err := kv.txn.Stat(kv.dbis[b])
+	if err != nil {
+		return
+	}
+
+	count = stat.Entries
+	return
+}
+
+func (kv *lmdbKV) seek(b bucket, key []byte,
+	fn func(key []byte, value []byte) (stop bool, err error)) (err error) {
+	cur, err := kv.txn.OpenCursor(kv.dbis[b])
+	if err != nil {
+		return
+	}
+	defer cur.Close()
+
+	var k, v []byte
+	if key == nil {
+		k, v, err = cur.Get(nil, nil, lmdb.First)
+	} else {
+		k, v, err = cur.Get(key, nil, lmdb.SetRange)
+	}
+
+	for {
+		if lmdb.IsNotFound(err) {
+			err = nil
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		var stop bool
+		stop, err = fn(k, v)
+		if err != nil || stop {
+			return
+		}
+
+		k, v, err = cur.Get(nil, nil, lmdb.Next)
+	}
+}
+
+// view executes a read-only LMDB transaction. The memory map is adopted
+// and the transaction retried if another process has grown the map.
+func (e *Env) view(fn lmdb.TxnOp) (err error) {
+	for {
+		// The lock is released even if fn panics so that the map can still grow.
+		err = func() error {
+			e.resizeMu.RLock()
+			defer e.resizeMu.RUnlock()
+
+			return e.env.View(fn)
+		}()
+
+		if !lmdb.IsMapResized(err) {
+			return
+		}
+
+		err = e.adoptMapSize()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// update executes a read-write LMDB transaction. The memory map is adopted
+// and the transaction retried if another process has grown the map.
+func (e *Env) update(fn lmdb.TxnOp) (err error) {
+	for {
+		// The lock is released even if fn panics so that the map can still grow.
+		err = func() error {
+			e.resizeMu.RLock()
+			defer e.resizeMu.RUnlock()
+
+			return e.env.Update(fn)
+		}()
+
+		if !lmdb.IsMapResized(err) {
+			return
+		}
+
+		err = e.adoptMapSize()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// adoptMapSize adopts the size of the memory map set by another process.
+func (e *Env) adoptMapSize() (err error) {
+	e.resizeMu.Lock()
+	defer e.resizeMu.Unlock()
+
+	err = e.env.SetMapSize(0)
+	if err != nil {
+		err = fmt.Errorf("failed to adopt the map size of the database %s: "+
+			"%s", e.Path, err.Error())
+	}
+	return
+}
+
+// grow doubles the size of the memory map up to the options.MaxMapSize.
+// It returns false if the map has already reached the maximum size.
+func (e *Env) grow() (grown bool, err error) {
+	e.resizeMu.Lock()
+	defer e.resizeMu.Unlock()
+
+	info, err := e.env.Info()
+	if err != nil {
+		return
+	}
+
+	if info.MapSize >= e.options.MaxMapSize {
+		return
+	}
+
+	size := 2 * info.MapSize
+	if size > e.options.MaxMapSize {
+		size = e.options.MaxMapSize
+	}
+
+	err = e.env.SetMapSize(size)
+	if err != nil {
+		err = fmt.Errorf("failed to grow the map of the database %s to %d "+
+			"bytes: %s", e.Path, size, err.Error())
+		return
+	}
+
+	grown = true
+	return
+}
+
+// MapUsage returns the used and the available size of the memory map.
+func (e *Env) MapUsage() (usage MapUsage, err error) {
+	e.resizeMu.RLock()
+	defer e.resizeMu.RUnlock()
+
+	info, err := e.env.Info()
+	if err != nil {
+		return
+	}
+
+	stat, err := e.env.Stat()
+	if err != nil {
+		return
+	}
+
+	usage = MapUsage{
+		Used: (info.LastPNO + 1) * int64(stat.PSize),
+		Size: info.MapSize}
+	return
+}
+
+// Update executes a read-write transaction.
+//
+// If the memory map is full, the map is grown up to the options.MaxMapSize
+// and fn is called again in a new transaction. Hence fn must not have
+// side effects outside of the transaction which can not be repeated.
+func (e *Env) Update(fn func(txn *Txn) error) (err error) {
+	for {
+		var kv *lmdbKV
+		err = e.update(func(lmdbTxn *lmdb.Txn) error {
+			txn, txnErr := e.newTxn(lmdbTxn)
+			if txnErr != nil {
+				return txnErr
+			}
+
+			kv = txn.kv.(*lmdbKV)
+			return fn(txn)
+		})
+
+		// The errors of the transaction methods do not preserve the LMDB
+		// error codes so the key-value transaction records the full map.
+		if err == nil || !(lmdb.IsMapFull(err) || (kv != nil && kv.mapFull)) {
+			return
+		}
+
+		grown, growErr := e.grow()
+		if growErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), growErr.Error())
+			return
+		}
+
+		if !grown {
+			err = fmt.Errorf("%s; the map of the database %s reached "+
+				"the max. size of %d bytes", err.Error(), e.Path,
+				e.options.MaxMapSize)
+			return
+		}
+
+		if e.options.OnGrow != nil {
+			usage, usageErr := e.MapUsage()
+			if usageErr == nil {
+				e.options.OnGrow(usage)
+			}
+		}
+	}
+}
+
+// View executes a read-only transaction.
+func (e *Env) View(fn func(txn *Txn) error) error {
+	return e.view(func(lmdbTxn *lmdb.Txn) error {
+		txn, err := e.newTxn(lmdbTxn)
+		if err != nil {
+			return err
+		}
+
+		return fn(txn)
+	})
+}
+
+// Close closes the database.
+func (e *Env) Close() error {
+	return e.env.Close()
+}
+
+// openLMDB opens the LMDB environment as a Database.
+func openLMDB(access Access, path string,
+	options LMDBOptions) (db Database, err error) {
+	e, err := NewEnvWithOptions(access, path, options)
+	if err != nil {
+		return
+	}
+
+	db = e
+	return
+}
+
+// openLMDBReadOnly opens the LMDB environment read-only.
+func openLMDBReadOnly(access Access, path string,
+	options LMDBOptions) (db Database, err error) {
+	e, err := openEnv(access, path, lmdb.Readonly, options)
+	if err != nil {
+		return
+	}
+
+	db = e
+	return
+}
+
+// initializeLMDB initializes the LMDB environment.
+func initializeLMDB(access Access, path string, options LMDBOptions) error {
+	return InitializeWithOptions(access, path, options)
+}
+
+// restoreLMDB restores the snapshot of an LMDB environment.
+func restoreLMDB(snapshotDir string, path string) (uint64, error) {
+	return Restore(snapshotDir, path)
+}
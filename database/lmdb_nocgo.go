@@ -0,0 +1,29 @@
+// +build !cgo
+
+package database
+
+import "errors"
+
+// errNoLMDB is returned on LMDB databases if the program has been built
+// without cgo.
+var errNoLMDB = errors.New("the lmdb database backend is not available " +
+	"since the program has been built without cgo; " +
+	"please use the bolt backend")
+
+func openLMDB(access Access, path string, options LMDBOptions) (Database,
+	error) {
+	return nil, errNoLMDB
+}
+
+func openLMDBReadOnly(access Access, path string, options LMDBOptions) (
+	Database, error) {
+	return nil, errNoLMDB
+}
+
+func initializeLMDB(access Access, path string, options LMDBOptions) error {
+	return errNoLMDB
+}
+
+func restoreLMDB(snapshotDir string, path string) (uint64, error) {
+	return 0, errNoLMDB
+}
@@ -0,0 +1,255 @@
+// +build cgo
+
+package database
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestLMDBOptions_Validate(t *testing.T) {
+	type testcase struct {
+		options LMDBOptions
+		valid   bool
+	}
+
+	testcases := []testcase{
+		{options: DefaultLMDBOptions(), valid: true},
+		{options: LMDBOptions{MapSize: 1 << 20, MaxMapSize: 1 << 20,
+			MaxDBs: minLMDBDBs}, valid: true},
+		{options: LMDBOptions{MapSize: 0, MaxMapSize: 1 << 20,
+			MaxDBs: minLMDBDBs}, valid: false},
+		{options: LMDBOptions{MapSize: 1 << 21, MaxMapSize: 1 << 20,
+			MaxDBs: minLMDBDBs}, valid: false},
+		{options: LMDBOptions{MapSize: 1 << 20, MaxMapSize: 1 << 20,
+			MaxDBs: 2}, valid: false},
+	}
+
+	for i, tc := range testcases {
+		err := tc.options.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("test case %d: expected no error, got: %s",
+				i, err.Error())
+		}
+
+		if !tc.valid && err == nil {
+			t.Errorf("test case %d: expected an error", i)
+		}
+	}
+}
+
+// fillRelayLog puts relay records with large subjects in a single
+// transaction so that the memory map of a small environment fills up.
+func fillRelayLog(e *Env, count int) error {
+	subject := strings.Repeat("x", 64*1024)
+	start := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	return e.Update(func(txn *Txn) (txnErr error) {
+		for i := 0; i < count; i++ {
+			txnErr = txn.PutRelayRecord(&protoed.RelayRecord{
+				Descriptor_: "client-1",
+				Time:        start.Add(time.Duration(i) * time.Second).UnixNano(),
+				Subject:     subject,
+				Outcome:     protoed.RelayRecord_RELAYED, Status: 200})
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+}
+
+func TestEnv_Grow(t *testing.T) {
+	var usages []MapUsage
+	options := LMDBOptions{
+		MapSize:    1 << 20,
+		MaxMapSize: 16 << 20,
+		MaxDBs:     minLMDBDBs,
+		OnGrow: func(usage MapUsage) {
+			usages = append(usages, usage)
+		}}
+
+	e, err := smallDatabase(options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(e.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = e.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	err = fillRelayLog(e, 64)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(usages) == 0 {
+		t.Fatalf("expected the map to grow")
+	}
+
+	usage, err := e.MapUsage()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if usage.Size <= options.MapSize || usage.Size > options.MaxMapSize {
+		t.Errorf("expected the map size between %d and %d, got %d",
+			options.MapSize, options.MaxMapSize, usage.Size)
+	}
+
+	if usage.Used > usage.Size || usage.Ratio() <= 0 || usage.Ratio() > 1 {
+		t.Errorf("expected a valid usage, got %s", usage.String())
+	}
+
+	err = e.View(func(txn *Txn) (txnErr error) {
+		count, txnErr := txn.CountRelayRecords()
+		if txnErr != nil {
+			return
+		}
+
+		if count != 64 {
+			t.Errorf("expected 64 relay records, got %d", count)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestEnv_Grow_Ceiling(t *testing.T) {
+	options := LMDBOptions{
+		MapSize:    1 << 20,
+		MaxMapSize: 2 << 20,
+		MaxDBs:     minLMDBDBs}
+
+	e, err := smallDatabase(options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(e.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = e.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	err = fillRelayLog(e, 64)
+	if err == nil {
+		t.Fatalf("expected an error when the map reaches the max. size")
+	}
+
+	if !strings.Contains(err.Error(), "max. size") {
+		t.Errorf("expected the error to mention the max. size, got: %s",
+			err.Error())
+	}
+
+	err = e.View(func(txn *Txn) (txnErr error) {
+		count, txnErr := txn.CountRelayRecords()
+		if txnErr != nil {
+			return
+		}
+
+		if count != 0 {
+			t.Errorf("expected the failed transaction to be discarded, "+
+				"got %d relay records", count)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestEnv_Grow_AfterPanic(t *testing.T) {
+	options := LMDBOptions{
+		MapSize:    1 << 20,
+		MaxMapSize: 16 << 20,
+		MaxDBs:     minLMDBDBs}
+
+	e, err := smallDatabase(options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(e.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = e.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	// The panics are recovered by net/http in the servers.
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected the transaction to panic")
+			}
+		}()
+
+		_ = e.Update(func(txn *Txn) error {
+			panic("simulated contract violation")
+		})
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fillRelayLog(e, 64)
+	}()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected the map to grow after a panicking transaction")
+	}
+}
+
+// smallDatabase creates an empty database with the given options.
+func smallDatabase(options LMDBOptions) (e *Env, err error) {
+	tmpdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		return
+	}
+
+	err = InitializeWithOptions(RelayAccess, tmpdir, options)
+	if err == nil {
+		e, err = NewEnvWithOptions(RelayAccess, tmpdir, options)
+	}
+	if err != nil {
+		removeErr := os.RemoveAll(tmpdir)
+		if removeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		}
+		return
+	}
+
+	return
+}
@@ -0,0 +1,351 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+const dbLockoutName = "lockout"
+
+// LockoutKind enumerates the subjects whose failed authentications are
+// tracked.
+type LockoutKind string
+
+const (
+	// LockoutIP tracks the failed authentications of a remote IP.
+	LockoutIP LockoutKind = "ip"
+
+	// LockoutDescriptor tracks the failed authentications of a descriptor.
+	LockoutDescriptor LockoutKind = "descriptor"
+)
+
+// LockoutKinds lists all the lockout kinds.
+var LockoutKinds = []LockoutKind{LockoutIP, LockoutDescriptor}
+
+// ParseLockoutKind parses the name of a lockout kind.
+func ParseLockoutKind(name string) (kind LockoutKind, err error) {
+	for _, k := range LockoutKinds {
+		if LockoutKind(name) == k {
+			kind = k
+			return
+		}
+	}
+
+	var names []string
+	for _, k := range LockoutKinds {
+		names = append(names, string(k))
+	}
+
+	err = fmt.Errorf("unknown lockout kind %#v, expected one of: %s",
+		name, strings.Join(names, ", "))
+	return
+}
+
+// LockoutPolicy defines when and for how long the subjects are locked out
+// after failed authentications.
+type LockoutPolicy struct {
+	// Threshold is the number of the consecutive failed authentications
+	// which locks the subject out. Zero disables the lockouts.
+	Threshold uint32
+
+	// Base is the duration of the first lockout. It doubles with each
+	// further failed authentication.
+	Base time.Duration
+
+	// Max caps the duration of a lockout; zero if unbounded.
+	Max time.Duration
+
+	// Forget is the duration without a failed authentication after which
+	// the failures of a subject are forgotten.
+	Forget time.Duration
+}
+
+// DefaultLockoutPolicy locks a subject out for a minute after five failed
+// authentications, for two minutes after six and so on up to a day.
+var DefaultLockoutPolicy = LockoutPolicy{
+	Threshold: 5,
+	Base:      time.Minute,
+	Max:       24 * time.Hour,
+	Forget:    24 * time.Hour}
+
+// IsEmpty indicates that the lockouts are disabled.
+func (p LockoutPolicy) IsEmpty() bool {
+	return p.Threshold == 0
+}
+
+// Duration returns the duration of the lockout after the given number of
+// consecutive failed authentications; zero if the subject is not locked out.
+func (p LockoutPolicy) Duration(failures uint32) time.Duration {
+	if p.IsEmpty() || failures < p.Threshold {
+		return 0
+	}
+
+	// Without a maximum, the duration is capped only so that it does not
+	// overflow.
+	d := p.Base
+	for i := p.Threshold; i < failures && (p.Max == 0 || d < p.Max); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+
+	if p.Max > 0 && d > p.Max {
+		d = p.Max
+	}
+	return d
+}
+
+// LockedOut indicates that the lockout is in effect at the given time.
+func LockedOut(lockout *protoed.Lockout, now time.Time) bool {
+	return lockout != nil && lockout.LockedUntil > now.UnixNano()
+}
+
+// lockoutKey encodes the key of a lockout as the kind followed by a zero
+// byte and the subject.
+func lockoutKey(kind LockoutKind, subject string) []byte {
+	return append(append([]byte(kind), 0), subject...)
+}
+
+// getLockout reads the lockout stored under the key; nil if there is none.
+func (t *Txn) getLockout(key []byte) (lockout *protoed.Lockout, err error) {
+	val, err := t.kv.get(lockoutBucket, key)
+	if err != nil {
+		err = fmt.Errorf("failed to get the lockout: %s", err.Error())
+		return
+	}
+
+	if val == nil {
+		return
+	}
+
+	lockout = &protoed.Lockout{}
+	err = proto.Unmarshal(val, lockout)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal the lockout: %s", err.Error())
+		lockout = nil
+		return
+	}
+
+	return
+}
+
+// GetLockout returns the failed authentications of the subject; nil if
+// none are tracked.
+//
+// GetLockout requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * kind == LockoutIP || kind == LockoutDescriptor
+func (t *Txn) GetLockout(kind LockoutKind, subject string) (
+	lockout *protoed.Lockout, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(kind == LockoutIP || kind == LockoutDescriptor):
+		panic("Violated: kind == LockoutIP || kind == LockoutDescriptor")
+	default:
+		// Pass
+	}
+
+	lockout, err = t.getLockout(lockoutKey(kind, subject))
+	return
+}
+
+// RecordAuthFailure counts a failed authentication of the subject and
+// locks it out according to the policy. The failures older than
+// the Forget duration of the policy are disregarded.
+//
+// RecordAuthFailure requires:
+// * t.access == RelayAccess
+// * kind == LockoutIP || kind == LockoutDescriptor
+// * !policy.IsEmpty()
+//
+// RecordAuthFailure ensures:
+// * err != nil || (lockout != nil && lockout.Failures > 0)
+func (t *Txn) RecordAuthFailure(kind LockoutKind, subject string,
+	now time.Time, policy LockoutPolicy) (lockout *protoed.Lockout, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(kind == LockoutIP || kind == LockoutDescriptor):
+		panic("Violated: kind == LockoutIP || kind == LockoutDescriptor")
+	case !(!policy.IsEmpty()):
+		panic("Violated: !policy.IsEmpty()")
+	default:
+		// Pass
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || (lockout != nil && lockout.Failures > 0)) {
+			panic("Violated: err != nil || (lockout != nil && lockout.Failures > 0)")
+		}
+	}()
+
+	key := lockoutKey(kind, subject)
+
+	stored, err := t.getLockout(key)
+	if err != nil {
+		return
+	}
+
+	if stored == nil || now.Sub(time.Unix(0, stored.LastFailure)) > policy.Forget {
+		stored = &protoed.Lockout{Kind: string(kind), Subject: subject}
+	}
+
+	stored.Failures++
+	stored.LastFailure = now.UnixNano()
+	if d := policy.Duration(stored.Failures); d > 0 {
+		stored.LockedUntil = now.Add(d).UnixNano()
+	}
+
+	serialized, err := proto.Marshal(stored)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the lockout: %s", err.Error())
+		return
+	}
+
+	err = t.kv.put(lockoutBucket, key, serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the lockout: %s", err.Error())
+		return
+	}
+
+	lockout = stored
+	return
+}
+
+// ClearLockout forgets the failed authentications of the subject and
+// lifts its lockout, if any.
+//
+// ClearLockout requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * kind == LockoutIP || kind == LockoutDescriptor
+func (t *Txn) ClearLockout(kind LockoutKind, subject string) (
+	removed bool, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(kind == LockoutIP || kind == LockoutDescriptor):
+		panic("Violated: kind == LockoutIP || kind == LockoutDescriptor")
+	default:
+		// Pass
+	}
+
+	key := lockoutKey(kind, subject)
+
+	val, err := t.kv.get(lockoutBucket, key)
+	if err != nil {
+		err = fmt.Errorf("failed to get the lockout: %s", err.Error())
+		return
+	}
+
+	if val == nil {
+		return
+	}
+
+	err = t.kv.remove(lockoutBucket, key)
+	if err != nil {
+		err = fmt.Errorf("failed to remove the lockout: %s", err.Error())
+		return
+	}
+
+	removed = true
+	return
+}
+
+// Lockouts lists the subjects locked out at the given time, ordered by
+// the kind and the subject.
+//
+// Lockouts requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+func (t *Txn) Lockouts(now time.Time) (lockouts []*protoed.Lockout, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess || t.access == RelayAccess) {
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	}
+
+	err = t.kv.seek(lockoutBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			lockout := &protoed.Lockout{}
+			seekErr = proto.Unmarshal(val, lockout)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the lockout: %s",
+					seekErr.Error())
+				return
+			}
+
+			if LockedOut(lockout, now) {
+				lockouts = append(lockouts, lockout)
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the lockouts: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// PruneLockouts removes the subjects which are not locked out at the given
+// time and whose failed authentications have been forgotten according to
+// the policy.
+//
+// PruneLockouts requires:
+// * t.access == RelayAccess
+func (t *Txn) PruneLockouts(now time.Time, policy LockoutPolicy) (
+	removed uint64, err error) {
+	// Pre-condition
+	if !(t.access == RelayAccess) {
+		panic("Violated: t.access == RelayAccess")
+	}
+
+	// The keys are collected first and removed after the iteration.
+	var obsolete [][]byte
+
+	err = t.kv.seek(lockoutBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			lockout := &protoed.Lockout{}
+			seekErr = proto.Unmarshal(val, lockout)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the lockout: %s",
+					seekErr.Error())
+				return
+			}
+
+			if !LockedOut(lockout, now) &&
+				now.Sub(time.Unix(0, lockout.LastFailure)) > policy.Forget {
+				// The key is only valid within the iteration.
+				obsolete = append(obsolete, append([]byte(nil), key...))
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the lockouts: %s",
+			err.Error())
+		return
+	}
+
+	for _, key := range obsolete {
+		err = t.kv.remove(lockoutBucket, key)
+		if err != nil {
+			err = fmt.Errorf("failed to remove the lockout: %s", err.Error())
+			return
+		}
+		removed++
+	}
+
+	return
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy_Duration(t *testing.T) {
+	policy := LockoutPolicy{
+		Threshold: 3,
+		Base:      time.Minute,
+		Max:       10 * time.Minute,
+		Forget:    time.Hour}
+
+	for _, tt := range []struct {
+		failures uint32
+		duration time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{1000, 10 * time.Minute}} {
+		if got := policy.Duration(tt.failures); got != tt.duration {
+			t.Errorf("expected the lockout of %s after %d failures, got %s",
+				tt.duration, tt.failures, got)
+		}
+	}
+
+	// Without a maximum, the lockout keeps doubling without overflowing.
+	unbounded := LockoutPolicy{Threshold: 3, Base: time.Minute}
+	for _, tt := range []struct {
+		failures uint32
+		duration time.Duration
+	}{
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{13, 1024 * time.Minute},
+		{1000, math.MaxInt64}} {
+		if got := unbounded.Duration(tt.failures); got != tt.duration {
+			t.Errorf("expected the unbounded lockout of %s after %d "+
+				"failures, got %s", tt.duration, tt.failures, got)
+		}
+	}
+
+	if got := (LockoutPolicy{}).Duration(100); got != 0 {
+		t.Errorf("expected no lockout with an empty policy, got %s", got)
+	}
+}
+
+func TestTxn_RecordAuthFailure(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	policy := LockoutPolicy{
+		Threshold: 2,
+		Base:      time.Minute,
+		Max:       time.Hour,
+		Forget:    time.Hour}
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		lockout, txnErr := txn.RecordAuthFailure(LockoutIP, "10.0.0.1", now,
+			policy)
+		if txnErr != nil {
+			return
+		}
+		if LockedOut(lockout, now) {
+			t.Errorf("expected no lockout after the first failure, got %v",
+				lockout)
+		}
+
+		lockout, txnErr = txn.RecordAuthFailure(LockoutIP, "10.0.0.1", now,
+			policy)
+		if txnErr != nil {
+			return
+		}
+		if lockout.LockedUntil != now.Add(time.Minute).UnixNano() {
+			t.Errorf("expected the lockout of a minute after the second "+
+				"failure, got %v", lockout)
+		}
+
+		// The failures of the descriptor are tracked separately.
+		lockout, txnErr = txn.RecordAuthFailure(LockoutDescriptor,
+			"10.0.0.1", now, policy)
+		if txnErr != nil {
+			return
+		}
+		if lockout.Failures != 1 {
+			t.Errorf("expected a single failure of the descriptor, got %v",
+				lockout)
+		}
+
+		// The failures are forgotten after a while.
+		later := now.Add(2 * time.Hour)
+		lockout, txnErr = txn.RecordAuthFailure(LockoutIP, "10.0.0.1", later,
+			policy)
+		if txnErr != nil {
+			return
+		}
+		if lockout.Failures != 1 || LockedOut(lockout, later) {
+			t.Errorf("expected the failures to have been forgotten, got %v",
+				lockout)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestTxn_Lockouts(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	policy := LockoutPolicy{
+		Threshold: 1,
+		Base:      time.Minute,
+		Max:       time.Hour,
+		Forget:    time.Hour}
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for _, subject := range []string{"client-1", "client-2"} {
+			_, txnErr = txn.RecordAuthFailure(LockoutDescriptor, subject, now,
+				policy)
+			if txnErr != nil {
+				return
+			}
+		}
+
+		_, txnErr = txn.RecordAuthFailure(LockoutIP, "10.0.0.1",
+			now.Add(-2*time.Hour), policy)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.Access = ControlAccess
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		lockouts, txnErr := txn.Lockouts(now)
+		if txnErr != nil {
+			return
+		}
+		if len(lockouts) != 2 || lockouts[0].Subject != "client-1" ||
+			lockouts[1].Subject != "client-2" {
+			t.Errorf("expected the two descriptors to be locked out, got %v",
+				lockouts)
+		}
+
+		removed, txnErr := txn.ClearLockout(LockoutDescriptor, "client-1")
+		if txnErr != nil {
+			return
+		}
+		if !removed {
+			t.Errorf("expected the lockout of client-1 to be removed")
+		}
+
+		removed, txnErr = txn.ClearLockout(LockoutDescriptor, "client-1")
+		if txnErr != nil {
+			return
+		}
+		if removed {
+			t.Errorf("expected no lockout of client-1 to remove")
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.Access = RelayAccess
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		// Only the forgotten failures of the IP are pruned.
+		removed, txnErr := txn.PruneLockouts(now, policy)
+		if txnErr != nil {
+			return
+		}
+		if removed != 1 {
+			t.Errorf("expected a single pruned lockout, got %d", removed)
+		}
+
+		lockout, txnErr := txn.GetLockout(LockoutDescriptor, "client-2")
+		if txnErr != nil {
+			return
+		}
+		if !LockedOut(lockout, now) {
+			t.Errorf("expected client-2 to be still locked out, got %v",
+				lockout)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// MemStore keeps the channels, the timestamps, the relay log and the audit
+// log in memory.
+//
+// The data is lost when the store is closed. MemStore is meant for the tests
+// and the embedded use where no database directory is available.
+type MemStore struct {
+	// Access defines the access rights of the transactions.
+	Access Access
+
+	mu      sync.RWMutex
+	buckets [bucketCount]*memBucket
+	closed  bool
+}
+
+// NewMemStore creates an empty in-memory store.
+func NewMemStore(access Access) *MemStore {
+	m := &MemStore{Access: access}
+	for i := range m.buckets {
+		m.buckets[i] = &memBucket{values: make(map[string][]byte)}
+	}
+	return m
+}
+
+// errMemStoreClosed is returned by the transactions of a closed store.
+var errMemStoreClosed = errors.New("the in-memory store has been closed")
+
+// View executes a read-only transaction.
+func (m *MemStore) View(fn func(txn *Txn) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return errMemStoreClosed
+	}
+
+	return fn(&Txn{kv: &memKV{store: m}, access: m.Access})
+}
+
+// Update executes a read-write transaction. The changes are rolled back if
+// fn returns an error or panics.
+func (m *MemStore) Update(fn func(txn *Txn) error) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errMemStoreClosed
+	}
+
+	kv := &memKV{store: m, writable: true}
+	committed := false
+	defer func() {
+		if !committed {
+			kv.rollback()
+		}
+	}()
+
+	err = fn(&Txn{kv: kv, access: m.Access})
+	if err != nil {
+		return
+	}
+
+	committed = true
+	return
+}
+
+// CheckSchemaVersion always succeeds since the in-memory store is created
+// with the current schema.
+func (m *MemStore) CheckSchemaVersion() error {
+	return nil
+}
+
+// Close discards the data of the store.
+func (m *MemStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	for i := range m.buckets {
+		m.buckets[i] = nil
+	}
+	return nil
+}
+
+// memBucket holds the entries of a bucket together with the sorted keys.
+type memBucket struct {
+	keys   []string
+	values map[string][]byte
+}
+
+// memUndo records the previous state of an entry changed in a transaction.
+type memUndo struct {
+	b       bucket
+	key     string
+	value   []byte
+	existed bool
+}
+
+// memKV implements the key-value transaction on a MemStore.
+type memKV struct {
+	store    *MemStore
+	writable bool
+	undo     []memUndo
+}
+
+// errReadOnly is returned on the changes within a read-only transaction.
+var errReadOnly = errors.New("the transaction is read-only")
+
+func (kv *memKV) get(b bucket, key []byte) (value []byte, err error) {
+	value = kv.store.buckets[b].values[string(key)]
+	return
+}
+
+func (kv *memKV) put(b bucket, key []byte, value []byte) error {
+	if !kv.writable {
+		return errReadOnly
+	}
+
+	bkt := kv.store.buckets[b]
+	k := string(key)
+
+	old, existed := bkt.values[k]
+	kv.undo = append(kv.undo, memUndo{b: b, key: k, value: old,
+		existed: existed})
+
+	bkt.set(k, append([]byte{}, value...))
+	return nil
+}
+
+func (kv *memKV) remove(b bucket, key []byte) error {
+	if !kv.writable {
+		return errReadOnly
+	}
+
+	bkt := kv.store.buckets[b]
+	k := string(key)
+
+	old, existed := bkt.values[k]
+	if !existed {
+		return nil
+	}
+
+	kv.undo = append(kv.undo, memUndo{b: b, key: k, value: old,
+		existed: true})
+
+	bkt.remove(k)
+	return nil
+}
+
+func (kv *memKV) count(b bucket) (uint64, error) {
+	return uint64(len(kv.store.buckets[b].keys)), nil
+}
+
+func (kv *memKV) seek(b bucket, key []byte,
+	fn func(key []byte, value []byte) (stop bool, err error)) error {
+	bkt := kv.store.buckets[b]
+
+	for i := sort.SearchStrings(bkt.keys, string(key)); i < len(bkt.keys); i++ {
+		k := bkt.keys[i]
+		stop, err := fn([]byte(k), bkt.values[k])
+		if err != nil || stop {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollback restores the entries changed in the transaction in the reverse
+// order of the changes.
+func (kv *memKV) rollback() {
+	for i := len(kv.undo) - 1; i >= 0; i-- {
+		u := kv.undo[i]
+		bkt := kv.store.buckets[u.b]
+
+		if !u.existed {
+			bkt.remove(u.key)
+			continue
+		}
+
+		bkt.set(u.key, u.value)
+	}
+	kv.undo = nil
+}
+
+// set sets the value of the key in the bucket.
+func (bkt *memBucket) set(key string, value []byte) {
+	if _, ok := bkt.values[key]; !ok {
+		i := sort.SearchStrings(bkt.keys, key)
+		bkt.keys = append(bkt.keys, "")
+		copy(bkt.keys[i+1:], bkt.keys[i:])
+		bkt.keys[i] = key
+	}
+
+	bkt.values[key] = value
+}
+
+// remove removes the key from the bucket, if it exists.
+func (bkt *memBucket) remove(key string) {
+	if _, ok := bkt.values[key]; !ok {
+		return
+	}
+
+	delete(bkt.values, key)
+	i := sort.SearchStrings(bkt.keys, key)
+	bkt.keys = append(bkt.keys[:i], bkt.keys[i+1:]...)
+}
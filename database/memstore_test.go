@@ -0,0 +1,303 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// exerciseStore runs the same sequence of operations on a store and checks
+// that the outcome is independent of the storage backend.
+func exerciseStore(s Store, setAccess func(access Access), t *testing.T) {
+	sender := protoed.Entity{Name: "Ludwig van Beethoven",
+		Email: "ludwig.van.beethoven@composers.com"}
+	recipients := []*protoed.Entity{
+		{Name: "Johannes Brahms", Email: "johannes.brahms@composers.com"}}
+
+	newChannel := func(descriptor string, minPeriod float32) *protoed.Channel {
+		return &protoed.Channel{Descriptor_: descriptor,
+			TokenHash: DummyTokenHash(),
+			Sender:    &sender, Recipients: recipients,
+			Domain:    "composers.com",
+			MinPeriod: minPeriod, MaxSize: 10000000}
+	}
+
+	descriptors := []string{"client-2", "client-1/pipeline-2",
+		"client-1/pipeline-1", "client-10"}
+
+	setAccess(ControlAccess)
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range descriptors {
+			txnErr = txn.PutChannel(newChannel(descriptor, 0.1))
+			if txnErr != nil {
+				return
+			}
+
+			ts := TimestampFromTime(time.Now())
+			txnErr = txn.PutTimestamp(Descriptor(descriptor), &ts)
+			if txnErr != nil {
+				return
+			}
+		}
+
+		// changing the min. period erases the timestamp
+		txnErr = txn.PutChannel(newChannel("client-10", 0.2))
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.RemoveChannel("client-2")
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		count, txnErr := txn.CountChannels()
+		if txnErr != nil {
+			return
+		}
+		if count != 3 {
+			t.Errorf("expected 3 channels, got %d", count)
+		}
+
+		count, txnErr = txn.CountTimestamps()
+		if txnErr != nil {
+			return
+		}
+		if count != 2 {
+			t.Errorf("expected 2 timestamps, got %d", count)
+		}
+
+		ts, txnErr := txn.GetTimestamp("client-10")
+		if txnErr != nil {
+			return
+		}
+		if ts != nil {
+			t.Errorf("expected no timestamp for client-10, got %v", *ts)
+		}
+
+		channels, txnErr := txn.ChannelPage(2, 2)
+		if txnErr != nil {
+			return
+		}
+		if len(channels) != 1 || channels[0].Descriptor_ != "client-10" {
+			t.Errorf("expected only client-10 on the second page, got %v",
+				channels)
+		}
+
+		channels, _, txnErr = txn.ChannelsAfter("client-1/pipeline-1", 10,
+			ChannelFilter{Prefix: "client-1/"})
+		if txnErr != nil {
+			return
+		}
+		if len(channels) != 1 || channels[0].Descriptor_ != "client-1/pipeline-2" {
+			t.Errorf("expected only client-1/pipeline-2 after "+
+				"client-1/pipeline-1, got %v", channels)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	setAccess(RelayAccess)
+	start := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		for i := 0; i < 3; i++ {
+			txnErr = txn.PutRelayRecord(&protoed.RelayRecord{
+				Descriptor_: "client-10", Time: start.UnixNano(),
+				Outcome: protoed.RelayRecord_RELAYED, Status: 200})
+			if txnErr != nil {
+				return
+			}
+		}
+
+		_, txnErr = txn.PruneRelayLog(RelayLogRetention{MaxCount: 2},
+			start)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		records, _, txnErr := txn.RelayRecords("client-10", time.Time{},
+			time.Time{}, 10)
+		if txnErr != nil {
+			return
+		}
+
+		var times []int64
+		for _, record := range records {
+			times = append(times, record.Time)
+		}
+
+		expected := []int64{start.UnixNano() + 1, start.UnixNano() + 2}
+		if !reflect.DeepEqual(expected, times) {
+			t.Errorf("expected the relay records at %v, got %v",
+				expected, times)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	setAccess(ControlAccess)
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range []string{"client-10", "client-11",
+			"client-10", "client-10"} {
+			txnErr = txn.PutAuditRecord(NewAuditRecord(
+				protoed.AuditRecord_PUT, nil,
+				&protoed.Channel{Descriptor_: descriptor},
+				Actor{Name: "test"}, start))
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		filter := AuditFilter{Descriptor: "client-10"}
+
+		records, more, txnErr := txn.AuditRecords(filter, 0, 2)
+		if txnErr != nil {
+			return
+		}
+
+		if len(records) != 2 || !more {
+			t.Fatalf("expected 2 audit records and more, got %d (more: %v)",
+				len(records), more)
+		}
+
+		records, more, txnErr = txn.AuditRecords(filter, records[1].Time, 2)
+		if txnErr != nil {
+			return
+		}
+
+		if len(records) != 1 || more ||
+			records[0].Time != start.UnixNano()+3 {
+			t.Errorf("expected the last audit record at %d, got %v "+
+				"(more: %v)", start.UnixNano()+3, records, more)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestStores(t *testing.T) {
+	m := NewMemStore(ControlAccess)
+	exerciseStore(m, func(access Access) { m.Access = access }, t)
+
+	b, err := emptyBolt(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(b.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	exerciseStore(b, func(access Access) { b.Access = access }, t)
+}
+
+func TestMemStore_Rollback(t *testing.T) {
+	m := NewMemStore(ControlAccess)
+
+	channel := &protoed.Channel{Descriptor_: "client-1",
+		TokenHash: DummyTokenHash(),
+		Sender:    &protoed.Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain:    "composers.com", MinPeriod: 0.1, MaxSize: 10000000}
+
+	err := m.Update(func(txn *Txn) error {
+		return txn.PutChannel(channel)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expectedErr := errors.New("some failure")
+	err = m.Update(func(txn *Txn) (txnErr error) {
+		txnErr = txn.RemoveChannel("client-1")
+		if txnErr != nil {
+			return
+		}
+
+		other := *channel
+		other.Descriptor_ = "client-2"
+		txnErr = txn.PutChannel(&other)
+		if txnErr != nil {
+			return
+		}
+
+		return expectedErr
+	})
+	if err != expectedErr {
+		t.Fatalf("expected the error %v, got %v", expectedErr, err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+
+		_ = m.Update(func(txn *Txn) error {
+			err := txn.RemoveChannel("client-1")
+			if err != nil {
+				return err
+			}
+			panic("some panic")
+		})
+	}()
+
+	err = m.View(func(txn *Txn) (txnErr error) {
+		channels, txnErr := txn.AllChannels()
+		if txnErr != nil {
+			return
+		}
+
+		if len(channels) != 1 || channels[0].String() != channel.String() {
+			t.Errorf("expected only the channel %s, got %v",
+				channel.String(), channels)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = m.View(func(txn *Txn) error {
+		return txn.kv.put(channelBucket, []byte("client-3"), []byte{})
+	})
+	if err != errReadOnly {
+		t.Errorf("expected the error %v on a read-only transaction, got %v",
+			errReadOnly, err)
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = m.View(func(txn *Txn) error { return nil })
+	if err != errMemStoreClosed {
+		t.Errorf("expected the error %v on a closed store, got %v",
+			errMemStoreClosed, err)
+	}
+}
@@ -0,0 +1,419 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+const dbQueueName = "queue"
+
+// DefaultMaxQueueDepth is the maximum number of the queued messages of
+// a channel which does not specify its max_queue_depth.
+const DefaultMaxQueueDepth = 100
+
+// MaxQueueDepth returns the maximum number of the queued messages of
+// the channel.
+func MaxQueueDepth(channel *protoed.Channel) uint64 {
+	if channel.MaxQueueDepth == 0 {
+		return DefaultMaxQueueDepth
+	}
+	return uint64(channel.MaxQueueDepth)
+}
+
+// FormatQueueID formats the identifier of a queued message as it is given
+// to the clients.
+func FormatQueueID(id uint64) string {
+	return fmt.Sprintf("%016x", id)
+}
+
+// ParseQueueID parses the identifier of a queued message given by
+// FormatQueueID.
+func ParseQueueID(s string) (id uint64, err error) {
+	if len(s) != 16 {
+		err = fmt.Errorf("expected 16 hexadecimal digits, got %d characters",
+			len(s))
+		return
+	}
+
+	id, err = strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the hexadecimal digits: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// queueKey encodes the key of a queued message as the descriptor followed
+// by a zero byte and the big-endian identifier so that the messages of
+// a channel are contiguous and ordered by the time they have been queued.
+func queueKey(descriptor string, id uint64) []byte {
+	key := make([]byte, len(descriptor)+1+8)
+	copy(key, descriptor)
+	binary.BigEndian.PutUint64(key[len(descriptor)+1:], id)
+	return key
+}
+
+// queuePrefix encodes the common prefix of the keys of all the queued
+// messages of a channel.
+func queuePrefix(descriptor string) []byte {
+	return append([]byte(descriptor), 0)
+}
+
+// unmarshalQueued parses a queued message as stored in the database.
+func unmarshalQueued(val []byte) (msg *protoed.QueuedMessage, err error) {
+	msg = &protoed.QueuedMessage{}
+	err = proto.Unmarshal(val, msg)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal the queued message: %s",
+			err.Error())
+		msg = nil
+		return
+	}
+
+	return
+}
+
+// seekQueued calls fn on the messages of the channel, including
+// the finished ones, in the order they have been queued.
+func (t *Txn) seekQueued(descriptor string,
+	fn func(msg *protoed.QueuedMessage) (stop bool)) (err error) {
+	prefix := queuePrefix(descriptor)
+
+	err = t.kv.seek(queueBucket, prefix,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if !bytes.HasPrefix(key, prefix) {
+				stop = true
+				return
+			}
+
+			msg, seekErr := unmarshalQueued(val)
+			if seekErr != nil {
+				return
+			}
+
+			stop = fn(msg)
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the queue of "+
+			"the descriptor %#v: %s", descriptor, err.Error())
+		return
+	}
+
+	return
+}
+
+// QueueDepth counts the messages of the channel waiting to be relayed.
+//
+// QueueDepth requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * !strings.Contains(descriptor, "\x00")
+func (t *Txn) QueueDepth(descriptor string) (depth uint64, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(!strings.Contains(descriptor, "\x00")):
+		panic("Violated: !strings.Contains(descriptor, \"\\x00\")")
+	default:
+		// Pass
+	}
+
+	err = t.seekQueued(descriptor, func(msg *protoed.QueuedMessage) bool {
+		if msg.Status == protoed.QueuedMessage_QUEUED {
+			depth++
+		}
+		return false
+	})
+	return
+}
+
+// Enqueue appends the message to the queue of its channel and assigns it
+// an identifier.
+//
+// The identifier is derived from the time when the message has been queued.
+// If the identifier is already taken, it is incremented until it is unique.
+//
+// Enqueue requires:
+// * t.access == RelayAccess
+// * msg != nil
+// * msg.Descriptor_ != ""
+// * !strings.Contains(msg.Descriptor_, "\x00")
+// * msg.Enqueued > 0
+// * msg.Status == protoed.QueuedMessage_QUEUED
+func (t *Txn) Enqueue(msg *protoed.QueuedMessage) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(msg != nil):
+		panic("Violated: msg != nil")
+	case !(msg.Descriptor_ != ""):
+		panic("Violated: msg.Descriptor_ != \"\"")
+	case !(!strings.Contains(msg.Descriptor_, "\x00")):
+		panic("Violated: !strings.Contains(msg.Descriptor_, \"\\x00\")")
+	case !(msg.Enqueued > 0):
+		panic("Violated: msg.Enqueued > 0")
+	case !(msg.Status == protoed.QueuedMessage_QUEUED):
+		panic("Violated: msg.Status == protoed.QueuedMessage_QUEUED")
+	default:
+		// Pass
+	}
+
+	id := uint64(msg.Enqueued)
+	for {
+		var existing []byte
+		existing, err = t.kv.get(queueBucket, queueKey(msg.Descriptor_, id))
+		if err != nil {
+			err = fmt.Errorf("failed to check for an existing queued "+
+				"message: %s", err.Error())
+			return
+		}
+
+		if existing == nil {
+			break
+		}
+		id++
+	}
+
+	msg.Id = id
+	err = t.PutQueued(msg)
+	return
+}
+
+// GetQueued returns the queued message of the channel; nil if there is
+// no message with the given identifier.
+//
+// GetQueued requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * !strings.Contains(descriptor, "\x00")
+func (t *Txn) GetQueued(descriptor string, id uint64) (
+	msg *protoed.QueuedMessage, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(!strings.Contains(descriptor, "\x00")):
+		panic("Violated: !strings.Contains(descriptor, \"\\x00\")")
+	default:
+		// Pass
+	}
+
+	val, err := t.kv.get(queueBucket, queueKey(descriptor, id))
+	if err != nil {
+		err = fmt.Errorf("failed to get the queued message: %s", err.Error())
+		return
+	}
+
+	if val == nil {
+		return
+	}
+
+	msg, err = unmarshalQueued(val)
+	return
+}
+
+// PutQueued stores the queued message under its identifier, overwriting
+// the previous state of the message.
+//
+// PutQueued requires:
+// * t.access == RelayAccess
+// * msg != nil
+// * msg.Descriptor_ != ""
+// * !strings.Contains(msg.Descriptor_, "\x00")
+func (t *Txn) PutQueued(msg *protoed.QueuedMessage) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(msg != nil):
+		panic("Violated: msg != nil")
+	case !(msg.Descriptor_ != ""):
+		panic("Violated: msg.Descriptor_ != \"\"")
+	case !(!strings.Contains(msg.Descriptor_, "\x00")):
+		panic("Violated: !strings.Contains(msg.Descriptor_, \"\\x00\")")
+	default:
+		// Pass
+	}
+
+	serialized, err := proto.Marshal(msg)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the queued message: %s",
+			err.Error())
+		return
+	}
+
+	err = t.kv.put(queueBucket, queueKey(msg.Descriptor_, msg.Id), serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the queued message: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// QueuePosition counts the messages of the channel waiting to be relayed
+// before the message with the given identifier.
+//
+// QueuePosition requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * !strings.Contains(descriptor, "\x00")
+func (t *Txn) QueuePosition(descriptor string, id uint64) (position uint64,
+	err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(!strings.Contains(descriptor, "\x00")):
+		panic("Violated: !strings.Contains(descriptor, \"\\x00\")")
+	default:
+		// Pass
+	}
+
+	err = t.seekQueued(descriptor, func(msg *protoed.QueuedMessage) bool {
+		if msg.Id >= id {
+			return true
+		}
+
+		if msg.Status == protoed.QueuedMessage_QUEUED {
+			position++
+		}
+		return false
+	})
+	return
+}
+
+// NextQueued returns the oldest message of the channel waiting to be
+// relayed; nil if there is none.
+//
+// NextQueued requires:
+// * t.access == RelayAccess
+// * !strings.Contains(descriptor, "\x00")
+//
+// NextQueued ensures:
+// * err != nil || msg == nil || msg.Status == protoed.QueuedMessage_QUEUED
+func (t *Txn) NextQueued(descriptor string) (msg *protoed.QueuedMessage,
+	err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(!strings.Contains(descriptor, "\x00")):
+		panic("Violated: !strings.Contains(descriptor, \"\\x00\")")
+	default:
+		// Pass
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || msg == nil || msg.Status == protoed.QueuedMessage_QUEUED) {
+			panic("Violated: err != nil || msg == nil || msg.Status == protoed.QueuedMessage_QUEUED")
+		}
+	}()
+
+	err = t.seekQueued(descriptor, func(queued *protoed.QueuedMessage) bool {
+		if queued.Status == protoed.QueuedMessage_QUEUED {
+			msg = queued
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		msg = nil
+		return
+	}
+
+	return
+}
+
+// QueuedDescriptors lists the descriptors of the channels with messages
+// waiting to be relayed in ascending order.
+//
+// QueuedDescriptors requires:
+// * t.access == RelayAccess
+func (t *Txn) QueuedDescriptors() (descriptors []string, err error) {
+	// Pre-condition
+	if !(t.access == RelayAccess) {
+		panic("Violated: t.access == RelayAccess")
+	}
+
+	err = t.kv.seek(queueBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			msg, seekErr := unmarshalQueued(val)
+			if seekErr != nil {
+				return
+			}
+
+			if msg.Status != protoed.QueuedMessage_QUEUED {
+				return
+			}
+
+			n := len(descriptors)
+			if n == 0 || descriptors[n-1] != msg.Descriptor_ {
+				descriptors = append(descriptors, msg.Descriptor_)
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the queue: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// PruneQueue removes the messages which have been relayed or given up
+// before the given time.
+//
+// PruneQueue requires:
+// * t.access == RelayAccess
+func (t *Txn) PruneQueue(before time.Time) (removed uint64, err error) {
+	// Pre-condition
+	if !(t.access == RelayAccess) {
+		panic("Violated: t.access == RelayAccess")
+	}
+
+	// The keys are collected first and removed after the iteration.
+	var obsolete [][]byte
+
+	err = t.kv.seek(queueBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			msg, seekErr := unmarshalQueued(val)
+			if seekErr != nil {
+				return
+			}
+
+			if msg.Status != protoed.QueuedMessage_QUEUED &&
+				msg.Finished < before.UnixNano() {
+				// The key is only valid within the iteration.
+				obsolete = append(obsolete, append([]byte(nil), key...))
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the queue: %s", err.Error())
+		return
+	}
+
+	for _, key := range obsolete {
+		err = t.kv.remove(queueBucket, key)
+		if err != nil {
+			err = fmt.Errorf("failed to remove the queued message: %s",
+				err.Error())
+			return
+		}
+		removed++
+	}
+
+	return
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestParseQueueID(t *testing.T) {
+	id := uint64(1538404620123456789)
+
+	parsed, err := ParseQueueID(FormatQueueID(id))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if parsed != id {
+		t.Errorf("expected the id %d, got %d", id, parsed)
+	}
+
+	for _, invalid := range []string{"", "155a", "155a6e1c6a6f2715x",
+		"zzzzzzzzzzzzzzzz"} {
+		if _, err = ParseQueueID(invalid); err == nil {
+			t.Errorf("expected an error for %#v, got nil", invalid)
+		}
+	}
+}
+
+func TestTxn_Enqueue(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	var msgs []*protoed.QueuedMessage
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		// The messages queued at the same time get distinct ids.
+		for _, subject := range []string{"first", "second", "third"} {
+			msg := &protoed.QueuedMessage{Descriptor_: "client-1",
+				Enqueued: now.UnixNano(), Subject: subject}
+			txnErr = txn.Enqueue(msg)
+			if txnErr != nil {
+				return
+			}
+			msgs = append(msgs, msg)
+		}
+
+		txnErr = txn.Enqueue(&protoed.QueuedMessage{Descriptor_: "client-2",
+			Enqueued: now.UnixNano(), Subject: "other"})
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if msgs[0].Id == msgs[1].Id || msgs[1].Id == msgs[2].Id {
+		t.Fatalf("expected distinct ids, got %v", msgs)
+	}
+
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		depth, txnErr := txn.QueueDepth("client-1")
+		if txnErr != nil {
+			return
+		}
+		if depth != 3 {
+			t.Errorf("expected the depth of 3, got %d", depth)
+		}
+
+		next, txnErr := txn.NextQueued("client-1")
+		if txnErr != nil {
+			return
+		}
+		if next == nil || next.Subject != "first" {
+			t.Fatalf("expected the first message to be next, got %v", next)
+		}
+
+		next.Status = protoed.QueuedMessage_RELAYED
+		next.Finished = now.Add(time.Minute).UnixNano()
+		txnErr = txn.PutQueued(next)
+		if txnErr != nil {
+			return
+		}
+
+		// The relayed messages are neither counted nor ahead in the queue.
+		depth, txnErr = txn.QueueDepth("client-1")
+		if txnErr != nil {
+			return
+		}
+		if depth != 2 {
+			t.Errorf("expected the depth of 2, got %d", depth)
+		}
+
+		position, txnErr := txn.QueuePosition("client-1", msgs[2].Id)
+		if txnErr != nil {
+			return
+		}
+		if position != 1 {
+			t.Errorf("expected the third message at the position 1, got %d",
+				position)
+		}
+
+		stored, txnErr := txn.GetQueued("client-1", msgs[0].Id)
+		if txnErr != nil {
+			return
+		}
+		if stored == nil || stored.Status != protoed.QueuedMessage_RELAYED {
+			t.Errorf("expected the first message to be relayed, got %v",
+				stored)
+		}
+
+		descriptors, txnErr := txn.QueuedDescriptors()
+		if txnErr != nil {
+			return
+		}
+		if len(descriptors) != 2 || descriptors[0] != "client-1" ||
+			descriptors[1] != "client-2" {
+			t.Errorf("expected both descriptors, got %v", descriptors)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestTxn_PruneQueue(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for i, status := range []protoed.QueuedMessage_Status{
+			protoed.QueuedMessage_RELAYED,
+			protoed.QueuedMessage_FAILED,
+			protoed.QueuedMessage_QUEUED} {
+			msg := &protoed.QueuedMessage{Descriptor_: "client-1",
+				Enqueued: now.Add(time.Duration(i) * time.Second).UnixNano()}
+			txnErr = txn.Enqueue(msg)
+			if txnErr != nil {
+				return
+			}
+
+			msg.Status = status
+			if status != protoed.QueuedMessage_QUEUED {
+				msg.Finished = now.Add(time.Duration(i) * time.Hour).UnixNano()
+			}
+			txnErr = txn.PutQueued(msg)
+			if txnErr != nil {
+				return
+			}
+		}
+
+		// Only the message relayed before the cut-off is removed.
+		removed, txnErr := txn.PruneQueue(now.Add(30 * time.Minute))
+		if txnErr != nil {
+			return
+		}
+		if removed != 1 {
+			t.Errorf("expected a single pruned message, got %d", removed)
+		}
+
+		// The queued messages are never pruned.
+		removed, txnErr = txn.PruneQueue(now.Add(24 * time.Hour))
+		if txnErr != nil {
+			return
+		}
+		if removed != 1 {
+			t.Errorf("expected a single pruned message, got %d", removed)
+		}
+
+		depth, txnErr := txn.QueueDepth("client-1")
+		if txnErr != nil {
+			return
+		}
+		if depth != 1 {
+			t.Errorf("expected the queued message to be kept, got %d", depth)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
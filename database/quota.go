@@ -0,0 +1,239 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+const dbQuotaName = "quota"
+
+// QuotaPeriod enumerates the calendar periods of the quotas.
+type QuotaPeriod string
+
+const (
+	// QuotaDay is a calendar day.
+	QuotaDay QuotaPeriod = "day"
+
+	// QuotaMonth is a calendar month.
+	QuotaMonth QuotaPeriod = "month"
+)
+
+// QuotaPeriods lists all the quota periods.
+var QuotaPeriods = []QuotaPeriod{QuotaDay, QuotaMonth}
+
+// Start returns the start of the period containing the given time in
+// the given location.
+func (p QuotaPeriod) Start(tm time.Time, loc *time.Location) time.Time {
+	year, month, day := tm.In(loc).Date()
+
+	switch p {
+	case QuotaDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case QuotaMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	default:
+		panic(fmt.Sprintf("unhandled quota period: %#v", p))
+	}
+}
+
+// Next returns the start of the period following the one containing
+// the given time in the given location, i.e., when the quota resets.
+func (p QuotaPeriod) Next(tm time.Time, loc *time.Location) time.Time {
+	start := p.Start(tm, loc)
+
+	switch p {
+	case QuotaDay:
+		return start.AddDate(0, 0, 1)
+	case QuotaMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		panic(fmt.Sprintf("unhandled quota period: %#v", p))
+	}
+}
+
+// code identifies the period in the keys of the quota counters.
+func (p QuotaPeriod) code() byte {
+	switch p {
+	case QuotaDay:
+		return 'd'
+	case QuotaMonth:
+		return 'm'
+	default:
+		panic(fmt.Sprintf("unhandled quota period: %#v", p))
+	}
+}
+
+// quotaKey encodes the key of the quota counters as the descriptor followed
+// by a zero byte and the code of the period.
+func quotaKey(descriptor string, period QuotaPeriod) []byte {
+	return append([]byte(descriptor), 0, period.code())
+}
+
+// QuotaCounters returns the messages of the channel counted against its
+// quota in the period starting at the given time. Only the counters of
+// the latest period are stored; the counters of an earlier period are
+// disregarded and empty counters are returned instead.
+//
+// QuotaCounters requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * period == QuotaDay || period == QuotaMonth
+//
+// QuotaCounters ensures:
+// * err != nil || (counters != nil && counters.Start == start.UnixNano())
+func (t *Txn) QuotaCounters(descriptor string, period QuotaPeriod,
+	start time.Time) (counters *protoed.QuotaCounters, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(period == QuotaDay || period == QuotaMonth):
+		panic("Violated: period == QuotaDay || period == QuotaMonth")
+	default:
+		// Pass
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || (counters != nil && counters.Start == start.UnixNano())) {
+			panic("Violated: err != nil || (counters != nil && counters.Start == start.UnixNano())")
+		}
+	}()
+
+	val, err := t.kv.get(quotaBucket, quotaKey(descriptor, period))
+	if err != nil {
+		err = fmt.Errorf("failed to get the quota counters: %s", err.Error())
+		return
+	}
+
+	stored := &protoed.QuotaCounters{}
+	if val != nil {
+		err = proto.Unmarshal(val, stored)
+		if err != nil {
+			err = fmt.Errorf("failed to unmarshal the quota counters: %s",
+				err.Error())
+			return
+		}
+	}
+
+	if stored.Start != start.UnixNano() {
+		stored = &protoed.QuotaCounters{Start: start.UnixNano()}
+	}
+
+	counters = stored
+	return
+}
+
+// CountQuota counts a message of the given size against the quota of
+// the channel in the period starting at the given time. The counters of
+// an earlier period are replaced.
+//
+// The counters are kept after the channel has been removed so that
+// the quotas can not be circumvented by re-creating the channel.
+//
+// CountQuota requires:
+// * t.access == RelayAccess
+// * period == QuotaDay || period == QuotaMonth
+// * !strings.Contains(descriptor, "\x00")
+// * size >= 0
+func (t *Txn) CountQuota(descriptor string, period QuotaPeriod,
+	start time.Time, size int64) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(period == QuotaDay || period == QuotaMonth):
+		panic("Violated: period == QuotaDay || period == QuotaMonth")
+	case !(!strings.Contains(descriptor, "\x00")):
+		panic("Violated: !strings.Contains(descriptor, \"\\x00\")")
+	case !(size >= 0):
+		panic("Violated: size >= 0")
+	default:
+		// Pass
+	}
+
+	counters, err := t.QuotaCounters(descriptor, period, start)
+	if err != nil {
+		return
+	}
+
+	counters.Messages++
+	counters.Bytes += uint64(size)
+
+	serialized, err := proto.Marshal(counters)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the quota counters: %s",
+			err.Error())
+		return
+	}
+
+	err = t.kv.put(quotaBucket, quotaKey(descriptor, period), serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the quota counters: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// UncountQuota gives a message of the given size counted by CountQuota
+// back to the quota of the channel in the period starting at the given
+// time. Nothing is given back if the period has passed in the meantime
+// since the counters of the later period do not include the message.
+//
+// UncountQuota requires:
+// * t.access == RelayAccess
+// * period == QuotaDay || period == QuotaMonth
+// * !strings.Contains(descriptor, "\x00")
+// * size >= 0
+func (t *Txn) UncountQuota(descriptor string, period QuotaPeriod,
+	start time.Time, size int64) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(period == QuotaDay || period == QuotaMonth):
+		panic("Violated: period == QuotaDay || period == QuotaMonth")
+	case !(!strings.Contains(descriptor, "\x00")):
+		panic("Violated: !strings.Contains(descriptor, \"\\x00\")")
+	case !(size >= 0):
+		panic("Violated: size >= 0")
+	default:
+		// Pass
+	}
+
+	counters, err := t.QuotaCounters(descriptor, period, start)
+	if err != nil {
+		return
+	}
+
+	if counters.Messages == 0 {
+		return
+	}
+
+	counters.Messages--
+	if counters.Bytes >= uint64(size) {
+		counters.Bytes -= uint64(size)
+	} else {
+		counters.Bytes = 0
+	}
+
+	serialized, err := proto.Marshal(counters)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the quota counters: %s",
+			err.Error())
+		return
+	}
+
+	err = t.kv.put(quotaBucket, quotaKey(descriptor, period), serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the quota counters: %s", err.Error())
+		return
+	}
+
+	return
+}
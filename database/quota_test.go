@@ -0,0 +1,140 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaPeriod_Start(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// It is already the 1st of November in Zurich.
+	tm := time.Date(2018, 10, 31, 23, 30, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		period QuotaPeriod
+		loc    *time.Location
+		start  time.Time
+		next   time.Time
+	}{
+		{QuotaDay, time.UTC,
+			time.Date(2018, 10, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{QuotaMonth, time.UTC,
+			time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{QuotaDay, zurich,
+			time.Date(2018, 11, 1, 0, 0, 0, 0, zurich),
+			time.Date(2018, 11, 2, 0, 0, 0, 0, zurich)},
+		{QuotaMonth, zurich,
+			time.Date(2018, 11, 1, 0, 0, 0, 0, zurich),
+			time.Date(2018, 12, 1, 0, 0, 0, 0, zurich)}} {
+		if got := tt.period.Start(tm, tt.loc); !got.Equal(tt.start) {
+			t.Errorf("expected the %s in %s to start at %s, got %s",
+				tt.period, tt.loc, tt.start, got)
+		}
+
+		if got := tt.period.Next(tm, tt.loc); !got.Equal(tt.next) {
+			t.Errorf("expected the %s in %s to reset at %s, got %s",
+				tt.period, tt.loc, tt.next, got)
+		}
+	}
+}
+
+func TestTxn_QuotaCounters(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	day := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for _, size := range []int64{100, 50} {
+			txnErr = txn.CountQuota("client-1", QuotaDay, day, size)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.Access = ControlAccess
+	err = s.View(func(txn *Txn) (txnErr error) {
+		counters, txnErr := txn.QuotaCounters("client-1", QuotaDay, day)
+		if txnErr != nil {
+			return
+		}
+
+		if counters.Messages != 2 || counters.Bytes != 150 {
+			t.Errorf("expected 2 messages with 150 bytes, got %v", counters)
+		}
+
+		// The counters of the month are kept separately.
+		counters, txnErr = txn.QuotaCounters("client-1", QuotaMonth, day)
+		if txnErr != nil {
+			return
+		}
+
+		if counters.Messages != 0 || counters.Bytes != 0 {
+			t.Errorf("expected empty monthly counters, got %v", counters)
+		}
+
+		// The counters of the previous day are disregarded on the next day.
+		next := day.AddDate(0, 0, 1)
+		counters, txnErr = txn.QuotaCounters("client-1", QuotaDay, next)
+		if txnErr != nil {
+			return
+		}
+
+		if counters.Start != next.UnixNano() || counters.Messages != 0 {
+			t.Errorf("expected empty counters of the next day, got %v",
+				counters)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestTxn_UncountQuota(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	day := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for _, size := range []int64{100, 50} {
+			txnErr = txn.CountQuota("client-1", QuotaDay, day, size)
+			if txnErr != nil {
+				return
+			}
+		}
+
+		txnErr = txn.UncountQuota("client-1", QuotaDay, day, 50)
+		if txnErr != nil {
+			return
+		}
+
+		// Nothing is given back to the counters of a later period.
+		txnErr = txn.UncountQuota("client-1", QuotaDay, day.AddDate(0, 0, 1),
+			100)
+		if txnErr != nil {
+			return
+		}
+
+		counters, txnErr := txn.QuotaCounters("client-1", QuotaDay, day)
+		if txnErr != nil {
+			return
+		}
+
+		if counters.Messages != 1 || counters.Bytes != 100 {
+			t.Errorf("expected a message with 100 bytes, got %v", counters)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// GetRateState returns the state of the token bucket of the channel, or nil
+// if no message has been relayed through the channel yet.
+//
+// The states are stored in the timestamp database in place of the times of
+// the last relayed messages.
+//
+// GetRateState requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+//
+// GetRateState ensures:
+// * err != nil || state == nil || state.Time > 0
+func (t *Txn) GetRateState(descriptor string) (
+	state *protoed.RateState, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess || t.access == RelayAccess) {
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || state == nil || state.Time > 0) {
+			panic("Violated: err != nil || state == nil || state.Time > 0")
+		}
+	}()
+
+	value, err := t.kv.get(timestampBucket, Descriptor(descriptor).Encode())
+	if err != nil {
+		err = fmt.Errorf("failed to get the rate state: %s", err.Error())
+		return
+	}
+
+	if value == nil {
+		return
+	}
+
+	state, err = decodeRateState(value)
+	if err != nil {
+		state = nil
+		return
+	}
+
+	return
+}
+
+// PutRateState stores the state of the token bucket of the channel.
+//
+// PutRateState requires:
+// * t.access == RelayAccess || t.access == ControlAccess
+// * state != nil
+// * state.Time > 0
+func (t *Txn) PutRateState(descriptor Descriptor,
+	state *protoed.RateState) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess || t.access == ControlAccess):
+		panic("Violated: t.access == RelayAccess || t.access == ControlAccess")
+	case !(state != nil):
+		panic("Violated: state != nil")
+	case !(state.Time > 0):
+		panic("Violated: state.Time > 0")
+	default:
+		// Pass
+	}
+
+	serialized, err := proto.Marshal(state)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the rate state: %s", err.Error())
+		return
+	}
+
+	err = t.kv.put(timestampBucket, descriptor.Encode(), serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the rate state: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// decodeRateState decodes a state of a token bucket as stored in
+// the timestamp database.
+func decodeRateState(value []byte) (state *protoed.RateState, err error) {
+	state = &protoed.RateState{}
+	err = proto.Unmarshal(value, state)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal the rate state: %s",
+			err.Error())
+		return
+	}
+
+	if state.Time <= 0 {
+		err = fmt.Errorf("expected a positive time of the rate state, got %d",
+			state.Time)
+		return
+	}
+
+	return
+}
+
+// stateTimestamp returns the time of the last relayed message of the state.
+func stateTimestamp(state *protoed.RateState) Timestamp {
+	return Timestamp(state.Time)
+}
+
+// convertTimestamps replaces the times of the last relayed messages in
+// the timestamp database with the states of the token buckets which were
+// emptied at those times. The values which are not timestamps are left
+// as-is.
+func (t *Txn) convertTimestamps() (err error) {
+	var keys, values [][]byte
+
+	// The entries are collected first and replaced after the iteration.
+	err = t.kv.seek(timestampBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if len(val) != 8 {
+				return
+			}
+
+			keys = append(keys, append([]byte(nil), key...))
+			values = append(values, append([]byte(nil), val...))
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the timestamps: %s",
+			err.Error())
+		return
+	}
+
+	for i, key := range keys {
+		state := &protoed.RateState{
+			Time: int64(DecodeTimestamp(values[i]))}
+
+		var serialized []byte
+		serialized, err = proto.Marshal(state)
+		if err != nil {
+			err = fmt.Errorf("failed to marshal the rate state: %s",
+				err.Error())
+			return
+		}
+
+		err = t.kv.put(timestampBucket, key, serialized)
+		if err != nil {
+			err = fmt.Errorf("failed to put the rate state: %s",
+				err.Error())
+			return
+		}
+	}
+
+	return
+}
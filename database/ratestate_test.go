@@ -0,0 +1,85 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestTxn_RateState(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	channel := &protoed.Channel{Descriptor_: "client-1",
+		TokenHash: DummyTokenHash(),
+		RateLimit: &protoed.RateLimit{Rate: 1, Burst: 3}}
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		return txn.PutChannel(channel)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	getState := func() (state *protoed.RateState) {
+		err := s.View(func(txn *Txn) (txnErr error) {
+			state, txnErr = txn.GetRateState("client-1")
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return
+	}
+
+	if got := getState(); got != nil {
+		t.Fatalf("expected no rate state of a fresh channel, got %v", got)
+	}
+
+	expected := &protoed.RateState{
+		Time: int64(TimestampFromTime(now)), Allowance: 1.5}
+
+	s.Access = RelayAccess
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		return txn.PutRateState("client-1", expected)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.Access = ControlAccess
+	if got := getState(); !proto.Equal(expected, got) {
+		t.Fatalf("expected the rate state %v, got %v", expected, got)
+	}
+
+	var ts *Timestamp
+	err = s.View(func(txn *Txn) (txnErr error) {
+		ts, txnErr = txn.GetTimestamp("client-1")
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if ts == nil || *ts != TimestampFromTime(now) {
+		t.Fatalf("expected the timestamp %d of the rate state, got %v",
+			TimestampFromTime(now), ts)
+	}
+
+	// Changing the rate limit erases the rate state.
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		changed := *channel
+		changed.RateLimit = &protoed.RateLimit{Rate: 2, Burst: 3}
+		return txn.PutChannel(&changed)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if got := getState(); got != nil {
+		t.Fatalf("expected the rate state to be erased, got %v", got)
+	}
+}
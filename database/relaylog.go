@@ -0,0 +1,372 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/dbc"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+const dbRelayLogName = "relaylog"
+
+// relayLogKey encodes the key of a relay record as the descriptor followed
+// by a zero byte and the big-endian time in nanoseconds so that the records
+// of a channel are contiguous and ordered by time.
+func relayLogKey(descriptor string, nanos int64) []byte {
+	key := make([]byte, len(descriptor)+1+8)
+	copy(key, descriptor)
+	binary.BigEndian.PutUint64(key[len(descriptor)+1:], uint64(nanos))
+	return key
+}
+
+// relayLogPrefix encodes the common prefix of the keys of all the relay
+// records of a channel.
+func relayLogPrefix(descriptor string) []byte {
+	return append([]byte(descriptor), 0)
+}
+
+// decodeRelayLogKey splits the key of a relay record into the descriptor
+// and the time in nanoseconds.
+func decodeRelayLogKey(key []byte) (descriptor string, nanos int64,
+	err error) {
+	if len(key) < 9 || key[len(key)-9] != 0 {
+		err = fmt.Errorf("invalid key of a relay record: %q", key)
+		return
+	}
+
+	descriptor = string(key[:len(key)-9])
+	nanos = int64(binary.BigEndian.Uint64(key[len(key)-8:]))
+	return
+}
+
+// RelayLogRetention defines which relay records are kept in the database.
+// Zero fields keep the records indefinitely.
+type RelayLogRetention struct {
+	// MaxAge is the age after which a record is pruned.
+	MaxAge time.Duration
+
+	// MaxCount is the number of the most recent records kept per channel.
+	MaxCount uint
+}
+
+// IsEmpty indicates that the retention keeps all the records.
+func (r RelayLogRetention) IsEmpty() bool {
+	return r.MaxAge == 0 && r.MaxCount == 0
+}
+
+// PutRelayRecord appends the record of a relay attempt to the relay log.
+//
+// If a record of the channel already exists at the very same time,
+// the time of the record is shifted by nanoseconds until it is unique.
+//
+// PutRelayRecord requires:
+// * t.access == RelayAccess
+// * record != nil
+// * record.Descriptor_ != ""
+// * !strings.Contains(record.Descriptor_, "\x00")
+// * record.Time > 0
+//
+// PutRelayRecord preamble:
+//  oldCount := uint64(0)
+//  if dbc.InTest {
+//  	oldCount = t.mustCountRl()
+//  }
+//
+// PutRelayRecord ensures:
+// * !dbc.InTest || err != nil || t.mustCountRl() == oldCount+1
+func (t *Txn) PutRelayRecord(record *protoed.RelayRecord) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(record != nil):
+		panic("Violated: record != nil")
+	case !(record.Descriptor_ != ""):
+		panic("Violated: record.Descriptor_ != \"\"")
+	case !(!strings.Contains(record.Descriptor_, "\x00")):
+		panic("Violated: !strings.Contains(record.Descriptor_, \"\\x00\")")
+	case !(record.Time > 0):
+		panic("Violated: record.Time > 0")
+	default:
+		// Pass
+	}
+
+	// Preamble starts.
+	oldCount := uint64(0)
+	if dbc.InTest {
+		oldCount = t.mustCountRl()
+	}
+	// Preamble ends.
+
+	// Post-condition
+	defer func() {
+		if !(!dbc.InTest || err != nil || t.mustCountRl() == oldCount+1) {
+			panic("Violated: !dbc.InTest || err != nil || t.mustCountRl() == oldCount+1")
+		}
+	}()
+
+	key := relayLogKey(record.Descriptor_, record.Time)
+	for {
+		var existing []byte
+		existing, err = t.kv.get(relayLogBucket, key)
+		if err != nil {
+			err = fmt.Errorf("failed to check for an existing relay "+
+				"record: %s", err.Error())
+			return
+		}
+
+		if existing == nil {
+			break
+		}
+
+		record.Time++
+		key = relayLogKey(record.Descriptor_, record.Time)
+	}
+
+	serialized, err := proto.Marshal(record)
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the relay record: %s",
+			err.Error())
+		return
+	}
+
+	err = t.kv.put(relayLogBucket, key, serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the relay record: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// RelayRecords returns the records of the channel in the time range
+// [since, until) ordered by time. A zero since or until leaves the range
+// open on that side.
+//
+// At most limit records are returned; more indicates that further records
+// follow in the range.
+//
+// RelayRecords requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * limit > 0
+// * since.IsZero() || until.IsZero() || !until.Before(since)
+//
+// RelayRecords ensures:
+// * err != nil || uint(len(records)) <= limit
+// * err != nil || !more || uint(len(records)) == limit
+func (t *Txn) RelayRecords(descriptor string, since time.Time,
+	until time.Time, limit uint) (records []*protoed.RelayRecord,
+	more bool, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(limit > 0):
+		panic("Violated: limit > 0")
+	case !(since.IsZero() || until.IsZero() || !until.Before(since)):
+		panic("Violated: since.IsZero() || until.IsZero() || !until.Before(since)")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || uint(len(records)) <= limit):
+			panic("Violated: err != nil || uint(len(records)) <= limit")
+		case !(err != nil || !more || uint(len(records)) == limit):
+			panic("Violated: err != nil || !more || uint(len(records)) == limit")
+		default:
+			// Pass
+		}
+	}()
+
+	prefix := relayLogPrefix(descriptor)
+
+	start := prefix
+	if !since.IsZero() && since.UnixNano() > 0 {
+		start = relayLogKey(descriptor, since.UnixNano())
+	}
+
+	err = t.kv.seek(relayLogBucket, start,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if !bytes.HasPrefix(key, prefix) || len(key) != len(prefix)+8 {
+				// past the records of the channel
+				stop = true
+				return
+			}
+
+			_, nanos, seekErr := decodeRelayLogKey(key)
+			if seekErr != nil {
+				return
+			}
+
+			if !until.IsZero() && nanos >= until.UnixNano() {
+				stop = true
+				return
+			}
+
+			if uint(len(records)) == limit {
+				more = true
+				stop = true
+				return
+			}
+
+			record := &protoed.RelayRecord{}
+			seekErr = proto.Unmarshal(val, record)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the relay "+
+					"record: %s", seekErr.Error())
+				return
+			}
+			records = append(records, record)
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the relay log: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// CountRelayRecords returns the total number of records in the relay log.
+//
+// CountRelayRecords requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+func (t *Txn) CountRelayRecords() (count uint64, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess || t.access == RelayAccess) {
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	}
+
+	count, err = t.kv.count(relayLogBucket)
+	if err != nil {
+		err = fmt.Errorf("failed to count the relay records: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// PruneRelayLog removes the records which are older than the maximum age
+// with respect to now as well as the records which exceed the maximum count
+// of their channel, starting with the oldest.
+//
+// PruneRelayLog requires:
+// * t.access == RelayAccess
+//
+// PruneRelayLog preamble:
+//  oldCount := uint64(0)
+//  if dbc.InTest {
+//  	oldCount = t.mustCountRl()
+//  }
+//
+// PruneRelayLog ensures:
+// * !dbc.InTest || err != nil || t.mustCountRl() == oldCount-removed
+func (t *Txn) PruneRelayLog(retention RelayLogRetention,
+	now time.Time) (removed uint64, err error) {
+	// Pre-condition
+	if !(t.access == RelayAccess) {
+		panic("Violated: t.access == RelayAccess")
+	}
+
+	// Preamble starts.
+	oldCount := uint64(0)
+	if dbc.InTest {
+		oldCount = t.mustCountRl()
+	}
+	// Preamble ends.
+
+	// Post-condition
+	defer func() {
+		if !(!dbc.InTest || err != nil || t.mustCountRl() == oldCount-removed) {
+			panic("Violated: !dbc.InTest || err != nil || t.mustCountRl() == oldCount-removed")
+		}
+	}()
+
+	if retention.IsEmpty() {
+		return
+	}
+
+	cutoff := int64(0)
+	if retention.MaxAge > 0 {
+		cutoff = now.Add(-retention.MaxAge).UnixNano()
+	}
+
+	// The keys are collected first and removed after the iteration.
+	var obsolete [][]byte
+
+	// group holds the keys of the current channel, ordered by time.
+	var group [][]byte
+	groupDescriptor := ""
+
+	flush := func() {
+		keep := len(group)
+		if retention.MaxCount > 0 && uint(keep) > retention.MaxCount {
+			keep = int(retention.MaxCount)
+		}
+		for i, key := range group {
+			_, nanos, _ := decodeRelayLogKey(key)
+			if i < len(group)-keep || nanos < cutoff {
+				obsolete = append(obsolete, key)
+			}
+		}
+		group = group[:0]
+	}
+
+	err = t.kv.seek(relayLogBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			descriptor, _, seekErr := decodeRelayLogKey(key)
+			if seekErr != nil {
+				return
+			}
+
+			if descriptor != groupDescriptor {
+				flush()
+				groupDescriptor = descriptor
+			}
+
+			// The key is only valid within the iteration.
+			group = append(group, append([]byte(nil), key...))
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the relay log: %s",
+			err.Error())
+		return
+	}
+	flush()
+
+	for _, key := range obsolete {
+		err = t.kv.remove(relayLogBucket, key)
+		if err != nil {
+			err = fmt.Errorf("failed to remove the relay record: %s",
+				err.Error())
+			return
+		}
+		removed++
+	}
+
+	return
+}
+
+// mustCountRl returns the count of entries in the relay log database.
+// In case of error, it panics.
+func (t *Txn) mustCountRl() uint64 {
+
+	count, getErr := t.CountRelayRecords()
+	if getErr != nil {
+		panic(fmt.Sprintf("failed to get the relay records count: %s", getErr.Error()))
+	}
+
+	return count
+}
@@ -0,0 +1,236 @@
+// +build cgo
+
+package database
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestRelayLogKey(t *testing.T) {
+	key := relayLogKey("client-1/pipeline-3", 1234567890)
+
+	descriptor, nanos, err := decodeRelayLogKey(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if descriptor != "client-1/pipeline-3" || nanos != 1234567890 {
+		t.Errorf("expected (client-1/pipeline-3, 1234567890), got (%s, %d)",
+			descriptor, nanos)
+	}
+
+	_, _, err = decodeRelayLogKey([]byte("client-1/pipeline-3"))
+	if err == nil {
+		t.Errorf("expected an error on a key without the time")
+	}
+}
+
+func TestTxn_RelayRecords(t *testing.T) {
+	d, err := emptyDatabase(RelayAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	start := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	err = d.Update(func(txn *Txn) (txnErr error) {
+		for i, descriptor := range []string{"client-1", "client-10",
+			"client-1", "client-1", "client-2"} {
+			txnErr = txn.PutRelayRecord(&protoed.RelayRecord{
+				Descriptor_: descriptor,
+				Time:        start.Add(time.Duration(i) * time.Minute).UnixNano(),
+				Subject:     "an alert", Size: 42,
+				Outcome: protoed.RelayRecord_RELAYED, Status: 200,
+				MessageId: "<20181001143700.1.1@marketing.composers.com>"})
+			if txnErr != nil {
+				return
+			}
+		}
+
+		// records at the very same time are kept apart
+		txnErr = txn.PutRelayRecord(&protoed.RelayRecord{
+			Descriptor_: "client-2", Time: start.Add(4 * time.Minute).UnixNano(),
+			Outcome: protoed.RelayRecord_TOO_SOON, Status: 429})
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	minute := func(i int) time.Time {
+		return start.Add(time.Duration(i) * time.Minute)
+	}
+
+	type testcase struct {
+		descriptor string
+		since      time.Time
+		until      time.Time
+		limit      uint
+		times      []int64
+		more       bool
+	}
+
+	testcases := []testcase{
+		{descriptor: "client-1", limit: 10,
+			times: []int64{minute(0).UnixNano(), minute(2).UnixNano(),
+				minute(3).UnixNano()}},
+		{descriptor: "client-1", since: minute(2), limit: 10,
+			times: []int64{minute(2).UnixNano(), minute(3).UnixNano()}},
+		{descriptor: "client-1", until: minute(3), limit: 10,
+			times: []int64{minute(0).UnixNano(), minute(2).UnixNano()}},
+		{descriptor: "client-1", since: minute(1), until: minute(2), limit: 10,
+			times: nil},
+		{descriptor: "client-1", limit: 2,
+			times: []int64{minute(0).UnixNano(), minute(2).UnixNano()},
+			more:  true},
+		{descriptor: "client-10", limit: 10,
+			times: []int64{minute(1).UnixNano()}},
+		{descriptor: "client-2", limit: 10,
+			times: []int64{minute(4).UnixNano(), minute(4).UnixNano() + 1}},
+		{descriptor: "client-3", limit: 10, times: nil},
+	}
+
+	for i, tc := range testcases {
+		err = d.View(func(txn *Txn) (txnErr error) {
+			records, more, txnErr := txn.RelayRecords(tc.descriptor,
+				tc.since, tc.until, tc.limit)
+			if txnErr != nil {
+				return
+			}
+
+			var times []int64
+			for _, record := range records {
+				if record.Descriptor_ != tc.descriptor {
+					t.Errorf("test case %d: expected the descriptor %s, got %s",
+						i, tc.descriptor, record.Descriptor_)
+				}
+				times = append(times, record.Time)
+			}
+
+			if !reflect.DeepEqual(tc.times, times) || tc.more != more {
+				t.Errorf("test case %d: expected %v (more: %v), got %v (more: %v)",
+					i, tc.times, tc.more, times, more)
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+}
+
+func TestTxn_PruneRelayLog(t *testing.T) {
+	d, err := emptyDatabase(RelayAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	err = d.Update(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range []string{"client-1", "client-2"} {
+			for i := 0; i < 5; i++ {
+				txnErr = txn.PutRelayRecord(&protoed.RelayRecord{
+					Descriptor_: descriptor,
+					Time:        now.Add(-time.Duration(i) * time.Hour).UnixNano(),
+					Outcome:     protoed.RelayRecord_RELAYED, Status: 200})
+				if txnErr != nil {
+					return
+				}
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type testcase struct {
+		retention RelayLogRetention
+		removed   uint64
+		remaining uint64
+	}
+
+	// the test cases are applied one after another
+	testcases := []testcase{
+		{retention: RelayLogRetention{}, removed: 0, remaining: 10},
+		{retention: RelayLogRetention{MaxAge: 150 * time.Minute},
+			removed: 4, remaining: 6},
+		{retention: RelayLogRetention{MaxCount: 2}, removed: 2, remaining: 4},
+		{retention: RelayLogRetention{MaxAge: 30 * time.Minute, MaxCount: 2},
+			removed: 2, remaining: 2},
+	}
+
+	for i, tc := range testcases {
+		err = d.Update(func(txn *Txn) (txnErr error) {
+			removed, txnErr := txn.PruneRelayLog(tc.retention, now)
+			if txnErr != nil {
+				return
+			}
+
+			remaining, txnErr := txn.CountRelayRecords()
+			if txnErr != nil {
+				return
+			}
+
+			if removed != tc.removed || remaining != tc.remaining {
+				t.Errorf("test case %d: expected %d removed and %d remaining, "+
+					"got %d removed and %d remaining",
+					i, tc.removed, tc.remaining, removed, remaining)
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	err = d.View(func(txn *Txn) (txnErr error) {
+		for _, descriptor := range []string{"client-1", "client-2"} {
+			var records []*protoed.RelayRecord
+			records, _, txnErr = txn.RelayRecords(descriptor, time.Time{},
+				time.Time{}, 10)
+			if txnErr != nil {
+				return
+			}
+
+			if len(records) != 1 || records[0].Time != now.UnixNano() {
+				t.Errorf("expected only the most recent record of %s, got %v",
+					descriptor, records)
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
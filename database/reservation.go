@@ -0,0 +1,170 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/ratelimit"
+)
+
+// RateReservation holds a message taken from the token bucket of a channel
+// while the message is processed.
+//
+// The message is taken in the transaction of the reservation so that
+// the concurrent requests can not exceed the limit. Once the message has
+// been relayed, the reservation is committed; otherwise it is rolled back
+// and the message is given back to the bucket.
+type RateReservation struct {
+	// Descriptor identifies the channel.
+	Descriptor string
+
+	// Limit is the limit of the channel at the time of the reservation.
+	Limit ratelimit.Limit
+
+	// State is the state of the bucket after the message has been taken.
+	State *protoed.RateState
+
+	done bool
+}
+
+// Done indicates that the reservation has been committed or rolled back.
+func (r *RateReservation) Done() bool {
+	return r.done
+}
+
+// Commit keeps the message taken from the bucket for good.
+//
+// Commit requires:
+// * !r.Done()
+//
+// Commit ensures:
+// * r.Done()
+func (r *RateReservation) Commit() {
+	// Pre-condition
+	if !(!r.Done()) {
+		panic("Violated: !r.Done()")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(r.Done()) {
+			panic("Violated: r.Done()")
+		}
+	}()
+
+	r.done = true
+}
+
+// ReserveRate takes a message from the token bucket of the channel at
+// the given time. If the bucket is empty, reservation is nil and the state
+// of the bucket is left as-is.
+//
+// The returned state is the state of the bucket after the reservation, or
+// the current state if the bucket is empty; nil stands for a full bucket.
+//
+// ReserveRate requires:
+// * t.access == RelayAccess
+// * !now.IsZero()
+//
+// ReserveRate ensures:
+// * err != nil || reservation == nil || reservation.State == state
+// * err != nil || reservation == nil || !reservation.Done()
+func (t *Txn) ReserveRate(descriptor string, limit ratelimit.Limit,
+	now time.Time) (reservation *RateReservation,
+	state *protoed.RateState, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(!now.IsZero()):
+		panic("Violated: !now.IsZero()")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || reservation == nil || reservation.State == state):
+			panic("Violated: err != nil || reservation == nil || reservation.State == state")
+		case !(err != nil || reservation == nil || !reservation.Done()):
+			panic("Violated: err != nil || reservation == nil || !reservation.Done()")
+		default:
+			// Pass
+		}
+	}()
+
+	state, err = t.GetRateState(descriptor)
+	if err != nil {
+		return
+	}
+
+	next, ok := limit.Take(state, now)
+	if !ok {
+		return
+	}
+
+	err = t.PutRateState(Descriptor(descriptor), next)
+	if err != nil {
+		return
+	}
+
+	state = next
+	reservation = &RateReservation{
+		Descriptor: descriptor, Limit: limit, State: next}
+	return
+}
+
+// RollbackRate gives the reserved message back to the token bucket of
+// the channel at the given time.
+//
+// Nothing is given back if the state of the bucket has been erased in
+// the meantime, e.g., since the rate limit of the channel changed.
+//
+// RollbackRate requires:
+// * t.access == RelayAccess
+// * reservation != nil
+// * !reservation.Done()
+// * !now.IsZero()
+//
+// RollbackRate ensures:
+// * err != nil || reservation.Done()
+func (t *Txn) RollbackRate(reservation *RateReservation,
+	now time.Time) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(reservation != nil):
+		panic("Violated: reservation != nil")
+	case !(!reservation.Done()):
+		panic("Violated: !reservation.Done()")
+	case !(!now.IsZero()):
+		panic("Violated: !now.IsZero()")
+	default:
+		// Pass
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || reservation.Done()) {
+			panic("Violated: err != nil || reservation.Done()")
+		}
+	}()
+
+	state, err := t.GetRateState(reservation.Descriptor)
+	if err != nil {
+		return
+	}
+
+	if state != nil {
+		err = t.PutRateState(Descriptor(reservation.Descriptor),
+			reservation.Limit.Give(state, now))
+		if err != nil {
+			return
+		}
+	}
+
+	reservation.done = true
+	return
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/ratelimit"
+)
+
+func TestTxn_ReserveRate(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	limit := ratelimit.Of(&protoed.Channel{MinPeriod: 60})
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	var reservation *RateReservation
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		reservation, _, txnErr = txn.ReserveRate("client-1", limit, now)
+		if txnErr != nil {
+			return
+		}
+		if reservation == nil {
+			t.Fatalf("expected the first message to be reserved")
+		}
+
+		// The concurrent requests are refused while the slot is held.
+		var other *RateReservation
+		other, _, txnErr = txn.ReserveRate("client-1", limit,
+			now.Add(time.Second))
+		if txnErr != nil {
+			return
+		}
+		if other != nil {
+			t.Errorf("expected the reserved slot to refuse the message")
+		}
+
+		// The slot is released on a rollback.
+		txnErr = txn.RollbackRate(reservation, now.Add(time.Second))
+		if txnErr != nil {
+			return
+		}
+
+		reservation, _, txnErr = txn.ReserveRate("client-1", limit,
+			now.Add(2*time.Second))
+		if txnErr != nil {
+			return
+		}
+		if reservation == nil {
+			t.Fatalf("expected the released slot to be reserved again")
+		}
+
+		// The committed slot is consumed.
+		reservation.Commit()
+
+		other, _, txnErr = txn.ReserveRate("client-1", limit,
+			now.Add(3*time.Second))
+		if txnErr != nil {
+			return
+		}
+		if other != nil {
+			t.Errorf("expected the committed slot to refuse the message")
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reservation.Done() {
+		t.Errorf("expected the committed reservation to be done")
+	}
+}
+
+func TestTxn_ReserveRate_Concurrent(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	limit := ratelimit.Of(&protoed.Channel{MinPeriod: 60})
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	// reserveAll reserves the slot from concurrent transactions and returns
+	// the reservations which succeeded.
+	reserveAll := func(at time.Time) (reservations []*RateReservation) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				var reservation *RateReservation
+				err := s.Update(func(txn *Txn) (txnErr error) {
+					reservation, _, txnErr = txn.ReserveRate("client-1",
+						limit, at)
+					return
+				})
+				if err != nil {
+					t.Error(err.Error())
+					return
+				}
+
+				if reservation != nil {
+					mu.Lock()
+					reservations = append(reservations, reservation)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		return
+	}
+
+	reservations := reserveAll(now)
+	if len(reservations) != 1 {
+		t.Fatalf("expected a single reservation of the slot, got %d",
+			len(reservations))
+	}
+
+	// The slot is held between the transactions, e.g., while MailGun is
+	// called.
+	if others := reserveAll(now.Add(time.Second)); len(others) != 0 {
+		t.Fatalf("expected the held slot to refuse the messages, "+
+			"got %d reservations", len(others))
+	}
+
+	// The failure of MailGun gives the slot back.
+	err := s.Update(func(txn *Txn) error {
+		return txn.RollbackRate(reservations[0], now.Add(2*time.Second))
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	reservations = reserveAll(now.Add(3 * time.Second))
+	if len(reservations) != 1 {
+		t.Fatalf("expected a single reservation of the released slot, "+
+			"got %d", len(reservations))
+	}
+
+	reservations[0].Commit()
+
+	if others := reserveAll(now.Add(4 * time.Second)); len(others) != 0 {
+		t.Errorf("expected the committed slot to refuse the messages, "+
+			"got %d reservations", len(others))
+	}
+}
@@ -0,0 +1,345 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/dbc"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+const dbRevisionName = "revision"
+
+// DefaultRevisions is the number of the revisions kept per channel
+// if not configured otherwise.
+const DefaultRevisions = uint(10)
+
+// revisionKey encodes the key of a revision as the descriptor followed by
+// a zero byte and the big-endian revision number so that the revisions of
+// a channel are contiguous and ordered by their numbers.
+func revisionKey(descriptor string, revision uint64) []byte {
+	key := make([]byte, len(descriptor)+1+8)
+	copy(key, descriptor)
+	binary.BigEndian.PutUint64(key[len(descriptor)+1:], revision)
+	return key
+}
+
+// revisionPrefix encodes the common prefix of the keys of all the revisions
+// of a channel.
+func revisionPrefix(descriptor string) []byte {
+	return append([]byte(descriptor), 0)
+}
+
+// Revisions returns the stored revisions of the channel ordered by their
+// numbers. The revisions are kept after the channel has been removed.
+//
+// Revisions requires:
+// * t.access == ControlAccess
+//
+// Revisions ensures:
+// * err != nil || len(revisions) == 0 || revisions[0].Revision > 0
+func (t *Txn) Revisions(descriptor string) (
+	revisions []*protoed.ChannelRevision, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || len(revisions) == 0 || revisions[0].Revision > 0) {
+			panic("Violated: err != nil || len(revisions) == 0 || revisions[0].Revision > 0")
+		}
+	}()
+
+	prefix := revisionPrefix(descriptor)
+	err = t.kv.seek(revisionBucket, prefix,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if !bytes.HasPrefix(key, prefix) {
+				stop = true
+				return
+			}
+
+			revision := &protoed.ChannelRevision{}
+			seekErr = proto.Unmarshal(val, revision)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the revision: %s",
+					seekErr.Error())
+				return
+			}
+
+			revisions = append(revisions, revision)
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the revisions: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// GetRevision returns the given revision of the channel, or nil if
+// the revision is not stored.
+//
+// GetRevision requires:
+// * t.access == ControlAccess
+//
+// GetRevision ensures:
+// * err != nil || revision == nil || revision.Revision == number
+func (t *Txn) GetRevision(descriptor string, number uint64) (
+	revision *protoed.ChannelRevision, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || revision == nil || revision.Revision == number) {
+			panic("Violated: err != nil || revision == nil || revision.Revision == number")
+		}
+	}()
+
+	value, err := t.kv.get(revisionBucket, revisionKey(descriptor, number))
+	if err != nil {
+		err = fmt.Errorf("failed to get the revision: %s", err.Error())
+		return
+	}
+
+	if value == nil {
+		return
+	}
+
+	revision = &protoed.ChannelRevision{}
+	err = proto.Unmarshal(value, revision)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal the revision: %s", err.Error())
+		revision = nil
+		return
+	}
+
+	return
+}
+
+// PutRevision stores the channel as its next revision and removes
+// the oldest revisions of the channel so that at most keep revisions
+// remain.
+//
+// The revisions are numbered per channel starting from 1.
+//
+// PutRevision requires:
+// * t.access == ControlAccess
+// * channel != nil
+// * channel.Descriptor_ != ""
+// * !strings.Contains(channel.Descriptor_, "\x00")
+// * keep > 0
+//
+// PutRevision ensures:
+// * err != nil || revision > 0
+// * !dbc.InTest || err != nil || t.mustGetRv(channel.Descriptor_, revision) != nil
+func (t *Txn) PutRevision(channel *protoed.Channel, actor string,
+	now time.Time, keep uint) (revision uint64, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(channel != nil):
+		panic("Violated: channel != nil")
+	case !(channel.Descriptor_ != ""):
+		panic("Violated: channel.Descriptor_ != \"\"")
+	case !(!strings.Contains(channel.Descriptor_, "\x00")):
+		panic("Violated: !strings.Contains(channel.Descriptor_, \"\\x00\")")
+	case !(keep > 0):
+		panic("Violated: keep > 0")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || revision > 0):
+			panic("Violated: err != nil || revision > 0")
+		case !(!dbc.InTest || err != nil || t.mustGetRv(channel.Descriptor_, revision) != nil):
+			panic("Violated: !dbc.InTest || err != nil || t.mustGetRv(channel.Descriptor_, revision) != nil")
+		default:
+			// Pass
+		}
+	}()
+
+	// The keys are collected first and removed after the iteration.
+	var existing [][]byte
+	last := uint64(0)
+
+	prefix := revisionPrefix(channel.Descriptor_)
+	err = t.kv.seek(revisionBucket, prefix,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if !bytes.HasPrefix(key, prefix) {
+				stop = true
+				return
+			}
+
+			if len(key) != len(prefix)+8 {
+				seekErr = fmt.Errorf("invalid key of a revision: %q", key)
+				return
+			}
+
+			last = binary.BigEndian.Uint64(key[len(prefix):])
+
+			// The key is only valid within the iteration.
+			existing = append(existing, append([]byte(nil), key...))
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the revisions: %s",
+			err.Error())
+		return
+	}
+
+	revision = last + 1
+	serialized, err := proto.Marshal(&protoed.ChannelRevision{
+		Revision: revision,
+		Time:     now.UnixNano(),
+		Actor:    actor,
+		Channel:  channel})
+	if err != nil {
+		err = fmt.Errorf("failed to marshal the revision: %s", err.Error())
+		return
+	}
+
+	err = t.kv.put(revisionBucket, revisionKey(channel.Descriptor_, revision),
+		serialized)
+	if err != nil {
+		err = fmt.Errorf("failed to put the revision: %s", err.Error())
+		return
+	}
+
+	// The new revision counts towards the kept ones.
+	for len(existing) > 0 && uint(len(existing)) >= keep {
+		err = t.kv.remove(revisionBucket, existing[0])
+		if err != nil {
+			err = fmt.Errorf("failed to remove the revision: %s",
+				err.Error())
+			return
+		}
+		existing = existing[1:]
+	}
+
+	return
+}
+
+// LastRevision returns the number of the most recent revision of
+// the channel, or 0 if no revision is stored.
+//
+// Since every change of a channel is stored as its next revision and
+// the revisions are kept after the channel has been removed, the number
+// increases monotonically with the changes of the channel.
+//
+// LastRevision requires:
+// * t.access == ControlAccess
+func (t *Txn) LastRevision(descriptor string) (last uint64, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	prefix := revisionPrefix(descriptor)
+	err = t.kv.seek(revisionBucket, prefix,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if !bytes.HasPrefix(key, prefix) {
+				stop = true
+				return
+			}
+
+			if len(key) != len(prefix)+8 {
+				seekErr = fmt.Errorf("invalid key of a revision: %q", key)
+				return
+			}
+
+			last = binary.BigEndian.Uint64(key[len(prefix):])
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the revisions: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// CountRevisions returns the total number of the revisions of all
+// the channels.
+//
+// CountRevisions requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+func (t *Txn) CountRevisions() (count uint64, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess || t.access == RelayAccess) {
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	}
+
+	count, err = t.kv.count(revisionBucket)
+	if err != nil {
+		err = fmt.Errorf("failed to count the revisions: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// RecordChange records the change of a channel in the audit log and,
+// unless the channel has been removed, stores the channel after the change
+// as its next revision keeping at most the given number of revisions.
+//
+// RecordChange requires:
+// * t.access == ControlAccess
+// * keep > 0
+func (t *Txn) RecordChange(operation protoed.AuditRecord_Operation,
+	before *protoed.Channel, after *protoed.Channel, actor Actor,
+	now time.Time, keep uint) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess):
+		panic("Violated: t.access == ControlAccess")
+	case !(keep > 0):
+		panic("Violated: keep > 0")
+	default:
+		// Pass
+	}
+
+	err = t.PutAuditRecord(NewAuditRecord(operation, before, after, actor,
+		now))
+	if err != nil {
+		return
+	}
+
+	if after != nil {
+		_, err = t.PutRevision(after, actor.Name, now, keep)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// mustGetRv returns the revision of the channel.
+// In case of error, it panics.
+func (t *Txn) mustGetRv(descriptor string,
+	number uint64) *protoed.ChannelRevision {
+
+	revision, getErr := t.GetRevision(descriptor, number)
+	if getErr != nil {
+		panic(fmt.Sprintf("failed to get the revision: %s", getErr.Error()))
+	}
+
+	return revision
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestTxn_PutRevision(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for i := 1; i <= 5; i++ {
+			for _, descriptor := range []string{"client-1", "client-10"} {
+				var revision uint64
+				revision, txnErr = txn.PutRevision(&protoed.Channel{
+					Descriptor_: descriptor, TokenHash: DummyTokenHash(),
+					MaxSize: int32(i)}, "ops", now, 3)
+				if txnErr != nil {
+					return
+				}
+
+				if revision != uint64(i) {
+					t.Fatalf("expected the revision %d of %s, got %d",
+						i, descriptor, revision)
+				}
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = s.View(func(txn *Txn) (txnErr error) {
+		revisions, txnErr := txn.Revisions("client-1")
+		if txnErr != nil {
+			return
+		}
+
+		var numbers []uint64
+		for _, revision := range revisions {
+			numbers = append(numbers, revision.Revision)
+
+			if revision.Channel.MaxSize != int32(revision.Revision) ||
+				revision.Actor != "ops" || revision.Time != now.UnixNano() {
+				t.Errorf("unexpected revision: %s", revision.String())
+			}
+		}
+
+		if !reflect.DeepEqual([]uint64{3, 4, 5}, numbers) {
+			t.Errorf("expected the revisions [3 4 5], got %v", numbers)
+		}
+
+		var revision *protoed.ChannelRevision
+		revision, txnErr = txn.GetRevision("client-1", 2)
+		if txnErr != nil {
+			return
+		}
+
+		if revision != nil {
+			t.Errorf("expected the revision 2 to be pruned")
+		}
+
+		var count uint64
+		count, txnErr = txn.CountRevisions()
+		if txnErr != nil {
+			return
+		}
+
+		if count != 6 {
+			t.Errorf("expected 6 revisions in total, got %d", count)
+		}
+
+		for descriptor, expected := range map[string]uint64{
+			"client-1": 5, "client-10": 5, "client-2": 0} {
+			var last uint64
+			last, txnErr = txn.LastRevision(descriptor)
+			if txnErr != nil {
+				return
+			}
+
+			if last != expected {
+				t.Errorf("expected the last revision %d of %s, got %d",
+					expected, descriptor, last)
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const dbMetaName = "meta"
+
+// schemaVersionKey is the key of the schema version in the meta database.
+var schemaVersionKey = []byte("schema_version")
+
+// legacySchemaVersion is the schema version of the database directories
+// created before the meta database was introduced.
+const legacySchemaVersion = uint64(1)
+
+// Migration upgrades the database schema by one version.
+type Migration struct {
+	// Description explains the migration to the operator.
+	Description string
+
+	// Apply migrates the data. It is executed within the same transaction
+	// as all the other pending migrations of the database.
+	Apply func(txn *Txn) error
+}
+
+// migrations lists the migrations in order. The migration at the index i
+// upgrades the database from the schema version i+1 to i+2.
+//
+// Append new migrations at the end and never change the existing ones.
+var migrations = []Migration{
+	{
+		Description: "replace the plain-text tokens with their salted hashes",
+		Apply: func(txn *Txn) error {
+			_, err := txn.HashPlaintextTokens()
+			return err
+		}},
+	{
+		Description: "create the relay log",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(relayLogBucket)
+		}},
+	{
+		Description: "create the audit log",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(auditBucket)
+		}},
+	{
+		Description: "store the current channels as their first revisions",
+		Apply: func(txn *Txn) error {
+			err := txn.kv.(bucketCreator).create(revisionBucket)
+			if err != nil {
+				return err
+			}
+
+			channels, err := txn.AllChannels()
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			for _, channel := range channels {
+				// The channels with invalid descriptors are left without
+				// revisions; the check mode reports them.
+				if ValidateDescriptor(channel.Descriptor_) != nil {
+					continue
+				}
+
+				_, err = txn.PutRevision(channel, "", now, DefaultRevisions)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	{
+		Description: "create the token usage",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(tokenUseBucket)
+		}},
+	{
+		Description: "create the usage counters",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(usageBucket)
+		}},
+	{
+		Description: "store the token bucket states in place of the timestamps",
+		Apply: func(txn *Txn) error {
+			return txn.convertTimestamps()
+		}},
+	{
+		Description: "create the quota counters",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(quotaBucket)
+		}},
+	{
+		Description: "create the lockouts",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(lockoutBucket)
+		}},
+	{
+		Description: "create the message queue",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(queueBucket)
+		}},
+	{
+		Description: "create the last relays",
+		Apply: func(txn *Txn) error {
+			return txn.kv.(bucketCreator).create(lastRelayBucket)
+		}},
+}
+
+// SchemaVersion is the schema version expected by this code base.
+var SchemaVersion = legacySchemaVersion + uint64(len(migrations))
+
+// Migrations returns the migrations pending to upgrade a database from
+// the given schema version to SchemaVersion.
+//
+// Migrations requires:
+// * version >= legacySchemaVersion
+// * version <= SchemaVersion
+func Migrations(version uint64) []Migration {
+	// Pre-conditions
+	switch {
+	case !(version >= legacySchemaVersion):
+		panic("Violated: version >= legacySchemaVersion")
+	case !(version <= SchemaVersion):
+		panic("Violated: version <= SchemaVersion")
+	default:
+		// Pass
+	}
+
+	return migrations[version-legacySchemaVersion:]
+}
+
+func encodeSchemaVersion(version uint64) []byte {
+	bytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(bytes, version)
+	return bytes
+}
+
+// decodeSchemaVersion decodes the schema version stored in the meta
+// database.
+func decodeSchemaVersion(value []byte) (version uint64, err error) {
+	if len(value) != 8 {
+		err = fmt.Errorf("expected the schema version to be encoded "+
+			"in 8 bytes, got %d", len(value))
+		return
+	}
+
+	version = binary.LittleEndian.Uint64(value)
+	return
+}
+
+// schemaVersionError returns an error if the schema version of
+// the database at the path differs from SchemaVersion.
+func schemaVersionError(path string, version uint64) (err error) {
+	switch {
+	case version < SchemaVersion:
+		err = fmt.Errorf("the database %s has the schema version %d, "+
+			"but %d is expected; please upgrade it with "+
+			"mailgun-relayery-init -upgrade", path, version, SchemaVersion)
+	case version > SchemaVersion:
+		err = fmt.Errorf("the database %s has the schema version %d, "+
+			"but %d is expected; it has been created by a newer version "+
+			"of mailgun-relayery", path, version, SchemaVersion)
+	default:
+		// pass
+	}
+
+	return
+}
+
+// migrate applies the migrations pending from the given schema version
+// within the transaction.
+func migrate(txn *Txn, from uint64) (err error) {
+	if from > SchemaVersion {
+		err = fmt.Errorf("the database has the schema version %d "+
+			"which is newer than the supported version %d",
+			from, SchemaVersion)
+		return
+	}
+
+	for i, migration := range Migrations(from) {
+		err = migration.Apply(txn)
+		if err != nil {
+			err = fmt.Errorf("failed to migrate from the schema "+
+				"version %d to %d (%s): %s", from+uint64(i),
+				from+uint64(i)+1, migration.Description, err.Error())
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,140 @@
+// +build cgo
+
+package database
+
+import (
+	"fmt"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+)
+
+// readSchemaVersion reads the schema version from the meta database.
+// Databases without the meta database are assumed to be of the legacy
+// schema version.
+func readSchemaVersion(txn *lmdb.Txn) (version uint64, err error) {
+	metaDbi, err := txn.OpenDBI(dbMetaName, 0)
+	switch {
+	case err == nil:
+		// pass
+	case lmdb.IsNotFound(err):
+		err = nil
+		version = legacySchemaVersion
+		return
+	default:
+		err = fmt.Errorf("failed to open the meta database: %s",
+			err.Error())
+		return
+	}
+
+	value, err := txn.Get(metaDbi, schemaVersionKey)
+	switch {
+	case err == nil:
+		// pass
+	case lmdb.IsNotFound(err):
+		err = nil
+		version = legacySchemaVersion
+		return
+	default:
+		err = fmt.Errorf("failed to get the schema version: %s",
+			err.Error())
+		return
+	}
+
+	version, err = decodeSchemaVersion(value)
+	return
+}
+
+// writeSchemaVersion stores the schema version in the meta database,
+// creating the meta database if necessary.
+func writeSchemaVersion(txn *lmdb.Txn, version uint64) (err error) {
+	metaDbi, err := txn.OpenDBI(dbMetaName, lmdb.Create)
+	if err != nil {
+		err = fmt.Errorf("failed to open the meta database: %s",
+			err.Error())
+		return
+	}
+
+	err = txn.Put(metaDbi, schemaVersionKey, encodeSchemaVersion(version), 0)
+	if err != nil {
+		err = fmt.Errorf("failed to put the schema version: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// SchemaVersion returns the schema version of the database.
+func (e *Env) SchemaVersion() (version uint64, err error) {
+	err = e.view(func(txn *lmdb.Txn) (txnErr error) {
+		version, txnErr = readSchemaVersion(txn)
+		return
+	})
+	return
+}
+
+// CheckSchemaVersion returns an error if the schema version of the database
+// differs from SchemaVersion.
+func (e *Env) CheckSchemaVersion() (err error) {
+	version, err := e.SchemaVersion()
+	if err != nil {
+		return
+	}
+
+	err = schemaVersionError(e.Path, version)
+	return
+}
+
+// Upgrade runs all the pending migrations in a single transaction.
+// If any migration fails, the database is left unchanged.
+//
+// Upgrade requires:
+// * e.Access == ControlAccess
+//
+// Upgrade ensures:
+// * err != nil || to == SchemaVersion
+// * err != nil || from <= to
+func (e *Env) Upgrade() (from uint64, to uint64, err error) {
+	// Pre-condition
+	if !(e.Access == ControlAccess) {
+		panic("Violated: e.Access == ControlAccess")
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || to == SchemaVersion):
+			panic("Violated: err != nil || to == SchemaVersion")
+		case !(err != nil || from <= to):
+			panic("Violated: err != nil || from <= to")
+		default:
+			// Pass
+		}
+	}()
+
+	err = e.update(func(lmdbTxn *lmdb.Txn) (txnErr error) {
+		from, txnErr = readSchemaVersion(lmdbTxn)
+		if txnErr != nil {
+			return
+		}
+
+		txn, txnErr := e.newTxn(lmdbTxn)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = migrate(txn, from)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = writeSchemaVersion(lmdbTxn, SchemaVersion)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	to = SchemaVersion
+	return
+}
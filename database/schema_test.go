@@ -0,0 +1,314 @@
+// +build cgo
+
+package database
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+func TestInitialize_SchemaVersion(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	version, err := d.SchemaVersion()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if version != SchemaVersion {
+		t.Fatalf("expected the schema version %d, got %d",
+			SchemaVersion, version)
+	}
+
+	err = d.CheckSchemaVersion()
+	if err != nil {
+		t.Fatalf("expected no error on a new database, got: %s", err.Error())
+	}
+
+	from, to, err := d.Upgrade()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if from != SchemaVersion || to != SchemaVersion {
+		t.Fatalf("expected a no-op upgrade, got from %d to %d", from, to)
+	}
+}
+
+func TestEnv_Upgrade(t *testing.T) {
+	d, err := legacyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	token := "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU"
+	descriptor := "legacy-channel"
+	sender := protoed.Entity{Name: "Johann Sebastian Bach",
+		Email: "johann.bach@composers.com"}
+	recipients := []*protoed.Entity{
+		{Name: "CPE Bach", Email: "cpe.bach@composers.com"}}
+	legacy := &protoed.Channel{Descriptor_: descriptor,
+		Token:  token,
+		Sender: &sender, Recipients: recipients,
+		MinPeriod: 0.0001, MaxSize: 10000000}
+	legacyTimestamp := Timestamp(1538404620000)
+
+	serialized, err := proto.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// A channel with an invalid descriptor must not abort the upgrade.
+	invalidDescriptor := "legacy\x00channel"
+	invalid := proto.Clone(legacy).(*protoed.Channel)
+	invalid.Descriptor_ = invalidDescriptor
+
+	serializedInvalid, err := proto.Marshal(invalid)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = d.env.Update(func(txn *lmdb.Txn) (txnErr error) {
+		var dbi lmdb.DBI
+		dbi, txnErr = txn.OpenDBI(dbChannelName, 0)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.Put(dbi, Descriptor(descriptor).Encode(), serialized, 0)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.Put(dbi, Descriptor(invalidDescriptor).Encode(),
+			serializedInvalid, 0)
+		if txnErr != nil {
+			return
+		}
+
+		dbi, txnErr = txn.OpenDBI(dbTimestampName, 0)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.Put(dbi, Descriptor(descriptor).Encode(),
+			legacyTimestamp.Encode(), 0)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	version, err := d.SchemaVersion()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if version != legacySchemaVersion {
+		t.Fatalf("expected the legacy schema version %d, got %d",
+			legacySchemaVersion, version)
+	}
+
+	err = d.CheckSchemaVersion()
+	if err == nil {
+		t.Fatalf("expected an error on a legacy database")
+	}
+
+	from, to, err := d.Upgrade()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if from != legacySchemaVersion || to != SchemaVersion {
+		t.Fatalf("expected an upgrade from %d to %d, got from %d to %d",
+			legacySchemaVersion, SchemaVersion, from, to)
+	}
+
+	err = d.CheckSchemaVersion()
+	if err != nil {
+		t.Fatalf("expected no error after the upgrade, got: %s",
+			err.Error())
+	}
+
+	err = d.View(func(txn *Txn) (txnErr error) {
+		var got *protoed.Channel
+		got, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil {
+			return
+		}
+
+		if got.Token != "" || !tokenhash.Verify(got.TokenHash, token) {
+			t.Fatalf("expected the token to be hashed, got %s",
+				got.String())
+		}
+
+		var count uint64
+		count, txnErr = txn.CountRelayRecords()
+		if txnErr != nil {
+			return
+		}
+
+		if count != 0 {
+			t.Fatalf("expected an empty relay log, got %d records", count)
+		}
+
+		count, txnErr = txn.CountAuditRecords()
+		if txnErr != nil {
+			return
+		}
+
+		if count != 0 {
+			t.Fatalf("expected an empty audit log, got %d records", count)
+		}
+
+		var revisions []*protoed.ChannelRevision
+		revisions, txnErr = txn.Revisions(descriptor)
+		if txnErr != nil {
+			return
+		}
+
+		if len(revisions) != 1 || revisions[0].Revision != 1 ||
+			!tokenhash.Verify(revisions[0].Channel.TokenHash, token) {
+			t.Fatalf("expected the channel as its first revision, got %v",
+				revisions)
+		}
+
+		got, txnErr = txn.GetChannel(invalidDescriptor)
+		if txnErr != nil {
+			return
+		}
+
+		if got == nil {
+			t.Fatalf("expected the channel with the invalid descriptor " +
+				"to be kept")
+		}
+
+		var state *protoed.RateState
+		state, txnErr = txn.GetRateState(descriptor)
+		if txnErr != nil {
+			return
+		}
+
+		expected := &protoed.RateState{Time: int64(legacyTimestamp)}
+		if !proto.Equal(expected, state) {
+			t.Fatalf("expected the timestamp converted to the rate state %v, "+
+				"got %v", expected, state)
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestEnv_CheckSchemaVersion_Newer(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	err = d.env.Update(func(txn *lmdb.Txn) error {
+		return writeSchemaVersion(txn, SchemaVersion+1)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = d.CheckSchemaVersion()
+	if err == nil {
+		t.Fatalf("expected an error on a database of a newer version")
+	}
+
+	_, _, err = d.Upgrade()
+	if err == nil {
+		t.Fatalf("expected the upgrade of a newer database to fail")
+	}
+}
+
+// legacyDatabase creates an empty database as it was initialized before
+// the schema version was introduced.
+func legacyDatabase() (e *Env, err error) {
+	tmpdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		return
+	}
+
+	e, err = NewEnv(ControlAccess, tmpdir)
+	if err != nil {
+		removeErr := os.RemoveAll(tmpdir)
+		if removeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		}
+		return
+	}
+
+	err = e.env.Update(func(txn *lmdb.Txn) (txnErr error) {
+		_, txnErr = txn.OpenDBI(dbChannelName, lmdb.Create)
+		if txnErr != nil {
+			return
+		}
+
+		_, txnErr = txn.OpenDBI(dbTimestampName, lmdb.Create)
+		return
+	})
+	if err != nil {
+		closeErr := e.Close()
+		if closeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), closeErr.Error())
+		}
+		removeErr := os.RemoveAll(tmpdir)
+		if removeErr != nil {
+			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		}
+		return
+	}
+
+	return
+}
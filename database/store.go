@@ -0,0 +1,95 @@
+package database
+
+// Store is a transactional storage of the channels, the timestamps,
+// the relay log, the audit log, the revisions of the channels,
+// the usage of their tokens, their usage counters, their quota counters and
+// the lockouts after failed authentications and the queue of the messages
+// exceeding the rate limits.
+//
+// The channels and the timestamps are read, put, removed, counted and paged
+// through the transactions. Env stores the data in an LMDB environment and
+// BoltStore in a bbolt file on disk while MemStore keeps it in memory.
+type Store interface {
+	// View executes a read-only transaction.
+	View(fn func(txn *Txn) error) error
+
+	// Update executes a read-write transaction. The changes are discarded
+	// if fn returns an error.
+	Update(fn func(txn *Txn) error) error
+
+	// CheckSchemaVersion returns an error if the schema version of the
+	// stored data differs from SchemaVersion.
+	CheckSchemaVersion() error
+
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// bucket enumerates the key-value collections of a store.
+type bucket int
+
+const (
+	channelBucket bucket = iota
+	timestampBucket
+	relayLogBucket
+	auditBucket
+	revisionBucket
+	tokenUseBucket
+	usageBucket
+	quotaBucket
+	lockoutBucket
+	queueBucket
+	lastRelayBucket
+)
+
+// bucketCount is the number of the key-value collections of a store.
+const bucketCount = 11
+
+// bucketNames maps the buckets to the names of the LMDB databases and
+// the bbolt buckets.
+var bucketNames = [bucketCount]string{
+	channelBucket:   dbChannelName,
+	timestampBucket: dbTimestampName,
+	relayLogBucket:  dbRelayLogName,
+	auditBucket:     dbAuditName,
+	revisionBucket:  dbRevisionName,
+	tokenUseBucket:  dbTokenUseName,
+	usageBucket:     dbUsageName,
+	quotaBucket:     dbQuotaName,
+	lockoutBucket:   dbLockoutName,
+	queueBucket:     dbQueueName,
+	lastRelayBucket: dbLastRelayName}
+
+// kvTxn is a transaction over the key-value collections of a storage
+// backend. The keys are ordered lexicographically by their bytes.
+//
+// The returned keys and values are only valid until the end of
+// the transaction and must not be modified.
+type kvTxn interface {
+	// get returns the value of the key, or nil if the key does not exist.
+	get(b bucket, key []byte) (value []byte, err error)
+
+	// put sets the value of the key.
+	put(b bucket, key []byte, value []byte) error
+
+	// remove removes the key, if it exists.
+	remove(b bucket, key []byte) error
+
+	// count returns the number of the keys.
+	count(b bucket) (uint64, error)
+
+	// seek calls fn on the entries in the key order starting from the first
+	// key which is not smaller than the given key, until fn requests to stop
+	// or returns an error. A nil key starts from the first entry.
+	//
+	// The bucket must not be modified during the iteration.
+	seek(b bucket, key []byte,
+		fn func(key []byte, value []byte) (stop bool, err error)) error
+}
+
+// bucketCreator is implemented by the key-value transactions of the backends
+// whose buckets need to be created explicitly by the migrations.
+type bucketCreator interface {
+	// create creates the bucket if it does not exist.
+	create(b bucket) error
+}
@@ -24,3 +24,18 @@ func CompareChannels(
 	}
 
 }
+
+// DummyTokenHash returns a well-formed token hash for populating the test
+// databases.
+//
+// The hash has not been derived from any token so that the tests need not
+// pay for the slow hashing.
+func DummyTokenHash() *protoed.TokenHash {
+	return &protoed.TokenHash{
+		Version: protoed.TokenHash_ARGON2ID,
+		Salt:    []byte("0123456789abcdef"),
+		Hash:    []byte("0123456789abcdef0123456789abcdef"),
+		Time:    1,
+		Memory:  64 * 1024,
+		Threads: 4}
+}
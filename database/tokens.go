@@ -0,0 +1,88 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/Parquery/mailgun-relayery/dbc"
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+// HashPlaintextTokens replaces the plain-text tokens of all the channels in
+// the database with their salted hashes.
+//
+// The migration is meant to be run once on the databases created before the
+// tokens were hashed. Channels which already carry a hash only lose their
+// plain-text token, if any.
+//
+// HashPlaintextTokens requires:
+// * t.access == ControlAccess
+//
+// HashPlaintextTokens ensures:
+// * !dbc.InTest || err != nil || t.mustCountPlaintext() == 0
+func (t *Txn) HashPlaintextTokens() (migrated uint64, err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(!dbc.InTest || err != nil || t.mustCountPlaintext() == 0) {
+			panic("Violated: !dbc.InTest || err != nil || t.mustCountPlaintext() == 0")
+		}
+	}()
+
+	channels, err := t.plaintextChannels()
+	if err != nil {
+		return
+	}
+
+	for _, channel := range channels {
+		if channel.TokenHash == nil {
+			channel.TokenHash, err = tokenhash.New(channel.Token)
+			if err != nil {
+				err = fmt.Errorf("failed to hash the token of the channel %s: %s",
+					channel.Descriptor_, err.Error())
+				return
+			}
+		}
+		channel.Token = ""
+
+		err = t.PutChannel(channel)
+		if err != nil {
+			return
+		}
+		migrated++
+	}
+
+	return
+}
+
+// plaintextChannels lists the channels which carry a plain-text token.
+func (t *Txn) plaintextChannels() (channels []*protoed.Channel, err error) {
+	all, err := t.AllChannels()
+	if err != nil {
+		return
+	}
+
+	for _, channel := range all {
+		if channel.Token != "" {
+			channels = append(channels, channel)
+		}
+	}
+
+	return
+}
+
+// mustCountPlaintext returns the number of channels carrying a plain-text
+// token. In case of error, it panics.
+func (t *Txn) mustCountPlaintext() int {
+	channels, err := t.plaintextChannels()
+	if err != nil {
+		panic(fmt.Sprintf("failed to list the plain-text channels: %s",
+			err.Error()))
+	}
+
+	return len(channels)
+}
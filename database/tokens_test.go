@@ -0,0 +1,123 @@
+// +build cgo
+
+package database
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+func TestTxn_HashPlaintextTokens(t *testing.T) {
+	d, err := emptyDatabase(ControlAccess)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = os.RemoveAll(d.Path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	token := "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU"
+	sender := protoed.Entity{Name: "Johann Sebastian Bach",
+		Email: "johann.bach@composers.com"}
+	recipients := []*protoed.Entity{
+		{Name: "CPE Bach", Email: "cpe.bach@composers.com"}}
+
+	legacy := &protoed.Channel{Descriptor_: "legacy-channel",
+		Token:  token,
+		Sender: &sender, Recipients: recipients,
+		MinPeriod: 0.0001, MaxSize: 10000000}
+	hashed := &protoed.Channel{Descriptor_: "hashed-channel",
+		TokenHash: DummyTokenHash(),
+		Sender:    &sender, Recipients: recipients,
+		MinPeriod: 0.0001, MaxSize: 10000000}
+
+	// put the legacy channel directly since PutChannel refuses plain-text
+	// tokens
+	err = d.Update(func(txn *Txn) (txnerr error) {
+		var serialized []byte
+		serialized, txnerr = proto.Marshal(legacy)
+		if txnerr != nil {
+			return
+		}
+
+		txnerr = txn.kv.put(channelBucket,
+			Descriptor(legacy.Descriptor_).Encode(), serialized)
+		if txnerr != nil {
+			return
+		}
+
+		txnerr = txn.PutChannel(hashed)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// migrate
+	var migrated uint64
+	err = d.Update(func(txn *Txn) (txnerr error) {
+		migrated, txnerr = txn.HashPlaintextTokens()
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if migrated != 1 {
+		t.Fatalf("expected 1 migrated channel, got %d", migrated)
+	}
+
+	// check that the token has been hashed
+	err = d.View(func(txn *Txn) (txnerr error) {
+		var got *protoed.Channel
+		got, txnerr = txn.GetChannel(legacy.Descriptor_)
+		if txnerr != nil {
+			return
+		}
+
+		if got.Token != "" {
+			t.Fatalf("expected no plain-text token, got %#v", got.Token)
+		}
+
+		if !tokenhash.Verify(got.TokenHash, token) {
+			t.Fatalf("expected the token to match the migrated hash")
+		}
+
+		got, txnerr = txn.GetChannel(hashed.Descriptor_)
+		if txnerr != nil {
+			return
+		}
+		CompareChannels(hashed, got, t)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// a second migration is a no-op
+	err = d.Update(func(txn *Txn) (txnerr error) {
+		migrated, txnerr = txn.HashPlaintextTokens()
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if migrated != 0 {
+		t.Fatalf("expected no migrated channel, got %d", migrated)
+	}
+}
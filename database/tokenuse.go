@@ -0,0 +1,178 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+const dbTokenUseName = "tokenuse"
+
+// tokenUseKey encodes the key of the last use of a token as the descriptor
+// followed by a zero byte and the name of the token so that the tokens of
+// a channel are contiguous.
+func tokenUseKey(descriptor string, name string) []byte {
+	return append(tokenUsePrefix(descriptor), name...)
+}
+
+// tokenUsePrefix encodes the common prefix of the keys of the last uses of
+// all the tokens of a channel.
+func tokenUsePrefix(descriptor string) []byte {
+	return append([]byte(descriptor), 0)
+}
+
+// PutTokenUse records that the named token of the channel has been used
+// at the given time.
+//
+// PutTokenUse requires:
+// * t.access == RelayAccess
+// * !strings.Contains(descriptor, "\x00")
+// * name != ""
+func (t *Txn) PutTokenUse(descriptor string, name string,
+	now time.Time) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(!strings.Contains(descriptor, "\x00")):
+		panic("Violated: !strings.Contains(descriptor, \"\\x00\")")
+	case !(name != ""):
+		panic("Violated: name != \"\"")
+	default:
+		// Pass
+	}
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(now.UnixNano()))
+
+	err = t.kv.put(tokenUseBucket, tokenUseKey(descriptor, name), value)
+	if err != nil {
+		err = fmt.Errorf("failed to put the token use: %s", err.Error())
+		return
+	}
+
+	return
+}
+
+// TokenUses returns the time of the last use of each used token of
+// the channel in nanoseconds since epoch keyed by the name of the token.
+//
+// TokenUses requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+//
+// TokenUses ensures:
+// * err != nil || uses != nil
+func (t *Txn) TokenUses(descriptor string) (uses map[string]int64,
+	err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess || t.access == RelayAccess) {
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || uses != nil) {
+			panic("Violated: err != nil || uses != nil")
+		}
+	}()
+
+	uses = make(map[string]int64)
+
+	prefix := tokenUsePrefix(descriptor)
+	err = t.kv.seek(tokenUseBucket, prefix,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if !bytes.HasPrefix(key, prefix) {
+				stop = true
+				return
+			}
+
+			if len(val) != 8 {
+				seekErr = fmt.Errorf("expected the token use to be encoded "+
+					"in 8 bytes, got %d", len(val))
+				return
+			}
+
+			uses[string(key[len(prefix):])] =
+				int64(binary.BigEndian.Uint64(val))
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the token uses: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// RemoveTokenUses removes the last uses of the given tokens of the channel,
+// or of all its tokens if no names are given.
+//
+// RemoveTokenUses requires:
+// * t.access == ControlAccess
+func (t *Txn) RemoveTokenUses(descriptor string, names ...string) (
+	err error) {
+	// Pre-condition
+	if !(t.access == ControlAccess) {
+		panic("Violated: t.access == ControlAccess")
+	}
+
+	if len(names) == 0 {
+		var uses map[string]int64
+		uses, err = t.TokenUses(descriptor)
+		if err != nil {
+			return
+		}
+
+		for name := range uses {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		err = t.kv.remove(tokenUseBucket, tokenUseKey(descriptor, name))
+		if err != nil {
+			err = fmt.Errorf("failed to remove the token use: %s",
+				err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+// tokenNames lists the names of all the tokens of the channel including
+// the default token.
+func tokenNames(channel *protoed.Channel) []string {
+	var names []string
+	if channel.TokenHash != nil {
+		names = append(names, tokenhash.DefaultName)
+	}
+	for _, token := range channel.Tokens {
+		names = append(names, token.Name)
+	}
+	return names
+}
+
+// removedTokenNames lists the names of the tokens of the channel before
+// the change which are missing after it.
+func removedTokenNames(before *protoed.Channel,
+	after *protoed.Channel) []string {
+	kept := make(map[string]bool)
+	for _, name := range tokenNames(after) {
+		kept[name] = true
+	}
+
+	var removed []string
+	for _, name := range tokenNames(before) {
+		if !kept[name] {
+			removed = append(removed, name)
+		}
+	}
+	return removed
+}
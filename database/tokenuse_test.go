@@ -0,0 +1,99 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestTxn_TokenUses(t *testing.T) {
+	s := NewMemStore(ControlAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	channel := &protoed.Channel{Descriptor_: "client-1",
+		TokenHash: DummyTokenHash(),
+		Tokens: []*protoed.ChannelToken{
+			{Name: "ci-2", Hash: DummyTokenHash(), Created: now.UnixNano()}}}
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for _, c := range []*protoed.Channel{channel, {
+			Descriptor_: "client-10", TokenHash: DummyTokenHash()}} {
+			txnErr = txn.PutChannel(c)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.Access = RelayAccess
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		for i, use := range [][2]string{
+			{"client-1", "default"}, {"client-1", "ci-2"},
+			{"client-10", "default"}} {
+			txnErr = txn.PutTokenUse(use[0], use[1],
+				now.Add(time.Duration(i)*time.Minute))
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.Access = ControlAccess
+	uses := func(descriptor string) (result map[string]int64) {
+		err := s.View(func(txn *Txn) (txnErr error) {
+			result, txnErr = txn.TokenUses(descriptor)
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return
+	}
+
+	expected := map[string]int64{
+		"default": now.UnixNano(),
+		"ci-2":    now.Add(time.Minute).UnixNano()}
+	if got := uses("client-1"); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected the token uses %v, got %v", expected, got)
+	}
+
+	// Putting the channel without a token erases the last use of the token.
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		return txn.PutChannel(&protoed.Channel{Descriptor_: "client-1",
+			TokenHash: DummyTokenHash()})
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected = map[string]int64{"default": now.UnixNano()}
+	if got := uses("client-1"); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected the token uses %v, got %v", expected, got)
+	}
+
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		return txn.RemoveChannel("client-1")
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if got := uses("client-1"); len(got) != 0 {
+		t.Errorf("expected no token uses of a removed channel, got %v", got)
+	}
+
+	expected = map[string]int64{"default": now.Add(2 * time.Minute).UnixNano()}
+	if got := uses("client-10"); !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected the token uses %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,329 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+const dbUsageName = "usage"
+
+// UsagePeriod enumerates the periods over which the relay attempts are
+// counted.
+type UsagePeriod string
+
+const (
+	// Hourly counts the relay attempts per hour.
+	Hourly UsagePeriod = "hour"
+
+	// Daily counts the relay attempts per day in UTC.
+	Daily UsagePeriod = "day"
+)
+
+// UsagePeriods lists all the usage periods.
+var UsagePeriods = []UsagePeriod{Hourly, Daily}
+
+// ParseUsagePeriod parses the name of a usage period.
+func ParseUsagePeriod(name string) (period UsagePeriod, err error) {
+	for _, p := range UsagePeriods {
+		if UsagePeriod(name) == p {
+			period = p
+			return
+		}
+	}
+
+	var names []string
+	for _, p := range UsagePeriods {
+		names = append(names, string(p))
+	}
+
+	err = fmt.Errorf("unknown usage period %#v, expected one of: %s",
+		name, strings.Join(names, ", "))
+	return
+}
+
+// Start returns the start of the period containing the given time.
+func (p UsagePeriod) Start(tm time.Time) time.Time {
+	tm = tm.UTC()
+
+	switch p {
+	case Hourly:
+		return tm.Truncate(time.Hour)
+	case Daily:
+		year, month, day := tm.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	default:
+		panic(fmt.Sprintf("unhandled usage period: %#v", p))
+	}
+}
+
+// End returns the end of the period starting at the given time.
+func (p UsagePeriod) End(start time.Time) time.Time {
+	switch p {
+	case Hourly:
+		return start.Add(time.Hour)
+	case Daily:
+		return start.UTC().AddDate(0, 0, 1)
+	default:
+		panic(fmt.Sprintf("unhandled usage period: %#v", p))
+	}
+}
+
+// code identifies the period in the keys of the usage counters.
+func (p UsagePeriod) code() byte {
+	switch p {
+	case Hourly:
+		return 'h'
+	case Daily:
+		return 'd'
+	default:
+		panic(fmt.Sprintf("unhandled usage period: %#v", p))
+	}
+}
+
+// usagePrefix encodes the common prefix of the keys of the usage counters
+// of a channel over the period as the descriptor followed by a zero byte and
+// the code of the period.
+func usagePrefix(descriptor string, period UsagePeriod) []byte {
+	return append([]byte(descriptor), 0, period.code())
+}
+
+// usageKey encodes the key of the usage counters as their prefix followed
+// by the big-endian start of the period in nanoseconds so that
+// the counters of a channel are ordered by time.
+func usageKey(descriptor string, period UsagePeriod, start int64) []byte {
+	key := usagePrefix(descriptor, period)
+	startBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(startBytes, uint64(start))
+	return append(key, startBytes...)
+}
+
+// countAttempt adds the relay attempt to the counters.
+func countAttempt(counters *protoed.UsageCounters,
+	record *protoed.RelayRecord) {
+	switch record.Outcome {
+	case protoed.RelayRecord_RELAYED:
+		counters.Relayed++
+		counters.Bytes += uint64(record.Size)
+	case protoed.RelayRecord_FORBIDDEN:
+		counters.Forbidden++
+	case protoed.RelayRecord_TOO_SOON:
+		counters.TooSoon++
+	case protoed.RelayRecord_TOO_LARGE:
+		counters.TooLarge++
+	case protoed.RelayRecord_INVALID:
+		counters.Invalid++
+	case protoed.RelayRecord_FAILED:
+		counters.Failed++
+	case protoed.RelayRecord_DISABLED:
+		counters.Disabled++
+	case protoed.RelayRecord_EXPIRED:
+		counters.Expired++
+	case protoed.RelayRecord_QUOTA_EXCEEDED:
+		counters.QuotaExceeded++
+	case protoed.RelayRecord_THROTTLED:
+		counters.Throttled++
+	case protoed.RelayRecord_LOCKED_OUT:
+		counters.LockedOut++
+	case protoed.RelayRecord_QUEUED:
+		counters.Queued++
+	case protoed.RelayRecord_QUEUE_FULL:
+		counters.QueueFull++
+	default:
+		panic(fmt.Sprintf("unhandled outcome: %s", record.Outcome))
+	}
+}
+
+// CountUsage adds the relay attempt to the hourly and the daily usage
+// counters of its channel. The counters are kept after the channel
+// has been removed until they are pruned.
+//
+// CountUsage requires:
+// * t.access == RelayAccess
+// * record != nil
+// * record.Descriptor_ != ""
+// * !strings.Contains(record.Descriptor_, "\x00")
+// * record.Time > 0
+// * record.Outcome != protoed.RelayRecord_UNKNOWN
+func (t *Txn) CountUsage(record *protoed.RelayRecord) (err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == RelayAccess):
+		panic("Violated: t.access == RelayAccess")
+	case !(record != nil):
+		panic("Violated: record != nil")
+	case !(record.Descriptor_ != ""):
+		panic("Violated: record.Descriptor_ != \"\"")
+	case !(!strings.Contains(record.Descriptor_, "\x00")):
+		panic("Violated: !strings.Contains(record.Descriptor_, \"\\x00\")")
+	case !(record.Time > 0):
+		panic("Violated: record.Time > 0")
+	case !(record.Outcome != protoed.RelayRecord_UNKNOWN):
+		panic("Violated: record.Outcome != protoed.RelayRecord_UNKNOWN")
+	default:
+		// Pass
+	}
+
+	for _, period := range UsagePeriods {
+		start := period.Start(time.Unix(0, record.Time)).UnixNano()
+		key := usageKey(record.Descriptor_, period, start)
+
+		var val []byte
+		val, err = t.kv.get(usageBucket, key)
+		if err != nil {
+			err = fmt.Errorf("failed to get the usage counters: %s",
+				err.Error())
+			return
+		}
+
+		counters := &protoed.UsageCounters{Start: start}
+		if val != nil {
+			err = proto.Unmarshal(val, counters)
+			if err != nil {
+				err = fmt.Errorf("failed to unmarshal the usage counters: %s",
+					err.Error())
+				return
+			}
+		}
+
+		countAttempt(counters, record)
+
+		var serialized []byte
+		serialized, err = proto.Marshal(counters)
+		if err != nil {
+			err = fmt.Errorf("failed to marshal the usage counters: %s",
+				err.Error())
+			return
+		}
+
+		err = t.kv.put(usageBucket, key, serialized)
+		if err != nil {
+			err = fmt.Errorf("failed to put the usage counters: %s",
+				err.Error())
+			return
+		}
+	}
+
+	return
+}
+
+// Usage returns the usage counters of the channel for the periods starting
+// in the time range [since, until) ordered by time. A zero since or until
+// leaves the range open on that side. The periods without any relay attempt
+// are omitted.
+//
+// Usage requires:
+// * t.access == ControlAccess || t.access == RelayAccess
+// * period == Hourly || period == Daily
+// * since.IsZero() || until.IsZero() || !until.Before(since)
+func (t *Txn) Usage(descriptor string, period UsagePeriod, since time.Time,
+	until time.Time) (counters []*protoed.UsageCounters, err error) {
+	// Pre-conditions
+	switch {
+	case !(t.access == ControlAccess || t.access == RelayAccess):
+		panic("Violated: t.access == ControlAccess || t.access == RelayAccess")
+	case !(period == Hourly || period == Daily):
+		panic("Violated: period == Hourly || period == Daily")
+	case !(since.IsZero() || until.IsZero() || !until.Before(since)):
+		panic("Violated: since.IsZero() || until.IsZero() || !until.Before(since)")
+	default:
+		// Pass
+	}
+
+	prefix := usagePrefix(descriptor, period)
+	start := prefix
+	if !since.IsZero() {
+		start = usageKey(descriptor, period, since.UnixNano())
+	}
+
+	err = t.kv.seek(usageBucket, start,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if !bytes.HasPrefix(key, prefix) || len(key) != len(prefix)+8 {
+				stop = true
+				return
+			}
+
+			periodStart := int64(binary.BigEndian.Uint64(key[len(prefix):]))
+			if !until.IsZero() && periodStart >= until.UnixNano() {
+				stop = true
+				return
+			}
+
+			c := &protoed.UsageCounters{}
+			seekErr = proto.Unmarshal(val, c)
+			if seekErr != nil {
+				seekErr = fmt.Errorf("failed to unmarshal the usage "+
+					"counters: %s", seekErr.Error())
+				return
+			}
+
+			counters = append(counters, c)
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the usage counters: %s",
+			err.Error())
+		return
+	}
+
+	return
+}
+
+// PruneUsage removes the usage counters of all the channels, including
+// the removed ones, whose periods ended before the given time.
+//
+// PruneUsage requires:
+// * t.access == RelayAccess
+func (t *Txn) PruneUsage(before time.Time) (removed uint64, err error) {
+	// Pre-condition
+	if !(t.access == RelayAccess) {
+		panic("Violated: t.access == RelayAccess")
+	}
+
+	// The keys are collected first and removed after the iteration.
+	var obsolete [][]byte
+
+	err = t.kv.seek(usageBucket, nil,
+		func(key []byte, val []byte) (stop bool, seekErr error) {
+			if len(key) < 10 || key[len(key)-10] != 0 {
+				seekErr = fmt.Errorf("invalid key of the usage counters: %#v",
+					string(key))
+				return
+			}
+
+			code := key[len(key)-9]
+			start := time.Unix(0,
+				int64(binary.BigEndian.Uint64(key[len(key)-8:])))
+
+			for _, period := range UsagePeriods {
+				if period.code() == code && period.End(start).Before(before) {
+					// The key is only valid within the iteration.
+					obsolete = append(obsolete, append([]byte(nil), key...))
+				}
+			}
+			return
+		})
+	if err != nil {
+		err = fmt.Errorf("error while browsing the usage counters: %s",
+			err.Error())
+		return
+	}
+
+	for _, key := range obsolete {
+		err = t.kv.remove(usageBucket, key)
+		if err != nil {
+			err = fmt.Errorf("failed to remove the usage counters: %s",
+				err.Error())
+			return
+		}
+		removed++
+	}
+
+	return
+}
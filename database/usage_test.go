@@ -0,0 +1,182 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestParseUsagePeriod(t *testing.T) {
+	for _, period := range UsagePeriods {
+		got, err := ParseUsagePeriod(string(period))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if got != period {
+			t.Errorf("expected the period %#v, got %#v", period, got)
+		}
+	}
+
+	_, err := ParseUsagePeriod("week")
+	if err == nil {
+		t.Error("expected an error for an unknown period, got nil")
+	}
+}
+
+func TestTxn_Usage(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	records := []*protoed.RelayRecord{
+		{Descriptor_: "client-1", Time: now.UnixNano(), Size: 100,
+			Outcome: protoed.RelayRecord_RELAYED},
+		{Descriptor_: "client-1", Time: now.Add(time.Minute).UnixNano(),
+			Outcome: protoed.RelayRecord_TOO_SOON},
+		{Descriptor_: "client-1", Time: now.Add(time.Hour).UnixNano(),
+			Size: 50, Outcome: protoed.RelayRecord_RELAYED},
+		{Descriptor_: "client-1", Time: now.Add(24 * time.Hour).UnixNano(),
+			Outcome: protoed.RelayRecord_FORBIDDEN},
+		{Descriptor_: "client-10", Time: now.UnixNano(), Size: 10,
+			Outcome: protoed.RelayRecord_RELAYED}}
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for _, record := range records {
+			txnErr = txn.CountUsage(record)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s.Access = ControlAccess
+	usage := func(period UsagePeriod, since time.Time,
+		until time.Time) (counters []*protoed.UsageCounters) {
+		err := s.View(func(txn *Txn) (txnErr error) {
+			counters, txnErr = txn.Usage("client-1", period, since, until)
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return
+	}
+
+	day := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	expected := []*protoed.UsageCounters{
+		{Start: day.UnixNano(), Relayed: 2, Bytes: 150, TooSoon: 1},
+		{Start: day.Add(24 * time.Hour).UnixNano(), Forbidden: 1}}
+
+	got := usage(Daily, time.Time{}, time.Time{})
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d daily counters, got %d: %v",
+			len(expected), len(got), got)
+	}
+	for i := range expected {
+		if !proto.Equal(got[i], expected[i]) {
+			t.Errorf("expected the daily counters %v at %d, got %v",
+				expected[i], i, got[i])
+		}
+	}
+
+	hour := time.Date(2018, 10, 1, 14, 0, 0, 0, time.UTC)
+	got = usage(Hourly, hour.Add(time.Hour), hour.Add(24*time.Hour))
+	if len(got) != 1 || !proto.Equal(got[0], &protoed.UsageCounters{
+		Start: hour.Add(time.Hour).UnixNano(), Relayed: 1, Bytes: 50}) {
+		t.Errorf("expected only the hourly counters of the second hour, "+
+			"got %v", got)
+	}
+
+	if got = usage(Hourly, now.Add(48*time.Hour), time.Time{}); len(got) != 0 {
+		t.Errorf("expected no counters after the last attempt, got %v", got)
+	}
+}
+
+func TestTxn_PruneUsage(t *testing.T) {
+	s := NewMemStore(RelayAccess)
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	records := []*protoed.RelayRecord{
+		{Descriptor_: "client-1", Time: now.UnixNano(),
+			Outcome: protoed.RelayRecord_RELAYED},
+		{Descriptor_: "client-1", Time: now.Add(time.Hour).UnixNano(),
+			Outcome: protoed.RelayRecord_RELAYED},
+		{Descriptor_: "client-removed", Time: now.UnixNano(),
+			Outcome: protoed.RelayRecord_FORBIDDEN}}
+
+	err := s.Update(func(txn *Txn) (txnErr error) {
+		for _, record := range records {
+			txnErr = txn.CountUsage(record)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Only the hourly counters of 14:00 ended before 15:30; the daily
+	// counters last until midnight.
+	var removed uint64
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		removed, txnErr = txn.PruneUsage(now.Add(53 * time.Minute))
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if removed != 2 {
+		t.Errorf("expected 2 removed counters, got %d", removed)
+	}
+
+	count := func(descriptor string, period UsagePeriod) int {
+		var counters []*protoed.UsageCounters
+		err := s.View(func(txn *Txn) (txnErr error) {
+			counters, txnErr = txn.Usage(descriptor, period,
+				time.Time{}, time.Time{})
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return len(counters)
+	}
+
+	for _, tc := range []struct {
+		descriptor string
+		period     UsagePeriod
+		expected   int
+	}{
+		{"client-1", Hourly, 1},
+		{"client-1", Daily, 1},
+		{"client-removed", Hourly, 0},
+		{"client-removed", Daily, 1}} {
+		if got := count(tc.descriptor, tc.period); got != tc.expected {
+			t.Errorf("expected %d %s counters of %s, got %d",
+				tc.expected, tc.period, tc.descriptor, got)
+		}
+	}
+
+	err = s.Update(func(txn *Txn) (txnErr error) {
+		removed, txnErr = txn.PruneUsage(now.Add(48 * time.Hour))
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if removed != 3 {
+		t.Errorf("expected the remaining 3 counters removed, got %d",
+			removed)
+	}
+}
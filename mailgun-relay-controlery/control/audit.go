@@ -0,0 +1,95 @@
+package control
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// EncodeAuditCursor encodes the time of the last listed audit record as
+// an opaque cursor.
+func EncodeAuditCursor(nanos int64) string {
+	bytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(bytes, uint64(nanos))
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeAuditCursor decodes the time of the last listed audit record from
+// the opaque cursor.
+func DecodeAuditCursor(cursor string) (nanos int64, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil && len(decoded) != 8 {
+		err = fmt.Errorf("expected 8 bytes, got %d", len(decoded))
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to decode the cursor %#v: %s",
+			cursor, err.Error())
+		return
+	}
+
+	nanos = int64(binary.BigEndian.Uint64(decoded))
+	return
+}
+
+// auditLog computes the response for an audit log request which continues
+// after the given time in nanoseconds; zero after starts from the beginning.
+//
+// auditLog requires:
+// * db != nil
+// * limit != 0
+// * filter.Since.IsZero() || filter.Until.IsZero() || !filter.Until.Before(filter.Since)
+//
+// auditLog ensures:
+// * err != nil || len(response.Records) <= int(limit)
+// * err != nil || response.NextCursor == nil || len(response.Records) == int(limit)
+func auditLog(filter database.AuditFilter, after int64, limit uint,
+	db database.Store) (response AuditLog, err error) {
+	// Pre-conditions
+	switch {
+	case !(db != nil):
+		panic("Violated: db != nil")
+	case !(limit != 0):
+		panic("Violated: limit != 0")
+	case !(filter.Since.IsZero() || filter.Until.IsZero() || !filter.Until.Before(filter.Since)):
+		panic("Violated: filter.Since.IsZero() || filter.Until.IsZero() || !filter.Until.Before(filter.Since)")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || len(response.Records) <= int(limit)):
+			panic("Violated: err != nil || len(response.Records) <= int(limit)")
+		case !(err != nil || response.NextCursor == nil || len(response.Records) == int(limit)):
+			panic("Violated: err != nil || response.NextCursor == nil || len(response.Records) == int(limit)")
+		default:
+			// Pass
+		}
+	}()
+
+	var records []*protoed.AuditRecord
+	var more bool
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		records, more, txnErr = txn.AuditRecords(filter, after, limit)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	response.Records = []AuditRecord{}
+	for _, record := range records {
+		response.Records = append(response.Records, AuditRecordToJSON(record))
+	}
+
+	if more {
+		cursor := EncodeAuditCursor(records[len(records)-1].Time)
+		response.NextCursor = &cursor
+	}
+
+	return
+}
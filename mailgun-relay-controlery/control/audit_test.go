@@ -0,0 +1,158 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Parquery/mailgun-relayery/database"
+)
+
+// serve sends the request through the router of the handler.
+func serve(h Handler, method string, target string, body string,
+	header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, value := range header {
+		r.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(w, r)
+	return w
+}
+
+// newTestHandler creates a handler on the store which discards the logs.
+func newTestHandler(db database.Store) *HandlerImpl {
+	return &HandlerImpl{Store: db,
+		LogErr:    log.New(ioutil.Discard, "", 0),
+		LogOut:    log.New(ioutil.Discard, "", 0),
+		Revisions: database.DefaultRevisions}
+}
+
+func TestHandlerImpl_GetAudit(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	h := newTestHandler(db)
+
+	header := map[string]string{"X-Actor": "ops@composers.com",
+		"User-Agent": "curl/7.58.0"}
+	channel := `{"descriptor": "client-1", "token": "%s",
+		"sender": {"email": "johann.bach@composers.com"},
+		"recipients": [{"email": "cpe.bach@composers.com"}],
+		"domain": "composers.com", "min_period": %s, "max_size": 1000}`
+
+	for _, body := range []string{
+		fmt.Sprintf(channel, "secret-1", "1"),
+		fmt.Sprintf(channel, "secret-2", "2"),
+	} {
+		w := serve(h, "PUT", "/api/channel", body, header)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the status %d on put, got %d: %s",
+				http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	// Removing a missing channel is not a change.
+	for _, descriptor := range []string{"client-1", "client-2"} {
+		w := serve(h, "DELETE", "/api/channel", `"`+descriptor+`"`, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the status %d on delete, got %d: %s",
+				http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	var records []AuditRecord
+	target := "/api/audit?descriptor=client-1&limit=2"
+	for {
+		w := serve(h, "GET", target, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the status %d on get, got %d: %s",
+				http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var page AuditLog
+		err = json.Unmarshal(w.Body.Bytes(), &page)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		records = append(records, page.Records...)
+		if page.NextCursor == nil {
+			break
+		}
+		target = "/api/audit?descriptor=client-1&limit=2&after=" +
+			*page.NextCursor
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("expected 3 audit records, got %d", len(records))
+	}
+
+	for i, op := range []string{"put", "put", "delete"} {
+		if records[i].Operation != op {
+			t.Errorf("expected the operation %s of the record %d, got %s",
+				op, i, records[i].Operation)
+		}
+	}
+
+	first, second, third := records[0], records[1], records[2]
+	switch {
+	case first.Before != nil || first.After == nil || first.TokenChanged:
+		t.Errorf("expected only the channel after the first put, got %#v",
+			first)
+	case first.Actor == nil || *first.Actor != "ops@composers.com":
+		t.Errorf("expected the actor from the header, got %#v", first.Actor)
+	case first.UserAgent == nil || *first.UserAgent != "curl/7.58.0":
+		t.Errorf("expected the user agent from the header, got %#v",
+			first.UserAgent)
+	case first.RemoteAddr == "":
+		t.Errorf("expected the remote address to be recorded")
+	case second.Before == nil || second.After == nil || !second.TokenChanged:
+		t.Errorf("expected both channels and a token change on the second "+
+			"put, got %#v", second)
+	case second.Before.MinPeriod != 1 || second.After.MinPeriod != 2:
+		t.Errorf("expected the min. periods 1 and 2, got %f and %f",
+			second.Before.MinPeriod, second.After.MinPeriod)
+	case third.Before == nil || third.After != nil || third.Actor != nil:
+		t.Errorf("expected only the channel before the delete, got %#v",
+			third)
+	default:
+		// pass
+	}
+
+	for i, record := range records {
+		for _, c := range []*Channel{record.Before, record.After} {
+			if c != nil && (c.Token != nil || c.TokenHash != nil) {
+				t.Errorf("expected the tokens to be redacted in the "+
+					"record %d, got %#v", i, c)
+			}
+		}
+	}
+
+	w := serve(h, "GET", "/api/audit?since=2018-10-01T14:37:00Z&"+
+		"until=2018-10-01T14:36:00Z", "", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected the status %d on until before since, got %d",
+			http.StatusBadRequest, w.Code)
+	}
+
+	w = serve(h, "GET", "/api/audit?after=invalid", "", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected the status %d on an invalid cursor, got %d",
+			http.StatusBadRequest, w.Code)
+	}
+}
@@ -1,18 +1,28 @@
 package control
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
 )
 
 // JSONToProto converts a parsed JSON channel to the protobuf channel
 // representation.
 //
+// The plain-text token, if given, is copied as-is and needs to be hashed
+// before the channel is stored. The last uses of the named tokens are
+// ignored since they are not part of the channel.
+//
 // JSONToProto requires:
 // * channel != nil
 //
 // JSONToProto ensures:
-// * protoChan != nil
-func JSONToProto(channel *Channel) (protoChan *protoed.Channel) {
+// * err != nil || protoChan != nil
+func JSONToProto(channel *Channel) (protoChan *protoed.Channel, err error) {
 	// Pre-condition
 	if !(channel != nil) {
 		panic("Violated: channel != nil")
@@ -20,26 +30,119 @@ func JSONToProto(channel *Channel) (protoChan *protoed.Channel) {
 
 	// Post-condition
 	defer func() {
-		if !(protoChan != nil) {
-			panic("Violated: protoChan != nil")
+		if !(err != nil || protoChan != nil) {
+			panic("Violated: err != nil || protoChan != nil")
 		}
 	}()
 
+	token := ""
+	if channel.Token != nil {
+		token = string(*channel.Token)
+	}
+
+	var hash *protoed.TokenHash
+	if channel.TokenHash != nil {
+		hash, err = tokenhash.Decode(*channel.TokenHash)
+		if err != nil {
+			err = fmt.Errorf("failed to decode the token hash: %s",
+				err.Error())
+			return
+		}
+	}
+
+	var tokens []*protoed.ChannelToken
+	for _, jsonToken := range channel.Tokens {
+		var protoToken *protoed.ChannelToken
+		protoToken, err = jsonToProtoToken(jsonToken)
+		if err != nil {
+			err = fmt.Errorf("failed to convert the token %#v: %s",
+				jsonToken.Name, err.Error())
+			return
+		}
+		tokens = append(tokens, protoToken)
+	}
+
+	var disabled *protoed.Disabled
+	if channel.Disabled != nil {
+		var disabledAt time.Time
+		disabledAt, err = time.Parse(time.RFC3339, channel.Disabled.Time)
+		if err != nil {
+			err = fmt.Errorf("failed to parse the time when the channel "+
+				"has been disabled: %s", err.Error())
+			return
+		}
+
+		disabled = &protoed.Disabled{Time: disabledAt.UnixNano()}
+		if channel.Disabled.Reason != nil {
+			disabled.Reason = *channel.Disabled.Reason
+		}
+	}
+
+	validUntil := int64(0)
+	if channel.ValidUntil != nil {
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, *channel.ValidUntil)
+		if err != nil {
+			err = fmt.Errorf("failed to parse the time until which "+
+				"the channel is valid: %s", err.Error())
+			return
+		}
+		validUntil = t.UnixNano()
+	}
+
+	var rateLimit *protoed.RateLimit
+	if channel.RateLimit != nil {
+		rateLimit = &protoed.RateLimit{Rate: channel.RateLimit.Rate,
+			Burst: uint32(channel.RateLimit.Burst)}
+	}
+
+	var protoQuota *protoed.Quota
+	if channel.Quota != nil {
+		protoQuota, err = jsonToProtoQuota(*channel.Quota)
+		if err != nil {
+			return
+		}
+	}
+
+	onThrottle := protoed.Channel_REJECT
+	if channel.OnThrottle != nil {
+		onThrottle, err = parseOnThrottle(*channel.OnThrottle)
+		if err != nil {
+			return
+		}
+	}
+
+	maxQueueDepth := uint32(0)
+	if channel.MaxQueueDepth != nil {
+		if *channel.MaxQueueDepth < 0 {
+			err = fmt.Errorf("expected a non-negative max_queue_depth, got %d",
+				*channel.MaxQueueDepth)
+			return
+		}
+		maxQueueDepth = uint32(*channel.MaxQueueDepth)
+	}
+
 	sender := jsonToProtoEntity(channel.Sender)
 	recipients := jsonToProtoEntityList(channel.Recipients)
 	cc := jsonToProtoEntityList(channel.Cc)
 	bcc := jsonToProtoEntityList(channel.Bcc)
 
 	protoChan = &protoed.Channel{Descriptor_: string(channel.Descriptor),
-		Token: string(channel.Token), Sender: sender,
+		Token: token, TokenHash: hash, Sender: sender,
 		Recipients: recipients, Cc: cc, Bcc: bcc, Domain: channel.Domain,
-		MinPeriod: channel.MinPeriod, MaxSize: channel.MaxSize}
+		MinPeriod: channel.MinPeriod, MaxSize: channel.MaxSize,
+		Disabled: disabled, ValidUntil: validUntil, Tokens: tokens,
+		RateLimit: rateLimit, Quota: protoQuota,
+		OnThrottle: onThrottle, MaxQueueDepth: maxQueueDepth}
 	return
 }
 
 // ProtoToJSON converts a protobuf channel to the parsed JSON channel
 // representation.
 //
+// The plain-text token is never included in the JSON representation,
+// only its hash. The last uses of the named tokens are left unset.
+//
 // ProtoToJSON requires:
 // * channel != nil
 //
@@ -63,10 +166,346 @@ func ProtoToJSON(channel *protoed.Channel) (jsonChan *Channel) {
 	cc := protoToJSONEntityList(channel.Cc)
 	bcc := protoToJSONEntityList(channel.Bcc)
 
+	var hash *string
+	if channel.TokenHash != nil &&
+		channel.TokenHash.Version == protoed.TokenHash_ARGON2ID {
+		encoded := tokenhash.Encode(channel.TokenHash)
+		hash = &encoded
+	}
+
+	var tokens []ChannelToken
+	for _, token := range channel.Tokens {
+		tokens = append(tokens, protoToJSONToken(token))
+	}
+
+	var disabled *Disabled
+	if channel.Disabled != nil {
+		disabled = &Disabled{Time: time.Unix(0, channel.Disabled.Time).
+			UTC().Format(time.RFC3339Nano)}
+		if channel.Disabled.Reason != "" {
+			reason := channel.Disabled.Reason
+			disabled.Reason = &reason
+		}
+	}
+
+	var validUntil *string
+	if channel.ValidUntil > 0 {
+		formatted := time.Unix(0, channel.ValidUntil).
+			UTC().Format(time.RFC3339Nano)
+		validUntil = &formatted
+	}
+
+	var rateLimit *RateLimit
+	if channel.RateLimit != nil {
+		rateLimit = &RateLimit{Rate: channel.RateLimit.Rate,
+			Burst: int32(channel.RateLimit.Burst)}
+	}
+
+	var jsonQuota *Quota
+	if channel.Quota != nil {
+		jsonQuota = protoToJSONQuota(channel.Quota)
+	}
+
+	var onThrottle *string
+	if channel.OnThrottle != protoed.Channel_REJECT {
+		name := strings.ToLower(channel.OnThrottle.String())
+		onThrottle = &name
+	}
+
+	var maxQueueDepth *int32
+	if channel.MaxQueueDepth > 0 {
+		depth := int32(channel.MaxQueueDepth)
+		maxQueueDepth = &depth
+	}
+
 	return &Channel{Descriptor: Descriptor(channel.Descriptor_),
-		Token: Token(channel.Token), Sender: sender,
+		TokenHash: hash, Sender: sender,
 		Recipients: recipients, Cc: cc, Bcc: bcc, Domain: channel.Domain,
-		MinPeriod: channel.MinPeriod, MaxSize: channel.MaxSize}
+		MinPeriod: channel.MinPeriod, MaxSize: channel.MaxSize,
+		Disabled: disabled, ValidUntil: validUntil, Tokens: tokens,
+		RateLimit: rateLimit, Quota: jsonQuota,
+		OnThrottle: onThrottle, MaxQueueDepth: maxQueueDepth}
+}
+
+// ValidateChannel validates the stored channel against the channel schema
+// as if it had been given in a request to the Control server.
+//
+// Since the plain-text token is never included in the JSON representation,
+// the channel needs no token to pass.
+//
+// ValidateChannel requires:
+// * channel != nil
+func ValidateChannel(channel *protoed.Channel) (err error) {
+	// Pre-condition
+	if !(channel != nil) {
+		panic("Violated: channel != nil")
+	}
+
+	serialized, err := json.Marshal(ProtoToJSON(channel))
+	if err != nil {
+		err = fmt.Errorf("failed to serialize the channel: %s", err.Error())
+		return
+	}
+
+	err = ValidateAgainstChannelSchema(serialized)
+	return
+}
+
+// RelayRecordToJSON converts a protobuf relay record to its JSON
+// representation.
+//
+// RelayRecordToJSON requires:
+// * record != nil
+func RelayRecordToJSON(record *protoed.RelayRecord) RelayRecord {
+	// Pre-condition
+	if !(record != nil) {
+		panic("Violated: record != nil")
+	}
+
+	var messageID *string
+	if record.MessageId != "" {
+		messageID = &record.MessageId
+	}
+
+	var token *string
+	if record.Token != "" {
+		token = &record.Token
+	}
+
+	return RelayRecord{Descriptor: Descriptor(record.Descriptor_),
+		Time:    time.Unix(0, record.Time).UTC().Format(time.RFC3339Nano),
+		Subject: record.Subject, Size: record.Size,
+		Outcome: strings.ToLower(record.Outcome.String()),
+		Status:  record.Status, MessageID: messageID, Token: token}
+}
+
+// UsageToJSON converts protobuf usage counters to their JSON
+// representation.
+//
+// UsageToJSON requires:
+// * counters != nil
+func UsageToJSON(counters *protoed.UsageCounters) Usage {
+	// Pre-condition
+	if !(counters != nil) {
+		panic("Violated: counters != nil")
+	}
+
+	return Usage{
+		Start:   time.Unix(0, counters.Start).UTC().Format(time.RFC3339Nano),
+		Relayed: int64(counters.Relayed), Bytes: int64(counters.Bytes),
+		Queued: int64(counters.Queued),
+		Rejected: Rejections{
+			Forbidden:     int64(counters.Forbidden),
+			TooSoon:       int64(counters.TooSoon),
+			TooLarge:      int64(counters.TooLarge),
+			Invalid:       int64(counters.Invalid),
+			Failed:        int64(counters.Failed),
+			Disabled:      int64(counters.Disabled),
+			Expired:       int64(counters.Expired),
+			QuotaExceeded: int64(counters.QuotaExceeded),
+			Throttled:     int64(counters.Throttled),
+			LockedOut:     int64(counters.LockedOut),
+			QueueFull:     int64(counters.QueueFull)}}
+}
+
+// LockoutToJSON converts a protobuf lockout to its JSON representation.
+//
+// LockoutToJSON requires:
+// * lockout != nil
+func LockoutToJSON(lockout *protoed.Lockout) Lockout {
+	// Pre-condition
+	if !(lockout != nil) {
+		panic("Violated: lockout != nil")
+	}
+
+	return Lockout{
+		Kind:     lockout.Kind,
+		Subject:  lockout.Subject,
+		Failures: int64(lockout.Failures),
+		LastFailure: time.Unix(0, lockout.LastFailure).UTC().Format(
+			time.RFC3339Nano),
+		LockedUntil: time.Unix(0, lockout.LockedUntil).UTC().Format(
+			time.RFC3339Nano)}
+}
+
+// AuditRecordToJSON converts a protobuf audit record to its JSON
+// representation.
+//
+// AuditRecordToJSON requires:
+// * record != nil
+func AuditRecordToJSON(record *protoed.AuditRecord) AuditRecord {
+	// Pre-condition
+	if !(record != nil) {
+		panic("Violated: record != nil")
+	}
+
+	optional := func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+
+	var before, after *Channel
+	if record.Before != nil {
+		before = ProtoToJSON(record.Before)
+	}
+	if record.After != nil {
+		after = ProtoToJSON(record.After)
+	}
+
+	return AuditRecord{Descriptor: Descriptor(record.Descriptor_),
+		Time:         time.Unix(0, record.Time).UTC().Format(time.RFC3339Nano),
+		Operation:    strings.ToLower(record.Operation.String()),
+		Actor:        optional(record.Actor),
+		RemoteAddr:   record.RemoteAddr,
+		ForwardedFor: optional(record.ForwardedFor),
+		UserAgent:    optional(record.UserAgent),
+		Before:       before, After: after,
+		TokenChanged: record.TokenChanged}
+}
+
+func jsonToProtoToken(token ChannelToken) (
+	protoToken *protoed.ChannelToken, err error) {
+	hash, err := tokenhash.Decode(token.TokenHash)
+	if err != nil {
+		err = fmt.Errorf("failed to decode the token hash: %s", err.Error())
+		return
+	}
+
+	protoToken = &protoed.ChannelToken{Name: token.Name, Hash: hash}
+
+	if token.Created != nil {
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, *token.Created)
+		if err != nil {
+			err = fmt.Errorf("failed to parse the creation time: %s",
+				err.Error())
+			return
+		}
+		protoToken.Created = t.UnixNano()
+	}
+
+	if token.Expires != nil {
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, *token.Expires)
+		if err != nil {
+			err = fmt.Errorf("failed to parse the expiry time: %s",
+				err.Error())
+			return
+		}
+		protoToken.Expires = t.UnixNano()
+	}
+
+	return
+}
+
+func protoToJSONToken(token *protoed.ChannelToken) ChannelToken {
+	optionalTime := func(nanos int64) *string {
+		if nanos == 0 {
+			return nil
+		}
+		formatted := time.Unix(0, nanos).UTC().Format(time.RFC3339Nano)
+		return &formatted
+	}
+
+	hash := ""
+	if token.Hash != nil && token.Hash.Version == protoed.TokenHash_ARGON2ID {
+		hash = tokenhash.Encode(token.Hash)
+	}
+
+	return ChannelToken{Name: token.Name, TokenHash: hash,
+		Created: optionalTime(token.Created),
+		Expires: optionalTime(token.Expires)}
+}
+
+func jsonToProtoQuota(quota Quota) (protoQuota *protoed.Quota, err error) {
+	optional := func(name string, value *int64) (result uint64, err error) {
+		if value == nil {
+			return
+		}
+		if *value < 0 {
+			err = fmt.Errorf("expected a non-negative quota of %s, got %d",
+				name, *value)
+			return
+		}
+		result = uint64(*value)
+		return
+	}
+
+	protoQuota = &protoed.Quota{}
+
+	protoQuota.DailyMessages, err = optional(
+		"daily messages", quota.DailyMessages)
+	if err != nil {
+		return
+	}
+
+	protoQuota.MonthlyMessages, err = optional(
+		"monthly messages", quota.MonthlyMessages)
+	if err != nil {
+		return
+	}
+
+	protoQuota.DailyBytes, err = optional("daily bytes", quota.DailyBytes)
+	if err != nil {
+		return
+	}
+
+	protoQuota.MonthlyBytes, err = optional(
+		"monthly bytes", quota.MonthlyBytes)
+	if err != nil {
+		return
+	}
+
+	if quota.Timezone != nil {
+		_, err = time.LoadLocation(*quota.Timezone)
+		if err != nil {
+			err = fmt.Errorf("failed to load the time zone of the quota: %s",
+				err.Error())
+			return
+		}
+		protoQuota.Timezone = *quota.Timezone
+	}
+
+	return
+}
+
+func protoToJSONQuota(quota *protoed.Quota) *Quota {
+	optional := func(value uint64) *int64 {
+		if value == 0 {
+			return nil
+		}
+		converted := int64(value)
+		return &converted
+	}
+
+	var timezone *string
+	if quota.Timezone != "" {
+		tz := quota.Timezone
+		timezone = &tz
+	}
+
+	return &Quota{DailyMessages: optional(quota.DailyMessages),
+		MonthlyMessages: optional(quota.MonthlyMessages),
+		DailyBytes:      optional(quota.DailyBytes),
+		MonthlyBytes:    optional(quota.MonthlyBytes),
+		Timezone:        timezone}
+}
+
+// parseOnThrottle parses the handling of the messages exceeding the rate
+// limit as given in the JSON representation of a channel.
+func parseOnThrottle(name string) (onThrottle protoed.Channel_OnThrottle,
+	err error) {
+	value, ok := protoed.Channel_OnThrottle_value[strings.ToUpper(name)]
+	if !ok || name != strings.ToLower(name) {
+		err = fmt.Errorf("expected on_throttle to be either \"reject\" or "+
+			"\"queue\", got %#v", name)
+		return
+	}
+
+	onThrottle = protoed.Channel_OnThrottle(value)
+	return
 }
 
 func jsonToProtoEntity(entity Entity) *protoed.Entity {
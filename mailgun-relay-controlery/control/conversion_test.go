@@ -2,11 +2,16 @@ package control
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
 
+	"github.com/Parquery/mailgun-relayery/database"
 	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
 	"strings"
 )
 
@@ -24,12 +29,16 @@ func TestJSONToProto(t *testing.T) {
 	bcc := []Entity{
 		{Name: &name4, Email: "robert.schumann@composers.com"}}
 	domain := "test.maildomain.com"
+	token := Token("oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU")
 
 	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
-		Token:  Token("oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU"),
+		Token:  &token,
 		Sender: sender, Recipients: recipients, Cc: cc, Bcc: bcc,
 		Domain: domain, MinPeriod: 0.0001, MaxSize: 10000000}
-	converted := JSONToProto(&jsonChan)
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
 
 	marshaler := jsonpb.Marshaler{OrigName: false}
 	protoChanStr, err := marshaler.MarshalToString(converted)
@@ -66,8 +75,9 @@ func TestProtoToJSON(t *testing.T) {
 	domain := "test.maildomain.com"
 
 	protoChan := &protoed.Channel{Descriptor_: "some-channel",
-		Token:  "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
-		Sender: &sender, Recipients: recipients, Cc: cc, Bcc: bcc,
+		Token:     "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
+		TokenHash: database.DummyTokenHash(),
+		Sender:    &sender, Recipients: recipients, Cc: cc, Bcc: bcc,
 		Domain: domain, MinPeriod: 0.0001, MaxSize: 10000000}
 	converted := ProtoToJSON(protoChan)
 
@@ -77,14 +87,15 @@ func TestProtoToJSON(t *testing.T) {
 	}
 	jsonChanStr := string(bts)
 	expected := dedent(`{"descriptor":"some-channel",
-					"token":"oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
 					"sender":{"email":"ludwig.van.beethoven@composers.com",
 					"name":"Ludwig van Beethoven"},"recipients":[{"email":
 					"johannes.brahms@composers.com","name":"Johannes Brahms"}],
 					"cc":[{"email":"richard.wagner@composers.com","name":
 					"Richard Wagner"}],"bcc":[{"email":"robert.schumann@
 					composers.com","name":"Robert Schumann"}],"domain":"test.
-					maildomain.com","min_period":0.0001,"max_size":10000000}`)
+					maildomain.com","min_period":0.0001,"max_size":10000000,
+					"token_hash":"$argon2id$v=19$m=65536,t=1,p=4$MDEyMzQ1Njc4
+					OWFiY2RlZg$MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"}`)
 
 	if expected != jsonChanStr {
 		t.Fatalf("expected %s\n, got %s", expected, jsonChanStr)
@@ -92,6 +103,322 @@ func TestProtoToJSON(t *testing.T) {
 
 }
 
+func TestTokenHashRoundTrip(t *testing.T) {
+	hash := tokenhash.Encode(database.DummyTokenHash())
+
+	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
+		TokenHash: &hash,
+		Sender:    Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain:    "test.maildomain.com", MinPeriod: 0.0001, MaxSize: 10000000}
+
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	if converted.Token != "" {
+		t.Fatalf("expected no plain-text token, got %#v", converted.Token)
+	}
+
+	back := ProtoToJSON(converted)
+	if back.TokenHash == nil || *back.TokenHash != hash {
+		t.Fatalf("expected token hash %#v, got %#v", hash, back.TokenHash)
+	}
+
+	invalid := "$argon2id$v=19$m=65536,t=1,p=4$not base64$"
+	jsonChan.TokenHash = &invalid
+	_, err = JSONToProto(&jsonChan)
+	if err == nil {
+		t.Fatalf("expected an error for the invalid token hash %#v", invalid)
+	}
+}
+
+func TestDisabledRoundTrip(t *testing.T) {
+	hash := tokenhash.Encode(database.DummyTokenHash())
+	reason := "sends a message every second"
+
+	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
+		TokenHash: &hash,
+		Sender:    Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain:    "test.maildomain.com", MinPeriod: 0.0001, MaxSize: 10000000,
+		Disabled: &Disabled{Time: "2018-10-01T14:37:00.123456789Z",
+			Reason: &reason}}
+
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	expected := time.Date(2018, 10, 1, 14, 37, 0, 123456789, time.UTC)
+	if converted.Disabled == nil ||
+		converted.Disabled.Time != expected.UnixNano() ||
+		converted.Disabled.Reason != reason {
+		t.Fatalf("expected the channel disabled at %s for %#v, got %v",
+			expected, reason, converted.Disabled)
+	}
+
+	back := ProtoToJSON(converted)
+	if back.Disabled == nil || back.Disabled.Time != jsonChan.Disabled.Time ||
+		back.Disabled.Reason == nil || *back.Disabled.Reason != reason {
+		t.Fatalf("expected %#v, got %#v", jsonChan.Disabled, back.Disabled)
+	}
+
+	jsonChan.Disabled = &Disabled{Time: "yesterday"}
+	_, err = JSONToProto(&jsonChan)
+	if err == nil {
+		t.Fatalf("expected an error for the invalid time %#v",
+			jsonChan.Disabled.Time)
+	}
+}
+
+func TestValidUntilRoundTrip(t *testing.T) {
+	hash := tokenhash.Encode(database.DummyTokenHash())
+	validUntil := "2018-10-31T23:59:59Z"
+
+	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
+		TokenHash: &hash,
+		Sender:    Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain:    "test.maildomain.com", MinPeriod: 0.0001, MaxSize: 10000000,
+		ValidUntil: &validUntil}
+
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	expected := time.Date(2018, 10, 31, 23, 59, 59, 0, time.UTC)
+	if converted.ValidUntil != expected.UnixNano() {
+		t.Fatalf("expected the channel valid until %s, got %d",
+			expected, converted.ValidUntil)
+	}
+
+	back := ProtoToJSON(converted)
+	if back.ValidUntil == nil || *back.ValidUntil != validUntil {
+		t.Fatalf("expected %#v, got %#v", validUntil, back.ValidUntil)
+	}
+
+	jsonChan.ValidUntil = nil
+	converted, err = JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	if converted.ValidUntil != 0 || ProtoToJSON(converted).ValidUntil != nil {
+		t.Fatalf("expected the channel to never expire, got %d",
+			converted.ValidUntil)
+	}
+}
+
+func TestTokensRoundTrip(t *testing.T) {
+	hash := tokenhash.Encode(database.DummyTokenHash())
+	created := "2018-10-01T14:37:00Z"
+	expires := "2018-10-02T14:37:00Z"
+
+	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
+		Tokens: []ChannelToken{
+			{Name: "default", TokenHash: hash, Created: &created,
+				Expires: &expires},
+			{Name: "ci-2", TokenHash: hash, Created: &created}},
+		Sender: Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain: "test.maildomain.com", MinPeriod: 0.0001, MaxSize: 10000000}
+
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	if converted.TokenHash != nil || len(converted.Tokens) != 2 {
+		t.Fatalf("expected only the named tokens, got %v", converted)
+	}
+
+	expected := time.Date(2018, 10, 2, 14, 37, 0, 0, time.UTC)
+	if converted.Tokens[0].Expires != expected.UnixNano() ||
+		converted.Tokens[1].Expires != 0 {
+		t.Fatalf("unexpected expiry times: %v", converted.Tokens)
+	}
+
+	back := ProtoToJSON(converted)
+	if !reflect.DeepEqual(jsonChan.Tokens, back.Tokens) {
+		t.Fatalf("expected the tokens %#v, got %#v", jsonChan.Tokens,
+			back.Tokens)
+	}
+}
+
+func TestRateLimitRoundTrip(t *testing.T) {
+	hash := tokenhash.Encode(database.DummyTokenHash())
+
+	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
+		TokenHash: &hash,
+		Sender:    Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain:    "test.maildomain.com", MinPeriod: 0.0001, MaxSize: 10000000,
+		RateLimit: &RateLimit{Rate: 0.5, Burst: 10}}
+
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	if converted.RateLimit == nil || converted.RateLimit.Rate != 0.5 ||
+		converted.RateLimit.Burst != 10 {
+		t.Fatalf("expected the rate limit 0.5 with the burst 10, got %v",
+			converted.RateLimit)
+	}
+
+	back := ProtoToJSON(converted)
+	if !reflect.DeepEqual(jsonChan.RateLimit, back.RateLimit) {
+		t.Fatalf("expected %#v, got %#v", jsonChan.RateLimit, back.RateLimit)
+	}
+
+	jsonChan.RateLimit = nil
+	converted, err = JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	if converted.RateLimit != nil || ProtoToJSON(converted).RateLimit != nil {
+		t.Fatalf("expected no rate limit, got %v", converted.RateLimit)
+	}
+}
+
+func TestQuotaRoundTrip(t *testing.T) {
+	hash := tokenhash.Encode(database.DummyTokenHash())
+	dailyMessages := int64(100)
+	monthlyBytes := int64(1000000)
+	timezone := "Europe/Zurich"
+
+	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
+		TokenHash: &hash,
+		Sender:    Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain:    "test.maildomain.com", MinPeriod: 0.0001, MaxSize: 10000000,
+		Quota: &Quota{DailyMessages: &dailyMessages,
+			MonthlyBytes: &monthlyBytes, Timezone: &timezone}}
+
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	expected := &protoed.Quota{DailyMessages: 100, MonthlyBytes: 1000000,
+		Timezone: "Europe/Zurich"}
+	if !proto.Equal(expected, converted.Quota) {
+		t.Fatalf("expected the quota %v, got %v", expected, converted.Quota)
+	}
+
+	back := ProtoToJSON(converted)
+	if !reflect.DeepEqual(jsonChan.Quota, back.Quota) {
+		t.Fatalf("expected %#v, got %#v", jsonChan.Quota, back.Quota)
+	}
+
+	unknown := "Mars/Olympus_Mons"
+	jsonChan.Quota.Timezone = &unknown
+	_, err = JSONToProto(&jsonChan)
+	if err == nil {
+		t.Fatalf("expected an error for an unknown time zone, got nil")
+	}
+}
+
+func TestOnThrottleRoundTrip(t *testing.T) {
+	hash := tokenhash.Encode(database.DummyTokenHash())
+	onThrottle := "queue"
+	maxQueueDepth := int32(10)
+
+	jsonChan := Channel{Descriptor: Descriptor("some-channel"),
+		TokenHash: &hash,
+		Sender:    Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Domain:    "test.maildomain.com", MinPeriod: 60, MaxSize: 10000000,
+		OnThrottle: &onThrottle, MaxQueueDepth: &maxQueueDepth}
+
+	converted, err := JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	if converted.OnThrottle != protoed.Channel_QUEUE ||
+		converted.MaxQueueDepth != 10 {
+		t.Fatalf("expected the queue of 10 messages, got %v", converted)
+	}
+
+	back := ProtoToJSON(converted)
+	if !reflect.DeepEqual(jsonChan.OnThrottle, back.OnThrottle) ||
+		!reflect.DeepEqual(jsonChan.MaxQueueDepth, back.MaxQueueDepth) {
+		t.Fatalf("expected %#v, got %#v", jsonChan, back)
+	}
+
+	// Rejecting is the default and is omitted.
+	reject := "reject"
+	jsonChan.OnThrottle = &reject
+	converted, err = JSONToProto(&jsonChan)
+	if err != nil {
+		t.Fatalf("failed to convert the channel: %s", err.Error())
+	}
+
+	if ProtoToJSON(converted).OnThrottle != nil {
+		t.Fatalf("expected no on_throttle, got %#v",
+			*ProtoToJSON(converted).OnThrottle)
+	}
+
+	for _, invalid := range []string{"QUEUE", "drop", ""} {
+		jsonChan.OnThrottle = &invalid
+		_, err = JSONToProto(&jsonChan)
+		if err == nil {
+			t.Fatalf("expected an error for on_throttle %#v, got nil",
+				invalid)
+		}
+	}
+}
+
+func TestValidateChannel(t *testing.T) {
+	channel := &protoed.Channel{Descriptor_: "some-channel",
+		TokenHash: database.DummyTokenHash(),
+		Sender:    &protoed.Entity{Email: "ludwig.van.beethoven@composers.com"},
+		Recipients: []*protoed.Entity{
+			{Email: "wolfgang.amadeus.mozart@composers.com"}},
+		Domain: "test.maildomain.com", MinPeriod: 0.0001, MaxSize: 10000000}
+
+	err := ValidateChannel(channel)
+	if err != nil {
+		t.Fatalf("expected the channel to be valid, got: %s", err.Error())
+	}
+
+	channel.Recipients = nil
+	err = ValidateChannel(channel)
+	if err == nil {
+		t.Fatalf("expected the channel without recipients to be invalid")
+	}
+}
+
+func TestRelayRecordToJSON(t *testing.T) {
+	record := &protoed.RelayRecord{Descriptor_: "client-1/pipeline-3",
+		Time:    time.Date(2018, 10, 1, 14, 37, 0, 123456789, time.UTC).UnixNano(),
+		Subject: "an alert", Size: 42,
+		Outcome: protoed.RelayRecord_TOO_SOON, Status: 429}
+
+	bb, err := json.Marshal(RelayRecordToJSON(record))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := `{"descriptor":"client-1/pipeline-3",` +
+		`"time":"2018-10-01T14:37:00.123456789Z","subject":"an alert",` +
+		`"size":42,"outcome":"too_soon","status":429}`
+	if string(bb) != expected {
+		t.Errorf("expected %s, got %s", expected, string(bb))
+	}
+
+	err = ValidateAgainstRelayRecordSchema(bb)
+	if err != nil {
+		t.Errorf("expected the record to validate, got: %s", err.Error())
+	}
+
+	record.Outcome = protoed.RelayRecord_RELAYED
+	record.MessageId = "<20181001143700.1.1@marketing.composers.com>"
+	got := RelayRecordToJSON(record)
+	if got.MessageID == nil || *got.MessageID != record.MessageId {
+		t.Errorf("expected the message id %s, got %v",
+			record.MessageId, got.MessageID)
+	}
+}
+
 func dedent(text string) string {
 	noTabs := strings.Replace(text, "\t", "", -1)
 	return strings.Replace(noTabs, "\n", "", -1)
@@ -0,0 +1,94 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Parquery/mailgun-relayery/database"
+)
+
+// channelETag formats the revision of a channel as a strong entity tag.
+func channelETag(revision uint64) string {
+	return fmt.Sprintf("\"%d\"", revision)
+}
+
+// currentETag returns the entity tag of the stored channel, or an empty
+// string if the channel does not exist.
+func currentETag(txn *database.Txn, descriptor string, exists bool) (
+	etag string, err error) {
+	if !exists {
+		return
+	}
+
+	revision, err := txn.LastRevision(descriptor)
+	if err != nil {
+		return
+	}
+
+	etag = channelETag(revision)
+	return
+}
+
+// matchesETag checks whether the comma-separated entity tags of
+// a conditional header match the entity tag of the channel. The wildcard
+// matches any existing channel while nothing matches a missing channel
+// (with an empty etag). The weak tags match only if weak is set.
+func matchesETag(header string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[len("W/"):]
+		}
+
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// preconditionFailed evaluates the If-Match and If-None-Match headers of
+// a request changing the channel against its entity tag, empty if
+// the channel does not exist.
+func preconditionFailed(r *http.Request, etag string) bool {
+	if values, ok := r.Header["If-Match"]; ok &&
+		!matchesETag(strings.Join(values, ","), etag, false) {
+		return true
+	}
+
+	if values, ok := r.Header["If-None-Match"]; ok &&
+		matchesETag(strings.Join(values, ","), etag, true) {
+		return true
+	}
+
+	return false
+}
+
+// respondPreconditionFailed rejects the request whose preconditions failed
+// and passes the current entity tag of the channel, if it exists.
+func (h *HandlerImpl) respondPreconditionFailed(w http.ResponseWriter,
+	r *http.Request, descriptor string, etag string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	http.Error(w, fmt.Sprintf("The channel with descriptor %s does not "+
+		"match the If-Match or the If-None-Match header.", descriptor),
+		http.StatusPreconditionFailed)
+	h.LogErr.Printf("%s: The channel with descriptor %s (ETag %s) does not "+
+		"match the preconditions of the request\n",
+		r.URL.String(), descriptor, etag)
+}
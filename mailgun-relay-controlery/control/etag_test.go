@@ -0,0 +1,26 @@
+package control
+
+import "testing"
+
+func TestMatchesETag(t *testing.T) {
+	for _, tt := range []struct {
+		header   string
+		etag     string
+		weak     bool
+		expected bool
+	}{
+		{`"3"`, `"3"`, false, true},
+		{`"2", "3"`, `"3"`, false, true},
+		{`"2"`, `"3"`, false, false},
+		{`*`, `"3"`, false, true},
+		{`*`, ``, false, false},
+		{`"3"`, ``, false, false},
+		{`W/"3"`, `"3"`, false, false},
+		{`W/"3"`, `"3"`, true, true},
+		{`3`, `"3"`, false, false}} {
+		if got := matchesETag(tt.header, tt.etag, tt.weak); got != tt.expected {
+			t.Errorf("expected matchesETag(%#v, %#v, %v) to be %v, got %v",
+				tt.header, tt.etag, tt.weak, tt.expected, got)
+		}
+	}
+}
@@ -13,9 +13,27 @@ type Handler interface {
 	//
 	// If there is already a channel associated with the descriptor, the old channel is overwritten with the new one.
 	//
-	// In order to enforce the min_period between messages, the Relay server keeps track of the time of the most
-	// recently relayed message for each descriptor. If a channel is overwritten, the time of relay of the most
-	// recent message is erased unless the new channel has the same min_period field as the old one.
+	// At most one of the token and the token hash can be given; they define the default token of the channel.
+	// A plain-text token is hashed with a random salt before it is stored; the token itself is never stored.
+	// A new channel needs either the default token or named tokens. If the token, the token hash and the tokens
+	// are omitted, an existing channel keeps its current tokens. If only the tokens are omitted, the named tokens
+	// of an existing channel are kept.
+	//
+	// In order to enforce the rate limit or the min_period between messages, the Relay server keeps track of the
+	// time of the most recently relayed message and of the messages left in the token bucket for each descriptor.
+	// If a channel is overwritten, this state is erased unless the new channel has the same min_period and
+	// rate_limit fields as the old one.
+	//
+	// If the disabled field is omitted, an existing channel keeps its current state so that an update does not
+	// enable a disabled channel by accident. Use /api/channel/{descriptor}/enable to enable it again.
+	//
+	// The change is recorded in the audit log together with the X-Actor header identifying the client,
+	// the remote address, the X-Forwarded-For and the User-Agent header.
+	//
+	// To avoid overwriting a concurrent change, pass the ETag of the channel as read from
+	// /api/channel/{descriptor} in the If-Match header; the update is rejected with 412 if the channel
+	// has changed since. Pass If-None-Match: * to create the channel only if it does not exist yet.
+	// The ETag of the stored channel is returned in the response.
 	PutChannel(w http.ResponseWriter,
 		r *http.Request,
 		channel Channel)
@@ -24,6 +42,9 @@ type Handler interface {
 	//
 	// Path description:
 	// removes the channel associated with the descriptor.
+	//
+	// The removal is recorded in the audit log like the updates.
+	// The If-Match and If-None-Match headers are honoured as on an update.
 	DeleteChannel(w http.ResponseWriter,
 		r *http.Request,
 		descriptor Descriptor)
@@ -32,10 +53,199 @@ type Handler interface {
 	//
 	// Path description:
 	// lists the available channels information.
+	//
+	// The channels are ordered by their descriptors. To walk through all the channels, pass the next_cursor of
+	// each page as the after parameter of the following request. Unlike the page indices, the cursors do not
+	// shift if the channels are inserted or removed between the requests.
+	//
+	// The listing can be narrowed down by the descriptor prefix, the domain, the sender, the recipient and
+	// the state of the channels. The domains and the email addresses are compared case-insensitively. Only the channels satisfying all
+	// the given criteria are listed and counted.
 	ListChannels(w http.ResponseWriter,
 		r *http.Request,
 		page *int32,
-		perPage *int32)
+		perPage *int32,
+		after *string,
+		prefix *string,
+		domain *string,
+		sender *string,
+		recipient *string,
+		disabled *bool)
+
+	// GetRelayLog handles the path `/api/relay_log` with the method "get".
+	//
+	// Path description:
+	// lists the attempts to relay a message through the channel, ordered by time.
+	//
+	// Every attempt through an existing channel is recorded, including the rejected and the failed ones.
+	// The Relay server prunes the records according to its retention settings.
+	GetRelayLog(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string,
+		since *string,
+		until *string,
+		limit *int32)
+
+	// GetAudit handles the path `/api/audit` with the method "get".
+	//
+	// Path description:
+	// lists the changes of the channels, ordered by time.
+	//
+	// Every update and removal of a channel is recorded with the channel before and after the change.
+	// The tokens and their hashes are redacted; token_changed indicates whether a new token has been stored.
+	// The audit log is append-only.
+	//
+	// To walk through all the changes, pass the next_cursor of each page as the after parameter of
+	// the following request.
+	GetAudit(w http.ResponseWriter,
+		r *http.Request,
+		descriptor *string,
+		since *string,
+		until *string,
+		limit *int32,
+		after *string)
+
+	// ListRevisions handles the path `/api/channel/{descriptor}/revisions` with the method "get".
+	//
+	// Path description:
+	// lists the kept revisions of the channel ordered by their numbers.
+	//
+	// Every update of a channel is stored as its next revision. Only the most recent revisions are kept
+	// (see -channel_revisions of the control server). The revisions are kept after the channel has been removed
+	// so that a removed channel can be rolled back as well.
+	//
+	// Each revision lists the fields which changed since the previous kept revision.
+	// The descriptor may contain slashes.
+	ListRevisions(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string)
+
+	// RollbackChannel handles the path `/api/channel/{descriptor}/rollback` with the method "post".
+	//
+	// Path description:
+	// restores the channel to the given revision.
+	//
+	// Only the configuration of the channel is restored; the current tokens, the disabled state and
+	// valid_until are kept so that a rollback neither brings back a revoked token nor enables a disabled
+	// channel. The restored channel is stored as the next revision and the rollback is recorded in the audit log.
+	// The time of the most recently relayed message is erased unless the restored channel has the same
+	// min_period as the current one, just as on an update.
+	//
+	// The If-Match and If-None-Match headers are honoured as on an update and the ETag of the restored
+	// channel is returned in the response.
+	// The descriptor may contain slashes.
+	RollbackChannel(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string,
+		rollback Rollback)
+
+	// DisableChannel handles the path `/api/channel/{descriptor}/disable` with the method "post".
+	//
+	// Path description:
+	// disables the channel without removing it.
+	//
+	// The Relay server refuses the messages of a disabled channel with 423 Locked, while the token,
+	// the recipients and the time of the most recently relayed message are kept.
+	// Disabling an already disabled channel leaves it untouched.
+	//
+	// The change is stored as the next revision and recorded in the audit log.
+	// The descriptor may contain slashes.
+	DisableChannel(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string,
+		disable Disable)
+
+	// EnableChannel handles the path `/api/channel/{descriptor}/enable` with the method "post".
+	//
+	// Path description:
+	// enables the disabled channel again.
+	//
+	// Enabling an already enabled channel leaves it untouched.
+	//
+	// The change is stored as the next revision and recorded in the audit log.
+	// The descriptor may contain slashes.
+	EnableChannel(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string)
+
+	// RotateToken handles the path `/api/channel/{descriptor}/rotate` with the method "post".
+	//
+	// Path description:
+	// issues a new token generated by the server and lets all the other tokens of the channel expire
+	// after the grace period.
+	//
+	// The new token is returned only in the response and never stored in plain text.
+	// The tokens which already expired are removed.
+	//
+	// The change is stored as the next revision and recorded in the audit log.
+	// The descriptor may contain slashes.
+	RotateToken(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string,
+		rotate Rotate)
+
+	// RevokeToken handles the path `/api/channel/{descriptor}/tokens/{name}/revoke` with the method "post".
+	//
+	// Path description:
+	// revokes the token of the channel immediately.
+	//
+	// The last live token of a channel can not be revoked; disable the channel instead.
+	//
+	// The change is stored as the next revision and recorded in the audit log.
+	// The descriptor may contain slashes.
+	RevokeToken(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string,
+		name string)
+
+	// GetChannelStats handles the path `/api/channel/{descriptor}/stats` with the method "get".
+	//
+	// Path description:
+	// lists the usage counters of the channel per hour or per day, ordered by time.
+	//
+	// The Relay server counts every attempt through an existing channel: the relayed messages and their size
+	// as well as the rejected attempts by reason. The periods without any attempt are omitted and the counters
+	// are kept after the channel has been removed.
+	// The descriptor may contain slashes.
+	GetChannelStats(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string,
+		period *string,
+		since *string,
+		until *string)
+
+	// GetLockouts handles the path `/api/lockouts` with the method "get".
+	//
+	// Path description:
+	// lists the remote IPs and the descriptors currently locked out after failed authentications.
+	//
+	// The Relay server counts the failed authentications per remote IP and per descriptor, regardless of
+	// whether the channel exists. Once the failures reach the threshold, the subject is locked out for
+	// a duration which doubles with each further failure up to a maximum.
+	GetLockouts(w http.ResponseWriter,
+		r *http.Request)
+
+	// Unlock handles the path `/api/unlock` with the method "post".
+	//
+	// Path description:
+	// lifts the lockout of a remote IP or of a descriptor and forgets its failed authentications.
+	Unlock(w http.ResponseWriter,
+		r *http.Request,
+		unlock Unlock)
+
+	// GetChannel handles the path `/api/channel/{descriptor}` with the method "get".
+	//
+	// Path description:
+	// serves the channel together with its revision as the ETag header.
+	//
+	// The revision is the number of the latest revision of the channel (see /api/channel/{descriptor}/revisions)
+	// and increases with every change of the channel, also after the channel has been removed and created again.
+	// Pass the ETag in the If-Match header of an update or a removal to detect concurrent changes.
+	// If the If-None-Match header matches the ETag, the channel is not served and 304 is returned.
+	// The descriptor may contain slashes.
+	GetChannel(w http.ResponseWriter,
+		r *http.Request,
+		descriptor string)
 }
 
 // Automatically generated file by swagger_to. DO NOT EDIT OR APPEND ANYTHING!
@@ -5,16 +5,54 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
 
 	"github.com/Parquery/mailgun-relayery/database"
 	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
 )
 
 // HandlerImpl implements the Handler.
 type HandlerImpl struct {
 	LogErr *log.Logger
 	LogOut *log.Logger
-	Env    *database.Env
+	Store  database.Store
+
+	// Revisions is the number of the revisions kept per channel.
+	Revisions uint
+
+	// TokenGrace is the default period after which the other tokens of
+	// a channel expire on rotation.
+	TokenGrace time.Duration
+}
+
+// requestActor identifies the client of the request for the audit log.
+func requestActor(r *http.Request) database.Actor {
+	return database.Actor{
+		Name:         r.Header.Get("X-Actor"),
+		RemoteAddr:   r.RemoteAddr,
+		ForwardedFor: r.Header.Get("X-Forwarded-For"),
+		UserAgent:    r.Header.Get("User-Agent")}
+}
+
+// validDescriptor checks the descriptor with database.ValidateDescriptor
+// before any change is written to the database. An invalid descriptor is
+// refused with 400 Bad Request.
+func (h *HandlerImpl) validDescriptor(w http.ResponseWriter,
+	r *http.Request, descriptor string) bool {
+	err := database.ValidateDescriptor(descriptor)
+	if err != nil {
+		http.Error(w, "Invalid descriptor: "+err.Error(),
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received an invalid descriptor: %s\n",
+			r.URL.String(), err.Error())
+		return false
+	}
+
+	return true
 }
 
 // PutChannel implements Handler.PutChannel.
@@ -22,11 +60,113 @@ func (h *HandlerImpl) PutChannel(w http.ResponseWriter,
 	r *http.Request,
 	channel Channel) {
 
-	protoChan := JSONToProto(&channel)
-	dbErr := h.Env.Update(func(txn *database.Txn) (txnErr error) {
+	if !h.validDescriptor(w, r, string(channel.Descriptor)) {
+		return
+	}
+
+	if channel.Token != nil && channel.TokenHash != nil {
+		http.Error(w, "Expected at most one of 'token' and 'token_hash'.",
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received a channel with both "+
+			"the token and the token hash\n", r.URL.String())
+		return
+	}
+
+	protoChan, err := JSONToProto(&channel)
+	if err != nil {
+		http.Error(w, "Failed to convert the channel: "+err.Error(),
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Failed to convert the channel: %s\n",
+			r.URL.String(), err.Error())
+		return
+	}
+
+	if channel.Token != nil {
+		protoChan.TokenHash, err = tokenhash.New(protoChan.Token)
+		if err != nil {
+			http.Error(w, "Failed to hash the token.",
+				http.StatusInternalServerError)
+			h.LogErr.Printf("%s: Failed to hash the token: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+		protoChan.Token = ""
+	}
+
+	now := time.Now()
+	for _, token := range protoChan.Tokens {
+		if token.Created == 0 {
+			token.Created = now.UnixNano()
+		}
+	}
+
+	actor := requestActor(r)
+	var invalidTokens error
+	var preconditionETag *string
+	var etag string
+	dbErr := h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		var before *protoed.Channel
+		before, txnErr = txn.GetChannel(protoChan.Descriptor_)
+		if txnErr != nil {
+			return
+		}
+
+		etag, txnErr = currentETag(txn, protoChan.Descriptor_, before != nil)
+		if txnErr != nil {
+			return
+		}
+
+		if preconditionFailed(r, etag) {
+			preconditionETag = &etag
+			return
+		}
+
+		if before != nil {
+			// An update must not enable a disabled channel by accident.
+			if protoChan.Disabled == nil {
+				protoChan.Disabled = before.Disabled
+			}
+
+			// The omitted tokens are kept.
+			if channel.Token == nil && channel.TokenHash == nil {
+				protoChan.TokenHash = before.TokenHash
+			}
+			if channel.Tokens == nil {
+				protoChan.Tokens = before.Tokens
+			}
+		}
+
+		invalidTokens = validateTokens(protoChan)
+		if invalidTokens != nil {
+			return
+		}
+
 		txnErr = txn.PutChannel(protoChan)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.RecordChange(protoed.AuditRecord_PUT, before, protoChan,
+			actor, now, h.Revisions)
+		if txnErr != nil {
+			return
+		}
+
+		etag, txnErr = currentETag(txn, protoChan.Descriptor_, true)
 		return
 	})
+	if preconditionETag != nil && dbErr == nil {
+		h.respondPreconditionFailed(w, r, protoChan.Descriptor_,
+			*preconditionETag)
+		return
+	}
+	if invalidTokens != nil && dbErr == nil {
+		http.Error(w, "Invalid tokens: "+invalidTokens.Error(),
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received invalid tokens: %s\n",
+			r.URL.String(), invalidTokens.Error())
+		return
+	}
 	if dbErr != nil {
 		http.Error(w,
 			"Failed to store the channel.",
@@ -36,8 +176,9 @@ func (h *HandlerImpl) PutChannel(w http.ResponseWriter,
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte(fmt.Sprintf("The channel with descriptor"+
+	_, err = w.Write([]byte(fmt.Sprintf("The channel with descriptor"+
 		" %s was correctly stored.", protoChan.Descriptor_)))
 	if err != nil {
 		h.LogErr.Printf("%s: Error while writing to the response "+
@@ -48,6 +189,29 @@ func (h *HandlerImpl) PutChannel(w http.ResponseWriter,
 		r.URL.String(), protoChan.Descriptor_)
 }
 
+// validateTokens checks that the channel has at least one token and that
+// the names of its tokens are unique.
+func validateTokens(channel *protoed.Channel) error {
+	if channel.TokenHash == nil && len(channel.Tokens) == 0 {
+		return fmt.Errorf("expected the default token or at least " +
+			"one named token")
+	}
+
+	names := make(map[string]bool)
+	if channel.TokenHash != nil {
+		names[tokenhash.DefaultName] = true
+	}
+
+	for _, token := range channel.Tokens {
+		if names[token.Name] {
+			return fmt.Errorf("duplicate token name: %#v", token.Name)
+		}
+		names[token.Name] = true
+	}
+
+	return nil
+}
+
 // DeleteChannel implements Handler.DeleteChannel.
 func (h *HandlerImpl) DeleteChannel(w http.ResponseWriter,
 	r *http.Request,
@@ -55,8 +219,14 @@ func (h *HandlerImpl) DeleteChannel(w http.ResponseWriter,
 
 	descriptorStr := string(descriptor)
 	var protoChan *protoed.Channel
-	err := h.Env.View(func(txn *database.Txn) (txnErr error) {
+	var etag string
+	err := h.Store.View(func(txn *database.Txn) (txnErr error) {
 		protoChan, txnErr = txn.GetChannel(descriptorStr)
+		if txnErr != nil {
+			return
+		}
+
+		etag, txnErr = currentETag(txn, descriptorStr, protoChan != nil)
 		return
 	})
 	if err != nil {
@@ -66,6 +236,10 @@ func (h *HandlerImpl) DeleteChannel(w http.ResponseWriter,
 			"in the database: %s\n", r.URL.String(), err.Error())
 		return
 	}
+	if preconditionFailed(r, etag) {
+		h.respondPreconditionFailed(w, r, descriptorStr, etag)
+		return
+	}
 	if protoChan == nil {
 		w.WriteHeader(http.StatusOK)
 		msg := fmt.Sprintf("No channel associated to "+
@@ -80,10 +254,43 @@ func (h *HandlerImpl) DeleteChannel(w http.ResponseWriter,
 		return
 	}
 
-	err = h.Env.Update(func(txn *database.Txn) (txnErr error) {
+	actor := requestActor(r)
+	var preconditionETag *string
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		var before *protoed.Channel
+		before, txnErr = txn.GetChannel(descriptorStr)
+		if txnErr != nil {
+			return
+		}
+
+		// The channel might have changed since it has been checked.
+		etag, txnErr = currentETag(txn, descriptorStr, before != nil)
+		if txnErr != nil {
+			return
+		}
+
+		if preconditionFailed(r, etag) {
+			preconditionETag = &etag
+			return
+		}
+
+		if before == nil {
+			return
+		}
+
 		txnErr = txn.RemoveChannel(descriptorStr)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.RecordChange(protoed.AuditRecord_DELETE, before, nil,
+			actor, time.Now(), h.Revisions)
 		return
 	})
+	if preconditionETag != nil && err == nil {
+		h.respondPreconditionFailed(w, r, descriptorStr, *preconditionETag)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to erase the channel.",
 			http.StatusInternalServerError)
@@ -109,7 +316,21 @@ func (h *HandlerImpl) DeleteChannel(w http.ResponseWriter,
 func (h *HandlerImpl) ListChannels(w http.ResponseWriter,
 	r *http.Request,
 	page *int32,
-	perPage *int32) {
+	perPage *int32,
+	after *string,
+	prefix *string,
+	domain *string,
+	sender *string,
+	recipient *string,
+	disabled *bool) {
+
+	if page != nil && after != nil {
+		http.Error(w, "Expected at most one of 'page' and 'after'.",
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received both a page number and "+
+			"a cursor\n", r.URL.String())
+		return
+	}
 
 	pageNr := uint(1)
 	if page != nil {
@@ -136,12 +357,44 @@ func (h *HandlerImpl) ListChannels(w http.ResponseWriter,
 		perPageNr = uint(*perPage)
 	}
 
-	channelsPage, err := paginateChannels(pageNr, perPageNr, h.Env)
+	filter := database.ChannelFilter{}
+	if prefix != nil {
+		filter.Prefix = *prefix
+	}
+	if domain != nil {
+		filter.Domain = *domain
+	}
+	if sender != nil {
+		filter.Sender = *sender
+	}
+	if recipient != nil {
+		filter.Recipient = *recipient
+	}
+	filter.Disabled = disabled
+
+	var channelsPage ChannelsPage
+	var err error
+	if after != nil {
+		var afterDescriptor Descriptor
+		afterDescriptor, err = DecodeCursor(*after)
+		if err != nil {
+			http.Error(w, "Invalid cursor.", http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid cursor: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+
+		channelsPage, err = channelsAfter(afterDescriptor, perPageNr, filter,
+			h.Store)
+	} else {
+		channelsPage, err = paginateChannels(pageNr, perPageNr, filter, h.Store)
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch the channel listing response.",
 			http.StatusInternalServerError)
 		h.LogErr.Printf("%s: Failed to fetch the channel listing "+
 			"from the database: %s\n", r.URL.String(), err.Error())
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -155,3 +408,836 @@ func (h *HandlerImpl) ListChannels(w http.ResponseWriter,
 	}
 
 }
+
+// GetRelayLog implements Handler.GetRelayLog.
+func (h *HandlerImpl) GetRelayLog(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string,
+	since *string,
+	until *string,
+	limit *int32) {
+
+	var sinceTime, untilTime time.Time
+	var err error
+	if since != nil {
+		sinceTime, err = time.Parse(time.RFC3339, *since)
+		if err != nil {
+			http.Error(w, "Invalid 'since': "+err.Error(),
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid since: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	if until != nil {
+		untilTime, err = time.Parse(time.RFC3339, *until)
+		if err != nil {
+			http.Error(w, "Invalid 'until': "+err.Error(),
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid until: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	if since != nil && until != nil && untilTime.Before(sinceTime) {
+		http.Error(w, "'until' before 'since' is not allowed.",
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received until (%s) before since (%s)\n",
+			r.URL.String(), *until, *since)
+		return
+	}
+
+	limitNr := uint(1000)
+	if limit != nil {
+		if *limit <= 0 {
+			http.Error(w, "Limit smaller than 1 is not allowed.",
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received a limit smaller than "+
+				"1 (%d)\n", r.URL.String(), *limit)
+			return
+		}
+		limitNr = uint(*limit)
+	}
+
+	response, err := relayLog(descriptor, sinceTime, untilTime, limitNr, h.Store)
+	if err != nil {
+		http.Error(w, "Failed to fetch the relay log.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the relay log "+
+			"from the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&response)
+
+	if err != nil {
+		http.Error(w, "Failed to marshal the relay log response.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to marshal the relay log "+
+			"response: %s\n", r.URL.String(), err.Error())
+	}
+}
+
+// GetAudit implements Handler.GetAudit.
+func (h *HandlerImpl) GetAudit(w http.ResponseWriter,
+	r *http.Request,
+	descriptor *string,
+	since *string,
+	until *string,
+	limit *int32,
+	after *string) {
+
+	filter := database.AuditFilter{}
+	if descriptor != nil {
+		filter.Descriptor = *descriptor
+	}
+
+	var err error
+	if since != nil {
+		filter.Since, err = time.Parse(time.RFC3339, *since)
+		if err != nil {
+			http.Error(w, "Invalid 'since': "+err.Error(),
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid since: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	if until != nil {
+		filter.Until, err = time.Parse(time.RFC3339, *until)
+		if err != nil {
+			http.Error(w, "Invalid 'until': "+err.Error(),
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid until: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	if since != nil && until != nil && filter.Until.Before(filter.Since) {
+		http.Error(w, "'until' before 'since' is not allowed.",
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received until (%s) before since (%s)\n",
+			r.URL.String(), *until, *since)
+		return
+	}
+
+	limitNr := uint(100)
+	if limit != nil {
+		if *limit <= 0 {
+			http.Error(w, "Limit smaller than 1 is not allowed.",
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received a limit smaller than "+
+				"1 (%d)\n", r.URL.String(), *limit)
+			return
+		}
+		limitNr = uint(*limit)
+	}
+
+	afterNanos := int64(0)
+	if after != nil {
+		afterNanos, err = DecodeAuditCursor(*after)
+		if err != nil {
+			http.Error(w, "Invalid cursor.", http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid cursor: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	response, err := auditLog(filter, afterNanos, limitNr, h.Store)
+	if err != nil {
+		http.Error(w, "Failed to fetch the audit log.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the audit log "+
+			"from the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&response)
+
+	if err != nil {
+		http.Error(w, "Failed to marshal the audit log response.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to marshal the audit log "+
+			"response: %s\n", r.URL.String(), err.Error())
+	}
+}
+
+// ListRevisions implements Handler.ListRevisions.
+func (h *HandlerImpl) ListRevisions(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string) {
+
+	response, err := channelRevisions(descriptor, h.Store)
+	if err != nil {
+		http.Error(w, "Failed to fetch the revisions.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the revisions "+
+			"from the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&response)
+
+	if err != nil {
+		http.Error(w, "Failed to marshal the revisions response.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to marshal the revisions "+
+			"response: %s\n", r.URL.String(), err.Error())
+	}
+}
+
+// RollbackChannel implements Handler.RollbackChannel.
+func (h *HandlerImpl) RollbackChannel(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string,
+	rollback Rollback) {
+
+	if !h.validDescriptor(w, r, descriptor) {
+		return
+	}
+
+	if rollback.Revision <= 0 {
+		http.Error(w, "Revision smaller than 1 is not allowed.",
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received a revision smaller than "+
+			"1 (%d)\n", r.URL.String(), rollback.Revision)
+		return
+	}
+
+	actor := requestActor(r)
+	found := false
+	var preconditionETag *string
+	var etag string
+	err := h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		var before *protoed.Channel
+		before, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil {
+			return
+		}
+
+		etag, txnErr = currentETag(txn, descriptor, before != nil)
+		if txnErr != nil {
+			return
+		}
+
+		if preconditionFailed(r, etag) {
+			preconditionETag = &etag
+			return
+		}
+
+		var revision *protoed.ChannelRevision
+		revision, txnErr = txn.GetRevision(descriptor,
+			uint64(rollback.Revision))
+		if txnErr != nil || revision == nil {
+			return
+		}
+		found = true
+
+		// The tokens and the lifecycle state are taken from the current
+		// channel, or from its last revision if it has been removed, so
+		// that a rollback does not bring back the revoked tokens or
+		// enable a disabled channel.
+		current := before
+		if current == nil {
+			var last uint64
+			last, txnErr = txn.LastRevision(descriptor)
+			if txnErr != nil {
+				return
+			}
+
+			var lastRevision *protoed.ChannelRevision
+			lastRevision, txnErr = txn.GetRevision(descriptor, last)
+			if txnErr != nil {
+				return
+			}
+			current = lastRevision.Channel
+		}
+
+		// PutChannel erases the timestamp if the min. period changes.
+		restored := proto.Clone(revision.Channel).(*protoed.Channel)
+		restored.TokenHash = current.TokenHash
+		restored.Tokens = current.Tokens
+		restored.Disabled = current.Disabled
+		restored.ValidUntil = current.ValidUntil
+
+		txnErr = txn.PutChannel(restored)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.RecordChange(protoed.AuditRecord_ROLLBACK, before,
+			restored, actor, time.Now(), h.Revisions)
+		if txnErr != nil {
+			return
+		}
+
+		etag, txnErr = currentETag(txn, descriptor, true)
+		return
+	})
+	if preconditionETag != nil && err == nil {
+		h.respondPreconditionFailed(w, r, descriptor, *preconditionETag)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to roll back the channel.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to roll back the channel in the "+
+			"database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	if !found {
+		msg := fmt.Sprintf("The revision %d of the channel with "+
+			"descriptor %s is not kept.", rollback.Revision, descriptor)
+		http.Error(w, msg, http.StatusNotFound)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(fmt.Sprintf("The channel with descriptor %s "+
+		"was rolled back to the revision %d.", descriptor,
+		rollback.Revision)))
+	if err != nil {
+		h.LogErr.Printf("%s: Error while writing to the response "+
+			"writer: %s\n", r.URL.String(), err.Error())
+	}
+	h.LogOut.Printf("%s: The channel with descriptor %s was rolled "+
+		"back to the revision %d.\n", r.URL.String(), descriptor,
+		rollback.Revision)
+}
+
+// DisableChannel implements Handler.DisableChannel.
+func (h *HandlerImpl) DisableChannel(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string,
+	disable Disable) {
+
+	disabled := &protoed.Disabled{Time: time.Now().UnixNano()}
+	if disable.Reason != nil {
+		disabled.Reason = *disable.Reason
+	}
+
+	h.setChannelState(w, r, descriptor, disabled)
+}
+
+// EnableChannel implements Handler.EnableChannel.
+func (h *HandlerImpl) EnableChannel(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string) {
+
+	h.setChannelState(w, r, descriptor, nil)
+}
+
+// setChannelState disables the channel if disabled is given and enables it
+// otherwise. A channel already in the requested state is left untouched.
+func (h *HandlerImpl) setChannelState(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string,
+	disabled *protoed.Disabled) {
+
+	if !h.validDescriptor(w, r, descriptor) {
+		return
+	}
+
+	state := "enabled"
+	operation := protoed.AuditRecord_ENABLE
+	if disabled != nil {
+		state = "disabled"
+		operation = protoed.AuditRecord_DISABLE
+	}
+
+	actor := requestActor(r)
+	found := false
+	changed := false
+	err := h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		var before *protoed.Channel
+		before, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil || before == nil {
+			return
+		}
+		found = true
+
+		if (before.Disabled != nil) == (disabled != nil) {
+			return
+		}
+		changed = true
+
+		after := proto.Clone(before).(*protoed.Channel)
+		after.Disabled = disabled
+		txnErr = txn.PutChannel(after)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.RecordChange(operation, before, after, actor, time.Now(),
+			h.Revisions)
+		return
+	})
+	if err != nil {
+		http.Error(w, "Failed to change the state of the channel.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to change the state of the channel in "+
+			"the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	if !found {
+		msg := fmt.Sprintf(
+			"No channel was found for the descriptor: %s", descriptor)
+		http.Error(w, msg, http.StatusNotFound)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	msg := fmt.Sprintf("The channel with descriptor %s was %s.",
+		descriptor, state)
+	if !changed {
+		msg = fmt.Sprintf("The channel with descriptor %s was already %s.",
+			descriptor, state)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(msg))
+	if err != nil {
+		h.LogErr.Printf("%s: Error while writing to the response "+
+			"writer: %s\n", r.URL.String(), err.Error())
+	}
+	h.LogOut.Printf("%s: %s\n", r.URL.String(), msg)
+}
+
+// RotateToken implements Handler.RotateToken.
+func (h *HandlerImpl) RotateToken(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string,
+	rotate Rotate) {
+
+	if !h.validDescriptor(w, r, descriptor) {
+		return
+	}
+
+	grace := h.TokenGrace
+	if rotate.GracePeriod != nil {
+		grace = time.Duration(float64(*rotate.GracePeriod) * float64(time.Second))
+	}
+
+	token, err := tokenhash.Generate()
+	if err != nil {
+		http.Error(w, "Failed to generate the token.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to generate the token: %s\n",
+			r.URL.String(), err.Error())
+		return
+	}
+
+	hash, err := tokenhash.New(token)
+	if err != nil {
+		http.Error(w, "Failed to hash the token.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to hash the token: %s\n",
+			r.URL.String(), err.Error())
+		return
+	}
+
+	now := time.Now()
+	expires := now.Add(grace).UnixNano()
+
+	actor := requestActor(r)
+	found := false
+	conflict := false
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		var before *protoed.Channel
+		before, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil || before == nil {
+			return
+		}
+		found = true
+
+		// The default token becomes a named token so that it can expire.
+		after := proto.Clone(before).(*protoed.Channel)
+		after.TokenHash = nil
+		after.Tokens = nil
+
+		others := proto.Clone(before).(*protoed.Channel).Tokens
+		if before.TokenHash != nil {
+			others = append([]*protoed.ChannelToken{{
+				Name: tokenhash.DefaultName, Hash: before.TokenHash}},
+				others...)
+		}
+
+		for _, other := range others {
+			if !tokenhash.Live(other, now) {
+				continue
+			}
+
+			if other.Name == rotate.Name {
+				conflict = true
+				return
+			}
+
+			if other.Expires == 0 || other.Expires > expires {
+				other.Expires = expires
+			}
+			after.Tokens = append(after.Tokens, other)
+		}
+
+		after.Tokens = append(after.Tokens, &protoed.ChannelToken{
+			Name: rotate.Name, Hash: hash, Created: now.UnixNano()})
+
+		// PutChannel erases the last uses of the removed tokens.
+		txnErr = txn.PutChannel(after)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.RecordChange(protoed.AuditRecord_ROTATE, before, after,
+			actor, now, h.Revisions)
+		return
+	})
+	if err != nil {
+		http.Error(w, "Failed to rotate the tokens of the channel.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to rotate the tokens of the channel in "+
+			"the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	if !found {
+		msg := fmt.Sprintf(
+			"No channel was found for the descriptor: %s", descriptor)
+		http.Error(w, msg, http.StatusNotFound)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	if conflict {
+		msg := fmt.Sprintf("The channel with descriptor %s already has "+
+			"a live token named %s.", descriptor, rotate.Name)
+		http.Error(w, msg, http.StatusConflict)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	issued := IssuedToken{Name: rotate.Name, Token: token,
+		ExpiresOthers: time.Unix(0, expires).UTC().Format(time.RFC3339Nano)}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&issued)
+	if err != nil {
+		h.LogErr.Printf("%s: Failed to marshal the issued token: %s\n",
+			r.URL.String(), err.Error())
+		return
+	}
+	h.LogOut.Printf("%s: The token %s was issued for the channel with "+
+		"descriptor %s.\n", r.URL.String(), rotate.Name, descriptor)
+}
+
+// RevokeToken implements Handler.RevokeToken.
+func (h *HandlerImpl) RevokeToken(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string,
+	name string) {
+
+	if !h.validDescriptor(w, r, descriptor) {
+		return
+	}
+
+	now := time.Now()
+	actor := requestActor(r)
+	found := false
+	foundToken := false
+	last := false
+	err := h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		var before *protoed.Channel
+		before, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil || before == nil {
+			return
+		}
+		found = true
+
+		after := proto.Clone(before).(*protoed.Channel)
+		if name == tokenhash.DefaultName && after.TokenHash != nil {
+			foundToken = true
+			after.TokenHash = nil
+		} else {
+			after.Tokens = nil
+			for _, token := range before.Tokens {
+				if token.Name == name {
+					foundToken = true
+					continue
+				}
+				after.Tokens = append(after.Tokens, token)
+			}
+		}
+
+		if !foundToken {
+			return
+		}
+
+		live := after.TokenHash != nil
+		for _, token := range after.Tokens {
+			live = live || tokenhash.Live(token, now)
+		}
+
+		if !live {
+			last = true
+			return
+		}
+
+		// PutChannel erases the last use of the revoked token.
+		txnErr = txn.PutChannel(after)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.RecordChange(protoed.AuditRecord_REVOKE, before, after,
+			actor, now, h.Revisions)
+		return
+	})
+	if err != nil {
+		http.Error(w, "Failed to revoke the token of the channel.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to revoke the token of the channel in "+
+			"the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	if !found || !foundToken {
+		msg := fmt.Sprintf("No token %s was found for the descriptor: %s",
+			name, descriptor)
+		if !found {
+			msg = fmt.Sprintf(
+				"No channel was found for the descriptor: %s", descriptor)
+		}
+		http.Error(w, msg, http.StatusNotFound)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	if last {
+		msg := fmt.Sprintf("The token %s is the last live token of "+
+			"the channel with descriptor %s.", name, descriptor)
+		http.Error(w, msg, http.StatusConflict)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	msg := fmt.Sprintf("The token %s of the channel with descriptor %s "+
+		"was revoked.", name, descriptor)
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(msg))
+	if err != nil {
+		h.LogErr.Printf("%s: Error while writing to the response "+
+			"writer: %s\n", r.URL.String(), err.Error())
+	}
+	h.LogOut.Printf("%s: %s\n", r.URL.String(), msg)
+}
+
+// GetChannelStats implements Handler.GetChannelStats.
+func (h *HandlerImpl) GetChannelStats(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string,
+	period *string,
+	since *string,
+	until *string) {
+
+	usagePeriod := database.Daily
+	var err error
+	if period != nil {
+		usagePeriod, err = database.ParseUsagePeriod(*period)
+		if err != nil {
+			http.Error(w, "Invalid 'period': "+err.Error(),
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid period: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	var sinceTime, untilTime time.Time
+	if since != nil {
+		sinceTime, err = time.Parse(time.RFC3339, *since)
+		if err != nil {
+			http.Error(w, "Invalid 'since': "+err.Error(),
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid since: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	if until != nil {
+		untilTime, err = time.Parse(time.RFC3339, *until)
+		if err != nil {
+			http.Error(w, "Invalid 'until': "+err.Error(),
+				http.StatusBadRequest)
+			h.LogErr.Printf("%s: Received an invalid until: %s\n",
+				r.URL.String(), err.Error())
+			return
+		}
+	}
+
+	if since != nil && until != nil && untilTime.Before(sinceTime) {
+		http.Error(w, "'until' before 'since' is not allowed.",
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received until (%s) before since (%s)\n",
+			r.URL.String(), *until, *since)
+		return
+	}
+
+	response, err := channelStats(descriptor, usagePeriod, sinceTime,
+		untilTime, h.Store)
+	if err != nil {
+		http.Error(w, "Failed to fetch the usage counters.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the usage counters "+
+			"from the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&response)
+
+	if err != nil {
+		http.Error(w, "Failed to marshal the usage counters response.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to marshal the usage counters "+
+			"response: %s\n", r.URL.String(), err.Error())
+	}
+}
+
+// GetLockouts implements Handler.GetLockouts.
+func (h *HandlerImpl) GetLockouts(w http.ResponseWriter,
+	r *http.Request) {
+
+	response, err := currentLockouts(time.Now(), h.Store)
+	if err != nil {
+		http.Error(w, "Failed to fetch the lockouts.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the lockouts "+
+			"from the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&response)
+
+	if err != nil {
+		http.Error(w, "Failed to marshal the lockouts response.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to marshal the lockouts "+
+			"response: %s\n", r.URL.String(), err.Error())
+	}
+}
+
+// Unlock implements Handler.Unlock.
+func (h *HandlerImpl) Unlock(w http.ResponseWriter,
+	r *http.Request,
+	unlock Unlock) {
+
+	kind, err := database.ParseLockoutKind(unlock.Kind)
+	if err != nil {
+		http.Error(w, "Invalid 'kind': "+err.Error(),
+			http.StatusBadRequest)
+		h.LogErr.Printf("%s: Received an invalid lockout kind: %s\n",
+			r.URL.String(), err.Error())
+		return
+	}
+
+	removed := false
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		removed, txnErr = txn.ClearLockout(kind, unlock.Subject)
+		return
+	})
+	if err != nil {
+		http.Error(w, "Failed to lift the lockout.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to lift the lockout in the "+
+			"database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	if !removed {
+		msg := fmt.Sprintf("No failed authentications are tracked "+
+			"for the %s %s.", kind, unlock.Subject)
+		http.Error(w, msg, http.StatusNotFound)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	msg := fmt.Sprintf("The lockout of the %s %s was lifted.", kind,
+		unlock.Subject)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(msg))
+	if err != nil {
+		h.LogErr.Printf("%s: Error while writing to the response "+
+			"writer: %s\n", r.URL.String(), err.Error())
+	}
+	h.LogOut.Printf("%s: %s\n", r.URL.String(), msg)
+}
+
+// GetChannel implements Handler.GetChannel.
+func (h *HandlerImpl) GetChannel(w http.ResponseWriter,
+	r *http.Request,
+	descriptor string) {
+
+	var channel Channel
+	var etag string
+	err := h.Store.View(func(txn *database.Txn) (txnErr error) {
+		var protoChan *protoed.Channel
+		protoChan, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil || protoChan == nil {
+			return
+		}
+
+		channel, txnErr = listedChannel(txn, protoChan)
+		if txnErr != nil {
+			return
+		}
+
+		etag, txnErr = currentETag(txn, descriptor, true)
+		return
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch the channel.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the channel from "+
+			"the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	if etag == "" {
+		msg := fmt.Sprintf(
+			"No channel was found for the descriptor: %s", descriptor)
+		http.Error(w, msg, http.StatusNotFound)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	if values, ok := r.Header["If-None-Match"]; ok &&
+		matchesETag(strings.Join(values, ","), etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&channel)
+
+	if err != nil {
+		http.Error(w, "Failed to marshal the channel.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to marshal the channel: %s\n",
+			r.URL.String(), err.Error())
+	}
+}
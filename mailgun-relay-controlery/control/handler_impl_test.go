@@ -0,0 +1,683 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+func TestHandlerImpl_DisableChannel(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	h := newTestHandler(db)
+
+	channel := `{"descriptor": "client-1/pipeline-3", "token": "secret",
+		"sender": {"email": "johann.bach@composers.com"},
+		"recipients": [{"email": "cpe.bach@composers.com"}],
+		"domain": "composers.com", "min_period": 1, "max_size": 1000}`
+
+	w := serve(h, "PUT", "/api/channel", channel, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on put, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "POST", "/api/channel/client-1/missing/disable", `{}`, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected the status %d for a missing channel, got %d: %s",
+			http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "POST", "/api/channel/client-1/pipeline-3/disable",
+		`{"reason": "sends a message every second"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on disable, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Disabling twice keeps the original reason.
+	w = serve(h, "POST", "/api/channel/client-1/pipeline-3/disable",
+		`{"reason": "another reason"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on disable, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// An update keeps the channel disabled.
+	w = serve(h, "PUT", "/api/channel", channel, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on put, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var stored *protoed.Channel
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		stored, txnErr = txn.GetChannel("client-1/pipeline-3")
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if stored.Disabled == nil ||
+		stored.Disabled.Reason != "sends a message every second" {
+		t.Fatalf("expected the channel to stay disabled, got %v",
+			stored.Disabled)
+	}
+
+	listings := map[string]int{
+		"/api/list_channels?disabled=true":  1,
+		"/api/list_channels?disabled=false": 0,
+		"/api/list_channels":                1}
+	for target, expected := range listings {
+		w = serve(h, "GET", target, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the status %d on %s, got %d: %s",
+				http.StatusOK, target, w.Code, w.Body.String())
+		}
+
+		page := ChannelsPage{}
+		err = json.Unmarshal(w.Body.Bytes(), &page)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(page.Channels) != expected {
+			t.Errorf("expected %d channel(s) on %s, got %d",
+				expected, target, len(page.Channels))
+		}
+	}
+
+	w = serve(h, "GET", "/api/list_channels?disabled=maybe", "", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected the status %d for an invalid state, got %d",
+			http.StatusBadRequest, w.Code)
+	}
+
+	w = serve(h, "POST", "/api/channel/client-1/pipeline-3/enable", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on enable, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var records []*protoed.AuditRecord
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		stored, txnErr = txn.GetChannel("client-1/pipeline-3")
+		if txnErr != nil {
+			return
+		}
+
+		records, _, txnErr = txn.AuditRecords(database.AuditFilter{}, 0, 100)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if stored.Disabled != nil {
+		t.Errorf("expected the channel to be enabled, got %v",
+			stored.Disabled)
+	}
+
+	var operations []protoed.AuditRecord_Operation
+	for _, record := range records {
+		operations = append(operations, record.Operation)
+	}
+
+	expected := []protoed.AuditRecord_Operation{
+		protoed.AuditRecord_PUT, protoed.AuditRecord_DISABLE,
+		protoed.AuditRecord_PUT, protoed.AuditRecord_ENABLE}
+	if len(operations) != len(expected) {
+		t.Fatalf("expected the operations %v, got %v", expected, operations)
+	}
+	for i := range expected {
+		if operations[i] != expected[i] {
+			t.Fatalf("expected the operations %v, got %v",
+				expected, operations)
+		}
+	}
+}
+
+func TestHandlerImpl_RotateToken(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	h := newTestHandler(db)
+
+	channel := `{"descriptor": "client-1/pipeline-3", "token": "secret",
+		"sender": {"email": "johann.bach@composers.com"},
+		"recipients": [{"email": "cpe.bach@composers.com"}],
+		"domain": "composers.com", "min_period": 1, "max_size": 1000}`
+
+	w := serve(h, "PUT", "/api/channel", channel, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on put, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "POST", "/api/channel/client-1/missing/rotate",
+		`{"name": "ci-2"}`, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected the status %d for a missing channel, got %d: %s",
+			http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	now := time.Now()
+	w = serve(h, "POST", "/api/channel/client-1/pipeline-3/rotate",
+		`{"name": "ci-2", "grace_period": 60}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on rotate, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	issued := IssuedToken{}
+	err = json.Unmarshal(w.Body.Bytes(), &issued)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if issued.Name != "ci-2" || issued.Token == "" {
+		t.Fatalf("unexpected issued token: %#v", issued)
+	}
+
+	w = serve(h, "POST", "/api/channel/client-1/pipeline-3/rotate",
+		`{"name": "ci-2"}`, nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected the status %d for a live token name, got %d: %s",
+			http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	var stored *protoed.Channel
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		stored, txnErr = txn.GetChannel("client-1/pipeline-3")
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Both tokens are accepted within the grace period, only the new one
+	// after it.
+	for _, check := range []struct {
+		token    string
+		at       time.Time
+		expected string
+	}{
+		{"secret", now, tokenhash.DefaultName},
+		{issued.Token, now, "ci-2"},
+		{"secret", now.Add(2 * time.Minute), ""},
+		{issued.Token, now.Add(2 * time.Minute), "ci-2"}} {
+		name, _, err := tokenhash.Authenticate(stored, check.token, check.at)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if name != check.expected {
+			t.Errorf("expected the token %#v at %s to authenticate as %#v, "+
+				"got %#v", check.token, check.at, check.expected, name)
+		}
+	}
+
+	w = serve(h, "POST",
+		"/api/channel/client-1/pipeline-3/tokens/unknown/revoke", "", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected the status %d for a missing token, got %d: %s",
+			http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "POST",
+		"/api/channel/client-1/pipeline-3/tokens/default/revoke", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on revoke, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "POST",
+		"/api/channel/client-1/pipeline-3/tokens/ci-2/revoke", "", nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected the status %d for the last live token, "+
+			"got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	// An update without any token keeps the tokens.
+	noToken := `{"descriptor": "client-1/pipeline-3",
+		"sender": {"email": "johann.bach@composers.com"},
+		"recipients": [{"email": "cpe.bach@composers.com"}],
+		"domain": "composers.com", "min_period": 2, "max_size": 1000}`
+
+	w = serve(h, "PUT", "/api/channel", noToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on put, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var records []*protoed.AuditRecord
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		stored, txnErr = txn.GetChannel("client-1/pipeline-3")
+		if txnErr != nil {
+			return
+		}
+
+		records, _, txnErr = txn.AuditRecords(database.AuditFilter{}, 0, 100)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if stored.TokenHash != nil || len(stored.Tokens) != 1 ||
+		stored.Tokens[0].Name != "ci-2" {
+		t.Errorf("expected only the token ci-2 to be kept, got %v",
+			stored.Tokens)
+	}
+
+	var operations []protoed.AuditRecord_Operation
+	for _, record := range records {
+		operations = append(operations, record.Operation)
+	}
+
+	expected := []protoed.AuditRecord_Operation{
+		protoed.AuditRecord_PUT, protoed.AuditRecord_ROTATE,
+		protoed.AuditRecord_REVOKE, protoed.AuditRecord_PUT}
+	if len(operations) != len(expected) {
+		t.Fatalf("expected the operations %v, got %v", expected, operations)
+	}
+	for i := range expected {
+		if operations[i] != expected[i] {
+			t.Fatalf("expected the operations %v, got %v",
+				expected, operations)
+		}
+	}
+
+	w = serve(h, "PUT", "/api/channel",
+		`{"descriptor": "client-2",
+		"sender": {"email": "johann.bach@composers.com"},
+		"recipients": [{"email": "cpe.bach@composers.com"}],
+		"domain": "composers.com", "min_period": 1, "max_size": 1000}`, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected the status %d for a new channel without "+
+			"a token, got %d: %s", http.StatusBadRequest, w.Code,
+			w.Body.String())
+	}
+}
+
+func TestHandlerImpl_GetChannelStats(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	db.Access = database.RelayAccess
+	err = db.Update(func(txn *database.Txn) (txnErr error) {
+		for _, record := range []*protoed.RelayRecord{
+			{Descriptor_: "client-1/pipeline-3", Time: now.UnixNano(),
+				Size: 100, Outcome: protoed.RelayRecord_RELAYED},
+			{Descriptor_: "client-1/pipeline-3",
+				Time:    now.Add(time.Hour).UnixNano(),
+				Outcome: protoed.RelayRecord_TOO_LARGE}} {
+			txnErr = txn.CountUsage(record)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.ControlAccess
+
+	h := newTestHandler(db)
+
+	w := serve(h, "GET", "/api/channel/client-1/pipeline-3/stats", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	stats := ChannelStats{}
+	err = json.Unmarshal(w.Body.Bytes(), &stats)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := Usage{Start: "2018-10-01T00:00:00Z", Relayed: 1, Bytes: 100,
+		Rejected: Rejections{TooLarge: 1}}
+	if stats.Descriptor != "client-1/pipeline-3" || stats.Period != "day" ||
+		len(stats.Usage) != 1 || stats.Usage[0] != expected {
+		t.Errorf("unexpected daily stats: %#v", stats)
+	}
+
+	w = serve(h, "GET", "/api/channel/client-1/pipeline-3/stats?"+
+		"period=hour&since=2018-10-01T15:00:00Z", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	stats = ChannelStats{}
+	err = json.Unmarshal(w.Body.Bytes(), &stats)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(stats.Usage) != 1 ||
+		stats.Usage[0].Start != "2018-10-01T15:00:00Z" ||
+		stats.Usage[0].Rejected.TooLarge != 1 {
+		t.Errorf("unexpected hourly stats: %#v", stats)
+	}
+
+	for _, target := range []string{
+		"/api/channel/client-1/pipeline-3/stats?period=week",
+		"/api/channel/client-1/pipeline-3/stats?since=yesterday",
+		"/api/channel/client-1/pipeline-3/stats?" +
+			"since=2018-10-02T00:00:00Z&until=2018-10-01T00:00:00Z"} {
+		w = serve(h, "GET", target, "", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected the status %d on %s, got %d",
+				http.StatusBadRequest, target, w.Code)
+		}
+	}
+
+	w = serve(h, "GET", "/api/channel/client-2/stats", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(),
+		`"usage":[]`) {
+		t.Errorf("expected empty usage for an unknown channel, got %d: %s",
+			w.Code, w.Body.String())
+	}
+}
+
+func TestHandlerImpl_Lockouts(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	policy := database.LockoutPolicy{
+		Threshold: 1,
+		Base:      time.Hour,
+		Max:       time.Hour,
+		Forget:    time.Hour}
+
+	db.Access = database.RelayAccess
+	err = db.Update(func(txn *database.Txn) (txnErr error) {
+		_, txnErr = txn.RecordAuthFailure(database.LockoutIP, "192.0.2.1",
+			time.Now(), policy)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.ControlAccess
+
+	h := newTestHandler(db)
+
+	w := serve(h, "GET", "/api/lockouts", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	lockouts := Lockouts{}
+	err = json.Unmarshal(w.Body.Bytes(), &lockouts)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(lockouts.Lockouts) != 1 || lockouts.Lockouts[0].Kind != "ip" ||
+		lockouts.Lockouts[0].Subject != "192.0.2.1" ||
+		lockouts.Lockouts[0].Failures != 1 {
+		t.Errorf("unexpected lockouts: %#v", lockouts)
+	}
+
+	for _, tt := range []struct {
+		body   string
+		status int
+	}{
+		{`{"kind": "host", "subject": "192.0.2.1"}`, http.StatusBadRequest},
+		{`{"kind": "ip"}`, http.StatusBadRequest},
+		{`{"kind": "descriptor", "subject": "192.0.2.1"}`,
+			http.StatusNotFound},
+		{`{"kind": "ip", "subject": "192.0.2.1"}`, http.StatusOK},
+		{`{"kind": "ip", "subject": "192.0.2.1"}`, http.StatusNotFound}} {
+		w = serve(h, "POST", "/api/unlock", tt.body, nil)
+		if w.Code != tt.status {
+			t.Errorf("expected the status %d on %s, got %d: %s",
+				tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	w = serve(h, "GET", "/api/lockouts", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(),
+		`"lockouts":[]`) {
+		t.Errorf("expected no lockouts after the unlock, got %d: %s",
+			w.Code, w.Body.String())
+	}
+}
+
+func TestHandlerImpl_ConditionalRequests(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	h := newTestHandler(db)
+
+	channel := func(minPeriod int) string {
+		return fmt.Sprintf(`{"descriptor": "client-1/pipeline-3",
+			"token": "secret",
+			"sender": {"email": "johann.bach@composers.com"},
+			"recipients": [{"email": "cpe.bach@composers.com"}],
+			"domain": "composers.com", "min_period": %d,
+			"max_size": 1000}`, minPeriod)
+	}
+
+	createOnly := map[string]string{"If-None-Match": "*"}
+
+	w := serve(h, "GET", "/api/channel/client-1/pipeline-3", "", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected the status %d for a missing channel, got %d: %s",
+			http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "PUT", "/api/channel", channel(1),
+		map[string]string{"If-Match": "*"})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected the status %d for If-Match on a missing "+
+			"channel, got %d: %s", http.StatusPreconditionFailed, w.Code,
+			w.Body.String())
+	}
+
+	w = serve(h, "PUT", "/api/channel", channel(1), createOnly)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on create, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("expected the ETag \"1\" after create, got %#v", etag)
+	}
+
+	w = serve(h, "PUT", "/api/channel", channel(1), createOnly)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected the status %d on a second create, got %d: %s",
+			http.StatusPreconditionFailed, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "GET", "/api/channel/client-1/pipeline-3", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on get, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+
+	got := Channel{}
+	err = json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if got.Descriptor != "client-1/pipeline-3" || got.MinPeriod != 1 {
+		t.Errorf("unexpected channel: %#v", got)
+	}
+
+	w = serve(h, "GET", "/api/channel/client-1/pipeline-3", "",
+		map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected the status %d for a matching If-None-Match, "+
+			"got %d", http.StatusNotModified, w.Code)
+	}
+
+	// The first of the two concurrent editors wins.
+	w = serve(h, "PUT", "/api/channel", channel(2),
+		map[string]string{"If-Match": etag})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on a matching update, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+	updated := w.Header().Get("ETag")
+
+	w = serve(h, "PUT", "/api/channel", channel(3),
+		map[string]string{"If-Match": etag})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected the status %d on a stale update, got %d: %s",
+			http.StatusPreconditionFailed, w.Code, w.Body.String())
+	}
+	if current := w.Header().Get("ETag"); current != updated {
+		t.Errorf("expected the current ETag %#v on conflict, got %#v",
+			updated, current)
+	}
+
+	w = serve(h, "DELETE", "/api/channel", `"client-1/pipeline-3"`,
+		map[string]string{"If-Match": etag})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected the status %d on a stale removal, got %d: %s",
+			http.StatusPreconditionFailed, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "DELETE", "/api/channel", `"client-1/pipeline-3"`,
+		map[string]string{"If-Match": updated})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on removal, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = serve(h, "DELETE", "/api/channel", `"client-1/pipeline-3"`,
+		map[string]string{"If-Match": "*"})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected the status %d on removing a missing channel, "+
+			"got %d: %s", http.StatusPreconditionFailed, w.Code,
+			w.Body.String())
+	}
+
+	// A re-created channel does not reuse the revisions of the removed one.
+	w = serve(h, "PUT", "/api/channel", channel(1), createOnly)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on re-create, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+	if recreated := w.Header().Get("ETag"); recreated != `"3"` {
+		t.Errorf("expected the ETag \"3\" after re-create, got %#v", recreated)
+	}
+}
+
+func TestHandlerImpl_PutChannel_InvalidDescriptor(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	h := newTestHandler(db)
+
+	for _, descriptor := range []string{`""`, `"a\u0000b"`} {
+		channel := fmt.Sprintf(`{"descriptor": %s, "token": "secret",
+			"sender": {"email": "johann.bach@composers.com"},
+			"recipients": [{"email": "cpe.bach@composers.com"}],
+			"domain": "composers.com", "min_period": 1, "max_size": 1000}`,
+			descriptor)
+
+		w := serve(h, "PUT", "/api/channel", channel, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected the status %d for the descriptor %s, "+
+				"got %d: %s", http.StatusBadRequest, descriptor, w.Code,
+				w.Body.String())
+		}
+	}
+
+	w := serve(h, "POST", "/api/channel/a%00b/rollback", `{"revision": 1}`,
+		nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected the status %d on a rollback of an invalid "+
+			"descriptor, got %d: %s", http.StatusBadRequest, w.Code,
+			w.Body.String())
+	}
+
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		count, txnErr := txn.CountChannels()
+		if txnErr != nil {
+			return
+		}
+		if count != 0 {
+			t.Errorf("expected no stored channels, got %d", count)
+		}
+
+		records, _, txnErr := txn.AuditRecords(database.AuditFilter{}, 0, 100)
+		if txnErr != nil {
+			return
+		}
+		if len(records) != 0 {
+			t.Errorf("expected no audit records, got %d", len(records))
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
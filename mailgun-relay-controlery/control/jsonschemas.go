@@ -21,6 +21,119 @@ var jsonSchemaChannelText = `{
   "title": "Channel",
   "$schema": "http://json-schema.org/draft-04/schema#",
   "definitions": {
+    "Quota": {
+      "description": "defines the hard quotas of a channel per calendar day and month.\n\nThe Relay server refuses the messages which would exceed a quota until the quota resets at the start\nof the next day or month in the time zone of the quota. The messages are counted only while the channel\nhas a quota.",
+      "type": "object",
+      "properties": {
+        "daily_messages": {
+          "description": "is the maximum number of the relayed messages per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_messages": {
+          "description": "is the maximum number of the relayed messages per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "daily_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "timezone": {
+          "description": "is the IANA time zone in which the days and the months start; absent for UTC.",
+          "type": "string",
+          "example": "Europe/Zurich"
+        }
+      }
+    },
+    "RateLimit": {
+      "description": "defines the token bucket limiting the rate of the messages of a channel.\n\nThe bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit\nis absent, the channel is limited by its min_period as if the bucket held a single message.",
+      "type": "object",
+      "properties": {
+        "rate": {
+          "description": "is the number of messages per second by which the bucket is refilled.",
+          "type": "number",
+          "format": "float",
+          "minimum": 0,
+          "exclusiveMinimum": true
+        },
+        "burst": {
+          "description": "is the maximum number of messages which can be relayed at once.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 1
+        }
+      },
+      "required": [
+        "rate",
+        "burst"
+      ]
+    },
+    "ChannelToken": {
+      "description": "is a named token authenticating the senders of a channel.\n\nThe Relay server accepts the messages authenticated by any live token.",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token, unique within the channel.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the token in the PHC string format.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "created": {
+          "description": "is the time when the token has been issued in RFC 3339 format; absent if unknown.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "expires": {
+          "description": "is the time in RFC 3339 format after which the token is rejected; absent if it never expires.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "last_used": {
+          "description": "is the time in RFC 3339 format when the token last authenticated a message; absent if never.\n\nThe field is only listed and ignored when a channel is stored.",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token_hash"
+      ]
+    },
+    "Disabled": {
+      "description": "indicates that the channel has been disabled and relays no messages.\n\nIf omitted when a channel is stored, an existing channel keeps its current state.",
+      "type": "object",
+      "properties": {
+        "time": {
+          "description": "is the time when the channel has been disabled in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "reason": {
+          "description": "is the reason why the channel has been disabled; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      },
+      "required": [
+        "time"
+      ]
+    },
     "Entity": {
       "description": "contains the email address and optionally the name of an entity.",
       "type": "object",
@@ -93,11 +206,48 @@ var jsonSchemaChannelText = `{
           "description": "indicates the maximum allowed size of the request, in bytes.",
           "type": "integer",
           "format": "int32"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the default token in the PHC string format.\n\nListings never include the token, only its hash.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "disabled": {
+          "$ref": "#/definitions/Disabled"
+        },
+        "valid_until": {
+          "description": "is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.\n\nThe Relay server refuses the messages of an expired channel. The Control server removes or disables\nthe expired channels after a grace period.",
+          "type": "string",
+          "format": "date-time",
+          "example": "2018-10-31T23:59:59Z"
+        },
+        "tokens": {
+          "description": "lists the named tokens of the channel in addition to the default one.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ChannelToken"
+          }
+        },
+        "rate_limit": {
+          "$ref": "#/definitions/RateLimit"
+        },
+        "quota": {
+          "$ref": "#/definitions/Quota"
+        },
+        "on_throttle": {
+          "description": "specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.\n\nOne of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued\nmessages are stored by the Relay server and relayed in order once the rate limit allows.",
+          "type": "string",
+          "example": "queue"
+        },
+        "max_queue_depth": {
+          "description": "is the maximum number of the messages of the channel waiting in the queue; absent or 0 for\nthe default of 100 messages.\n\nThe messages beyond it are refused with 429 Too Many Requests.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 0
         }
       },
       "required": [
         "descriptor",
-        "token",
         "sender",
         "recipients",
         "domain",
@@ -154,6 +304,119 @@ var jsonSchemaChannelsPageText = `{
   "title": "ChannelsPage",
   "$schema": "http://json-schema.org/draft-04/schema#",
   "definitions": {
+    "Quota": {
+      "description": "defines the hard quotas of a channel per calendar day and month.\n\nThe Relay server refuses the messages which would exceed a quota until the quota resets at the start\nof the next day or month in the time zone of the quota. The messages are counted only while the channel\nhas a quota.",
+      "type": "object",
+      "properties": {
+        "daily_messages": {
+          "description": "is the maximum number of the relayed messages per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_messages": {
+          "description": "is the maximum number of the relayed messages per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "daily_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "timezone": {
+          "description": "is the IANA time zone in which the days and the months start; absent for UTC.",
+          "type": "string",
+          "example": "Europe/Zurich"
+        }
+      }
+    },
+    "RateLimit": {
+      "description": "defines the token bucket limiting the rate of the messages of a channel.\n\nThe bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit\nis absent, the channel is limited by its min_period as if the bucket held a single message.",
+      "type": "object",
+      "properties": {
+        "rate": {
+          "description": "is the number of messages per second by which the bucket is refilled.",
+          "type": "number",
+          "format": "float",
+          "minimum": 0,
+          "exclusiveMinimum": true
+        },
+        "burst": {
+          "description": "is the maximum number of messages which can be relayed at once.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 1
+        }
+      },
+      "required": [
+        "rate",
+        "burst"
+      ]
+    },
+    "ChannelToken": {
+      "description": "is a named token authenticating the senders of a channel.\n\nThe Relay server accepts the messages authenticated by any live token.",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token, unique within the channel.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the token in the PHC string format.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "created": {
+          "description": "is the time when the token has been issued in RFC 3339 format; absent if unknown.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "expires": {
+          "description": "is the time in RFC 3339 format after which the token is rejected; absent if it never expires.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "last_used": {
+          "description": "is the time in RFC 3339 format when the token last authenticated a message; absent if never.\n\nThe field is only listed and ignored when a channel is stored.",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token_hash"
+      ]
+    },
+    "Disabled": {
+      "description": "indicates that the channel has been disabled and relays no messages.\n\nIf omitted when a channel is stored, an existing channel keeps its current state.",
+      "type": "object",
+      "properties": {
+        "time": {
+          "description": "is the time when the channel has been disabled in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "reason": {
+          "description": "is the reason why the channel has been disabled; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      },
+      "required": [
+        "time"
+      ]
+    },
     "Entity": {
       "description": "contains the email address and optionally the name of an entity.",
       "type": "object",
@@ -226,11 +489,48 @@ var jsonSchemaChannelsPageText = `{
           "description": "indicates the maximum allowed size of the request, in bytes.",
           "type": "integer",
           "format": "int32"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the default token in the PHC string format.\n\nListings never include the token, only its hash.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "disabled": {
+          "$ref": "#/definitions/Disabled"
+        },
+        "valid_until": {
+          "description": "is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.\n\nThe Relay server refuses the messages of an expired channel. The Control server removes or disables\nthe expired channels after a grace period.",
+          "type": "string",
+          "format": "date-time",
+          "example": "2018-10-31T23:59:59Z"
+        },
+        "tokens": {
+          "description": "lists the named tokens of the channel in addition to the default one.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ChannelToken"
+          }
+        },
+        "rate_limit": {
+          "$ref": "#/definitions/RateLimit"
+        },
+        "quota": {
+          "$ref": "#/definitions/Quota"
+        },
+        "on_throttle": {
+          "description": "specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.\n\nOne of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued\nmessages are stored by the Relay server and relayed in order once the rate limit allows.",
+          "type": "string",
+          "example": "queue"
+        },
+        "max_queue_depth": {
+          "description": "is the maximum number of the messages of the channel waiting in the queue; absent or 0 for\nthe default of 100 messages.\n\nThe messages beyond it are refused with 429 Too Many Requests.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 0
         }
       },
       "required": [
         "descriptor",
-        "token",
         "sender",
         "recipients",
         "domain",
@@ -243,7 +543,7 @@ var jsonSchemaChannelsPageText = `{
   "type": "object",
   "properties": {
     "page": {
-      "description": "specifies the index of the page.",
+      "description": "specifies the index of the page; 0 if the page has been listed after a cursor.",
       "type": "integer",
       "format": "int32"
     },
@@ -263,6 +563,10 @@ var jsonSchemaChannelsPageText = `{
       "items": {
         "$ref": "#/definitions/Channel"
       }
+    },
+    "next_cursor": {
+      "description": "is the opaque cursor to list the following page; absent if there are no more channels.",
+      "type": "string"
     }
   },
   "required": [
@@ -273,52 +577,2452 @@ var jsonSchemaChannelsPageText = `{
   ]
 }`
 
-var jsonSchemaChannel = mustNewJSONSchema(
-	jsonSchemaChannelText,
-	"Channel")
-
-var jsonSchemaDescriptor = mustNewJSONSchema(
-	jsonSchemaDescriptorText,
-	"Descriptor")
-
-var jsonSchemaToken = mustNewJSONSchema(
-	jsonSchemaTokenText,
-	"Token")
-
-var jsonSchemaEntity = mustNewJSONSchema(
-	jsonSchemaEntityText,
-	"Entity")
-
-var jsonSchemaChannelsPage = mustNewJSONSchema(
-	jsonSchemaChannelsPageText,
-	"ChannelsPage")
-
-// ValidateAgainstChannelSchema validates a message coming from the client against Channel schema.
-func ValidateAgainstChannelSchema(bb []byte) error {
-	loader := gojsonschema.NewStringLoader(string(bb))
-	result, err := jsonSchemaChannel.Validate(loader)
-	if err != nil {
-		return err
-	}
-
-	if result.Valid() {
-		return nil
-	}
+var jsonSchemaRelayRecordText = `{
+  "title": "RelayRecord",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Descriptor": {
+      "description": "identifies a channel.",
+      "type": "string",
+      "example": "client-1/pipeline-3"
+    },
+    "RelayRecord": {
+      "description": "records an attempt to relay a message through a channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "time": {
+          "description": "is the time of the attempt in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "subject": {
+          "description": "is the subject of the message; empty if the message has not been parsed.",
+          "type": "string"
+        },
+        "size": {
+          "description": "is the size of the request body, in bytes.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "outcome": {
+          "description": "is the outcome of the attempt.\n\nOne of relayed, forbidden, too_soon, too_large, invalid, failed, disabled, expired, quota_exceeded, throttled,\nlocked_out, queued and queue_full.\n",
+          "type": "string",
+          "example": "relayed"
+        },
+        "status": {
+          "description": "is the HTTP status returned to the client; 0 if the message has been relayed from the queue.",
+          "type": "integer",
+          "format": "int32"
+        },
+        "message_id": {
+          "description": "is the MailGun message id; absent unless the message has been relayed.",
+          "type": "string"
+        },
+        "token": {
+          "description": "is the name of the token which authenticated the attempt; absent if none did.",
+          "type": "string",
+          "example": "default"
+        }
+      },
+      "required": [
+        "descriptor",
+        "time",
+        "subject",
+        "size",
+        "outcome",
+        "status"
+      ]
+    }
+  },
+  "$ref": "#/definitions/RelayRecord"
+}`
 
-	msg := ""
-	for i, valErr := range result.Errors() {
-		if i > 0 {
-			msg += ", "
-		}
-		msg += valErr.String()
-	}
-	return errors.New(msg)
-}
+var jsonSchemaRelayLogText = `{
+  "title": "RelayLog",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Descriptor": {
+      "description": "identifies a channel.",
+      "type": "string",
+      "example": "client-1/pipeline-3"
+    },
+    "RelayRecord": {
+      "description": "records an attempt to relay a message through a channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "time": {
+          "description": "is the time of the attempt in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "subject": {
+          "description": "is the subject of the message; empty if the message has not been parsed.",
+          "type": "string"
+        },
+        "size": {
+          "description": "is the size of the request body, in bytes.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "outcome": {
+          "description": "is the outcome of the attempt.\n\nOne of relayed, forbidden, too_soon, too_large, invalid, failed, disabled, expired, quota_exceeded, throttled,\nlocked_out, queued and queue_full.\n",
+          "type": "string",
+          "example": "relayed"
+        },
+        "status": {
+          "description": "is the HTTP status returned to the client; 0 if the message has been relayed from the queue.",
+          "type": "integer",
+          "format": "int32"
+        },
+        "message_id": {
+          "description": "is the MailGun message id; absent unless the message has been relayed.",
+          "type": "string"
+        },
+        "token": {
+          "description": "is the name of the token which authenticated the attempt; absent if none did.",
+          "type": "string",
+          "example": "default"
+        }
+      },
+      "required": [
+        "descriptor",
+        "time",
+        "subject",
+        "size",
+        "outcome",
+        "status"
+      ]
+    },
+    "RelayLog": {
+      "description": "lists the attempts to relay a message through a channel.",
+      "type": "object",
+      "properties": {
+        "records": {
+          "description": "contains the attempts ordered by time.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/RelayRecord"
+          }
+        },
+        "more": {
+          "description": "indicates that further attempts exceeding the limit are available in the time range.",
+          "type": "boolean"
+        }
+      },
+      "required": [
+        "records",
+        "more"
+      ]
+    }
+  },
+  "$ref": "#/definitions/RelayLog"
+}`
 
-// ValidateAgainstDescriptorSchema validates a message coming from the client against Descriptor schema.
-func ValidateAgainstDescriptorSchema(bb []byte) error {
-	loader := gojsonschema.NewStringLoader(string(bb))
-	result, err := jsonSchemaDescriptor.Validate(loader)
+var jsonSchemaAuditRecordText = `{
+  "title": "AuditRecord",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Descriptor": {
+      "description": "identifies a channel.",
+      "type": "string",
+      "example": "client-1/pipeline-3"
+    },
+    "Token": {
+      "description": "is a string authenticating the sender of an HTTP request.",
+      "type": "string",
+      "example": "RBbPYhmPXurT8nM5TAJpPOcHMaFkJblA62mr6MCvpF4oVa6cy"
+    },
+    "Entity": {
+      "description": "contains the email address and optionally the name of an entity.",
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "example": "name@domain.com"
+        },
+        "name": {
+          "type": "string",
+          "example": "John Doe"
+        }
+      },
+      "required": [
+        "email"
+      ]
+    },
+    "Disabled": {
+      "description": "indicates that the channel has been disabled and relays no messages.\n\nIf omitted when a channel is stored, an existing channel keeps its current state.\n",
+      "type": "object",
+      "properties": {
+        "time": {
+          "description": "is the time when the channel has been disabled in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "reason": {
+          "description": "is the reason why the channel has been disabled; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      },
+      "required": [
+        "time"
+      ]
+    },
+    "ChannelToken": {
+      "description": "is a named token authenticating the senders of a channel.\n\nThe Relay server accepts the messages authenticated by any live token.\n",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token, unique within the channel.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the token in the PHC string format.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "created": {
+          "description": "is the time when the token has been issued in RFC 3339 format; absent if unknown.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "expires": {
+          "description": "is the time in RFC 3339 format after which the token is rejected; absent if it never expires.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "last_used": {
+          "description": "is the time in RFC 3339 format when the token last authenticated a message; absent if never.\n\nThe field is only listed and ignored when a channel is stored.\n",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token_hash"
+      ]
+    },
+    "RateLimit": {
+      "description": "defines the token bucket limiting the rate of the messages of a channel.\n\nThe bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit\nis absent, the channel is limited by its min_period as if the bucket held a single message.\n",
+      "type": "object",
+      "properties": {
+        "rate": {
+          "description": "is the number of messages per second by which the bucket is refilled.",
+          "type": "number",
+          "format": "float",
+          "minimum": 0,
+          "exclusiveMinimum": true
+        },
+        "burst": {
+          "description": "is the maximum number of messages which can be relayed at once.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 1
+        }
+      },
+      "required": [
+        "rate",
+        "burst"
+      ]
+    },
+    "Quota": {
+      "description": "defines the hard quotas of a channel per calendar day and month.\n\nThe Relay server refuses the messages which would exceed a quota until the quota resets at the start\nof the next day or month in the time zone of the quota. The messages are counted only while the channel\nhas a quota.\n",
+      "type": "object",
+      "properties": {
+        "daily_messages": {
+          "description": "is the maximum number of the relayed messages per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_messages": {
+          "description": "is the maximum number of the relayed messages per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "daily_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "timezone": {
+          "description": "is the IANA time zone in which the days and the months start; absent for UTC.",
+          "type": "string",
+          "example": "Europe/Zurich"
+        }
+      }
+    },
+    "Channel": {
+      "description": "defines the messaging channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "token": {
+          "$ref": "#/definitions/Token"
+        },
+        "sender": {
+          "$ref": "#/definitions/Entity"
+        },
+        "recipients": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "cc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "bcc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "domain": {
+          "description": "indicates the MailGun domain for the channel.",
+          "type": "string",
+          "example": "marketing.domainname.com"
+        },
+        "min_period": {
+          "description": "is the minimum push period frequency for a channel, in seconds.",
+          "type": "number",
+          "format": "float"
+        },
+        "max_size": {
+          "description": "indicates the maximum allowed size of the request, in bytes.",
+          "type": "integer",
+          "format": "int32"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the default token in the PHC string format.\n\nListings never include the token, only its hash.\n",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "disabled": {
+          "$ref": "#/definitions/Disabled"
+        },
+        "valid_until": {
+          "description": "is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.\n\nThe Relay server refuses the messages of an expired channel. The Control server removes or disables\nthe expired channels after a grace period.\n",
+          "type": "string",
+          "format": "date-time",
+          "example": "2018-10-31T23:59:59Z"
+        },
+        "tokens": {
+          "description": "lists the named tokens of the channel in addition to the default one.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ChannelToken"
+          }
+        },
+        "rate_limit": {
+          "$ref": "#/definitions/RateLimit"
+        },
+        "quota": {
+          "$ref": "#/definitions/Quota"
+        },
+        "on_throttle": {
+          "description": "specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.\n\nOne of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued\nmessages are stored by the Relay server and relayed in order once the rate limit allows.\n",
+          "type": "string",
+          "example": "queue"
+        },
+        "max_queue_depth": {
+          "description": "is the maximum number of the messages of the channel waiting in the queue; absent or 0 for\nthe default of 100 messages.\n\nThe messages beyond it are refused with 429 Too Many Requests.\n",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 0
+        }
+      },
+      "required": [
+        "descriptor",
+        "sender",
+        "recipients",
+        "domain",
+        "min_period",
+        "max_size"
+      ]
+    },
+    "AuditRecord": {
+      "description": "records a change of a channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "time": {
+          "description": "is the time of the change in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "operation": {
+          "description": "is the kind of the change.\n\nOne of put, delete, rollback, disable, enable, rotate and revoke.\n",
+          "type": "string",
+          "example": "put"
+        },
+        "actor": {
+          "description": "is the identity of the client given in the X-Actor header; absent if not given.",
+          "type": "string",
+          "example": "ops@domain.com"
+        },
+        "remote_addr": {
+          "description": "is the network address of the client; empty if the change has been made locally.",
+          "type": "string",
+          "example": "192.168.1.10:53124"
+        },
+        "forwarded_for": {
+          "description": "is the X-Forwarded-For header of the request; absent if not given.",
+          "type": "string"
+        },
+        "user_agent": {
+          "description": "is the User-Agent header of the request; absent if not given.",
+          "type": "string"
+        },
+        "before": {
+          "$ref": "#/definitions/Channel"
+        },
+        "after": {
+          "$ref": "#/definitions/Channel"
+        },
+        "token_changed": {
+          "description": "indicates that a token of an existing channel has been added, replaced or removed.",
+          "type": "boolean"
+        }
+      },
+      "required": [
+        "descriptor",
+        "time",
+        "operation",
+        "remote_addr",
+        "token_changed"
+      ]
+    }
+  },
+  "$ref": "#/definitions/AuditRecord"
+}`
+
+var jsonSchemaAuditLogText = `{
+  "title": "AuditLog",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Descriptor": {
+      "description": "identifies a channel.",
+      "type": "string",
+      "example": "client-1/pipeline-3"
+    },
+    "Token": {
+      "description": "is a string authenticating the sender of an HTTP request.",
+      "type": "string",
+      "example": "RBbPYhmPXurT8nM5TAJpPOcHMaFkJblA62mr6MCvpF4oVa6cy"
+    },
+    "Entity": {
+      "description": "contains the email address and optionally the name of an entity.",
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "example": "name@domain.com"
+        },
+        "name": {
+          "type": "string",
+          "example": "John Doe"
+        }
+      },
+      "required": [
+        "email"
+      ]
+    },
+    "Disabled": {
+      "description": "indicates that the channel has been disabled and relays no messages.\n\nIf omitted when a channel is stored, an existing channel keeps its current state.\n",
+      "type": "object",
+      "properties": {
+        "time": {
+          "description": "is the time when the channel has been disabled in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "reason": {
+          "description": "is the reason why the channel has been disabled; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      },
+      "required": [
+        "time"
+      ]
+    },
+    "ChannelToken": {
+      "description": "is a named token authenticating the senders of a channel.\n\nThe Relay server accepts the messages authenticated by any live token.\n",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token, unique within the channel.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the token in the PHC string format.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "created": {
+          "description": "is the time when the token has been issued in RFC 3339 format; absent if unknown.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "expires": {
+          "description": "is the time in RFC 3339 format after which the token is rejected; absent if it never expires.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "last_used": {
+          "description": "is the time in RFC 3339 format when the token last authenticated a message; absent if never.\n\nThe field is only listed and ignored when a channel is stored.\n",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token_hash"
+      ]
+    },
+    "RateLimit": {
+      "description": "defines the token bucket limiting the rate of the messages of a channel.\n\nThe bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit\nis absent, the channel is limited by its min_period as if the bucket held a single message.\n",
+      "type": "object",
+      "properties": {
+        "rate": {
+          "description": "is the number of messages per second by which the bucket is refilled.",
+          "type": "number",
+          "format": "float",
+          "minimum": 0,
+          "exclusiveMinimum": true
+        },
+        "burst": {
+          "description": "is the maximum number of messages which can be relayed at once.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 1
+        }
+      },
+      "required": [
+        "rate",
+        "burst"
+      ]
+    },
+    "Quota": {
+      "description": "defines the hard quotas of a channel per calendar day and month.\n\nThe Relay server refuses the messages which would exceed a quota until the quota resets at the start\nof the next day or month in the time zone of the quota. The messages are counted only while the channel\nhas a quota.\n",
+      "type": "object",
+      "properties": {
+        "daily_messages": {
+          "description": "is the maximum number of the relayed messages per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_messages": {
+          "description": "is the maximum number of the relayed messages per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "daily_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "timezone": {
+          "description": "is the IANA time zone in which the days and the months start; absent for UTC.",
+          "type": "string",
+          "example": "Europe/Zurich"
+        }
+      }
+    },
+    "Channel": {
+      "description": "defines the messaging channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "token": {
+          "$ref": "#/definitions/Token"
+        },
+        "sender": {
+          "$ref": "#/definitions/Entity"
+        },
+        "recipients": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "cc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "bcc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "domain": {
+          "description": "indicates the MailGun domain for the channel.",
+          "type": "string",
+          "example": "marketing.domainname.com"
+        },
+        "min_period": {
+          "description": "is the minimum push period frequency for a channel, in seconds.",
+          "type": "number",
+          "format": "float"
+        },
+        "max_size": {
+          "description": "indicates the maximum allowed size of the request, in bytes.",
+          "type": "integer",
+          "format": "int32"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the default token in the PHC string format.\n\nListings never include the token, only its hash.\n",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "disabled": {
+          "$ref": "#/definitions/Disabled"
+        },
+        "valid_until": {
+          "description": "is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.\n\nThe Relay server refuses the messages of an expired channel. The Control server removes or disables\nthe expired channels after a grace period.\n",
+          "type": "string",
+          "format": "date-time",
+          "example": "2018-10-31T23:59:59Z"
+        },
+        "tokens": {
+          "description": "lists the named tokens of the channel in addition to the default one.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ChannelToken"
+          }
+        },
+        "rate_limit": {
+          "$ref": "#/definitions/RateLimit"
+        },
+        "quota": {
+          "$ref": "#/definitions/Quota"
+        },
+        "on_throttle": {
+          "description": "specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.\n\nOne of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued\nmessages are stored by the Relay server and relayed in order once the rate limit allows.\n",
+          "type": "string",
+          "example": "queue"
+        },
+        "max_queue_depth": {
+          "description": "is the maximum number of the messages of the channel waiting in the queue; absent or 0 for\nthe default of 100 messages.\n\nThe messages beyond it are refused with 429 Too Many Requests.\n",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 0
+        }
+      },
+      "required": [
+        "descriptor",
+        "sender",
+        "recipients",
+        "domain",
+        "min_period",
+        "max_size"
+      ]
+    },
+    "AuditRecord": {
+      "description": "records a change of a channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "time": {
+          "description": "is the time of the change in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "operation": {
+          "description": "is the kind of the change.\n\nOne of put, delete, rollback, disable, enable, rotate and revoke.\n",
+          "type": "string",
+          "example": "put"
+        },
+        "actor": {
+          "description": "is the identity of the client given in the X-Actor header; absent if not given.",
+          "type": "string",
+          "example": "ops@domain.com"
+        },
+        "remote_addr": {
+          "description": "is the network address of the client; empty if the change has been made locally.",
+          "type": "string",
+          "example": "192.168.1.10:53124"
+        },
+        "forwarded_for": {
+          "description": "is the X-Forwarded-For header of the request; absent if not given.",
+          "type": "string"
+        },
+        "user_agent": {
+          "description": "is the User-Agent header of the request; absent if not given.",
+          "type": "string"
+        },
+        "before": {
+          "$ref": "#/definitions/Channel"
+        },
+        "after": {
+          "$ref": "#/definitions/Channel"
+        },
+        "token_changed": {
+          "description": "indicates that a token of an existing channel has been added, replaced or removed.",
+          "type": "boolean"
+        }
+      },
+      "required": [
+        "descriptor",
+        "time",
+        "operation",
+        "remote_addr",
+        "token_changed"
+      ]
+    },
+    "AuditLog": {
+      "description": "lists the changes of the channels.",
+      "type": "object",
+      "properties": {
+        "records": {
+          "description": "contains the changes ordered by time.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/AuditRecord"
+          }
+        },
+        "next_cursor": {
+          "description": "is the opaque cursor to list the following page; absent if there are no more changes.",
+          "type": "string"
+        }
+      },
+      "required": [
+        "records"
+      ]
+    }
+  },
+  "$ref": "#/definitions/AuditLog"
+}`
+
+var jsonSchemaFieldChangeText = `{
+  "title": "FieldChange",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "FieldChange": {
+      "description": "describes how a field of a channel changed between two revisions.",
+      "type": "object",
+      "properties": {
+        "field": {
+          "description": "is the name of the field.",
+          "type": "string",
+          "example": "recipients"
+        },
+        "before": {
+          "description": "is the JSON representation of the field in the previous revision; absent if unset.",
+          "type": "string",
+          "example": "[{\"email\":\"name@domain.com\"}]"
+        },
+        "after": {
+          "description": "is the JSON representation of the field in this revision; absent if unset.",
+          "type": "string",
+          "example": "[{\"email\":\"name@domain.com\"},{\"email\":\"other@domain.com\"}]"
+        }
+      },
+      "required": [
+        "field"
+      ]
+    }
+  },
+  "$ref": "#/definitions/FieldChange"
+}`
+
+var jsonSchemaChannelRevisionText = `{
+  "title": "ChannelRevision",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Descriptor": {
+      "description": "identifies a channel.",
+      "type": "string",
+      "example": "client-1/pipeline-3"
+    },
+    "Token": {
+      "description": "is a string authenticating the sender of an HTTP request.",
+      "type": "string",
+      "example": "RBbPYhmPXurT8nM5TAJpPOcHMaFkJblA62mr6MCvpF4oVa6cy"
+    },
+    "Entity": {
+      "description": "contains the email address and optionally the name of an entity.",
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "example": "name@domain.com"
+        },
+        "name": {
+          "type": "string",
+          "example": "John Doe"
+        }
+      },
+      "required": [
+        "email"
+      ]
+    },
+    "Disabled": {
+      "description": "indicates that the channel has been disabled and relays no messages.\n\nIf omitted when a channel is stored, an existing channel keeps its current state.\n",
+      "type": "object",
+      "properties": {
+        "time": {
+          "description": "is the time when the channel has been disabled in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "reason": {
+          "description": "is the reason why the channel has been disabled; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      },
+      "required": [
+        "time"
+      ]
+    },
+    "ChannelToken": {
+      "description": "is a named token authenticating the senders of a channel.\n\nThe Relay server accepts the messages authenticated by any live token.\n",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token, unique within the channel.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the token in the PHC string format.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "created": {
+          "description": "is the time when the token has been issued in RFC 3339 format; absent if unknown.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "expires": {
+          "description": "is the time in RFC 3339 format after which the token is rejected; absent if it never expires.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "last_used": {
+          "description": "is the time in RFC 3339 format when the token last authenticated a message; absent if never.\n\nThe field is only listed and ignored when a channel is stored.\n",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token_hash"
+      ]
+    },
+    "RateLimit": {
+      "description": "defines the token bucket limiting the rate of the messages of a channel.\n\nThe bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit\nis absent, the channel is limited by its min_period as if the bucket held a single message.\n",
+      "type": "object",
+      "properties": {
+        "rate": {
+          "description": "is the number of messages per second by which the bucket is refilled.",
+          "type": "number",
+          "format": "float",
+          "minimum": 0,
+          "exclusiveMinimum": true
+        },
+        "burst": {
+          "description": "is the maximum number of messages which can be relayed at once.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 1
+        }
+      },
+      "required": [
+        "rate",
+        "burst"
+      ]
+    },
+    "Quota": {
+      "description": "defines the hard quotas of a channel per calendar day and month.\n\nThe Relay server refuses the messages which would exceed a quota until the quota resets at the start\nof the next day or month in the time zone of the quota. The messages are counted only while the channel\nhas a quota.\n",
+      "type": "object",
+      "properties": {
+        "daily_messages": {
+          "description": "is the maximum number of the relayed messages per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_messages": {
+          "description": "is the maximum number of the relayed messages per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "daily_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "timezone": {
+          "description": "is the IANA time zone in which the days and the months start; absent for UTC.",
+          "type": "string",
+          "example": "Europe/Zurich"
+        }
+      }
+    },
+    "Channel": {
+      "description": "defines the messaging channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "token": {
+          "$ref": "#/definitions/Token"
+        },
+        "sender": {
+          "$ref": "#/definitions/Entity"
+        },
+        "recipients": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "cc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "bcc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "domain": {
+          "description": "indicates the MailGun domain for the channel.",
+          "type": "string",
+          "example": "marketing.domainname.com"
+        },
+        "min_period": {
+          "description": "is the minimum push period frequency for a channel, in seconds.",
+          "type": "number",
+          "format": "float"
+        },
+        "max_size": {
+          "description": "indicates the maximum allowed size of the request, in bytes.",
+          "type": "integer",
+          "format": "int32"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the default token in the PHC string format.\n\nListings never include the token, only its hash.\n",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "disabled": {
+          "$ref": "#/definitions/Disabled"
+        },
+        "valid_until": {
+          "description": "is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.\n\nThe Relay server refuses the messages of an expired channel. The Control server removes or disables\nthe expired channels after a grace period.\n",
+          "type": "string",
+          "format": "date-time",
+          "example": "2018-10-31T23:59:59Z"
+        },
+        "tokens": {
+          "description": "lists the named tokens of the channel in addition to the default one.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ChannelToken"
+          }
+        },
+        "rate_limit": {
+          "$ref": "#/definitions/RateLimit"
+        },
+        "quota": {
+          "$ref": "#/definitions/Quota"
+        },
+        "on_throttle": {
+          "description": "specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.\n\nOne of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued\nmessages are stored by the Relay server and relayed in order once the rate limit allows.\n",
+          "type": "string",
+          "example": "queue"
+        },
+        "max_queue_depth": {
+          "description": "is the maximum number of the messages of the channel waiting in the queue; absent or 0 for\nthe default of 100 messages.\n\nThe messages beyond it are refused with 429 Too Many Requests.\n",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 0
+        }
+      },
+      "required": [
+        "descriptor",
+        "sender",
+        "recipients",
+        "domain",
+        "min_period",
+        "max_size"
+      ]
+    },
+    "FieldChange": {
+      "description": "describes how a field of a channel changed between two revisions.",
+      "type": "object",
+      "properties": {
+        "field": {
+          "description": "is the name of the field.",
+          "type": "string",
+          "example": "recipients"
+        },
+        "before": {
+          "description": "is the JSON representation of the field in the previous revision; absent if unset.",
+          "type": "string",
+          "example": "[{\"email\":\"name@domain.com\"}]"
+        },
+        "after": {
+          "description": "is the JSON representation of the field in this revision; absent if unset.",
+          "type": "string",
+          "example": "[{\"email\":\"name@domain.com\"},{\"email\":\"other@domain.com\"}]"
+        }
+      },
+      "required": [
+        "field"
+      ]
+    },
+    "ChannelRevision": {
+      "description": "is a stored version of a channel.",
+      "type": "object",
+      "properties": {
+        "revision": {
+          "description": "is the number of the revision, counting from 1 for each channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "time": {
+          "description": "is the time when the revision has been stored in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "actor": {
+          "description": "is the identity of the client which stored the revision; absent if not given.",
+          "type": "string",
+          "example": "ops@domain.com"
+        },
+        "channel": {
+          "$ref": "#/definitions/Channel"
+        },
+        "changes": {
+          "description": "lists the fields changed since the previous kept revision; empty for the oldest kept revision.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/FieldChange"
+          }
+        }
+      },
+      "required": [
+        "revision",
+        "time",
+        "channel",
+        "changes"
+      ]
+    }
+  },
+  "$ref": "#/definitions/ChannelRevision"
+}`
+
+var jsonSchemaChannelRevisionsText = `{
+  "title": "ChannelRevisions",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Descriptor": {
+      "description": "identifies a channel.",
+      "type": "string",
+      "example": "client-1/pipeline-3"
+    },
+    "Token": {
+      "description": "is a string authenticating the sender of an HTTP request.",
+      "type": "string",
+      "example": "RBbPYhmPXurT8nM5TAJpPOcHMaFkJblA62mr6MCvpF4oVa6cy"
+    },
+    "Entity": {
+      "description": "contains the email address and optionally the name of an entity.",
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "example": "name@domain.com"
+        },
+        "name": {
+          "type": "string",
+          "example": "John Doe"
+        }
+      },
+      "required": [
+        "email"
+      ]
+    },
+    "Disabled": {
+      "description": "indicates that the channel has been disabled and relays no messages.\n\nIf omitted when a channel is stored, an existing channel keeps its current state.\n",
+      "type": "object",
+      "properties": {
+        "time": {
+          "description": "is the time when the channel has been disabled in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "reason": {
+          "description": "is the reason why the channel has been disabled; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      },
+      "required": [
+        "time"
+      ]
+    },
+    "ChannelToken": {
+      "description": "is a named token authenticating the senders of a channel.\n\nThe Relay server accepts the messages authenticated by any live token.\n",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token, unique within the channel.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the token in the PHC string format.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "created": {
+          "description": "is the time when the token has been issued in RFC 3339 format; absent if unknown.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "expires": {
+          "description": "is the time in RFC 3339 format after which the token is rejected; absent if it never expires.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "last_used": {
+          "description": "is the time in RFC 3339 format when the token last authenticated a message; absent if never.\n\nThe field is only listed and ignored when a channel is stored.\n",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token_hash"
+      ]
+    },
+    "RateLimit": {
+      "description": "defines the token bucket limiting the rate of the messages of a channel.\n\nThe bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit\nis absent, the channel is limited by its min_period as if the bucket held a single message.\n",
+      "type": "object",
+      "properties": {
+        "rate": {
+          "description": "is the number of messages per second by which the bucket is refilled.",
+          "type": "number",
+          "format": "float",
+          "minimum": 0,
+          "exclusiveMinimum": true
+        },
+        "burst": {
+          "description": "is the maximum number of messages which can be relayed at once.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 1
+        }
+      },
+      "required": [
+        "rate",
+        "burst"
+      ]
+    },
+    "Quota": {
+      "description": "defines the hard quotas of a channel per calendar day and month.\n\nThe Relay server refuses the messages which would exceed a quota until the quota resets at the start\nof the next day or month in the time zone of the quota. The messages are counted only while the channel\nhas a quota.\n",
+      "type": "object",
+      "properties": {
+        "daily_messages": {
+          "description": "is the maximum number of the relayed messages per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_messages": {
+          "description": "is the maximum number of the relayed messages per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "daily_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "timezone": {
+          "description": "is the IANA time zone in which the days and the months start; absent for UTC.",
+          "type": "string",
+          "example": "Europe/Zurich"
+        }
+      }
+    },
+    "Channel": {
+      "description": "defines the messaging channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "$ref": "#/definitions/Descriptor"
+        },
+        "token": {
+          "$ref": "#/definitions/Token"
+        },
+        "sender": {
+          "$ref": "#/definitions/Entity"
+        },
+        "recipients": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "cc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "bcc": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Entity"
+          }
+        },
+        "domain": {
+          "description": "indicates the MailGun domain for the channel.",
+          "type": "string",
+          "example": "marketing.domainname.com"
+        },
+        "min_period": {
+          "description": "is the minimum push period frequency for a channel, in seconds.",
+          "type": "number",
+          "format": "float"
+        },
+        "max_size": {
+          "description": "indicates the maximum allowed size of the request, in bytes.",
+          "type": "integer",
+          "format": "int32"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the default token in the PHC string format.\n\nListings never include the token, only its hash.\n",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "disabled": {
+          "$ref": "#/definitions/Disabled"
+        },
+        "valid_until": {
+          "description": "is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.\n\nThe Relay server refuses the messages of an expired channel. The Control server removes or disables\nthe expired channels after a grace period.\n",
+          "type": "string",
+          "format": "date-time",
+          "example": "2018-10-31T23:59:59Z"
+        },
+        "tokens": {
+          "description": "lists the named tokens of the channel in addition to the default one.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ChannelToken"
+          }
+        },
+        "rate_limit": {
+          "$ref": "#/definitions/RateLimit"
+        },
+        "quota": {
+          "$ref": "#/definitions/Quota"
+        },
+        "on_throttle": {
+          "description": "specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.\n\nOne of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued\nmessages are stored by the Relay server and relayed in order once the rate limit allows.\n",
+          "type": "string",
+          "example": "queue"
+        },
+        "max_queue_depth": {
+          "description": "is the maximum number of the messages of the channel waiting in the queue; absent or 0 for\nthe default of 100 messages.\n\nThe messages beyond it are refused with 429 Too Many Requests.\n",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 0
+        }
+      },
+      "required": [
+        "descriptor",
+        "sender",
+        "recipients",
+        "domain",
+        "min_period",
+        "max_size"
+      ]
+    },
+    "FieldChange": {
+      "description": "describes how a field of a channel changed between two revisions.",
+      "type": "object",
+      "properties": {
+        "field": {
+          "description": "is the name of the field.",
+          "type": "string",
+          "example": "recipients"
+        },
+        "before": {
+          "description": "is the JSON representation of the field in the previous revision; absent if unset.",
+          "type": "string",
+          "example": "[{\"email\":\"name@domain.com\"}]"
+        },
+        "after": {
+          "description": "is the JSON representation of the field in this revision; absent if unset.",
+          "type": "string",
+          "example": "[{\"email\":\"name@domain.com\"},{\"email\":\"other@domain.com\"}]"
+        }
+      },
+      "required": [
+        "field"
+      ]
+    },
+    "ChannelRevision": {
+      "description": "is a stored version of a channel.",
+      "type": "object",
+      "properties": {
+        "revision": {
+          "description": "is the number of the revision, counting from 1 for each channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "time": {
+          "description": "is the time when the revision has been stored in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "actor": {
+          "description": "is the identity of the client which stored the revision; absent if not given.",
+          "type": "string",
+          "example": "ops@domain.com"
+        },
+        "channel": {
+          "$ref": "#/definitions/Channel"
+        },
+        "changes": {
+          "description": "lists the fields changed since the previous kept revision; empty for the oldest kept revision.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/FieldChange"
+          }
+        }
+      },
+      "required": [
+        "revision",
+        "time",
+        "channel",
+        "changes"
+      ]
+    },
+    "ChannelRevisions": {
+      "description": "lists the kept revisions of a channel.",
+      "type": "object",
+      "properties": {
+        "revisions": {
+          "description": "contains the revisions ordered by their numbers.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/ChannelRevision"
+          }
+        }
+      },
+      "required": [
+        "revisions"
+      ]
+    }
+  },
+  "$ref": "#/definitions/ChannelRevisions"
+}`
+
+var jsonSchemaRollbackText = `{
+  "title": "Rollback",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Rollback": {
+      "description": "selects the revision to which a channel is restored.",
+      "type": "object",
+      "properties": {
+        "revision": {
+          "description": "is the number of the revision.",
+          "type": "integer",
+          "format": "int64"
+        }
+      },
+      "required": [
+        "revision"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Rollback"
+}`
+
+var jsonSchemaDisabledText = `{
+  "title": "Disabled",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Disabled": {
+      "description": "indicates that the channel has been disabled and relays no messages.\n\nIf omitted when a channel is stored, an existing channel keeps its current state.\n",
+      "type": "object",
+      "properties": {
+        "time": {
+          "description": "is the time when the channel has been disabled in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "reason": {
+          "description": "is the reason why the channel has been disabled; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      },
+      "required": [
+        "time"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Disabled"
+}`
+
+var jsonSchemaDisableText = `{
+  "title": "Disable",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Disable": {
+      "description": "gives the reason why a channel is disabled.",
+      "type": "object",
+      "properties": {
+        "reason": {
+          "description": "is the reason reported to the clients of the channel; absent if not given.",
+          "type": "string",
+          "example": "sends a message every second"
+        }
+      }
+    }
+  },
+  "$ref": "#/definitions/Disable"
+}`
+
+var jsonSchemaChannelTokenText = `{
+  "title": "ChannelToken",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "ChannelToken": {
+      "description": "is a named token authenticating the senders of a channel.\n\nThe Relay server accepts the messages authenticated by any live token.\n",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token, unique within the channel.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline"
+        },
+        "token_hash": {
+          "description": "is the salted hash of the token in the PHC string format.",
+          "type": "string",
+          "example": "$argon2id$v=19$m=65536,t=1,p=4$c29tZXNhbHRzb21lc2FsdA$MjX8U1ig1k8bJrVx2EOSlUm5CNTPgGm4PcJr9/Y1yq4"
+        },
+        "created": {
+          "description": "is the time when the token has been issued in RFC 3339 format; absent if unknown.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "expires": {
+          "description": "is the time in RFC 3339 format after which the token is rejected; absent if it never expires.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "last_used": {
+          "description": "is the time in RFC 3339 format when the token last authenticated a message; absent if never.\n\nThe field is only listed and ignored when a channel is stored.\n",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token_hash"
+      ]
+    }
+  },
+  "$ref": "#/definitions/ChannelToken"
+}`
+
+var jsonSchemaRotateText = `{
+  "title": "Rotate",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Rotate": {
+      "description": "defines the rotation of the tokens of a channel.",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the new token.",
+          "type": "string",
+          "pattern": "^[a-zA-Z0-9._-]+$",
+          "example": "ci-pipeline-2"
+        },
+        "grace_period": {
+          "description": "is the period in seconds after which the other tokens expire;\nabsent to use the default grace period of the Control server.\n",
+          "type": "number",
+          "format": "float",
+          "minimum": 0
+        }
+      },
+      "required": [
+        "name"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Rotate"
+}`
+
+var jsonSchemaIssuedTokenText = `{
+  "title": "IssuedToken",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "IssuedToken": {
+      "description": "contains a token issued by the server.",
+      "type": "object",
+      "properties": {
+        "name": {
+          "description": "is the name of the token.",
+          "type": "string",
+          "example": "ci-pipeline-2"
+        },
+        "token": {
+          "description": "is the token in plain text; it is not stored and can not be retrieved later.",
+          "type": "string"
+        },
+        "expires_others": {
+          "description": "is the time in RFC 3339 format after which the other tokens of the channel expire.",
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "name",
+        "token",
+        "expires_others"
+      ]
+    }
+  },
+  "$ref": "#/definitions/IssuedToken"
+}`
+
+var jsonSchemaChannelStatsText = `{
+  "title": "ChannelStats",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Rejections": {
+      "description": "counts the attempts which have not been relayed by reason.",
+      "type": "object",
+      "properties": {
+        "forbidden": {
+          "description": "is the number of the attempts with an invalid token.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "too_soon": {
+          "description": "is the number of the attempts before the min_period elapsed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "too_large": {
+          "description": "is the number of the attempts exceeding the max_size.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "invalid": {
+          "description": "is the number of the attempts whose message failed the schema or could not be parsed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "failed": {
+          "description": "is the number of the attempts which failed due to an error, e.g., of MailGun.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "disabled": {
+          "description": "is the number of the attempts through the disabled channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "expired": {
+          "description": "is the number of the attempts through the expired channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "quota_exceeded": {
+          "description": "is the number of the attempts which would have exceeded a quota of the channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "throttled": {
+          "description": "is the number of the attempts refused since a global limit of the relay has been reached.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "locked_out": {
+          "description": "is the number of the attempts refused since the remote IP or the descriptor was locked out.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "queue_full": {
+          "description": "is the number of the attempts refused since the queue of the channel was full.",
+          "type": "integer",
+          "format": "int64"
+        }
+      },
+      "required": [
+        "forbidden",
+        "too_soon",
+        "too_large",
+        "invalid",
+        "failed",
+        "disabled",
+        "expired",
+        "quota_exceeded",
+        "throttled",
+        "locked_out",
+        "queue_full"
+      ]
+    },
+    "Usage": {
+      "description": "counts the attempts to relay a message through a channel within a period.",
+      "type": "object",
+      "properties": {
+        "start": {
+          "description": "is the start of the period in RFC 3339 format.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "relayed": {
+          "description": "is the number of the relayed messages.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "bytes": {
+          "description": "is the total size of the relayed messages in bytes.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "queued": {
+          "description": "is the number of the messages queued since they exceeded the rate limit.\n\nThe queued messages are counted as relayed once they have been relayed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "rejected": {
+          "$ref": "#/definitions/Rejections"
+        }
+      },
+      "required": [
+        "start",
+        "relayed",
+        "bytes",
+        "queued",
+        "rejected"
+      ]
+    },
+    "ChannelStats": {
+      "description": "lists the usage counters of a channel.",
+      "type": "object",
+      "properties": {
+        "descriptor": {
+          "type": "string"
+        },
+        "period": {
+          "description": "is the period of the counters, either \"hour\" or \"day\".",
+          "type": "string",
+          "example": "day"
+        },
+        "usage": {
+          "description": "contains the counters ordered by the start of their period.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Usage"
+          }
+        }
+      },
+      "required": [
+        "descriptor",
+        "period",
+        "usage"
+      ]
+    }
+  },
+  "$ref": "#/definitions/ChannelStats"
+}`
+
+var jsonSchemaUsageText = `{
+  "title": "Usage",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Rejections": {
+      "description": "counts the attempts which have not been relayed by reason.",
+      "type": "object",
+      "properties": {
+        "forbidden": {
+          "description": "is the number of the attempts with an invalid token.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "too_soon": {
+          "description": "is the number of the attempts before the min_period elapsed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "too_large": {
+          "description": "is the number of the attempts exceeding the max_size.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "invalid": {
+          "description": "is the number of the attempts whose message failed the schema or could not be parsed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "failed": {
+          "description": "is the number of the attempts which failed due to an error, e.g., of MailGun.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "disabled": {
+          "description": "is the number of the attempts through the disabled channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "expired": {
+          "description": "is the number of the attempts through the expired channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "quota_exceeded": {
+          "description": "is the number of the attempts which would have exceeded a quota of the channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "throttled": {
+          "description": "is the number of the attempts refused since a global limit of the relay has been reached.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "locked_out": {
+          "description": "is the number of the attempts refused since the remote IP or the descriptor was locked out.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "queue_full": {
+          "description": "is the number of the attempts refused since the queue of the channel was full.",
+          "type": "integer",
+          "format": "int64"
+        }
+      },
+      "required": [
+        "forbidden",
+        "too_soon",
+        "too_large",
+        "invalid",
+        "failed",
+        "disabled",
+        "expired",
+        "quota_exceeded",
+        "throttled",
+        "locked_out",
+        "queue_full"
+      ]
+    },
+    "Usage": {
+      "description": "counts the attempts to relay a message through a channel within a period.",
+      "type": "object",
+      "properties": {
+        "start": {
+          "description": "is the start of the period in RFC 3339 format.",
+          "type": "string",
+          "format": "date-time"
+        },
+        "relayed": {
+          "description": "is the number of the relayed messages.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "bytes": {
+          "description": "is the total size of the relayed messages in bytes.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "queued": {
+          "description": "is the number of the messages queued since they exceeded the rate limit.\n\nThe queued messages are counted as relayed once they have been relayed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "rejected": {
+          "$ref": "#/definitions/Rejections"
+        }
+      },
+      "required": [
+        "start",
+        "relayed",
+        "bytes",
+        "queued",
+        "rejected"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Usage"
+}`
+
+var jsonSchemaRejectionsText = `{
+  "title": "Rejections",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Rejections": {
+      "description": "counts the attempts which have not been relayed by reason.",
+      "type": "object",
+      "properties": {
+        "forbidden": {
+          "description": "is the number of the attempts with an invalid token.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "too_soon": {
+          "description": "is the number of the attempts before the min_period elapsed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "too_large": {
+          "description": "is the number of the attempts exceeding the max_size.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "invalid": {
+          "description": "is the number of the attempts whose message failed the schema or could not be parsed.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "failed": {
+          "description": "is the number of the attempts which failed due to an error, e.g., of MailGun.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "disabled": {
+          "description": "is the number of the attempts through the disabled channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "expired": {
+          "description": "is the number of the attempts through the expired channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "quota_exceeded": {
+          "description": "is the number of the attempts which would have exceeded a quota of the channel.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "throttled": {
+          "description": "is the number of the attempts refused since a global limit of the relay has been reached.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "locked_out": {
+          "description": "is the number of the attempts refused since the remote IP or the descriptor was locked out.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "queue_full": {
+          "description": "is the number of the attempts refused since the queue of the channel was full.",
+          "type": "integer",
+          "format": "int64"
+        }
+      },
+      "required": [
+        "forbidden",
+        "too_soon",
+        "too_large",
+        "invalid",
+        "failed",
+        "disabled",
+        "expired",
+        "quota_exceeded",
+        "throttled",
+        "locked_out",
+        "queue_full"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Rejections"
+}`
+
+var jsonSchemaRateLimitText = `{
+  "title": "RateLimit",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "RateLimit": {
+      "description": "defines the token bucket limiting the rate of the messages of a channel.\n\nThe bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit\nis absent, the channel is limited by its min_period as if the bucket held a single message.\n",
+      "type": "object",
+      "properties": {
+        "rate": {
+          "description": "is the number of messages per second by which the bucket is refilled.",
+          "type": "number",
+          "format": "float",
+          "minimum": 0,
+          "exclusiveMinimum": true
+        },
+        "burst": {
+          "description": "is the maximum number of messages which can be relayed at once.",
+          "type": "integer",
+          "format": "int32",
+          "minimum": 1
+        }
+      },
+      "required": [
+        "rate",
+        "burst"
+      ]
+    }
+  },
+  "$ref": "#/definitions/RateLimit"
+}`
+
+var jsonSchemaQuotaText = `{
+  "title": "Quota",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Quota": {
+      "description": "defines the hard quotas of a channel per calendar day and month.\n\nThe Relay server refuses the messages which would exceed a quota until the quota resets at the start\nof the next day or month in the time zone of the quota. The messages are counted only while the channel\nhas a quota.\n",
+      "type": "object",
+      "properties": {
+        "daily_messages": {
+          "description": "is the maximum number of the relayed messages per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_messages": {
+          "description": "is the maximum number of the relayed messages per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "daily_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "monthly_bytes": {
+          "description": "is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.",
+          "type": "integer",
+          "format": "int64",
+          "minimum": 0
+        },
+        "timezone": {
+          "description": "is the IANA time zone in which the days and the months start; absent for UTC.",
+          "type": "string",
+          "example": "Europe/Zurich"
+        }
+      }
+    }
+  },
+  "$ref": "#/definitions/Quota"
+}`
+
+var jsonSchemaLockoutText = `{
+  "title": "Lockout",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Lockout": {
+      "description": "represents the failed authentications of a remote IP or of a descriptor.",
+      "type": "object",
+      "properties": {
+        "kind": {
+          "description": "is either \"ip\" or \"descriptor\".",
+          "type": "string",
+          "example": "ip"
+        },
+        "subject": {
+          "description": "is the remote IP or the descriptor.",
+          "type": "string",
+          "example": "192.0.2.1"
+        },
+        "failures": {
+          "description": "is the number of the consecutive failed authentications.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "last_failure": {
+          "description": "is the time of the last failed authentication in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "locked_until": {
+          "description": "is the end of the lockout in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:38:00.123456789Z"
+        }
+      },
+      "required": [
+        "kind",
+        "subject",
+        "failures",
+        "last_failure",
+        "locked_until"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Lockout"
+}`
+
+var jsonSchemaLockoutsText = `{
+  "title": "Lockouts",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Lockout": {
+      "description": "represents the failed authentications of a remote IP or of a descriptor.",
+      "type": "object",
+      "properties": {
+        "kind": {
+          "description": "is either \"ip\" or \"descriptor\".",
+          "type": "string",
+          "example": "ip"
+        },
+        "subject": {
+          "description": "is the remote IP or the descriptor.",
+          "type": "string",
+          "example": "192.0.2.1"
+        },
+        "failures": {
+          "description": "is the number of the consecutive failed authentications.",
+          "type": "integer",
+          "format": "int64"
+        },
+        "last_failure": {
+          "description": "is the time of the last failed authentication in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "locked_until": {
+          "description": "is the end of the lockout in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:38:00.123456789Z"
+        }
+      },
+      "required": [
+        "kind",
+        "subject",
+        "failures",
+        "last_failure",
+        "locked_until"
+      ]
+    },
+    "Lockouts": {
+      "description": "lists the current lockouts.",
+      "type": "object",
+      "properties": {
+        "lockouts": {
+          "description": "contains the lockouts ordered by the kind and the subject.",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/Lockout"
+          }
+        }
+      },
+      "required": [
+        "lockouts"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Lockouts"
+}`
+
+var jsonSchemaUnlockText = `{
+  "title": "Unlock",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "Unlock": {
+      "description": "selects the remote IP or the descriptor whose lockout is lifted.",
+      "type": "object",
+      "properties": {
+        "kind": {
+          "description": "is either \"ip\" or \"descriptor\".",
+          "type": "string",
+          "example": "descriptor"
+        },
+        "subject": {
+          "description": "is the remote IP or the descriptor.",
+          "type": "string"
+        }
+      },
+      "required": [
+        "kind",
+        "subject"
+      ]
+    }
+  },
+  "$ref": "#/definitions/Unlock"
+}`
+
+var jsonSchemaChannel = mustNewJSONSchema(
+	jsonSchemaChannelText,
+	"Channel")
+
+var jsonSchemaDescriptor = mustNewJSONSchema(
+	jsonSchemaDescriptorText,
+	"Descriptor")
+
+var jsonSchemaToken = mustNewJSONSchema(
+	jsonSchemaTokenText,
+	"Token")
+
+var jsonSchemaEntity = mustNewJSONSchema(
+	jsonSchemaEntityText,
+	"Entity")
+
+var jsonSchemaChannelsPage = mustNewJSONSchema(
+	jsonSchemaChannelsPageText,
+	"ChannelsPage")
+
+var jsonSchemaRelayRecord = mustNewJSONSchema(
+	jsonSchemaRelayRecordText,
+	"RelayRecord")
+
+var jsonSchemaRelayLog = mustNewJSONSchema(
+	jsonSchemaRelayLogText,
+	"RelayLog")
+
+var jsonSchemaAuditRecord = mustNewJSONSchema(
+	jsonSchemaAuditRecordText,
+	"AuditRecord")
+
+var jsonSchemaAuditLog = mustNewJSONSchema(
+	jsonSchemaAuditLogText,
+	"AuditLog")
+
+var jsonSchemaFieldChange = mustNewJSONSchema(
+	jsonSchemaFieldChangeText,
+	"FieldChange")
+
+var jsonSchemaChannelRevision = mustNewJSONSchema(
+	jsonSchemaChannelRevisionText,
+	"ChannelRevision")
+
+var jsonSchemaChannelRevisions = mustNewJSONSchema(
+	jsonSchemaChannelRevisionsText,
+	"ChannelRevisions")
+
+var jsonSchemaRollback = mustNewJSONSchema(
+	jsonSchemaRollbackText,
+	"Rollback")
+
+var jsonSchemaDisabled = mustNewJSONSchema(
+	jsonSchemaDisabledText,
+	"Disabled")
+
+var jsonSchemaDisable = mustNewJSONSchema(
+	jsonSchemaDisableText,
+	"Disable")
+
+var jsonSchemaChannelToken = mustNewJSONSchema(
+	jsonSchemaChannelTokenText,
+	"ChannelToken")
+
+var jsonSchemaRotate = mustNewJSONSchema(
+	jsonSchemaRotateText,
+	"Rotate")
+
+var jsonSchemaIssuedToken = mustNewJSONSchema(
+	jsonSchemaIssuedTokenText,
+	"IssuedToken")
+
+var jsonSchemaChannelStats = mustNewJSONSchema(
+	jsonSchemaChannelStatsText,
+	"ChannelStats")
+
+var jsonSchemaUsage = mustNewJSONSchema(
+	jsonSchemaUsageText,
+	"Usage")
+
+var jsonSchemaRejections = mustNewJSONSchema(
+	jsonSchemaRejectionsText,
+	"Rejections")
+
+var jsonSchemaRateLimit = mustNewJSONSchema(
+	jsonSchemaRateLimitText,
+	"RateLimit")
+
+var jsonSchemaQuota = mustNewJSONSchema(
+	jsonSchemaQuotaText,
+	"Quota")
+
+var jsonSchemaLockout = mustNewJSONSchema(
+	jsonSchemaLockoutText,
+	"Lockout")
+
+var jsonSchemaLockouts = mustNewJSONSchema(
+	jsonSchemaLockoutsText,
+	"Lockouts")
+
+var jsonSchemaUnlock = mustNewJSONSchema(
+	jsonSchemaUnlockText,
+	"Unlock")
+
+// ValidateAgainstChannelSchema validates a message coming from the client against Channel schema.
+func ValidateAgainstChannelSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaChannel.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstDescriptorSchema validates a message coming from the client against Descriptor schema.
+func ValidateAgainstDescriptorSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaDescriptor.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstTokenSchema validates a message coming from the client against Token schema.
+func ValidateAgainstTokenSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaToken.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstEntitySchema validates a message coming from the client against Entity schema.
+func ValidateAgainstEntitySchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaEntity.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstChannelsPageSchema validates a message coming from the client against ChannelsPage schema.
+func ValidateAgainstChannelsPageSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaChannelsPage.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstRelayRecordSchema validates a message coming from the client against RelayRecord schema.
+func ValidateAgainstRelayRecordSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaRelayRecord.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstRelayLogSchema validates a message coming from the client against RelayLog schema.
+func ValidateAgainstRelayLogSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaRelayLog.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstAuditRecordSchema validates a message coming from the client against AuditRecord schema.
+func ValidateAgainstAuditRecordSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaAuditRecord.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstAuditLogSchema validates a message coming from the client against AuditLog schema.
+func ValidateAgainstAuditLogSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaAuditLog.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstFieldChangeSchema validates a message coming from the client against FieldChange schema.
+func ValidateAgainstFieldChangeSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaFieldChange.Validate(loader)
 	if err != nil {
 		return err
 	}
@@ -337,10 +3041,10 @@ func ValidateAgainstDescriptorSchema(bb []byte) error {
 	return errors.New(msg)
 }
 
-// ValidateAgainstTokenSchema validates a message coming from the client against Token schema.
-func ValidateAgainstTokenSchema(bb []byte) error {
+// ValidateAgainstChannelRevisionSchema validates a message coming from the client against ChannelRevision schema.
+func ValidateAgainstChannelRevisionSchema(bb []byte) error {
 	loader := gojsonschema.NewStringLoader(string(bb))
-	result, err := jsonSchemaToken.Validate(loader)
+	result, err := jsonSchemaChannelRevision.Validate(loader)
 	if err != nil {
 		return err
 	}
@@ -359,10 +3063,10 @@ func ValidateAgainstTokenSchema(bb []byte) error {
 	return errors.New(msg)
 }
 
-// ValidateAgainstEntitySchema validates a message coming from the client against Entity schema.
-func ValidateAgainstEntitySchema(bb []byte) error {
+// ValidateAgainstChannelRevisionsSchema validates a message coming from the client against ChannelRevisions schema.
+func ValidateAgainstChannelRevisionsSchema(bb []byte) error {
 	loader := gojsonschema.NewStringLoader(string(bb))
-	result, err := jsonSchemaEntity.Validate(loader)
+	result, err := jsonSchemaChannelRevisions.Validate(loader)
 	if err != nil {
 		return err
 	}
@@ -381,10 +3085,296 @@ func ValidateAgainstEntitySchema(bb []byte) error {
 	return errors.New(msg)
 }
 
-// ValidateAgainstChannelsPageSchema validates a message coming from the client against ChannelsPage schema.
-func ValidateAgainstChannelsPageSchema(bb []byte) error {
+// ValidateAgainstRollbackSchema validates a message coming from the client against Rollback schema.
+func ValidateAgainstRollbackSchema(bb []byte) error {
 	loader := gojsonschema.NewStringLoader(string(bb))
-	result, err := jsonSchemaChannelsPage.Validate(loader)
+	result, err := jsonSchemaRollback.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstDisabledSchema validates a message coming from the client against Disabled schema.
+func ValidateAgainstDisabledSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaDisabled.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstDisableSchema validates a message coming from the client against Disable schema.
+func ValidateAgainstDisableSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaDisable.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstChannelTokenSchema validates a message coming from the client against ChannelToken schema.
+func ValidateAgainstChannelTokenSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaChannelToken.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstRotateSchema validates a message coming from the client against Rotate schema.
+func ValidateAgainstRotateSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaRotate.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstIssuedTokenSchema validates a message coming from the client against IssuedToken schema.
+func ValidateAgainstIssuedTokenSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaIssuedToken.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstChannelStatsSchema validates a message coming from the client against ChannelStats schema.
+func ValidateAgainstChannelStatsSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaChannelStats.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstUsageSchema validates a message coming from the client against Usage schema.
+func ValidateAgainstUsageSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaUsage.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstRejectionsSchema validates a message coming from the client against Rejections schema.
+func ValidateAgainstRejectionsSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaRejections.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstRateLimitSchema validates a message coming from the client against RateLimit schema.
+func ValidateAgainstRateLimitSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaRateLimit.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstQuotaSchema validates a message coming from the client against Quota schema.
+func ValidateAgainstQuotaSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaQuota.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstLockoutSchema validates a message coming from the client against Lockout schema.
+func ValidateAgainstLockoutSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaLockout.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstLockoutsSchema validates a message coming from the client against Lockouts schema.
+func ValidateAgainstLockoutsSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaLockouts.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
+// ValidateAgainstUnlockSchema validates a message coming from the client against Unlock schema.
+func ValidateAgainstUnlockSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaUnlock.Validate(loader)
 	if err != nil {
 		return err
 	}
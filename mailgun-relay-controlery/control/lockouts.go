@@ -0,0 +1,47 @@
+package control
+
+import (
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// currentLockouts computes the response for a request of the lockouts in
+// effect at the given time.
+//
+// currentLockouts requires:
+// * db != nil
+//
+// currentLockouts ensures:
+// * err != nil || response.Lockouts != nil
+func currentLockouts(now time.Time, db database.Store) (
+	response Lockouts, err error) {
+	// Pre-condition
+	if !(db != nil) {
+		panic("Violated: db != nil")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || response.Lockouts != nil) {
+			panic("Violated: err != nil || response.Lockouts != nil")
+		}
+	}()
+
+	var lockouts []*protoed.Lockout
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		lockouts, txnErr = txn.Lockouts(now)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	response = Lockouts{Lockouts: []Lockout{}}
+	for _, lockout := range lockouts {
+		response.Lockouts = append(response.Lockouts, LockoutToJSON(lockout))
+	}
+
+	return
+}
@@ -1,8 +1,10 @@
 package control
 
 import (
+	"encoding/base64"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/Parquery/mailgun-relayery/database"
 	"github.com/Parquery/mailgun-relayery/dbc"
@@ -12,6 +14,8 @@ import (
 // paginateChannels computes the response for a channels page listing
 // request. If the page is out of bounds, the response contains no channels.
 //
+// Only the channels matching the filter are listed and counted.
+//
 // paginateChannels requires:
 // * db != nil
 // * page != 0
@@ -23,8 +27,8 @@ import (
 // * err != nil || channelsPage.PageCount >= 0
 // * err != nil || len(channelsPage.Channels) <= int(perPage)
 // * err != nil || !dbc.InTest || mustCount(db) != 0 || (page == 1 && len(channelsPage.Channels) == 0 && channelsPage.PageCount == 0)
-func paginateChannels(page uint, perPage uint,
-	db *database.Env) (channelsPage ChannelsPage, err error) {
+func paginateChannels(page uint, perPage uint, filter database.ChannelFilter,
+	db database.Store) (channelsPage ChannelsPage, err error) {
 	// Pre-conditions
 	switch {
 	case !(db != nil):
@@ -59,6 +63,27 @@ func paginateChannels(page uint, perPage uint,
 	pageCount := int32(0)
 	channelCount := uint64(0)
 	dbErr := db.View(func(txn *database.Txn) (txnErr error) {
+		if !filter.IsEmpty() {
+			// The matching channels can only be counted by a pass over them.
+			skip := uint64((page - 1) * perPage)
+			txnErr = txn.IterateChannels("", filter,
+				func(protoChan *protoed.Channel) (bool, error) {
+					if channelCount >= skip &&
+						channelCount < skip+uint64(perPage) {
+						channel, listErr := listedChannel(txn, protoChan)
+						if listErr != nil {
+							return true, listErr
+						}
+						channels = append(channels, channel)
+					}
+					channelCount++
+					return false, nil
+				})
+			pageCount = int32(math.Ceil(
+				float64(channelCount) / float64(perPage)))
+			return
+		}
+
 		channelCount, txnErr = txn.CountChannels()
 		if txnErr != nil {
 			return
@@ -72,9 +97,123 @@ func paginateChannels(page uint, perPage uint,
 				return
 			}
 			for _, protoChan := range channelsProto {
-				jsonChannel := ProtoToJSON(protoChan)
-				channels = append(channels, *jsonChannel)
+				var jsonChannel Channel
+				jsonChannel, txnErr = listedChannel(txn, protoChan)
+				if txnErr != nil {
+					return
+				}
+				channels = append(channels, jsonChannel)
+			}
+		}
+
+		return
+	})
+	if dbErr != nil {
+		err = fmt.Errorf("error while retrieving the channels in the "+
+			"database: %s", dbErr.Error())
+		return
+	}
+
+	var nextCursor *string
+	if len(channels) > 0 && uint64(page*perPage) < channelCount {
+		cursor := EncodeCursor(channels[len(channels)-1].Descriptor)
+		nextCursor = &cursor
+	}
+
+	channelsPage = ChannelsPage{PageCount: pageCount, PerPage: int32(perPage),
+		Page: int32(page), Channels: channels, NextCursor: nextCursor}
+	return
+}
+
+// EncodeCursor encodes the descriptor of the last listed channel as
+// an opaque cursor.
+func EncodeCursor(descriptor Descriptor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(descriptor))
+}
+
+// DecodeCursor decodes the descriptor of the last listed channel from
+// the opaque cursor.
+func DecodeCursor(cursor string) (descriptor Descriptor, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		err = fmt.Errorf("failed to decode the cursor %#v: %s",
+			cursor, err.Error())
+		return
+	}
+
+	descriptor = Descriptor(decoded)
+	return
+}
+
+// channelsAfter computes the response for a channels listing request
+// which continues after the given descriptor.
+//
+// Only the channels matching the filter are listed and counted.
+//
+// channelsAfter requires:
+// * db != nil
+// * perPage != 0
+//
+// channelsAfter ensures:
+// * err != nil || channelsPage.Page == 0
+// * err != nil || channelsPage.PerPage == int32(perPage)
+// * err != nil || channelsPage.PageCount >= 0
+// * err != nil || len(channelsPage.Channels) <= int(perPage)
+// * err != nil || channelsPage.NextCursor == nil || len(channelsPage.Channels) == int(perPage)
+func channelsAfter(after Descriptor, perPage uint, filter database.ChannelFilter,
+	db database.Store) (channelsPage ChannelsPage, err error) {
+	// Pre-conditions
+	switch {
+	case !(db != nil):
+		panic("Violated: db != nil")
+	case !(perPage != 0):
+		panic("Violated: perPage != 0")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || channelsPage.Page == 0):
+			panic("Violated: err != nil || channelsPage.Page == 0")
+		case !(err != nil || channelsPage.PerPage == int32(perPage)):
+			panic("Violated: err != nil || channelsPage.PerPage == int32(perPage)")
+		case !(err != nil || channelsPage.PageCount >= 0):
+			panic("Violated: err != nil || channelsPage.PageCount >= 0")
+		case !(err != nil || len(channelsPage.Channels) <= int(perPage)):
+			panic("Violated: err != nil || len(channelsPage.Channels) <= int(perPage)")
+		case !(err != nil || channelsPage.NextCursor == nil || len(channelsPage.Channels) == int(perPage)):
+			panic("Violated: err != nil || channelsPage.NextCursor == nil || len(channelsPage.Channels) == int(perPage)")
+		default:
+			// Pass
+		}
+	}()
+
+	channels := []Channel{}
+	pageCount := int32(0)
+	more := false
+	dbErr := db.View(func(txn *database.Txn) (txnErr error) {
+		var channelCount uint64
+		channelCount, txnErr = txn.CountMatchingChannels(filter)
+		if txnErr != nil {
+			return
+		}
+		pageCount = int32(math.Ceil(float64(channelCount) / float64(perPage)))
+
+		var channelsProto []*protoed.Channel
+		channelsProto, more, txnErr = txn.ChannelsAfter(string(after),
+			perPage, filter)
+		if txnErr != nil {
+			return
+		}
+		for _, protoChan := range channelsProto {
+			var jsonChannel Channel
+			jsonChannel, txnErr = listedChannel(txn, protoChan)
+			if txnErr != nil {
+				return
 			}
+			channels = append(channels, jsonChannel)
 		}
 
 		return
@@ -85,14 +224,44 @@ func paginateChannels(page uint, perPage uint,
 		return
 	}
 
+	var nextCursor *string
+	if more {
+		cursor := EncodeCursor(channels[len(channels)-1].Descriptor)
+		nextCursor = &cursor
+	}
+
 	channelsPage = ChannelsPage{PageCount: pageCount, PerPage: int32(perPage),
-		Page: int32(page), Channels: channels}
+		Page: 0, Channels: channels, NextCursor: nextCursor}
+	return
+}
+
+// listedChannel converts the channel to its JSON representation together
+// with the last uses of its named tokens.
+func listedChannel(txn *database.Txn, protoChan *protoed.Channel) (
+	channel Channel, err error) {
+	channel = *ProtoToJSON(protoChan)
+	if len(channel.Tokens) == 0 {
+		return
+	}
+
+	uses, err := txn.TokenUses(protoChan.Descriptor_)
+	if err != nil {
+		return
+	}
+
+	for i := range channel.Tokens {
+		if nanos, ok := uses[channel.Tokens[i].Name]; ok {
+			lastUsed := time.Unix(0, nanos).UTC().Format(time.RFC3339Nano)
+			channel.Tokens[i].LastUsed = &lastUsed
+		}
+	}
+
 	return
 }
 
 // mustCount returns the Count of entries in the database. In case of error,
 // it panics.
-func mustCount(db *database.Env) uint64 {
+func mustCount(db database.Store) uint64 {
 	var count uint64
 	err := db.View(func(txn *database.Txn) (txnerr error) {
 		count, txnerr = txn.CountChannels()
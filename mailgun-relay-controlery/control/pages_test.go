@@ -4,11 +4,9 @@ import (
 	"strconv"
 	"testing"
 
-	"fmt"
 	"github.com/Parquery/mailgun-relayery/database"
 	"github.com/Parquery/mailgun-relayery/protoed"
-	"io/ioutil"
-	"os"
+	"reflect"
 )
 
 func TestPaginateChannels_EmptyDatabase(t *testing.T) {
@@ -16,12 +14,6 @@ func TestPaginateChannels_EmptyDatabase(t *testing.T) {
 	if err != nil {
 		t.Fatal(err.Error())
 	}
-	defer func() {
-		err = os.RemoveAll(d.Path)
-		if err != nil {
-			t.Fatal(err.Error())
-		}
-	}()
 	defer func() {
 		err = d.Close()
 		if err != nil {
@@ -29,7 +21,7 @@ func TestPaginateChannels_EmptyDatabase(t *testing.T) {
 		}
 	}()
 
-	channelsPage, err := paginateChannels(1, 100, d)
+	channelsPage, err := paginateChannels(1, 100, database.ChannelFilter{}, d)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
@@ -55,12 +47,6 @@ func TestPaginateChannels(t *testing.T) {
 	if err != nil {
 		t.Fatal(err.Error())
 	}
-	defer func() {
-		err = os.RemoveAll(d.Path)
-		if err != nil {
-			t.Fatal(err.Error())
-		}
-	}()
 	defer func() {
 		err = d.Close()
 		if err != nil {
@@ -69,7 +55,7 @@ func TestPaginateChannels(t *testing.T) {
 	}()
 
 	// typical usecase: first page, 100 items per page
-	channelsPage, err := paginateChannels(1, 100, d)
+	channelsPage, err := paginateChannels(1, 100, database.ChannelFilter{}, d)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
@@ -88,7 +74,10 @@ func TestPaginateChannels(t *testing.T) {
 	}
 	channelsPageMap := make(map[string]*protoed.Channel)
 	for _, chann := range channelsPage.Channels {
-		protoChan := JSONToProto(&chann)
+		protoChan, convErr := JSONToProto(&chann)
+		if convErr != nil {
+			t.Fatal(convErr.Error())
+		}
 		channelsPageMap[protoChan.Descriptor_] = protoChan
 	}
 	if len(channelsPageMap) != len(expectedMap) {
@@ -101,7 +90,7 @@ func TestPaginateChannels(t *testing.T) {
 	}
 
 	// custom usecase: page 10, 1 item per page
-	channelsPage, err = paginateChannels(10, 1, d)
+	channelsPage, err = paginateChannels(10, 1, database.ChannelFilter{}, d)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
@@ -122,7 +111,10 @@ func TestPaginateChannels(t *testing.T) {
 	channelsPageMap = make(map[string]*protoed.Channel)
 
 	for _, chann := range channelsPage.Channels {
-		protoChan := JSONToProto(&chann)
+		protoChan, convErr := JSONToProto(&chann)
+		if convErr != nil {
+			t.Fatal(convErr.Error())
+		}
 		channelsPageMap[protoChan.Descriptor_] = protoChan
 	}
 	if len(channelsPageMap) != len(expectedMap) {
@@ -135,7 +127,7 @@ func TestPaginateChannels(t *testing.T) {
 	}
 
 	// another custom usecase: page 4, 5 items per page
-	channelsPage, err = paginateChannels(4, 5, d)
+	channelsPage, err = paginateChannels(4, 5, database.ChannelFilter{}, d)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
@@ -160,7 +152,10 @@ func TestPaginateChannels(t *testing.T) {
 	channelsPageMap = make(map[string]*protoed.Channel)
 
 	for _, chann := range channelsPage.Channels {
-		protoChan := JSONToProto(&chann)
+		protoChan, convErr := JSONToProto(&chann)
+		if convErr != nil {
+			t.Fatal(convErr.Error())
+		}
 		channelsPageMap[protoChan.Descriptor_] = protoChan
 	}
 	if len(channelsPageMap) != len(expectedMap) {
@@ -171,62 +166,207 @@ func TestPaginateChannels(t *testing.T) {
 			database.CompareChannels(expected, channelsPageMap[desc], t)
 		}
 	}
+
+	if channelsPage.NextCursor != nil {
+		t.Errorf("Expected no next cursor on the last page, got %#v",
+			*channelsPage.NextCursor)
+	}
 }
 
-// emptyDatabase creates an empty database.
-func emptyDatabase() (e *database.Env, err error) {
-	tmpdir, err := ioutil.TempDir("", "")
+func TestChannelsAfter(t *testing.T) {
+	d, populatedChannels, err := populatedDatabase()
 	if err != nil {
-		return
+		t.Fatal(err.Error())
 	}
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
 
-	err = database.Initialize(database.ControlAccess, tmpdir)
+	// the first page listed by index continues with the cursor
+	channelsPage, err := paginateChannels(1, 7, database.ChannelFilter{}, d)
 	if err != nil {
-		removeErr := os.RemoveAll(tmpdir)
-		if removeErr != nil {
-			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+		t.Fatalf(err.Error())
+	}
+	if channelsPage.NextCursor == nil {
+		t.Fatalf("Expected a next cursor on the first page")
+	}
+
+	channelsPageMap := make(map[string]*protoed.Channel)
+	pageCount := 1
+	for {
+		for _, chann := range channelsPage.Channels {
+			protoChan, convErr := JSONToProto(&chann)
+			if convErr != nil {
+				t.Fatal(convErr.Error())
+			}
+			if _, ok := channelsPageMap[protoChan.Descriptor_]; ok {
+				t.Fatalf("Expected the channel %s to be listed only once",
+					protoChan.Descriptor_)
+			}
+			channelsPageMap[protoChan.Descriptor_] = protoChan
+		}
+
+		if channelsPage.NextCursor == nil {
+			break
+		}
+
+		var after Descriptor
+		after, err = DecodeCursor(*channelsPage.NextCursor)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		channelsPage, err = channelsAfter(after, 7, database.ChannelFilter{}, d)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		pageCount++
+
+		if channelsPage.Page != 0 {
+			t.Errorf("Expected page equals %d, got %d",
+				0, channelsPage.Page)
+		}
+		if channelsPage.PageCount != 3 {
+			t.Errorf("Expected page count equals %d, got %d",
+				3, channelsPage.PageCount)
 		}
-		return
 	}
 
-	e, err = database.NewEnv(database.ControlAccess, tmpdir)
-	if err != nil {
-		removeErr := os.RemoveAll(tmpdir)
-		if removeErr != nil {
-			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+	if pageCount != 3 {
+		t.Errorf("Expected %d pages, got %d", 3, pageCount)
+	}
+
+	if len(channelsPageMap) != len(populatedChannels) {
+		t.Errorf("Expected %d items, got %d",
+			len(populatedChannels), len(channelsPageMap))
+	} else {
+		for desc, expected := range populatedChannels {
+			database.CompareChannels(expected, channelsPageMap[desc], t)
 		}
-		return
 	}
 
-	return
+	// the empty cursor lists from the beginning
+	channelsPage, err = channelsAfter("", 100, database.ChannelFilter{}, d)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(channelsPage.Channels) != len(populatedChannels) {
+		t.Errorf("Expected %d items, got %d",
+			len(populatedChannels), len(channelsPage.Channels))
+	}
+	if channelsPage.NextCursor != nil {
+		t.Errorf("Expected no next cursor, got %#v",
+			*channelsPage.NextCursor)
+	}
 }
 
-// populatedDatabase creates an empty database and populates it of dumnmy data.
-func populatedDatabase() (e *database.Env,
-	channelsMap map[string]*protoed.Channel, err error) {
-	tmpdir, err := ioutil.TempDir("", "")
+func TestPaginateChannels_Filter(t *testing.T) {
+	d, _, err := populatedDatabase()
 	if err != nil {
-		return
+		t.Fatal(err.Error())
 	}
+	defer func() {
+		err = d.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
 
-	err = database.Initialize(database.ControlAccess, tmpdir)
-	if err != nil {
-		removeErr := os.RemoveAll(tmpdir)
-		if removeErr != nil {
-			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
+	descriptorsOf := func(channelsPage ChannelsPage) []string {
+		descriptors := []string{}
+		for _, chann := range channelsPage.Channels {
+			descriptors = append(descriptors, string(chann.Descriptor))
 		}
-		return
+		return descriptors
 	}
 
-	e, err = database.NewEnv(database.ControlAccess, tmpdir)
+	filter := database.ChannelFilter{Prefix: "channel-1"}
+
+	channelsPage, err := paginateChannels(2, 3, filter, d)
 	if err != nil {
-		removeErr := os.RemoveAll(tmpdir)
-		if removeErr != nil {
-			err = fmt.Errorf("%s; %s", err.Error(), removeErr.Error())
-		}
-		return
+		t.Fatalf(err.Error())
+	}
+	expected := []string{"channel-13", "channel-14", "channel-15"}
+	if !reflect.DeepEqual(expected, descriptorsOf(channelsPage)) {
+		t.Errorf("Expected %v, got %v", expected, descriptorsOf(channelsPage))
+	}
+	if channelsPage.PageCount != 4 {
+		t.Errorf("Expected page count equals %d, got %d",
+			4, channelsPage.PageCount)
+	}
+	if channelsPage.NextCursor == nil {
+		t.Fatalf("Expected a next cursor")
+	}
+
+	channelsPage, err = channelsAfter("channel-17", 3, filter, d)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected = []string{"channel-18", "channel-19"}
+	if !reflect.DeepEqual(expected, descriptorsOf(channelsPage)) {
+		t.Errorf("Expected %v, got %v", expected, descriptorsOf(channelsPage))
+	}
+	if channelsPage.PageCount != 4 {
+		t.Errorf("Expected page count equals %d, got %d",
+			4, channelsPage.PageCount)
+	}
+	if channelsPage.NextCursor != nil {
+		t.Errorf("Expected no next cursor, got %#v",
+			*channelsPage.NextCursor)
+	}
+
+	filter = database.ChannelFilter{Recipient: "CPE.Bach@composers.com"}
+	channelsPage, err = paginateChannels(1, 100, filter, d)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(channelsPage.Channels) != 20 {
+		t.Errorf("Expected %d items, got %d", 20, len(channelsPage.Channels))
 	}
 
+	filter = database.ChannelFilter{Sender: "cpe.bach@composers.com"}
+	channelsPage, err = paginateChannels(1, 100, filter, d)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(channelsPage.Channels) != 0 || channelsPage.PageCount != 0 {
+		t.Errorf("Expected no items, got %d on %d pages",
+			len(channelsPage.Channels), channelsPage.PageCount)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	descriptor := Descriptor("client-1/pipeline-3")
+
+	got, err := DecodeCursor(EncodeCursor(descriptor))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if got != descriptor {
+		t.Errorf("Expected %#v, got %#v", descriptor, got)
+	}
+
+	_, err = DecodeCursor("not a cursor!")
+	if err == nil {
+		t.Errorf("Expected an error on an invalid cursor")
+	}
+}
+
+// emptyDatabase creates an empty in-memory database.
+func emptyDatabase() (e *database.MemStore, err error) {
+	e = database.NewMemStore(database.ControlAccess)
+	return
+}
+
+// populatedDatabase creates an empty in-memory database and populates it
+// of dumnmy data.
+func populatedDatabase() (e *database.MemStore,
+	channelsMap map[string]*protoed.Channel, err error) {
+	e = database.NewMemStore(database.ControlAccess)
+
 	channelsMap = make(map[string]*protoed.Channel)
 	for i := 1; i <= 20; i++ {
 		descriptor := "channel-"
@@ -243,8 +383,8 @@ func populatedDatabase() (e *database.Env,
 		bcc := []*protoed.Entity{{Name: "Franz Schubert",
 			Email: "franz.schubert@composers.com"}}
 		channel := &protoed.Channel{Descriptor_: descriptor,
-			Token:  "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU",
-			Sender: &sender, Recipients: recipients, Cc: cc,
+			TokenHash: database.DummyTokenHash(),
+			Sender:    &sender, Recipients: recipients, Cc: cc,
 			Bcc: bcc, MinPeriod: 0.0001, MaxSize: 10000000}
 		channelsMap[descriptor] = channel
 
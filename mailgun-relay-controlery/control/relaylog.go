@@ -0,0 +1,63 @@
+package control
+
+import (
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// relayLog computes the response for a relay log request. Zero since or
+// until leave the time range open on that side.
+//
+// relayLog requires:
+// * db != nil
+// * limit != 0
+// * since.IsZero() || until.IsZero() || !until.Before(since)
+//
+// relayLog ensures:
+// * err != nil || len(response.Records) <= int(limit)
+// * err != nil || !response.More || len(response.Records) == int(limit)
+func relayLog(descriptor string, since time.Time, until time.Time,
+	limit uint, db database.Store) (response RelayLog, err error) {
+	// Pre-conditions
+	switch {
+	case !(db != nil):
+		panic("Violated: db != nil")
+	case !(limit != 0):
+		panic("Violated: limit != 0")
+	case !(since.IsZero() || until.IsZero() || !until.Before(since)):
+		panic("Violated: since.IsZero() || until.IsZero() || !until.Before(since)")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || len(response.Records) <= int(limit)):
+			panic("Violated: err != nil || len(response.Records) <= int(limit)")
+		case !(err != nil || !response.More || len(response.Records) == int(limit)):
+			panic("Violated: err != nil || !response.More || len(response.Records) == int(limit)")
+		default:
+			// Pass
+		}
+	}()
+
+	var records []*protoed.RelayRecord
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		records, response.More, txnErr = txn.RelayRecords(descriptor, since, until,
+			limit)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	response.Records = []RelayRecord{}
+	for _, record := range records {
+		response.Records = append(response.Records, RelayRecordToJSON(record))
+	}
+
+	return
+}
@@ -0,0 +1,94 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestRelayLog(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	start := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	// Only the Relay server records the relay attempts.
+	db.Access = database.RelayAccess
+	err = db.Update(func(txn *database.Txn) (txnErr error) {
+		for i := 0; i < 3; i++ {
+			txnErr = txn.PutRelayRecord(&protoed.RelayRecord{
+				Descriptor_: "client-1/pipeline-3",
+				Time:        start.Add(time.Duration(i) * time.Minute).UnixNano(),
+				Subject:     "an alert", Size: 42,
+				Outcome: protoed.RelayRecord_RELAYED, Status: 200,
+				MessageId: "<20181001143700.1.1@marketing.composers.com>"})
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	db.Access = database.ControlAccess
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type testcase struct {
+		since time.Time
+		until time.Time
+		limit uint
+		times []string
+		more  bool
+	}
+
+	testcases := []testcase{
+		{limit: 10, times: []string{"2018-10-01T14:37:00Z",
+			"2018-10-01T14:38:00Z", "2018-10-01T14:39:00Z"}},
+		{since: start.Add(time.Minute), limit: 10,
+			times: []string{"2018-10-01T14:38:00Z", "2018-10-01T14:39:00Z"}},
+		{since: start, until: start.Add(time.Minute), limit: 10,
+			times: []string{"2018-10-01T14:37:00Z"}},
+		{limit: 1, times: []string{"2018-10-01T14:37:00Z"}, more: true},
+	}
+
+	for i, tc := range testcases {
+		response, err := relayLog("client-1/pipeline-3", tc.since, tc.until,
+			tc.limit, db)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(response.Records) != len(tc.times) || response.More != tc.more {
+			t.Fatalf("test case %d: expected %d records (more: %v), "+
+				"got %d (more: %v)", i, len(tc.times), tc.more,
+				len(response.Records), response.More)
+		}
+
+		for j, record := range response.Records {
+			if record.Time != tc.times[j] {
+				t.Errorf("test case %d: expected the time %s of the "+
+					"record %d, got %s", i, tc.times[j], j, record.Time)
+			}
+		}
+	}
+
+	response, err := relayLog("client-2", time.Time{}, time.Time{}, 10, db)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if response.Records == nil || len(response.Records) != 0 {
+		t.Errorf("expected an empty list of records, got %#v",
+			response.Records)
+	}
+}
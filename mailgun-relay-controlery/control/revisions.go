@@ -0,0 +1,146 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// channelFields lists the JSON names of the fields of a channel in
+// the order of their declaration.
+var channelFields = func() (fields []string) {
+	channelType := reflect.TypeOf(Channel{})
+	for i := 0; i < channelType.NumField(); i++ {
+		tag := channelType.Field(i).Tag.Get("json")
+		fields = append(fields, strings.Split(tag, ",")[0])
+	}
+	return
+}()
+
+// DiffChannels lists the fields which differ between the two channels
+// together with their JSON representations.
+//
+// DiffChannels requires:
+// * before != nil
+// * after != nil
+func DiffChannels(before *Channel, after *Channel) (changes []FieldChange,
+	err error) {
+	// Pre-conditions
+	switch {
+	case !(before != nil):
+		panic("Violated: before != nil")
+	case !(after != nil):
+		panic("Violated: after != nil")
+	default:
+		// Pass
+	}
+
+	var beforeFields, afterFields map[string]json.RawMessage
+	for _, pair := range []struct {
+		channel *Channel
+		fields  *map[string]json.RawMessage
+	}{{before, &beforeFields}, {after, &afterFields}} {
+		var encoded []byte
+		encoded, err = json.Marshal(pair.channel)
+		if err != nil {
+			err = fmt.Errorf("failed to encode the channel: %s", err.Error())
+			return
+		}
+
+		err = json.Unmarshal(encoded, pair.fields)
+		if err != nil {
+			err = fmt.Errorf("failed to decode the fields of the channel: %s",
+				err.Error())
+			return
+		}
+	}
+
+	optional := func(value json.RawMessage) *string {
+		if value == nil {
+			return nil
+		}
+		s := string(value)
+		return &s
+	}
+
+	changes = []FieldChange{}
+	for _, field := range channelFields {
+		beforeValue, afterValue := beforeFields[field], afterFields[field]
+		if bytes.Equal(beforeValue, afterValue) {
+			continue
+		}
+
+		changes = append(changes, FieldChange{Field: field,
+			Before: optional(beforeValue), After: optional(afterValue)})
+	}
+
+	return
+}
+
+// channelRevisions computes the response for a revisions listing request.
+//
+// channelRevisions requires:
+// * db != nil
+//
+// channelRevisions ensures:
+// * err != nil || response.Revisions != nil
+func channelRevisions(descriptor string, db database.Store) (
+	response ChannelRevisions, err error) {
+	// Pre-condition
+	if !(db != nil) {
+		panic("Violated: db != nil")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || response.Revisions != nil) {
+			panic("Violated: err != nil || response.Revisions != nil")
+		}
+	}()
+
+	var revisions []*protoed.ChannelRevision
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		revisions, txnErr = txn.Revisions(descriptor)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	response.Revisions = []ChannelRevision{}
+	var previous *Channel
+	for _, revision := range revisions {
+		channel := ProtoToJSON(revision.Channel)
+
+		changes := []FieldChange{}
+		if previous != nil {
+			changes, err = DiffChannels(previous, channel)
+			if err != nil {
+				return
+			}
+		}
+
+		var actor *string
+		if revision.Actor != "" {
+			actor = &revision.Actor
+		}
+
+		response.Revisions = append(response.Revisions, ChannelRevision{
+			Revision: int64(revision.Revision),
+			Time: time.Unix(0, revision.Time).UTC().Format(
+				time.RFC3339Nano),
+			Actor:   actor,
+			Channel: *channel,
+			Changes: changes})
+
+		previous = channel
+	}
+
+	return
+}
@@ -0,0 +1,292 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+func TestDiffChannels(t *testing.T) {
+	name := "CPE Bach"
+	before := &Channel{Descriptor: "client-1",
+		Sender:     Entity{Email: "johann.bach@composers.com"},
+		Recipients: []Entity{{Email: "cpe.bach@composers.com"}},
+		Domain:     "composers.com", MinPeriod: 1, MaxSize: 1000}
+	after := &Channel{Descriptor: "client-1",
+		Sender:     Entity{Email: "johann.bach@composers.com"},
+		Recipients: []Entity{{Email: "cpe.bach@composers.com", Name: &name}},
+		Cc:         []Entity{{Email: "wf.bach@composers.com"}},
+		Domain:     "composers.com", MinPeriod: 2, MaxSize: 1000}
+
+	changes, err := DiffChannels(before, after)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var fields []string
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+
+	expected := []string{"recipients", "cc", "min_period"}
+	if !reflect.DeepEqual(expected, fields) {
+		t.Fatalf("expected the changed fields %v, got %v", expected, fields)
+	}
+
+	if changes[1].Before != nil || changes[1].After == nil ||
+		*changes[1].After != `[{"email":"wf.bach@composers.com"}]` {
+		t.Errorf("expected cc to be added, got %#v", changes[1])
+	}
+
+	changes, err = DiffChannels(before, before)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if changes == nil || len(changes) != 0 {
+		t.Errorf("expected no changes between equal channels, got %v",
+			changes)
+	}
+}
+
+func TestHandlerImpl_RollbackChannel(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	h := newTestHandler(db)
+	h.Revisions = 3
+
+	descriptor := "client-1/pipeline-3"
+	channel := `{"descriptor": "%s", "token": "secret",
+		"sender": {"email": "johann.bach@composers.com"},
+		"recipients": [%s],
+		"domain": "composers.com", "min_period": %d, "max_size": 1000}`
+
+	for i, recipients := range []string{
+		`{"email": "cpe.bach@composers.com"}`,
+		`{"email": "cpe.bach@composers.com"}, {"email": "jc.bach@composers.com"}`,
+		`{"email": "jc.bach@composers.com"}`,
+		`{"email": "wf.bach@composers.com"}`,
+	} {
+		w := serve(h, "PUT", "/api/channel",
+			fmt.Sprintf(channel, descriptor, recipients, i+1), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the status %d on put, got %d: %s",
+				http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	listRevisions := func() (revisions []ChannelRevision) {
+		w := serve(h, "GET", "/api/channel/"+descriptor+"/revisions", "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the status %d on listing, got %d: %s",
+				http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response ChannelRevisions
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return response.Revisions
+	}
+
+	revisions := listRevisions()
+	var numbers []int64
+	for _, revision := range revisions {
+		numbers = append(numbers, revision.Revision)
+	}
+
+	if !reflect.DeepEqual([]int64{2, 3, 4}, numbers) {
+		t.Fatalf("expected the revisions [2 3 4], got %v", numbers)
+	}
+
+	if len(revisions[0].Changes) != 0 {
+		t.Errorf("expected no changes for the oldest revision, got %v",
+			revisions[0].Changes)
+	}
+
+	var fields []string
+	for _, change := range revisions[1].Changes {
+		fields = append(fields, change.Field)
+	}
+
+	// The token is re-hashed with a new salt on each put.
+	expected := []string{"recipients", "min_period", "token_hash"}
+	if !reflect.DeepEqual(expected, fields) {
+		t.Errorf("expected the changed fields %v, got %v", expected, fields)
+	}
+
+	// Only the configuration is restored while the current token is kept.
+	restored := revisions[0].Channel
+	restored.TokenHash = revisions[len(revisions)-1].Channel.TokenHash
+
+	// The relay server records a relayed message.
+	db.Access = database.RelayAccess
+	err = db.Update(func(txn *database.Txn) error {
+		ts := database.TimestampFromTime(time.Now())
+		return txn.PutTimestamp(database.Descriptor(descriptor), &ts)
+	})
+	db.Access = database.ControlAccess
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w := serve(h, "POST", "/api/channel/"+descriptor+"/rollback",
+		`{"revision": 1}`, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected the status %d on a pruned revision, got %d",
+			http.StatusNotFound, w.Code)
+	}
+
+	w = serve(h, "POST", "/api/channel/"+descriptor+"/rollback",
+		`{"revision": 2}`, map[string]string{"If-Match": `"3"`})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected the status %d on a stale ETag, got %d: %s",
+			http.StatusPreconditionFailed, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("ETag"); got != `"4"` {
+		t.Errorf("expected the current ETag \"4\", got %#v", got)
+	}
+
+	w = serve(h, "POST", "/api/channel/"+descriptor+"/rollback",
+		`{"revision": 2}`, map[string]string{"X-Actor": "ops",
+			"If-Match": `"4"`})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on rollback, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("ETag"); got != `"5"` {
+		t.Errorf("expected the ETag \"5\" of the rollback, got %#v", got)
+	}
+
+	revisions = listRevisions()
+	last := revisions[len(revisions)-1]
+	if last.Revision != 5 || last.Actor == nil || *last.Actor != "ops" {
+		t.Fatalf("expected the rollback as the revision 5 by ops, got %#v",
+			last)
+	}
+
+	if !reflect.DeepEqual(restored, last.Channel) {
+		t.Errorf("expected the channel of the revision 2, got %#v",
+			last.Channel)
+	}
+
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		ts, txnErr := txn.GetTimestamp(descriptor)
+		if txnErr != nil {
+			return
+		}
+
+		if ts != nil {
+			t.Errorf("expected the timestamp to be erased on a rollback " +
+				"which changes the min. period")
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w = serve(h, "GET", "/api/audit?descriptor="+descriptor, "", nil)
+	var audit AuditLog
+	err = json.Unmarshal(w.Body.Bytes(), &audit)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(audit.Records) != 5 ||
+		audit.Records[4].Operation != "rollback" {
+		t.Errorf("expected the rollback in the audit log, got %#v",
+			audit.Records)
+	}
+}
+
+func TestHandlerImpl_RollbackChannel_KeepsTokensAndState(t *testing.T) {
+	db, err := emptyDatabase()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		err = db.Close()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}()
+
+	h := newTestHandler(db)
+
+	descriptor := "client-1"
+	channel := `{"descriptor": "client-1", "token": "%s",
+		"sender": {"email": "johann.bach@composers.com"},
+		"recipients": [{"email": "%s"}],
+		"domain": "composers.com", "min_period": 1, "max_size": 1000}`
+
+	// The leaked token is rotated and the channel is disabled.
+	for _, tc := range []struct {
+		method string
+		target string
+		body   string
+	}{
+		{"PUT", "/api/channel", fmt.Sprintf(channel, "leaked-secret",
+			"cpe.bach@composers.com")},
+		{"PUT", "/api/channel", fmt.Sprintf(channel, "rotated-secret",
+			"jc.bach@composers.com")},
+		{"POST", "/api/channel/" + descriptor + "/disable",
+			`{"reason": "unpaid invoice"}`}} {
+		w := serve(h, tc.method, tc.target, tc.body, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected the status %d on %s %s, got %d: %s",
+				http.StatusOK, tc.method, tc.target, w.Code,
+				w.Body.String())
+		}
+	}
+
+	w := serve(h, "POST", "/api/channel/"+descriptor+"/rollback",
+		`{"revision": 1}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status %d on rollback, got %d: %s",
+			http.StatusOK, w.Code, w.Body.String())
+	}
+
+	err = db.View(func(txn *database.Txn) error {
+		got, err := txn.GetChannel(descriptor)
+		if err != nil {
+			return err
+		}
+
+		if len(got.Recipients) != 1 ||
+			got.Recipients[0].Email != "cpe.bach@composers.com" {
+			t.Errorf("expected the recipients of the revision 1, got %v",
+				got.Recipients)
+		}
+
+		if tokenhash.Matches(got, "leaked-secret") ||
+			!tokenhash.Matches(got, "rotated-secret") {
+			t.Errorf("expected the rotated token to be kept")
+		}
+
+		if got.Disabled == nil || got.Disabled.Reason != "unpaid invoice" {
+			t.Errorf("expected the channel to stay disabled, got %v",
+				got.Disabled)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
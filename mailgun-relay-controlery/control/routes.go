@@ -25,11 +25,71 @@ func SetupRouter(h Handler) *mux.Router {
 			WrapDeleteChannel(h, w, r)
 		}).Methods("delete")
 
+	r.HandleFunc(`/api/channel/{descriptor:.+}/revisions`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapListRevisions(h, w, r)
+		}).Methods("get")
+
+	r.HandleFunc(`/api/channel/{descriptor:.+}/rollback`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapRollbackChannel(h, w, r)
+		}).Methods("post")
+
 	r.HandleFunc(`/api/list_channels`,
 		func(w http.ResponseWriter, r *http.Request) {
 			WrapListChannels(h, w, r)
 		}).Methods("get")
 
+	r.HandleFunc(`/api/relay_log`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapGetRelayLog(h, w, r)
+		}).Methods("get")
+
+	r.HandleFunc(`/api/audit`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapGetAudit(h, w, r)
+		}).Methods("get")
+
+	r.HandleFunc(`/api/channel/{descriptor:.+}/disable`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapDisableChannel(h, w, r)
+		}).Methods("post")
+
+	r.HandleFunc(`/api/channel/{descriptor:.+}/enable`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapEnableChannel(h, w, r)
+		}).Methods("post")
+
+	r.HandleFunc(`/api/channel/{descriptor:.+}/rotate`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapRotateToken(h, w, r)
+		}).Methods("post")
+
+	r.HandleFunc(`/api/channel/{descriptor:.+}/tokens/{name}/revoke`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapRevokeToken(h, w, r)
+		}).Methods("post")
+
+	r.HandleFunc(`/api/channel/{descriptor:.+}/stats`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapGetChannelStats(h, w, r)
+		}).Methods("get")
+
+	r.HandleFunc(`/api/lockouts`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapGetLockouts(h, w, r)
+		}).Methods("get")
+
+	r.HandleFunc(`/api/unlock`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapUnlock(h, w, r)
+		}).Methods("post")
+
+	r.HandleFunc(`/api/channel/{descriptor:.+}`,
+		func(w http.ResponseWriter, r *http.Request) {
+			WrapGetChannel(h, w, r)
+		}).Methods("get")
+
 	return r
 }
 
@@ -40,9 +100,27 @@ func SetupRouter(h Handler) *mux.Router {
 //
 // If there is already a channel associated with the descriptor, the old channel is overwritten with the new one.
 //
-// In order to enforce the min_period between messages, the Relay server keeps track of the time of the most
-// recently relayed message for each descriptor. If a channel is overwritten, the time of relay of the most
-// recent message is erased unless the new channel has the same min_period field as the old one.
+// At most one of the token and the token hash can be given; they define the default token of the channel.
+// A plain-text token is hashed with a random salt before it is stored; the token itself is never stored.
+// A new channel needs either the default token or named tokens. If the token, the token hash and the tokens
+// are omitted, an existing channel keeps its current tokens. If only the tokens are omitted, the named tokens
+// of an existing channel are kept.
+//
+// In order to enforce the rate limit or the min_period between messages, the Relay server keeps track of the
+// time of the most recently relayed message and of the messages left in the token bucket for each descriptor.
+// If a channel is overwritten, this state is erased unless the new channel has the same min_period and
+// rate_limit fields as the old one.
+//
+// If the disabled field is omitted, an existing channel keeps its current state so that an update does not
+// enable a disabled channel by accident. Use /api/channel/{descriptor}/enable to enable it again.
+//
+// The change is recorded in the audit log together with the X-Actor header identifying the client,
+// the remote address, the X-Forwarded-For and the User-Agent header.
+//
+// To avoid overwriting a concurrent change, pass the ETag of the channel as read from
+// /api/channel/{descriptor} in the If-Match header; the update is rejected with 412 if the channel
+// has changed since. Pass If-None-Match: * to create the channel only if it does not exist yet.
+// The ETag of the stored channel is returned in the response.
 func WrapPutChannel(h Handler, w http.ResponseWriter, r *http.Request) {
 	var aChannel Channel
 
@@ -82,6 +160,9 @@ func WrapPutChannel(h Handler, w http.ResponseWriter, r *http.Request) {
 //
 // Path description:
 // removes the channel associated with the descriptor.
+//
+// The removal is recorded in the audit log like the updates.
+// The If-Match and If-None-Match headers are honoured as on an update.
 func WrapDeleteChannel(h Handler, w http.ResponseWriter, r *http.Request) {
 	var aDescriptor Descriptor
 
@@ -121,9 +202,23 @@ func WrapDeleteChannel(h Handler, w http.ResponseWriter, r *http.Request) {
 //
 // Path description:
 // lists the available channels information.
+//
+// The channels are ordered by their descriptors. To walk through all the channels, pass the next_cursor of
+// each page as the after parameter of the following request. Unlike the page indices, the cursors do not
+// shift if the channels are inserted or removed between the requests.
+//
+// The listing can be narrowed down by the descriptor prefix, the domain, the sender, the recipient and
+// the state of the channels. The domains and the email addresses are compared case-insensitively. Only the channels satisfying all
+// the given criteria are listed and counted.
 func WrapListChannels(h Handler, w http.ResponseWriter, r *http.Request) {
 	var aPage *int32
 	var aPerPage *int32
+	var aAfter *string
+	var aPrefix *string
+	var aDomain *string
+	var aSender *string
+	var aRecipient *string
+	var aDisabled *bool
 
 	q := r.URL.Query()
 
@@ -151,10 +246,510 @@ func WrapListChannels(h Handler, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if _, ok := q["after"]; ok {
+		aAfterValue := q.Get("after")
+		aAfter = &aAfterValue
+	}
+
+	if _, ok := q["prefix"]; ok {
+		aPrefixValue := q.Get("prefix")
+		aPrefix = &aPrefixValue
+	}
+
+	if _, ok := q["domain"]; ok {
+		aDomainValue := q.Get("domain")
+		aDomain = &aDomainValue
+	}
+
+	if _, ok := q["sender"]; ok {
+		aSenderValue := q.Get("sender")
+		aSender = &aSenderValue
+	}
+
+	if _, ok := q["recipient"]; ok {
+		aRecipientValue := q.Get("recipient")
+		aRecipient = &aRecipientValue
+	}
+
+	if _, ok := q["disabled"]; ok {
+		{
+			parsed, err := strconv.ParseBool(q.Get("disabled"))
+			if err != nil {
+				http.Error(w, "Parameter 'disabled': "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			aDisabled = &parsed
+		}
+	}
+
 	h.ListChannels(w,
 		r,
 		aPage,
-		aPerPage)
+		aPerPage,
+		aAfter,
+		aPrefix,
+		aDomain,
+		aSender,
+		aRecipient,
+		aDisabled)
+}
+
+// WrapGetRelayLog wraps the path `/api/relay_log` with the method "get"
+//
+// Path description:
+// lists the attempts to relay a message through the channel, ordered by time.
+//
+// Every attempt through an existing channel is recorded, including the rejected and the failed ones.
+// The Relay server prunes the records according to its retention settings.
+func WrapGetRelayLog(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+	var aSince *string
+	var aUntil *string
+	var aLimit *int32
+
+	q := r.URL.Query()
+
+	if _, ok := q["descriptor"]; !ok {
+		http.Error(w, "Parameter 'descriptor' expected in query", http.StatusBadRequest)
+		return
+	}
+	aDescriptor = q.Get("descriptor")
+
+	if _, ok := q["since"]; ok {
+		aSinceValue := q.Get("since")
+		aSince = &aSinceValue
+	}
+
+	if _, ok := q["until"]; ok {
+		aUntilValue := q.Get("until")
+		aUntil = &aUntilValue
+	}
+
+	if _, ok := q["limit"]; ok {
+		{
+			parsed, err := strconv.ParseInt(q.Get("limit"), 10, 32)
+			if err != nil {
+				http.Error(w, "Parameter 'limit': "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			converted := int32(parsed)
+			aLimit = &converted
+		}
+	}
+
+	h.GetRelayLog(w,
+		r,
+		aDescriptor,
+		aSince,
+		aUntil,
+		aLimit)
+}
+
+// WrapGetAudit wraps the path `/api/audit` with the method "get"
+//
+// Path description:
+// lists the changes of the channels, ordered by time.
+//
+// Every update and removal of a channel is recorded with the channel before and after the change.
+// The tokens and their hashes are redacted; token_changed indicates whether a new token has been stored.
+// The audit log is append-only.
+//
+// To walk through all the changes, pass the next_cursor of each page as the after parameter of
+// the following request.
+func WrapGetAudit(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor *string
+	var aSince *string
+	var aUntil *string
+	var aLimit *int32
+	var aAfter *string
+
+	q := r.URL.Query()
+
+	if _, ok := q["descriptor"]; ok {
+		aDescriptorValue := q.Get("descriptor")
+		aDescriptor = &aDescriptorValue
+	}
+
+	if _, ok := q["since"]; ok {
+		aSinceValue := q.Get("since")
+		aSince = &aSinceValue
+	}
+
+	if _, ok := q["until"]; ok {
+		aUntilValue := q.Get("until")
+		aUntil = &aUntilValue
+	}
+
+	if _, ok := q["limit"]; ok {
+		{
+			parsed, err := strconv.ParseInt(q.Get("limit"), 10, 32)
+			if err != nil {
+				http.Error(w, "Parameter 'limit': "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			converted := int32(parsed)
+			aLimit = &converted
+		}
+	}
+
+	if _, ok := q["after"]; ok {
+		aAfterValue := q.Get("after")
+		aAfter = &aAfterValue
+	}
+
+	h.GetAudit(w,
+		r,
+		aDescriptor,
+		aSince,
+		aUntil,
+		aLimit,
+		aAfter)
+}
+
+// WrapListRevisions wraps the path `/api/channel/{descriptor}/revisions` with the method "get"
+//
+// Path description:
+// lists the kept revisions of the channel ordered by their numbers.
+//
+// Every update of a channel is stored as its next revision. Only the most recent revisions are kept
+// (see -channel_revisions of the control server). The revisions are kept after the channel has been removed
+// so that a removed channel can be rolled back as well.
+//
+// Each revision lists the fields which changed since the previous kept revision.
+// The descriptor may contain slashes.
+func WrapListRevisions(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	h.ListRevisions(w,
+		r,
+		aDescriptor)
+}
+
+// WrapRollbackChannel wraps the path `/api/channel/{descriptor}/rollback` with the method "post"
+//
+// Path description:
+// restores the channel to the given revision.
+//
+// Only the configuration of the channel is restored; the current tokens, the disabled state and
+// valid_until are kept so that a rollback neither brings back a revoked token nor enables a disabled
+// channel. The restored channel is stored as the next revision and the rollback is recorded in the audit log.
+// The time of the most recently relayed message is erased unless the restored channel has the same
+// min_period as the current one, just as on an update.
+//
+// The If-Match and If-None-Match headers are honoured as on an update and the ETag of the restored
+// channel is returned in the response.
+// The descriptor may contain slashes.
+func WrapRollbackChannel(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+	var aRollback Rollback
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	if r.Body == nil {
+		http.Error(w, "Parameter 'rollback' expected in body, but got no body", http.StatusBadRequest)
+		return
+	}
+	{
+		var err error
+		r.Body = http.MaxBytesReader(w, r.Body, 1024*1024)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Body unreadable: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = ValidateAgainstRollbackSchema(body)
+		if err != nil {
+			http.Error(w, "Failed to validate against schema: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = json.Unmarshal(body, &aRollback)
+		if err != nil {
+			http.Error(w, "Error JSON-decoding body parameter 'rollback': "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.RollbackChannel(w,
+		r,
+		aDescriptor,
+		aRollback)
+}
+
+// WrapDisableChannel wraps the path `/api/channel/{descriptor}/disable` with the method "post"
+//
+// Path description:
+// disables the channel without removing it.
+//
+// The Relay server refuses the messages of a disabled channel with 423 Locked, while the token,
+// the recipients and the time of the most recently relayed message are kept.
+// Disabling an already disabled channel leaves it untouched.
+//
+// The change is stored as the next revision and recorded in the audit log.
+// The descriptor may contain slashes.
+func WrapDisableChannel(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+	var aDisable Disable
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	if r.Body == nil {
+		http.Error(w, "Parameter 'disable' expected in body, but got no body", http.StatusBadRequest)
+		return
+	}
+	{
+		var err error
+		r.Body = http.MaxBytesReader(w, r.Body, 1024*1024)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Body unreadable: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = ValidateAgainstDisableSchema(body)
+		if err != nil {
+			http.Error(w, "Failed to validate against schema: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = json.Unmarshal(body, &aDisable)
+		if err != nil {
+			http.Error(w, "Error JSON-decoding body parameter 'disable': "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.DisableChannel(w,
+		r,
+		aDescriptor,
+		aDisable)
+}
+
+// WrapEnableChannel wraps the path `/api/channel/{descriptor}/enable` with the method "post"
+//
+// Path description:
+// enables the disabled channel again.
+//
+// Enabling an already enabled channel leaves it untouched.
+//
+// The change is stored as the next revision and recorded in the audit log.
+// The descriptor may contain slashes.
+func WrapEnableChannel(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	h.EnableChannel(w,
+		r,
+		aDescriptor)
+}
+
+// WrapRotateToken wraps the path `/api/channel/{descriptor}/rotate` with the method "post"
+//
+// Path description:
+// issues a new token generated by the server and lets all the other tokens of the channel expire
+// after the grace period.
+//
+// The new token is returned only in the response and never stored in plain text.
+// The tokens which already expired are removed.
+//
+// The change is stored as the next revision and recorded in the audit log.
+// The descriptor may contain slashes.
+func WrapRotateToken(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+	var aRotate Rotate
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	if r.Body == nil {
+		http.Error(w, "Parameter 'rotate' expected in body, but got no body", http.StatusBadRequest)
+		return
+	}
+	{
+		var err error
+		r.Body = http.MaxBytesReader(w, r.Body, 1024*1024)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Body unreadable: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = ValidateAgainstRotateSchema(body)
+		if err != nil {
+			http.Error(w, "Failed to validate against schema: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = json.Unmarshal(body, &aRotate)
+		if err != nil {
+			http.Error(w, "Error JSON-decoding body parameter 'rotate': "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.RotateToken(w,
+		r,
+		aDescriptor,
+		aRotate)
+}
+
+// WrapRevokeToken wraps the path `/api/channel/{descriptor}/tokens/{name}/revoke` with the method "post"
+//
+// Path description:
+// revokes the token of the channel immediately.
+//
+// The last live token of a channel can not be revoked; disable the channel instead.
+//
+// The change is stored as the next revision and recorded in the audit log.
+// The descriptor may contain slashes.
+func WrapRevokeToken(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+	var aName string
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	aName = vars["name"]
+
+	h.RevokeToken(w,
+		r,
+		aDescriptor,
+		aName)
+}
+
+// WrapGetChannelStats wraps the path `/api/channel/{descriptor}/stats` with the method "get"
+//
+// Path description:
+// lists the usage counters of the channel per hour or per day, ordered by time.
+//
+// The Relay server counts every attempt through an existing channel: the relayed messages and their size
+// as well as the rejected attempts by reason. The periods without any attempt are omitted and the counters
+// are kept after the channel has been removed.
+// The descriptor may contain slashes.
+func WrapGetChannelStats(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+	var aPeriod *string
+	var aSince *string
+	var aUntil *string
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	q := r.URL.Query()
+
+	if _, ok := q["period"]; ok {
+		aPeriodValue := q.Get("period")
+		aPeriod = &aPeriodValue
+	}
+
+	if _, ok := q["since"]; ok {
+		aSinceValue := q.Get("since")
+		aSince = &aSinceValue
+	}
+
+	if _, ok := q["until"]; ok {
+		aUntilValue := q.Get("until")
+		aUntil = &aUntilValue
+	}
+
+	h.GetChannelStats(w,
+		r,
+		aDescriptor,
+		aPeriod,
+		aSince,
+		aUntil)
+}
+
+// WrapGetLockouts wraps the path `/api/lockouts` with the method "get"
+//
+// Path description:
+// lists the remote IPs and the descriptors currently locked out after failed authentications.
+//
+// The Relay server counts the failed authentications per remote IP and per descriptor, regardless of
+// whether the channel exists. Once the failures reach the threshold, the subject is locked out for
+// a duration which doubles with each further failure up to a maximum.
+func WrapGetLockouts(h Handler, w http.ResponseWriter, r *http.Request) {
+	h.GetLockouts(w,
+		r)
+}
+
+// WrapUnlock wraps the path `/api/unlock` with the method "post"
+//
+// Path description:
+// lifts the lockout of a remote IP or of a descriptor and forgets its failed authentications.
+func WrapUnlock(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aUnlock Unlock
+
+	if r.Body == nil {
+		http.Error(w, "Parameter 'unlock' expected in body, but got no body", http.StatusBadRequest)
+		return
+	}
+	{
+		var err error
+		r.Body = http.MaxBytesReader(w, r.Body, 1024*1024)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Body unreadable: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = ValidateAgainstUnlockSchema(body)
+		if err != nil {
+			http.Error(w, "Failed to validate against schema: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		err = json.Unmarshal(body, &aUnlock)
+		if err != nil {
+			http.Error(w, "Error JSON-decoding body parameter 'unlock': "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.Unlock(w,
+		r,
+		aUnlock)
+}
+
+// WrapGetChannel wraps the path `/api/channel/{descriptor}` with the method "get"
+//
+// Path description:
+// serves the channel together with its revision as the ETag header.
+//
+// The revision is the number of the latest revision of the channel (see /api/channel/{descriptor}/revisions)
+// and increases with every change of the channel, also after the channel has been removed and created again.
+// Pass the ETag in the If-Match header of an update or a removal to detect concurrent changes.
+// If the If-None-Match header matches the ETag, the channel is not served and 304 is returned.
+// The descriptor may contain slashes.
+func WrapGetChannel(h Handler, w http.ResponseWriter, r *http.Request) {
+	var aDescriptor string
+
+	vars := mux.Vars(r)
+
+	aDescriptor = vars["descriptor"]
+
+	h.GetChannel(w,
+		r,
+		aDescriptor)
 }
 
 // Automatically generated file by swagger_to. DO NOT EDIT OR APPEND ANYTHING!
@@ -0,0 +1,58 @@
+package control
+
+import (
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// channelStats computes the response for a usage statistics request. Zero
+// since or until leave the time range open on that side.
+//
+// channelStats requires:
+// * db != nil
+// * period == database.Hourly || period == database.Daily
+// * since.IsZero() || until.IsZero() || !until.Before(since)
+//
+// channelStats ensures:
+// * err != nil || response.Usage != nil
+func channelStats(descriptor string, period database.UsagePeriod,
+	since time.Time, until time.Time, db database.Store) (
+	response ChannelStats, err error) {
+	// Pre-conditions
+	switch {
+	case !(db != nil):
+		panic("Violated: db != nil")
+	case !(period == database.Hourly || period == database.Daily):
+		panic("Violated: period == database.Hourly || period == database.Daily")
+	case !(since.IsZero() || until.IsZero() || !until.Before(since)):
+		panic("Violated: since.IsZero() || until.IsZero() || !until.Before(since)")
+	default:
+		// Pass
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || response.Usage != nil) {
+			panic("Violated: err != nil || response.Usage != nil")
+		}
+	}()
+
+	var counters []*protoed.UsageCounters
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		counters, txnErr = txn.Usage(descriptor, period, since, until)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	response = ChannelStats{Descriptor: descriptor, Period: string(period),
+		Usage: []Usage{}}
+	for _, c := range counters {
+		response.Usage = append(response.Usage, UsageToJSON(c))
+	}
+
+	return
+}
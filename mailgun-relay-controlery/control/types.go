@@ -19,7 +19,7 @@ type Entity struct {
 type Channel struct {
 	Descriptor Descriptor `json:"descriptor"`
 
-	Token Token `json:"token"`
+	Token *Token `json:"token,omitempty"`
 
 	Sender Entity `json:"sender"`
 
@@ -37,11 +37,43 @@ type Channel struct {
 
 	// indicates the maximum allowed size of the request, in bytes.
 	MaxSize int32 `json:"max_size"`
+
+	// is the salted hash of the default token in the PHC string format.
+	//
+	// Listings never include the token, only its hash.
+	TokenHash *string `json:"token_hash,omitempty"`
+
+	Disabled *Disabled `json:"disabled,omitempty"`
+
+	// is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.
+	//
+	// The Relay server refuses the messages of an expired channel. The Control server removes or disables
+	// the expired channels after a grace period.
+	ValidUntil *string `json:"valid_until,omitempty"`
+
+	// lists the named tokens of the channel in addition to the default one.
+	Tokens []ChannelToken `json:"tokens,omitempty"`
+
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+
+	Quota *Quota `json:"quota,omitempty"`
+
+	// specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.
+	//
+	// One of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued
+	// messages are stored by the Relay server and relayed in order once the rate limit allows.
+	OnThrottle *string `json:"on_throttle,omitempty"`
+
+	// is the maximum number of the messages of the channel waiting in the queue; absent or 0 for
+	// the default of 100 messages.
+	//
+	// The messages beyond it are refused with 429 Too Many Requests.
+	MaxQueueDepth *int32 `json:"max_queue_depth,omitempty"`
 }
 
 // ChannelsPage lists channels in a paginated manner.
 type ChannelsPage struct {
-	// specifies the index of the page.
+	// specifies the index of the page; 0 if the page has been listed after a cursor.
 	Page int32 `json:"page"`
 
 	// specifies the number of pages available.
@@ -52,4 +84,321 @@ type ChannelsPage struct {
 
 	// contains the channel data.
 	Channels []Channel `json:"channels"`
+
+	// is the opaque cursor to list the following page; absent if there are no more channels.
+	NextCursor *string `json:"next_cursor,omitempty"`
+}
+
+// RelayRecord records an attempt to relay a message through a channel.
+type RelayRecord struct {
+	Descriptor Descriptor `json:"descriptor"`
+
+	// is the time of the attempt in RFC 3339 format with nanoseconds.
+	Time string `json:"time"`
+
+	// is the subject of the message; empty if the message has not been parsed.
+	Subject string `json:"subject"`
+
+	// is the size of the request body, in bytes.
+	Size int64 `json:"size"`
+
+	// is the outcome of the attempt.
+	//
+	// One of relayed, forbidden, too_soon, too_large, invalid, failed, disabled, expired, quota_exceeded, throttled,
+	// locked_out, queued and queue_full.
+	Outcome string `json:"outcome"`
+
+	// is the HTTP status returned to the client; 0 if the message has been relayed from the queue.
+	Status int32 `json:"status"`
+
+	// is the MailGun message id; absent unless the message has been relayed.
+	MessageID *string `json:"message_id,omitempty"`
+
+	// is the name of the token which authenticated the attempt; absent if none did.
+	Token *string `json:"token,omitempty"`
+}
+
+// RelayLog lists the attempts to relay a message through a channel.
+type RelayLog struct {
+	// contains the attempts ordered by time.
+	Records []RelayRecord `json:"records"`
+
+	// indicates that further attempts exceeding the limit are available in the time range.
+	More bool `json:"more"`
+}
+
+// AuditRecord records a change of a channel.
+type AuditRecord struct {
+	Descriptor Descriptor `json:"descriptor"`
+
+	// is the time of the change in RFC 3339 format with nanoseconds.
+	Time string `json:"time"`
+
+	// is the kind of the change.
+	//
+	// One of put, delete, rollback, disable, enable, rotate and revoke.
+	Operation string `json:"operation"`
+
+	// is the identity of the client given in the X-Actor header; absent if not given.
+	Actor *string `json:"actor,omitempty"`
+
+	// is the network address of the client; empty if the change has been made locally.
+	RemoteAddr string `json:"remote_addr"`
+
+	// is the X-Forwarded-For header of the request; absent if not given.
+	ForwardedFor *string `json:"forwarded_for,omitempty"`
+
+	// is the User-Agent header of the request; absent if not given.
+	UserAgent *string `json:"user_agent,omitempty"`
+
+	Before *Channel `json:"before,omitempty"`
+
+	After *Channel `json:"after,omitempty"`
+
+	// indicates that a token of an existing channel has been added, replaced or removed.
+	TokenChanged bool `json:"token_changed"`
+}
+
+// AuditLog lists the changes of the channels.
+type AuditLog struct {
+	// contains the changes ordered by time.
+	Records []AuditRecord `json:"records"`
+
+	// is the opaque cursor to list the following page; absent if there are no more changes.
+	NextCursor *string `json:"next_cursor,omitempty"`
+}
+
+// FieldChange describes how a field of a channel changed between two revisions.
+type FieldChange struct {
+	// is the name of the field.
+	Field string `json:"field"`
+
+	// is the JSON representation of the field in the previous revision; absent if unset.
+	Before *string `json:"before,omitempty"`
+
+	// is the JSON representation of the field in this revision; absent if unset.
+	After *string `json:"after,omitempty"`
+}
+
+// ChannelRevision is a stored version of a channel.
+type ChannelRevision struct {
+	// is the number of the revision, counting from 1 for each channel.
+	Revision int64 `json:"revision"`
+
+	// is the time when the revision has been stored in RFC 3339 format with nanoseconds.
+	Time string `json:"time"`
+
+	// is the identity of the client which stored the revision; absent if not given.
+	Actor *string `json:"actor,omitempty"`
+
+	Channel Channel `json:"channel"`
+
+	// lists the fields changed since the previous kept revision; empty for the oldest kept revision.
+	Changes []FieldChange `json:"changes"`
+}
+
+// ChannelRevisions lists the kept revisions of a channel.
+type ChannelRevisions struct {
+	// contains the revisions ordered by their numbers.
+	Revisions []ChannelRevision `json:"revisions"`
+}
+
+// Rollback selects the revision to which a channel is restored.
+type Rollback struct {
+	// is the number of the revision.
+	Revision int64 `json:"revision"`
+}
+
+// Disabled indicates that the channel has been disabled and relays no messages.
+//
+// If omitted when a channel is stored, an existing channel keeps its current state.
+type Disabled struct {
+	// is the time when the channel has been disabled in RFC 3339 format with nanoseconds.
+	Time string `json:"time"`
+
+	// is the reason why the channel has been disabled; absent if not given.
+	Reason *string `json:"reason,omitempty"`
+}
+
+// Disable gives the reason why a channel is disabled.
+type Disable struct {
+	// is the reason reported to the clients of the channel; absent if not given.
+	Reason *string `json:"reason,omitempty"`
+}
+
+// ChannelToken is a named token authenticating the senders of a channel.
+//
+// The Relay server accepts the messages authenticated by any live token.
+type ChannelToken struct {
+	// is the name of the token, unique within the channel.
+	Name string `json:"name"`
+
+	// is the salted hash of the token in the PHC string format.
+	TokenHash string `json:"token_hash"`
+
+	// is the time when the token has been issued in RFC 3339 format; absent if unknown.
+	Created *string `json:"created,omitempty"`
+
+	// is the time in RFC 3339 format after which the token is rejected; absent if it never expires.
+	Expires *string `json:"expires,omitempty"`
+
+	// is the time in RFC 3339 format when the token last authenticated a message; absent if never.
+	//
+	// The field is only listed and ignored when a channel is stored.
+	LastUsed *string `json:"last_used,omitempty"`
+}
+
+// Rotate defines the rotation of the tokens of a channel.
+type Rotate struct {
+	// is the name of the new token.
+	Name string `json:"name"`
+
+	// is the period in seconds after which the other tokens expire;
+	// absent to use the default grace period of the Control server.
+	GracePeriod *float32 `json:"grace_period,omitempty"`
+}
+
+// IssuedToken contains a token issued by the server.
+type IssuedToken struct {
+	// is the name of the token.
+	Name string `json:"name"`
+
+	// is the token in plain text; it is not stored and can not be retrieved later.
+	Token string `json:"token"`
+
+	// is the time in RFC 3339 format after which the other tokens of the channel expire.
+	ExpiresOthers string `json:"expires_others"`
+}
+
+// ChannelStats lists the usage counters of a channel.
+type ChannelStats struct {
+	Descriptor string `json:"descriptor"`
+
+	// is the period of the counters, either "hour" or "day".
+	Period string `json:"period"`
+
+	// contains the counters ordered by the start of their period.
+	Usage []Usage `json:"usage"`
+}
+
+// Usage counts the attempts to relay a message through a channel within a period.
+type Usage struct {
+	// is the start of the period in RFC 3339 format.
+	Start string `json:"start"`
+
+	// is the number of the relayed messages.
+	Relayed int64 `json:"relayed"`
+
+	// is the total size of the relayed messages in bytes.
+	Bytes int64 `json:"bytes"`
+
+	// is the number of the messages queued since they exceeded the rate limit.
+	//
+	// The queued messages are counted as relayed once they have been relayed.
+	Queued int64 `json:"queued"`
+
+	Rejected Rejections `json:"rejected"`
+}
+
+// Rejections counts the attempts which have not been relayed by reason.
+type Rejections struct {
+	// is the number of the attempts with an invalid token.
+	Forbidden int64 `json:"forbidden"`
+
+	// is the number of the attempts before the min_period elapsed.
+	TooSoon int64 `json:"too_soon"`
+
+	// is the number of the attempts exceeding the max_size.
+	TooLarge int64 `json:"too_large"`
+
+	// is the number of the attempts whose message failed the schema or could not be parsed.
+	Invalid int64 `json:"invalid"`
+
+	// is the number of the attempts which failed due to an error, e.g., of MailGun.
+	Failed int64 `json:"failed"`
+
+	// is the number of the attempts through the disabled channel.
+	Disabled int64 `json:"disabled"`
+
+	// is the number of the attempts through the expired channel.
+	Expired int64 `json:"expired"`
+
+	// is the number of the attempts which would have exceeded a quota of the channel.
+	QuotaExceeded int64 `json:"quota_exceeded"`
+
+	// is the number of the attempts refused since a global limit of the relay has been reached.
+	Throttled int64 `json:"throttled"`
+
+	// is the number of the attempts refused since the remote IP or the descriptor was locked out.
+	LockedOut int64 `json:"locked_out"`
+
+	// is the number of the attempts refused since the queue of the channel was full.
+	QueueFull int64 `json:"queue_full"`
+}
+
+// RateLimit defines the token bucket limiting the rate of the messages of a channel.
+//
+// The bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit
+// is absent, the channel is limited by its min_period as if the bucket held a single message.
+type RateLimit struct {
+	// is the number of messages per second by which the bucket is refilled.
+	Rate float32 `json:"rate"`
+
+	// is the maximum number of messages which can be relayed at once.
+	Burst int32 `json:"burst"`
+}
+
+// Quota defines the hard quotas of a channel per calendar day and month.
+//
+// The Relay server refuses the messages which would exceed a quota until the quota resets at the start
+// of the next day or month in the time zone of the quota. The messages are counted only while the channel
+// has a quota.
+type Quota struct {
+	// is the maximum number of the relayed messages per day; absent or 0 if unlimited.
+	DailyMessages *int64 `json:"daily_messages,omitempty"`
+
+	// is the maximum number of the relayed messages per month; absent or 0 if unlimited.
+	MonthlyMessages *int64 `json:"monthly_messages,omitempty"`
+
+	// is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.
+	DailyBytes *int64 `json:"daily_bytes,omitempty"`
+
+	// is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.
+	MonthlyBytes *int64 `json:"monthly_bytes,omitempty"`
+
+	// is the IANA time zone in which the days and the months start; absent for UTC.
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+// Lockout represents the failed authentications of a remote IP or of a descriptor.
+type Lockout struct {
+	// is either "ip" or "descriptor".
+	Kind string `json:"kind"`
+
+	// is the remote IP or the descriptor.
+	Subject string `json:"subject"`
+
+	// is the number of the consecutive failed authentications.
+	Failures int64 `json:"failures"`
+
+	// is the time of the last failed authentication in RFC 3339 format with nanoseconds.
+	LastFailure string `json:"last_failure"`
+
+	// is the end of the lockout in RFC 3339 format with nanoseconds.
+	LockedUntil string `json:"locked_until"`
+}
+
+// Lockouts lists the current lockouts.
+type Lockouts struct {
+	// contains the lockouts ordered by the kind and the subject.
+	Lockouts []Lockout `json:"lockouts"`
+}
+
+// Unlock selects the remote IP or the descriptor whose lockout is lifted.
+type Unlock struct {
+	// is either "ip" or "descriptor".
+	Kind string `json:"kind"`
+
+	// is the remote IP or the descriptor.
+	Subject string `json:"subject"`
 }
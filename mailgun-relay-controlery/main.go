@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -23,7 +24,37 @@ var version = flag.Bool("version", false,
 	"print the version to STDOUT and exit immediately")
 
 var databaseDir = flag.String("database_dir", "",
-	"Path to the LMDB containing channel and timestamps data")
+	"Path to the database directory containing channel and timestamps data")
+
+var databaseBackend = flag.String("database_backend", "lmdb",
+	"Storage backend of the database directory: lmdb or bolt")
+
+var lmdbMapSize = flag.Int64("lmdb_map_size",
+	database.DefaultLMDBOptions().MapSize/mib,
+	"Initial size of the LMDB memory map in MiB")
+
+var lmdbMaxMapSize = flag.Int64("lmdb_max_map_size",
+	database.DefaultLMDBOptions().MaxMapSize/mib,
+	"Size of the LMDB memory map in MiB up to which the map is grown "+
+		"when it is full")
+
+var lmdbMaxDBs = flag.Int("lmdb_max_dbs",
+	database.DefaultLMDBOptions().MaxDBs,
+	"Maximum number of named databases in the LMDB environment")
+
+// mib is the number of bytes in a mebibyte.
+const mib = 1024 * 1024
+
+// lmdbOptions returns the LMDB options given by the flags.
+func lmdbOptions(logOut *log.Logger) database.LMDBOptions {
+	return database.LMDBOptions{
+		MapSize:    *lmdbMapSize * mib,
+		MaxMapSize: *lmdbMaxMapSize * mib,
+		MaxDBs:     *lmdbMaxDBs,
+		OnGrow: func(usage database.MapUsage) {
+			logOut.Printf("The database map has been grown: %s\n", usage)
+		}}
+}
 
 var address = flag.String("address", ":8300",
 	"address to be used for the control server")
@@ -31,6 +62,61 @@ var address = flag.String("address", ":8300",
 var quiet = flag.Bool("quiet", false,
 	"If set, outputs as little messages as possible")
 
+var channelRevisions = flag.Uint("channel_revisions",
+	database.DefaultRevisions,
+	"Number of the most recent revisions kept per channel")
+
+var expiryGrace = flag.Duration("expiry_grace", 24*time.Hour,
+	"Period after the valid_until time of a channel before the channel "+
+		"is swept")
+
+var expiryAction = flag.String("expiry_action", string(database.RemoveExpired),
+	"Action applied to the expired channels after the grace period: "+
+		"remove or disable")
+
+var expirySweepPeriod = flag.Duration("expiry_sweep_period", time.Hour,
+	"Period between two sweeps of the expired channels")
+
+var tokenRotationGrace = flag.Duration("token_rotation_grace", 24*time.Hour,
+	"Default period after a token rotation before the other tokens "+
+		"of the channel expire")
+
+// sweeper is the actor recorded in the audit log for the swept channels.
+var sweeper = database.Actor{Name: "expiry sweeper"}
+
+// sweepExpired periodically removes or disables the expired channels
+// until stop is closed.
+func sweepExpired(store database.Store, grace time.Duration,
+	action database.ExpiryAction, keep uint, period time.Duration,
+	stop <-chan struct{}, logOut *log.Logger, logErr *log.Logger) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var descriptors []string
+			err := store.Update(func(txn *database.Txn) (txnErr error) {
+				descriptors, txnErr = txn.SweepExpired(time.Now(), grace,
+					action, sweeper, keep)
+				return
+			})
+			if err != nil {
+				logErr.Printf("failed to sweep the expired channels: %s\n",
+					err.Error())
+				continue
+			}
+
+			for _, descriptor := range descriptors {
+				logOut.Printf("The expired channel %s has been swept "+
+					"(%s).\n", descriptor, action)
+			}
+		}
+	}
+}
+
 func routeTableAsString(r *mux.Router) (string, error) {
 	var lines []string
 	err := r.Walk(func(route *mux.Route, router *mux.Router,
@@ -80,15 +166,53 @@ func main() {
 			return 1
 		}
 
-		logOut.Println("Hi from control server.")
+		if *channelRevisions == 0 {
+			logErr.Println("-channel_revisions must be positive")
+			return 1
+		}
+
+		if *expiryGrace < 0 {
+			logErr.Println("-expiry_grace must not be negative")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *expirySweepPeriod <= 0 {
+			logErr.Println("-expiry_sweep_period must be positive")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *tokenRotationGrace < 0 {
+			logErr.Println("-token_rotation_grace must not be negative")
+			flag.PrintDefaults()
+			return 1
+		}
 
 		var err error
 
+		var action database.ExpiryAction
+		action, err = database.ParseExpiryAction(*expiryAction)
+		if err != nil {
+			logErr.Printf("invalid -expiry_action: %s\n", err.Error())
+			return 1
+		}
+
+		logOut.Println("Hi from control server.")
+
 		////
 		// Set up the database
 		////
-		var env *database.Env
-		env, err = database.NewEnv(database.ControlAccess, *databaseDir)
+		var backend database.Backend
+		backend, err = database.ParseBackend(*databaseBackend)
+		if err != nil {
+			logErr.Printf("invalid -database_backend: %s\n", err.Error())
+			return 1
+		}
+
+		var env database.Database
+		env, err = database.Open(backend, database.ControlAccess, *databaseDir,
+			lmdbOptions(logOut))
 		if err != nil {
 			logErr.Printf("failed to open the database "+
 				"%#v: %s\n", *databaseDir, err.Error())
@@ -103,15 +227,51 @@ func main() {
 			}
 		}()
 
+		err = env.CheckSchemaVersion()
+		if err != nil {
+			logErr.Printf("refusing to start: %s\n", err.Error())
+			return 1
+		}
+
+		if reporter, ok := env.(database.MapUsageReporter); ok {
+			usage, usageErr := reporter.MapUsage()
+			if usageErr != nil {
+				logErr.Printf("failed to determine the usage of the "+
+					"database map: %s\n", usageErr.Error())
+			} else {
+				logOut.Printf("Database map: %s\n", usage)
+			}
+		}
+
+		////
+		// Sweep the expired channels in the background
+		////
+		stopSweeping := make(chan struct{})
+		var sweeping sync.WaitGroup
+		sweeping.Add(1)
+		go func() {
+			defer sweeping.Done()
+			sweepExpired(env, *expiryGrace, action, *channelRevisions,
+				*expirySweepPeriod, stopSweeping, logOut, logErr)
+		}()
+
+		// The sweeping needs to stop before the database is closed.
+		defer func() {
+			close(stopSweeping)
+			sweeping.Wait()
+		}()
+
 		ctlSrver := http.Server{Addr: *address,
 			ReadTimeout:       60 * time.Second,
 			ReadHeaderTimeout: 60 * time.Second}
 
 		go func() {
 			h := &control.HandlerImpl{
-				Env:    env,
-				LogOut: logOut,
-				LogErr: logErr}
+				Store:      env,
+				LogOut:     logOut,
+				LogErr:     logErr,
+				Revisions:  *channelRevisions,
+				TokenGrace: *tokenRotationGrace}
 
 			r := control.SetupRouter(h)
 
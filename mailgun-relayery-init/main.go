@@ -3,10 +3,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/user"
+	"path/filepath"
+	"time"
 
+	"github.com/Parquery/mailgun-relayery/channeldoc"
 	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/mailgun-relay-controlery/control"
 	ver "github.com/Parquery/mailgun-relayery/version"
 )
 
@@ -16,6 +22,497 @@ var version = flag.Bool("version", false,
 var databaseDir = flag.String("database_dir", "",
 	"Path to the directory where the database should be initialized")
 
+var databaseBackend = flag.String("database_backend", "lmdb",
+	"Storage backend of the database directory: lmdb or bolt")
+
+var lmdbMapSize = flag.Int64("lmdb_map_size",
+	database.DefaultLMDBOptions().MapSize/mib,
+	"Initial size of the LMDB memory map in MiB")
+
+var lmdbMaxMapSize = flag.Int64("lmdb_max_map_size",
+	database.DefaultLMDBOptions().MaxMapSize/mib,
+	"Size of the LMDB memory map in MiB up to which the map is grown "+
+		"when it is full")
+
+var lmdbMaxDBs = flag.Int("lmdb_max_dbs",
+	database.DefaultLMDBOptions().MaxDBs,
+	"Maximum number of named databases in the LMDB environment")
+
+// mib is the number of bytes in a mebibyte.
+const mib = 1024 * 1024
+
+// lmdbOptions returns the LMDB options given by the flags.
+func lmdbOptions(logOut *log.Logger) database.LMDBOptions {
+	return database.LMDBOptions{
+		MapSize:    *lmdbMapSize * mib,
+		MaxMapSize: *lmdbMaxMapSize * mib,
+		MaxDBs:     *lmdbMaxDBs,
+		OnGrow: func(usage database.MapUsage) {
+			logOut.Printf("The database map has been grown: %s\n", usage)
+		}}
+}
+
+var upgrade = flag.Bool("upgrade", false,
+	"If set, runs the pending migrations on an already initialized "+
+		"database instead of initializing it")
+
+var backupDir = flag.String("backup_dir", "",
+	"If set, writes a compacted snapshot of the database to this empty "+
+		"directory instead of initializing the database; "+
+		"the servers can keep on running during the backup")
+
+var restoreDir = flag.String("restore_dir", "",
+	"If set, restores the snapshot from this directory into the empty "+
+		"database directory instead of initializing the database")
+
+var exportPath = flag.String("export_path", "",
+	"If set, exports the channels to this JSON or YAML file "+
+		"instead of initializing the database; use - for STDOUT")
+
+var withTimestamps = flag.Bool("with_timestamps", false,
+	"If set, the export includes the time of the last relayed message "+
+		"of each channel")
+
+var importPath = flag.String("import_path", "",
+	"If set, imports the channels from this JSON or YAML file "+
+		"instead of initializing the database; use - for STDIN. "+
+		"An uninitialized database directory is initialized first")
+
+var importPolicy = flag.String("import_policy", "merge",
+	"Defines how the channels already in the database are treated on "+
+		"import: merge (update them and keep the ones missing in the file), "+
+		"overwrite (update them and remove the ones missing in the file) "+
+		"or skip-existing (leave them untouched)")
+
+var dryRun = flag.Bool("dry_run", false,
+	"If set, the import only prints the changes without applying them")
+
+var actor = flag.String("actor", "",
+	"Identifies the operator in the audit log of the imported and "+
+		"repaired changes; "+
+		"if not set, the name of the current OS user is used")
+
+var channelRevisions = flag.Uint("channel_revisions",
+	database.DefaultRevisions,
+	"Number of the most recent revisions kept per channel on import "+
+		"and repair")
+
+var convertDir = flag.String("convert_dir", "",
+	"If set, converts the database to a new database in this empty "+
+		"directory instead of initializing the database")
+
+var convertBackend = flag.String("convert_backend", "",
+	"Storage backend of the converted database, lmdb or bolt; "+
+		"if not set, the backend other than -database_backend is used")
+
+var check = flag.Bool("check", false,
+	"If set, checks the database for inconsistencies and reports them "+
+		"instead of initializing the database; the database is opened "+
+		"read-only unless -repair is set")
+
+var repair = flag.Bool("repair", false,
+	"If set together with -check, repairs the inconsistencies which can "+
+		"be safely repaired")
+
+var format = flag.String("format", "",
+	"Format of the exported or imported file, json or yaml; "+
+		"if not set, it is inferred from the file extension")
+
+// backend is the parsed -database_backend.
+var backend database.Backend
+
+// openEnv opens the database and returns a function to close it.
+// If readOnly is set, the database is opened so that it can only be read.
+func openEnv(logOut *log.Logger, logErr *log.Logger, readOnly bool) (
+	env database.Database, closeEnv func() int, err error) {
+	open := database.Open
+	if readOnly {
+		open = database.OpenReadOnly
+	}
+
+	env, err = open(backend, database.ControlAccess, *databaseDir,
+		lmdbOptions(logOut))
+	if err != nil {
+		logErr.Printf("failed to open the "+
+			"database %#v: %s\n", *databaseDir, err.Error())
+		return
+	}
+
+	closeEnv = func() int {
+		closeErr := env.Close()
+		if closeErr != nil {
+			logErr.Printf("failed to close the database "+
+				"%#v: %s\n", *databaseDir, closeErr.Error())
+			return 1
+		}
+		return 0
+	}
+	return
+}
+
+// runUpgrade runs the pending migrations on the database.
+func runUpgrade(logOut *log.Logger, logErr *log.Logger) (retcode int) {
+	env, closeEnv, err := openEnv(logOut, logErr, false)
+	if err != nil {
+		return 1
+	}
+	defer func() {
+		if closeRetcode := closeEnv(); closeRetcode != 0 {
+			retcode = closeRetcode
+		}
+	}()
+
+	from, to, err := env.Upgrade()
+	if err != nil {
+		logErr.Printf("failed to upgrade the "+
+			"database %#v: %s\n", *databaseDir, err.Error())
+		return 1
+	}
+
+	if from == to {
+		logOut.Printf("Database is up to date at the schema "+
+			"version %d.\n", to)
+		return 0
+	}
+
+	logOut.Printf("Database succesfully upgraded from the schema "+
+		"version %d to %d.\n", from, to)
+	return 0
+}
+
+// runBackup writes a snapshot of the database to the backup directory.
+func runBackup(logOut *log.Logger, logErr *log.Logger) (retcode int) {
+	env, closeEnv, err := openEnv(logOut, logErr, false)
+	if err != nil {
+		return 1
+	}
+	defer func() {
+		if closeRetcode := closeEnv(); closeRetcode != 0 {
+			retcode = closeRetcode
+		}
+	}()
+
+	err = env.Backup(*backupDir)
+	if err != nil {
+		logErr.Printf("failed to back up the "+
+			"database %#v: %s\n", *databaseDir, err.Error())
+		return 1
+	}
+
+	logOut.Printf("Database succesfully backed up to %#v.\n", *backupDir)
+	return 0
+}
+
+// runRestore restores the snapshot into the database directory.
+func runRestore(logOut *log.Logger, logErr *log.Logger) (retcode int) {
+	snapshotVersion, err := database.RestoreBackend(backend, *restoreDir,
+		*databaseDir)
+	if err != nil {
+		logErr.Printf("failed to restore the snapshot %#v into the "+
+			"database %#v: %s\n", *restoreDir, *databaseDir, err.Error())
+		return 1
+	}
+
+	logOut.Printf("Snapshot %#v succesfully restored.\n", *restoreDir)
+	if snapshotVersion < database.SchemaVersion {
+		logOut.Printf("The snapshot has the schema version %d; please "+
+			"upgrade it to %d with -upgrade before starting the "+
+			"servers.\n", snapshotVersion, database.SchemaVersion)
+	}
+	return 0
+}
+
+// documentFormat determines the format of the exported or imported file.
+func documentFormat(path string) (channeldoc.Format, error) {
+	if *format != "" {
+		return channeldoc.ParseFormat(*format)
+	}
+
+	return channeldoc.FormatOfPath(path), nil
+}
+
+// runExport exports the channels to the export path.
+func runExport(logOut *log.Logger, logErr *log.Logger) (retcode int) {
+	docFormat, err := documentFormat(*exportPath)
+	if err != nil {
+		logErr.Printf("invalid -format: %s\n", err.Error())
+		return 1
+	}
+
+	env, closeEnv, err := openEnv(logOut, logErr, false)
+	if err != nil {
+		return 1
+	}
+	defer func() {
+		if closeRetcode := closeEnv(); closeRetcode != 0 {
+			retcode = closeRetcode
+		}
+	}()
+
+	err = env.CheckSchemaVersion()
+	if err != nil {
+		logErr.Printf("refusing to export: %s\n", err.Error())
+		return 1
+	}
+
+	var doc *channeldoc.Document
+	err = env.View(func(txn *database.Txn) (txnErr error) {
+		doc, txnErr = channeldoc.Export(txn, *withTimestamps)
+		return
+	})
+	if err != nil {
+		logErr.Printf("failed to export the channels from the "+
+			"database %#v: %s\n", *databaseDir, err.Error())
+		return 1
+	}
+
+	data, err := channeldoc.Marshal(doc, docFormat)
+	if err != nil {
+		logErr.Printf("failed to serialize the channels: %s\n", err.Error())
+		return 1
+	}
+
+	if *exportPath == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = ioutil.WriteFile(*exportPath, data, 0600)
+	}
+	if err != nil {
+		logErr.Printf("failed to write the channels to %#v: %s\n",
+			*exportPath, err.Error())
+		return 1
+	}
+
+	if *exportPath != "-" {
+		logOut.Printf("%d channel(s) succesfully exported to %#v.\n",
+			len(doc.Channels), *exportPath)
+	}
+	return 0
+}
+
+// operator identifies the operator in the audit log of the import and
+// the repair.
+func operator() database.Actor {
+	name := *actor
+	if name == "" {
+		if current, err := user.Current(); err == nil {
+			name = current.Username
+		}
+	}
+
+	return database.Actor{Name: name, UserAgent: "mailgun-relayery-init"}
+}
+
+// runImport imports the channels from the import path.
+func runImport(logOut *log.Logger, logErr *log.Logger) (retcode int) {
+	docFormat, err := documentFormat(*importPath)
+	if err != nil {
+		logErr.Printf("invalid -format: %s\n", err.Error())
+		return 1
+	}
+
+	policy, err := channeldoc.ParsePolicy(*importPolicy)
+	if err != nil {
+		logErr.Printf("invalid -import_policy: %s\n", err.Error())
+		return 1
+	}
+
+	if *channelRevisions == 0 {
+		logErr.Println("-channel_revisions must be positive")
+		return 1
+	}
+
+	var data []byte
+	if *importPath == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*importPath)
+	}
+	if err != nil {
+		logErr.Printf("failed to read the channels from %#v: %s\n",
+			*importPath, err.Error())
+		return 1
+	}
+
+	doc, err := channeldoc.Unmarshal(data, docFormat)
+	if err != nil {
+		logErr.Printf("failed to parse the channels from %#v: %s\n",
+			*importPath, err.Error())
+		return 1
+	}
+
+	_, err = os.Stat(filepath.Join(*databaseDir, backend.DataFile()))
+	switch {
+	case err == nil:
+		// pass
+	case os.IsNotExist(err):
+		err = database.InitializeBackend(backend, database.ControlAccess,
+			*databaseDir, lmdbOptions(logOut))
+		if err != nil {
+			logErr.Printf("failed to initialize the "+
+				"database %#v: %s\n", *databaseDir, err.Error())
+			return 1
+		}
+		logOut.Println("Database succesfully initialized.")
+	default:
+		logErr.Printf("failed to inspect the database %#v: %s\n",
+			*databaseDir, err.Error())
+		return 1
+	}
+
+	env, closeEnv, err := openEnv(logOut, logErr, false)
+	if err != nil {
+		return 1
+	}
+	defer func() {
+		if closeRetcode := closeEnv(); closeRetcode != 0 {
+			retcode = closeRetcode
+		}
+	}()
+
+	err = env.CheckSchemaVersion()
+	if err != nil {
+		logErr.Printf("refusing to import: %s\n", err.Error())
+		return 1
+	}
+
+	var changes []channeldoc.Change
+	importFn := func(txn *database.Txn) (txnErr error) {
+		changes, txnErr = channeldoc.Import(txn, doc, policy, *dryRun,
+			operator(), *channelRevisions)
+		return
+	}
+
+	if *dryRun {
+		err = env.View(importFn)
+	} else {
+		err = env.Update(importFn)
+	}
+	if err != nil {
+		logErr.Printf("failed to import the channels into the "+
+			"database %#v: %s\n", *databaseDir, err.Error())
+		return 1
+	}
+
+	for _, change := range changes {
+		fmt.Println(change.String())
+	}
+
+	if *dryRun {
+		logOut.Println("Dry run; no changes applied.")
+		return 0
+	}
+
+	logOut.Printf("Channels succesfully imported from %#v.\n", *importPath)
+	return 0
+}
+
+// runCheck checks the database for inconsistencies and repairs them
+// if requested.
+//
+// The check is run in a read-only transaction unless the problems are
+// repaired.
+func runCheck(logOut *log.Logger, logErr *log.Logger) (retcode int) {
+	if *repair && *channelRevisions == 0 {
+		logErr.Println("-channel_revisions must be positive")
+		return 1
+	}
+
+	// The database is only written to on repair.
+	env, closeEnv, err := openEnv(logOut, logErr, !*repair)
+	if err != nil {
+		return 1
+	}
+	defer func() {
+		if closeRetcode := closeEnv(); closeRetcode != 0 {
+			retcode = closeRetcode
+		}
+	}()
+
+	err = env.CheckSchemaVersion()
+	if err != nil {
+		logErr.Printf("refusing to check: %s\n", err.Error())
+		return 1
+	}
+
+	var problems []database.Problem
+	var repaired []database.Problem
+	checkFn := func(txn *database.Txn) (txnErr error) {
+		now := time.Now()
+
+		problems, txnErr = txn.Check(now, control.ValidateChannel)
+		if txnErr != nil || !*repair {
+			return
+		}
+
+		repaired, txnErr = txn.Repair(problems, now, operator(),
+			*channelRevisions)
+		return
+	}
+
+	if *repair {
+		err = env.Update(checkFn)
+	} else {
+		err = env.View(checkFn)
+	}
+	if err != nil {
+		logErr.Printf("failed to check the database %#v: %s\n",
+			*databaseDir, err.Error())
+		return 1
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem.String())
+	}
+
+	if len(problems) == 0 {
+		logOut.Println("No problems found.")
+		return 0
+	}
+
+	if *repair {
+		logOut.Printf("Found %d problem(s), repaired %d.\n",
+			len(problems), len(repaired))
+	} else {
+		logOut.Printf("Found %d problem(s).\n", len(problems))
+	}
+
+	if len(repaired) < len(problems) {
+		return 1
+	}
+	return 0
+}
+
+// runConvert converts the database to the convert directory.
+func runConvert(logOut *log.Logger, logErr *log.Logger) (retcode int) {
+	var target database.Backend
+	switch {
+	case *convertBackend != "":
+		var err error
+		target, err = database.ParseBackend(*convertBackend)
+		if err != nil {
+			logErr.Printf("invalid -convert_backend: %s\n", err.Error())
+			return 1
+		}
+	case backend == database.LMDBBackend:
+		target = database.BoltBackend
+	default:
+		target = database.LMDBBackend
+	}
+
+	err := database.Convert(backend, *databaseDir, target, *convertDir,
+		lmdbOptions(logOut))
+	if err != nil {
+		logErr.Printf("failed to convert the database %#v from %s to %s: "+
+			"%s\n", *databaseDir, backend, target, err.Error())
+		return 1
+	}
+
+	logOut.Printf("Database succesfully converted from %s to %s in %#v.\n",
+		backend, target, *convertDir)
+	return 0
+}
+
 func main() {
 	os.Exit(func() (retcode int) {
 		flag.Parse()
@@ -35,9 +532,56 @@ func main() {
 		}
 
 		var err error
+		backend, err = database.ParseBackend(*databaseBackend)
+		if err != nil {
+			logErr.Printf("invalid -database_backend: %s\n", err.Error())
+			return 1
+		}
+
+		modes := 0
+		for _, set := range []bool{*upgrade, *backupDir != "",
+			*restoreDir != "", *exportPath != "", *importPath != "",
+			*convertDir != "", *check} {
+			if set {
+				modes++
+			}
+		}
+		if modes > 1 {
+			logErr.Println("-upgrade, -backup_dir, -restore_dir, " +
+				"-export_path, -import_path, -convert_dir and -check are " +
+				"mutually exclusive")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *repair && !*check {
+			logErr.Println("-repair requires -check")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		switch {
+		case *upgrade:
+			return runUpgrade(logOut, logErr)
+		case *backupDir != "":
+			return runBackup(logOut, logErr)
+		case *restoreDir != "":
+			return runRestore(logOut, logErr)
+		case *exportPath != "":
+			return runExport(logOut, logErr)
+		case *importPath != "":
+			return runImport(logOut, logErr)
+		case *convertDir != "":
+			return runConvert(logOut, logErr)
+		case *check:
+			return runCheck(logOut, logErr)
+		default:
+			// Initialize
+		}
 
 		// Set up the database
-		err = database.Initialize(database.ControlAccess, *databaseDir)
+		err = database.InitializeBackend(backend, database.ControlAccess,
+			*databaseDir, lmdbOptions(logOut))
 		if err != nil {
 			logErr.Printf("failed to initialize the "+
 				"database %#v: %s\n", *databaseDir, err.Error())
@@ -6,15 +6,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 
 	"github.com/Parquery/mailgun-relayery/database"
 	"github.com/Parquery/mailgun-relayery/mailgun-relayery/relay"
+	"github.com/Parquery/mailgun-relayery/ratelimit"
 	"github.com/Parquery/mailgun-relayery/siger"
 	ver "github.com/Parquery/mailgun-relayery/version"
 )
@@ -23,7 +26,37 @@ var version = flag.Bool("version", false,
 	"print the version to STDOUT and exit immediately")
 
 var databaseDir = flag.String("database_dir", "",
-	"Path to the LMDB containing channel and timestamps data")
+	"Path to the database directory containing channel and timestamps data")
+
+var databaseBackend = flag.String("database_backend", "lmdb",
+	"Storage backend of the database directory: lmdb or bolt")
+
+var lmdbMapSize = flag.Int64("lmdb_map_size",
+	database.DefaultLMDBOptions().MapSize/mib,
+	"Initial size of the LMDB memory map in MiB")
+
+var lmdbMaxMapSize = flag.Int64("lmdb_max_map_size",
+	database.DefaultLMDBOptions().MaxMapSize/mib,
+	"Size of the LMDB memory map in MiB up to which the map is grown "+
+		"when it is full")
+
+var lmdbMaxDBs = flag.Int("lmdb_max_dbs",
+	database.DefaultLMDBOptions().MaxDBs,
+	"Maximum number of named databases in the LMDB environment")
+
+// mib is the number of bytes in a mebibyte.
+const mib = 1024 * 1024
+
+// lmdbOptions returns the LMDB options given by the flags.
+func lmdbOptions(logOut *log.Logger) database.LMDBOptions {
+	return database.LMDBOptions{
+		MapSize:    *lmdbMapSize * mib,
+		MaxMapSize: *lmdbMaxMapSize * mib,
+		MaxDBs:     *lmdbMaxDBs,
+		OnGrow: func(usage database.MapUsage) {
+			logOut.Printf("The database map has been grown: %s\n", usage)
+		}}
+}
 
 var apiKeyPath = flag.String("api_key_path", "",
 	"Path to where the MailGun API key is stored")
@@ -37,6 +70,203 @@ var address = flag.String("address", ":8200",
 var quiet = flag.Bool("quiet", false,
 	"If set, outputs as little messages as possible")
 
+var relayLogMaxAge = flag.Duration("relay_log_max_age", 30*24*time.Hour,
+	"Age after which the records of the relay attempts are pruned; "+
+		"0 keeps them regardless of their age")
+
+var relayLogMaxCount = flag.Uint("relay_log_max_count", 0,
+	"Number of the most recent records of the relay attempts kept "+
+		"per channel; 0 keeps them regardless of their count")
+
+var relayLogPrunePeriod = flag.Duration("relay_log_prune_period", time.Hour,
+	"Period between two prunings of the relay log and of the usage counters")
+
+var usageRetention = flag.Duration("usage_retention", 400*24*time.Hour,
+	"Duration after the end of their period for which the hourly and "+
+		"the daily usage counters are kept; 0 keeps them forever")
+
+var globalRatePerSecond = flag.Float64("global_rate_per_second", 0,
+	"Number of messages per second relayed over all the channels; "+
+		"0 if unlimited")
+
+var globalRatePerMinute = flag.Float64("global_rate_per_minute", 0,
+	"Number of messages per minute relayed over all the channels; "+
+		"0 if unlimited")
+
+var globalConcurrency = flag.Int("global_concurrency", 0,
+	"Number of messages relayed at the same time over all the channels; "+
+		"0 if unlimited")
+
+var domainRateLimits = flag.String("domain_rate_limits", "",
+	"Comma-separated limits of the MailGun domains in addition to "+
+		"the global ones, e.g., "+
+		"\"marketing.example.com=5/s,news.example.com=100/m\"")
+
+var lockoutThreshold = flag.Uint("lockout_threshold",
+	uint(database.DefaultLockoutPolicy.Threshold),
+	"Number of the consecutive failed authentications of a remote IP or "+
+		"of a descriptor after which it is locked out; 0 disables "+
+		"the lockouts")
+
+var lockoutDescriptors = flag.Bool("lockout_descriptors", false,
+	"If set, the descriptors are locked out after the failed "+
+		"authentications as well; a locked-out descriptor only refuses "+
+		"the invalid tokens")
+
+var lockoutBase = flag.Duration("lockout_base",
+	database.DefaultLockoutPolicy.Base,
+	"Duration of the first lockout; it doubles with each further "+
+		"failed authentication")
+
+var lockoutMax = flag.Duration("lockout_max",
+	database.DefaultLockoutPolicy.Max,
+	"Maximum duration of a lockout")
+
+var lockoutForget = flag.Duration("lockout_forget",
+	database.DefaultLockoutPolicy.Forget,
+	"Duration without a failed authentication after which the failures "+
+		"of a remote IP or of a descriptor are forgotten")
+
+// lockoutPrunePeriod is the period between two prunings of the lockouts.
+const lockoutPrunePeriod = time.Hour
+
+var queueDrainPeriod = flag.Duration("queue_drain_period", time.Second,
+	"Period between two attempts to relay the messages queued since "+
+		"they exceeded the rate limits of their channels")
+
+var queueRetention = flag.Duration("queue_retention", 24*time.Hour,
+	"Duration for which the status of a relayed or given up queued "+
+		"message is kept")
+
+// queuePrunePeriod is the period between two prunings of the queue.
+const queuePrunePeriod = time.Hour
+
+// pruneRelayLog periodically prunes the relay log and the usage counters
+// until stop is closed. A zero usageRetention keeps the usage counters.
+func pruneRelayLog(store database.Store, retention database.RelayLogRetention,
+	usageRetention time.Duration, period time.Duration,
+	stop <-chan struct{}, logOut *log.Logger, logErr *log.Logger) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !retention.IsEmpty() {
+				var removed uint64
+				err := store.Update(func(txn *database.Txn) (txnErr error) {
+					removed, txnErr = txn.PruneRelayLog(retention, time.Now())
+					return
+				})
+				if err != nil {
+					logErr.Printf("failed to prune the relay log: %s\n",
+						err.Error())
+				} else if removed > 0 {
+					logOut.Printf("Pruned %d record(s) from the relay log.\n",
+						removed)
+				}
+			}
+
+			if usageRetention > 0 {
+				var removed uint64
+				err := store.Update(func(txn *database.Txn) (txnErr error) {
+					removed, txnErr = txn.PruneUsage(
+						time.Now().Add(-usageRetention))
+					return
+				})
+				if err != nil {
+					logErr.Printf("failed to prune the usage counters: %s\n",
+						err.Error())
+				} else if removed > 0 {
+					logOut.Printf("Pruned %d usage counter(s).\n", removed)
+				}
+			}
+		}
+	}
+}
+
+// pruneLockouts periodically removes the forgotten failed authentications
+// until stop is closed.
+func pruneLockouts(store database.Store, policy database.LockoutPolicy,
+	stop <-chan struct{}, logOut *log.Logger, logErr *log.Logger) {
+	ticker := time.NewTicker(lockoutPrunePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var removed uint64
+			err := store.Update(func(txn *database.Txn) (txnErr error) {
+				removed, txnErr = txn.PruneLockouts(time.Now(), policy)
+				return
+			})
+			if err != nil {
+				logErr.Printf("failed to prune the lockouts: %s\n",
+					err.Error())
+				continue
+			}
+
+			if removed > 0 {
+				logOut.Printf("Pruned %d forgotten lockout(s).\n", removed)
+			}
+		}
+	}
+}
+
+// drainQueue periodically relays the queued messages until stop is closed.
+func drainQueue(h *relay.Handler, period time.Duration,
+	stop <-chan struct{}, logOut *log.Logger) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			processed := relay.DrainQueue(h)
+			if processed > 0 {
+				logOut.Printf("Relayed or gave up %d queued message(s).\n",
+					processed)
+			}
+		}
+	}
+}
+
+// pruneQueue periodically removes the queued messages which have been
+// relayed or given up longer than the retention ago until stop is closed.
+func pruneQueue(store database.Store, retention time.Duration,
+	stop <-chan struct{}, logOut *log.Logger, logErr *log.Logger) {
+	ticker := time.NewTicker(queuePrunePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var removed uint64
+			err := store.Update(func(txn *database.Txn) (txnErr error) {
+				removed, txnErr = txn.PruneQueue(time.Now().Add(-retention))
+				return
+			})
+			if err != nil {
+				logErr.Printf("failed to prune the queue: %s\n", err.Error())
+				continue
+			}
+
+			if removed > 0 {
+				logOut.Printf("Pruned %d finished queued message(s).\n",
+					removed)
+			}
+		}
+	}
+}
+
 func routeTableAsString(r *mux.Router) (string, error) {
 	var lines []string
 	err := r.Walk(func(route *mux.Route, router *mux.Router,
@@ -95,9 +325,96 @@ func main() {
 			return 1
 		}
 
-		logOut.Println("Hi from relay server.")
+		if *relayLogMaxAge < 0 {
+			logErr.Println("-relay_log_max_age must not be negative")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *usageRetention < 0 {
+			logErr.Println("-usage_retention must not be negative")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *relayLogPrunePeriod <= 0 {
+			logErr.Println("-relay_log_prune_period must be positive")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *globalRatePerSecond < 0 || *globalRatePerMinute < 0 {
+			logErr.Println("-global_rate_per_second and " +
+				"-global_rate_per_minute must not be negative")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *globalConcurrency < 0 {
+			logErr.Println("-global_concurrency must not be negative")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *lockoutThreshold > math.MaxUint32 {
+			logErr.Printf("-lockout_threshold must not exceed %d\n",
+				uint32(math.MaxUint32))
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *lockoutBase <= 0 || *lockoutMax < *lockoutBase {
+			logErr.Println("-lockout_base must be positive and " +
+				"must not exceed -lockout_max")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *lockoutForget <= 0 {
+			logErr.Println("-lockout_forget must be positive")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *queueDrainPeriod <= 0 {
+			logErr.Println("-queue_drain_period must be positive")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		if *queueRetention <= 0 {
+			logErr.Println("-queue_retention must be positive")
+			flag.PrintDefaults()
+			return 1
+		}
+
+		lockoutPolicy := database.LockoutPolicy{
+			Threshold: uint32(*lockoutThreshold),
+			Base:      *lockoutBase,
+			Max:       *lockoutMax,
+			Forget:    *lockoutForget}
+
+		limiterConfig := ratelimit.GlobalConfig{
+			Concurrency: *globalConcurrency}
+		if *globalRatePerSecond > 0 {
+			limiterConfig.Limits = append(limiterConfig.Limits,
+				ratelimit.PerSecond(*globalRatePerSecond))
+		}
+		if *globalRatePerMinute > 0 {
+			limiterConfig.Limits = append(limiterConfig.Limits,
+				ratelimit.PerMinute(*globalRatePerMinute))
+		}
 
 		var err error
+		limiterConfig.Domains, err = ratelimit.ParseDomainLimits(
+			*domainRateLimits)
+		if err != nil {
+			logErr.Printf("invalid -domain_rate_limits: %s\n", err.Error())
+			flag.PrintDefaults()
+			return 1
+		}
+
+		logOut.Println("Hi from relay server.")
 
 		////
 		// Read the API key and create a MailgunData object
@@ -115,8 +432,16 @@ func main() {
 		////
 		// Set up the database
 		////
-		var env *database.Env
-		env, err = database.NewEnv(database.RelayAccess, *databaseDir)
+		var backend database.Backend
+		backend, err = database.ParseBackend(*databaseBackend)
+		if err != nil {
+			logErr.Printf("invalid -database_backend: %s\n", err.Error())
+			return 1
+		}
+
+		var env database.Database
+		env, err = database.Open(backend, database.RelayAccess, *databaseDir,
+			lmdbOptions(logOut))
 		if err != nil {
 			logErr.Printf("failed to open the "+
 				"database %#v: %s\n", *databaseDir, err.Error())
@@ -131,17 +456,84 @@ func main() {
 			}
 		}()
 
+		err = env.CheckSchemaVersion()
+		if err != nil {
+			logErr.Printf("refusing to start: %s\n", err.Error())
+			return 1
+		}
+
+		if reporter, ok := env.(database.MapUsageReporter); ok {
+			usage, usageErr := reporter.MapUsage()
+			if usageErr != nil {
+				logErr.Printf("failed to determine the usage of the "+
+					"database map: %s\n", usageErr.Error())
+			} else {
+				logOut.Printf("Database map: %s\n", usage)
+			}
+		}
+
+		h := &relay.Handler{
+			Store:       env,
+			MailgunData: mailgunData,
+			LogOut:      logOut,
+			LogErr:      logErr,
+			Lockout:     lockoutPolicy,
+
+			LockoutDescriptors: *lockoutDescriptors}
+
+		if !limiterConfig.IsEmpty() {
+			h.Limiter = ratelimit.NewGlobal(limiterConfig)
+		}
+
+		////
+		// Drain the queue and prune the relay log, the usage counters,
+		// the lockouts and the queue in the background
+		////
+		retention := database.RelayLogRetention{
+			MaxAge:   *relayLogMaxAge,
+			MaxCount: *relayLogMaxCount}
+
+		stopBackground := make(chan struct{})
+		var background sync.WaitGroup
+		if !retention.IsEmpty() || *usageRetention > 0 {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				pruneRelayLog(env, retention, *usageRetention,
+					*relayLogPrunePeriod, stopBackground, logOut, logErr)
+			}()
+		}
+
+		if !lockoutPolicy.IsEmpty() {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				pruneLockouts(env, lockoutPolicy, stopBackground, logOut,
+					logErr)
+			}()
+		}
+
+		background.Add(2)
+		go func() {
+			defer background.Done()
+			drainQueue(h, *queueDrainPeriod, stopBackground, logOut)
+		}()
+		go func() {
+			defer background.Done()
+			pruneQueue(env, *queueRetention, stopBackground, logOut, logErr)
+		}()
+
+		// The background work needs to stop before the database is closed.
+		defer func() {
+			close(stopBackground)
+			background.Wait()
+		}()
+
 		srver := http.Server{Addr: *address,
 			ReadTimeout:       60 * time.Second,
 			ReadHeaderTimeout: 60 * time.Second}
 
 		go func() {
-			h := &relay.Handler{
-				Env:         env,
-				MailgunData: mailgunData,
-				LogOut:      logOut,
-				LogErr:      logErr}
-
 			r := relay.SetupRouter(h)
 
 			r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter,
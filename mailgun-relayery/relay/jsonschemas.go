@@ -66,6 +66,71 @@ var jsonSchemaDescriptorText = `{
   "example": "client-1/pipeline-3"
 }`
 
+var jsonSchemaQueueStatusText = `{
+  "title": "QueueStatus",
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "definitions": {
+    "QueueStatus": {
+      "description": "represents the status of a queued message.",
+      "type": "object",
+      "properties": {
+        "id": {
+          "description": "is the identifier of the queued message.",
+          "type": "string",
+          "example": "155a6e1c6a6f2715"
+        },
+        "status": {
+          "description": "is the state of the message, one of queued, relayed and failed.",
+          "type": "string",
+          "example": "queued"
+        },
+        "enqueued": {
+          "description": "is the time when the message has been queued in RFC 3339 format with nanoseconds.",
+          "type": "string",
+          "example": "2018-10-01T14:37:00.123456789Z"
+        },
+        "position": {
+          "description": "is the number of the messages of the channel to be relayed before this one; absent unless\nthe message is queued.\n",
+          "type": "integer",
+          "format": "int32"
+        },
+        "attempts": {
+          "description": "is the number of the failed attempts to relay the message.",
+          "type": "integer",
+          "format": "int32"
+        },
+        "retry_at": {
+          "description": "is the time in RFC 3339 format with nanoseconds before which the message is not relayed again\nafter a failed attempt; absent if the message is not delayed.\n",
+          "type": "string"
+        },
+        "error": {
+          "description": "is the error of the last failed attempt; absent if none failed.",
+          "type": "string"
+        },
+        "finished": {
+          "description": "is the time in RFC 3339 format with nanoseconds when the message has been relayed or given up;\nabsent if the message is still queued.\n",
+          "type": "string"
+        },
+        "message_id": {
+          "description": "is the MailGun message id; absent unless the message has been relayed.",
+          "type": "string"
+        },
+        "deferred": {
+          "description": "is why the due message could not be relayed at the last attempt, e.g., since a global limit of\nthe relay or a quota of the channel has been reached; absent unless the message is deferred.\n",
+          "type": "string"
+        }
+      },
+      "required": [
+        "id",
+        "status",
+        "enqueued",
+        "attempts"
+      ]
+    }
+  },
+  "$ref": "#/definitions/QueueStatus"
+}`
+
 var jsonSchemaMessage = mustNewJSONSchema(
 	jsonSchemaMessageText,
 	"Message")
@@ -78,6 +143,10 @@ var jsonSchemaDescriptor = mustNewJSONSchema(
 	jsonSchemaDescriptorText,
 	"Descriptor")
 
+var jsonSchemaQueueStatus = mustNewJSONSchema(
+	jsonSchemaQueueStatusText,
+	"QueueStatus")
+
 // ValidateAgainstMessageSchema validates a message coming from the client against Message schema.
 func ValidateAgainstMessageSchema(bb []byte) error {
 	loader := gojsonschema.NewStringLoader(string(bb))
@@ -144,4 +213,26 @@ func ValidateAgainstDescriptorSchema(bb []byte) error {
 	return errors.New(msg)
 }
 
+// ValidateAgainstQueueStatusSchema validates a message coming from the client against QueueStatus schema.
+func ValidateAgainstQueueStatusSchema(bb []byte) error {
+	loader := gojsonschema.NewStringLoader(string(bb))
+	result, err := jsonSchemaQueueStatus.Validate(loader)
+	if err != nil {
+		return err
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	msg := ""
+	for i, valErr := range result.Errors() {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += valErr.String()
+	}
+	return errors.New(msg)
+}
+
 // Automatically generated file by swagger_to. DO NOT EDIT OR APPEND ANYTHING!
@@ -0,0 +1,613 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/mailgun-relay-controlery/control"
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/quota"
+	"github.com/Parquery/mailgun-relayery/ratelimit"
+)
+
+// maxQueueAttempts is the number of the failed attempts to relay a queued
+// message after which the message is given up.
+const maxQueueAttempts = 10
+
+// maxQueueRetryDelay caps the delay between two attempts to relay a queued
+// message.
+const maxQueueRetryDelay = 10 * time.Minute
+
+// queueRetryDelay returns the delay before the next attempt to relay
+// a queued message after the given number of failed attempts. The delay
+// doubles from a second with each failed attempt up to maxQueueRetryDelay.
+func queueRetryDelay(attempts uint32) time.Duration {
+	d := time.Second
+	for i := uint32(1); i < attempts && d < maxQueueRetryDelay; i++ {
+		d *= 2
+	}
+
+	if d > maxQueueRetryDelay {
+		d = maxQueueRetryDelay
+	}
+	return d
+}
+
+// queueMessage reads the message exceeding the rate limit of the channel
+// and stores it in the queue of the channel. The message is accepted with
+// 202 Accepted or refused with 429 Too Many Requests if the queue is full.
+func queueMessage(h *Handler, w http.ResponseWriter, r *http.Request,
+	chann *control.Channel, protoChan *protoed.Channel,
+	record *protoed.RelayRecord, limit ratelimit.Limit,
+	state *protoed.RateState) {
+	body, message, ok := readMessage(h, w, r, chann, record)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	queued := &protoed.QueuedMessage{
+		Descriptor_: record.Descriptor_,
+		Enqueued:    now.UnixNano(),
+		Message:     body,
+		Subject:     message.Subject,
+		Size:        record.Size,
+		Token:       record.Token}
+
+	maxDepth := database.MaxQueueDepth(protoChan)
+	full := false
+
+	err := h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		depth, txnErr := txn.QueueDepth(record.Descriptor_)
+		if txnErr != nil {
+			return
+		}
+
+		if depth >= maxDepth {
+			full = true
+			return
+		}
+
+		txnErr = txn.Enqueue(queued)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.PutTokenUse(record.Descriptor_, record.Token, now)
+		return
+	})
+	if err != nil {
+		record.Outcome = protoed.RelayRecord_FAILED
+		record.Status = http.StatusInternalServerError
+		http.Error(w, fmt.Sprintf(
+			"Error queueing the message of the descriptor: %s",
+			record.Descriptor_),
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to queue the message: %s\n",
+			r.URL.String(), err.Error())
+		return
+	}
+
+	if full {
+		record.Outcome = protoed.RelayRecord_QUEUE_FULL
+		record.Status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", retryAfter(limit.Wait(state, now)))
+		msg := fmt.Sprintf("The queue of %d messages is full "+
+			"for the descriptor: %s", maxDepth, record.Descriptor_)
+		http.Error(w, msg, http.StatusTooManyRequests)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	id := database.FormatQueueID(queued.Id)
+
+	record.Outcome = protoed.RelayRecord_QUEUED
+	record.Status = http.StatusAccepted
+
+	w.Header().Set("X-Queue-Id", id)
+	w.Header().Set("Location", "/api/queue/"+id)
+	w.WriteHeader(http.StatusAccepted)
+	_, err = w.Write([]byte(
+		fmt.Sprintf("The message has been queued with the id: %s", id)))
+	if err != nil {
+		h.LogErr.Printf("%s: Error while writing to the response "+
+			"writer: %s\n", r.URL.String(), err.Error())
+	}
+	h.LogOut.Printf("%s: The message has been queued with the id %s "+
+		"for the descriptor: %s\n", r.URL.String(), id, record.Descriptor_)
+}
+
+// queueStatus converts a queued message to the JSON representation of its
+// status.
+//
+// queueStatus requires:
+// * msg != nil
+func queueStatus(msg *protoed.QueuedMessage, position uint64) QueueStatus {
+	// Pre-condition
+	if !(msg != nil) {
+		panic("Violated: msg != nil")
+	}
+
+	optionalTime := func(nanos int64) *string {
+		if nanos == 0 {
+			return nil
+		}
+		formatted := time.Unix(0, nanos).UTC().Format(time.RFC3339Nano)
+		return &formatted
+	}
+
+	optionalString := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+
+	status := QueueStatus{
+		ID:     database.FormatQueueID(msg.Id),
+		Status: strings.ToLower(msg.Status.String()),
+		Enqueued: time.Unix(0, msg.Enqueued).UTC().
+			Format(time.RFC3339Nano),
+		Attempts:  int32(msg.Attempts),
+		RetryAt:   optionalTime(msg.RetryAt),
+		Error:     optionalString(msg.Error),
+		Finished:  optionalTime(msg.Finished),
+		MessageID: optionalString(msg.MessageId),
+		Deferred:  optionalString(msg.Deferred)}
+
+	if msg.Status == protoed.QueuedMessage_QUEUED {
+		p := int32(position)
+		status.Position = &p
+	}
+
+	return status
+}
+
+// GetQueued serves the status of a message queued since it exceeded
+// the rate limit of its channel.
+//
+// The request is authenticated by the (descriptor, token) pair just as
+// in PutMessage, including the lockouts after the failed authentications,
+// but it is not recorded in the relay log.
+func GetQueued(h *Handler, w http.ResponseWriter, r *http.Request) {
+	var xDescriptor string
+	var xToken string
+
+	////
+	// Parse the header and the path
+	////
+
+	hdr := r.Header
+
+	if _, ok := hdr["X-Descriptor"]; !ok {
+		http.Error(w, "Parameter 'X-Descriptor' expected in header", http.StatusBadRequest)
+		return
+	}
+	xDescriptor = hdr.Get("X-Descriptor")
+
+	if err := database.ValidateDescriptor(xDescriptor); err != nil {
+		http.Error(w, "Invalid 'X-Descriptor': "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := hdr["X-Token"]; !ok {
+		http.Error(w, "Parameter 'X-Token' expected in header", http.StatusBadRequest)
+		return
+	}
+	xToken = hdr.Get("X-Token")
+
+	id, err := database.ParseQueueID(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid queue id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	////
+	// Authenticate
+	////
+
+	ip := remoteIP(r)
+
+	protoChan, ipLockout, descriptorLockout, err := fetchChannel(h,
+		xDescriptor, ip)
+	if err != nil {
+		http.Error(w, "Failed to fetch the channel data from the database.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the channel data from "+
+			"the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	now := time.Now()
+	if until := lockedUntil(now, ipLockout); !until.IsZero() {
+		w.Header().Set("Retry-After", retryAfter(until.Sub(now)))
+		msg := fmt.Sprintf("Too many failed authentications, locked out "+
+			"for the descriptor: %s", xDescriptor)
+		http.Error(w, msg, http.StatusTooManyRequests)
+		h.LogErr.Printf("%s: %s (remote IP %s)\n", r.URL.String(), msg, ip)
+		return
+	}
+
+	_, ok, err := authenticate(protoChan, xToken, now)
+	if err != nil {
+		refuseBusy(h, w, r, xDescriptor, ip)
+		return
+	}
+
+	if !ok {
+		recordAuthFailure(h, r, ip, xDescriptor, now)
+
+		// A locked-out descriptor only refuses the invalid tokens.
+		if until := lockedUntil(now, descriptorLockout); !until.IsZero() {
+			w.Header().Set("Retry-After", retryAfter(until.Sub(now)))
+			msg := fmt.Sprintf("Too many failed authentications, locked "+
+				"out for the descriptor: %s", xDescriptor)
+			http.Error(w, msg, http.StatusTooManyRequests)
+			h.LogErr.Printf("%s: %s (remote IP %s)\n",
+				r.URL.String(), msg, ip)
+			return
+		}
+
+		msg := fmt.Sprintf("The request token for the "+
+			"descriptor is invalid: %s", xDescriptor)
+		http.Error(w, msg, http.StatusForbidden)
+		h.LogErr.Printf("%s: %s (remote IP %s)\n", r.URL.String(), msg, ip)
+		return
+	}
+
+	if ipLockout != nil || descriptorLockout != nil {
+		clearLockouts(h, r, ip, xDescriptor)
+	}
+
+	////
+	// Serve the status
+	////
+
+	var queued *protoed.QueuedMessage
+	var position uint64
+	err = h.Store.View(func(txn *database.Txn) (txnErr error) {
+		queued, txnErr = txn.GetQueued(xDescriptor, id)
+		if txnErr != nil || queued == nil ||
+			queued.Status != protoed.QueuedMessage_QUEUED {
+			return
+		}
+
+		position, txnErr = txn.QueuePosition(xDescriptor, id)
+		return
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch the queued message from the database.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to fetch the queued message from "+
+			"the database: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	if queued == nil {
+		http.Error(w, fmt.Sprintf("No queued message %s for the descriptor: %s",
+			database.FormatQueueID(id), xDescriptor), http.StatusNotFound)
+		return
+	}
+
+	response := queueStatus(queued, position)
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&response)
+
+	if err != nil {
+		http.Error(w, "Failed to marshal the queue status response.",
+			http.StatusInternalServerError)
+		h.LogErr.Printf("%s: Failed to marshal the queue status "+
+			"response: %s\n", r.URL.String(), err.Error())
+	}
+}
+
+// finishQueued stores the final state of the queued message together with
+// the record of the attempt to relay it. The record is given the status 0
+// since no client awaits a response.
+func finishQueued(h *Handler, msg *protoed.QueuedMessage,
+	outcome protoed.RelayRecord_Outcome, now time.Time) (err error) {
+	record := &protoed.RelayRecord{
+		Descriptor_: msg.Descriptor_,
+		Time:        now.UnixNano(),
+		Subject:     msg.Subject,
+		Size:        msg.Size,
+		Outcome:     outcome,
+		MessageId:   msg.MessageId,
+		Token:       msg.Token}
+
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		txnErr = txn.PutQueued(msg)
+		if txnErr != nil {
+			return
+		}
+
+		txnErr = txn.PutRelayRecord(record)
+		if txnErr != nil {
+			return
+		}
+
+		if outcome == protoed.RelayRecord_RELAYED {
+			txnErr = txn.PutLastRelay(database.Descriptor(msg.Descriptor_),
+				database.TimestampFromTime(now))
+			if txnErr != nil {
+				return
+			}
+		}
+
+		txnErr = txn.CountUsage(record)
+		return
+	})
+	return
+}
+
+// deferQueued records why the due queued message could not be relayed so
+// that its status explains the delay. The reason is only stored and logged
+// when it changes so that a message waiting for a limit does not flood
+// the log at every drain.
+func deferQueued(h *Handler, msg *protoed.QueuedMessage,
+	reason string) (err error) {
+	if msg.Deferred == reason {
+		return
+	}
+	msg.Deferred = reason
+
+	err = h.Store.Update(func(txn *database.Txn) error {
+		return txn.PutQueued(msg)
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to defer the queued message %s: %s",
+			database.FormatQueueID(msg.Id), err.Error())
+		return
+	}
+
+	h.LogErr.Printf("queue: Deferred the message %s of the descriptor "+
+		"%s since %s.\n", database.FormatQueueID(msg.Id), msg.Descriptor_,
+		reason)
+	return
+}
+
+// undeliverable explains why the queued messages of the channel can not be
+// relayed at the given time; empty if they can.
+func undeliverable(protoChan *protoed.Channel, now time.Time) string {
+	switch {
+	case protoChan == nil:
+		return "the channel has been removed"
+	case protoChan.Disabled != nil:
+		return "the channel has been disabled"
+	case protoChan.ValidUntil > 0 && now.UnixNano() >= protoChan.ValidUntil:
+		return "the channel has expired"
+	default:
+		return ""
+	}
+}
+
+// relayNextQueued relays the oldest queued message of the channel if
+// the limits allow it. The message is given up if its channel can no longer
+// relay messages or once it failed maxQueueAttempts times.
+//
+// The next message of the channel is due if progressed is true; otherwise
+// the queue of the channel has to wait.
+func relayNextQueued(h *Handler, descriptor string) (progressed bool,
+	err error) {
+	now := time.Now()
+
+	var msg *protoed.QueuedMessage
+	var protoChan *protoed.Channel
+	var reservation *database.RateReservation
+
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		msg, txnErr = txn.NextQueued(descriptor)
+		if txnErr != nil || msg == nil || msg.RetryAt > now.UnixNano() {
+			return
+		}
+
+		protoChan, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil || undeliverable(protoChan, now) != "" {
+			return
+		}
+
+		reservation, _, txnErr = txn.ReserveRate(descriptor,
+			ratelimit.Of(protoChan), now)
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to reserve the next queued message: %s",
+			err.Error())
+		return
+	}
+
+	if msg == nil || msg.RetryAt > now.UnixNano() {
+		return
+	}
+
+	if reason := undeliverable(protoChan, now); reason != "" {
+		msg.Status = protoed.QueuedMessage_FAILED
+		msg.Error = reason
+		msg.Finished = now.UnixNano()
+
+		err = h.Store.Update(func(txn *database.Txn) error {
+			return txn.PutQueued(msg)
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to give up the queued message %s: %s",
+				database.FormatQueueID(msg.Id), err.Error())
+			return
+		}
+
+		h.LogErr.Printf("queue: Gave up the message %s of the descriptor "+
+			"%s since %s.\n", database.FormatQueueID(msg.Id), descriptor,
+			reason)
+		progressed = true
+		return
+	}
+
+	if reservation == nil {
+		return
+	}
+
+	// The slot is only consumed if the message is relayed.
+	defer func() {
+		if msg.Status == protoed.QueuedMessage_RELAYED {
+			reservation.Commit()
+			return
+		}
+
+		rollbackRate(h, "queue", reservation)
+	}()
+
+	////
+	// Check the global limits of the relay and the quotas of the channel
+	////
+
+	if h.Limiter != nil {
+		release, refusal := h.Limiter.Acquire(descriptor, protoChan.Domain,
+			time.Now())
+		if refusal != nil {
+			err = deferQueued(h, msg, fmt.Sprintf(
+				"a global limit of the relay has been reached: %s",
+				refusal.Reason))
+			return
+		}
+
+		// The message holds its slot until it has been relayed and is given
+		// back to the global limits unless it is relayed.
+		defer func() {
+			release(msg.Status == protoed.QueuedMessage_RELAYED, time.Now())
+		}()
+	}
+
+	var quotaReservation *quota.Reservation
+	var exceeded *quota.Exceeded
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		quotaReservation, exceeded, txnErr = quota.Reserve(
+			txn, protoChan, time.Now(), msg.Size)
+		return
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to access and update the quotas: %s",
+			err.Error())
+		return
+	}
+
+	if exceeded != nil {
+		err = deferQueued(h, msg, fmt.Sprintf(
+			"a quota of the channel has been exceeded: %s",
+			exceeded.String()))
+		return
+	}
+
+	// The message only counts against the quotas if it is relayed.
+	if quotaReservation != nil {
+		defer func() {
+			if msg.Status == protoed.QueuedMessage_RELAYED {
+				quotaReservation.Commit()
+				return
+			}
+
+			rollbackQuota(h, "queue", quotaReservation)
+		}()
+	}
+
+	////
+	// Relay
+	////
+
+	msg.Deferred = ""
+
+	message := &Message{}
+	err = json.Unmarshal(msg.Message, message)
+	if err == nil {
+		var resp *MailgunResponse
+		resp, err = relayMessage(message, control.ProtoToJSON(protoChan),
+			h.MailgunData)
+		if err == nil {
+			msg.Status = protoed.QueuedMessage_RELAYED
+			msg.MessageId = resp.MsgID
+		}
+	}
+
+	finished := time.Now()
+	outcome := protoed.RelayRecord_RELAYED
+	if err != nil {
+		outcome = protoed.RelayRecord_FAILED
+
+		msg.Attempts++
+		msg.Error = err.Error()
+		msg.RetryAt = finished.Add(queueRetryDelay(msg.Attempts)).UnixNano()
+		if msg.Attempts >= maxQueueAttempts {
+			msg.Status = protoed.QueuedMessage_FAILED
+			msg.RetryAt = 0
+		}
+
+		h.LogErr.Printf("queue: Failed to relay the message %s of "+
+			"the descriptor %s (attempt %d): %s\n",
+			database.FormatQueueID(msg.Id), descriptor, msg.Attempts,
+			err.Error())
+	}
+
+	if msg.Status != protoed.QueuedMessage_QUEUED {
+		msg.Finished = finished.UnixNano()
+	}
+
+	err = finishQueued(h, msg, outcome, finished)
+	if err != nil {
+		err = fmt.Errorf("failed to store the queued message %s: %s",
+			database.FormatQueueID(msg.Id), err.Error())
+		return
+	}
+
+	if msg.Status == protoed.QueuedMessage_RELAYED {
+		h.LogOut.Printf("queue: The message %s has been relayed for "+
+			"the descriptor %s. Mailgun message id: %s\n",
+			database.FormatQueueID(msg.Id), descriptor, msg.MessageId)
+		progressed = true
+	}
+
+	return
+}
+
+// DrainQueue relays the queued messages of all the channels in order as far
+// as the rate limits of the channels, the global limits of the relay and
+// the quotas of the channels allow. A channel whose next message can not be
+// relayed yet is skipped until the next call.
+//
+// The number of the messages which have been relayed or given up is
+// returned. The errors are logged and do not stop the other channels from
+// being drained.
+func DrainQueue(h *Handler) (processed uint64) {
+	var descriptors []string
+	err := h.Store.View(func(txn *database.Txn) (txnErr error) {
+		descriptors, txnErr = txn.QueuedDescriptors()
+		return
+	})
+	if err != nil {
+		h.LogErr.Printf("queue: Failed to list the queued descriptors: %s\n",
+			err.Error())
+		return
+	}
+
+	for _, descriptor := range descriptors {
+		for {
+			progressed, relayErr := relayNextQueued(h, descriptor)
+			if relayErr != nil {
+				h.LogErr.Printf("queue: Failed to drain the queue of "+
+					"the descriptor %s: %s\n", descriptor, relayErr.Error())
+				break
+			}
+
+			if !progressed {
+				break
+			}
+			processed++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,107 @@
+package relay
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestQueueRetryDelay(t *testing.T) {
+	for _, tt := range []struct {
+		attempts uint32
+		delay    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{10, 512 * time.Second},
+		{11, maxQueueRetryDelay},
+		{1000, maxQueueRetryDelay}} {
+		if got := queueRetryDelay(tt.attempts); got != tt.delay {
+			t.Errorf("expected the delay of %s after %d attempts, got %s",
+				tt.delay, tt.attempts, got)
+		}
+	}
+}
+
+func TestQueueStatus(t *testing.T) {
+	enqueued := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	queued := &protoed.QueuedMessage{Id: 1, Descriptor_: "client-1",
+		Enqueued: enqueued.UnixNano()}
+	status := queueStatus(queued, 3)
+	if status.ID != "0000000000000001" || status.Status != "queued" ||
+		status.Position == nil || *status.Position != 3 ||
+		status.Finished != nil || status.Deferred != nil {
+		t.Errorf("unexpected status of the queued message: %#v", status)
+	}
+
+	queued.Deferred = "a global limit of the relay has been reached"
+	status = queueStatus(queued, 3)
+	if status.Deferred == nil || *status.Deferred != queued.Deferred {
+		t.Errorf("expected the deferral reason, got %#v", status)
+	}
+
+	relayed := &protoed.QueuedMessage{Id: 2, Descriptor_: "client-1",
+		Enqueued: enqueued.UnixNano(), Status: protoed.QueuedMessage_RELAYED,
+		Finished:  enqueued.Add(time.Second).UnixNano(),
+		MessageId: "<20181001143701.1@mailgun.org>"}
+	status = queueStatus(relayed, 0)
+	if status.Status != "relayed" || status.Position != nil ||
+		status.Finished == nil ||
+		*status.Finished != "2018-10-01T14:37:01Z" ||
+		status.MessageID == nil {
+		t.Errorf("unexpected status of the relayed message: %#v", status)
+	}
+}
+
+func TestDeferQueued(t *testing.T) {
+	db := database.NewMemStore(database.RelayAccess)
+
+	msg := &protoed.QueuedMessage{Descriptor_: "client-1",
+		Enqueued: time.Now().UnixNano()}
+	err := db.Update(func(txn *database.Txn) error {
+		return txn.Enqueue(msg)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	logErr := &bytes.Buffer{}
+	h := &Handler{Store: db, LogErr: log.New(logErr, "", 0),
+		LogOut: log.New(ioutil.Discard, "", 0)}
+
+	reason := "a quota of the channel has been exceeded"
+	for i := 0; i < 3; i++ {
+		err = deferQueued(h, msg, reason)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	var stored *protoed.QueuedMessage
+	err = db.View(func(txn *database.Txn) (txnErr error) {
+		stored, txnErr = txn.GetQueued("client-1", msg.Id)
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if stored.Deferred != reason {
+		t.Errorf("expected the deferral reason %#v, got %#v",
+			reason, stored.Deferred)
+	}
+
+	// The deferral is only logged once while the reason stays the same.
+	if lines := strings.Count(logErr.String(), "\n"); lines != 1 {
+		t.Errorf("expected a single logged deferral, got %d: %s",
+			lines, logErr.String())
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -13,6 +16,9 @@ import (
 	"github.com/Parquery/mailgun-relayery/database"
 	"github.com/Parquery/mailgun-relayery/mailgun-relay-controlery/control"
 	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/quota"
+	"github.com/Parquery/mailgun-relayery/ratelimit"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
 )
 
 // Token is a string authenticating the sender of an HTTP request.
@@ -35,12 +41,62 @@ type Message struct {
 	HTML *string `json:"html,omitempty"`
 }
 
+// QueueStatus represents the status of a queued message.
+type QueueStatus struct {
+	// is the identifier of the queued message.
+	ID string `json:"id"`
+
+	// is the state of the message, one of queued, relayed and failed.
+	Status string `json:"status"`
+
+	// is the time when the message has been queued in RFC 3339 format with nanoseconds.
+	Enqueued string `json:"enqueued"`
+
+	// is the number of the messages of the channel to be relayed before this one; absent unless
+	// the message is queued.
+	Position *int32 `json:"position,omitempty"`
+
+	// is the number of the failed attempts to relay the message.
+	Attempts int32 `json:"attempts"`
+
+	// is the time in RFC 3339 format with nanoseconds before which the message is not relayed again
+	// after a failed attempt; absent if the message is not delayed.
+	RetryAt *string `json:"retry_at,omitempty"`
+
+	// is the error of the last failed attempt; absent if none failed.
+	Error *string `json:"error,omitempty"`
+
+	// is the time in RFC 3339 format with nanoseconds when the message has been relayed or given up;
+	// absent if the message is still queued.
+	Finished *string `json:"finished,omitempty"`
+
+	// is the MailGun message id; absent unless the message has been relayed.
+	MessageID *string `json:"message_id,omitempty"`
+
+	// is why the due message could not be relayed at the last attempt, e.g., since a global limit of
+	// the relay or a quota of the channel has been reached; absent unless the message is deferred.
+	Deferred *string `json:"deferred,omitempty"`
+}
+
 // Handler holds the global dependencies for handling the routes.
 type Handler struct {
 	LogErr      *log.Logger
 	LogOut      *log.Logger
 	MailgunData MailgunData
-	Env         *database.Env
+	Store       database.Store
+
+	// Limiter limits the messages of all the channels; nil if unlimited.
+	Limiter *ratelimit.Global
+
+	// Lockout locks out the remote IPs and the descriptors after failed
+	// authentications; the lockouts are disabled if it is empty.
+	Lockout database.LockoutPolicy
+
+	// LockoutDescriptors enables the lockouts of the descriptors in
+	// addition to the ones of the remote IPs. A locked-out descriptor only
+	// refuses the invalid tokens so that nobody can lock the legitimate
+	// senders out by guessing.
+	LockoutDescriptors bool
 }
 
 // SetupRouter sets up a router. If you don't use any middleware, you are good to go.
@@ -53,13 +109,390 @@ func SetupRouter(h *Handler) *mux.Router {
 			PutMessage(h, w, r)
 		}).Methods("post")
 
+	r.HandleFunc(`/api/queue/{id}`,
+		func(w http.ResponseWriter, r *http.Request) {
+			GetQueued(h, w, r)
+		}).Methods("get")
+
 	return r
 }
 
+// putRelayRecord stores the record of a relay attempt in the relay log and
+// adds it to the usage counters of the channel. A relayed message also
+// becomes the last relay of the channel.
+//
+// Failing to store the record does not fail the request; the error is
+// only logged.
+func putRelayRecord(h *Handler, r *http.Request,
+	record *protoed.RelayRecord) {
+	err := h.Store.Update(func(txn *database.Txn) error {
+		err := txn.PutRelayRecord(record)
+		if err != nil {
+			return err
+		}
+
+		if record.Outcome == protoed.RelayRecord_RELAYED {
+			err = txn.PutLastRelay(database.Descriptor(record.Descriptor_),
+				database.TimestampFromTime(time.Now()))
+			if err != nil {
+				return err
+			}
+		}
+
+		return txn.CountUsage(record)
+	})
+	if err != nil {
+		h.LogErr.Printf("%s: Failed to log the relay attempt for "+
+			"the descriptor %s: %s\n",
+			r.URL.String(), record.Descriptor_, err.Error())
+	}
+}
+
+// wholeSeconds rounds the duration up to whole seconds; 0 if it is not
+// positive.
+func wholeSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// retryAfter formats the duration as the value of the Retry-After header
+// in whole seconds, rounded up, and at least one second.
+func retryAfter(d time.Duration) string {
+	seconds := wholeSeconds(d)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// rollbackRate gives the reserved slot back to the token bucket of
+// the channel since the message has not been relayed. The where prefixes
+// the logged error.
+//
+// Failing to give the slot back does not fail the request; the error is
+// only logged and the slot stays consumed.
+func rollbackRate(h *Handler, where string,
+	reservation *database.RateReservation) {
+	err := h.Store.Update(func(txn *database.Txn) error {
+		return txn.RollbackRate(reservation, time.Now())
+	})
+	if err != nil {
+		h.LogErr.Printf("%s: Failed to give the reserved slot back to "+
+			"the rate limit of the descriptor %s: %s\n",
+			where, reservation.Descriptor, err.Error())
+	}
+}
+
+// rollbackQuota gives the reserved message back to the quotas of
+// the channel since the message has not been relayed. The where prefixes
+// the logged error.
+//
+// Failing to give the message back does not fail the request; the error
+// is only logged and the message stays counted.
+func rollbackQuota(h *Handler, where string, reservation *quota.Reservation) {
+	err := h.Store.Update(func(txn *database.Txn) error {
+		return quota.Rollback(txn, reservation)
+	})
+	if err != nil {
+		h.LogErr.Printf("%s: Failed to give the reserved message back to "+
+			"the quota of the descriptor %s: %s\n",
+			where, reservation.Descriptor, err.Error())
+	}
+}
+
+// setRateLimitHeaders reports the state of the token bucket of the channel
+// in the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// headers. The reset gives the number of seconds until the bucket is full
+// again. The headers are omitted for an unlimited channel.
+func setRateLimitHeaders(w http.ResponseWriter, limit ratelimit.Limit,
+	state *protoed.RateState, now time.Time) {
+	if limit.Unlimited() {
+		return
+	}
+
+	hdr := w.Header()
+	hdr.Set("X-RateLimit-Limit", strconv.FormatUint(uint64(limit.Burst), 10))
+	hdr.Set("X-RateLimit-Remaining",
+		strconv.FormatUint(uint64(limit.Remaining(state, now)), 10))
+	hdr.Set("X-RateLimit-Reset",
+		strconv.FormatInt(wholeSeconds(limit.Refill(state, now)), 10))
+}
+
+// remoteIP returns the IP of the remote end of the request; the whole
+// remote address if it can not be split into a host and a port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// decoy holds a hash of a random token which is verified in place of
+// the token hash of a missing channel.
+var decoy struct {
+	once sync.Once
+	hash *protoed.TokenHash
+}
+
+// verifyDecoy verifies the token against the decoy hash so that
+// the requests for missing channels take as long as the requests
+// with an invalid token for the existing ones. Just as for them,
+// tokenhash.ErrBusy is returned if too many tokens are being hashed.
+func verifyDecoy(token string) (err error) {
+	decoy.once.Do(func() {
+		secret, genErr := tokenhash.Generate()
+		if genErr != nil {
+			return
+		}
+
+		decoy.hash, _ = tokenhash.New(secret)
+	})
+
+	if decoy.hash != nil {
+		_, err = tokenhash.TryVerify(decoy.hash, token)
+	}
+	return
+}
+
+// authBusyRetry is the time after which a request refused since too many
+// tokens were being hashed should be retried.
+const authBusyRetry = time.Second
+
+// authenticate checks the token against the channel, or against the decoy
+// hash if the channel is missing, and returns the name of the matching
+// token. The err is tokenhash.ErrBusy if too many tokens are being hashed
+// at once.
+func authenticate(protoChan *protoed.Channel, token string, now time.Time) (
+	name string, ok bool, err error) {
+	if protoChan == nil {
+		err = verifyDecoy(token)
+		return
+	}
+
+	return tokenhash.Authenticate(protoChan, token, now)
+}
+
+// refuseBusy refuses the request with 503 Service Unavailable since too
+// many tokens are being hashed at once.
+func refuseBusy(h *Handler, w http.ResponseWriter, r *http.Request,
+	descriptor string, ip string) {
+	w.Header().Set("Retry-After", retryAfter(authBusyRetry))
+	msg := fmt.Sprintf("Too many requests are being authenticated at once "+
+		"for the descriptor: %s", descriptor)
+	http.Error(w, msg, http.StatusServiceUnavailable)
+	h.LogErr.Printf("%s: %s (remote IP %s)\n", r.URL.String(), msg, ip)
+}
+
+// recordAuthFailure counts the failed authentication against the remote IP
+// and, if the descriptor lockouts are enabled, against the descriptor.
+//
+// Failing to count the failure does not fail the request; the error is
+// only logged.
+func recordAuthFailure(h *Handler, r *http.Request, ip string,
+	descriptor string, now time.Time) {
+	if h.Lockout.IsEmpty() {
+		return
+	}
+
+	err := h.Store.Update(func(txn *database.Txn) error {
+		_, err := txn.RecordAuthFailure(database.LockoutIP, ip, now,
+			h.Lockout)
+		if err != nil || !h.LockoutDescriptors {
+			return err
+		}
+
+		_, err = txn.RecordAuthFailure(database.LockoutDescriptor,
+			descriptor, now, h.Lockout)
+		return err
+	})
+	if err != nil {
+		h.LogErr.Printf("%s: Failed to record the failed authentication "+
+			"from %s for the descriptor %s: %s\n",
+			r.URL.String(), ip, descriptor, err.Error())
+	}
+}
+
+// clearLockouts forgets the failed authentications of the remote IP and
+// of the descriptor after a successful authentication.
+//
+// Failing to clear them does not fail the request; the error is only logged.
+func clearLockouts(h *Handler, r *http.Request, ip string,
+	descriptor string) {
+	err := h.Store.Update(func(txn *database.Txn) error {
+		_, err := txn.ClearLockout(database.LockoutIP, ip)
+		if err != nil {
+			return err
+		}
+
+		_, err = txn.ClearLockout(database.LockoutDescriptor, descriptor)
+		return err
+	})
+	if err != nil {
+		h.LogErr.Printf("%s: Failed to clear the failed authentications "+
+			"from %s for the descriptor %s: %s\n",
+			r.URL.String(), ip, descriptor, err.Error())
+	}
+}
+
+// fetchChannel reads the channel and the failed authentications of
+// the remote IP and of the descriptor; protoChan is nil if the channel does
+// not exist and the lockouts are nil if no failures are tracked.
+func fetchChannel(h *Handler, descriptor string, ip string) (
+	protoChan *protoed.Channel, ipLockout *protoed.Lockout,
+	descriptorLockout *protoed.Lockout, err error) {
+	err = h.Store.View(func(txn *database.Txn) (txnErr error) {
+		protoChan, txnErr = txn.GetChannel(descriptor)
+		if txnErr != nil {
+			return
+		}
+
+		if h.Lockout.IsEmpty() {
+			return
+		}
+
+		ipLockout, txnErr = txn.GetLockout(database.LockoutIP, ip)
+		if txnErr != nil || !h.LockoutDescriptors {
+			return
+		}
+
+		descriptorLockout, txnErr = txn.GetLockout(
+			database.LockoutDescriptor, descriptor)
+		return
+	})
+	return
+}
+
+// lockedUntil returns the end of the longest lockout in effect at
+// the given time; zero if none is in effect. The nil lockouts are ignored.
+func lockedUntil(now time.Time, lockouts ...*protoed.Lockout) (
+	until time.Time) {
+	for _, lockout := range lockouts {
+		if !database.LockedOut(lockout, now) {
+			continue
+		}
+
+		if end := time.Unix(0, lockout.LockedUntil); end.After(until) {
+			until = end
+		}
+	}
+	return
+}
+
+// readMessage reads the body of the request and parses the message.
+// If the body is too large or the message is invalid, the request is
+// refused and ok is false.
+func readMessage(h *Handler, w http.ResponseWriter, r *http.Request,
+	chann *control.Channel, record *protoed.RelayRecord) (
+	body []byte, message *Message, ok bool) {
+	if r.ContentLength > int64(chann.MaxSize) {
+		record.Outcome = protoed.RelayRecord_TOO_LARGE
+		record.Status = http.StatusRequestEntityTooLarge
+		msg := fmt.Sprintf("Request is too large. Content length is %d, "+
+			"max. allowed content length is %d for descriptor %s",
+			r.ContentLength, chann.MaxSize, record.Descriptor_)
+		http.Error(w, msg, http.StatusRequestEntityTooLarge)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(chann.MaxSize))
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		record.Outcome = protoed.RelayRecord_INVALID
+		record.Status = http.StatusBadRequest
+		http.Error(w, "Body unreadable: "+err.Error(), http.StatusBadRequest)
+		h.LogErr.Printf("%s: body unreadable: %s\n", r.URL.String(), err.Error())
+		return
+	}
+
+	record.Size = int64(len(body))
+
+	message = &Message{}
+	err = ValidateAgainstMessageSchema(body)
+	if err != nil {
+		record.Outcome = protoed.RelayRecord_INVALID
+		record.Status = http.StatusBadRequest
+		h.LogErr.Printf("%s: Failed to validate against schema: %s\n",
+			r.URL.String(), err.Error())
+		http.Error(w, "Failed to validate against message schema.",
+			http.StatusBadRequest)
+		return
+	}
+
+	err = json.Unmarshal(body, message)
+	if err != nil {
+		record.Outcome = protoed.RelayRecord_INVALID
+		record.Status = http.StatusBadRequest
+		h.LogErr.Printf("%s: Failed to unmarshal the message: %s\n",
+			r.URL.String(), err.Error())
+		http.Error(w, "Failed to unmarshal the message.", http.StatusBadRequest)
+		return
+	}
+
+	record.Subject = message.Subject
+	ok = true
+	return
+}
+
 // PutMessage sends a message to the server, which relays it to the MailGun API.
 //
-// The given (descriptor, token) pair are authenticated first.
+// A descriptor which can not identify a channel, i.e., an empty one or one
+// with a zero byte, is refused with 400 Bad Request.
+// The given (descriptor, token) pair are authenticated first. Any live token
+// of the channel is accepted; the name of the used token is recorded in
+// the relay log and its last use is stored in the database.
+// A missing channel is refused with 403 Forbidden just as an invalid token
+// so that the existence of a descriptor is not revealed.
+// The failed authentications are counted per remote IP; once they reach
+// the threshold of the lockout policy, the requests of the IP are refused
+// with 429 Too Many Requests for an exponentially growing period given in
+// the Retry-After header. If the descriptor lockouts are enabled,
+// the failures are counted per descriptor as well, but a locked-out
+// descriptor only refuses the invalid tokens with 429 Too Many Requests
+// so that the legitimate senders can not be locked out by guessing.
+// A successful authentication clears the failures of both.
+// If too many tokens are being hashed at once, the request is refused with
+// 503 Service Unavailable and the Retry-After header.
 // The message's metadata is determined by the channel information from the database.
+// The messages of a disabled channel are refused with 423 Locked and
+// the reason of the disablement, if any, in the X-Disabled-Reason header.
+// The messages of a channel past its valid_until time are refused with
+// 410 Gone.
+// The messages exceeding the rate limit of the channel are refused with
+// 429 Too Many Requests. The rate limit is a token bucket which is taken
+// from atomically together with the check; the channels without a rate
+// limit are limited by their min_period as a bucket with the burst of one.
+// The message taken from the bucket is reserved while the request is
+// processed and given back unless the message is relayed, e.g., if
+// the message is invalid or MailGun fails, so that the client can retry
+// right away.
+// The Retry-After header gives the number of seconds until the bucket holds
+// a message again. Once the bucket has been checked, the responses report
+// its state in the X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset headers.
+// If the channel is in the queue mode, the valid messages exceeding its
+// rate limit are stored in the queue of the channel instead and accepted
+// with 202 Accepted; the X-Queue-Id and the Location headers identify
+// the queued message. While the queue is not empty, all the messages of
+// the channel are queued so that they are relayed in order. Once the queue
+// holds max_queue_depth messages, the further messages are refused with
+// 429 Too Many Requests. The queued messages are relayed by DrainQueue.
+// The messages which would exceed a daily or a monthly quota of the channel
+// are refused with 429 Too Many Requests as well; the X-Quota-Reset header
+// gives the time when the quota resets and the Retry-After header
+// the number of seconds until then. The message is only counted against
+// the quotas if it is relayed.
+// If a global limit of the relay has been reached, the message is refused
+// with 429 Too Many Requests, or with 503 Service Unavailable in case of
+// the concurrency cap, and the Retry-After header gives the number of
+// seconds after which the message should be retried.
+//
+// Every attempt to relay a message through an existing channel is recorded
+// in the relay log of the database and counted in the usage counters of
+// the channel.
 func PutMessage(h *Handler, w http.ResponseWriter, r *http.Request) {
 	var xDescriptor string
 	var xToken string
@@ -76,6 +509,13 @@ func PutMessage(h *Handler, w http.ResponseWriter, r *http.Request) {
 	}
 	xDescriptor = hdr.Get("X-Descriptor")
 
+	// The invalid descriptors are refused once here so that none of
+	// the records keyed on the descriptor is written for them.
+	if err := database.ValidateDescriptor(xDescriptor); err != nil {
+		http.Error(w, "Invalid 'X-Descriptor': "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if _, ok := hdr["X-Token"]; !ok {
 		http.Error(w, "Parameter 'X-Token' expected in header", http.StatusBadRequest)
 		return
@@ -91,11 +531,10 @@ func PutMessage(h *Handler, w http.ResponseWriter, r *http.Request) {
 	// Get channel information
 	////
 
-	var protoChan *protoed.Channel
-	err := h.Env.View(func(txn *database.Txn) (txnErr error) {
-		protoChan, txnErr = txn.GetChannel(xDescriptor)
-		return
-	})
+	ip := remoteIP(r)
+
+	protoChan, ipLockout, descriptorLockout, err := fetchChannel(h,
+		xDescriptor, ip)
 	if err != nil {
 		http.Error(w, "Failed to fetch the channel data from the database.",
 			http.StatusInternalServerError)
@@ -104,22 +543,110 @@ func PutMessage(h *Handler, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	////
+	// Log the attempt through an existing channel on return
+	////
+
+	now := time.Now()
+	record := &protoed.RelayRecord{
+		Descriptor_: xDescriptor,
+		Time:        now.UnixNano()}
+	if r.ContentLength > 0 {
+		record.Size = r.ContentLength
+	}
+	defer func() {
+		if protoChan != nil && record.Outcome != protoed.RelayRecord_UNKNOWN {
+			putRelayRecord(h, r, record)
+		}
+	}()
+
+	////
+	// Check the lockout of the remote IP
+	////
+
+	if until := lockedUntil(now, ipLockout); !until.IsZero() {
+		record.Outcome = protoed.RelayRecord_LOCKED_OUT
+		record.Status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", retryAfter(until.Sub(now)))
+		msg := fmt.Sprintf("Too many failed authentications, locked out "+
+			"for the descriptor: %s", xDescriptor)
+		http.Error(w, msg, http.StatusTooManyRequests)
+		h.LogErr.Printf("%s: %s (remote IP %s)\n", r.URL.String(), msg, ip)
+		return
+	}
+
 	////
 	// Verify the (descriptor, token) pair
 	////
 
-	if protoChan == nil {
-		msg := fmt.Sprintf(
-			"No channel was found for the descriptor: %s", xDescriptor)
-		http.Error(w, msg, http.StatusNotFound)
-		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+	tokenName, ok, err := authenticate(protoChan, xToken, now)
+	if err != nil {
+		record.Outcome = protoed.RelayRecord_THROTTLED
+		record.Status = http.StatusServiceUnavailable
+		refuseBusy(h, w, r, xDescriptor, ip)
 		return
 	}
 
-	if protoChan.Token != string(xToken) {
+	if !ok {
+		recordAuthFailure(h, r, ip, xDescriptor, now)
+
+		// A locked-out descriptor only refuses the invalid tokens.
+		if until := lockedUntil(now, descriptorLockout); !until.IsZero() {
+			record.Outcome = protoed.RelayRecord_LOCKED_OUT
+			record.Status = http.StatusTooManyRequests
+			w.Header().Set("Retry-After", retryAfter(until.Sub(now)))
+			msg := fmt.Sprintf("Too many failed authentications, locked "+
+				"out for the descriptor: %s", xDescriptor)
+			http.Error(w, msg, http.StatusTooManyRequests)
+			h.LogErr.Printf("%s: %s (remote IP %s)\n",
+				r.URL.String(), msg, ip)
+			return
+		}
+
+		record.Outcome = protoed.RelayRecord_FORBIDDEN
+		record.Status = http.StatusForbidden
+
 		msg := fmt.Sprintf("The request token for the "+
 			"descriptor is invalid: %s", xDescriptor)
 		http.Error(w, msg, http.StatusForbidden)
+		if protoChan == nil {
+			h.LogErr.Printf("%s: %s (no channel was found, remote IP %s)\n",
+				r.URL.String(), msg, ip)
+		} else {
+			h.LogErr.Printf("%s: %s (remote IP %s)\n",
+				r.URL.String(), msg, ip)
+		}
+		return
+	}
+	record.Token = tokenName
+
+	if ipLockout != nil || descriptorLockout != nil {
+		clearLockouts(h, r, ip, xDescriptor)
+	}
+
+	if protoChan.Disabled != nil {
+		record.Outcome = protoed.RelayRecord_DISABLED
+		record.Status = http.StatusLocked
+
+		msg := fmt.Sprintf("The channel has been disabled "+
+			"for the descriptor: %s", xDescriptor)
+		if protoChan.Disabled.Reason != "" {
+			w.Header().Set("X-Disabled-Reason", protoChan.Disabled.Reason)
+			msg += fmt.Sprintf(" (%s)", protoChan.Disabled.Reason)
+		}
+		http.Error(w, msg, http.StatusLocked)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+		return
+	}
+
+	if protoChan.ValidUntil > 0 && record.Time >= protoChan.ValidUntil {
+		record.Outcome = protoed.RelayRecord_EXPIRED
+		record.Status = http.StatusGone
+
+		msg := fmt.Sprintf("The channel expired at %s for the descriptor: %s",
+			time.Unix(0, protoChan.ValidUntil).UTC().Format(time.RFC3339),
+			xDescriptor)
+		http.Error(w, msg, http.StatusGone)
 		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
 		return
 	}
@@ -127,117 +654,200 @@ func PutMessage(h *Handler, w http.ResponseWriter, r *http.Request) {
 	chann := control.ProtoToJSON(protoChan)
 
 	////
-	// Check that this request obeys the minimum period between two requests.
+	// Check that this request obeys the rate limit of the channel.
 	////
 
-	var timeLastRequest *database.Timestamp
-	tooSoon := false
+	limit := ratelimit.Of(protoChan)
 
-	err = h.Env.Update(func(txn *database.Txn) (txnErr error) {
-		////
-		// Get
-		////
+	// The messages exceeding the rate limit of a channel in the queue mode
+	// are queued. While the queue is not empty, all the messages are queued
+	// so that they are relayed in order.
+	queueing := protoChan.OnThrottle == protoed.Channel_QUEUE
 
-		timeLastRequest, txnErr = txn.GetTimestamp(xDescriptor)
-		if txnErr != nil {
-			return
-		}
+	// state is the state of the bucket after the check.
+	var state *protoed.RateState
+	var reservation *database.RateReservation
+	checked := time.Now()
 
-		////
-		// Check
-		////
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		if queueing {
+			var depth uint64
+			depth, txnErr = txn.QueueDepth(xDescriptor)
+			if txnErr != nil {
+				return
+			}
 
-		if timeLastRequest != nil {
-			t := timeLastRequest.ToTime()
-			elapsedSeconds := time.Now().Sub(t).Seconds()
-			if elapsedSeconds < float64(chann.MinPeriod) {
-				tooSoon = true
+			if depth > 0 {
+				state, txnErr = txn.GetRateState(xDescriptor)
 				return
 			}
 		}
 
 		////
-		// Update
+		// Reserve
 		////
 
-		ts := database.TimestampFromTime(time.Now())
-		txnErr = txn.PutTimestamp(database.Descriptor(xDescriptor), &ts)
-		if txnErr != nil {
+		reservation, state, txnErr = txn.ReserveRate(xDescriptor, limit,
+			checked)
+		if txnErr != nil || reservation == nil {
 			return
 		}
 
+		////
+		// Update
+		////
+
+		txnErr = txn.PutTokenUse(xDescriptor, tokenName, checked)
 		return
 	})
 	if err != nil {
+		record.Outcome = protoed.RelayRecord_FAILED
+		record.Status = http.StatusInternalServerError
 		http.Error(w, fmt.Sprintf(
-			"Error accessing/updating the timestamp of the last request for the descriptor: %s",
+			"Error accessing/updating the rate state of the descriptor: %s",
 			xDescriptor),
 			http.StatusInternalServerError)
 		h.LogErr.Printf(
-			"%s: Failed to access and update the timestamp of the last request for the descriptor: %s\n",
+			"%s: Failed to access and update the rate state of the descriptor: %s\n",
 			r.URL.String(), err.Error())
 		return
 	}
 
-	if tooSoon {
+	setRateLimitHeaders(w, limit, state, checked)
+
+	if reservation == nil && queueing {
+		queueMessage(h, w, r, chann, protoChan, record, limit, state)
+		return
+	}
+
+	if reservation == nil {
+		record.Outcome = protoed.RelayRecord_TOO_SOON
+		record.Status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", retryAfter(limit.Wait(state, checked)))
 		msg := fmt.Sprintf("The minimum waiting "+
 			"period of %f seconds between requests "+
 			"did not elapse for the descriptor: %s",
 			chann.MinPeriod, xDescriptor)
+		if protoChan.RateLimit != nil {
+			msg = fmt.Sprintf("The rate limit of %f messages per second "+
+				"with the burst of %d messages has been exceeded "+
+				"for the descriptor: %s",
+				protoChan.RateLimit.Rate, protoChan.RateLimit.Burst,
+				xDescriptor)
+		}
 		http.Error(w, msg, http.StatusTooManyRequests)
 		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
 		return
 	}
 
+	// The slot is only consumed if the message is relayed.
+	defer func() {
+		if record.Outcome == protoed.RelayRecord_RELAYED {
+			reservation.Commit()
+			return
+		}
+
+		rollbackRate(h, r.URL.String(), reservation)
+	}()
+
 	////
-	// Read the body
+	// Read the body and parse the message
 	////
 
-	if r.ContentLength > int64(chann.MaxSize) {
-		msg := fmt.Sprintf("Request is too large. Content length is %d, "+
-			"max. allowed content length is %d for descriptor %s",
-			r.ContentLength, chann.MaxSize, xDescriptor)
-		http.Error(w, msg, http.StatusRequestEntityTooLarge)
-		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+	_, message, ok := readMessage(h, w, r, chann, record)
+	if !ok {
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, int64(chann.MaxSize))
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Body unreadable: "+err.Error(), http.StatusBadRequest)
-		h.LogErr.Printf("%s: body unreadable: %s\n", r.URL.String(), err.Error())
-		return
+	////
+	// Check that the relay obeys its global limits.
+	////
+
+	if h.Limiter != nil {
+		release, refusal := h.Limiter.Acquire(xDescriptor, chann.Domain,
+			time.Now())
+		if refusal != nil {
+			status := http.StatusTooManyRequests
+			if refusal.Concurrency {
+				status = http.StatusServiceUnavailable
+			}
+
+			record.Outcome = protoed.RelayRecord_THROTTLED
+			record.Status = int32(status)
+			w.Header().Set("Retry-After", retryAfter(refusal.RetryAfter))
+			msg := fmt.Sprintf("The relay is throttled for "+
+				"the descriptor %s: %s", xDescriptor, refusal.Reason)
+			http.Error(w, msg, status)
+			h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
+			return
+		}
+
+		// The message holds its slot until it has been relayed and is given
+		// back to the global limits unless it is relayed.
+		defer func() {
+			release(record.Outcome == protoed.RelayRecord_RELAYED, time.Now())
+		}()
 	}
 
 	////
-	// Parse the message
+	// Check that this message obeys the quotas of the channel.
 	////
 
-	message := &Message{}
-	err = ValidateAgainstMessageSchema(body)
+	var quotaReservation *quota.Reservation
+	var exceeded *quota.Exceeded
+	counted := time.Now()
+	err = h.Store.Update(func(txn *database.Txn) (txnErr error) {
+		quotaReservation, exceeded, txnErr = quota.Reserve(
+			txn, protoChan, counted, record.Size)
+		return
+	})
 	if err != nil {
-		h.LogErr.Printf("%s: Failed to validate against schema: %s\n",
+		record.Outcome = protoed.RelayRecord_FAILED
+		record.Status = http.StatusInternalServerError
+		http.Error(w, fmt.Sprintf(
+			"Error accessing/updating the quotas of the descriptor: %s",
+			xDescriptor),
+			http.StatusInternalServerError)
+		h.LogErr.Printf(
+			"%s: Failed to access and update the quotas of the descriptor: %s\n",
 			r.URL.String(), err.Error())
-		http.Error(w, "Failed to validate against message schema.",
-			http.StatusBadRequest)
 		return
 	}
 
-	err = json.Unmarshal(body, message)
-	if err != nil {
-		h.LogErr.Printf("%s: Failed to unmarshal the message: %s\n",
-			r.URL.String(), err.Error())
-		http.Error(w, "Failed to unmarshal the message.", http.StatusBadRequest)
+	if exceeded != nil {
+		record.Outcome = protoed.RelayRecord_QUOTA_EXCEEDED
+		record.Status = http.StatusTooManyRequests
+		w.Header().Set("X-Quota-Reset",
+			exceeded.Reset.UTC().Format(time.RFC3339))
+		w.Header().Set("Retry-After",
+			retryAfter(exceeded.Reset.Sub(counted)))
+		msg := fmt.Sprintf("The quota has been exceeded for "+
+			"the descriptor %s: %s", xDescriptor, exceeded.String())
+		http.Error(w, msg, http.StatusTooManyRequests)
+		h.LogErr.Printf("%s: %s\n", r.URL.String(), msg)
 		return
 	}
 
+	// The message only counts against the quotas if it is relayed.
+	if quotaReservation != nil {
+		defer func() {
+			if record.Outcome == protoed.RelayRecord_RELAYED {
+				quotaReservation.Commit()
+				return
+			}
+
+			rollbackQuota(h, r.URL.String(), quotaReservation)
+		}()
+	}
+
 	////
 	// Relay
 	////
 
 	resp, err := relayMessage(message, chann, h.MailgunData)
 	if err != nil {
+		record.Outcome = protoed.RelayRecord_FAILED
+		record.Status = http.StatusInternalServerError
 		http.Error(w, "Failed to relay the message.",
 			http.StatusInternalServerError)
 		h.LogErr.Printf("%s: Failed to relay the message: %s\n",
@@ -245,6 +855,10 @@ func PutMessage(h *Handler, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	record.Outcome = protoed.RelayRecord_RELAYED
+	record.Status = http.StatusOK
+	record.MessageId = resp.MsgID
+
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write([]byte(
 		fmt.Sprintf("The message has been correctly relayed.")))
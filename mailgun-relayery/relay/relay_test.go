@@ -0,0 +1,568 @@
+package relay
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+	"github.com/Parquery/mailgun-relayery/tokenhash"
+)
+
+const testToken = "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU"
+
+const validMessage = `{"subject": "broken pipeline observed", ` +
+	`"content": "A broken pipeline was observed."}`
+
+// serve routes the request through the handler and records the response.
+func serve(h *Handler, method string, target string, body string,
+	header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, value := range header {
+		r.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(w, r)
+	return w
+}
+
+// newTestHandler creates a handler on the store which relays to the MailGun
+// at the address and discards the logs.
+func newTestHandler(db database.Store, mailgunAddress string) *Handler {
+	return &Handler{Store: db,
+		LogErr:      log.New(ioutil.Discard, "", 0),
+		LogOut:      log.New(ioutil.Discard, "", 0),
+		MailgunData: MailgunData{APIKey: "key-test", Address: mailgunAddress}}
+}
+
+func TestPutMessage_InvalidDescriptor(t *testing.T) {
+	db := database.NewMemStore(database.RelayAccess)
+	h := newTestHandler(db, "")
+
+	for _, descriptor := range []string{"", "client\x001"} {
+		w := serve(h, "POST", "/api/message", validMessage,
+			map[string]string{"X-Descriptor": descriptor,
+				"X-Token": testToken})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected the status 400 for the descriptor %#v, "+
+				"got %d: %s", descriptor, w.Code, w.Body.String())
+		}
+	}
+
+	err := db.View(func(txn *database.Txn) error {
+		lockout, err := txn.GetLockout(database.LockoutDescriptor, "")
+		if err == nil && lockout != nil {
+			t.Errorf("expected no lockout of the empty descriptor")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestPutMessage_Rollback(t *testing.T) {
+	var failing int32
+	var sent int32
+	mailgun := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&failing) == 1 {
+				http.Error(w, "Service unavailable.",
+					http.StatusServiceUnavailable)
+				return
+			}
+
+			n := atomic.AddInt32(&sent, 1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"message": "Queued. Thank you.", `+
+				`"id": "<20181001143700.%d@example.com>"}`, n)
+		}))
+	defer mailgun.Close()
+
+	hash, err := tokenhash.New(testToken)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db := database.NewMemStore(database.ControlAccess)
+	err = db.Update(func(txn *database.Txn) error {
+		return txn.PutChannel(&protoed.Channel{Descriptor_: "client-1",
+			TokenHash:  hash,
+			Sender:     &protoed.Entity{Email: "johann.bach@composers.com"},
+			Recipients: []*protoed.Entity{{Email: "cpe.bach@composers.com"}},
+			Domain:     "example.com",
+			MaxSize:    1024,
+			RateLimit:  &protoed.RateLimit{Rate: 0.001, Burst: 1},
+			Quota:      &protoed.Quota{DailyMessages: 1}})
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.RelayAccess
+
+	h := newTestHandler(db, mailgun.URL)
+	header := map[string]string{"X-Descriptor": "client-1",
+		"X-Token": testToken}
+
+	for _, tc := range []struct {
+		body    string
+		failing int32
+		status  int
+	}{
+		// The invalid message gives the slot and the quota back.
+		{`{"subject": "no content"}`, 0, http.StatusBadRequest},
+		// So does the failure of MailGun.
+		{validMessage, 1, http.StatusInternalServerError},
+		{validMessage, 0, http.StatusOK},
+		// The relayed message keeps the slot.
+		{validMessage, 0, http.StatusTooManyRequests}} {
+		atomic.StoreInt32(&failing, tc.failing)
+
+		w := serve(h, "POST", "/api/message", tc.body, header)
+		if w.Code != tc.status {
+			t.Fatalf("expected the status %d, got %d: %s",
+				tc.status, w.Code, w.Body.String())
+		}
+
+		switch w.Code {
+		case http.StatusOK:
+			for key, expected := range map[string]string{
+				"X-RateLimit-Limit":     "1",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "1000"} {
+				if got := w.Header().Get(key); got != expected {
+					t.Errorf("expected %s of %s, got %#v",
+						key, expected, got)
+				}
+			}
+		case http.StatusTooManyRequests:
+			if got := w.Header().Get("Retry-After"); got != "1000" {
+				t.Errorf("expected Retry-After of 1000, got %#v", got)
+			}
+		}
+	}
+
+	if n := atomic.LoadInt32(&sent); n != 1 {
+		t.Errorf("expected a single message sent to MailGun, got %d", n)
+	}
+
+	var outcomes []protoed.RelayRecord_Outcome
+	err = db.View(func(txn *database.Txn) error {
+		now := time.Now()
+		counters, err := txn.QuotaCounters("client-1", database.QuotaDay,
+			database.QuotaDay.Start(now, time.UTC))
+		if err != nil {
+			return err
+		}
+		if counters.Messages != 1 {
+			t.Errorf("expected only the relayed message to be counted "+
+				"against the quota, got %d", counters.Messages)
+		}
+
+		records, _, err := txn.RelayRecords("client-1", time.Time{},
+			time.Time{}, 100)
+		for _, record := range records {
+			outcomes = append(outcomes, record.Outcome)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []protoed.RelayRecord_Outcome{
+		protoed.RelayRecord_INVALID,
+		protoed.RelayRecord_FAILED,
+		protoed.RelayRecord_RELAYED,
+		protoed.RelayRecord_TOO_SOON}
+	if fmt.Sprint(outcomes) != fmt.Sprint(expected) {
+		t.Errorf("expected the outcomes %v, got %v", expected, outcomes)
+	}
+}
+
+func TestPutMessage_HeldSlot(t *testing.T) {
+	// The first message blocks in MailGun until it is released and then
+	// fails; the later messages are accepted.
+	var calls int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	mailgun := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(entered)
+				<-release
+				http.Error(w, "Service unavailable.",
+					http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"message": "Queued. Thank you.", `+
+				`"id": "<20181001143700.1@example.com>"}`)
+		}))
+	defer mailgun.Close()
+
+	hash, err := tokenhash.New(testToken)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db := database.NewMemStore(database.ControlAccess)
+	err = db.Update(func(txn *database.Txn) error {
+		return txn.PutChannel(&protoed.Channel{Descriptor_: "client-1",
+			TokenHash:  hash,
+			Sender:     &protoed.Entity{Email: "johann.bach@composers.com"},
+			Recipients: []*protoed.Entity{{Email: "cpe.bach@composers.com"}},
+			Domain:     "example.com",
+			MaxSize:    1024,
+			RateLimit:  &protoed.RateLimit{Rate: 0.001, Burst: 1}})
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.RelayAccess
+
+	h := newTestHandler(db, mailgun.URL)
+	header := map[string]string{"X-Descriptor": "client-1",
+		"X-Token": testToken}
+
+	var first *httptest.ResponseRecorder
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = serve(h, "POST", "/api/message", validMessage, header)
+	}()
+
+	// The concurrent message is refused while the first one holds the slot.
+	<-entered
+	w := serve(h, "POST", "/api/message", validMessage, header)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the status 429 while the slot is held, "+
+			"got %d: %s", w.Code, w.Body.String())
+	}
+
+	close(release)
+	wg.Wait()
+	if first.Code != http.StatusInternalServerError {
+		t.Fatalf("expected the status 500 of the failed message, "+
+			"got %d: %s", first.Code, first.Body.String())
+	}
+
+	// The failure of MailGun gave the slot back.
+	w = serve(h, "POST", "/api/message", validMessage, header)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the status 200 after the failure, got %d: %s",
+			w.Code, w.Body.String())
+	}
+}
+
+func TestPutMessage_Concurrent(t *testing.T) {
+	var sent int32
+	mailgun := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&sent, 1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"message": "Queued. Thank you.", `+
+				`"id": "<20181001143700.%d@example.com>"}`, n)
+		}))
+	defer mailgun.Close()
+
+	hash, err := tokenhash.New(testToken)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db := database.NewMemStore(database.ControlAccess)
+	err = db.Update(func(txn *database.Txn) error {
+		return txn.PutChannel(&protoed.Channel{Descriptor_: "client-1",
+			TokenHash:  hash,
+			Sender:     &protoed.Entity{Email: "johann.bach@composers.com"},
+			Recipients: []*protoed.Entity{{Email: "cpe.bach@composers.com"}},
+			Domain:     "example.com",
+			MaxSize:    1024,
+			RateLimit:  &protoed.RateLimit{Rate: 0.001, Burst: 3}})
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.RelayAccess
+
+	h := newTestHandler(db, mailgun.URL)
+
+	const requests = 6
+
+	statuses := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = serve(h, "POST", "/api/message", validMessage,
+				map[string]string{"X-Descriptor": "client-1",
+					"X-Token": testToken}).Code
+		}(i)
+	}
+	wg.Wait()
+
+	relayed := 0
+	for _, status := range statuses {
+		switch status {
+		case http.StatusOK:
+			relayed++
+		case http.StatusTooManyRequests:
+		default:
+			t.Errorf("expected 200 or 429, got %d", status)
+		}
+	}
+
+	if n := atomic.LoadInt32(&sent); relayed != 3 || n != 3 {
+		t.Errorf("expected the burst of 3 messages to be relayed, "+
+			"got %d relayed and %d sent", relayed, n)
+	}
+}
+
+func TestPutMessage_Refused(t *testing.T) {
+	mailgun := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("expected no message sent to MailGun")
+		}))
+	defer mailgun.Close()
+
+	hash, err := tokenhash.New(testToken)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	now := time.Now()
+	channels := []*protoed.Channel{
+		{Descriptor_: "client-disabled",
+			Disabled: &protoed.Disabled{Time: now.UnixNano(),
+				Reason: "unpaid invoice"}},
+		{Descriptor_: "client-expired",
+			ValidUntil: now.Add(-time.Hour).UnixNano()},
+		{Descriptor_: "client-1"}}
+
+	db := database.NewMemStore(database.ControlAccess)
+	err = db.Update(func(txn *database.Txn) (txnErr error) {
+		for _, channel := range channels {
+			channel.TokenHash = hash
+			channel.Sender = &protoed.Entity{Email: "johann.bach@composers.com"}
+			channel.Recipients = []*protoed.Entity{
+				{Email: "cpe.bach@composers.com"}}
+			channel.Domain = "example.com"
+
+			txnErr = txn.PutChannel(channel)
+			if txnErr != nil {
+				return
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.RelayAccess
+
+	h := newTestHandler(db, mailgun.URL)
+	h.Lockout = database.LockoutPolicy{Threshold: 2, Base: time.Minute,
+		Max: time.Hour, Forget: time.Hour}
+
+	for _, tc := range []struct {
+		descriptor string
+		token      string
+		status     int
+		header     string
+		value      string
+	}{
+		{"client-disabled", testToken, http.StatusLocked,
+			"X-Disabled-Reason", "unpaid invoice"},
+		{"client-expired", testToken, http.StatusGone, "", ""},
+		{"client-1", "invalid-token", http.StatusForbidden, "", ""},
+		{"client-1", "invalid-token", http.StatusForbidden, "", ""},
+		// Even the valid token is refused during the lockout of the IP.
+		{"client-1", testToken, http.StatusTooManyRequests,
+			"Retry-After", "60"}} {
+		w := serve(h, "POST", "/api/message", validMessage,
+			map[string]string{"X-Descriptor": tc.descriptor,
+				"X-Token": tc.token})
+		if w.Code != tc.status {
+			t.Fatalf("expected the status %d for %s, got %d: %s",
+				tc.status, tc.descriptor, w.Code, w.Body.String())
+		}
+
+		if tc.header != "" {
+			if got := w.Header().Get(tc.header); got != tc.value {
+				t.Errorf("expected %s of %#v, got %#v",
+					tc.header, tc.value, got)
+			}
+		}
+	}
+
+	var outcomes []protoed.RelayRecord_Outcome
+	err = db.View(func(txn *database.Txn) error {
+		records, _, err := txn.RelayRecords("client-1", time.Time{},
+			time.Time{}, 100)
+		for _, record := range records {
+			outcomes = append(outcomes, record.Outcome)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := []protoed.RelayRecord_Outcome{
+		protoed.RelayRecord_FORBIDDEN,
+		protoed.RelayRecord_FORBIDDEN,
+		protoed.RelayRecord_LOCKED_OUT}
+	if fmt.Sprint(outcomes) != fmt.Sprint(expected) {
+		t.Errorf("expected the outcomes %v, got %v", expected, outcomes)
+	}
+}
+
+func TestPutMessage_Queue(t *testing.T) {
+	var sent int32
+	mailgun := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&sent, 1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"message": "Queued. Thank you.", `+
+				`"id": "<20181001143700.%d@example.com>"}`, n)
+		}))
+	defer mailgun.Close()
+
+	hash, err := tokenhash.New(testToken)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db := database.NewMemStore(database.ControlAccess)
+	err = db.Update(func(txn *database.Txn) error {
+		return txn.PutChannel(&protoed.Channel{Descriptor_: "client-1",
+			TokenHash:  hash,
+			Sender:     &protoed.Entity{Email: "johann.bach@composers.com"},
+			Recipients: []*protoed.Entity{{Email: "cpe.bach@composers.com"}},
+			Domain:     "example.com",
+			MaxSize:    1024,
+			RateLimit:  &protoed.RateLimit{Rate: 0.001, Burst: 1},
+			OnThrottle: protoed.Channel_QUEUE})
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.RelayAccess
+
+	h := newTestHandler(db, mailgun.URL)
+	header := map[string]string{"X-Descriptor": "client-1",
+		"X-Token": testToken}
+
+	w := serve(h, "POST", "/api/message", validMessage, header)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status 200, got %d: %s",
+			w.Code, w.Body.String())
+	}
+
+	// The message exceeding the rate limit is queued.
+	w = serve(h, "POST", "/api/message", validMessage, header)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected the status 202, got %d: %s",
+			w.Code, w.Body.String())
+	}
+
+	id := w.Header().Get("X-Queue-Id")
+	if _, err := database.ParseQueueID(id); err != nil {
+		t.Fatalf("expected a valid X-Queue-Id, got %#v", id)
+	}
+	if got := w.Header().Get("Location"); got != "/api/queue/"+id {
+		t.Errorf("expected the Location of the queued message, got %#v", got)
+	}
+
+	w = serve(h, "GET", "/api/queue/"+id, "", header)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status 200, got %d: %s",
+			w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"status":"queued"`) {
+		t.Errorf("expected the message to be queued, got %s", w.Body.String())
+	}
+
+	if n := atomic.LoadInt32(&sent); n != 1 {
+		t.Errorf("expected a single message sent to MailGun, got %d", n)
+	}
+}
+
+func TestPutMessage_DescriptorLockout(t *testing.T) {
+	mailgun := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"message": "Queued. Thank you.", `+
+				`"id": "<20181001143700.1@example.com>"}`)
+		}))
+	defer mailgun.Close()
+
+	hash, err := tokenhash.New(testToken)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db := database.NewMemStore(database.ControlAccess)
+	err = db.Update(func(txn *database.Txn) error {
+		return txn.PutChannel(&protoed.Channel{Descriptor_: "client-1",
+			TokenHash:  hash,
+			Sender:     &protoed.Entity{Email: "johann.bach@composers.com"},
+			Recipients: []*protoed.Entity{{Email: "cpe.bach@composers.com"}},
+			Domain:     "example.com",
+			MaxSize:    1024})
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db.Access = database.RelayAccess
+
+	h := newTestHandler(db, mailgun.URL)
+	h.Lockout = database.LockoutPolicy{Threshold: 2, Base: time.Minute,
+		Max: time.Hour, Forget: time.Hour}
+	h.LockoutDescriptors = true
+
+	for _, tc := range []struct {
+		remoteAddr string
+		token      string
+		status     int
+	}{
+		// The guesses from different IPs lock the descriptor out.
+		{"192.0.2.1:1234", "invalid-token", http.StatusForbidden},
+		{"192.0.2.2:1234", "invalid-token", http.StatusForbidden},
+		{"192.0.2.3:1234", "invalid-token", http.StatusTooManyRequests},
+		// The legitimate sender is not locked out.
+		{"198.51.100.1:1234", testToken, http.StatusOK},
+		// The successful authentication lifted the lockout.
+		{"198.51.100.1:1234", "invalid-token", http.StatusForbidden}} {
+		r := httptest.NewRequest("POST", "/api/message",
+			strings.NewReader(validMessage))
+		r.RemoteAddr = tc.remoteAddr
+		r.Header.Set("X-Descriptor", "client-1")
+		r.Header.Set("X-Token", tc.token)
+
+		w := httptest.NewRecorder()
+		SetupRouter(h).ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Fatalf("expected the status %d from %s, got %d: %s",
+				tc.status, tc.remoteAddr, w.Code, w.Body.String())
+		}
+
+		if w.Code == http.StatusTooManyRequests &&
+			w.Header().Get("Retry-After") == "" {
+			t.Errorf("expected the Retry-After header of the lockout")
+		}
+	}
+}
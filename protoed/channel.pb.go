@@ -22,27 +22,212 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// enumerates how the messages exceeding the rate limit are handled.
+type Channel_OnThrottle int32
+
+const (
+	Channel_REJECT Channel_OnThrottle = 0
+	Channel_QUEUE  Channel_OnThrottle = 1
+)
+
+var Channel_OnThrottle_name = map[int32]string{
+	0: "REJECT",
+	1: "QUEUE",
+}
+var Channel_OnThrottle_value = map[string]int32{
+	"REJECT": 0,
+	"QUEUE":  1,
+}
+
+func (x Channel_OnThrottle) String() string {
+	return proto.EnumName(Channel_OnThrottle_name, int32(x))
+}
+func (Channel_OnThrottle) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{0, 0}
+}
+
+// enumerates the states of a queued message.
+type QueuedMessage_Status int32
+
+const (
+	QueuedMessage_QUEUED  QueuedMessage_Status = 0
+	QueuedMessage_RELAYED QueuedMessage_Status = 1
+	QueuedMessage_FAILED  QueuedMessage_Status = 2
+)
+
+var QueuedMessage_Status_name = map[int32]string{
+	0: "QUEUED",
+	1: "RELAYED",
+	2: "FAILED",
+}
+var QueuedMessage_Status_value = map[string]int32{
+	"QUEUED":  0,
+	"RELAYED": 1,
+	"FAILED":  2,
+}
+
+func (x QueuedMessage_Status) String() string {
+	return proto.EnumName(QueuedMessage_Status_name, int32(x))
+}
+func (QueuedMessage_Status) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{7, 0}
+}
+
+// enumerates the supported hashing schemes.
+type TokenHash_Version int32
+
+const (
+	TokenHash_UNKNOWN  TokenHash_Version = 0
+	TokenHash_ARGON2ID TokenHash_Version = 1
+)
+
+var TokenHash_Version_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "ARGON2ID",
+}
+var TokenHash_Version_value = map[string]int32{
+	"UNKNOWN":  0,
+	"ARGON2ID": 1,
+}
+
+func (x TokenHash_Version) String() string {
+	return proto.EnumName(TokenHash_Version_name, int32(x))
+}
+func (TokenHash_Version) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{9, 0}
+}
+
+// enumerates the outcomes of a relay attempt.
+type RelayRecord_Outcome int32
+
+const (
+	RelayRecord_UNKNOWN        RelayRecord_Outcome = 0
+	RelayRecord_RELAYED        RelayRecord_Outcome = 1
+	RelayRecord_FORBIDDEN      RelayRecord_Outcome = 2
+	RelayRecord_TOO_SOON       RelayRecord_Outcome = 3
+	RelayRecord_TOO_LARGE      RelayRecord_Outcome = 4
+	RelayRecord_INVALID        RelayRecord_Outcome = 5
+	RelayRecord_FAILED         RelayRecord_Outcome = 6
+	RelayRecord_DISABLED       RelayRecord_Outcome = 7
+	RelayRecord_EXPIRED        RelayRecord_Outcome = 8
+	RelayRecord_QUOTA_EXCEEDED RelayRecord_Outcome = 9
+	RelayRecord_THROTTLED      RelayRecord_Outcome = 10
+	RelayRecord_LOCKED_OUT     RelayRecord_Outcome = 11
+	RelayRecord_QUEUED         RelayRecord_Outcome = 12
+	RelayRecord_QUEUE_FULL     RelayRecord_Outcome = 13
+)
+
+var RelayRecord_Outcome_name = map[int32]string{
+	0:  "UNKNOWN",
+	1:  "RELAYED",
+	2:  "FORBIDDEN",
+	3:  "TOO_SOON",
+	4:  "TOO_LARGE",
+	5:  "INVALID",
+	6:  "FAILED",
+	7:  "DISABLED",
+	8:  "EXPIRED",
+	9:  "QUOTA_EXCEEDED",
+	10: "THROTTLED",
+	11: "LOCKED_OUT",
+	12: "QUEUED",
+	13: "QUEUE_FULL",
+}
+var RelayRecord_Outcome_value = map[string]int32{
+	"UNKNOWN":        0,
+	"RELAYED":        1,
+	"FORBIDDEN":      2,
+	"TOO_SOON":       3,
+	"TOO_LARGE":      4,
+	"INVALID":        5,
+	"FAILED":         6,
+	"DISABLED":       7,
+	"EXPIRED":        8,
+	"QUOTA_EXCEEDED": 9,
+	"THROTTLED":      10,
+	"LOCKED_OUT":     11,
+	"QUEUED":         12,
+	"QUEUE_FULL":     13,
+}
+
+func (x RelayRecord_Outcome) String() string {
+	return proto.EnumName(RelayRecord_Outcome_name, int32(x))
+}
+func (RelayRecord_Outcome) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{11, 0}
+}
+
+// enumerates the operations on a channel.
+type AuditRecord_Operation int32
+
+const (
+	AuditRecord_UNKNOWN  AuditRecord_Operation = 0
+	AuditRecord_PUT      AuditRecord_Operation = 1
+	AuditRecord_DELETE   AuditRecord_Operation = 2
+	AuditRecord_ROLLBACK AuditRecord_Operation = 3
+	AuditRecord_DISABLE  AuditRecord_Operation = 4
+	AuditRecord_ENABLE   AuditRecord_Operation = 5
+	AuditRecord_ROTATE   AuditRecord_Operation = 6
+	AuditRecord_REVOKE   AuditRecord_Operation = 7
+)
+
+var AuditRecord_Operation_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "PUT",
+	2: "DELETE",
+	3: "ROLLBACK",
+	4: "DISABLE",
+	5: "ENABLE",
+	6: "ROTATE",
+	7: "REVOKE",
+}
+var AuditRecord_Operation_value = map[string]int32{
+	"UNKNOWN":  0,
+	"PUT":      1,
+	"DELETE":   2,
+	"ROLLBACK": 3,
+	"DISABLE":  4,
+	"ENABLE":   5,
+	"ROTATE":   6,
+	"REVOKE":   7,
+}
+
+func (x AuditRecord_Operation) String() string {
+	return proto.EnumName(AuditRecord_Operation_name, int32(x))
+}
+func (AuditRecord_Operation) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{13, 0}
+}
+
 // represents a messaging channel.
 type Channel struct {
-	Descriptor_          string    `protobuf:"bytes,1,opt,name=descriptor" json:"descriptor,omitempty"`
-	Token                string    `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
-	Sender               *Entity   `protobuf:"bytes,3,opt,name=sender" json:"sender,omitempty"`
-	Recipients           []*Entity `protobuf:"bytes,4,rep,name=recipients" json:"recipients,omitempty"`
-	Cc                   []*Entity `protobuf:"bytes,5,rep,name=cc" json:"cc,omitempty"`
-	Bcc                  []*Entity `protobuf:"bytes,6,rep,name=bcc" json:"bcc,omitempty"`
-	Domain               string    `protobuf:"bytes,7,opt,name=domain" json:"domain,omitempty"`
-	MinPeriod            float32   `protobuf:"fixed32,8,opt,name=min_period,json=minPeriod" json:"min_period,omitempty"`
-	MaxSize              int32     `protobuf:"varint,9,opt,name=max_size,json=maxSize" json:"max_size,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
-	XXX_unrecognized     []byte    `json:"-"`
-	XXX_sizecache        int32     `json:"-"`
+	Descriptor_          string             `protobuf:"bytes,1,opt,name=descriptor" json:"descriptor,omitempty"`
+	Token                string             `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
+	Sender               *Entity            `protobuf:"bytes,3,opt,name=sender" json:"sender,omitempty"`
+	Recipients           []*Entity          `protobuf:"bytes,4,rep,name=recipients" json:"recipients,omitempty"`
+	Cc                   []*Entity          `protobuf:"bytes,5,rep,name=cc" json:"cc,omitempty"`
+	Bcc                  []*Entity          `protobuf:"bytes,6,rep,name=bcc" json:"bcc,omitempty"`
+	Domain               string             `protobuf:"bytes,7,opt,name=domain" json:"domain,omitempty"`
+	MinPeriod            float32            `protobuf:"fixed32,8,opt,name=min_period,json=minPeriod" json:"min_period,omitempty"`
+	MaxSize              int32              `protobuf:"varint,9,opt,name=max_size,json=maxSize" json:"max_size,omitempty"`
+	TokenHash            *TokenHash         `protobuf:"bytes,10,opt,name=token_hash,json=tokenHash" json:"token_hash,omitempty"`
+	Disabled             *Disabled          `protobuf:"bytes,11,opt,name=disabled" json:"disabled,omitempty"`
+	ValidUntil           int64              `protobuf:"varint,12,opt,name=valid_until,json=validUntil" json:"valid_until,omitempty"`
+	Tokens               []*ChannelToken    `protobuf:"bytes,13,rep,name=tokens" json:"tokens,omitempty"`
+	RateLimit            *RateLimit         `protobuf:"bytes,14,opt,name=rate_limit,json=rateLimit" json:"rate_limit,omitempty"`
+	Quota                *Quota             `protobuf:"bytes,15,opt,name=quota" json:"quota,omitempty"`
+	OnThrottle           Channel_OnThrottle `protobuf:"varint,16,opt,name=on_throttle,json=onThrottle,enum=protoed.channel.Channel_OnThrottle" json:"on_throttle,omitempty"`
+	MaxQueueDepth        uint32             `protobuf:"varint,17,opt,name=max_queue_depth,json=maxQueueDepth" json:"max_queue_depth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
 }
 
 func (m *Channel) Reset()         { *m = Channel{} }
 func (m *Channel) String() string { return proto.CompactTextString(m) }
 func (*Channel) ProtoMessage()    {}
 func (*Channel) Descriptor() ([]byte, []int) {
-	return fileDescriptor_channel_3a2c204eaf1a3b56, []int{0}
+	return fileDescriptor_channel_3c87f561a468e095, []int{0}
 }
 func (m *Channel) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Channel.Unmarshal(m, b)
@@ -125,77 +310,1286 @@ func (m *Channel) GetMaxSize() int32 {
 	return 0
 }
 
-// represents a sender or recipient of an email.
-type Entity struct {
-	Email                string   `protobuf:"bytes,1,opt,name=email" json:"email,omitempty"`
-	Name                 string   `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+func (m *Channel) GetTokenHash() *TokenHash {
+	if m != nil {
+		return m.TokenHash
+	}
+	return nil
+}
+
+func (m *Channel) GetDisabled() *Disabled {
+	if m != nil {
+		return m.Disabled
+	}
+	return nil
+}
+
+func (m *Channel) GetValidUntil() int64 {
+	if m != nil {
+		return m.ValidUntil
+	}
+	return 0
+}
+
+func (m *Channel) GetTokens() []*ChannelToken {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
+func (m *Channel) GetRateLimit() *RateLimit {
+	if m != nil {
+		return m.RateLimit
+	}
+	return nil
+}
+
+func (m *Channel) GetQuota() *Quota {
+	if m != nil {
+		return m.Quota
+	}
+	return nil
+}
+
+func (m *Channel) GetOnThrottle() Channel_OnThrottle {
+	if m != nil {
+		return m.OnThrottle
+	}
+	return Channel_REJECT
+}
+
+func (m *Channel) GetMaxQueueDepth() uint32 {
+	if m != nil {
+		return m.MaxQueueDepth
+	}
+	return 0
+}
+
+// represents a named HTTP authentication token of a channel.
+type ChannelToken struct {
+	Name                 string     `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Hash                 *TokenHash `protobuf:"bytes,2,opt,name=hash" json:"hash,omitempty"`
+	Created              int64      `protobuf:"varint,3,opt,name=created" json:"created,omitempty"`
+	Expires              int64      `protobuf:"varint,4,opt,name=expires" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ChannelToken) Reset()         { *m = ChannelToken{} }
+func (m *ChannelToken) String() string { return proto.CompactTextString(m) }
+func (*ChannelToken) ProtoMessage()    {}
+func (*ChannelToken) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{1}
+}
+func (m *ChannelToken) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChannelToken.Unmarshal(m, b)
+}
+func (m *ChannelToken) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChannelToken.Marshal(b, m, deterministic)
+}
+func (dst *ChannelToken) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChannelToken.Merge(dst, src)
+}
+func (m *ChannelToken) XXX_Size() int {
+	return xxx_messageInfo_ChannelToken.Size(m)
+}
+func (m *ChannelToken) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChannelToken.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChannelToken proto.InternalMessageInfo
+
+func (m *ChannelToken) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ChannelToken) GetHash() *TokenHash {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *ChannelToken) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *ChannelToken) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+// represents a token bucket limiting the rate of the messages of a channel.
+type RateLimit struct {
+	Rate                 float32  `protobuf:"fixed32,1,opt,name=rate" json:"rate,omitempty"`
+	Burst                uint32   `protobuf:"varint,2,opt,name=burst" json:"burst,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Entity) Reset()         { *m = Entity{} }
-func (m *Entity) String() string { return proto.CompactTextString(m) }
-func (*Entity) ProtoMessage()    {}
-func (*Entity) Descriptor() ([]byte, []int) {
-	return fileDescriptor_channel_3a2c204eaf1a3b56, []int{1}
+func (m *RateLimit) Reset()         { *m = RateLimit{} }
+func (m *RateLimit) String() string { return proto.CompactTextString(m) }
+func (*RateLimit) ProtoMessage()    {}
+func (*RateLimit) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{2}
 }
-func (m *Entity) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Entity.Unmarshal(m, b)
+func (m *RateLimit) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RateLimit.Unmarshal(m, b)
 }
-func (m *Entity) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Entity.Marshal(b, m, deterministic)
+func (m *RateLimit) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RateLimit.Marshal(b, m, deterministic)
 }
-func (dst *Entity) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Entity.Merge(dst, src)
+func (dst *RateLimit) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RateLimit.Merge(dst, src)
 }
-func (m *Entity) XXX_Size() int {
-	return xxx_messageInfo_Entity.Size(m)
+func (m *RateLimit) XXX_Size() int {
+	return xxx_messageInfo_RateLimit.Size(m)
 }
-func (m *Entity) XXX_DiscardUnknown() {
-	xxx_messageInfo_Entity.DiscardUnknown(m)
+func (m *RateLimit) XXX_DiscardUnknown() {
+	xxx_messageInfo_RateLimit.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Entity proto.InternalMessageInfo
+var xxx_messageInfo_RateLimit proto.InternalMessageInfo
 
-func (m *Entity) GetEmail() string {
+func (m *RateLimit) GetRate() float32 {
 	if m != nil {
-		return m.Email
+		return m.Rate
+	}
+	return 0
+}
+
+func (m *RateLimit) GetBurst() uint32 {
+	if m != nil {
+		return m.Burst
+	}
+	return 0
+}
+
+// represents the quotas of a channel per calendar day and month.
+type Quota struct {
+	DailyMessages        uint64   `protobuf:"varint,1,opt,name=daily_messages,json=dailyMessages" json:"daily_messages,omitempty"`
+	MonthlyMessages      uint64   `protobuf:"varint,2,opt,name=monthly_messages,json=monthlyMessages" json:"monthly_messages,omitempty"`
+	DailyBytes           uint64   `protobuf:"varint,3,opt,name=daily_bytes,json=dailyBytes" json:"daily_bytes,omitempty"`
+	MonthlyBytes         uint64   `protobuf:"varint,4,opt,name=monthly_bytes,json=monthlyBytes" json:"monthly_bytes,omitempty"`
+	Timezone             string   `protobuf:"bytes,5,opt,name=timezone" json:"timezone,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Quota) Reset()         { *m = Quota{} }
+func (m *Quota) String() string { return proto.CompactTextString(m) }
+func (*Quota) ProtoMessage()    {}
+func (*Quota) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{3}
+}
+func (m *Quota) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Quota.Unmarshal(m, b)
+}
+func (m *Quota) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Quota.Marshal(b, m, deterministic)
+}
+func (dst *Quota) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Quota.Merge(dst, src)
+}
+func (m *Quota) XXX_Size() int {
+	return xxx_messageInfo_Quota.Size(m)
+}
+func (m *Quota) XXX_DiscardUnknown() {
+	xxx_messageInfo_Quota.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Quota proto.InternalMessageInfo
+
+func (m *Quota) GetDailyMessages() uint64 {
+	if m != nil {
+		return m.DailyMessages
+	}
+	return 0
+}
+
+func (m *Quota) GetMonthlyMessages() uint64 {
+	if m != nil {
+		return m.MonthlyMessages
+	}
+	return 0
+}
+
+func (m *Quota) GetDailyBytes() uint64 {
+	if m != nil {
+		return m.DailyBytes
+	}
+	return 0
+}
+
+func (m *Quota) GetMonthlyBytes() uint64 {
+	if m != nil {
+		return m.MonthlyBytes
+	}
+	return 0
+}
+
+func (m *Quota) GetTimezone() string {
+	if m != nil {
+		return m.Timezone
 	}
 	return ""
 }
 
-func (m *Entity) GetName() string {
+// represents the messages counted against the quota of a channel in a period.
+type QuotaCounters struct {
+	Start                int64    `protobuf:"varint,1,opt,name=start" json:"start,omitempty"`
+	Messages             uint64   `protobuf:"varint,2,opt,name=messages" json:"messages,omitempty"`
+	Bytes                uint64   `protobuf:"varint,3,opt,name=bytes" json:"bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QuotaCounters) Reset()         { *m = QuotaCounters{} }
+func (m *QuotaCounters) String() string { return proto.CompactTextString(m) }
+func (*QuotaCounters) ProtoMessage()    {}
+func (*QuotaCounters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{4}
+}
+func (m *QuotaCounters) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QuotaCounters.Unmarshal(m, b)
+}
+func (m *QuotaCounters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QuotaCounters.Marshal(b, m, deterministic)
+}
+func (dst *QuotaCounters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QuotaCounters.Merge(dst, src)
+}
+func (m *QuotaCounters) XXX_Size() int {
+	return xxx_messageInfo_QuotaCounters.Size(m)
+}
+func (m *QuotaCounters) XXX_DiscardUnknown() {
+	xxx_messageInfo_QuotaCounters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QuotaCounters proto.InternalMessageInfo
+
+func (m *QuotaCounters) GetStart() int64 {
 	if m != nil {
-		return m.Name
+		return m.Start
+	}
+	return 0
+}
+
+func (m *QuotaCounters) GetMessages() uint64 {
+	if m != nil {
+		return m.Messages
+	}
+	return 0
+}
+
+func (m *QuotaCounters) GetBytes() uint64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+// represents the state of the token bucket of a channel.
+type RateState struct {
+	Time                 int64    `protobuf:"varint,1,opt,name=time" json:"time,omitempty"`
+	Allowance            float64  `protobuf:"fixed64,2,opt,name=allowance" json:"allowance,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RateState) Reset()         { *m = RateState{} }
+func (m *RateState) String() string { return proto.CompactTextString(m) }
+func (*RateState) ProtoMessage()    {}
+func (*RateState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{5}
+}
+func (m *RateState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RateState.Unmarshal(m, b)
+}
+func (m *RateState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RateState.Marshal(b, m, deterministic)
+}
+func (dst *RateState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RateState.Merge(dst, src)
+}
+func (m *RateState) XXX_Size() int {
+	return xxx_messageInfo_RateState.Size(m)
+}
+func (m *RateState) XXX_DiscardUnknown() {
+	xxx_messageInfo_RateState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RateState proto.InternalMessageInfo
+
+func (m *RateState) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *RateState) GetAllowance() float64 {
+	if m != nil {
+		return m.Allowance
+	}
+	return 0
+}
+
+// represents the failed authentications of a remote IP or of a descriptor.
+type Lockout struct {
+	Kind                 string   `protobuf:"bytes,1,opt,name=kind" json:"kind,omitempty"`
+	Subject              string   `protobuf:"bytes,2,opt,name=subject" json:"subject,omitempty"`
+	Failures             uint32   `protobuf:"varint,3,opt,name=failures" json:"failures,omitempty"`
+	LastFailure          int64    `protobuf:"varint,4,opt,name=last_failure,json=lastFailure" json:"last_failure,omitempty"`
+	LockedUntil          int64    `protobuf:"varint,5,opt,name=locked_until,json=lockedUntil" json:"locked_until,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Lockout) Reset()         { *m = Lockout{} }
+func (m *Lockout) String() string { return proto.CompactTextString(m) }
+func (*Lockout) ProtoMessage()    {}
+func (*Lockout) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{6}
+}
+func (m *Lockout) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Lockout.Unmarshal(m, b)
+}
+func (m *Lockout) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Lockout.Marshal(b, m, deterministic)
+}
+func (dst *Lockout) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Lockout.Merge(dst, src)
+}
+func (m *Lockout) XXX_Size() int {
+	return xxx_messageInfo_Lockout.Size(m)
+}
+func (m *Lockout) XXX_DiscardUnknown() {
+	xxx_messageInfo_Lockout.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Lockout proto.InternalMessageInfo
+
+func (m *Lockout) GetKind() string {
+	if m != nil {
+		return m.Kind
 	}
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*Channel)(nil), "protoed.channel.Channel")
-	proto.RegisterType((*Entity)(nil), "protoed.channel.Entity")
+func (m *Lockout) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *Lockout) GetFailures() uint32 {
+	if m != nil {
+		return m.Failures
+	}
+	return 0
+}
+
+func (m *Lockout) GetLastFailure() int64 {
+	if m != nil {
+		return m.LastFailure
+	}
+	return 0
 }
 
-func init() { proto.RegisterFile("channel.proto", fileDescriptor_channel_3a2c204eaf1a3b56) }
-
-var fileDescriptor_channel_3a2c204eaf1a3b56 = []byte{
-	// 263 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x90, 0xc1, 0x4a, 0xc3, 0x40,
-	0x10, 0x86, 0x49, 0xda, 0x24, 0xcd, 0x88, 0x08, 0x83, 0xe8, 0x7a, 0x50, 0x42, 0x2f, 0xc6, 0x4b,
-	0x84, 0x7a, 0xf0, 0x01, 0xc4, 0xbb, 0xc4, 0x07, 0x28, 0xdb, 0xcd, 0x80, 0x83, 0xdd, 0xd9, 0xb0,
-	0xd9, 0x43, 0xed, 0x4b, 0xfa, 0x4a, 0xd2, 0x4d, 0x0e, 0xc5, 0x43, 0x4e, 0x3b, 0xff, 0xbf, 0xdf,
-	0xf0, 0xef, 0xfe, 0x70, 0x69, 0xbe, 0xb4, 0x08, 0xed, 0x9b, 0xde, 0xbb, 0xe0, 0xf0, 0x2a, 0x1e,
-	0xd4, 0x35, 0x93, 0xbd, 0xfe, 0x4d, 0xa1, 0x78, 0x1b, 0x67, 0x7c, 0x00, 0xe8, 0x68, 0x30, 0x9e,
-	0xfb, 0xe0, 0xbc, 0x4a, 0xaa, 0xa4, 0x2e, 0xdb, 0x33, 0x07, 0xaf, 0x21, 0x0b, 0xee, 0x9b, 0x44,
-	0xa5, 0xf1, 0x6a, 0x14, 0xf8, 0x0c, 0xf9, 0x40, 0xd2, 0x91, 0x57, 0x8b, 0x2a, 0xa9, 0x2f, 0x36,
-	0xb7, 0xcd, 0xbf, 0x8c, 0xe6, 0x5d, 0x02, 0x87, 0x9f, 0x76, 0xc2, 0xf0, 0x15, 0xc0, 0x93, 0xe1,
-	0x9e, 0x49, 0xc2, 0xa0, 0x96, 0xd5, 0x62, 0x6e, 0xe9, 0x0c, 0xc5, 0x47, 0x48, 0x8d, 0x51, 0xd9,
-	0xfc, 0x42, 0x6a, 0x0c, 0x3e, 0xc1, 0x62, 0x67, 0x8c, 0xca, 0xe7, 0xc9, 0x13, 0x83, 0x37, 0x90,
-	0x77, 0xce, 0x6a, 0x16, 0x55, 0xc4, 0x4f, 0x4d, 0x0a, 0xef, 0x01, 0x2c, 0xcb, 0xb6, 0x27, 0xcf,
-	0xae, 0x53, 0xab, 0x2a, 0xa9, 0xd3, 0xb6, 0xb4, 0x2c, 0x1f, 0xd1, 0xc0, 0x3b, 0x58, 0x59, 0x7d,
-	0xd8, 0x0e, 0x7c, 0x24, 0x55, 0x56, 0x49, 0x9d, 0xb5, 0x85, 0xd5, 0x87, 0x4f, 0x3e, 0xd2, 0x7a,
-	0x03, 0xf9, 0x18, 0x70, 0xea, 0x8b, 0xac, 0xe6, 0xfd, 0x54, 0xe5, 0x28, 0x10, 0x61, 0x29, 0xda,
-	0xd2, 0x54, 0x62, 0x9c, 0x77, 0x79, 0x7c, 0xe2, 0xcb, 0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xfb,
-	0x0f, 0x00, 0x6e, 0xae, 0x01, 0x00, 0x00,
+func (m *Lockout) GetLockedUntil() int64 {
+	if m != nil {
+		return m.LockedUntil
+	}
+	return 0
+}
+
+// represents a message queued for the deferred relay since it exceeded the rate limit of its channel.
+type QueuedMessage struct {
+	Id                   uint64               `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Descriptor_          string               `protobuf:"bytes,2,opt,name=descriptor" json:"descriptor,omitempty"`
+	Enqueued             int64                `protobuf:"varint,3,opt,name=enqueued" json:"enqueued,omitempty"`
+	Message              []byte               `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Subject              string               `protobuf:"bytes,5,opt,name=subject" json:"subject,omitempty"`
+	Size                 int64                `protobuf:"varint,6,opt,name=size" json:"size,omitempty"`
+	Token                string               `protobuf:"bytes,7,opt,name=token" json:"token,omitempty"`
+	Status               QueuedMessage_Status `protobuf:"varint,8,opt,name=status,enum=protoed.channel.QueuedMessage_Status" json:"status,omitempty"`
+	Attempts             uint32               `protobuf:"varint,9,opt,name=attempts" json:"attempts,omitempty"`
+	RetryAt              int64                `protobuf:"varint,10,opt,name=retry_at,json=retryAt" json:"retry_at,omitempty"`
+	Error                string               `protobuf:"bytes,11,opt,name=error" json:"error,omitempty"`
+	Finished             int64                `protobuf:"varint,12,opt,name=finished" json:"finished,omitempty"`
+	MessageId            string               `protobuf:"bytes,13,opt,name=message_id,json=messageId" json:"message_id,omitempty"`
+	Deferred             string               `protobuf:"bytes,14,opt,name=deferred" json:"deferred,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *QueuedMessage) Reset()         { *m = QueuedMessage{} }
+func (m *QueuedMessage) String() string { return proto.CompactTextString(m) }
+func (*QueuedMessage) ProtoMessage()    {}
+func (*QueuedMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{7}
+}
+func (m *QueuedMessage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueuedMessage.Unmarshal(m, b)
+}
+func (m *QueuedMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueuedMessage.Marshal(b, m, deterministic)
+}
+func (dst *QueuedMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueuedMessage.Merge(dst, src)
+}
+func (m *QueuedMessage) XXX_Size() int {
+	return xxx_messageInfo_QueuedMessage.Size(m)
+}
+func (m *QueuedMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueuedMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueuedMessage proto.InternalMessageInfo
+
+func (m *QueuedMessage) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *QueuedMessage) GetDescriptor_() string {
+	if m != nil {
+		return m.Descriptor_
+	}
+	return ""
+}
+
+func (m *QueuedMessage) GetEnqueued() int64 {
+	if m != nil {
+		return m.Enqueued
+	}
+	return 0
+}
+
+func (m *QueuedMessage) GetMessage() []byte {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *QueuedMessage) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *QueuedMessage) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *QueuedMessage) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *QueuedMessage) GetStatus() QueuedMessage_Status {
+	if m != nil {
+		return m.Status
+	}
+	return QueuedMessage_QUEUED
+}
+
+func (m *QueuedMessage) GetAttempts() uint32 {
+	if m != nil {
+		return m.Attempts
+	}
+	return 0
+}
+
+func (m *QueuedMessage) GetRetryAt() int64 {
+	if m != nil {
+		return m.RetryAt
+	}
+	return 0
+}
+
+func (m *QueuedMessage) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *QueuedMessage) GetFinished() int64 {
+	if m != nil {
+		return m.Finished
+	}
+	return 0
+}
+
+func (m *QueuedMessage) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
+}
+
+func (m *QueuedMessage) GetDeferred() string {
+	if m != nil {
+		return m.Deferred
+	}
+	return ""
+}
+
+// represents that the channel has been disabled and relays no messages.
+type Disabled struct {
+	Time                 int64    `protobuf:"varint,1,opt,name=time" json:"time,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Disabled) Reset()         { *m = Disabled{} }
+func (m *Disabled) String() string { return proto.CompactTextString(m) }
+func (*Disabled) ProtoMessage()    {}
+func (*Disabled) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{8}
+}
+func (m *Disabled) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Disabled.Unmarshal(m, b)
+}
+func (m *Disabled) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Disabled.Marshal(b, m, deterministic)
+}
+func (dst *Disabled) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Disabled.Merge(dst, src)
+}
+func (m *Disabled) XXX_Size() int {
+	return xxx_messageInfo_Disabled.Size(m)
+}
+func (m *Disabled) XXX_DiscardUnknown() {
+	xxx_messageInfo_Disabled.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Disabled proto.InternalMessageInfo
+
+func (m *Disabled) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *Disabled) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// represents a salted hash of an authentication token.
+type TokenHash struct {
+	Version              TokenHash_Version `protobuf:"varint,1,opt,name=version,enum=protoed.channel.TokenHash_Version" json:"version,omitempty"`
+	Salt                 []byte            `protobuf:"bytes,2,opt,name=salt,proto3" json:"salt,omitempty"`
+	Hash                 []byte            `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	Time                 uint32            `protobuf:"varint,4,opt,name=time" json:"time,omitempty"`
+	Memory               uint32            `protobuf:"varint,5,opt,name=memory" json:"memory,omitempty"`
+	Threads              uint32            `protobuf:"varint,6,opt,name=threads" json:"threads,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *TokenHash) Reset()         { *m = TokenHash{} }
+func (m *TokenHash) String() string { return proto.CompactTextString(m) }
+func (*TokenHash) ProtoMessage()    {}
+func (*TokenHash) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{9}
+}
+func (m *TokenHash) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TokenHash.Unmarshal(m, b)
+}
+func (m *TokenHash) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TokenHash.Marshal(b, m, deterministic)
+}
+func (dst *TokenHash) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TokenHash.Merge(dst, src)
+}
+func (m *TokenHash) XXX_Size() int {
+	return xxx_messageInfo_TokenHash.Size(m)
+}
+func (m *TokenHash) XXX_DiscardUnknown() {
+	xxx_messageInfo_TokenHash.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TokenHash proto.InternalMessageInfo
+
+func (m *TokenHash) GetVersion() TokenHash_Version {
+	if m != nil {
+		return m.Version
+	}
+	return TokenHash_UNKNOWN
+}
+
+func (m *TokenHash) GetSalt() []byte {
+	if m != nil {
+		return m.Salt
+	}
+	return nil
+}
+
+func (m *TokenHash) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *TokenHash) GetTime() uint32 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *TokenHash) GetMemory() uint32 {
+	if m != nil {
+		return m.Memory
+	}
+	return 0
+}
+
+func (m *TokenHash) GetThreads() uint32 {
+	if m != nil {
+		return m.Threads
+	}
+	return 0
+}
+
+// represents a sender or recipient of an email.
+type Entity struct {
+	Email                string   `protobuf:"bytes,1,opt,name=email" json:"email,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Entity) Reset()         { *m = Entity{} }
+func (m *Entity) String() string { return proto.CompactTextString(m) }
+func (*Entity) ProtoMessage()    {}
+func (*Entity) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{10}
+}
+func (m *Entity) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Entity.Unmarshal(m, b)
+}
+func (m *Entity) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Entity.Marshal(b, m, deterministic)
+}
+func (dst *Entity) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Entity.Merge(dst, src)
+}
+func (m *Entity) XXX_Size() int {
+	return xxx_messageInfo_Entity.Size(m)
+}
+func (m *Entity) XXX_DiscardUnknown() {
+	xxx_messageInfo_Entity.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Entity proto.InternalMessageInfo
+
+func (m *Entity) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *Entity) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+// represents an attempt to relay a message through a channel.
+type RelayRecord struct {
+	Descriptor_          string              `protobuf:"bytes,1,opt,name=descriptor" json:"descriptor,omitempty"`
+	Time                 int64               `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	Subject              string              `protobuf:"bytes,3,opt,name=subject" json:"subject,omitempty"`
+	Size                 int64               `protobuf:"varint,4,opt,name=size" json:"size,omitempty"`
+	Outcome              RelayRecord_Outcome `protobuf:"varint,5,opt,name=outcome,enum=protoed.channel.RelayRecord_Outcome" json:"outcome,omitempty"`
+	Status               int32               `protobuf:"varint,6,opt,name=status" json:"status,omitempty"`
+	MessageId            string              `protobuf:"bytes,7,opt,name=message_id,json=messageId" json:"message_id,omitempty"`
+	Token                string              `protobuf:"bytes,8,opt,name=token" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *RelayRecord) Reset()         { *m = RelayRecord{} }
+func (m *RelayRecord) String() string { return proto.CompactTextString(m) }
+func (*RelayRecord) ProtoMessage()    {}
+func (*RelayRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{11}
+}
+func (m *RelayRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RelayRecord.Unmarshal(m, b)
+}
+func (m *RelayRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RelayRecord.Marshal(b, m, deterministic)
+}
+func (dst *RelayRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RelayRecord.Merge(dst, src)
+}
+func (m *RelayRecord) XXX_Size() int {
+	return xxx_messageInfo_RelayRecord.Size(m)
+}
+func (m *RelayRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_RelayRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RelayRecord proto.InternalMessageInfo
+
+func (m *RelayRecord) GetDescriptor_() string {
+	if m != nil {
+		return m.Descriptor_
+	}
+	return ""
+}
+
+func (m *RelayRecord) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *RelayRecord) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *RelayRecord) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *RelayRecord) GetOutcome() RelayRecord_Outcome {
+	if m != nil {
+		return m.Outcome
+	}
+	return RelayRecord_UNKNOWN
+}
+
+func (m *RelayRecord) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *RelayRecord) GetMessageId() string {
+	if m != nil {
+		return m.MessageId
+	}
+	return ""
+}
+
+func (m *RelayRecord) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+// counts the relay attempts through a channel within an hour or a day.
+type UsageCounters struct {
+	Start                int64    `protobuf:"varint,1,opt,name=start" json:"start,omitempty"`
+	Relayed              uint64   `protobuf:"varint,2,opt,name=relayed" json:"relayed,omitempty"`
+	Bytes                uint64   `protobuf:"varint,3,opt,name=bytes" json:"bytes,omitempty"`
+	Forbidden            uint64   `protobuf:"varint,4,opt,name=forbidden" json:"forbidden,omitempty"`
+	TooSoon              uint64   `protobuf:"varint,5,opt,name=too_soon,json=tooSoon" json:"too_soon,omitempty"`
+	TooLarge             uint64   `protobuf:"varint,6,opt,name=too_large,json=tooLarge" json:"too_large,omitempty"`
+	Invalid              uint64   `protobuf:"varint,7,opt,name=invalid" json:"invalid,omitempty"`
+	Failed               uint64   `protobuf:"varint,8,opt,name=failed" json:"failed,omitempty"`
+	Disabled             uint64   `protobuf:"varint,9,opt,name=disabled" json:"disabled,omitempty"`
+	Expired              uint64   `protobuf:"varint,10,opt,name=expired" json:"expired,omitempty"`
+	QuotaExceeded        uint64   `protobuf:"varint,11,opt,name=quota_exceeded,json=quotaExceeded" json:"quota_exceeded,omitempty"`
+	Throttled            uint64   `protobuf:"varint,12,opt,name=throttled" json:"throttled,omitempty"`
+	LockedOut            uint64   `protobuf:"varint,13,opt,name=locked_out,json=lockedOut" json:"locked_out,omitempty"`
+	Queued               uint64   `protobuf:"varint,14,opt,name=queued" json:"queued,omitempty"`
+	QueueFull            uint64   `protobuf:"varint,15,opt,name=queue_full,json=queueFull" json:"queue_full,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UsageCounters) Reset()         { *m = UsageCounters{} }
+func (m *UsageCounters) String() string { return proto.CompactTextString(m) }
+func (*UsageCounters) ProtoMessage()    {}
+func (*UsageCounters) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{12}
+}
+func (m *UsageCounters) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UsageCounters.Unmarshal(m, b)
+}
+func (m *UsageCounters) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UsageCounters.Marshal(b, m, deterministic)
+}
+func (dst *UsageCounters) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UsageCounters.Merge(dst, src)
+}
+func (m *UsageCounters) XXX_Size() int {
+	return xxx_messageInfo_UsageCounters.Size(m)
+}
+func (m *UsageCounters) XXX_DiscardUnknown() {
+	xxx_messageInfo_UsageCounters.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UsageCounters proto.InternalMessageInfo
+
+func (m *UsageCounters) GetStart() int64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetRelayed() uint64 {
+	if m != nil {
+		return m.Relayed
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetBytes() uint64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetForbidden() uint64 {
+	if m != nil {
+		return m.Forbidden
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetTooSoon() uint64 {
+	if m != nil {
+		return m.TooSoon
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetTooLarge() uint64 {
+	if m != nil {
+		return m.TooLarge
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetInvalid() uint64 {
+	if m != nil {
+		return m.Invalid
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetFailed() uint64 {
+	if m != nil {
+		return m.Failed
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetDisabled() uint64 {
+	if m != nil {
+		return m.Disabled
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetExpired() uint64 {
+	if m != nil {
+		return m.Expired
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetQuotaExceeded() uint64 {
+	if m != nil {
+		return m.QuotaExceeded
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetThrottled() uint64 {
+	if m != nil {
+		return m.Throttled
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetLockedOut() uint64 {
+	if m != nil {
+		return m.LockedOut
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetQueued() uint64 {
+	if m != nil {
+		return m.Queued
+	}
+	return 0
+}
+
+func (m *UsageCounters) GetQueueFull() uint64 {
+	if m != nil {
+		return m.QueueFull
+	}
+	return 0
+}
+
+// represents a change of a channel through the control plane.
+type AuditRecord struct {
+	Time                 int64                 `protobuf:"varint,1,opt,name=time" json:"time,omitempty"`
+	Descriptor_          string                `protobuf:"bytes,2,opt,name=descriptor" json:"descriptor,omitempty"`
+	Operation            AuditRecord_Operation `protobuf:"varint,3,opt,name=operation,enum=protoed.channel.AuditRecord_Operation" json:"operation,omitempty"`
+	Actor                string                `protobuf:"bytes,4,opt,name=actor" json:"actor,omitempty"`
+	RemoteAddr           string                `protobuf:"bytes,5,opt,name=remote_addr,json=remoteAddr" json:"remote_addr,omitempty"`
+	ForwardedFor         string                `protobuf:"bytes,6,opt,name=forwarded_for,json=forwardedFor" json:"forwarded_for,omitempty"`
+	UserAgent            string                `protobuf:"bytes,7,opt,name=user_agent,json=userAgent" json:"user_agent,omitempty"`
+	Before               *Channel              `protobuf:"bytes,8,opt,name=before" json:"before,omitempty"`
+	After                *Channel              `protobuf:"bytes,9,opt,name=after" json:"after,omitempty"`
+	TokenChanged         bool                  `protobuf:"varint,10,opt,name=token_changed,json=tokenChanged" json:"token_changed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *AuditRecord) Reset()         { *m = AuditRecord{} }
+func (m *AuditRecord) String() string { return proto.CompactTextString(m) }
+func (*AuditRecord) ProtoMessage()    {}
+func (*AuditRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{13}
+}
+func (m *AuditRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditRecord.Unmarshal(m, b)
+}
+func (m *AuditRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditRecord.Marshal(b, m, deterministic)
+}
+func (dst *AuditRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditRecord.Merge(dst, src)
+}
+func (m *AuditRecord) XXX_Size() int {
+	return xxx_messageInfo_AuditRecord.Size(m)
+}
+func (m *AuditRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditRecord proto.InternalMessageInfo
+
+func (m *AuditRecord) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AuditRecord) GetDescriptor_() string {
+	if m != nil {
+		return m.Descriptor_
+	}
+	return ""
+}
+
+func (m *AuditRecord) GetOperation() AuditRecord_Operation {
+	if m != nil {
+		return m.Operation
+	}
+	return AuditRecord_UNKNOWN
+}
+
+func (m *AuditRecord) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *AuditRecord) GetRemoteAddr() string {
+	if m != nil {
+		return m.RemoteAddr
+	}
+	return ""
+}
+
+func (m *AuditRecord) GetForwardedFor() string {
+	if m != nil {
+		return m.ForwardedFor
+	}
+	return ""
+}
+
+func (m *AuditRecord) GetUserAgent() string {
+	if m != nil {
+		return m.UserAgent
+	}
+	return ""
+}
+
+func (m *AuditRecord) GetBefore() *Channel {
+	if m != nil {
+		return m.Before
+	}
+	return nil
+}
+
+func (m *AuditRecord) GetAfter() *Channel {
+	if m != nil {
+		return m.After
+	}
+	return nil
+}
+
+func (m *AuditRecord) GetTokenChanged() bool {
+	if m != nil {
+		return m.TokenChanged
+	}
+	return false
+}
+
+// represents a stored version of a channel.
+type ChannelRevision struct {
+	Revision             uint64   `protobuf:"varint,1,opt,name=revision" json:"revision,omitempty"`
+	Time                 int64    `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	Actor                string   `protobuf:"bytes,3,opt,name=actor" json:"actor,omitempty"`
+	Channel              *Channel `protobuf:"bytes,4,opt,name=channel" json:"channel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChannelRevision) Reset()         { *m = ChannelRevision{} }
+func (m *ChannelRevision) String() string { return proto.CompactTextString(m) }
+func (*ChannelRevision) ProtoMessage()    {}
+func (*ChannelRevision) Descriptor() ([]byte, []int) {
+	return fileDescriptor_channel_3c87f561a468e095, []int{14}
+}
+func (m *ChannelRevision) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChannelRevision.Unmarshal(m, b)
+}
+func (m *ChannelRevision) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChannelRevision.Marshal(b, m, deterministic)
+}
+func (dst *ChannelRevision) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChannelRevision.Merge(dst, src)
+}
+func (m *ChannelRevision) XXX_Size() int {
+	return xxx_messageInfo_ChannelRevision.Size(m)
+}
+func (m *ChannelRevision) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChannelRevision.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChannelRevision proto.InternalMessageInfo
+
+func (m *ChannelRevision) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *ChannelRevision) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *ChannelRevision) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *ChannelRevision) GetChannel() *Channel {
+	if m != nil {
+		return m.Channel
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Channel)(nil), "protoed.channel.Channel")
+	proto.RegisterType((*ChannelToken)(nil), "protoed.channel.ChannelToken")
+	proto.RegisterType((*RateLimit)(nil), "protoed.channel.RateLimit")
+	proto.RegisterType((*Quota)(nil), "protoed.channel.Quota")
+	proto.RegisterType((*QuotaCounters)(nil), "protoed.channel.QuotaCounters")
+	proto.RegisterType((*RateState)(nil), "protoed.channel.RateState")
+	proto.RegisterType((*Lockout)(nil), "protoed.channel.Lockout")
+	proto.RegisterType((*QueuedMessage)(nil), "protoed.channel.QueuedMessage")
+	proto.RegisterType((*Disabled)(nil), "protoed.channel.Disabled")
+	proto.RegisterType((*TokenHash)(nil), "protoed.channel.TokenHash")
+	proto.RegisterType((*Entity)(nil), "protoed.channel.Entity")
+	proto.RegisterType((*RelayRecord)(nil), "protoed.channel.RelayRecord")
+	proto.RegisterType((*UsageCounters)(nil), "protoed.channel.UsageCounters")
+	proto.RegisterType((*AuditRecord)(nil), "protoed.channel.AuditRecord")
+	proto.RegisterType((*ChannelRevision)(nil), "protoed.channel.ChannelRevision")
+	proto.RegisterEnum("protoed.channel.Channel_OnThrottle", Channel_OnThrottle_name, Channel_OnThrottle_value)
+	proto.RegisterEnum("protoed.channel.QueuedMessage_Status", QueuedMessage_Status_name, QueuedMessage_Status_value)
+	proto.RegisterEnum("protoed.channel.TokenHash_Version", TokenHash_Version_name, TokenHash_Version_value)
+	proto.RegisterEnum("protoed.channel.RelayRecord_Outcome", RelayRecord_Outcome_name, RelayRecord_Outcome_value)
+	proto.RegisterEnum("protoed.channel.AuditRecord_Operation", AuditRecord_Operation_name, AuditRecord_Operation_value)
+}
+
+func init() { proto.RegisterFile("channel.proto", fileDescriptor_channel_3c87f561a468e095) }
+
+var fileDescriptor_channel_3c87f561a468e095 = []byte{
+	// 1729 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x57, 0x5f, 0x73, 0xe3, 0x48,
+	0x11, 0x3f, 0xdb, 0xb2, 0x65, 0xb5, 0xff, 0x44, 0x4c, 0x51, 0x8b, 0x6e, 0xe1, 0xb8, 0xa0, 0xbd,
+	0x3b, 0x72, 0x55, 0x60, 0xa8, 0x50, 0x0b, 0x45, 0x15, 0x47, 0x95, 0x37, 0x56, 0xee, 0xc2, 0x9a,
+	0x78, 0x77, 0xe2, 0xec, 0x1d, 0x4f, 0x2a, 0xc5, 0x9a, 0x6c, 0xc4, 0xca, 0x9a, 0xdc, 0x68, 0xb4,
+	0x97, 0xec, 0x07, 0xe0, 0x85, 0x67, 0x1e, 0xf8, 0x12, 0x7c, 0x01, 0x3e, 0x02, 0x4f, 0xf0, 0xce,
+	0xe7, 0xe0, 0x95, 0xea, 0x9e, 0x91, 0xec, 0x4d, 0xb2, 0x9b, 0x27, 0x4f, 0xf7, 0xfc, 0x7a, 0xd4,
+	0xd3, 0x7f, 0x7e, 0x3d, 0x86, 0xd1, 0xea, 0x22, 0x29, 0x0a, 0x91, 0x4f, 0x2e, 0x95, 0xd4, 0x92,
+	0xed, 0xd0, 0x8f, 0x48, 0x27, 0x56, 0x1d, 0xfe, 0xad, 0x07, 0xee, 0x81, 0x59, 0xb3, 0x1f, 0x03,
+	0xa4, 0xa2, 0x5c, 0xa9, 0xec, 0x52, 0x4b, 0x15, 0xb4, 0x76, 0x5b, 0x7b, 0x1e, 0xdf, 0xd2, 0xb0,
+	0xef, 0x43, 0x57, 0xcb, 0x57, 0xa2, 0x08, 0xda, 0xb4, 0x65, 0x04, 0xf6, 0x0b, 0xe8, 0x95, 0xa2,
+	0x48, 0x85, 0x0a, 0x3a, 0xbb, 0xad, 0xbd, 0xc1, 0xfe, 0x0f, 0x26, 0x37, 0xbe, 0x31, 0x89, 0x0a,
+	0x9d, 0xe9, 0x6b, 0x6e, 0x61, 0xec, 0x37, 0x00, 0x4a, 0xac, 0xb2, 0xcb, 0x4c, 0x14, 0xba, 0x0c,
+	0x9c, 0xdd, 0xce, 0xfb, 0x8c, 0xb6, 0xa0, 0xec, 0xa7, 0xd0, 0x5e, 0xad, 0x82, 0xee, 0xfb, 0x0d,
+	0xda, 0xab, 0x15, 0xfb, 0x1c, 0x3a, 0x67, 0xab, 0x55, 0xd0, 0x7b, 0x3f, 0x12, 0x31, 0xec, 0x01,
+	0xf4, 0x52, 0xb9, 0x4e, 0xb2, 0x22, 0x70, 0xe9, 0x52, 0x56, 0x62, 0x1f, 0x01, 0xac, 0xb3, 0x22,
+	0xbe, 0x14, 0x2a, 0x93, 0x69, 0xd0, 0xdf, 0x6d, 0xed, 0xb5, 0xb9, 0xb7, 0xce, 0x8a, 0x67, 0xa4,
+	0x60, 0x1f, 0x42, 0x7f, 0x9d, 0x5c, 0xc5, 0x65, 0xf6, 0x46, 0x04, 0xde, 0x6e, 0x6b, 0xaf, 0xcb,
+	0xdd, 0x75, 0x72, 0x75, 0x92, 0xbd, 0x11, 0xec, 0xb7, 0x00, 0x14, 0x98, 0xf8, 0x22, 0x29, 0x2f,
+	0x02, 0xa0, 0x98, 0x3c, 0xbc, 0xe5, 0xc3, 0x12, 0x21, 0x5f, 0x25, 0xe5, 0x05, 0xf7, 0x74, 0xbd,
+	0x64, 0x8f, 0xa1, 0x9f, 0x66, 0x65, 0x72, 0x96, 0x8b, 0x34, 0x18, 0x90, 0xe1, 0x87, 0xb7, 0x0c,
+	0x67, 0x16, 0xc0, 0x1b, 0x28, 0xfb, 0x18, 0x06, 0xaf, 0x93, 0x3c, 0x4b, 0xe3, 0xaa, 0xd0, 0x59,
+	0x1e, 0x0c, 0x77, 0x5b, 0x7b, 0x1d, 0x0e, 0xa4, 0x3a, 0x45, 0x0d, 0x7b, 0x0c, 0x3d, 0xfa, 0x48,
+	0x19, 0x8c, 0x28, 0x24, 0x1f, 0xdd, 0x3a, 0xd5, 0x96, 0x00, 0x79, 0xc5, 0x2d, 0x18, 0x6f, 0xa2,
+	0x12, 0x2d, 0xe2, 0x3c, 0x5b, 0x67, 0x3a, 0x18, 0xbf, 0xe3, 0x26, 0x3c, 0xd1, 0x62, 0x8e, 0x08,
+	0xee, 0xa9, 0x7a, 0xc9, 0x7e, 0x06, 0xdd, 0x6f, 0x2b, 0xa9, 0x93, 0x60, 0x87, 0xac, 0x1e, 0xdc,
+	0xb2, 0x7a, 0x8e, 0xbb, 0xdc, 0x80, 0xd8, 0x0c, 0x06, 0xb2, 0x88, 0xf5, 0x85, 0x92, 0x5a, 0xe7,
+	0x22, 0xf0, 0x77, 0x5b, 0x7b, 0xe3, 0xfd, 0x47, 0xef, 0x72, 0x72, 0xb2, 0x28, 0x96, 0x16, 0xca,
+	0x41, 0x36, 0x6b, 0xf6, 0x19, 0xec, 0x60, 0x4e, 0xbe, 0xad, 0x44, 0x25, 0xe2, 0x54, 0x5c, 0xea,
+	0x8b, 0xe0, 0x7b, 0xbb, 0xad, 0xbd, 0x11, 0x1f, 0xad, 0x93, 0xab, 0xe7, 0xa8, 0x9d, 0xa1, 0x32,
+	0x7c, 0x04, 0xb0, 0x39, 0x81, 0x01, 0xf4, 0x78, 0xf4, 0x87, 0xe8, 0x60, 0xe9, 0x7f, 0xc0, 0x3c,
+	0xe8, 0x3e, 0x3f, 0x8d, 0x4e, 0x23, 0xbf, 0x15, 0xfe, 0xa5, 0x05, 0xc3, 0xed, 0xa0, 0x30, 0x06,
+	0x4e, 0x91, 0xac, 0x85, 0x6d, 0x0b, 0x5a, 0xb3, 0x09, 0x38, 0x94, 0xe4, 0xf6, 0xbd, 0x49, 0x26,
+	0x1c, 0x0b, 0xc0, 0x5d, 0x29, 0x91, 0x68, 0x91, 0x52, 0xaf, 0x74, 0x78, 0x2d, 0xe2, 0x8e, 0xb8,
+	0xba, 0xcc, 0x94, 0xc0, 0x86, 0xa0, 0x1d, 0x2b, 0x86, 0x8f, 0xc1, 0x6b, 0x22, 0x8c, 0x4e, 0x60,
+	0x8c, 0xc9, 0x89, 0x36, 0xa7, 0x35, 0x76, 0xe5, 0x59, 0xa5, 0x4a, 0x4d, 0x5e, 0x8c, 0xb8, 0x11,
+	0xc2, 0x7f, 0xb6, 0xa0, 0x4b, 0x31, 0x66, 0x9f, 0xc2, 0x38, 0x4d, 0xb2, 0xfc, 0x3a, 0x5e, 0x8b,
+	0xb2, 0x4c, 0x5e, 0x8a, 0x92, 0xac, 0x1d, 0x3e, 0x22, 0xed, 0x1f, 0xad, 0x92, 0x7d, 0x0e, 0xfe,
+	0x5a, 0x16, 0xfa, 0x62, 0x1b, 0xd8, 0x26, 0xe0, 0x8e, 0xd5, 0x37, 0xd0, 0x8f, 0x61, 0x60, 0x4e,
+	0x3c, 0xbb, 0xd6, 0xa2, 0xa4, 0xab, 0x38, 0x1c, 0x48, 0xf5, 0x04, 0x35, 0xec, 0x11, 0x8c, 0xea,
+	0xb3, 0x0c, 0xc4, 0x21, 0xc8, 0xd0, 0x2a, 0x0d, 0xe8, 0x21, 0xf4, 0x75, 0xb6, 0x16, 0x6f, 0x64,
+	0x21, 0x82, 0x2e, 0x05, 0xb5, 0x91, 0xc3, 0xaf, 0x61, 0x44, 0xce, 0x1f, 0xc8, 0xaa, 0xd0, 0x42,
+	0x95, 0x78, 0xc9, 0x52, 0x27, 0x4a, 0x93, 0xef, 0x1d, 0x6e, 0x04, 0x3c, 0xe2, 0x86, 0xaf, 0x8d,
+	0x4c, 0x61, 0xd9, 0x72, 0xcf, 0x08, 0xe1, 0x17, 0x26, 0x9a, 0x27, 0x1a, 0x23, 0xc7, 0xc0, 0xc1,
+	0x2f, 0xda, 0x33, 0x69, 0xcd, 0x7e, 0x04, 0x5e, 0x92, 0xe7, 0xf2, 0xbb, 0xa4, 0x58, 0x09, 0x3a,
+	0xb3, 0xc5, 0x37, 0x8a, 0xf0, 0xef, 0x2d, 0x70, 0xe7, 0x72, 0xf5, 0x4a, 0x56, 0x94, 0x8b, 0x57,
+	0x59, 0x91, 0xd6, 0x05, 0x81, 0x6b, 0x4c, 0x63, 0x59, 0x9d, 0xfd, 0x59, 0xac, 0xb4, 0xe5, 0xc8,
+	0x5a, 0x44, 0x57, 0xcf, 0x93, 0x2c, 0xaf, 0x94, 0xf5, 0x68, 0xc4, 0x1b, 0x99, 0xfd, 0x04, 0x86,
+	0x79, 0x52, 0xea, 0xd8, 0x2a, 0x6c, 0x05, 0x0c, 0x50, 0x77, 0x68, 0x54, 0x04, 0x91, 0xab, 0x57,
+	0xa2, 0xee, 0xf1, 0xae, 0x85, 0x90, 0x8e, 0x9a, 0x3c, 0xfc, 0x57, 0x07, 0x83, 0x26, 0x2a, 0x91,
+	0xda, 0x44, 0xb1, 0x31, 0xb4, 0xb3, 0xd4, 0x66, 0xbb, 0x9d, 0xa5, 0x37, 0xf8, 0xbd, 0x7d, 0x8b,
+	0xdf, 0x1f, 0x42, 0x5f, 0x14, 0xd4, 0x3e, 0x75, 0x7d, 0x36, 0x32, 0xde, 0xcc, 0x86, 0x96, 0xdc,
+	0x1b, 0xf2, 0x5a, 0xdc, 0xbe, 0x73, 0xf7, 0xed, 0x3b, 0x33, 0x70, 0x88, 0x20, 0x7b, 0x26, 0xbe,
+	0xb8, 0xde, 0xcc, 0x10, 0x77, 0x7b, 0x86, 0x7c, 0x01, 0xbd, 0x52, 0x27, 0xba, 0x2a, 0x89, 0x69,
+	0xc7, 0xfb, 0x9f, 0xde, 0xc1, 0x17, 0x5b, 0x37, 0x9b, 0x9c, 0x10, 0x98, 0x5b, 0x23, 0x74, 0x3c,
+	0xd1, 0x5a, 0xac, 0x2f, 0x75, 0x49, 0x6c, 0x3c, 0xe2, 0x8d, 0x8c, 0x4c, 0xad, 0x84, 0x56, 0xd7,
+	0x71, 0xa2, 0x89, 0x8c, 0x3b, 0xdc, 0x25, 0x79, 0xaa, 0xd1, 0x17, 0xa1, 0x94, 0x54, 0xc4, 0xb5,
+	0x1e, 0x37, 0x02, 0x65, 0x2a, 0x2b, 0xb2, 0xf2, 0x42, 0xa4, 0x96, 0x4a, 0x1b, 0x99, 0xa6, 0x82,
+	0x71, 0x21, 0xce, 0xd2, 0x60, 0x44, 0x66, 0x9e, 0xd5, 0x1c, 0xa5, 0x68, 0x9a, 0x8a, 0x73, 0xa1,
+	0x94, 0x48, 0x89, 0x2e, 0x3d, 0xde, 0xc8, 0xe1, 0xcf, 0xa1, 0x67, 0xbc, 0x46, 0xc6, 0x21, 0x96,
+	0x99, 0xf9, 0x1f, 0xb0, 0x01, 0xb8, 0x3c, 0x9a, 0x4f, 0xff, 0x14, 0xcd, 0xfc, 0x16, 0x6e, 0x1c,
+	0x4e, 0x8f, 0xe6, 0xd1, 0xcc, 0x6f, 0x87, 0xbf, 0x86, 0x7e, 0xcd, 0xf4, 0x77, 0xd6, 0xe9, 0x03,
+	0xe8, 0x29, 0x91, 0x94, 0xb2, 0x1e, 0xc6, 0x56, 0x0a, 0xff, 0xdb, 0x02, 0xaf, 0xa1, 0x1d, 0xf6,
+	0x3b, 0x70, 0x5f, 0x0b, 0x55, 0x66, 0xb2, 0x20, 0xe3, 0xf1, 0x7e, 0xf8, 0x6e, 0x8e, 0x9a, 0xbc,
+	0x30, 0x48, 0x5e, 0x9b, 0x50, 0xfe, 0x92, 0xdc, 0x94, 0xf2, 0x90, 0xd3, 0x1a, 0x75, 0x44, 0x79,
+	0x1d, 0xa3, 0xc3, 0x75, 0xe3, 0x9f, 0x43, 0xa1, 0x6f, 0xfc, 0x5b, 0x8b, 0xb5, 0x54, 0xd7, 0x54,
+	0x14, 0x23, 0x6e, 0x25, 0xac, 0x16, 0x7d, 0xa1, 0x44, 0x92, 0x96, 0x54, 0x16, 0x23, 0x5e, 0x8b,
+	0xe1, 0x27, 0xe0, 0x5a, 0x0f, 0x30, 0x2a, 0xa7, 0xc7, 0x4f, 0x8f, 0x17, 0x5f, 0x1f, 0xfb, 0x1f,
+	0xb0, 0x21, 0xf4, 0xa7, 0xfc, 0xcb, 0xc5, 0xf1, 0xfe, 0xd1, 0xcc, 0x6f, 0x85, 0xfb, 0xd0, 0x33,
+	0xe3, 0x9b, 0xb2, 0xb7, 0x4e, 0xb2, 0xdc, 0x36, 0xa0, 0x11, 0x1a, 0x9a, 0x6e, 0x6f, 0x68, 0x3a,
+	0xfc, 0x77, 0x07, 0x06, 0x5c, 0xe4, 0xc9, 0x35, 0x17, 0x2b, 0xa9, 0xd2, 0x7b, 0xdf, 0x39, 0xf5,
+	0x7d, 0xda, 0x5b, 0xf1, 0xde, 0xaa, 0xf2, 0xce, 0xdd, 0x55, 0xee, 0x6c, 0x55, 0xf9, 0xef, 0xc1,
+	0x95, 0x95, 0x5e, 0xc9, 0xb5, 0xa1, 0xb6, 0xf1, 0xfe, 0x27, 0xb7, 0xc7, 0xe6, 0xc6, 0xa1, 0xc9,
+	0xc2, 0x60, 0x79, 0x6d, 0x84, 0xd1, 0xb3, 0xfd, 0xd0, 0xa3, 0xc7, 0x85, 0x95, 0x6e, 0xd4, 0x9f,
+	0x7b, 0xb3, 0xfe, 0x9a, 0xe6, 0xea, 0x6f, 0x35, 0x57, 0xf8, 0x9f, 0x16, 0xb8, 0xf6, 0x0b, 0x6f,
+	0x47, 0xf6, 0xad, 0xe2, 0x1b, 0x81, 0x77, 0xb8, 0xe0, 0x4f, 0x8e, 0x66, 0xb3, 0xe8, 0xd8, 0x6f,
+	0x63, 0xd4, 0x97, 0x8b, 0x45, 0x7c, 0xb2, 0x58, 0x1c, 0xfb, 0x1d, 0xdc, 0x44, 0x69, 0x3e, 0xe5,
+	0x5f, 0x46, 0xbe, 0x83, 0x86, 0x47, 0xc7, 0x2f, 0xa6, 0xf3, 0xa3, 0x99, 0xdf, 0xdd, 0xaa, 0xda,
+	0x1e, 0x5a, 0xcd, 0x8e, 0x4e, 0xa6, 0x4f, 0x50, 0x72, 0x11, 0x16, 0x7d, 0xf3, 0xec, 0x88, 0x47,
+	0x33, 0xbf, 0xcf, 0x18, 0x8c, 0x9f, 0x9f, 0x2e, 0x96, 0xd3, 0x38, 0xfa, 0xe6, 0x20, 0x8a, 0x66,
+	0xd1, 0xcc, 0xf7, 0xe8, 0xd8, 0xaf, 0xf8, 0x62, 0xb9, 0x44, 0x3c, 0xb0, 0x31, 0xc0, 0x7c, 0x71,
+	0xf0, 0x34, 0x9a, 0xc5, 0x8b, 0xd3, 0xa5, 0x3f, 0xd8, 0x6a, 0x94, 0x21, 0xee, 0xd1, 0x3a, 0x3e,
+	0x3c, 0x9d, 0xcf, 0xfd, 0x51, 0xf8, 0x8f, 0x0e, 0x8c, 0x4e, 0xf1, 0xda, 0xf7, 0x8c, 0x88, 0x00,
+	0x5c, 0x85, 0x91, 0x16, 0xa9, 0x9d, 0x10, 0xb5, 0x78, 0xf7, 0x80, 0x40, 0xfe, 0x3f, 0x97, 0xea,
+	0x2c, 0x4b, 0x53, 0x51, 0xd8, 0xb1, 0xb5, 0x51, 0x20, 0x99, 0x68, 0x29, 0xe3, 0x52, 0xca, 0x82,
+	0x12, 0xeb, 0x70, 0x57, 0x4b, 0x79, 0x22, 0x65, 0xc1, 0x7e, 0x08, 0x1e, 0x6e, 0xe5, 0x89, 0x7a,
+	0x69, 0x18, 0xcf, 0xe1, 0x88, 0x9d, 0xa3, 0x8c, 0x5e, 0x64, 0x05, 0x3d, 0xc8, 0x28, 0x69, 0x0e,
+	0xaf, 0x45, 0xcc, 0x34, 0xd2, 0xbe, 0x30, 0x6f, 0x4c, 0x87, 0x5b, 0x89, 0xa8, 0xa4, 0x7e, 0x0a,
+	0x7a, 0xe6, 0xb4, 0x5a, 0xde, 0x3c, 0x16, 0x52, 0x62, 0x34, 0xa7, 0x7e, 0x2c, 0xa4, 0x38, 0xeb,
+	0xe9, 0x45, 0x15, 0x8b, 0xab, 0x95, 0x10, 0xa9, 0x7d, 0x46, 0x3a, 0x7c, 0x44, 0xda, 0xc8, 0x2a,
+	0xf1, 0x92, 0xf5, 0x63, 0xcb, 0x70, 0x9c, 0xc3, 0x37, 0x0a, 0x2c, 0x32, 0x3b, 0x6b, 0x64, 0xa5,
+	0x89, 0xe4, 0x1c, 0xee, 0x19, 0xcd, 0xa2, 0xd2, 0xe8, 0xb1, 0x9d, 0x11, 0x63, 0xe3, 0xb1, 0x91,
+	0xd0, 0x8c, 0x56, 0xf1, 0x79, 0x95, 0xe7, 0xf4, 0xee, 0x73, 0xb8, 0x47, 0x9a, 0xc3, 0x2a, 0xcf,
+	0xc3, 0xff, 0x75, 0x60, 0x30, 0xad, 0xd2, 0x4c, 0xdb, 0x26, 0xbc, 0x8b, 0xd4, 0xee, 0x1b, 0x50,
+	0x33, 0xf0, 0xe4, 0xa5, 0x50, 0x89, 0x46, 0x42, 0xeb, 0x50, 0x63, 0x7d, 0x76, 0xab, 0xb1, 0xb6,
+	0x3e, 0x32, 0x59, 0xd4, 0x68, 0xbe, 0x31, 0xc4, 0xc4, 0x27, 0x2b, 0xfc, 0x80, 0x63, 0xba, 0x84,
+	0x04, 0x7c, 0xd4, 0x28, 0xb1, 0x96, 0x5a, 0xc4, 0x49, 0x9a, 0x2a, 0x3b, 0xca, 0xc0, 0xa8, 0xa6,
+	0x69, 0xaa, 0xf0, 0x51, 0x73, 0x2e, 0xd5, 0x77, 0x89, 0x4a, 0x45, 0x1a, 0x9f, 0x4b, 0x45, 0x49,
+	0xf6, 0xf8, 0xb0, 0x51, 0x1e, 0x4a, 0x85, 0x41, 0xa8, 0x4a, 0xa1, 0xe2, 0xe4, 0xa5, 0x28, 0x74,
+	0xdd, 0xa0, 0xa8, 0x99, 0xa2, 0x82, 0xfd, 0x12, 0x7a, 0x67, 0xe2, 0x5c, 0x2a, 0x41, 0xd9, 0x1e,
+	0xec, 0x07, 0xef, 0x7a, 0xe3, 0x72, 0x8b, 0x63, 0x13, 0xe8, 0x26, 0xe7, 0x5a, 0xa8, 0xc0, 0xbb,
+	0xc7, 0xc0, 0xc0, 0xd0, 0x4b, 0xf3, 0xef, 0x03, 0xf7, 0x5f, 0xda, 0x0a, 0xe9, 0xf3, 0x21, 0x29,
+	0x0f, 0x8c, 0x2e, 0xcc, 0xc1, 0x6b, 0x22, 0xf3, 0x36, 0x25, 0xb8, 0xd0, 0x79, 0x76, 0xba, 0x34,
+	0xb3, 0x68, 0x16, 0xcd, 0xa3, 0x65, 0x64, 0xb8, 0x80, 0x2f, 0xe6, 0xf3, 0x27, 0xd3, 0x83, 0xa7,
+	0x7e, 0x07, 0xf1, 0xb6, 0xc7, 0x7d, 0x07, 0x61, 0xd1, 0x31, 0xad, 0x89, 0x08, 0xf8, 0x62, 0x39,
+	0x5d, 0x46, 0x7e, 0x8f, 0xd6, 0xd1, 0x8b, 0xc5, 0xd3, 0xc8, 0x77, 0xc3, 0xbf, 0xb6, 0x60, 0xa7,
+	0xf6, 0x52, 0xbc, 0xce, 0x88, 0xe1, 0x1f, 0xe2, 0x54, 0x36, 0x6b, 0xfb, 0x40, 0x69, 0xe4, 0x3b,
+	0xe9, 0xb7, 0xc9, 0x59, 0x67, 0x3b, 0x67, 0xfb, 0xe0, 0xda, 0x30, 0x04, 0xce, 0x3d, 0xe1, 0xa9,
+	0x81, 0x67, 0x3d, 0x42, 0xfc, 0xea, 0xff, 0x03, 0x00, 0xd3, 0xbd, 0x48, 0xcb, 0x19, 0x0f, 0x00,
+	0x00,
 }
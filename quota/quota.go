@@ -0,0 +1,265 @@
+package quota
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// Exceeded describes a quota which a message would exceed.
+type Exceeded struct {
+	// Period is the calendar period of the quota.
+	Period database.QuotaPeriod
+
+	// Unit is either "messages" or "bytes".
+	Unit string
+
+	// Limit is the quota.
+	Limit uint64
+
+	// Reset is the time when the quota resets.
+	Reset time.Time
+}
+
+// String describes the exceeded quota.
+func (e *Exceeded) String() string {
+	return fmt.Sprintf("the quota of %d %s per %s has been reached "+
+		"until %s", e.Limit, e.Unit, e.Period,
+		e.Reset.Format(time.RFC3339))
+}
+
+// Location returns the time zone in which the periods of the quota start.
+//
+// Location requires:
+// * quota != nil
+func Location(quota *protoed.Quota) (loc *time.Location, err error) {
+	// Pre-condition
+	if !(quota != nil) {
+		panic("Violated: quota != nil")
+	}
+
+	loc, err = time.LoadLocation(quota.Timezone)
+	if err != nil {
+		err = fmt.Errorf("failed to load the time zone %#v: %s",
+			quota.Timezone, err.Error())
+		return
+	}
+
+	return
+}
+
+// limits returns the quotas of the messages and of the bytes in the period;
+// 0 stands for unlimited.
+func limits(quota *protoed.Quota, period database.QuotaPeriod) (
+	messages uint64, bytes uint64) {
+	switch period {
+	case database.QuotaDay:
+		return quota.DailyMessages, quota.DailyBytes
+	case database.QuotaMonth:
+		return quota.MonthlyMessages, quota.MonthlyBytes
+	default:
+		panic(fmt.Sprintf("unhandled quota period: %#v", period))
+	}
+}
+
+// Reservation holds a message counted against the quotas of a channel
+// while the message is relayed.
+//
+// The message is counted in the transaction of the reservation so that
+// the concurrent requests can not exceed the quotas. Once the message has
+// been relayed, the reservation is committed; otherwise it is rolled back
+// and the message is given back to the quotas.
+type Reservation struct {
+	// Descriptor identifies the channel.
+	Descriptor string
+
+	// Size is the size of the message in bytes.
+	Size int64
+
+	// Starts are the starts of the periods in which the message has been
+	// counted.
+	Starts map[database.QuotaPeriod]time.Time
+
+	done bool
+}
+
+// Done indicates that the reservation has been committed or rolled back.
+func (r *Reservation) Done() bool {
+	return r.done
+}
+
+// Commit keeps the message counted against the quotas for good.
+//
+// Commit requires:
+// * !r.Done()
+//
+// Commit ensures:
+// * r.Done()
+func (r *Reservation) Commit() {
+	// Pre-condition
+	if !(!r.Done()) {
+		panic("Violated: !r.Done()")
+	}
+
+	// Post-condition
+	defer func() {
+		if !(r.Done()) {
+			panic("Violated: r.Done()")
+		}
+	}()
+
+	r.done = true
+}
+
+// Reserve counts the message of the given size against the quotas of
+// the channel unless it would exceed any of them. If the message is
+// refused, the exceeded quota with the latest reset is returned and
+// nothing is counted.
+//
+// The messages are only counted while the channel has quotas; reservation
+// is nil if nothing has been counted.
+//
+// Reserve requires:
+// * channel != nil
+// * size >= 0
+//
+// Reserve ensures:
+// * err != nil || exceeded == nil || reservation == nil
+// * err != nil || reservation == nil || !reservation.Done()
+func Reserve(txn *database.Txn, channel *protoed.Channel, now time.Time,
+	size int64) (reservation *Reservation, exceeded *Exceeded, err error) {
+	// Pre-conditions
+	switch {
+	case !(channel != nil):
+		panic("Violated: channel != nil")
+	case !(size >= 0):
+		panic("Violated: size >= 0")
+	default:
+		// Pass
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || exceeded == nil || reservation == nil):
+			panic("Violated: err != nil || exceeded == nil || reservation == nil")
+		case !(err != nil || reservation == nil || !reservation.Done()):
+			panic("Violated: err != nil || reservation == nil || !reservation.Done()")
+		default:
+			// Pass
+		}
+	}()
+
+	if channel.Quota == nil {
+		return
+	}
+
+	loc, err := Location(channel.Quota)
+	if err != nil {
+		return
+	}
+
+	for _, period := range database.QuotaPeriods {
+		maxMessages, maxBytes := limits(channel.Quota, period)
+		if maxMessages == 0 && maxBytes == 0 {
+			continue
+		}
+
+		var counters *protoed.QuotaCounters
+		counters, err = txn.QuotaCounters(channel.Descriptor_, period,
+			period.Start(now, loc))
+		if err != nil {
+			return
+		}
+
+		reset := period.Next(now, loc)
+		if exceeded != nil && !reset.After(exceeded.Reset) {
+			continue
+		}
+
+		switch {
+		case maxMessages > 0 && counters.Messages+1 > maxMessages:
+			exceeded = &Exceeded{Period: period, Unit: "messages",
+				Limit: maxMessages, Reset: reset}
+		case maxBytes > 0 && counters.Bytes+uint64(size) > maxBytes:
+			exceeded = &Exceeded{Period: period, Unit: "bytes",
+				Limit: maxBytes, Reset: reset}
+		}
+	}
+
+	if exceeded != nil {
+		return
+	}
+
+	starts := make(map[database.QuotaPeriod]time.Time)
+	for _, period := range database.QuotaPeriods {
+		maxMessages, maxBytes := limits(channel.Quota, period)
+		if maxMessages == 0 && maxBytes == 0 {
+			continue
+		}
+
+		start := period.Start(now, loc)
+		err = txn.CountQuota(channel.Descriptor_, period, start, size)
+		if err != nil {
+			return
+		}
+		starts[period] = start
+	}
+
+	if len(starts) > 0 {
+		reservation = &Reservation{Descriptor: channel.Descriptor_,
+			Size: size, Starts: starts}
+	}
+	return
+}
+
+// Rollback gives the reserved message back to the quotas of the channel.
+//
+// Nothing is given back to the quota of a period which has passed in
+// the meantime.
+//
+// Rollback requires:
+// * txn != nil
+// * reservation != nil
+// * !reservation.Done()
+//
+// Rollback ensures:
+// * err != nil || reservation.Done()
+func Rollback(txn *database.Txn, reservation *Reservation) (err error) {
+	// Pre-conditions
+	switch {
+	case !(txn != nil):
+		panic("Violated: txn != nil")
+	case !(reservation != nil):
+		panic("Violated: reservation != nil")
+	case !(!reservation.Done()):
+		panic("Violated: !reservation.Done()")
+	default:
+		// Pass
+	}
+
+	// Post-condition
+	defer func() {
+		if !(err != nil || reservation.Done()) {
+			panic("Violated: err != nil || reservation.Done()")
+		}
+	}()
+
+	for _, period := range database.QuotaPeriods {
+		start, ok := reservation.Starts[period]
+		if !ok {
+			continue
+		}
+
+		err = txn.UncountQuota(reservation.Descriptor, period, start,
+			reservation.Size)
+		if err != nil {
+			return
+		}
+	}
+
+	reservation.done = true
+	return
+}
@@ -0,0 +1,153 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/database"
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestReserve(t *testing.T) {
+	s := database.NewMemStore(database.RelayAccess)
+	now := time.Date(2018, 10, 15, 14, 37, 0, 0, time.UTC)
+
+	channel := &protoed.Channel{Descriptor_: "client-1",
+		Quota: &protoed.Quota{DailyMessages: 2, MonthlyBytes: 250}}
+
+	take := func(tm time.Time, size int64) (exceeded *Exceeded) {
+		err := s.Update(func(txn *database.Txn) (txnErr error) {
+			var reservation *Reservation
+			reservation, exceeded, txnErr = Reserve(txn, channel, tm, size)
+			if reservation != nil {
+				reservation.Commit()
+			}
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		if exceeded := take(now, 100); exceeded != nil {
+			t.Fatalf("expected the message %d to be counted, got %s",
+				i, exceeded.String())
+		}
+	}
+
+	exceeded := take(now, 10)
+	if exceeded == nil || exceeded.Period != database.QuotaDay ||
+		exceeded.Unit != "messages" ||
+		!exceeded.Reset.Equal(time.Date(2018, 10, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the daily quota of the messages to be exceeded, "+
+			"got %v", exceeded)
+	}
+
+	// Both quotas are exceeded; the later reset is reported.
+	exceeded = take(now, 100)
+	if exceeded == nil || exceeded.Period != database.QuotaMonth ||
+		exceeded.Unit != "bytes" || exceeded.Limit != 250 {
+		t.Fatalf("expected the monthly quota of the bytes to be exceeded, "+
+			"got %v", exceeded)
+	}
+
+	// The daily quota resets on the next day.
+	if exceeded = take(now.AddDate(0, 0, 1), 10); exceeded != nil {
+		t.Fatalf("expected the quotas to reset, got %s", exceeded.String())
+	}
+
+	// The channels without quotas are not limited.
+	channel = &protoed.Channel{Descriptor_: "client-2"}
+	for i := 0; i < 3; i++ {
+		if exceeded = take(now, 1000); exceeded != nil {
+			t.Fatalf("expected no quota, got %s", exceeded.String())
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	s := database.NewMemStore(database.RelayAccess)
+	now := time.Date(2018, 10, 15, 14, 37, 0, 0, time.UTC)
+
+	channel := &protoed.Channel{Descriptor_: "client-1",
+		Quota: &protoed.Quota{DailyMessages: 1, MonthlyBytes: 250}}
+
+	reserve := func() (reservation *Reservation, exceeded *Exceeded) {
+		err := s.Update(func(txn *database.Txn) (txnErr error) {
+			reservation, exceeded, txnErr = Reserve(txn, channel, now, 100)
+			return
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		return
+	}
+
+	reservation, exceeded := reserve()
+	if reservation == nil || exceeded != nil {
+		t.Fatalf("expected the message to be reserved, got %v", exceeded)
+	}
+
+	// The reserved message counts against the quotas until it is rolled back.
+	if _, exceeded = reserve(); exceeded == nil {
+		t.Fatalf("expected the daily quota to be exceeded by the reservation")
+	}
+
+	err := s.Update(func(txn *database.Txn) error {
+		return Rollback(txn, reservation)
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reservation.Done() {
+		t.Errorf("expected the reservation to be done after the rollback")
+	}
+
+	err = s.View(func(txn *database.Txn) (txnErr error) {
+		for _, period := range database.QuotaPeriods {
+			var counters *protoed.QuotaCounters
+			counters, txnErr = txn.QuotaCounters("client-1", period,
+				period.Start(now, time.UTC))
+			if txnErr != nil {
+				return
+			}
+			if counters.Messages != 0 || counters.Bytes != 0 {
+				t.Errorf("expected the %s counters to be given back, got %v",
+					period, counters)
+			}
+		}
+		return
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if reservation, exceeded = reserve(); reservation == nil || exceeded != nil {
+		t.Fatalf("expected the message to be reserved again, got %v", exceeded)
+	}
+
+	// The channels without quotas reserve nothing.
+	channel = &protoed.Channel{Descriptor_: "client-2"}
+	if reservation, _ = reserve(); reservation != nil {
+		t.Errorf("expected no reservation without quotas, got %v", reservation)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	loc, err := Location(&protoed.Quota{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if loc != time.UTC {
+		t.Errorf("expected UTC by default, got %s", loc)
+	}
+
+	_, err = Location(&protoed.Quota{Timezone: "Mars/Olympus_Mons"})
+	if err == nil {
+		t.Errorf("expected an error for an unknown time zone, got nil")
+	}
+}
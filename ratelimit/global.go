@@ -0,0 +1,378 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// ActiveWindow is the period since its last message during which the fair
+// shares of a channel are kept.
+const ActiveWindow = time.Minute
+
+// ContentionWindow is the period after the suggested retry of its last
+// refusal during which a channel still competes for the global limits.
+const ContentionWindow = 10 * time.Second
+
+// ConcurrencyRetry is the time after which a message refused due to
+// the concurrency cap should be retried.
+const ConcurrencyRetry = time.Second
+
+// PerSecond returns the limit of n messages per second.
+func PerSecond(n float64) Limit {
+	return Limit{Rate: n, Burst: burstOf(n)}
+}
+
+// PerMinute returns the limit of n messages per minute.
+func PerMinute(n float64) Limit {
+	return Limit{Rate: n / 60, Burst: burstOf(n)}
+}
+
+// burstOf lets the whole period's worth of messages through at once.
+func burstOf(n float64) uint32 {
+	if n < 1 {
+		return 1
+	}
+	return uint32(math.Ceil(n))
+}
+
+// ParseDomainLimits parses the limits of the MailGun domains given as
+// a comma-separated list of domain=N/s or domain=N/m entries, e.g.,
+// "marketing.example.com=5/s,news.example.com=100/m".
+func ParseDomainLimits(text string) (limits map[string]Limit, err error) {
+	limits = make(map[string]Limit)
+
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	for _, entry := range strings.Split(text, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			err = fmt.Errorf("expected an entry domain=N/s or domain=N/m, "+
+				"got %#v", entry)
+			return
+		}
+
+		domain := parts[0]
+		if _, exists := limits[domain]; exists {
+			err = fmt.Errorf("duplicate limit of the domain %#v", domain)
+			return
+		}
+
+		rate := parts[1]
+		var unit func(float64) Limit
+		switch {
+		case strings.HasSuffix(rate, "/s"):
+			unit = PerSecond
+		case strings.HasSuffix(rate, "/m"):
+			unit = PerMinute
+		default:
+			err = fmt.Errorf("expected the limit of the domain %#v to end "+
+				"in /s or /m, got %#v", domain, rate)
+			return
+		}
+
+		var n float64
+		n, err = strconv.ParseFloat(rate[:len(rate)-2], 64)
+		if err != nil || n <= 0 {
+			err = fmt.Errorf("expected a positive number of messages "+
+				"in the limit of the domain %#v, got %#v", domain, rate)
+			return
+		}
+
+		limits[domain] = unit(n)
+	}
+
+	return
+}
+
+// GlobalConfig configures the limits shared by all the channels of
+// the relay.
+type GlobalConfig struct {
+	// Limits lists the rate limits over all the channels, e.g., per second
+	// and per minute.
+	Limits []Limit
+
+	// Concurrency caps the number of the messages relayed at the same time;
+	// 0 if unlimited.
+	Concurrency int
+
+	// Domains maps the MailGun domains to their additional rate limits.
+	Domains map[string]Limit
+}
+
+// IsEmpty indicates that the configuration limits nothing.
+func (c GlobalConfig) IsEmpty() bool {
+	return len(c.Limits) == 0 && c.Concurrency == 0 && len(c.Domains) == 0
+}
+
+// Refusal explains why the global limiter refused a message.
+type Refusal struct {
+	// Reason describes the reached limit.
+	Reason string
+
+	// RetryAfter is the time after which the message should be retried.
+	RetryAfter time.Duration
+
+	// Concurrency indicates that the concurrency cap has been reached
+	// rather than a rate limit.
+	Concurrency bool
+}
+
+// bucket is a token bucket shared by the channels together with the fair
+// shares of the channels.
+type bucket struct {
+	limit  Limit
+	state  *protoed.RateState
+	shares map[string]*protoed.RateState
+}
+
+// Global limits the messages of all the channels of the relay.
+//
+// The channels competing for the limits share them fairly: while other
+// channels are sending messages or have been refused within
+// the ContentionWindow, a channel can use at most its even share of each
+// rate limit and of the concurrency cap. A channel alone can use the whole
+// limits. Global is safe for concurrent use.
+type Global struct {
+	mu sync.Mutex
+
+	config   GlobalConfig
+	buckets  []*bucket
+	domains  map[string]*bucket
+	lastSeen map[string]time.Time
+	inFlight map[string]int
+	total    int
+
+	// contending maps the refused channels to the time until which they
+	// compete for the limits.
+	contending map[string]time.Time
+}
+
+// NewGlobal creates a global limiter with the given configuration.
+//
+// NewGlobal requires:
+// * config.Concurrency >= 0
+func NewGlobal(config GlobalConfig) *Global {
+	// Pre-condition
+	if !(config.Concurrency >= 0) {
+		panic("Violated: config.Concurrency >= 0")
+	}
+
+	g := &Global{config: config,
+		domains:    make(map[string]*bucket),
+		lastSeen:   make(map[string]time.Time),
+		inFlight:   make(map[string]int),
+		contending: make(map[string]time.Time)}
+
+	for _, limit := range config.Limits {
+		g.buckets = append(g.buckets,
+			&bucket{limit: limit, shares: make(map[string]*protoed.RateState)})
+	}
+
+	for domain, limit := range config.Domains {
+		g.domains[domain] = &bucket{limit: limit,
+			shares: make(map[string]*protoed.RateState)}
+	}
+
+	return g
+}
+
+// share returns the fair share of the limit among the competing channels.
+func share(limit Limit, competing int) Limit {
+	if competing <= 1 {
+		return limit
+	}
+
+	burst := limit.Burst / uint32(competing)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return Limit{Rate: limit.Rate / float64(competing), Burst: burst}
+}
+
+// competingChannels prunes the channels which left the ActiveWindow or
+// the ContentionWindow and returns the number of the channels competing for
+// the limits, i.e., the given one and the others which are sending messages
+// or have been refused recently.
+func (g *Global) competingChannels(descriptor string, now time.Time) int {
+	for d, seen := range g.lastSeen {
+		if now.Sub(seen) > ActiveWindow && g.inFlight[d] == 0 {
+			delete(g.lastSeen, d)
+			for _, b := range g.buckets {
+				delete(b.shares, d)
+			}
+			for _, b := range g.domains {
+				delete(b.shares, d)
+			}
+		}
+	}
+
+	for d, until := range g.contending {
+		if now.After(until) {
+			delete(g.contending, d)
+		}
+	}
+
+	competing := 1
+	for d := range g.inFlight {
+		if d != descriptor {
+			competing++
+		}
+	}
+	for d := range g.contending {
+		if _, ok := g.inFlight[d]; !ok && d != descriptor {
+			competing++
+		}
+	}
+	return competing
+}
+
+// Acquire takes a message of the channel from the global limits.
+// If the message is accepted, the caller must call release once
+// the message has been relayed or given up; otherwise the refusal explains
+// why. A message which has not been relayed is given back to the rate
+// limits on release so that it does not use up the limits of the others.
+//
+// Acquire requires:
+// * !now.IsZero()
+//
+// Acquire ensures:
+// * (release == nil) != (refusal == nil)
+func (g *Global) Acquire(descriptor string, domain string, now time.Time) (
+	release func(relayed bool, now time.Time), refusal *Refusal) {
+	// Pre-condition
+	if !(!now.IsZero()) {
+		panic("Violated: !now.IsZero()")
+	}
+
+	// Post-condition
+	defer func() {
+		if !((release == nil) != (refusal == nil)) {
+			panic("Violated: (release == nil) != (refusal == nil)")
+		}
+	}()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	competing := g.competingChannels(descriptor, now)
+	g.lastSeen[descriptor] = now
+
+	// A refused channel competes for the limits until a while after it
+	// should have retried.
+	defer func() {
+		if refusal != nil {
+			g.contending[descriptor] = now.Add(
+				refusal.RetryAfter + ContentionWindow)
+		}
+	}()
+
+	if g.config.Concurrency > 0 {
+		fair := int(math.Ceil(
+			float64(g.config.Concurrency) / float64(competing)))
+		switch {
+		case g.total >= g.config.Concurrency:
+			refusal = &Refusal{Concurrency: true, RetryAfter: ConcurrencyRetry,
+				Reason: fmt.Sprintf("the relay is already sending "+
+					"%d messages at once", g.total)}
+			return
+		case g.inFlight[descriptor] >= fair:
+			refusal = &Refusal{Concurrency: true, RetryAfter: ConcurrencyRetry,
+				Reason: fmt.Sprintf("the channel is already sending its "+
+					"fair share of %d messages at once", fair)}
+			return
+		}
+	}
+
+	buckets := append([]*bucket(nil), g.buckets...)
+	if b, ok := g.domains[domain]; ok {
+		buckets = append(buckets, b)
+	}
+
+	// Take from all the buckets or from none of them.
+	states := make([]*protoed.RateState, len(buckets))
+	shareStates := make([]*protoed.RateState, len(buckets))
+	fairs := make([]Limit, len(buckets))
+	for i, b := range buckets {
+		fair := share(b.limit, competing)
+		fairs[i] = fair
+
+		var ok bool
+		states[i], ok = b.limit.Take(b.state, now)
+		if !ok {
+			refusal = rateRefusal(refusal, b.limit.Wait(b.state, now),
+				fmt.Sprintf("the limit of %s has been reached",
+					describe(b.limit)))
+			continue
+		}
+
+		shareStates[i], ok = fair.Take(b.shares[descriptor], now)
+		if !ok {
+			refusal = rateRefusal(refusal,
+				fair.Wait(b.shares[descriptor], now),
+				fmt.Sprintf("the fair share of %s among %d competing "+
+					"channels has been reached", describe(b.limit), competing))
+		}
+	}
+
+	if refusal != nil {
+		return
+	}
+
+	for i, b := range buckets {
+		b.state = states[i]
+		b.shares[descriptor] = shareStates[i]
+	}
+
+	g.inFlight[descriptor]++
+	g.total++
+
+	var once sync.Once
+	release = func(relayed bool, releasedAt time.Time) {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+
+			g.inFlight[descriptor]--
+			if g.inFlight[descriptor] == 0 {
+				delete(g.inFlight, descriptor)
+			}
+			g.total--
+
+			if relayed {
+				return
+			}
+
+			for i, b := range buckets {
+				b.state = b.limit.Give(b.state, releasedAt)
+				if state, ok := b.shares[descriptor]; ok {
+					b.shares[descriptor] = fairs[i].Give(state, releasedAt)
+				}
+			}
+		})
+	}
+	return
+}
+
+// rateRefusal keeps the refusal with the longest wait.
+func rateRefusal(refusal *Refusal, wait time.Duration,
+	reason string) *Refusal {
+	if refusal != nil && refusal.RetryAfter >= wait {
+		return refusal
+	}
+	return &Refusal{Reason: reason, RetryAfter: wait}
+}
+
+// describe gives a human-readable representation of the limit.
+func describe(limit Limit) string {
+	return fmt.Sprintf("%g messages per second with the burst of %d",
+		limit.Rate, limit.Burst)
+}
@@ -0,0 +1,207 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDomainLimits(t *testing.T) {
+	limits, err := ParseDomainLimits(
+		"marketing.example.com=5/s, news.example.com=120/m")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := map[string]Limit{
+		"marketing.example.com": {Rate: 5, Burst: 5},
+		"news.example.com":      {Rate: 2, Burst: 120}}
+	if !reflect.DeepEqual(expected, limits) {
+		t.Errorf("expected the limits %v, got %v", expected, limits)
+	}
+
+	for _, text := range []string{
+		"marketing.example.com", "marketing.example.com=5",
+		"marketing.example.com=-1/s", "a=1/s,a=2/s"} {
+		if _, err = ParseDomainLimits(text); err == nil {
+			t.Errorf("expected an error for %#v, got nil", text)
+		}
+	}
+}
+
+func TestGlobal_Acquire_Rate(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	g := NewGlobal(GlobalConfig{Limits: []Limit{PerSecond(2)},
+		Domains: map[string]Limit{"slow.example.com": PerMinute(1)}})
+
+	for i := 0; i < 2; i++ {
+		release, refusal := g.Acquire("client-1", "fast.example.com", now)
+		if refusal != nil {
+			t.Fatalf("expected the message %d to be accepted, got %s",
+				i, refusal.Reason)
+		}
+		release(true, now)
+	}
+
+	_, refusal := g.Acquire("client-1", "fast.example.com", now)
+	if refusal == nil || refusal.Concurrency ||
+		refusal.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected the rate limit to be reached with a retry "+
+			"after 500ms, got %#v", refusal)
+	}
+
+	later := now.Add(time.Second)
+	release, refusal := g.Acquire("client-1", "slow.example.com", later)
+	if refusal != nil {
+		t.Fatalf("expected the message to be accepted, got %s",
+			refusal.Reason)
+	}
+	release(true, later)
+
+	// The domain limit applies in addition to the global one.
+	_, refusal = g.Acquire("client-1", "slow.example.com", later)
+	if refusal == nil || refusal.RetryAfter != time.Minute {
+		t.Fatalf("expected the domain limit to be reached with a retry "+
+			"after a minute, got %#v", refusal)
+	}
+}
+
+func TestGlobal_Acquire_GiveBack(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	g := NewGlobal(GlobalConfig{Limits: []Limit{PerSecond(2)}})
+
+	// The messages which have not been relayed do not use up the limit.
+	for i := 0; i < 5; i++ {
+		release, refusal := g.Acquire("client-1", "example.com", now)
+		if refusal != nil {
+			t.Fatalf("expected the message %d to be accepted, got %s",
+				i, refusal.Reason)
+		}
+		release(false, now)
+	}
+
+	for i := 0; i < 2; i++ {
+		release, refusal := g.Acquire("client-1", "example.com", now)
+		if refusal != nil {
+			t.Fatalf("expected the message %d to be accepted, got %s",
+				i, refusal.Reason)
+		}
+		release(true, now)
+	}
+
+	if _, refusal := g.Acquire("client-1", "example.com", now); refusal == nil {
+		t.Fatalf("expected the relayed messages to use up the limit")
+	}
+}
+
+func TestGlobal_Acquire_FairShare(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	g := NewGlobal(GlobalConfig{Limits: []Limit{PerSecond(4)}})
+
+	release, refusal := g.Acquire("client-2", "example.com", now)
+	if refusal != nil {
+		t.Fatal(refusal.Reason)
+	}
+	release(true, now)
+
+	// client-2 does not compete any more so that client-1 can use
+	// the rest of the limit.
+	for i := 0; i < 3; i++ {
+		release, refusal = g.Acquire("client-1", "example.com", now)
+		if refusal != nil {
+			t.Fatalf("expected the message %d to be accepted, got %s",
+				i, refusal.Reason)
+		}
+		release(true, now)
+	}
+
+	if _, refusal = g.Acquire("client-2", "example.com", now); refusal == nil {
+		t.Fatalf("expected client-2 to be refused by the limit")
+	}
+
+	// While client-2 competes, client-1 only gets half of the limit.
+	later := now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		release, refusal = g.Acquire("client-1", "example.com", later)
+		if refusal != nil {
+			t.Fatalf("expected the message %d to be accepted, got %s",
+				i, refusal.Reason)
+		}
+		release(true, later)
+	}
+
+	if _, refusal = g.Acquire("client-1", "example.com", later); refusal == nil {
+		t.Fatalf("expected client-1 to exceed its fair share")
+	}
+
+	release, refusal = g.Acquire("client-2", "example.com", later)
+	if refusal != nil {
+		t.Fatalf("expected client-2 to keep its fair share, got %s",
+			refusal.Reason)
+	}
+	release(true, later)
+
+	// Once nobody has been refused within the contention window, client-1
+	// can use the whole limit again.
+	quiet := later.Add(time.Second + ContentionWindow)
+	for i := 0; i < 4; i++ {
+		release, refusal = g.Acquire("client-1", "example.com", quiet)
+		if refusal != nil {
+			t.Fatalf("expected the message %d to be accepted after "+
+				"the contention, got %s", i, refusal.Reason)
+		}
+		release(true, quiet)
+	}
+}
+
+func TestGlobal_Acquire_Concurrency(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	g := NewGlobal(GlobalConfig{Concurrency: 2})
+
+	releaseFirst, refusal := g.Acquire("client-1", "example.com", now)
+	if refusal != nil {
+		t.Fatal(refusal.Reason)
+	}
+
+	releaseSecond, refusal := g.Acquire("client-2", "example.com", now)
+	if refusal != nil {
+		t.Fatal(refusal.Reason)
+	}
+
+	_, refusal = g.Acquire("client-1", "example.com", now)
+	if refusal == nil || !refusal.Concurrency ||
+		refusal.RetryAfter != ConcurrencyRetry {
+		t.Fatalf("expected the concurrency cap to be reached, got %#v",
+			refusal)
+	}
+
+	releaseSecond(true, now)
+	releaseSecond(true, now)
+
+	// client-1 is not competing with anybody and can use the whole cap.
+	releaseThird, refusal := g.Acquire("client-1", "example.com", now)
+	if refusal != nil {
+		t.Fatalf("expected client-1 to use the free slot, got %s",
+			refusal.Reason)
+	}
+
+	if _, refusal = g.Acquire("client-2", "example.com", now); refusal == nil {
+		t.Fatalf("expected client-2 to be refused by the concurrency cap")
+	}
+
+	releaseThird(true, now)
+
+	// client-1 holds its fair share while client-2 competes.
+	if _, refusal = g.Acquire("client-1", "example.com", now); refusal == nil {
+		t.Fatalf("expected client-1 to exceed its fair share")
+	}
+
+	releaseFirst(true, now)
+
+	release, refusal := g.Acquire("client-1", "example.com", now)
+	if refusal != nil {
+		t.Fatalf("expected the released slot to be free, got %s",
+			refusal.Reason)
+	}
+	release(true, now)
+}
@@ -0,0 +1,244 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// epsilon tolerates the rounding errors when refilling the bucket so that
+// a message arriving exactly after the refill period is not refused.
+const epsilon = 1e-9
+
+// Limit defines the token bucket of a channel.
+type Limit struct {
+	// Rate is the number of messages per second by which the bucket is
+	// refilled; 0 leaves the channel unlimited.
+	Rate float64
+
+	// Burst is the capacity of the bucket.
+	Burst uint32
+}
+
+// Of returns the limit of the channel.
+//
+// The channels without a rate limit are limited by their min_period, i.e.,
+// by a bucket refilled by one message per min_period with the burst of 1.
+//
+// Of requires:
+// * channel != nil
+func Of(channel *protoed.Channel) Limit {
+	// Pre-condition
+	if !(channel != nil) {
+		panic("Violated: channel != nil")
+	}
+
+	if channel.RateLimit != nil {
+		return Limit{Rate: float64(channel.RateLimit.Rate),
+			Burst: channel.RateLimit.Burst}
+	}
+
+	if channel.MinPeriod <= 0 {
+		return Limit{Burst: 1}
+	}
+
+	return Limit{Rate: 1 / float64(channel.MinPeriod), Burst: 1}
+}
+
+// Unlimited indicates that the bucket never runs out of messages.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// millis converts the time to milliseconds since epoch as stored in
+// the rate states.
+func millis(tm time.Time) int64 {
+	return tm.UnixNano() / int64(time.Millisecond)
+}
+
+// Allowance returns the number of messages in the bucket at the given time.
+// A missing state stands for a full bucket.
+func (l Limit) Allowance(state *protoed.RateState, now time.Time) float64 {
+	if state == nil || l.Unlimited() {
+		return float64(l.Burst)
+	}
+
+	elapsed := float64(millis(now)-state.Time) / 1000
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	allowance := state.Allowance + elapsed*l.Rate
+	if allowance > float64(l.Burst) {
+		allowance = float64(l.Burst)
+	}
+
+	return allowance
+}
+
+// Take takes a message from the bucket at the given time. If the bucket
+// holds at least one message, it returns the state of the bucket afterwards;
+// otherwise ok is false and the state is left as-is.
+//
+// Take requires:
+// * !now.IsZero()
+//
+// Take ensures:
+// * !ok || next != nil
+// * !ok || next.Time == millis(now)
+func (l Limit) Take(state *protoed.RateState, now time.Time) (
+	next *protoed.RateState, ok bool) {
+	// Pre-condition
+	if !(!now.IsZero()) {
+		panic("Violated: !now.IsZero()")
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(!ok || next != nil):
+			panic("Violated: !ok || next != nil")
+		case !(!ok || next.Time == millis(now)):
+			panic("Violated: !ok || next.Time == millis(now)")
+		default:
+			// Pass
+		}
+	}()
+
+	if l.Unlimited() {
+		next = &protoed.RateState{Time: millis(now)}
+		ok = true
+		return
+	}
+
+	allowance := l.Allowance(state, now)
+	if allowance < 1-epsilon {
+		return
+	}
+
+	if allowance < 1 {
+		allowance = 1
+	}
+
+	next = &protoed.RateState{Time: millis(now), Allowance: allowance - 1}
+	ok = true
+	return
+}
+
+// Give gives a message taken before back to the bucket at the given time
+// and returns the state of the bucket afterwards. The bucket never holds
+// more than the burst.
+//
+// Give requires:
+// * !now.IsZero()
+//
+// Give ensures:
+// * next != nil
+// * next.Time == millis(now)
+func (l Limit) Give(state *protoed.RateState, now time.Time) (
+	next *protoed.RateState) {
+	// Pre-condition
+	if !(!now.IsZero()) {
+		panic("Violated: !now.IsZero()")
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(next != nil):
+			panic("Violated: next != nil")
+		case !(next.Time == millis(now)):
+			panic("Violated: next.Time == millis(now)")
+		default:
+			// Pass
+		}
+	}()
+
+	if l.Unlimited() {
+		next = &protoed.RateState{Time: millis(now)}
+		return
+	}
+
+	allowance := l.Allowance(state, now) + 1
+	if allowance > float64(l.Burst) {
+		allowance = float64(l.Burst)
+	}
+
+	next = &protoed.RateState{Time: millis(now), Allowance: allowance}
+	return
+}
+
+// Wait returns how long it takes until the bucket holds a message again
+// at the given time; 0 if it already holds one.
+//
+// Wait ensures:
+// * wait >= 0
+func (l Limit) Wait(state *protoed.RateState, now time.Time) (
+	wait time.Duration) {
+	// Post-condition
+	defer func() {
+		if !(wait >= 0) {
+			panic("Violated: wait >= 0")
+		}
+	}()
+
+	if l.Unlimited() {
+		return
+	}
+
+	allowance := l.Allowance(state, now)
+	if allowance >= 1-epsilon {
+		return
+	}
+
+	wait = time.Duration((1 - allowance) / l.Rate * float64(time.Second))
+	return
+}
+
+// Remaining returns the number of whole messages in the bucket at the given
+// time.
+//
+// Remaining ensures:
+// * remaining <= l.Burst
+func (l Limit) Remaining(state *protoed.RateState, now time.Time) (
+	remaining uint32) {
+	// Post-condition
+	defer func() {
+		if !(remaining <= l.Burst) {
+			panic("Violated: remaining <= l.Burst")
+		}
+	}()
+
+	remaining = uint32(l.Allowance(state, now) + epsilon)
+	if remaining > l.Burst {
+		remaining = l.Burst
+	}
+	return
+}
+
+// Refill returns how long it takes until the bucket is full again at
+// the given time; 0 if it is already full.
+//
+// Refill ensures:
+// * refill >= 0
+func (l Limit) Refill(state *protoed.RateState, now time.Time) (
+	refill time.Duration) {
+	// Post-condition
+	defer func() {
+		if !(refill >= 0) {
+			panic("Violated: refill >= 0")
+		}
+	}()
+
+	if l.Unlimited() {
+		return
+	}
+
+	missing := float64(l.Burst) - l.Allowance(state, now)
+	if missing <= epsilon {
+		return
+	}
+
+	refill = time.Duration(missing / l.Rate * float64(time.Second))
+	return
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestOf(t *testing.T) {
+	for _, tt := range []struct {
+		channel  *protoed.Channel
+		expected Limit
+	}{
+		{&protoed.Channel{MinPeriod: 2}, Limit{Rate: 0.5, Burst: 1}},
+		{&protoed.Channel{MinPeriod: 0}, Limit{Burst: 1}},
+		{&protoed.Channel{MinPeriod: 2,
+			RateLimit: &protoed.RateLimit{Rate: 3, Burst: 5}},
+			Limit{Rate: 3, Burst: 5}}} {
+		if got := Of(tt.channel); got != tt.expected {
+			t.Errorf("expected the limit %#v of the channel %v, got %#v",
+				tt.expected, tt.channel, got)
+		}
+	}
+}
+
+func TestLimit_Take(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	limit := Limit{Rate: 1, Burst: 3}
+
+	// A fresh bucket lets the whole burst through at once.
+	var state *protoed.RateState
+	for i := 0; i < 3; i++ {
+		var ok bool
+		state, ok = limit.Take(state, now)
+		if !ok {
+			t.Fatalf("expected the message %d of the burst to be taken", i)
+		}
+	}
+
+	if _, ok := limit.Take(state, now.Add(500*time.Millisecond)); ok {
+		t.Fatalf("expected the empty bucket to refuse the message")
+	}
+
+	next, ok := limit.Take(state, now.Add(time.Second))
+	if !ok {
+		t.Fatalf("expected the refilled bucket to accept the message")
+	}
+	if next.Allowance != 0 {
+		t.Errorf("expected no allowance left, got %f", next.Allowance)
+	}
+
+	// The bucket never holds more than the burst.
+	if got := limit.Allowance(state, now.Add(time.Hour)); got != 3 {
+		t.Errorf("expected the allowance 3 of a full bucket, got %f", got)
+	}
+}
+
+func TestLimit_Take_MinPeriod(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	limit := Of(&protoed.Channel{MinPeriod: 60})
+
+	state, ok := limit.Take(nil, now)
+	if !ok {
+		t.Fatalf("expected the first message to be taken")
+	}
+
+	if _, ok = limit.Take(state, now.Add(59*time.Second)); ok {
+		t.Errorf("expected the message before the min_period to be refused")
+	}
+
+	if _, ok = limit.Take(state, now.Add(60*time.Second)); !ok {
+		t.Errorf("expected the message after the min_period to be taken")
+	}
+
+	unlimited := Of(&protoed.Channel{})
+	for i := 0; i < 10; i++ {
+		if _, ok = unlimited.Take(state, now); !ok {
+			t.Fatalf("expected the unlimited channel to take every message")
+		}
+	}
+}
+
+func TestLimit_Headers(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	limit := Limit{Rate: 0.5, Burst: 3}
+
+	var state *protoed.RateState
+	for i := 0; i < 3; i++ {
+		state, _ = limit.Take(state, now)
+	}
+
+	for _, tt := range []struct {
+		after     time.Duration
+		remaining uint32
+		wait      time.Duration
+		refill    time.Duration
+	}{
+		{0, 0, 2 * time.Second, 6 * time.Second},
+		{time.Second, 0, time.Second, 5 * time.Second},
+		{2 * time.Second, 1, 0, 4 * time.Second},
+		{time.Minute, 3, 0, 0}} {
+		at := now.Add(tt.after)
+		if got := limit.Remaining(state, at); got != tt.remaining {
+			t.Errorf("expected %d remaining messages after %s, got %d",
+				tt.remaining, tt.after, got)
+		}
+		if got := limit.Wait(state, at); got != tt.wait {
+			t.Errorf("expected the wait of %s after %s, got %s",
+				tt.wait, tt.after, got)
+		}
+		if got := limit.Refill(state, at); got != tt.refill {
+			t.Errorf("expected the refill of %s after %s, got %s",
+				tt.refill, tt.after, got)
+		}
+	}
+}
+
+func TestLimit_Give(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+	limit := Of(&protoed.Channel{MinPeriod: 60})
+
+	state, ok := limit.Take(nil, now)
+	if !ok {
+		t.Fatalf("expected the first message to be taken")
+	}
+
+	// The message given back can be taken again right away.
+	state = limit.Give(state, now.Add(time.Second))
+	if _, ok = limit.Take(state, now.Add(time.Second)); !ok {
+		t.Errorf("expected the message given back to be taken")
+	}
+
+	// The bucket never holds more than the burst.
+	state = limit.Give(state, now.Add(time.Hour))
+	if state.Allowance != 1 {
+		t.Errorf("expected the allowance 1 of a full bucket, got %f",
+			state.Allowance)
+	}
+}
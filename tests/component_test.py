@@ -2,6 +2,7 @@
 """Run a component test of Mailgun Relayery."""
 import argparse
 import contextlib
+import copy
 import http
 import http.server
 import json
@@ -11,6 +12,7 @@ import socket
 import subprocess
 import sys
 import threading
+import time
 import uuid
 from typing import Optional, Any, List  # pylint: disable=unused-import
 
@@ -81,6 +83,23 @@ def start_mock_server(port: int, quiet: bool):
     mock_server_thread.start()
 
 
+def strip_token_hashes(pages: tests.control.ChannelsPage) -> Any:
+    """
+    Strip the randomly salted token hashes from a channel listing after checking that they are present.
+
+    :param pages: channel listing as returned by the control server
+    :return: JSON-able representation of the listing without the token hashes
+    """
+    jsonable = pages.to_jsonable()
+    for channel in jsonable['channels']:
+        assert 'token' not in channel, "Expected no plain-text token in the listing, got {}.".format(channel)
+        assert channel.get('token_hash', '').startswith('$argon2id$'), \
+            "Expected an argon2id token hash in the listing, got {}.".format(channel)
+        del channel['token_hash']
+
+    return jsonable
+
+
 def run_test_control(release_dir: pathlib.Path, operation_dir: pathlib.Path, quiet: bool) -> None:
     """
     Test that the mailgun relayery control server works correctly.
@@ -152,13 +171,21 @@ def run_test_control(release_dir: pathlib.Path, operation_dir: pathlib.Path, qui
             max_size=1000000)
         client.put_channel(channel=channel)
 
+        # the listing includes only the hash of the token
+        listed_channel = copy.copy(channel)
+        listed_channel.token = None
+
         # get channels listing to check successful insertion
         pages = client.list_channels()
         assert isinstance(pages, tests.control.ChannelsPage)
-        expected = tests.control.ChannelsPage(page=1, per_page=100, page_count=1, channels=[channel])
-        assert pages.to_jsonable() == expected.to_jsonable(), \
+        expected = tests.control.ChannelsPage(page=1, per_page=100, page_count=1, channels=[listed_channel])
+        assert strip_token_hashes(pages) == expected.to_jsonable(), \
             "Expected empty page listing ({}), got {}.".format(expected.to_jsonable(), pages.to_jsonable())
 
+        got_channel = client.get_channel(descriptor=desc)
+        assert got_channel.descriptor == desc
+        assert got_channel.token_hash is not None
+
         # remove channel
         client.delete_channel(descriptor=desc)
 
@@ -177,15 +204,65 @@ def run_test_control(release_dir: pathlib.Path, operation_dir: pathlib.Path, qui
         # get channels listing to check successful overwrite
         pages = client.list_channels()
         assert isinstance(pages, tests.control.ChannelsPage)
-        expected = tests.control.ChannelsPage(page=1, per_page=100, page_count=1, channels=[channel])
-        assert pages.to_jsonable() == expected.to_jsonable(), \
+        expected = tests.control.ChannelsPage(page=1, per_page=100, page_count=1, channels=[listed_channel])
+        assert strip_token_hashes(pages) == expected.to_jsonable(), \
             "Expected empty page listing ({}), got {}.".format(expected.to_jsonable(), pages.to_jsonable())
 
+        # walk through the channels with the cursor
+        other_channel = copy.copy(channel)
+        other_channel.descriptor = desc + "-other"
+        client.put_channel(channel=other_channel)
+
+        first_page = client.list_channels(per_page=1)
+        assert [chan.descriptor for chan in first_page.channels] == [desc]
+        assert first_page.next_cursor is not None, "Expected a next cursor on the first page."
+
+        second_page = client.list_channels(per_page=1, after=first_page.next_cursor)
+        assert second_page.page == 0
+        assert second_page.page_count == 2
+        assert [chan.descriptor for chan in second_page.channels] == [other_channel.descriptor]
+        assert second_page.next_cursor is None, \
+            "Expected no next cursor on the last page, got {}.".format(second_page.next_cursor)
+
+        # filter the channels
+        filtered = client.list_channels(prefix=desc + "-o")
+        assert [chan.descriptor for chan in filtered.channels] == [other_channel.descriptor]
+
+        filtered = client.list_channels(recipient="Devop-2@some-domain.com")
+        assert [chan.descriptor for chan in filtered.channels] == [desc, other_channel.descriptor]
+
+        filtered = client.list_channels(domain="component.test.com", sender="nobody@some-domain.com")
+        assert filtered.channels == [] and filtered.page_count == 0
+
+        client.delete_channel(descriptor=other_channel.descriptor)
+
         # delete non-existing channel
         resp = client.delete_channel(descriptor=desc + "-suffix")
         expected_resp = b'No channel associated to the descriptor some-channel-name-suffix was found.'
         assert resp == expected_resp, "expected {}, got {}".format(resp, expected_resp)
 
+        # roll back the other channel after its removal
+        revisions = client.list_revisions(descriptor=other_channel.descriptor)
+        assert [revision.revision for revision in revisions.revisions] == [1]
+        client.rollback_channel(descriptor=other_channel.descriptor, rollback=tests.control.Rollback(revision=1))
+        restored = client.list_channels(prefix=other_channel.descriptor)
+        assert [chan.descriptor for chan in restored.channels] == [other_channel.descriptor]
+        client.delete_channel(descriptor=other_channel.descriptor)
+
+        # inspect the audit log of the changes
+        audit = client.get_audit(descriptor=other_channel.descriptor)
+        operations = [record.operation for record in audit.records]
+        assert operations == ['put', 'delete', 'rollback', 'delete'], \
+            "expected put, delete, rollback and delete, got {}".format(operations)
+        assert audit.records[0].before is None and audit.records[0].after is not None
+        assert audit.records[0].after.token is None and audit.records[0].after.token_hash is None
+        assert audit.next_cursor is None
+
+        first_page = client.get_audit(limit=1)
+        assert len(first_page.records) == 1 and first_page.next_cursor is not None
+        second_page = client.get_audit(limit=1, after=first_page.next_cursor)
+        assert second_page.records[0].time > first_page.records[0].time
+
 
 def run_test_relay(release_dir: pathlib.Path, operation_dir: pathlib.Path, quiet: bool) -> None:
     """
@@ -311,7 +388,7 @@ def run_test_relay(release_dir: pathlib.Path, operation_dir: pathlib.Path, quiet
         expected_err = "403 Client Error: Forbidden for url: {}/api/message".format(url_rel)
         assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
 
-        # error 404: non-existing descriptor
+        # error 403: non-existing descriptor, indistinguishable from an invalid token
         wrong_desc = desc + "_suffix"
         http_err = None
         try:
@@ -319,9 +396,58 @@ def run_test_relay(release_dir: pathlib.Path, operation_dir: pathlib.Path, quiet
         except requests.exceptions.HTTPError as err:
             http_err = err
 
-        expected_err = "404 Client Error: Not Found for url: {}/api/message".format(url_rel)
+        expected_err = "403 Client Error: Forbidden for url: {}/api/message".format(url_rel)
         assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
 
+    # query the relay log through the control server
+    # yapf: disable
+    cmd = [str(release_dir / 'bin' / 'mailgun-relay-controlery'),
+           '-database_dir', database_dir.as_posix(),
+           '-address', ':{}'.format(port_ctl)]
+    # yapf: enable
+
+    proc = subprocess.Popen(cmd, stdout=stdout)
+    with tests.proc.terminating(proc=proc, timeout=5):
+        # let the server initialize
+        tests.proc.sleep_while_process(proc=proc, seconds=1)
+
+        if proc.poll() is not None:
+            raise AssertionError("Expected the server process to be alive, but it died.")
+
+        client = tests.control.RemoteCaller('http://127.0.0.1:{}'.format(port_ctl))
+
+        relay_log = client.get_relay_log(descriptor=desc)
+        outcomes = [(record.outcome, record.status) for record in relay_log.records]
+        expected_outcomes = [('relayed', 200), ('forbidden', 403)]
+        assert outcomes == expected_outcomes, "expected {}, got {}".format(expected_outcomes, outcomes)
+        assert not relay_log.more
+        assert relay_log.records[0].subject == message.subject
+
+        relay_log = client.get_relay_log(descriptor=desc, limit=1)
+        assert len(relay_log.records) == 1
+        assert relay_log.more
+
+        relay_log = client.get_relay_log(descriptor=desc, until=relay_log.records[0].time)
+        assert relay_log.records == []
+
+        relay_log = client.get_relay_log(descriptor=desc + "_suffix")
+        assert relay_log.records == []
+
+        # the attempts might straddle the midnight, hence sum up the days
+        stats = client.get_channel_stats(descriptor=desc)
+        assert stats.period == 'day'
+        relayed = sum(usage.relayed for usage in stats.usage)
+        forbidden = sum(usage.rejected.forbidden for usage in stats.usage)
+        assert (relayed, forbidden) == (1, 1), "expected (1, 1), got {}".format((relayed, forbidden))
+        assert sum(usage.bytes for usage in stats.usage) > 0
+
+        stats = client.get_channel_stats(descriptor=desc + "_suffix", period='hour')
+        assert stats.usage == []
+
+        # two failed authentications do not reach the default lockout threshold
+        lockouts = client.get_lockouts()
+        assert lockouts.lockouts == [], "expected no lockouts, got {}".format(lockouts.to_jsonable())
+
 
 def run_test_relay_errors(release_dir: pathlib.Path, operation_dir: pathlib.Path, quiet: bool) -> None:
     """
@@ -450,6 +576,9 @@ def run_test_relay_errors(release_dir: pathlib.Path, operation_dir: pathlib.Path
 
             expected_err = "429 Client Error: Too Many Requests for url: {}/api/message".format(url_rel)
             assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
+            assert int(http_err.response.headers['Retry-After']) >= 1
+            assert http_err.response.headers['X-RateLimit-Limit'] == '1'
+            assert http_err.response.headers['X-RateLimit-Remaining'] == '0'
 
             # error 413: request entity too large
             http_err = None
@@ -461,6 +590,122 @@ def run_test_relay_errors(release_dir: pathlib.Path, operation_dir: pathlib.Path
             expected_err = "413 Client Error: Request Entity Too Large for url: {}/api/message".format(url_rel)
             assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
 
+            # error 423: the channel has been disabled
+            client_ctl.disable_channel(
+                descriptor=desc_small_max_size, disable=tests.control.Disable(reason="sends too large messages"))
+
+            disabled = client_ctl.list_channels(disabled=True)
+            assert [chan.descriptor for chan in disabled.channels] == [desc_small_max_size]
+            assert disabled.channels[0].disabled.reason == "sends too large messages"
+
+            http_err = None
+            try:
+                _ = client_rel.put_message(x_descriptor=desc_small_max_size, x_token=token, message=message)
+            except requests.exceptions.HTTPError as err:
+                http_err = err
+
+            expected_err = "423 Client Error: Locked for url: {}/api/message".format(url_rel)
+            assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
+            assert http_err.response.headers['X-Disabled-Reason'] == "sends too large messages"
+
+            client_ctl.enable_channel(descriptor=desc_small_max_size)
+            assert client_ctl.list_channels(disabled=True).channels == []
+
+            # error 410: the channel has expired
+            desc_expired = "expired-channel"
+            client_ctl.put_channel(
+                channel=tests.control.Channel(
+                    descriptor=desc_expired,
+                    token=token,
+                    sender=tests.control.Entity(email="someone@some-domain.com"),
+                    recipients=[tests.control.Entity(email="client@another-domain.com")],
+                    domain="component.test.com",
+                    min_period=0,
+                    max_size=1000000,
+                    valid_until="2018-10-31T23:59:59Z"))
+
+            http_err = None
+            try:
+                _ = client_rel.put_message(x_descriptor=desc_expired, x_token=token, message=message)
+            except requests.exceptions.HTTPError as err:
+                http_err = err
+
+            expected_err = "410 Client Error: Gone for url: {}/api/message".format(url_rel)
+            assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
+
+            client_ctl.delete_channel(descriptor=desc_expired)
+
+            # error 429: the message would exceed the quota of the channel
+            desc_quota = "quota-channel"
+            client_ctl.put_channel(
+                channel=tests.control.Channel(
+                    descriptor=desc_quota,
+                    token=token,
+                    sender=tests.control.Entity(email="someone@some-domain.com"),
+                    recipients=[tests.control.Entity(email="client@another-domain.com")],
+                    domain="component.test.com",
+                    min_period=0,
+                    max_size=1000000,
+                    quota=tests.control.Quota(daily_bytes=1, timezone="Europe/Zurich")))
+
+            http_err = None
+            try:
+                _ = client_rel.put_message(x_descriptor=desc_quota, x_token=token, message=message)
+            except requests.exceptions.HTTPError as err:
+                http_err = err
+
+            expected_err = "429 Client Error: Too Many Requests for url: {}/api/message".format(url_rel)
+            assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
+            assert 'X-Quota-Reset' in http_err.response.headers
+            assert int(http_err.response.headers['Retry-After']) >= 1
+
+            client_ctl.delete_channel(descriptor=desc_quota)
+
+            # rotate the token of a channel without a grace period
+            desc_rotated = "rotated-channel"
+            client_ctl.put_channel(
+                channel=tests.control.Channel(
+                    descriptor=desc_rotated,
+                    token=token,
+                    sender=tests.control.Entity(email="someone@some-domain.com"),
+                    recipients=[tests.control.Entity(email="client@another-domain.com")],
+                    domain="component.test.com",
+                    min_period=0,
+                    max_size=1000000))
+
+            issued = client_ctl.rotate_token(
+                descriptor=desc_rotated, rotate=tests.control.Rotate(name="rotated", grace_period=0))
+            assert issued.name == "rotated"
+
+            http_err = None
+            try:
+                _ = client_rel.put_message(x_descriptor=desc_rotated, x_token=token, message=message)
+            except requests.exceptions.HTTPError as err:
+                http_err = err
+
+            expected_err = "403 Client Error: Forbidden for url: {}/api/message".format(url_rel)
+            assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
+
+            resp = client_rel.put_message(x_descriptor=desc_rotated, x_token=issued.token, message=message)
+            assert resp == b'The message has been correctly relayed.', "got {}".format(resp)
+
+            rotated = client_ctl.list_channels(prefix=desc_rotated).channels[0]
+            assert [tok.name for tok in rotated.tokens] == ["default", "rotated"]
+            assert rotated.tokens[1].last_used is not None
+
+            # the last live token can not be revoked
+            http_err = None
+            try:
+                _ = client_ctl.revoke_token(descriptor=desc_rotated, name="rotated")
+            except requests.exceptions.HTTPError as err:
+                http_err = err
+
+            expected_err = "409 Client Error: Conflict for url: {}/api/channel/{}/tokens/rotated/revoke".format(
+                url, desc_rotated)
+            assert http_err.__str__() == expected_err, "expected {}, got {}".format(expected_err, http_err)
+
+            client_ctl.delete_channel(descriptor=desc_rotated)
+
             # overwrite a channel with a different (still very large) min_period
             sender = tests.control.Entity(email="someone@some-domain.com")
             recipients = [
@@ -491,6 +736,74 @@ def run_test_relay_errors(release_dir: pathlib.Path, operation_dir: pathlib.Path
             assert len(CORRECT_REQUESTS) == 2
             assert len(WRONG_REQUESTS) == 0
 
+            # an invalid message does not consume the slot of the min_period
+            desc_retry = "retry-channel"
+            client_ctl.put_channel(
+                channel=tests.control.Channel(
+                    descriptor=desc_retry,
+                    token=token,
+                    sender=sender,
+                    recipients=recipients,
+                    domain="component.test.com",
+                    min_period=60,
+                    max_size=1000000))
+
+            resp = requests.post(
+                url_rel + '/api/message',
+                headers={'X-Descriptor': desc_retry, 'X-Token': token},
+                data='{"subject": "missing the content"}')
+            assert resp.status_code == 400, "expected 400, got {}".format(resp.status_code)
+            assert resp.headers['X-RateLimit-Limit'] == '1'
+
+            resp = client_rel.put_message(x_descriptor=desc_retry, x_token=token, message=message)
+            assert resp == expected, "expected {}, got {}".format(expected, resp)
+            assert len(CORRECT_REQUESTS) == 3
+
+            # the messages exceeding the rate limit of a channel in the queue mode are queued and relayed later
+            desc_queue = "queue-channel"
+            client_ctl.put_channel(
+                channel=tests.control.Channel(
+                    descriptor=desc_queue,
+                    token=token,
+                    sender=sender,
+                    recipients=recipients,
+                    domain="component.test.com",
+                    min_period=1,
+                    max_size=1000000,
+                    on_throttle="queue",
+                    max_queue_depth=1))
+
+            resp = client_rel.put_message(x_descriptor=desc_queue, x_token=token, message=message)
+            assert resp == expected, "expected {}, got {}".format(expected, resp)
+            assert len(CORRECT_REQUESTS) == 4
+
+            resp = requests.post(
+                url_rel + '/api/message',
+                headers={'X-Descriptor': desc_queue, 'X-Token': token},
+                json=message.to_jsonable())
+            assert resp.status_code == 202, "expected 202, got {}".format(resp.status_code)
+            queue_id = resp.headers['X-Queue-Id']
+            assert resp.headers['Location'] == '/api/queue/{}'.format(queue_id)
+
+            # the queue holds a single message
+            resp = requests.post(
+                url_rel + '/api/message',
+                headers={'X-Descriptor': desc_queue, 'X-Token': token},
+                json=message.to_jsonable())
+            assert resp.status_code == 429, "expected 429, got {}".format(resp.status_code)
+
+            status = None  # type: Optional[tests.relay.QueueStatus]
+            for _ in range(100):
+                status = client_rel.get_queued(id=queue_id, x_descriptor=desc_queue, x_token=token)
+                if status.status != "queued":
+                    break
+                time.sleep(0.1)
+
+            assert status is not None
+            assert status.status == "relayed", "expected relayed, got {}".format(status.status)
+            assert status.message_id is not None
+            assert len(CORRECT_REQUESTS) == 5
+
 
 def find_free_port() -> int:
     """
@@ -68,6 +68,69 @@ def from_obj(obj: Any, expected: List[type], path: str = '') -> Any:
     if exp == ChannelsPage:
         return channels_page_from_obj(obj, path=path)
 
+    if exp == RelayRecord:
+        return relay_record_from_obj(obj, path=path)
+
+    if exp == RelayLog:
+        return relay_log_from_obj(obj, path=path)
+
+    if exp == AuditRecord:
+        return audit_record_from_obj(obj, path=path)
+
+    if exp == AuditLog:
+        return audit_log_from_obj(obj, path=path)
+
+    if exp == FieldChange:
+        return field_change_from_obj(obj, path=path)
+
+    if exp == ChannelRevision:
+        return channel_revision_from_obj(obj, path=path)
+
+    if exp == ChannelRevisions:
+        return channel_revisions_from_obj(obj, path=path)
+
+    if exp == Rollback:
+        return rollback_from_obj(obj, path=path)
+
+    if exp == Disabled:
+        return disabled_from_obj(obj, path=path)
+
+    if exp == Disable:
+        return disable_from_obj(obj, path=path)
+
+    if exp == ChannelToken:
+        return channel_token_from_obj(obj, path=path)
+
+    if exp == RateLimit:
+        return rate_limit_from_obj(obj, path=path)
+
+    if exp == Quota:
+        return quota_from_obj(obj, path=path)
+
+    if exp == Rotate:
+        return rotate_from_obj(obj, path=path)
+
+    if exp == IssuedToken:
+        return issued_token_from_obj(obj, path=path)
+
+    if exp == ChannelStats:
+        return channel_stats_from_obj(obj, path=path)
+
+    if exp == Usage:
+        return usage_from_obj(obj, path=path)
+
+    if exp == Rejections:
+        return rejections_from_obj(obj, path=path)
+
+    if exp == Lockout:
+        return lockout_from_obj(obj, path=path)
+
+    if exp == Lockouts:
+        return lockouts_from_obj(obj, path=path)
+
+    if exp == Unlock:
+        return unlock_from_obj(obj, path=path)
+
     raise ValueError("Unexpected `expected` type: {}".format(exp))
 
 
@@ -137,6 +200,90 @@ def to_jsonable(obj: Any, expected: List[type], path: str = "") -> Any:
         assert isinstance(obj, ChannelsPage)
         return channels_page_to_jsonable(obj, path=path)
 
+    if exp == RelayRecord:
+        assert isinstance(obj, RelayRecord)
+        return relay_record_to_jsonable(obj, path=path)
+
+    if exp == RelayLog:
+        assert isinstance(obj, RelayLog)
+        return relay_log_to_jsonable(obj, path=path)
+
+    if exp == AuditRecord:
+        assert isinstance(obj, AuditRecord)
+        return audit_record_to_jsonable(obj, path=path)
+
+    if exp == AuditLog:
+        assert isinstance(obj, AuditLog)
+        return audit_log_to_jsonable(obj, path=path)
+
+    if exp == FieldChange:
+        assert isinstance(obj, FieldChange)
+        return field_change_to_jsonable(obj, path=path)
+
+    if exp == ChannelRevision:
+        assert isinstance(obj, ChannelRevision)
+        return channel_revision_to_jsonable(obj, path=path)
+
+    if exp == ChannelRevisions:
+        assert isinstance(obj, ChannelRevisions)
+        return channel_revisions_to_jsonable(obj, path=path)
+
+    if exp == Rollback:
+        assert isinstance(obj, Rollback)
+        return rollback_to_jsonable(obj, path=path)
+
+    if exp == Disabled:
+        assert isinstance(obj, Disabled)
+        return disabled_to_jsonable(obj, path=path)
+
+    if exp == Disable:
+        assert isinstance(obj, Disable)
+        return disable_to_jsonable(obj, path=path)
+
+    if exp == ChannelToken:
+        assert isinstance(obj, ChannelToken)
+        return channel_token_to_jsonable(obj, path=path)
+
+    if exp == RateLimit:
+        assert isinstance(obj, RateLimit)
+        return rate_limit_to_jsonable(obj, path=path)
+
+    if exp == Quota:
+        assert isinstance(obj, Quota)
+        return quota_to_jsonable(obj, path=path)
+
+    if exp == Rotate:
+        assert isinstance(obj, Rotate)
+        return rotate_to_jsonable(obj, path=path)
+
+    if exp == IssuedToken:
+        assert isinstance(obj, IssuedToken)
+        return issued_token_to_jsonable(obj, path=path)
+
+    if exp == ChannelStats:
+        assert isinstance(obj, ChannelStats)
+        return channel_stats_to_jsonable(obj, path=path)
+
+    if exp == Usage:
+        assert isinstance(obj, Usage)
+        return usage_to_jsonable(obj, path=path)
+
+    if exp == Rejections:
+        assert isinstance(obj, Rejections)
+        return rejections_to_jsonable(obj, path=path)
+
+    if exp == Lockout:
+        assert isinstance(obj, Lockout)
+        return lockout_to_jsonable(obj, path=path)
+
+    if exp == Lockouts:
+        assert isinstance(obj, Lockouts)
+        return lockouts_to_jsonable(obj, path=path)
+
+    if exp == Unlock:
+        assert isinstance(obj, Unlock)
+        return unlock_to_jsonable(obj, path=path)
+
     raise ValueError("Unexpected `expected` type: {}".format(exp))
 
 
@@ -206,62 +353,130 @@ def entity_to_jsonable(entity: Entity, path: str = "") -> MutableMapping[str, An
     return res
 
 
-class Channel:
-    """Defines the messaging channel."""
+class Disabled:
+    """
+    Indicates that the channel has been disabled and relays no messages.
 
-    def __init__(self,
-                 descriptor: str,
-                 token: str,
-                 sender: Entity,
-                 recipients: List[Entity],
-                 domain: str,
-                 min_period: float,
-                 max_size: int,
-                 cc: Optional[List[Entity]] = None,
-                 bcc: Optional[List[Entity]] = None) -> None:
+    If omitted when a channel is stored, an existing channel keeps its current state.
+    """
+
+    def __init__(self, time: str, reason: Optional[str] = None) -> None:
         """Initializes with the given values."""
-        self.descriptor = descriptor
+        # is the time when the channel has been disabled in RFC 3339 format with nanoseconds.
+        self.time = time
 
-        self.token = token
+        # is the reason why the channel has been disabled; absent if not given.
+        self.reason = reason
 
-        self.sender = sender
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to disabled_to_jsonable.
 
-        self.recipients = recipients
+        :return: JSON-able representation
+        """
+        return disabled_to_jsonable(self)
 
-        # indicates the MailGun domain for the channel.
-        self.domain = domain
 
-        # is the minimum push period frequency for a channel, in seconds.
-        self.min_period = min_period
+def new_disabled() -> Disabled:
+    """Generates an instance of Disabled with default values."""
+    return Disabled(time='')
 
-        # indicates the maximum allowed size of the request, in bytes.
-        self.max_size = max_size
 
-        self.cc = cc
+def disabled_from_obj(obj: Any, path: str = "") -> Disabled:
+    """
+    Generates an instance of Disabled from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Disabled
+    :param path: path to the object used for debugging
+    :return: parsed instance of Disabled
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    time_from_obj = from_obj(obj['time'], expected=[str], path=path + '.time')  # type: str
+
+    if 'reason' in obj:
+        reason_from_obj = from_obj(obj['reason'], expected=[str], path=path + '.reason')  # type: Optional[str]
+    else:
+        reason_from_obj = None
+
+    return Disabled(time=time_from_obj, reason=reason_from_obj)
+
+
+def disabled_to_jsonable(disabled: Disabled, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Disabled.
+
+    :param disabled: instance of Disabled to be JSON-ized
+    :param path: path to the disabled used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['time'] = disabled.time
+
+    if disabled.reason is not None:
+        res['reason'] = disabled.reason
+
+    return res
 
-        self.bcc = bcc
+
+class ChannelToken:
+    """
+    Is a named token authenticating the senders of a channel.
+
+    The Relay server accepts the messages authenticated by any live token.
+    """
+
+    def __init__(self,
+                 name: str,
+                 token_hash: str,
+                 created: Optional[str] = None,
+                 expires: Optional[str] = None,
+                 last_used: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # is the name of the token, unique within the channel.
+        self.name = name
+
+        # is the salted hash of the token in the PHC string format.
+        self.token_hash = token_hash
+
+        # is the time when the token has been issued in RFC 3339 format; absent if unknown.
+        self.created = created
+
+        # is the time in RFC 3339 format after which the token is rejected; absent if it never expires.
+        self.expires = expires
+
+        # is the time in RFC 3339 format when the token last authenticated a message; absent if never.
+        #
+        # The field is only listed and ignored when a channel is stored.
+        self.last_used = last_used
 
     def to_jsonable(self) -> MutableMapping[str, Any]:
         """
-        Dispatches the conversion to channel_to_jsonable.
+        Dispatches the conversion to channel_token_to_jsonable.
 
         :return: JSON-able representation
         """
-        return channel_to_jsonable(self)
+        return channel_token_to_jsonable(self)
 
 
-def new_channel() -> Channel:
-    """Generates an instance of Channel with default values."""
-    return Channel(descriptor='', token='', sender=new_entity(), recipients=[], domain='', min_period=0.0, max_size=0)
+def new_channel_token() -> ChannelToken:
+    """Generates an instance of ChannelToken with default values."""
+    return ChannelToken(name='', token_hash='')
 
 
-def channel_from_obj(obj: Any, path: str = "") -> Channel:
+def channel_token_from_obj(obj: Any, path: str = "") -> ChannelToken:
     """
-    Generates an instance of Channel from a dictionary object.
+    Generates an instance of ChannelToken from a dictionary object.
 
-    :param obj: a JSON-ed dictionary object representing an instance of Channel
+    :param obj: a JSON-ed dictionary object representing an instance of ChannelToken
     :param path: path to the object used for debugging
-    :return: parsed instance of Channel
+    :return: parsed instance of ChannelToken
     """
     if not isinstance(obj, dict):
         raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
@@ -270,114 +485,96 @@ def channel_from_obj(obj: Any, path: str = "") -> Channel:
         if not isinstance(key, str):
             raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
 
-    descriptor_from_obj = from_obj(obj['descriptor'], expected=[str], path=path + '.descriptor')  # type: str
-
-    token_from_obj = from_obj(obj['token'], expected=[str], path=path + '.token')  # type: str
-
-    sender_from_obj = from_obj(obj['sender'], expected=[Entity], path=path + '.sender')  # type: Entity
-
-    recipients_from_obj = from_obj(
-        obj['recipients'], expected=[list, Entity], path=path + '.recipients')  # type: List[Entity]
+    name_from_obj = from_obj(obj['name'], expected=[str], path=path + '.name')  # type: str
 
-    domain_from_obj = from_obj(obj['domain'], expected=[str], path=path + '.domain')  # type: str
-
-    min_period_from_obj = from_obj(obj['min_period'], expected=[float], path=path + '.min_period')  # type: float
+    token_hash_from_obj = from_obj(obj['token_hash'], expected=[str], path=path + '.token_hash')  # type: str
 
-    max_size_from_obj = from_obj(obj['max_size'], expected=[int], path=path + '.max_size')  # type: int
+    if 'created' in obj:
+        created_from_obj = from_obj(obj['created'], expected=[str], path=path + '.created')  # type: Optional[str]
+    else:
+        created_from_obj = None
 
-    if 'cc' in obj:
-        cc_from_obj = from_obj(obj['cc'], expected=[list, Entity], path=path + '.cc')  # type: Optional[List[Entity]]
+    if 'expires' in obj:
+        expires_from_obj = from_obj(obj['expires'], expected=[str], path=path + '.expires')  # type: Optional[str]
     else:
-        cc_from_obj = None
+        expires_from_obj = None
 
-    if 'bcc' in obj:
-        bcc_from_obj = from_obj(obj['bcc'], expected=[list, Entity], path=path + '.bcc')  # type: Optional[List[Entity]]
+    if 'last_used' in obj:
+        last_used_from_obj = from_obj(obj['last_used'], expected=[str], path=path + '.last_used')  # type: Optional[str]
     else:
-        bcc_from_obj = None
+        last_used_from_obj = None
 
-    return Channel(
-        descriptor=descriptor_from_obj,
-        token=token_from_obj,
-        sender=sender_from_obj,
-        recipients=recipients_from_obj,
-        domain=domain_from_obj,
-        min_period=min_period_from_obj,
-        max_size=max_size_from_obj,
-        cc=cc_from_obj,
-        bcc=bcc_from_obj)
+    return ChannelToken(
+        name=name_from_obj,
+        token_hash=token_hash_from_obj,
+        created=created_from_obj,
+        expires=expires_from_obj,
+        last_used=last_used_from_obj)
 
 
-def channel_to_jsonable(channel: Channel, path: str = "") -> MutableMapping[str, Any]:
+def channel_token_to_jsonable(channel_token: ChannelToken, path: str = "") -> MutableMapping[str, Any]:
     """
-    Generates a JSON-able mapping from an instance of Channel.
+    Generates a JSON-able mapping from an instance of ChannelToken.
 
-    :param channel: instance of Channel to be JSON-ized
-    :param path: path to the channel used for debugging
+    :param channel_token: instance of ChannelToken to be JSON-ized
+    :param path: path to the channel_token used for debugging
     :return: a JSON-able representation
     """
     res = dict()  # type: Dict[str, Any]
 
-    res['descriptor'] = channel.descriptor
-
-    res['token'] = channel.token
-
-    res['sender'] = to_jsonable(channel.sender, expected=[Entity], path='{}.sender'.format(path))
-
-    res['recipients'] = to_jsonable(channel.recipients, expected=[list, Entity], path='{}.recipients'.format(path))
-
-    res['domain'] = channel.domain
+    res['name'] = channel_token.name
 
-    res['min_period'] = channel.min_period
+    res['token_hash'] = channel_token.token_hash
 
-    res['max_size'] = channel.max_size
+    if channel_token.created is not None:
+        res['created'] = channel_token.created
 
-    if channel.cc is not None:
-        res['cc'] = to_jsonable(channel.cc, expected=[list, Entity], path='{}.cc'.format(path))
+    if channel_token.expires is not None:
+        res['expires'] = channel_token.expires
 
-    if channel.bcc is not None:
-        res['bcc'] = to_jsonable(channel.bcc, expected=[list, Entity], path='{}.bcc'.format(path))
+    if channel_token.last_used is not None:
+        res['last_used'] = channel_token.last_used
 
     return res
 
 
-class ChannelsPage:
-    """Lists channels in a paginated manner."""
-
-    def __init__(self, page: int, page_count: int, per_page: int, channels: List[Channel]) -> None:
-        """Initializes with the given values."""
-        # specifies the index of the page.
-        self.page = page
+class RateLimit:
+    """
+    Defines the token bucket limiting the rate of the messages of a channel.
 
-        # specifies the number of pages available.
-        self.page_count = page_count
+    The bucket holds at most burst messages and is refilled by rate messages per second. If the rate limit
+    is absent, the channel is limited by its min_period as if the bucket held a single message.
+    """
 
-        # specifies the number of items per page.
-        self.per_page = per_page
+    def __init__(self, rate: float, burst: int) -> None:
+        """Initializes with the given values."""
+        # is the number of messages per second by which the bucket is refilled.
+        self.rate = rate
 
-        # contains the channel data.
-        self.channels = channels
+        # is the maximum number of messages which can be relayed at once.
+        self.burst = burst
 
     def to_jsonable(self) -> MutableMapping[str, Any]:
         """
-        Dispatches the conversion to channels_page_to_jsonable.
+        Dispatches the conversion to rate_limit_to_jsonable.
 
         :return: JSON-able representation
         """
-        return channels_page_to_jsonable(self)
+        return rate_limit_to_jsonable(self)
 
 
-def new_channels_page() -> ChannelsPage:
-    """Generates an instance of ChannelsPage with default values."""
-    return ChannelsPage(page=0, page_count=0, per_page=0, channels=[])
+def new_rate_limit() -> RateLimit:
+    """Generates an instance of RateLimit with default values."""
+    return RateLimit(rate=0.0, burst=0)
 
 
-def channels_page_from_obj(obj: Any, path: str = "") -> ChannelsPage:
+def rate_limit_from_obj(obj: Any, path: str = "") -> RateLimit:
     """
-    Generates an instance of ChannelsPage from a dictionary object.
+    Generates an instance of RateLimit from a dictionary object.
 
-    :param obj: a JSON-ed dictionary object representing an instance of ChannelsPage
+    :param obj: a JSON-ed dictionary object representing an instance of RateLimit
     :param path: path to the object used for debugging
-    :return: parsed instance of ChannelsPage
+    :return: parsed instance of RateLimit
     """
     if not isinstance(obj, dict):
         raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
@@ -386,101 +583,2301 @@ def channels_page_from_obj(obj: Any, path: str = "") -> ChannelsPage:
         if not isinstance(key, str):
             raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
 
-    page_from_obj = from_obj(obj['page'], expected=[int], path=path + '.page')  # type: int
-
-    page_count_from_obj = from_obj(obj['page_count'], expected=[int], path=path + '.page_count')  # type: int
-
-    per_page_from_obj = from_obj(obj['per_page'], expected=[int], path=path + '.per_page')  # type: int
+    rate_from_obj = from_obj(obj['rate'], expected=[float], path=path + '.rate')  # type: float
 
-    channels_from_obj = from_obj(
-        obj['channels'], expected=[list, Channel], path=path + '.channels')  # type: List[Channel]
+    burst_from_obj = from_obj(obj['burst'], expected=[int], path=path + '.burst')  # type: int
 
-    return ChannelsPage(
-        page=page_from_obj, page_count=page_count_from_obj, per_page=per_page_from_obj, channels=channels_from_obj)
+    return RateLimit(rate=rate_from_obj, burst=burst_from_obj)
 
 
-def channels_page_to_jsonable(channels_page: ChannelsPage, path: str = "") -> MutableMapping[str, Any]:
+def rate_limit_to_jsonable(rate_limit: RateLimit, path: str = "") -> MutableMapping[str, Any]:
     """
-    Generates a JSON-able mapping from an instance of ChannelsPage.
+    Generates a JSON-able mapping from an instance of RateLimit.
 
-    :param channels_page: instance of ChannelsPage to be JSON-ized
-    :param path: path to the channels_page used for debugging
+    :param rate_limit: instance of RateLimit to be JSON-ized
+    :param path: path to the rate_limit used for debugging
     :return: a JSON-able representation
     """
     res = dict()  # type: Dict[str, Any]
 
-    res['page'] = channels_page.page
+    res['rate'] = rate_limit.rate
 
-    res['page_count'] = channels_page.page_count
+    res['burst'] = rate_limit.burst
 
-    res['per_page'] = channels_page.per_page
+    return res
 
-    res['channels'] = to_jsonable(channels_page.channels, expected=[list, Channel], path='{}.channels'.format(path))
 
-    return res
+class Quota:
+    """
+    Defines the hard quotas of a channel per calendar day and month.
 
+    The Relay server refuses the messages which would exceed a quota until the quota resets at the start
+    of the next day or month in the time zone of the quota. The messages are counted only while the channel
+    has a quota.
+    """
 
-class RemoteCaller:
-    """Executes the remote calls to the server."""
+    def __init__(self,
+                 daily_messages: Optional[int] = None,
+                 monthly_messages: Optional[int] = None,
+                 daily_bytes: Optional[int] = None,
+                 monthly_bytes: Optional[int] = None,
+                 timezone: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # is the maximum number of the relayed messages per day; absent or 0 if unlimited.
+        self.daily_messages = daily_messages
 
-    def __init__(self, url_prefix: str, auth: Optional[requests.auth.AuthBase] = None) -> None:
-        self.url_prefix = url_prefix
-        self.auth = auth
+        # is the maximum number of the relayed messages per month; absent or 0 if unlimited.
+        self.monthly_messages = monthly_messages
 
-    def put_channel(self, channel: Channel) -> bytes:
-        """
-        Updates the channel uniquely identified by a descriptor.
+        # is the maximum total size of the relayed messages in bytes per day; absent or 0 if unlimited.
+        self.daily_bytes = daily_bytes
 
-        If there is already a channel associated with the descriptor, the old channel is overwritten with the new one.
+        # is the maximum total size of the relayed messages in bytes per month; absent or 0 if unlimited.
+        self.monthly_bytes = monthly_bytes
 
-        In order to enforce the min_period between messages, the Relay server keeps track of the time of the most
-        recently relayed message for each descriptor. If a channel is overwritten, the time of relay of the most
-        recent message is erased unless the new channel has the same min_period field as the old one.
+        # is the IANA time zone in which the days and the months start; absent for UTC.
+        self.timezone = timezone
 
-        :param channel:
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to quota_to_jsonable.
 
-        :return: signals that the channel update was accepted.
+        :return: JSON-able representation
         """
-        url = self.url_prefix + '/api/channel'
+        return quota_to_jsonable(self)
 
-        data = to_jsonable(channel, expected=[Channel])
 
-        resp = requests.request(method='put', url=url, json=data, auth=self.auth)
+def new_quota() -> Quota:
+    """Generates an instance of Quota with default values."""
+    return Quota()
 
-        with contextlib.closing(resp):
-            resp.raise_for_status()
-            return resp.content
 
-    def delete_channel(self, descriptor: str) -> bytes:
-        """
-        Removes the channel associated with the descriptor.
+def quota_from_obj(obj: Any, path: str = "") -> Quota:
+    """
+    Generates an instance of Quota from a dictionary object.
 
-        :param descriptor:
+    :param obj: a JSON-ed dictionary object representing an instance of Quota
+    :param path: path to the object used for debugging
+    :return: parsed instance of Quota
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
 
-        :return: signals that the channel was correctly erased, or that the channel was not found.
-        """
-        url = self.url_prefix + '/api/channel'
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
 
-        data = descriptor
+    if 'daily_messages' in obj:
+        daily_messages_from_obj = from_obj(
+            obj['daily_messages'], expected=[int], path=path + '.daily_messages')  # type: Optional[int]
+    else:
+        daily_messages_from_obj = None
 
-        resp = requests.request(method='delete', url=url, json=data, auth=self.auth)
+    if 'monthly_messages' in obj:
+        monthly_messages_from_obj = from_obj(
+            obj['monthly_messages'], expected=[int], path=path + '.monthly_messages')  # type: Optional[int]
+    else:
+        monthly_messages_from_obj = None
 
-        with contextlib.closing(resp):
-            resp.raise_for_status()
-            return resp.content
+    if 'daily_bytes' in obj:
+        daily_bytes_from_obj = from_obj(
+            obj['daily_bytes'], expected=[int], path=path + '.daily_bytes')  # type: Optional[int]
+    else:
+        daily_bytes_from_obj = None
 
-    def list_channels(self, page: Optional[int] = None, per_page: Optional[int] = None) -> ChannelsPage:
-        """
-        Lists the available channels information.
+    if 'monthly_bytes' in obj:
+        monthly_bytes_from_obj = from_obj(
+            obj['monthly_bytes'], expected=[int], path=path + '.monthly_bytes')  # type: Optional[int]
+    else:
+        monthly_bytes_from_obj = None
 
-        :param page: specifies the index of a page. The default is 1 (first page).
-        :param per_page: specifies a desired number of items per page. The default is 100.
+    if 'timezone' in obj:
+        timezone_from_obj = from_obj(obj['timezone'], expected=[str], path=path + '.timezone')  # type: Optional[str]
+    else:
+        timezone_from_obj = None
 
-        :return: serves the channel information list.
+    return Quota(
+        daily_messages=daily_messages_from_obj,
+        monthly_messages=monthly_messages_from_obj,
+        daily_bytes=daily_bytes_from_obj,
+        monthly_bytes=monthly_bytes_from_obj,
+        timezone=timezone_from_obj)
+
+
+def quota_to_jsonable(quota: Quota, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Quota.
+
+    :param quota: instance of Quota to be JSON-ized
+    :param path: path to the quota used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    if quota.daily_messages is not None:
+        res['daily_messages'] = quota.daily_messages
+
+    if quota.monthly_messages is not None:
+        res['monthly_messages'] = quota.monthly_messages
+
+    if quota.daily_bytes is not None:
+        res['daily_bytes'] = quota.daily_bytes
+
+    if quota.monthly_bytes is not None:
+        res['monthly_bytes'] = quota.monthly_bytes
+
+    if quota.timezone is not None:
+        res['timezone'] = quota.timezone
+
+    return res
+
+
+class Channel:
+    """Defines the messaging channel."""
+
+    def __init__(self,
+                 descriptor: str,
+                 sender: Entity,
+                 recipients: List[Entity],
+                 domain: str,
+                 min_period: float,
+                 max_size: int,
+                 token: Optional[str] = None,
+                 cc: Optional[List[Entity]] = None,
+                 bcc: Optional[List[Entity]] = None,
+                 token_hash: Optional[str] = None,
+                 disabled: Optional[Disabled] = None,
+                 valid_until: Optional[str] = None,
+                 tokens: Optional[List[ChannelToken]] = None,
+                 rate_limit: Optional[RateLimit] = None,
+                 quota: Optional[Quota] = None,
+                 on_throttle: Optional[str] = None,
+                 max_queue_depth: Optional[int] = None) -> None:
+        """Initializes with the given values."""
+        self.descriptor = descriptor
+
+        self.sender = sender
+
+        self.recipients = recipients
+
+        # indicates the MailGun domain for the channel.
+        self.domain = domain
+
+        # is the minimum push period frequency for a channel, in seconds.
+        self.min_period = min_period
+
+        # indicates the maximum allowed size of the request, in bytes.
+        self.max_size = max_size
+
+        self.token = token
+
+        self.cc = cc
+
+        self.bcc = bcc
+
+        # is the salted hash of the default token in the PHC string format.
+        #
+        # Listings never include the token, only its hash.
+        self.token_hash = token_hash
+
+        self.disabled = disabled
+
+        # is the time in RFC 3339 format after which the channel expires; absent if the channel never expires.
+        #
+        # The Relay server refuses the messages of an expired channel. The Control server removes or disables
+        # the expired channels after a grace period.
+        self.valid_until = valid_until
+
+        # lists the named tokens of the channel in addition to the default one.
+        self.tokens = tokens
+
+        self.rate_limit = rate_limit
+
+        self.quota = quota
+
+        # specifies how the messages exceeding the rate limit of the channel are handled; absent for reject.
+        #
+        # One of reject and queue. The rejected messages are refused with 429 Too Many Requests. The queued
+        # messages are stored by the Relay server and relayed in order once the rate limit allows.
+        self.on_throttle = on_throttle
+
+        # is the maximum number of the messages of the channel waiting in the queue; absent or 0 for
+        # the default of 100 messages.
+        #
+        # The messages beyond it are refused with 429 Too Many Requests.
+        self.max_queue_depth = max_queue_depth
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to channel_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return channel_to_jsonable(self)
+
+
+def new_channel() -> Channel:
+    """Generates an instance of Channel with default values."""
+    return Channel(descriptor='', sender=new_entity(), recipients=[], domain='', min_period=0.0, max_size=0)
+
+
+def channel_from_obj(obj: Any, path: str = "") -> Channel:
+    """
+    Generates an instance of Channel from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Channel
+    :param path: path to the object used for debugging
+    :return: parsed instance of Channel
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    descriptor_from_obj = from_obj(obj['descriptor'], expected=[str], path=path + '.descriptor')  # type: str
+
+    sender_from_obj = from_obj(obj['sender'], expected=[Entity], path=path + '.sender')  # type: Entity
+
+    recipients_from_obj = from_obj(
+        obj['recipients'], expected=[list, Entity], path=path + '.recipients')  # type: List[Entity]
+
+    domain_from_obj = from_obj(obj['domain'], expected=[str], path=path + '.domain')  # type: str
+
+    min_period_from_obj = from_obj(obj['min_period'], expected=[float], path=path + '.min_period')  # type: float
+
+    max_size_from_obj = from_obj(obj['max_size'], expected=[int], path=path + '.max_size')  # type: int
+
+    if 'token' in obj:
+        token_from_obj = from_obj(obj['token'], expected=[str], path=path + '.token')  # type: Optional[str]
+    else:
+        token_from_obj = None
+
+    if 'cc' in obj:
+        cc_from_obj = from_obj(obj['cc'], expected=[list, Entity], path=path + '.cc')  # type: Optional[List[Entity]]
+    else:
+        cc_from_obj = None
+
+    if 'bcc' in obj:
+        bcc_from_obj = from_obj(obj['bcc'], expected=[list, Entity], path=path + '.bcc')  # type: Optional[List[Entity]]
+    else:
+        bcc_from_obj = None
+
+    if 'token_hash' in obj:
+        token_hash_from_obj = from_obj(obj['token_hash'], expected=[str], path=path + '.token_hash')  # type: Optional[str]
+    else:
+        token_hash_from_obj = None
+
+    if 'disabled' in obj:
+        disabled_from_obj_ = from_obj(obj['disabled'], expected=[Disabled], path=path + '.disabled')  # type: Optional[Disabled]
+    else:
+        disabled_from_obj_ = None
+
+    if 'valid_until' in obj:
+        valid_until_from_obj = from_obj(obj['valid_until'], expected=[str], path=path + '.valid_until')  # type: Optional[str]
+    else:
+        valid_until_from_obj = None
+
+    if 'tokens' in obj:
+        tokens_from_obj = from_obj(
+            obj['tokens'], expected=[list, ChannelToken], path=path + '.tokens')  # type: Optional[List[ChannelToken]]
+    else:
+        tokens_from_obj = None
+
+    if 'rate_limit' in obj:
+        rate_limit_from_obj_ = from_obj(
+            obj['rate_limit'], expected=[RateLimit], path=path + '.rate_limit')  # type: Optional[RateLimit]
+    else:
+        rate_limit_from_obj_ = None
+
+    if 'quota' in obj:
+        quota_from_obj_ = from_obj(obj['quota'], expected=[Quota], path=path + '.quota')  # type: Optional[Quota]
+    else:
+        quota_from_obj_ = None
+
+    if 'on_throttle' in obj:
+        on_throttle_from_obj = from_obj(obj['on_throttle'], expected=[str], path=path + '.on_throttle')  # type: Optional[str]
+    else:
+        on_throttle_from_obj = None
+
+    if 'max_queue_depth' in obj:
+        max_queue_depth_from_obj = from_obj(
+            obj['max_queue_depth'], expected=[int], path=path + '.max_queue_depth')  # type: Optional[int]
+    else:
+        max_queue_depth_from_obj = None
+
+    return Channel(
+        descriptor=descriptor_from_obj,
+        sender=sender_from_obj,
+        recipients=recipients_from_obj,
+        domain=domain_from_obj,
+        min_period=min_period_from_obj,
+        max_size=max_size_from_obj,
+        token=token_from_obj,
+        cc=cc_from_obj,
+        bcc=bcc_from_obj,
+        token_hash=token_hash_from_obj,
+        disabled=disabled_from_obj_,
+        valid_until=valid_until_from_obj,
+        tokens=tokens_from_obj,
+        rate_limit=rate_limit_from_obj_,
+        quota=quota_from_obj_,
+        on_throttle=on_throttle_from_obj,
+        max_queue_depth=max_queue_depth_from_obj)
+
+
+def channel_to_jsonable(channel: Channel, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Channel.
+
+    :param channel: instance of Channel to be JSON-ized
+    :param path: path to the channel used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['descriptor'] = channel.descriptor
+
+    res['sender'] = to_jsonable(channel.sender, expected=[Entity], path='{}.sender'.format(path))
+
+    res['recipients'] = to_jsonable(channel.recipients, expected=[list, Entity], path='{}.recipients'.format(path))
+
+    res['domain'] = channel.domain
+
+    res['min_period'] = channel.min_period
+
+    res['max_size'] = channel.max_size
+
+    if channel.token is not None:
+        res['token'] = channel.token
+
+    if channel.cc is not None:
+        res['cc'] = to_jsonable(channel.cc, expected=[list, Entity], path='{}.cc'.format(path))
+
+    if channel.bcc is not None:
+        res['bcc'] = to_jsonable(channel.bcc, expected=[list, Entity], path='{}.bcc'.format(path))
+
+    if channel.token_hash is not None:
+        res['token_hash'] = channel.token_hash
+
+    if channel.disabled is not None:
+        res['disabled'] = to_jsonable(channel.disabled, expected=[Disabled], path='{}.disabled'.format(path))
+
+    if channel.valid_until is not None:
+        res['valid_until'] = channel.valid_until
+
+    if channel.tokens is not None:
+        res['tokens'] = to_jsonable(channel.tokens, expected=[list, ChannelToken], path='{}.tokens'.format(path))
+
+    if channel.rate_limit is not None:
+        res['rate_limit'] = to_jsonable(channel.rate_limit, expected=[RateLimit], path='{}.rate_limit'.format(path))
+
+    if channel.quota is not None:
+        res['quota'] = to_jsonable(channel.quota, expected=[Quota], path='{}.quota'.format(path))
+
+    if channel.on_throttle is not None:
+        res['on_throttle'] = channel.on_throttle
+
+    if channel.max_queue_depth is not None:
+        res['max_queue_depth'] = channel.max_queue_depth
+
+    return res
+
+
+class ChannelsPage:
+    """Lists channels in a paginated manner."""
+
+    def __init__(self,
+                 page: int,
+                 page_count: int,
+                 per_page: int,
+                 channels: List[Channel],
+                 next_cursor: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # specifies the index of the page; 0 if the page has been listed after a cursor.
+        self.page = page
+
+        # specifies the number of pages available.
+        self.page_count = page_count
+
+        # specifies the number of items per page.
+        self.per_page = per_page
+
+        # contains the channel data.
+        self.channels = channels
+
+        # is the opaque cursor to list the following page; absent if there are no more channels.
+        self.next_cursor = next_cursor
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to channels_page_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return channels_page_to_jsonable(self)
+
+
+def new_channels_page() -> ChannelsPage:
+    """Generates an instance of ChannelsPage with default values."""
+    return ChannelsPage(page=0, page_count=0, per_page=0, channels=[])
+
+
+def channels_page_from_obj(obj: Any, path: str = "") -> ChannelsPage:
+    """
+    Generates an instance of ChannelsPage from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of ChannelsPage
+    :param path: path to the object used for debugging
+    :return: parsed instance of ChannelsPage
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    page_from_obj = from_obj(obj['page'], expected=[int], path=path + '.page')  # type: int
+
+    page_count_from_obj = from_obj(obj['page_count'], expected=[int], path=path + '.page_count')  # type: int
+
+    per_page_from_obj = from_obj(obj['per_page'], expected=[int], path=path + '.per_page')  # type: int
+
+    channels_from_obj = from_obj(
+        obj['channels'], expected=[list, Channel], path=path + '.channels')  # type: List[Channel]
+
+    if 'next_cursor' in obj:
+        next_cursor_from_obj = from_obj(
+            obj['next_cursor'], expected=[str], path=path + '.next_cursor')  # type: Optional[str]
+    else:
+        next_cursor_from_obj = None
+
+    return ChannelsPage(
+        page=page_from_obj,
+        page_count=page_count_from_obj,
+        per_page=per_page_from_obj,
+        channels=channels_from_obj,
+        next_cursor=next_cursor_from_obj)
+
+
+def channels_page_to_jsonable(channels_page: ChannelsPage, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of ChannelsPage.
+
+    :param channels_page: instance of ChannelsPage to be JSON-ized
+    :param path: path to the channels_page used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['page'] = channels_page.page
+
+    res['page_count'] = channels_page.page_count
+
+    res['per_page'] = channels_page.per_page
+
+    res['channels'] = to_jsonable(channels_page.channels, expected=[list, Channel], path='{}.channels'.format(path))
+
+    if channels_page.next_cursor is not None:
+        res['next_cursor'] = channels_page.next_cursor
+
+    return res
+
+
+class RelayRecord:
+    """Records an attempt to relay a message through a channel."""
+
+    def __init__(self,
+                 descriptor: str,
+                 time: str,
+                 subject: str,
+                 size: int,
+                 outcome: str,
+                 status: int,
+                 message_id: Optional[str] = None,
+                 token: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        self.descriptor = descriptor
+
+        # is the time of the attempt in RFC 3339 format with nanoseconds.
+        self.time = time
+
+        # is the subject of the message; empty if the message has not been parsed.
+        self.subject = subject
+
+        # is the size of the request body, in bytes.
+        self.size = size
+
+        # is the outcome of the attempt.
+        #
+        # One of relayed, forbidden, too_soon, too_large, invalid, failed, disabled, expired, quota_exceeded, throttled,
+        # locked_out, queued and queue_full.
+        self.outcome = outcome
+
+        # is the HTTP status returned to the client; 0 if the message has been relayed from the queue.
+        self.status = status
+
+        # is the MailGun message id; absent unless the message has been relayed.
+        self.message_id = message_id
+
+        # is the name of the token which authenticated the attempt; absent if none did.
+        self.token = token
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to relay_record_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return relay_record_to_jsonable(self)
+
+
+def new_relay_record() -> RelayRecord:
+    """Generates an instance of RelayRecord with default values."""
+    return RelayRecord(descriptor='', time='', subject='', size=0, outcome='', status=0)
+
+
+def relay_record_from_obj(obj: Any, path: str = "") -> RelayRecord:
+    """
+    Generates an instance of RelayRecord from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of RelayRecord
+    :param path: path to the object used for debugging
+    :return: parsed instance of RelayRecord
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    descriptor_from_obj = from_obj(obj['descriptor'], expected=[str], path=path + '.descriptor')  # type: str
+
+    time_from_obj = from_obj(obj['time'], expected=[str], path=path + '.time')  # type: str
+
+    subject_from_obj = from_obj(obj['subject'], expected=[str], path=path + '.subject')  # type: str
+
+    size_from_obj = from_obj(obj['size'], expected=[int], path=path + '.size')  # type: int
+
+    outcome_from_obj = from_obj(obj['outcome'], expected=[str], path=path + '.outcome')  # type: str
+
+    status_from_obj = from_obj(obj['status'], expected=[int], path=path + '.status')  # type: int
+
+    if 'message_id' in obj:
+        message_id_from_obj = from_obj(
+            obj['message_id'], expected=[str], path=path + '.message_id')  # type: Optional[str]
+    else:
+        message_id_from_obj = None
+
+    if 'token' in obj:
+        token_from_obj = from_obj(obj['token'], expected=[str], path=path + '.token')  # type: Optional[str]
+    else:
+        token_from_obj = None
+
+    return RelayRecord(
+        descriptor=descriptor_from_obj,
+        time=time_from_obj,
+        subject=subject_from_obj,
+        size=size_from_obj,
+        outcome=outcome_from_obj,
+        status=status_from_obj,
+        message_id=message_id_from_obj,
+        token=token_from_obj)
+
+
+def relay_record_to_jsonable(relay_record: RelayRecord, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of RelayRecord.
+
+    :param relay_record: instance of RelayRecord to be JSON-ized
+    :param path: path to the relay_record used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['descriptor'] = relay_record.descriptor
+
+    res['time'] = relay_record.time
+
+    res['subject'] = relay_record.subject
+
+    res['size'] = relay_record.size
+
+    res['outcome'] = relay_record.outcome
+
+    res['status'] = relay_record.status
+
+    if relay_record.message_id is not None:
+        res['message_id'] = relay_record.message_id
+
+    if relay_record.token is not None:
+        res['token'] = relay_record.token
+
+    return res
+
+
+class RelayLog:
+    """Lists the attempts to relay a message through a channel."""
+
+    def __init__(self, records: List[RelayRecord], more: bool) -> None:
+        """Initializes with the given values."""
+        # contains the attempts ordered by time.
+        self.records = records
+
+        # indicates that further attempts exceeding the limit are available in the time range.
+        self.more = more
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to relay_log_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return relay_log_to_jsonable(self)
+
+
+def new_relay_log() -> RelayLog:
+    """Generates an instance of RelayLog with default values."""
+    return RelayLog(records=[], more=False)
+
+
+def relay_log_from_obj(obj: Any, path: str = "") -> RelayLog:
+    """
+    Generates an instance of RelayLog from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of RelayLog
+    :param path: path to the object used for debugging
+    :return: parsed instance of RelayLog
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    records_from_obj = from_obj(
+        obj['records'], expected=[list, RelayRecord], path=path + '.records')  # type: List[RelayRecord]
+
+    more_from_obj = from_obj(obj['more'], expected=[bool], path=path + '.more')  # type: bool
+
+    return RelayLog(records=records_from_obj, more=more_from_obj)
+
+
+def relay_log_to_jsonable(relay_log: RelayLog, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of RelayLog.
+
+    :param relay_log: instance of RelayLog to be JSON-ized
+    :param path: path to the relay_log used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['records'] = to_jsonable(relay_log.records, expected=[list, RelayRecord], path='{}.records'.format(path))
+
+    res['more'] = relay_log.more
+
+    return res
+
+
+class AuditRecord:
+    """Records a change of a channel."""
+
+    def __init__(self,
+                 descriptor: str,
+                 time: str,
+                 operation: str,
+                 remote_addr: str,
+                 token_changed: bool,
+                 actor: Optional[str] = None,
+                 forwarded_for: Optional[str] = None,
+                 user_agent: Optional[str] = None,
+                 before: Optional[Channel] = None,
+                 after: Optional[Channel] = None) -> None:
+        """Initializes with the given values."""
+        self.descriptor = descriptor
+
+        # is the time of the change in RFC 3339 format with nanoseconds.
+        self.time = time
+
+        # is the kind of the change.
+        #
+        # One of put, delete, rollback, disable and enable.
+        self.operation = operation
+
+        # is the network address of the client; empty if the change has been made locally.
+        self.remote_addr = remote_addr
+
+        # indicates that a new token or token hash has been stored for an existing channel.
+        self.token_changed = token_changed
+
+        # is the identity of the client given in the X-Actor header; absent if not given.
+        self.actor = actor
+
+        # is the X-Forwarded-For header of the request; absent if not given.
+        self.forwarded_for = forwarded_for
+
+        # is the User-Agent header of the request; absent if not given.
+        self.user_agent = user_agent
+
+        self.before = before
+
+        self.after = after
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to audit_record_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return audit_record_to_jsonable(self)
+
+
+def new_audit_record() -> AuditRecord:
+    """Generates an instance of AuditRecord with default values."""
+    return AuditRecord(descriptor='', time='', operation='', remote_addr='', token_changed=False)
+
+
+def audit_record_from_obj(obj: Any, path: str = "") -> AuditRecord:
+    """
+    Generates an instance of AuditRecord from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of AuditRecord
+    :param path: path to the object used for debugging
+    :return: parsed instance of AuditRecord
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    descriptor_from_obj = from_obj(obj['descriptor'], expected=[str], path=path + '.descriptor')  # type: str
+
+    time_from_obj = from_obj(obj['time'], expected=[str], path=path + '.time')  # type: str
+
+    operation_from_obj = from_obj(obj['operation'], expected=[str], path=path + '.operation')  # type: str
+
+    remote_addr_from_obj = from_obj(obj['remote_addr'], expected=[str], path=path + '.remote_addr')  # type: str
+
+    token_changed_from_obj = from_obj(obj['token_changed'], expected=[bool], path=path + '.token_changed')  # type: bool
+
+    if 'actor' in obj:
+        actor_from_obj = from_obj(obj['actor'], expected=[str], path=path + '.actor')  # type: Optional[str]
+    else:
+        actor_from_obj = None
+
+    if 'forwarded_for' in obj:
+        forwarded_for_from_obj = from_obj(
+            obj['forwarded_for'], expected=[str], path=path + '.forwarded_for')  # type: Optional[str]
+    else:
+        forwarded_for_from_obj = None
+
+    if 'user_agent' in obj:
+        user_agent_from_obj = from_obj(
+            obj['user_agent'], expected=[str], path=path + '.user_agent')  # type: Optional[str]
+    else:
+        user_agent_from_obj = None
+
+    if 'before' in obj:
+        before_from_obj = from_obj(obj['before'], expected=[Channel], path=path + '.before')  # type: Optional[Channel]
+    else:
+        before_from_obj = None
+
+    if 'after' in obj:
+        after_from_obj = from_obj(obj['after'], expected=[Channel], path=path + '.after')  # type: Optional[Channel]
+    else:
+        after_from_obj = None
+
+    return AuditRecord(
+        descriptor=descriptor_from_obj,
+        time=time_from_obj,
+        operation=operation_from_obj,
+        remote_addr=remote_addr_from_obj,
+        token_changed=token_changed_from_obj,
+        actor=actor_from_obj,
+        forwarded_for=forwarded_for_from_obj,
+        user_agent=user_agent_from_obj,
+        before=before_from_obj,
+        after=after_from_obj)
+
+
+def audit_record_to_jsonable(audit_record: AuditRecord, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of AuditRecord.
+
+    :param audit_record: instance of AuditRecord to be JSON-ized
+    :param path: path to the audit_record used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['descriptor'] = audit_record.descriptor
+
+    res['time'] = audit_record.time
+
+    res['operation'] = audit_record.operation
+
+    res['remote_addr'] = audit_record.remote_addr
+
+    res['token_changed'] = audit_record.token_changed
+
+    if audit_record.actor is not None:
+        res['actor'] = audit_record.actor
+
+    if audit_record.forwarded_for is not None:
+        res['forwarded_for'] = audit_record.forwarded_for
+
+    if audit_record.user_agent is not None:
+        res['user_agent'] = audit_record.user_agent
+
+    if audit_record.before is not None:
+        res['before'] = to_jsonable(audit_record.before, expected=[Channel], path='{}.before'.format(path))
+
+    if audit_record.after is not None:
+        res['after'] = to_jsonable(audit_record.after, expected=[Channel], path='{}.after'.format(path))
+
+    return res
+
+
+class AuditLog:
+    """Lists the changes of the channels."""
+
+    def __init__(self, records: List[AuditRecord], next_cursor: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # contains the changes ordered by time.
+        self.records = records
+
+        # is the opaque cursor to list the following page; absent if there are no more changes.
+        self.next_cursor = next_cursor
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to audit_log_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return audit_log_to_jsonable(self)
+
+
+def new_audit_log() -> AuditLog:
+    """Generates an instance of AuditLog with default values."""
+    return AuditLog(records=[])
+
+
+def audit_log_from_obj(obj: Any, path: str = "") -> AuditLog:
+    """
+    Generates an instance of AuditLog from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of AuditLog
+    :param path: path to the object used for debugging
+    :return: parsed instance of AuditLog
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    records_from_obj = from_obj(
+        obj['records'], expected=[list, AuditRecord], path=path + '.records')  # type: List[AuditRecord]
+
+    if 'next_cursor' in obj:
+        next_cursor_from_obj = from_obj(
+            obj['next_cursor'], expected=[str], path=path + '.next_cursor')  # type: Optional[str]
+    else:
+        next_cursor_from_obj = None
+
+    return AuditLog(records=records_from_obj, next_cursor=next_cursor_from_obj)
+
+
+def audit_log_to_jsonable(audit_log: AuditLog, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of AuditLog.
+
+    :param audit_log: instance of AuditLog to be JSON-ized
+    :param path: path to the audit_log used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['records'] = to_jsonable(audit_log.records, expected=[list, AuditRecord], path='{}.records'.format(path))
+
+    if audit_log.next_cursor is not None:
+        res['next_cursor'] = audit_log.next_cursor
+
+    return res
+
+
+class FieldChange:
+    """Describes how a field of a channel changed between two revisions."""
+
+    def __init__(self, field: str, before: Optional[str] = None, after: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # is the name of the field.
+        self.field = field
+
+        # is the JSON representation of the field in the previous revision; absent if unset.
+        self.before = before
+
+        # is the JSON representation of the field in this revision; absent if unset.
+        self.after = after
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to field_change_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return field_change_to_jsonable(self)
+
+
+def new_field_change() -> FieldChange:
+    """Generates an instance of FieldChange with default values."""
+    return FieldChange(field='')
+
+
+def field_change_from_obj(obj: Any, path: str = "") -> FieldChange:
+    """
+    Generates an instance of FieldChange from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of FieldChange
+    :param path: path to the object used for debugging
+    :return: parsed instance of FieldChange
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    field_from_obj = from_obj(obj['field'], expected=[str], path=path + '.field')  # type: str
+
+    if 'before' in obj:
+        before_from_obj = from_obj(obj['before'], expected=[str], path=path + '.before')  # type: Optional[str]
+    else:
+        before_from_obj = None
+
+    if 'after' in obj:
+        after_from_obj = from_obj(obj['after'], expected=[str], path=path + '.after')  # type: Optional[str]
+    else:
+        after_from_obj = None
+
+    return FieldChange(field=field_from_obj, before=before_from_obj, after=after_from_obj)
+
+
+def field_change_to_jsonable(field_change: FieldChange, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of FieldChange.
+
+    :param field_change: instance of FieldChange to be JSON-ized
+    :param path: path to the field_change used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['field'] = field_change.field
+
+    if field_change.before is not None:
+        res['before'] = field_change.before
+
+    if field_change.after is not None:
+        res['after'] = field_change.after
+
+    return res
+
+
+class ChannelRevision:
+    """Is a stored version of a channel."""
+
+    def __init__(self,
+                 revision: int,
+                 time: str,
+                 channel: Channel,
+                 changes: List[FieldChange],
+                 actor: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # is the number of the revision, counting from 1 for each channel.
+        self.revision = revision
+
+        # is the time when the revision has been stored in RFC 3339 format with nanoseconds.
+        self.time = time
+
+        self.channel = channel
+
+        # lists the fields changed since the previous kept revision; empty for the oldest kept revision.
+        self.changes = changes
+
+        # is the identity of the client which stored the revision; absent if not given.
+        self.actor = actor
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to channel_revision_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return channel_revision_to_jsonable(self)
+
+
+def new_channel_revision() -> ChannelRevision:
+    """Generates an instance of ChannelRevision with default values."""
+    return ChannelRevision(revision=0, time='', channel=new_channel(), changes=[])
+
+
+def channel_revision_from_obj(obj: Any, path: str = "") -> ChannelRevision:
+    """
+    Generates an instance of ChannelRevision from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of ChannelRevision
+    :param path: path to the object used for debugging
+    :return: parsed instance of ChannelRevision
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    revision_from_obj = from_obj(obj['revision'], expected=[int], path=path + '.revision')  # type: int
+
+    time_from_obj = from_obj(obj['time'], expected=[str], path=path + '.time')  # type: str
+
+    channel_from_obj_ = from_obj(obj['channel'], expected=[Channel], path=path + '.channel')  # type: Channel
+
+    changes_from_obj = from_obj(
+        obj['changes'], expected=[list, FieldChange], path=path + '.changes')  # type: List[FieldChange]
+
+    if 'actor' in obj:
+        actor_from_obj = from_obj(obj['actor'], expected=[str], path=path + '.actor')  # type: Optional[str]
+    else:
+        actor_from_obj = None
+
+    return ChannelRevision(
+        revision=revision_from_obj,
+        time=time_from_obj,
+        channel=channel_from_obj_,
+        changes=changes_from_obj,
+        actor=actor_from_obj)
+
+
+def channel_revision_to_jsonable(channel_revision: ChannelRevision, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of ChannelRevision.
+
+    :param channel_revision: instance of ChannelRevision to be JSON-ized
+    :param path: path to the channel_revision used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['revision'] = channel_revision.revision
+
+    res['time'] = channel_revision.time
+
+    res['channel'] = to_jsonable(channel_revision.channel, expected=[Channel], path='{}.channel'.format(path))
+
+    res['changes'] = to_jsonable(
+        channel_revision.changes, expected=[list, FieldChange], path='{}.changes'.format(path))
+
+    if channel_revision.actor is not None:
+        res['actor'] = channel_revision.actor
+
+    return res
+
+
+class ChannelRevisions:
+    """Lists the kept revisions of a channel."""
+
+    def __init__(self, revisions: List[ChannelRevision]) -> None:
+        """Initializes with the given values."""
+        # contains the revisions ordered by their numbers.
+        self.revisions = revisions
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to channel_revisions_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return channel_revisions_to_jsonable(self)
+
+
+def new_channel_revisions() -> ChannelRevisions:
+    """Generates an instance of ChannelRevisions with default values."""
+    return ChannelRevisions(revisions=[])
+
+
+def channel_revisions_from_obj(obj: Any, path: str = "") -> ChannelRevisions:
+    """
+    Generates an instance of ChannelRevisions from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of ChannelRevisions
+    :param path: path to the object used for debugging
+    :return: parsed instance of ChannelRevisions
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    revisions_from_obj = from_obj(
+        obj['revisions'], expected=[list, ChannelRevision], path=path + '.revisions')  # type: List[ChannelRevision]
+
+    return ChannelRevisions(revisions=revisions_from_obj)
+
+
+def channel_revisions_to_jsonable(channel_revisions: ChannelRevisions, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of ChannelRevisions.
+
+    :param channel_revisions: instance of ChannelRevisions to be JSON-ized
+    :param path: path to the channel_revisions used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['revisions'] = to_jsonable(
+        channel_revisions.revisions, expected=[list, ChannelRevision], path='{}.revisions'.format(path))
+
+    return res
+
+
+class Rollback:
+    """Selects the revision to which a channel is restored."""
+
+    def __init__(self, revision: int) -> None:
+        """Initializes with the given values."""
+        # is the number of the revision.
+        self.revision = revision
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to rollback_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return rollback_to_jsonable(self)
+
+
+def new_rollback() -> Rollback:
+    """Generates an instance of Rollback with default values."""
+    return Rollback(revision=0)
+
+
+def rollback_from_obj(obj: Any, path: str = "") -> Rollback:
+    """
+    Generates an instance of Rollback from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Rollback
+    :param path: path to the object used for debugging
+    :return: parsed instance of Rollback
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    revision_from_obj = from_obj(obj['revision'], expected=[int], path=path + '.revision')  # type: int
+
+    return Rollback(revision=revision_from_obj)
+
+
+def rollback_to_jsonable(rollback: Rollback, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Rollback.
+
+    :param rollback: instance of Rollback to be JSON-ized
+    :param path: path to the rollback used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['revision'] = rollback.revision
+
+    return res
+
+
+class Disable:
+    """Gives the reason why a channel is disabled."""
+
+    def __init__(self, reason: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # is the reason reported to the clients of the channel; absent if not given.
+        self.reason = reason
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to disable_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return disable_to_jsonable(self)
+
+
+def new_disable() -> Disable:
+    """Generates an instance of Disable with default values."""
+    return Disable()
+
+
+def disable_from_obj(obj: Any, path: str = "") -> Disable:
+    """
+    Generates an instance of Disable from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Disable
+    :param path: path to the object used for debugging
+    :return: parsed instance of Disable
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    if 'reason' in obj:
+        reason_from_obj = from_obj(obj['reason'], expected=[str], path=path + '.reason')  # type: Optional[str]
+    else:
+        reason_from_obj = None
+
+    return Disable(reason=reason_from_obj)
+
+
+def disable_to_jsonable(disable: Disable, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Disable.
+
+    :param disable: instance of Disable to be JSON-ized
+    :param path: path to the disable used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    if disable.reason is not None:
+        res['reason'] = disable.reason
+
+    return res
+
+
+class Rotate:
+    """Defines the rotation of the tokens of a channel."""
+
+    def __init__(self, name: str, grace_period: Optional[float] = None) -> None:
+        """Initializes with the given values."""
+        # is the name of the new token.
+        self.name = name
+
+        # is the period in seconds after which the other tokens expire;
+        # absent to use the default grace period of the Control server.
+        self.grace_period = grace_period
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to rotate_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return rotate_to_jsonable(self)
+
+
+def new_rotate() -> Rotate:
+    """Generates an instance of Rotate with default values."""
+    return Rotate(name='')
+
+
+def rotate_from_obj(obj: Any, path: str = "") -> Rotate:
+    """
+    Generates an instance of Rotate from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Rotate
+    :param path: path to the object used for debugging
+    :return: parsed instance of Rotate
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    name_from_obj = from_obj(obj['name'], expected=[str], path=path + '.name')  # type: str
+
+    if 'grace_period' in obj:
+        grace_period_from_obj = from_obj(
+            obj['grace_period'], expected=[float], path=path + '.grace_period')  # type: Optional[float]
+    else:
+        grace_period_from_obj = None
+
+    return Rotate(name=name_from_obj, grace_period=grace_period_from_obj)
+
+
+def rotate_to_jsonable(rotate: Rotate, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Rotate.
+
+    :param rotate: instance of Rotate to be JSON-ized
+    :param path: path to the rotate used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['name'] = rotate.name
+
+    if rotate.grace_period is not None:
+        res['grace_period'] = rotate.grace_period
+
+    return res
+
+
+class IssuedToken:
+    """Contains a token issued by the server."""
+
+    def __init__(self, name: str, token: str, expires_others: str) -> None:
+        """Initializes with the given values."""
+        # is the name of the token.
+        self.name = name
+
+        # is the token in plain text; it is not stored and can not be retrieved later.
+        self.token = token
+
+        # is the time in RFC 3339 format after which the other tokens of the channel expire.
+        self.expires_others = expires_others
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to issued_token_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return issued_token_to_jsonable(self)
+
+
+def new_issued_token() -> IssuedToken:
+    """Generates an instance of IssuedToken with default values."""
+    return IssuedToken(name='', token='', expires_others='')
+
+
+def issued_token_from_obj(obj: Any, path: str = "") -> IssuedToken:
+    """
+    Generates an instance of IssuedToken from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of IssuedToken
+    :param path: path to the object used for debugging
+    :return: parsed instance of IssuedToken
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    name_from_obj = from_obj(obj['name'], expected=[str], path=path + '.name')  # type: str
+
+    token_from_obj = from_obj(obj['token'], expected=[str], path=path + '.token')  # type: str
+
+    expires_others_from_obj = from_obj(
+        obj['expires_others'], expected=[str], path=path + '.expires_others')  # type: str
+
+    return IssuedToken(name=name_from_obj, token=token_from_obj, expires_others=expires_others_from_obj)
+
+
+def issued_token_to_jsonable(issued_token: IssuedToken, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of IssuedToken.
+
+    :param issued_token: instance of IssuedToken to be JSON-ized
+    :param path: path to the issued_token used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['name'] = issued_token.name
+
+    res['token'] = issued_token.token
+
+    res['expires_others'] = issued_token.expires_others
+
+    return res
+
+
+class Rejections:
+    """Counts the attempts which have not been relayed by reason."""
+
+    def __init__(self,
+                 forbidden: int,
+                 too_soon: int,
+                 too_large: int,
+                 invalid: int,
+                 failed: int,
+                 disabled: int,
+                 expired: int,
+                 quota_exceeded: int,
+                 throttled: int,
+                 locked_out: int,
+                 queue_full: int) -> None:
+        """Initializes with the given values."""
+        # is the number of the attempts with an invalid token.
+        self.forbidden = forbidden
+
+        # is the number of the attempts before the min_period elapsed.
+        self.too_soon = too_soon
+
+        # is the number of the attempts exceeding the max_size.
+        self.too_large = too_large
+
+        # is the number of the attempts whose message failed the schema or could not be parsed.
+        self.invalid = invalid
+
+        # is the number of the attempts which failed due to an error, e.g., of MailGun.
+        self.failed = failed
+
+        # is the number of the attempts through the disabled channel.
+        self.disabled = disabled
+
+        # is the number of the attempts through the expired channel.
+        self.expired = expired
+
+        # is the number of the attempts which would have exceeded a quota of the channel.
+        self.quota_exceeded = quota_exceeded
+
+        # is the number of the attempts refused since a global limit of the relay has been reached.
+        self.throttled = throttled
+
+        # is the number of the attempts refused since the remote IP or the descriptor was locked out.
+        self.locked_out = locked_out
+
+        # is the number of the attempts refused since the queue of the channel was full.
+        self.queue_full = queue_full
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to rejections_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return rejections_to_jsonable(self)
+
+
+def new_rejections() -> Rejections:
+    """Generates an instance of Rejections with default values."""
+    return Rejections(
+        forbidden=0,
+        too_soon=0,
+        too_large=0,
+        invalid=0,
+        failed=0,
+        disabled=0,
+        expired=0,
+        quota_exceeded=0,
+        throttled=0,
+        locked_out=0,
+        queue_full=0)
+
+
+def rejections_from_obj(obj: Any, path: str = "") -> Rejections:
+    """
+    Generates an instance of Rejections from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Rejections
+    :param path: path to the object used for debugging
+    :return: parsed instance of Rejections
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    forbidden_from_obj = from_obj(obj['forbidden'], expected=[int], path=path + '.forbidden')  # type: int
+
+    too_soon_from_obj = from_obj(obj['too_soon'], expected=[int], path=path + '.too_soon')  # type: int
+
+    too_large_from_obj = from_obj(obj['too_large'], expected=[int], path=path + '.too_large')  # type: int
+
+    invalid_from_obj = from_obj(obj['invalid'], expected=[int], path=path + '.invalid')  # type: int
+
+    failed_from_obj = from_obj(obj['failed'], expected=[int], path=path + '.failed')  # type: int
+
+    disabled_from_obj = from_obj(obj['disabled'], expected=[int], path=path + '.disabled')  # type: int
+
+    expired_from_obj = from_obj(obj['expired'], expected=[int], path=path + '.expired')  # type: int
+
+    quota_exceeded_from_obj = from_obj(obj['quota_exceeded'], expected=[int], path=path + '.quota_exceeded')  # type: int
+
+    throttled_from_obj = from_obj(obj['throttled'], expected=[int], path=path + '.throttled')  # type: int
+
+    locked_out_from_obj = from_obj(obj['locked_out'], expected=[int], path=path + '.locked_out')  # type: int
+
+    queue_full_from_obj = from_obj(obj['queue_full'], expected=[int], path=path + '.queue_full')  # type: int
+
+    return Rejections(
+        forbidden=forbidden_from_obj,
+        too_soon=too_soon_from_obj,
+        too_large=too_large_from_obj,
+        invalid=invalid_from_obj,
+        failed=failed_from_obj,
+        disabled=disabled_from_obj,
+        expired=expired_from_obj,
+        quota_exceeded=quota_exceeded_from_obj,
+        throttled=throttled_from_obj,
+        locked_out=locked_out_from_obj,
+        queue_full=queue_full_from_obj)
+
+
+def rejections_to_jsonable(rejections: Rejections, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Rejections.
+
+    :param rejections: instance of Rejections to be JSON-ized
+    :param path: path to the rejections used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['forbidden'] = rejections.forbidden
+
+    res['too_soon'] = rejections.too_soon
+
+    res['too_large'] = rejections.too_large
+
+    res['invalid'] = rejections.invalid
+
+    res['failed'] = rejections.failed
+
+    res['disabled'] = rejections.disabled
+
+    res['expired'] = rejections.expired
+
+    res['quota_exceeded'] = rejections.quota_exceeded
+
+    res['throttled'] = rejections.throttled
+
+    res['locked_out'] = rejections.locked_out
+
+    res['queue_full'] = rejections.queue_full
+    return res
+
+
+class Usage:
+    """Counts the attempts to relay a message through a channel within a period."""
+
+    def __init__(self, start: str, relayed: int, bytes: int, queued: int, rejected: Rejections) -> None:
+        """Initializes with the given values."""
+        # is the start of the period in RFC 3339 format.
+        self.start = start
+
+        # is the number of the relayed messages.
+        self.relayed = relayed
+
+        # is the total size of the relayed messages in bytes.
+        self.bytes = bytes
+
+        # is the number of the messages queued since they exceeded the rate limit.
+        #
+        # The queued messages are counted as relayed once they have been relayed.
+        self.queued = queued
+
+        self.rejected = rejected
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to usage_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return usage_to_jsonable(self)
+
+
+def new_usage() -> Usage:
+    """Generates an instance of Usage with default values."""
+    return Usage(start='', relayed=0, bytes=0, queued=0, rejected=new_rejections())
+
+
+def usage_from_obj(obj: Any, path: str = "") -> Usage:
+    """
+    Generates an instance of Usage from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Usage
+    :param path: path to the object used for debugging
+    :return: parsed instance of Usage
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    start_from_obj = from_obj(obj['start'], expected=[str], path=path + '.start')  # type: str
+
+    relayed_from_obj = from_obj(obj['relayed'], expected=[int], path=path + '.relayed')  # type: int
+
+    bytes_from_obj = from_obj(obj['bytes'], expected=[int], path=path + '.bytes')  # type: int
+
+    queued_from_obj = from_obj(obj['queued'], expected=[int], path=path + '.queued')  # type: int
+
+    rejected_from_obj = from_obj(obj['rejected'], expected=[Rejections], path=path + '.rejected')  # type: Rejections
+
+    return Usage(
+        start=start_from_obj,
+        relayed=relayed_from_obj,
+        bytes=bytes_from_obj,
+        queued=queued_from_obj,
+        rejected=rejected_from_obj)
+
+
+def usage_to_jsonable(usage: Usage, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Usage.
+
+    :param usage: instance of Usage to be JSON-ized
+    :param path: path to the usage used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['start'] = usage.start
+
+    res['relayed'] = usage.relayed
+
+    res['bytes'] = usage.bytes
+
+    res['queued'] = usage.queued
+
+    res['rejected'] = to_jsonable(usage.rejected, expected=[Rejections], path='{}.rejected'.format(path))
+
+    return res
+
+
+class ChannelStats:
+    """Lists the usage counters of a channel."""
+
+    def __init__(self, descriptor: str, period: str, usage: List[Usage]) -> None:
+        """Initializes with the given values."""
+        self.descriptor = descriptor
+
+        # is the period of the counters, either "hour" or "day".
+        self.period = period
+
+        # contains the counters ordered by the start of their period.
+        self.usage = usage
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to channel_stats_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return channel_stats_to_jsonable(self)
+
+
+def new_channel_stats() -> ChannelStats:
+    """Generates an instance of ChannelStats with default values."""
+    return ChannelStats(descriptor='', period='', usage=[])
+
+
+def channel_stats_from_obj(obj: Any, path: str = "") -> ChannelStats:
+    """
+    Generates an instance of ChannelStats from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of ChannelStats
+    :param path: path to the object used for debugging
+    :return: parsed instance of ChannelStats
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    descriptor_from_obj = from_obj(obj['descriptor'], expected=[str], path=path + '.descriptor')  # type: str
+
+    period_from_obj = from_obj(obj['period'], expected=[str], path=path + '.period')  # type: str
+
+    usage_from_obj = from_obj(obj['usage'], expected=[list, Usage], path=path + '.usage')  # type: List[Usage]
+
+    return ChannelStats(descriptor=descriptor_from_obj, period=period_from_obj, usage=usage_from_obj)
+
+
+def channel_stats_to_jsonable(channel_stats: ChannelStats, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of ChannelStats.
+
+    :param channel_stats: instance of ChannelStats to be JSON-ized
+    :param path: path to the channel_stats used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['descriptor'] = channel_stats.descriptor
+
+    res['period'] = channel_stats.period
+
+    res['usage'] = to_jsonable(channel_stats.usage, expected=[list, Usage], path='{}.usage'.format(path))
+
+    return res
+
+
+class Lockout:
+    """Represents the failed authentications of a remote IP or of a descriptor."""
+
+    def __init__(self, kind: str, subject: str, failures: int, last_failure: str, locked_until: str) -> None:
+        """Initializes with the given values."""
+        # is either "ip" or "descriptor".
+        self.kind = kind
+
+        # is the remote IP or the descriptor.
+        self.subject = subject
+
+        # is the number of the consecutive failed authentications.
+        self.failures = failures
+
+        # is the time of the last failed authentication in RFC 3339 format with nanoseconds.
+        self.last_failure = last_failure
+
+        # is the end of the lockout in RFC 3339 format with nanoseconds.
+        self.locked_until = locked_until
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to lockout_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return lockout_to_jsonable(self)
+
+
+def new_lockout() -> Lockout:
+    """Generates an instance of Lockout with default values."""
+    return Lockout(kind='', subject='', failures=0, last_failure='', locked_until='')
+
+
+def lockout_from_obj(obj: Any, path: str = "") -> Lockout:
+    """
+    Generates an instance of Lockout from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Lockout
+    :param path: path to the object used for debugging
+    :return: parsed instance of Lockout
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    kind_from_obj = from_obj(obj['kind'], expected=[str], path=path + '.kind')  # type: str
+
+    subject_from_obj = from_obj(obj['subject'], expected=[str], path=path + '.subject')  # type: str
+
+    failures_from_obj = from_obj(obj['failures'], expected=[int], path=path + '.failures')  # type: int
+
+    last_failure_from_obj = from_obj(obj['last_failure'], expected=[str], path=path + '.last_failure')  # type: str
+
+    locked_until_from_obj = from_obj(obj['locked_until'], expected=[str], path=path + '.locked_until')  # type: str
+
+    return Lockout(
+        kind=kind_from_obj,
+        subject=subject_from_obj,
+        failures=failures_from_obj,
+        last_failure=last_failure_from_obj,
+        locked_until=locked_until_from_obj)
+
+
+def lockout_to_jsonable(lockout: Lockout, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Lockout.
+
+    :param lockout: instance of Lockout to be JSON-ized
+    :param path: path to the lockout used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['kind'] = lockout.kind
+
+    res['subject'] = lockout.subject
+
+    res['failures'] = lockout.failures
+
+    res['last_failure'] = lockout.last_failure
+
+    res['locked_until'] = lockout.locked_until
+
+    return res
+
+
+class Lockouts:
+    """Lists the current lockouts."""
+
+    def __init__(self, lockouts: List[Lockout]) -> None:
+        """Initializes with the given values."""
+        # contains the lockouts ordered by the kind and the subject.
+        self.lockouts = lockouts
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to lockouts_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return lockouts_to_jsonable(self)
+
+
+def new_lockouts() -> Lockouts:
+    """Generates an instance of Lockouts with default values."""
+    return Lockouts(lockouts=[])
+
+
+def lockouts_from_obj(obj: Any, path: str = "") -> Lockouts:
+    """
+    Generates an instance of Lockouts from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Lockouts
+    :param path: path to the object used for debugging
+    :return: parsed instance of Lockouts
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    lockouts_from_obj_ = from_obj(
+        obj['lockouts'], expected=[list, Lockout], path=path + '.lockouts')  # type: List[Lockout]
+
+    return Lockouts(lockouts=lockouts_from_obj_)
+
+
+def lockouts_to_jsonable(lockouts: Lockouts, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Lockouts.
+
+    :param lockouts: instance of Lockouts to be JSON-ized
+    :param path: path to the lockouts used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['lockouts'] = to_jsonable(lockouts.lockouts, expected=[list, Lockout], path='{}.lockouts'.format(path))
+
+    return res
+
+
+class Unlock:
+    """Selects the remote IP or the descriptor whose lockout is lifted."""
+
+    def __init__(self, kind: str, subject: str) -> None:
+        """Initializes with the given values."""
+        # is either "ip" or "descriptor".
+        self.kind = kind
+
+        # is the remote IP or the descriptor.
+        self.subject = subject
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to unlock_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return unlock_to_jsonable(self)
+
+
+def new_unlock() -> Unlock:
+    """Generates an instance of Unlock with default values."""
+    return Unlock(kind='', subject='')
+
+
+def unlock_from_obj(obj: Any, path: str = "") -> Unlock:
+    """
+    Generates an instance of Unlock from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of Unlock
+    :param path: path to the object used for debugging
+    :return: parsed instance of Unlock
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    kind_from_obj = from_obj(obj['kind'], expected=[str], path=path + '.kind')  # type: str
+
+    subject_from_obj = from_obj(obj['subject'], expected=[str], path=path + '.subject')  # type: str
+
+    return Unlock(kind=kind_from_obj, subject=subject_from_obj)
+
+
+def unlock_to_jsonable(unlock: Unlock, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of Unlock.
+
+    :param unlock: instance of Unlock to be JSON-ized
+    :param path: path to the unlock used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['kind'] = unlock.kind
+
+    res['subject'] = unlock.subject
+
+    return res
+
+
+class RemoteCaller:
+    """Executes the remote calls to the server."""
+
+    def __init__(self, url_prefix: str, auth: Optional[requests.auth.AuthBase] = None) -> None:
+        self.url_prefix = url_prefix
+        self.auth = auth
+
+    def put_channel(self, channel: Channel) -> bytes:
+        """
+        Updates the channel uniquely identified by a descriptor.
+
+        If there is already a channel associated with the descriptor, the old channel is overwritten with the new one.
+
+        In order to enforce the rate limit or the min_period between messages, the Relay server keeps track of the
+        time of the most recently relayed message and of the messages left in the token bucket for each descriptor.
+        If a channel is overwritten, this state is erased unless the new channel has the same min_period and
+        rate_limit fields as the old one.
+
+        :param channel:
+
+        :return: signals that the channel update was accepted.
+        """
+        url = self.url_prefix + '/api/channel'
+
+        data = to_jsonable(channel, expected=[Channel])
+
+        resp = requests.request(method='put', url=url, json=data, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return resp.content
+
+    def delete_channel(self, descriptor: str) -> bytes:
+        """
+        Removes the channel associated with the descriptor.
+
+        :param descriptor:
+
+        :return: signals that the channel was correctly erased, or that the channel was not found.
+        """
+        url = self.url_prefix + '/api/channel'
+
+        data = descriptor
+
+        resp = requests.request(method='delete', url=url, json=data, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return resp.content
+
+    def list_revisions(self, descriptor: str) -> ChannelRevisions:
+        """
+        Lists the kept revisions of the channel ordered by their numbers.
+
+        Every update of a channel is stored as its next revision. Only the most recent revisions are kept
+        (see -channel_revisions of the control server). The revisions are kept after the channel has been removed
+        so that a removed channel can be rolled back as well.
+
+        Each revision lists the fields which changed since the previous kept revision.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+
+        :return: serves the revisions.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor), '/revisions'])
+
+        resp = requests.request(method='get', url=url, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[ChannelRevisions])
+
+    def rollback_channel(self, descriptor: str, rollback: Rollback) -> bytes:
+        """
+        Restores the channel to the given revision.
+
+        Only the configuration of the channel is restored; the current tokens, the disabled state and
+        valid_until are kept so that a rollback neither brings back a revoked token nor enables a disabled
+        channel. The restored channel is stored as the next revision and the rollback is recorded in the audit log.
+        The time of the most recently relayed message is erased unless the restored channel has the same
+        min_period as the current one, just as on an update.
+
+        The If-Match and If-None-Match headers are honoured as on an update and the ETag of the restored
+        channel is returned in the response.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+        :param rollback:
+
+        :return: signals that the channel has been restored.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor), '/rollback'])
+
+        data = to_jsonable(rollback, expected=[Rollback])
+
+        resp = requests.request(method='post', url=url, json=data, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return resp.content
+
+    def disable_channel(self, descriptor: str, disable: Disable) -> bytes:
+        """
+        Disables the channel without removing it.
+
+        The Relay server refuses the messages of a disabled channel with 423 Locked, while the token,
+        the recipients and the time of the most recently relayed message are kept.
+        Disabling an already disabled channel leaves it untouched.
+
+        The change is stored as the next revision and recorded in the audit log.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+        :param disable:
+
+        :return: signals that the channel is disabled.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor), '/disable'])
+
+        data = to_jsonable(disable, expected=[Disable])
+
+        resp = requests.request(method='post', url=url, json=data, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return resp.content
+
+    def enable_channel(self, descriptor: str) -> bytes:
+        """
+        Enables the disabled channel again.
+
+        Enabling an already enabled channel leaves it untouched.
+
+        The change is stored as the next revision and recorded in the audit log.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+
+        :return: signals that the channel is enabled.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor), '/enable'])
+
+        resp = requests.request(method='post', url=url, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return resp.content
+
+    def rotate_token(self, descriptor: str, rotate: Rotate) -> IssuedToken:
+        """
+        Issues a new token generated by the server and lets all the other tokens of the channel expire
+        after the grace period.
+
+        The new token is returned only in the response and never stored in plain text.
+        The tokens which already expired are removed.
+
+        The change is stored as the next revision and recorded in the audit log.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+        :param rotate:
+
+        :return: contains the new token.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor), '/rotate'])
+
+        data = to_jsonable(rotate, expected=[Rotate])
+
+        resp = requests.request(method='post', url=url, json=data, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[IssuedToken])
+
+    def revoke_token(self, descriptor: str, name: str) -> bytes:
+        """
+        Revokes the token of the channel immediately.
+
+        The last live token of a channel can not be revoked; disable the channel instead.
+
+        The change is stored as the next revision and recorded in the audit log.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+        :param name: is the name of the token; "default" refers to the default token.
+
+        :return: signals that the token has been revoked.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor), '/tokens/', str(name), '/revoke'])
+
+        resp = requests.request(method='post', url=url, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return resp.content
+
+    def get_channel_stats(self,
+                          descriptor: str,
+                          period: Optional[str] = None,
+                          since: Optional[str] = None,
+                          until: Optional[str] = None) -> ChannelStats:
+        """
+        Lists the usage counters of the channel per hour or per day, ordered by time.
+
+        The Relay server counts every attempt through an existing channel: the relayed messages and their size
+        as well as the rejected attempts by reason. The periods without any attempt are omitted and the counters
+        are kept after the channel has been removed.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+        :param period: is either "hour" or "day" (the default); the days are in UTC.
+        :param since: lists only the periods starting at or after the given time in RFC 3339 format.
+        :param until: lists only the periods starting before the given time in RFC 3339 format.
+
+        :return: serves the usage counters.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor), '/stats'])
+
+        params = {'period': period, 'since': since, 'until': until}
+
+        resp = requests.request(method='get', url=url, params=params, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[ChannelStats])
+
+    def get_lockouts(self) -> Lockouts:
+        """
+        Lists the remote IPs and the descriptors currently locked out after failed authentications.
+
+        The Relay server counts the failed authentications per remote IP and per descriptor, regardless of
+        whether the channel exists. Once the failures reach the threshold, the subject is locked out for
+        a duration which doubles with each further failure up to a maximum.
+
+        :return: serves the lockouts.
+        """
+        url = self.url_prefix + '/api/lockouts'
+
+        resp = requests.request(method='get', url=url, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[Lockouts])
+
+    def unlock(self, unlock: Unlock) -> bytes:
+        """
+        Lifts the lockout of a remote IP or of a descriptor and forgets its failed authentications.
+
+        :param unlock:
+
+        :return: signals that the lockout has been lifted.
+        """
+        url = self.url_prefix + '/api/unlock'
+
+        data = to_jsonable(unlock, expected=[Unlock])
+
+        resp = requests.request(method='post', url=url, json=data, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return resp.content
+
+    def get_channel(self, descriptor: str) -> Channel:
+        """
+        Serves the channel together with its revision as the ETag header.
+
+        The revision is the number of the latest revision of the channel (see /api/channel/{descriptor}/revisions)
+        and increases with every change of the channel, also after the channel has been removed and created again.
+        Pass the ETag in the If-Match header of an update or a removal to detect concurrent changes.
+        If the If-None-Match header matches the ETag, the channel is not served and 304 is returned.
+        The descriptor may contain slashes.
+
+        :param descriptor: identifies the channel.
+
+        :return: serves the channel.
+        """
+        url = "".join([self.url_prefix, '/api/channel/', str(descriptor)])
+
+        resp = requests.request(method='get', url=url, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[Channel])
+
+    def list_channels(self,
+                      page: Optional[int] = None,
+                      per_page: Optional[int] = None,
+                      after: Optional[str] = None,
+                      prefix: Optional[str] = None,
+                      domain: Optional[str] = None,
+                      sender: Optional[str] = None,
+                      recipient: Optional[str] = None,
+                      disabled: Optional[bool] = None) -> ChannelsPage:
+        """
+        Lists the available channels information.
+
+        The channels are ordered by their descriptors. To walk through all the channels, pass the next_cursor of
+        each page as the after parameter of the following request. Unlike the page indices, the cursors do not
+        shift if the channels are inserted or removed between the requests.
+
+        The listing can be narrowed down by the descriptor prefix, the domain, the sender, the recipient and
+        the state of the channels. The domains and the email addresses are compared case-insensitively. Only the channels satisfying all
+        the given criteria are listed and counted.
+
+        :param page:
+            specifies the index of a page. The default is 1 (first page).
+
+            The page can not be given together with the cursor.
+        :param per_page: specifies a desired number of items per page. The default is 100.
+        :param after:
+            is the opaque cursor returned as next_cursor of the previous page. The page lists the channels
+            following the cursor. An empty cursor lists the channels from the beginning.
+        :param prefix: lists only the channels whose descriptors start with the prefix.
+        :param domain: lists only the channels of the MailGun domain.
+        :param sender: lists only the channels sent from the email address.
+        :param recipient: lists only the channels whose recipients, cc or bcc include the email address.
+        :param disabled: lists only the disabled channels if true and only the enabled ones if false.
+
+        :return: serves the channel information list.
         """
         url = self.url_prefix + '/api/list_channels'
 
-        params = {'page': page, 'per_page': per_page}
+        params = {
+            'page': page,
+            'per_page': per_page,
+            'after': after,
+            'prefix': prefix,
+            'domain': domain,
+            'sender': sender,
+            'recipient': recipient,
+            'disabled': None if disabled is None else str(disabled).lower()
+        }
 
         resp = requests.request(method='get', url=url, params=params, auth=self.auth)
 
@@ -488,5 +2885,67 @@ class RemoteCaller:
             resp.raise_for_status()
             return from_obj(obj=resp.json(), expected=[ChannelsPage])
 
+    def get_relay_log(self,
+                      descriptor: str,
+                      since: Optional[str] = None,
+                      until: Optional[str] = None,
+                      limit: Optional[int] = None) -> RelayLog:
+        """
+        Lists the attempts to relay a message through the channel, ordered by time.
+
+        Every attempt through an existing channel is recorded, including the rejected and the failed ones.
+        The Relay server prunes the records according to its retention settings.
+
+        :param descriptor: identifies the channel.
+        :param since: lists only the attempts at or after the given time in RFC 3339 format.
+        :param until: lists only the attempts before the given time in RFC 3339 format.
+        :param limit: specifies the maximum number of listed attempts. The default is 1000.
+
+        :return: serves the relay attempts.
+        """
+        url = self.url_prefix + '/api/relay_log'
+
+        params = {'descriptor': descriptor, 'since': since, 'until': until, 'limit': limit}
+
+        resp = requests.request(method='get', url=url, params=params, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[RelayLog])
+
+    def get_audit(self,
+                  descriptor: Optional[str] = None,
+                  since: Optional[str] = None,
+                  until: Optional[str] = None,
+                  limit: Optional[int] = None,
+                  after: Optional[str] = None) -> AuditLog:
+        """
+        Lists the changes of the channels, ordered by time.
+
+        Every update and removal of a channel is recorded with the channel before and after the change.
+        The tokens and their hashes are redacted; token_changed indicates whether a new token has been stored.
+        The audit log is append-only.
+
+        To walk through all the changes, pass the next_cursor of each page as the after parameter of
+        the following request.
+
+        :param descriptor: lists only the changes of the given channel.
+        :param since: lists only the changes at or after the given time in RFC 3339 format.
+        :param until: lists only the changes before the given time in RFC 3339 format.
+        :param limit: specifies the maximum number of listed changes. The default is 100.
+        :param after: is the opaque cursor of the previous page; lists only the changes after it.
+
+        :return: serves the changes.
+        """
+        url = self.url_prefix + '/api/audit'
+
+        params = {'descriptor': descriptor, 'since': since, 'until': until, 'limit': limit, 'after': after}
+
+        resp = requests.request(method='get', url=url, params=params, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[AuditLog])
+
 
 # Automatically generated file by swagger_to. DO NOT EDIT OR APPEND ANYTHING!
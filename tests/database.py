@@ -1,6 +1,7 @@
 #!/usr/bin/env python3
 """Initialize the mailgun-relayery channel database."""
 import pathlib
+import struct
 
 import icontract
 import lmdb
@@ -11,6 +12,39 @@ DB_CHANNEL_KEY = 'channel'.encode()  # database name
 # Descriptor -> Timestamp database
 DB_TIMESTAMP_KEY = 'timestamp'.encode()  # database name
 
+# Descriptor, time -> RelayRecord database
+DB_RELAY_LOG_KEY = 'relaylog'.encode()  # database name
+
+# Time -> AuditRecord database
+DB_AUDIT_KEY = 'audit'.encode()  # database name
+
+# Descriptor, revision -> ChannelRevision database
+DB_REVISION_KEY = 'revision'.encode()  # database name
+
+# Descriptor, token name -> time of the last use database
+DB_TOKEN_USE_KEY = 'tokenuse'.encode()  # database name
+
+# Descriptor, period, start -> UsageCounters database
+DB_USAGE_KEY = 'usage'.encode()  # database name
+
+# Descriptor, period -> QuotaCounters database
+DB_QUOTA_KEY = 'quota'.encode()  # database name
+
+# Kind, subject -> Lockout database
+DB_LOCKOUT_KEY = 'lockout'.encode()  # database name
+
+# Descriptor, id -> QueuedMessage database
+DB_QUEUE_KEY = 'queue'.encode()  # database name
+
+# Key -> metadata database
+DB_META_KEY = 'meta'.encode()  # database name
+
+# Key of the schema version in the metadata database
+SCHEMA_VERSION_KEY = 'schema_version'.encode()
+
+# Schema version expected by the servers
+SCHEMA_VERSION = 11
+
 
 @icontract.require(lambda database_dir: database_dir.exists())
 def initialize_environment(database_dir: pathlib.Path) -> None:
@@ -21,6 +55,18 @@ def initialize_environment(database_dir: pathlib.Path) -> None:
     :return:
 
     """
-    with lmdb.open(path=database_dir.as_posix(), map_size=32 * 1024 * 1024 * 1024, max_dbs=2, readonly=False) as env:
+    with lmdb.open(path=database_dir.as_posix(), map_size=32 * 1024 * 1024 * 1024, max_dbs=11, readonly=False) as env:
         env.open_db(DB_CHANNEL_KEY, create=True)
         env.open_db(DB_TIMESTAMP_KEY, create=True)
+        env.open_db(DB_RELAY_LOG_KEY, create=True)
+        env.open_db(DB_AUDIT_KEY, create=True)
+        env.open_db(DB_REVISION_KEY, create=True)
+        env.open_db(DB_TOKEN_USE_KEY, create=True)
+        env.open_db(DB_USAGE_KEY, create=True)
+        env.open_db(DB_QUOTA_KEY, create=True)
+        env.open_db(DB_LOCKOUT_KEY, create=True)
+        env.open_db(DB_QUEUE_KEY, create=True)
+        meta_db = env.open_db(DB_META_KEY, create=True)
+
+        with env.begin(write=True, db=meta_db) as txn:
+            txn.put(SCHEMA_VERSION_KEY, struct.pack('<Q', SCHEMA_VERSION))
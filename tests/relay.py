@@ -63,6 +63,9 @@ def from_obj(obj: Any, expected: List[type], path: str = '') -> Any:
     if exp == Message:
         return message_from_obj(obj, path=path)
 
+    if exp == QueueStatus:
+        return queue_status_from_obj(obj, path=path)
+
     raise ValueError("Unexpected `expected` type: {}".format(exp))
 
 
@@ -124,6 +127,10 @@ def to_jsonable(obj: Any, expected: List[type], path: str = "") -> Any:
         assert isinstance(obj, Message)
         return message_to_jsonable(obj, path=path)
 
+    if exp == QueueStatus:
+        assert isinstance(obj, QueueStatus)
+        return queue_status_to_jsonable(obj, path=path)
+
     raise ValueError("Unexpected `expected` type: {}".format(exp))
 
 
@@ -204,6 +211,174 @@ def message_to_jsonable(message: Message, path: str = "") -> MutableMapping[str,
     return res
 
 
+class QueueStatus:
+    """Represents the status of a queued message."""
+
+    def __init__(self,
+                 id: str,
+                 status: str,
+                 enqueued: str,
+                 attempts: int,
+                 position: Optional[int] = None,
+                 retry_at: Optional[str] = None,
+                 error: Optional[str] = None,
+                 finished: Optional[str] = None,
+                 message_id: Optional[str] = None,
+                 deferred: Optional[str] = None) -> None:
+        """Initializes with the given values."""
+        # is the identifier of the queued message.
+        self.id = id
+
+        # is the state of the message, one of queued, relayed and failed.
+        self.status = status
+
+        # is the time when the message has been queued in RFC 3339 format with nanoseconds.
+        self.enqueued = enqueued
+
+        # is the number of the failed attempts to relay the message.
+        self.attempts = attempts
+
+        # is the number of the messages of the channel to be relayed before this one; absent unless
+        # the message is queued.
+        self.position = position
+
+        # is the time in RFC 3339 format with nanoseconds before which the message is not relayed again
+        # after a failed attempt; absent if the message is not delayed.
+        self.retry_at = retry_at
+
+        # is the error of the last failed attempt; absent if none failed.
+        self.error = error
+
+        # is the time in RFC 3339 format with nanoseconds when the message has been relayed or given up;
+        # absent if the message is still queued.
+        self.finished = finished
+
+        # is the MailGun message id; absent unless the message has been relayed.
+        self.message_id = message_id
+
+        # is why the due message could not be relayed at the last attempt, e.g., since a global limit of
+        # the relay or a quota of the channel has been reached; absent unless the message is deferred.
+        self.deferred = deferred
+
+    def to_jsonable(self) -> MutableMapping[str, Any]:
+        """
+        Dispatches the conversion to queue_status_to_jsonable.
+
+        :return: JSON-able representation
+        """
+        return queue_status_to_jsonable(self)
+
+
+def new_queue_status() -> QueueStatus:
+    """Generates an instance of QueueStatus with default values."""
+    return QueueStatus(id='', status='', enqueued='', attempts=0)
+
+
+def queue_status_from_obj(obj: Any, path: str = "") -> QueueStatus:
+    """
+    Generates an instance of QueueStatus from a dictionary object.
+
+    :param obj: a JSON-ed dictionary object representing an instance of QueueStatus
+    :param path: path to the object used for debugging
+    :return: parsed instance of QueueStatus
+    """
+    if not isinstance(obj, dict):
+        raise ValueError('Expected a dict at path {}, but got: {}'.format(path, type(obj)))
+
+    for key in obj:
+        if not isinstance(key, str):
+            raise ValueError('Expected a key of type str at path {}, but got: {}'.format(path, type(key)))
+
+    id_from_obj = from_obj(obj['id'], expected=[str], path=path + '.id')  # type: str
+
+    status_from_obj = from_obj(obj['status'], expected=[str], path=path + '.status')  # type: str
+
+    enqueued_from_obj = from_obj(obj['enqueued'], expected=[str], path=path + '.enqueued')  # type: str
+
+    attempts_from_obj = from_obj(obj['attempts'], expected=[int], path=path + '.attempts')  # type: int
+
+    if 'position' in obj:
+        position_from_obj = from_obj(obj['position'], expected=[int], path=path + '.position')  # type: Optional[int]
+    else:
+        position_from_obj = None
+
+    if 'retry_at' in obj:
+        retry_at_from_obj = from_obj(obj['retry_at'], expected=[str], path=path + '.retry_at')  # type: Optional[str]
+    else:
+        retry_at_from_obj = None
+
+    if 'error' in obj:
+        error_from_obj = from_obj(obj['error'], expected=[str], path=path + '.error')  # type: Optional[str]
+    else:
+        error_from_obj = None
+
+    if 'finished' in obj:
+        finished_from_obj = from_obj(obj['finished'], expected=[str], path=path + '.finished')  # type: Optional[str]
+    else:
+        finished_from_obj = None
+
+    if 'message_id' in obj:
+        message_id_from_obj = from_obj(obj['message_id'], expected=[str], path=path + '.message_id')  # type: Optional[str]
+    else:
+        message_id_from_obj = None
+
+    if 'deferred' in obj:
+        deferred_from_obj = from_obj(obj['deferred'], expected=[str], path=path + '.deferred')  # type: Optional[str]
+    else:
+        deferred_from_obj = None
+
+    return QueueStatus(
+        id=id_from_obj,
+        status=status_from_obj,
+        enqueued=enqueued_from_obj,
+        attempts=attempts_from_obj,
+        position=position_from_obj,
+        retry_at=retry_at_from_obj,
+        error=error_from_obj,
+        finished=finished_from_obj,
+        message_id=message_id_from_obj,
+        deferred=deferred_from_obj)
+
+
+def queue_status_to_jsonable(queue_status: QueueStatus, path: str = "") -> MutableMapping[str, Any]:
+    """
+    Generates a JSON-able mapping from an instance of QueueStatus.
+
+    :param queue_status: instance of QueueStatus to be JSON-ized
+    :param path: path to the queue_status used for debugging
+    :return: a JSON-able representation
+    """
+    res = dict()  # type: Dict[str, Any]
+
+    res['id'] = queue_status.id
+
+    res['status'] = queue_status.status
+
+    res['enqueued'] = queue_status.enqueued
+
+    res['attempts'] = queue_status.attempts
+
+    if queue_status.position is not None:
+        res['position'] = queue_status.position
+
+    if queue_status.retry_at is not None:
+        res['retry_at'] = queue_status.retry_at
+
+    if queue_status.error is not None:
+        res['error'] = queue_status.error
+
+    if queue_status.finished is not None:
+        res['finished'] = queue_status.finished
+
+    if queue_status.message_id is not None:
+        res['message_id'] = queue_status.message_id
+
+    if queue_status.deferred is not None:
+        res['deferred'] = queue_status.deferred
+
+    return res
+
+
 class RemoteCaller:
     """Executes the remote calls to the server."""
 
@@ -218,6 +393,20 @@ class RemoteCaller:
         The given (descriptor, token) pair are authenticated first.
         The message's metadata is determined by the channel information from the database.
 
+        Once the rate limit of the channel has been checked, the responses report the state of its token bucket
+        in the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers, also when the message
+        is refused afterwards. The headers are omitted for the channels without a rate limit and min_period.
+        The message is held in the bucket while it is processed and given back unless it is relayed, e.g., if
+        the message is invalid or MailGun fails, so that it can be retried right away.
+        Whenever a message is refused with 429 or 503, the Retry-After header gives the number of seconds
+        after which the message should be retried.
+
+        If the channel sets on_throttle to queue, a valid message exceeding the rate limit is not refused, but
+        stored in the queue of the channel and 202 is returned. The messages arriving while the queue is not
+        empty are queued as well so that the messages are relayed in order. The relay delivers the queued
+        messages in the background at the pace allowed by the rate limit. Only once the queue holds
+        max_queue_depth messages are the further messages refused with 429.
+
         :param x_descriptor:
         :param x_token:
         :param message:
@@ -240,5 +429,33 @@ class RemoteCaller:
             resp.raise_for_status()
             return resp.content
 
+    def get_queued(self, id: str, x_descriptor: str, x_token: str) -> QueueStatus:
+        """
+        Serves the status of a message queued since it exceeded the rate limit of its channel.
+
+        The request is authenticated by the (descriptor, token) pair of the channel just as a message.
+        The relayed and the given up messages are served until they are pruned after the retention period
+        of the relay.
+
+        :param id: is the identifier of the queued message as returned in the X-Queue-Id header.
+        :param x_descriptor:
+        :param x_token:
+
+        :return: serves the status of the queued message.
+        """
+        url = "".join([self.url_prefix, '/api/queue/', str(id)])
+
+        headers = {}  # type: Dict[str, str]
+
+        headers['X-Descriptor'] = x_descriptor
+
+        headers['X-Token'] = x_token
+
+        resp = requests.request(method='get', url=url, headers=headers, auth=self.auth)
+
+        with contextlib.closing(resp):
+            resp.raise_for_status()
+            return from_obj(obj=resp.json(), expected=[QueueStatus])
+
 
 # Automatically generated file by swagger_to. DO NOT EDIT OR APPEND ANYTHING!
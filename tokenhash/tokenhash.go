@@ -0,0 +1,409 @@
+package tokenhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+// Parameters of the argon2id hashing used for the new tokens.
+//
+// The values follow the recommendations of RFC 9106 for a memory-constrained
+// environment.
+const (
+	saltLen      = 16
+	keyLen       = 32
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+// Bounds of the argon2id parameters accepted from the stored or the supplied
+// hashes so that a single verification can not exhaust the memory or
+// the CPU of the relay.
+const (
+	maxMemory = memoryBudget
+	maxTime   = 16
+	maxKeyLen = 1024
+)
+
+// memoryBudget bounds the memory of all the hashes computed at once, in KiB.
+//
+// Every hash allocates its memory parameter, i.e., 64 MiB for the new
+// tokens, so that the concurrent requests could otherwise exhaust
+// the memory of the relay. With the budget of 512 MiB, eight new tokens are
+// hashed at once; Authenticate refuses to wait for the budget with ErrBusy
+// instead so that the requests do not pile up.
+const memoryBudget = 512 * 1024
+
+// ErrBusy signals that the memory budget of the hashing is used up.
+var ErrBusy = errors.New("too many tokens are being hashed at once")
+
+// budget tracks the memory taken by the hashes being computed.
+var budget = struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	used uint32
+}{}
+
+func init() {
+	budget.cond = sync.NewCond(&budget.mu)
+}
+
+// acquire takes the memory from the budget. If wait is set, it waits until
+// the memory is available; otherwise it returns false if it is not.
+func acquire(memory uint32, wait bool) bool {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	for budget.used+memory > memoryBudget {
+		if !wait {
+			return false
+		}
+		budget.cond.Wait()
+	}
+
+	budget.used += memory
+	return true
+}
+
+// release gives the memory back to the budget.
+func release(memory uint32) {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.used -= memory
+	budget.cond.Broadcast()
+}
+
+// idKey computes the argon2id key within the memory budget; ErrBusy is
+// returned if wait is not set and the budget is used up.
+func idKey(token string, salt []byte, iterations uint32, memory uint32,
+	threads uint8, length uint32, wait bool) (key []byte, err error) {
+	if !acquire(memory, wait) {
+		err = ErrBusy
+		return
+	}
+	defer release(memory)
+
+	key = argon2.IDKey([]byte(token), salt, iterations, memory, threads,
+		length)
+	return
+}
+
+// validParams checks the argon2id parameters of the hash against the bounds.
+func validParams(h *protoed.TokenHash) bool {
+	return h.Time >= 1 && h.Time <= maxTime &&
+		h.Memory >= 1 && h.Memory <= maxMemory &&
+		h.Threads >= 1 && h.Threads <= 255 &&
+		len(h.Hash) >= 1 && len(h.Hash) <= maxKeyLen
+}
+
+// New hashes the token with a random salt.
+//
+// New ensures:
+// * err != nil || h.Version == protoed.TokenHash_ARGON2ID
+// * err != nil || len(h.Salt) == saltLen
+// * err != nil || len(h.Hash) == keyLen
+func New(token string) (h *protoed.TokenHash, err error) {
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(err != nil || h.Version == protoed.TokenHash_ARGON2ID):
+			panic("Violated: err != nil || h.Version == protoed.TokenHash_ARGON2ID")
+		case !(err != nil || len(h.Salt) == saltLen):
+			panic("Violated: err != nil || len(h.Salt) == saltLen")
+		case !(err != nil || len(h.Hash) == keyLen):
+			panic("Violated: err != nil || len(h.Hash) == keyLen")
+		default:
+			// Pass
+		}
+	}()
+
+	salt := make([]byte, saltLen)
+	_, err = rand.Read(salt)
+	if err != nil {
+		err = fmt.Errorf("failed to generate the salt: %s", err.Error())
+		return
+	}
+
+	h = &protoed.TokenHash{
+		Version: protoed.TokenHash_ARGON2ID,
+		Salt:    salt,
+		Time:    argonTime,
+		Memory:  argonMemory,
+		Threads: argonThreads}
+	h.Hash, err = idKey(token, h.Salt, h.Time, h.Memory,
+		uint8(h.Threads), keyLen, true)
+	if err != nil {
+		h = nil
+		return
+	}
+
+	return
+}
+
+// Verify checks in constant time whether the token matches the hash.
+// It waits until the memory budget allows for hashing the token.
+//
+// Hashes of unknown versions or with the parameters out of bounds never match.
+func Verify(h *protoed.TokenHash, token string) bool {
+	ok, _ := verify(h, token, true)
+	return ok
+}
+
+// TryVerify checks in constant time whether the token matches the hash
+// just as Verify, but returns ErrBusy instead of waiting if the memory
+// budget is used up.
+func TryVerify(h *protoed.TokenHash, token string) (ok bool, err error) {
+	return verify(h, token, false)
+}
+
+// verify implements Verify and TryVerify.
+func verify(h *protoed.TokenHash, token string, wait bool) (
+	ok bool, err error) {
+	if h == nil || h.Version != protoed.TokenHash_ARGON2ID || !validParams(h) {
+		return
+	}
+
+	derived, err := idKey(token, h.Salt, h.Time, h.Memory,
+		uint8(h.Threads), uint32(len(h.Hash)), wait)
+	if err != nil {
+		return
+	}
+
+	ok = subtle.ConstantTimeCompare(derived, h.Hash) == 1
+	return
+}
+
+// Matches checks whether the token authenticates the channel.
+//
+// Channels which have not been migrated yet carry a plain-text token instead
+// of a hash; the plain-text token is compared in constant time as well.
+//
+// Matches requires:
+// * channel != nil
+func Matches(channel *protoed.Channel, token string) bool {
+	// Pre-condition
+	if !(channel != nil) {
+		panic("Violated: channel != nil")
+	}
+
+	if channel.TokenHash != nil {
+		return Verify(channel.TokenHash, token)
+	}
+
+	if channel.Token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(
+		[]byte(channel.Token), []byte(token)) == 1
+}
+
+// DefaultName is the name of the default token of a channel given by
+// its token hash.
+const DefaultName = "default"
+
+// generatedLen is the number of random bytes of a generated token.
+const generatedLen = 32
+
+// Generate generates a random token encoded in URL-safe base64.
+func Generate() (token string, err error) {
+	raw := make([]byte, generatedLen)
+	_, err = rand.Read(raw)
+	if err != nil {
+		err = fmt.Errorf("failed to generate the token: %s", err.Error())
+		return
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return
+}
+
+// Live indicates that the named token has not expired at the given time.
+//
+// Live requires:
+// * token != nil
+func Live(token *protoed.ChannelToken, now time.Time) bool {
+	// Pre-condition
+	if !(token != nil) {
+		panic("Violated: token != nil")
+	}
+
+	return token.Expires == 0 || now.UnixNano() < token.Expires
+}
+
+// Authenticate checks whether the token matches the default token or any
+// live named token of the channel and returns the name of the matching
+// token.
+//
+// Rather than waiting, ErrBusy is returned if the memory budget of
+// the hashing is used up.
+//
+// Authenticate requires:
+// * channel != nil
+//
+// Authenticate ensures:
+// * ok == (name != "")
+// * err == nil || !ok
+func Authenticate(channel *protoed.Channel, token string, now time.Time) (
+	name string, ok bool, err error) {
+	// Pre-condition
+	if !(channel != nil) {
+		panic("Violated: channel != nil")
+	}
+
+	// Post-conditions
+	defer func() {
+		switch {
+		case !(ok == (name != "")):
+			panic("Violated: ok == (name != \"\")")
+		case !(err == nil || !ok):
+			panic("Violated: err == nil || !ok")
+		default:
+			// Pass
+		}
+	}()
+
+	matched := false
+	if channel.TokenHash != nil {
+		matched, err = TryVerify(channel.TokenHash, token)
+		if err != nil {
+			return
+		}
+	} else {
+		matched = Matches(channel, token)
+	}
+
+	if matched {
+		return DefaultName, true, nil
+	}
+
+	for _, named := range channel.Tokens {
+		if !Live(named, now) {
+			continue
+		}
+
+		matched, err = TryVerify(named.Hash, token)
+		if err != nil {
+			return
+		}
+
+		if matched {
+			return named.Name, true, nil
+		}
+	}
+
+	return
+}
+
+// Encode represents the hash as a string in the PHC string format,
+// e.g., "$argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>".
+//
+// Encode requires:
+// * h != nil
+// * h.Version == protoed.TokenHash_ARGON2ID
+func Encode(h *protoed.TokenHash) string {
+	// Pre-conditions
+	switch {
+	case !(h != nil):
+		panic("Violated: h != nil")
+	case !(h.Version == protoed.TokenHash_ARGON2ID):
+		panic("Violated: h.Version == protoed.TokenHash_ARGON2ID")
+	default:
+		// Pass
+	}
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(h.Salt),
+		base64.RawStdEncoding.EncodeToString(h.Hash))
+}
+
+// Decode parses a hash given in the PHC string format.
+//
+// Decode ensures:
+// * err != nil || h.Version == protoed.TokenHash_ARGON2ID
+func Decode(text string) (h *protoed.TokenHash, err error) {
+	// Post-condition
+	defer func() {
+		if !(err != nil || h.Version == protoed.TokenHash_ARGON2ID) {
+			panic("Violated: err != nil || h.Version == protoed.TokenHash_ARGON2ID")
+		}
+	}()
+
+	parts := strings.Split(text, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		err = fmt.Errorf("expected a hash in the PHC string format, "+
+			"got: %#v", text)
+		return
+	}
+
+	if parts[1] != "argon2id" {
+		err = fmt.Errorf("unsupported hashing scheme: %#v", parts[1])
+		return
+	}
+
+	var version int
+	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the version %#v: %s",
+			parts[2], err.Error())
+		return
+	}
+	if version != argon2.Version {
+		err = fmt.Errorf("unsupported argon2 version: %d", version)
+		return
+	}
+
+	h = &protoed.TokenHash{Version: protoed.TokenHash_ARGON2ID}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d",
+		&h.Memory, &h.Time, &h.Threads)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the parameters %#v: %s",
+			parts[3], err.Error())
+		return
+	}
+	if h.Time == 0 || h.Memory == 0 || h.Threads == 0 || h.Threads > 255 {
+		err = fmt.Errorf("invalid parameters: %#v", parts[3])
+		return
+	}
+	if h.Time > maxTime || h.Memory > maxMemory {
+		err = fmt.Errorf("parameters out of bounds (expected m <= %d "+
+			"and t <= %d): %#v", maxMemory, maxTime, parts[3])
+		return
+	}
+
+	h.Salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		err = fmt.Errorf("failed to decode the salt: %s", err.Error())
+		return
+	}
+
+	h.Hash, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		err = fmt.Errorf("failed to decode the hash: %s", err.Error())
+		return
+	}
+	if len(h.Hash) == 0 {
+		err = fmt.Errorf("unexpected empty hash")
+		return
+	}
+	if len(h.Hash) > maxKeyLen {
+		err = fmt.Errorf("expected a hash of at most %d bytes, got %d",
+			maxKeyLen, len(h.Hash))
+		return
+	}
+
+	return
+}
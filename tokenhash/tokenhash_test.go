@@ -0,0 +1,224 @@
+package tokenhash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parquery/mailgun-relayery/protoed"
+)
+
+func TestNewVerify(t *testing.T) {
+	token := "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU"
+
+	h, err := New(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !Verify(h, token) {
+		t.Errorf("expected the token %#v to match its hash", token)
+	}
+
+	if Verify(h, token+"_suffix") {
+		t.Errorf("expected a different token not to match the hash")
+	}
+
+	other, err := New(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if string(other.Salt) == string(h.Salt) {
+		t.Errorf("expected different salts for two hashes of the same token")
+	}
+
+	unknown := *h
+	unknown.Version = protoed.TokenHash_UNKNOWN
+	if Verify(&unknown, token) {
+		t.Errorf("expected a hash of unknown version never to match")
+	}
+
+	for _, mutate := range []func(h *protoed.TokenHash){
+		func(h *protoed.TokenHash) { h.Time = 0 },
+		func(h *protoed.TokenHash) { h.Time = maxTime + 1 },
+		func(h *protoed.TokenHash) { h.Memory = maxMemory + 1 },
+		func(h *protoed.TokenHash) { h.Threads = 0 }} {
+		invalid := *h
+		mutate(&invalid)
+		if Verify(&invalid, token) {
+			t.Errorf("expected a hash with parameters out of bounds "+
+				"never to match: %s", invalid.String())
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	h := &protoed.TokenHash{
+		Version: protoed.TokenHash_ARGON2ID,
+		Salt:    []byte("0123456789abcdef"),
+		Hash:    []byte("0123456789abcdef0123456789abcdef"),
+		Time:    1,
+		Memory:  64 * 1024,
+		Threads: 4}
+
+	expected := "$argon2id$v=19$m=65536,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$" +
+		"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"
+
+	encoded := Encode(h)
+	if encoded != expected {
+		t.Fatalf("expected %s, got %s", expected, encoded)
+	}
+
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if decoded.String() != h.String() {
+		t.Errorf("expected %s, got %s", h.String(), decoded.String())
+	}
+
+	invalids := []string{
+		"",
+		"plain-text",
+		"$bcrypt$v=19$m=65536,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEy",
+		"$argon2id$v=16$m=65536,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEy",
+		"$argon2id$v=19$m=65536,t=0,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEy",
+		"$argon2id$v=19$m=4294967295,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEy",
+		"$argon2id$v=19$m=65536,t=1000,p=4$MDEyMzQ1Njc4OWFiY2RlZg$MDEy",
+		"$argon2id$v=19$m=65536,t=1,p=4$MDEyMzQ1Njc4OWFiY2RlZg$",
+	}
+
+	for _, invalid := range invalids {
+		_, err = Decode(invalid)
+		if err == nil {
+			t.Errorf("expected an error when decoding %#v", invalid)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	token := "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU"
+
+	legacy := &protoed.Channel{Token: token}
+	if !Matches(legacy, token) {
+		t.Errorf("expected the plain-text token to match")
+	}
+	if Matches(legacy, token+"_suffix") {
+		t.Errorf("expected a different token not to match the plain-text token")
+	}
+
+	if Matches(&protoed.Channel{}, "") {
+		t.Errorf("expected a channel without a token never to match")
+	}
+
+	h, err := New(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	hashed := &protoed.Channel{TokenHash: h}
+	if !Matches(hashed, token) {
+		t.Errorf("expected the token to match the hashed channel")
+	}
+	if Matches(hashed, token+"_suffix") {
+		t.Errorf("expected a different token not to match the hashed channel")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	first, err := Generate()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	second, err := Generate()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(first) != 43 || first == second {
+		t.Errorf("expected two different tokens of 43 characters, "+
+			"got %#v and %#v", first, second)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Date(2018, 10, 1, 14, 37, 0, 0, time.UTC)
+
+	hashes := make(map[string]*protoed.TokenHash)
+	for _, token := range []string{"default-token", "ci-token", "old-token"} {
+		h, err := New(token)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		hashes[token] = h
+	}
+
+	channel := &protoed.Channel{TokenHash: hashes["default-token"],
+		Tokens: []*protoed.ChannelToken{
+			{Name: "ci", Hash: hashes["ci-token"]},
+			{Name: "old", Hash: hashes["old-token"],
+				Expires: now.UnixNano()}}}
+
+	for _, tc := range []struct {
+		token string
+		name  string
+		ok    bool
+	}{
+		{"default-token", DefaultName, true},
+		{"ci-token", "ci", true},
+		{"old-token", "", false},
+		{"unknown-token", "", false}} {
+		name, ok, err := Authenticate(channel, tc.token, now)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if name != tc.name || ok != tc.ok {
+			t.Errorf("expected (%#v, %v) for the token %#v, got (%#v, %v)",
+				tc.name, tc.ok, tc.token, name, ok)
+		}
+	}
+
+	name, ok, err := Authenticate(channel, "old-token", now.Add(-time.Second))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if name != "old" || !ok {
+		t.Errorf("expected the token to be live before its expiry, "+
+			"got (%#v, %v)", name, ok)
+	}
+}
+
+func TestAuthenticate_Busy(t *testing.T) {
+	token := "oqiwdJKNsdKIUwezd92DNQsndkDERDFKJNQWSwq3rODIU"
+
+	h, err := New(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	channel := &protoed.Channel{TokenHash: h}
+
+	// Use up the budget as the concurrent requests would.
+	if !acquire(memoryBudget-argonMemory+1, false) {
+		t.Fatalf("expected the budget to be free")
+	}
+
+	_, ok, err := Authenticate(channel, token, time.Now())
+	if err != ErrBusy || ok {
+		t.Errorf("expected ErrBusy while the budget is used up, "+
+			"got (%v, %v)", ok, err)
+	}
+
+	if _, err = TryVerify(h, token); err != ErrBusy {
+		t.Errorf("expected ErrBusy from TryVerify, got %v", err)
+	}
+
+	release(memoryBudget - argonMemory + 1)
+
+	_, ok, err = Authenticate(channel, token, time.Now())
+	if err != nil || !ok {
+		t.Errorf("expected the token to authenticate once the budget "+
+			"is free, got (%v, %v)", ok, err)
+	}
+}